@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// frame is the subset of server frames loadgen reads
+type frame struct {
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	ProbeID string `json:"probe_id,omitempty"`
+	Room    string `json:"room,omitempty"`
+}
+
+// client is one synthetic chat user
+type client struct {
+	username string
+	room     string
+	conn     *websocket.Conn
+	stats    *stats
+
+	writeMutex sync.Mutex
+	joined     chan error // ผลของการเข้าห้อง (nil = สำเร็จ)
+	joinDone   int32      // 1 เมื่อส่งผลเข้า joined แล้ว
+	sequence   int64
+	closed     int32 // 1 เมื่อ loadgen ปิดเอง หรือ connection หลุดแล้ว
+}
+
+// dial connects a client and joins its room; the returned client reads frames until closed
+func dial(url string, id int, prefix, room string, s *stats, timeout time.Duration) (*client, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: timeout}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		username: fmt.Sprintf("%s%d", prefix, id),
+		room:     room,
+		conn:     conn,
+		stats:    s,
+		joined:   make(chan error, 1),
+	}
+	go c.readLoop()
+
+	if err := c.write(map[string]interface{}{"type": "join", "username": c.username}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case err := <-c.joined:
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	case <-time.After(timeout):
+		conn.Close()
+		return nil, fmt.Errorf("%s: timed out joining %s", c.username, room)
+	}
+	return c, nil
+}
+
+// write sends one JSON frame (gorilla/websocket อนุญาต writer ได้ทีละ goroutine)
+func (c *client) write(v interface{}) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.conn.WriteJSON(v)
+}
+
+// finishJoin reports the result of joining the room; only the first result counts
+func (c *client) finishJoin(err error) {
+	if atomic.CompareAndSwapInt32(&c.joinDone, 0, 1) {
+		c.joined <- err
+	}
+}
+
+// readLoop dispatches frames until the connection closes
+func (c *client) readLoop() {
+	defer func() {
+		if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+			atomic.AddInt64(&c.stats.disconnected, 1)
+		}
+		c.finishJoin(fmt.Errorf("%s: connection closed before joining", c.username))
+	}()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// broadcast ข้อความในห้องมาเป็น JSON พร้อม probe_id (load test mode) ส่วน text frame อื่นไม่ใช่ probe
+		var f frame
+		if json.Unmarshal(data, &f) != nil {
+			continue
+		}
+
+		switch f.Type {
+		case "message":
+			if f.ProbeID != "" {
+				c.stats.recordDelivery(f.ProbeID)
+			}
+		case "probe_echo":
+			c.stats.recordEcho(f.ProbeID)
+		case "delivery_probe":
+			// ตอบ delivery SLO probe ของ server เหมือน client จริง
+			c.write(map[string]interface{}{"type": "delivery_ack", "probe_id": f.ProbeID})
+		case "rooms_list":
+			// ได้ rooms_list หลัง welcome: อยู่ใน general แล้ว
+			if atomic.LoadInt32(&c.joinDone) == 1 {
+				continue
+			}
+			if c.room == defaultRoom {
+				c.finishJoin(nil)
+			} else {
+				c.write(map[string]interface{}{"type": "join_room", "room": c.room})
+			}
+		case "room_joined":
+			if f.Room == c.room {
+				c.finishJoin(nil)
+			}
+		case "error":
+			// error ก่อนเข้าห้องได้ทำให้ join ล้มเหลว หลังจากนั้นคือข้อความที่ถูกปฏิเสธ
+			if atomic.LoadInt32(&c.joinDone) == 0 {
+				c.finishJoin(fmt.Errorf("%s: %s", c.username, f.Message))
+				continue
+			}
+			c.stats.recordError(f)
+		}
+	}
+}
+
+// send writes one probe-tagged message of the given size.
+// probe_id นำหน้า content ด้วยเพื่อให้ทุกข้อความไม่ซ้ำกัน ผู้รับอ่าน probe จาก field probe_id เท่านั้น
+func (c *client) send(size int, expected int) error {
+	c.sequence++
+	probeID := fmt.Sprintf("%s-%d", c.username, c.sequence)
+	content := probeID + " " + filler(size-len(probeID)-1)
+
+	c.stats.recordSent(probeID, expected)
+	return c.write(map[string]interface{}{"type": "message", "content": content, "probe_id": probeID})
+}
+
+// fillerChars are cycled to pad messages (validator ถือว่าข้อความที่มีตัวอักษรเดียวซ้ำเกิน 10 ครั้งเป็น spam)
+const fillerChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// filler returns n padding characters starting at a random offset
+func filler(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	offset := rand.Intn(len(fillerChars))
+	for i := 0; i < n; i++ {
+		b.WriteByte(fillerChars[(offset+i)%len(fillerChars)])
+	}
+	return b.String()
+}
+
+// close closes the connection without counting it as a disconnect
+func (c *client) close() {
+	atomic.StoreInt32(&c.closed, 1)
+	c.writeMutex.Lock()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMutex.Unlock()
+	c.conn.Close()
+}
@@ -0,0 +1,242 @@
+// Command loadgen drives a chat server with synthetic clients and reports end-to-end delivery latency.
+//
+// ทุก client อยู่ใน process เดียวกัน จึงวัด latency จากเวลาที่ผู้ส่งเขียน frame จนถึงผู้รับอ่าน broadcast
+// ด้วยนาฬิกาเดียวกันได้ตรงๆ server ต้องเปิด load_test_mode (CHAT_LOAD_TEST_MODE=true) เพื่อส่ง probe_id
+// ต่อไปกับ broadcast ตอบ probe_echo และไม่จำกัดการเชื่อมต่อต่อ IP และควรปิด rate limit (CHAT_ENABLE_RATE_LIMIT=false) เมื่อส่งถี่
+//
+//	go run ./cmd/loadgen -url ws://localhost:8080/ws -clients 200 -rooms 4 -rate 2 -duration 1m
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRoom is the room every user joins after the handshake
+const defaultRoom = "general"
+
+// options are the command line flags
+type options struct {
+	url         string
+	clients     int
+	rooms       int
+	rate        float64
+	duration    time.Duration
+	size        int
+	connectRate int
+	drain       time.Duration
+	timeout     time.Duration
+	prefix      string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.url, "url", "ws://localhost:8080/ws", "WebSocket endpoint of the chat server")
+	flag.IntVar(&opts.clients, "clients", 50, "number of concurrent clients")
+	flag.IntVar(&opts.rooms, "rooms", 1, "number of rooms to spread clients over (1 = general)")
+	flag.Float64Var(&opts.rate, "rate", 0.1, "messages per second sent by each client")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long clients send messages")
+	flag.IntVar(&opts.size, "size", 64, "message size in bytes (keep under ~500: the server treats a character repeated over 10 times as spam)")
+	flag.IntVar(&opts.connectRate, "connect-rate", 100, "new connections per second while ramping up")
+	flag.DurationVar(&opts.drain, "drain", 3*time.Second, "time to wait for in-flight messages after sending stops")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "connect and join timeout per client")
+	flag.StringVar(&opts.prefix, "prefix", "", "username prefix (default: random per run)")
+	flag.Parse()
+
+	if opts.clients < 1 || opts.rooms < 1 || opts.rate <= 0 || opts.connectRate < 1 {
+		fmt.Fprintln(os.Stderr, "❌ -clients, -rooms, -rate and -connect-rate must be positive")
+		os.Exit(2)
+	}
+	if opts.prefix == "" {
+		// prefix ต่างกันทุก run เพราะ server จองชื่อของ connection ที่เพิ่งหลุดไว้ช่วง reconnect grace
+		opts.prefix = fmt.Sprintf("lg%04x_", rand.Intn(0x10000))
+	}
+
+	if err := run(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// roomName returns the room of the i-th client
+func roomName(opts options, i int) string {
+	if opts.rooms == 1 {
+		return defaultRoom
+	}
+	return fmt.Sprintf("loadgen-%d", i%opts.rooms+1)
+}
+
+// run connects the clients, sends messages for the configured duration and prints the report
+func run(opts options) error {
+	s := newStats()
+
+	if opts.rooms > 1 {
+		if err := createRooms(opts, s); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("🔌 Connecting %d clients to %s (%d/s)\n", opts.clients, opts.url, opts.connectRate)
+	clients := connect(opts, s)
+	if len(clients) == 0 {
+		return fmt.Errorf("no client could connect")
+	}
+	defer func() {
+		for _, c := range clients {
+			c.close()
+		}
+	}()
+
+	// ผู้รับที่คาดว่าจะได้ข้อความ = client ที่เชื่อมต่อได้ในห้องเดียวกัน ยกเว้นผู้ส่ง
+	members := make(map[string]int)
+	for _, c := range clients {
+		members[c.room]++
+	}
+
+	fmt.Printf("📨 Sending %.2f msg/s per client for %v\n", opts.rate, opts.duration)
+	start := time.Now()
+	interval := time.Duration(float64(time.Second) / opts.rate)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *client) {
+			defer wg.Done()
+			// เริ่มแต่ละ client ห่างกันแบบสุ่ม ไม่ให้ทุกคนส่งพร้อมกันเป็นจังหวะ
+			select {
+			case <-time.After(time.Duration(rand.Int63n(int64(interval)))):
+			case <-stop:
+				return
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				if atomic.LoadInt32(&c.closed) == 1 {
+					return
+				}
+				if err := c.send(opts.size, members[c.room]-1); err != nil {
+					return
+				}
+				select {
+				case <-ticker.C:
+				case <-stop:
+					return
+				}
+			}
+		}(c)
+	}
+
+	time.Sleep(opts.duration)
+	close(stop)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Printf("⏳ Draining for %v\n", opts.drain)
+	time.Sleep(opts.drain)
+
+	report(s, elapsed)
+	return nil
+}
+
+// createRooms creates the loadgen rooms with a setup connection; rooms that already exist are reused
+func createRooms(opts options, s *stats) error {
+	setup, err := dial(opts.url, 0, opts.prefix+"setup", defaultRoom, s, opts.timeout)
+	if err != nil {
+		return fmt.Errorf("setup client: %v", err)
+	}
+	defer setup.close()
+
+	for i := 0; i < opts.rooms; i++ {
+		name := roomName(opts, i)
+		// frame create_room ยังไม่สร้างห้องจริง จึงใช้คำสั่ง /create
+		if err := setup.write(map[string]interface{}{"type": "command", "command": "/create " + name}); err != nil {
+			return fmt.Errorf("create room %s: %v", name, err)
+		}
+		// ไม่รอผล: ห้องที่มีอยู่แล้วตอบ error ซึ่งไม่เป็นไร ห้องที่สร้างไม่ได้จะทำให้ client join ไม่ได้เอง
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// connect dials the clients at the configured rate and returns those that joined their room
+func connect(opts options, s *stats) []*client {
+	var (
+		mutex   sync.Mutex
+		clients []*client
+		wg      sync.WaitGroup
+		failed  int32
+	)
+	ticker := time.NewTicker(time.Second / time.Duration(opts.connectRate))
+	defer ticker.Stop()
+
+	for i := 0; i < opts.clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := dial(opts.url, i, opts.prefix, roomName(opts, i), s, opts.timeout)
+			if err != nil {
+				atomic.AddInt64(&s.connectFails, 1)
+				if atomic.AddInt32(&failed, 1) <= 5 {
+					fmt.Fprintf(os.Stderr, "⚠️ client %d: %v\n", i, err)
+				}
+				return
+			}
+			atomic.AddInt64(&s.connected, 1)
+			mutex.Lock()
+			clients = append(clients, c)
+			mutex.Unlock()
+		}(i)
+		<-ticker.C
+	}
+	wg.Wait()
+	return clients
+}
+
+// report prints counters and latency percentiles
+func report(s *stats, elapsed time.Duration) {
+	result := s.summarize()
+
+	fmt.Println()
+	fmt.Println("📊 Load test results")
+	fmt.Printf("   clients     %d connected, %d failed, %d disconnected\n",
+		atomic.LoadInt64(&s.connected), atomic.LoadInt64(&s.connectFails), atomic.LoadInt64(&s.disconnected))
+	fmt.Printf("   sent        %d (%.1f msg/s)\n", result.Sent, float64(result.Sent)/elapsed.Seconds())
+	errors := s.errorCounts()
+	var errorFrames int64
+	for _, count := range errors {
+		errorFrames += count
+	}
+	fmt.Printf("   accepted    %d (probe_echo), %d without echo, %d error frames\n",
+		result.Echoed, result.Unechoed, errorFrames)
+	for key, count := range errors {
+		fmt.Printf("                 %6d  %s\n", count, key)
+	}
+	fmt.Printf("   deliveries  %d of %d expected (%.1f/s)\n",
+		result.Delivered, result.Expected, float64(result.Delivered)/elapsed.Seconds())
+	dropRate := 0.0
+	if result.Expected > 0 {
+		dropRate = float64(result.Dropped) / float64(result.Expected) * 100
+	}
+	fmt.Printf("   dropped     %d (%.2f%%), %d duplicates, %d unknown probes\n",
+		result.Dropped, dropRate, result.Duplicates, atomic.LoadInt64(&s.unknown))
+
+	fmt.Println()
+	fmt.Printf("   %-10s %10s %10s %10s %10s %10s\n", "latency", "count", "p50 ms", "p95 ms", "p99 ms", "max ms")
+	for _, stage := range []string{stageAck, stageDelivery} {
+		entry := s.latency.Stage(stage)
+		fmt.Printf("   %-10s %10d %10.2f %10.2f %10.2f %10.2f\n", entry.Stage, entry.Count, entry.P50Ms, entry.P95Ms, entry.P99Ms, entry.MaxMs)
+	}
+
+	if result.Sent > 0 && result.Echoed == 0 {
+		fmt.Println()
+		fmt.Println("⚠️ No probe_echo received: start the server with CHAT_LOAD_TEST_MODE=true")
+	} else if errors["rate_limited"] > 0 {
+		fmt.Println()
+		fmt.Println("⚠️ Messages were rate limited; CHAT_ENABLE_RATE_LIMIT=false removes the per-user message limit")
+	}
+}
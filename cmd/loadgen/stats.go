@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// Latency stages reported by loadgen
+const (
+	stageDelivery = "delivery" // ผู้ส่งเขียน frame จนถึงผู้รับอ่าน broadcast ได้ (end-to-end)
+	stageAck      = "ack"      // ผู้ส่งเขียน frame จนถึงได้รับ probe_echo จาก server
+)
+
+// probe is one message sent with a probe_id
+type probe struct {
+	sentAt    time.Time
+	expected  int32 // จำนวนผู้รับในห้องตอนส่ง (ไม่รวมผู้ส่ง)
+	delivered int32
+	echoed    int32
+}
+
+// stats collects counters and latency histograms shared by all clients
+type stats struct {
+	latency *config.LatencyRecorder
+
+	connected    int64
+	connectFails int64
+	disconnected int64
+	unknown      int64 // broadcast ที่มี probe_id ซึ่งไม่ได้ส่งจาก run นี้
+
+	mutex  sync.Mutex
+	probes map[string]*probe
+	errors map[string]int64 // error frame หลังเข้าห้องแล้ว ตาม code (หรือข้อความเมื่อไม่มี code)
+}
+
+// newStats creates an empty stats collector
+func newStats() *stats {
+	return &stats{
+		latency: config.NewLatencyRecorder(),
+		probes:  make(map[string]*probe),
+		errors:  make(map[string]int64),
+	}
+}
+
+// recordSent registers a probe just before it is written
+func (s *stats) recordSent(probeID string, expected int) {
+	s.mutex.Lock()
+	s.probes[probeID] = &probe{sentAt: time.Now(), expected: int32(expected)}
+	s.mutex.Unlock()
+}
+
+// lookup returns the probe with the given ID
+func (s *stats) lookup(probeID string) (*probe, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p, exists := s.probes[probeID]
+	return p, exists
+}
+
+// recordDelivery records a broadcast received by another client
+func (s *stats) recordDelivery(probeID string) {
+	p, exists := s.lookup(probeID)
+	if !exists {
+		atomic.AddInt64(&s.unknown, 1)
+		return
+	}
+	s.latency.ObserveSince(stageDelivery, p.sentAt)
+	atomic.AddInt32(&p.delivered, 1)
+}
+
+// recordEcho records the server's probe_echo to the sender
+func (s *stats) recordEcho(probeID string) {
+	p, exists := s.lookup(probeID)
+	if !exists || !atomic.CompareAndSwapInt32(&p.echoed, 0, 1) {
+		return
+	}
+	s.latency.ObserveSince(stageAck, p.sentAt)
+}
+
+// recordError counts an error frame, e.g. a message rejected with rate_limited
+func (s *stats) recordError(f frame) {
+	key := f.Code
+	if key == "" {
+		key = f.Message
+	}
+	s.mutex.Lock()
+	s.errors[key]++
+	s.mutex.Unlock()
+}
+
+// errorCounts returns a copy of the error frame counts
+func (s *stats) errorCounts() map[string]int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := make(map[string]int64, len(s.errors))
+	for key, count := range s.errors {
+		counts[key] = count
+	}
+	return counts
+}
+
+// summary is the end-of-run result
+type summary struct {
+	Sent       int64
+	Echoed     int64
+	Unechoed   int64 // ไม่ได้ probe_echo: ถูกปฏิเสธ (rate limit, validation) หรือหายก่อนถึง server
+	Expected   int64 // delivery ที่ควรได้รับจากข้อความที่ server ตอบ echo แล้ว
+	Delivered  int64
+	Dropped    int64
+	Duplicates int64
+}
+
+// summarize counts expected and dropped deliveries.
+// นับเฉพาะข้อความที่ได้ probe_echo เพราะข้อความที่ถูกปฏิเสธไม่ถูก broadcast อยู่แล้ว
+func (s *stats) summarize() summary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result summary
+	result.Sent = int64(len(s.probes))
+	for _, p := range s.probes {
+		delivered := int64(atomic.LoadInt32(&p.delivered))
+		result.Delivered += delivered
+		if atomic.LoadInt32(&p.echoed) == 0 {
+			result.Unechoed++
+			continue
+		}
+		result.Echoed++
+		result.Expected += int64(p.expected)
+		switch {
+		case delivered < int64(p.expected):
+			result.Dropped += int64(p.expected) - delivered
+		case delivered > int64(p.expected):
+			result.Duplicates += delivered - int64(p.expected)
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package account
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Account is a registered username protected by a password.
+// แยกจาก user.User ที่มีอายุเท่ากับ connection: account อยู่ถาวรและเป็นเจ้าของชื่อผู้ใช้
+type Account struct {
+	ID           string    `json:"id"` // = user.AccountID(username) จึงใช้ profile เดียวกับก่อนมี account
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session is one login of an account.
+// token ส่งให้ client ครั้งเดียวตอน login; repository เก็บเฉพาะ SHA-256 ของ token
+type Session struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	Username  string    `json:"username"`
+	TokenHash string    `json:"-"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the session can no longer be used
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Repository persists accounts and their login sessions
+type Repository interface {
+	CreateAccount(account *Account) error // ErrUsernameTaken ถ้ามี account ID นี้แล้ว
+	GetAccount(id string) (*Account, error)
+	CreateSession(session *Session) error
+	GetSession(tokenHash string) (*Session, error)
+	ListSessions(accountID string) ([]*Session, error)
+	DeleteSession(accountID, sessionID string) error
+}
+
+// Account errors
+var (
+	ErrUsernameTaken      = errors.New("username is already registered")
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidPassword    = errors.New("invalid password")
+)
+
+// InMemoryRepository implements Repository using in-memory storage
+type InMemoryRepository struct {
+	accounts map[string]*Account // accountID -> Account
+	sessions map[string]*Session // tokenHash -> Session
+	mutex    sync.RWMutex
+}
+
+// NewInMemoryRepository creates a new in-memory account repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		accounts: make(map[string]*Account),
+		sessions: make(map[string]*Session),
+	}
+}
+
+// CreateAccount stores a new account
+func (r *InMemoryRepository) CreateAccount(account *Account) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.accounts[account.ID]; exists {
+		return ErrUsernameTaken
+	}
+	copied := *account
+	r.accounts[account.ID] = &copied
+	return nil
+}
+
+// GetAccount returns a copy of an account
+func (r *InMemoryRepository) GetAccount(id string) (*Account, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	account, exists := r.accounts[id]
+	if !exists {
+		return nil, ErrAccountNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+// CreateSession stores a new session
+func (r *InMemoryRepository) CreateSession(session *Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	copied := *session
+	r.sessions[session.TokenHash] = &copied
+	return nil
+}
+
+// GetSession returns a copy of the session with this token hash
+func (r *InMemoryRepository) GetSession(tokenHash string) (*Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	session, exists := r.sessions[tokenHash]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// ListSessions returns the account's sessions, newest first.
+// session ที่หมดอายุถูกลบไปพร้อมกัน (memory backend ไม่มี TTL index แบบ MongoDB)
+func (r *InMemoryRepository) ListSessions(accountID string) ([]*Session, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	sessions := []*Session{}
+	for tokenHash, session := range r.sessions {
+		if session.AccountID != accountID {
+			continue
+		}
+		if session.Expired(now) {
+			delete(r.sessions, tokenHash)
+			continue
+		}
+		copied := *session
+		sessions = append(sessions, &copied)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteSession removes one of the account's sessions
+func (r *InMemoryRepository) DeleteSession(accountID, sessionID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for tokenHash, session := range r.sessions {
+		if session.AccountID == accountID && session.ID == sessionID {
+			delete(r.sessions, tokenHash)
+			return nil
+		}
+	}
+	return ErrSessionNotFound
+}
@@ -0,0 +1,172 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountDocument represents an account document in MongoDB; _id is the account ID
+type AccountDocument struct {
+	ID           string    `bson:"_id"`
+	Username     string    `bson:"username"`
+	PasswordHash string    `bson:"password_hash"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
+
+// SessionDocument represents a login session in MongoDB (ลบอัตโนมัติด้วย TTL index ของ expires_at)
+type SessionDocument struct {
+	ID        string    `bson:"_id"`
+	AccountID string    `bson:"account_id"`
+	Username  string    `bson:"username"`
+	TokenHash string    `bson:"token_hash"`
+	UserAgent string    `bson:"user_agent,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// MongoRepository implements Repository using MongoDB
+type MongoRepository struct {
+	accounts *mongo.Collection
+	sessions *mongo.Collection
+}
+
+// NewMongoRepository creates a new MongoDB account repository
+func NewMongoRepository(db *database.MongoDB) *MongoRepository {
+	return &MongoRepository{
+		accounts: db.GetCollection("accounts"),
+		sessions: db.GetCollection("account_sessions"),
+	}
+}
+
+// CreateAccount inserts a new account
+func (r *MongoRepository) CreateAccount(account *Account) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.accounts.InsertOne(ctx, AccountDocument{
+		ID:           account.ID,
+		Username:     account.Username,
+		PasswordHash: account.PasswordHash,
+		CreatedAt:    account.CreatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create account: %v", err)
+	}
+	return nil
+}
+
+// GetAccount returns an account by ID
+func (r *MongoRepository) GetAccount(id string) (*Account, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var doc AccountDocument
+	if err := r.accounts.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	return &Account{
+		ID:           doc.ID,
+		Username:     doc.Username,
+		PasswordHash: doc.PasswordHash,
+		CreatedAt:    doc.CreatedAt,
+	}, nil
+}
+
+// CreateSession inserts a new session
+func (r *MongoRepository) CreateSession(session *Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.sessions.InsertOne(ctx, SessionDocument{
+		ID:        session.ID,
+		AccountID: session.AccountID,
+		Username:  session.Username,
+		TokenHash: session.TokenHash,
+		UserAgent: session.UserAgent,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+// GetSession returns the session with this token hash
+func (r *MongoRepository) GetSession(tokenHash string) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var doc SessionDocument
+	if err := r.sessions.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+	return doc.toSession(), nil
+}
+
+// ListSessions returns the account's sessions, newest first
+func (r *MongoRepository) ListSessions(accountID string) ([]*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.sessions.Find(ctx, bson.M{"account_id": accountID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []SessionDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %v", err)
+	}
+	sessions := make([]*Session, 0, len(docs))
+	for i := range docs {
+		sessions = append(sessions, docs[i].toSession())
+	}
+	return sessions, nil
+}
+
+// DeleteSession removes one of the account's sessions
+func (r *MongoRepository) DeleteSession(accountID, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.sessions.DeleteOne(ctx, bson.M{"_id": sessionID, "account_id": accountID})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// toSession converts a session document to a Session
+func (doc *SessionDocument) toSession() *Session {
+	return &Session{
+		ID:        doc.ID,
+		AccountID: doc.AccountID,
+		Username:  doc.Username,
+		TokenHash: doc.TokenHash,
+		UserAgent: doc.UserAgent,
+		CreatedAt: doc.CreatedAt,
+		ExpiresAt: doc.ExpiresAt,
+	}
+}
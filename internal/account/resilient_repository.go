@@ -0,0 +1,63 @@
+package account
+
+import "realtime-chat/internal/database"
+
+// ResilientRepository wraps a Repository with the database circuit breaker; while the breaker is open
+// every call fails fast with database.ErrUnavailable instead of waiting for the MongoDB timeout
+type ResilientRepository struct {
+	Repository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientRepository creates an account repository that fails fast during outages
+func NewResilientRepository(repo Repository, breaker *database.CircuitBreaker) *ResilientRepository {
+	return &ResilientRepository{Repository: repo, breaker: breaker}
+}
+
+// CreateAccount fails fast while the database is unavailable
+func (r *ResilientRepository) CreateAccount(account *Account) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.CreateAccount(account)
+}
+
+// GetAccount fails fast while the database is unavailable
+func (r *ResilientRepository) GetAccount(id string) (*Account, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.GetAccount(id)
+}
+
+// CreateSession fails fast while the database is unavailable
+func (r *ResilientRepository) CreateSession(session *Session) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.CreateSession(session)
+}
+
+// GetSession fails fast while the database is unavailable
+func (r *ResilientRepository) GetSession(tokenHash string) (*Session, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.GetSession(tokenHash)
+}
+
+// ListSessions fails fast while the database is unavailable
+func (r *ResilientRepository) ListSessions(accountID string) ([]*Session, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.ListSessions(accountID)
+}
+
+// DeleteSession fails fast while the database is unavailable
+func (r *ResilientRepository) DeleteSession(accountID, sessionID string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.DeleteSession(accountID, sessionID)
+}
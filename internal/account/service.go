@@ -0,0 +1,183 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"realtime-chat/internal/config"
+	userPkg "realtime-chat/internal/user"
+)
+
+// maxPasswordBytes is the longest password bcrypt hashes without truncating
+const maxPasswordBytes = 72
+
+// Service registers accounts, logs them in and checks session tokens
+type Service struct {
+	repo              Repository
+	minPasswordLength int
+	sessionTTL        time.Duration
+	dummyHash         []byte // เทียบกับ username ที่ไม่มีอยู่ เพื่อให้ login ใช้เวลาเท่ากันทั้งสองกรณี
+}
+
+// NewService creates an account service on top of repo
+func NewService(repo Repository, cfg *config.ServerConfig) *Service {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return &Service{
+		repo:              repo,
+		minPasswordLength: cfg.MinPasswordLength,
+		sessionTTL:        cfg.AccountSessionTTL,
+		dummyHash:         dummyHash,
+	}
+}
+
+// Register creates an account for an already validated username.
+// ชื่อผู้ใช้ไม่สนตัวพิมพ์ (Alice กับ alice เป็น account เดียวกัน) ตาม user.AccountID
+func (s *Service) Register(username, password string) (*Account, error) {
+	if err := s.validatePassword(password); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	account := &Account{
+		ID:           userPkg.AccountID(username),
+		Username:     username,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.CreateAccount(account); err != nil {
+		return nil, err
+	}
+	slog.Info("🔑 Account registered", "username", username, "account_id", account.ID)
+	return account, nil
+}
+
+// Login checks a password and starts a session; the returned token is only available here
+func (s *Service) Login(username, password, userAgent string) (*Session, string, error) {
+	account, err := s.repo.GetAccount(userPkg.AccountID(username))
+	if err == ErrAccountNotFound {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)) != nil {
+		return nil, "", ErrInvalidCredentials
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	sessionID, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := &Session{
+		ID:        sessionID,
+		AccountID: account.ID,
+		Username:  account.Username,
+		TokenHash: hashToken(token),
+		UserAgent: userAgent,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, "", err
+	}
+	slog.Info("🔓 Account logged in", "username", account.Username, "session", session.ID)
+	return session, token, nil
+}
+
+// Authenticate returns the session of a token; expired sessions are deleted and rejected
+func (s *Service) Authenticate(token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	session, err := s.repo.GetSession(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if session.Expired(time.Now()) {
+		s.repo.DeleteSession(session.AccountID, session.ID)
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// Logout ends the session of a token
+func (s *Service) Logout(token string) error {
+	session, err := s.Authenticate(token)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteSession(session.AccountID, session.ID)
+}
+
+// Sessions lists the account's active sessions
+func (s *Service) Sessions(accountID string) ([]*Session, error) {
+	sessions, err := s.repo.ListSessions(accountID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.Expired(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends one of the account's sessions (e.g. a login on a lost device)
+func (s *Service) Revoke(accountID, sessionID string) error {
+	return s.repo.DeleteSession(accountID, sessionID)
+}
+
+// IsRegistered reports whether a username belongs to an account
+func (s *Service) IsRegistered(username string) (bool, error) {
+	_, err := s.repo.GetAccount(userPkg.AccountID(username))
+	if err == ErrAccountNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// validatePassword checks the password length policy
+func (s *Service) validatePassword(password string) error {
+	if len([]rune(password)) < s.minPasswordLength {
+		return fmt.Errorf("%w: too short (min %d characters)", ErrInvalidPassword, s.minPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: too long (max %d bytes)", ErrInvalidPassword, maxPasswordBytes)
+	}
+	return nil
+}
+
+// hashToken returns the SHA-256 of a session token as stored by the repository
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
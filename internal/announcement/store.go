@@ -0,0 +1,166 @@
+package announcement
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Announcement is an admin notice sent to every online user, with per-user delivery and acknowledgment
+type Announcement struct {
+	ID         string
+	Content    string
+	CreatedBy  string
+	CreatedAt  time.Time
+	recipients map[string]time.Time // username -> delivered at
+	acks       map[string]time.Time // username -> acknowledged at
+}
+
+// Ack records when a user acknowledged an announcement
+type Ack struct {
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+}
+
+// Report summarises delivery and acknowledgment compliance of an announcement
+type Report struct {
+	ID             string    `json:"id"`
+	Content        string    `json:"content"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	Recipients     int       `json:"recipients"`
+	Acknowledged   int       `json:"acknowledged"`
+	ComplianceRate float64   `json:"compliance_rate"` // acknowledged / recipients
+	AckedBy        []Ack     `json:"acked_by,omitempty"`
+	Pending        []string  `json:"pending,omitempty"` // ได้รับแล้วแต่ยังไม่ ack
+}
+
+// Store keeps recent announcements in memory; the oldest are dropped beyond the limit
+type Store struct {
+	announcements []*Announcement // เรียงจากเก่าไปใหม่
+	byID          map[string]*Announcement
+	maxKept       int
+	nextID        int
+	mutex         sync.RWMutex
+}
+
+// NewStore creates a new announcement store
+func NewStore(maxKept int) *Store {
+	if maxKept <= 0 {
+		maxKept = 50
+	}
+	return &Store{
+		byID:    make(map[string]*Announcement),
+		maxKept: maxKept,
+	}
+}
+
+// Create records a new announcement and returns its ID
+func (s *Store) Create(content, createdBy string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	announcement := &Announcement{
+		ID:         "a" + strconv.Itoa(s.nextID),
+		Content:    content,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+		recipients: make(map[string]time.Time),
+		acks:       make(map[string]time.Time),
+	}
+	s.announcements = append(s.announcements, announcement)
+	s.byID[announcement.ID] = announcement
+
+	if len(s.announcements) > s.maxKept {
+		oldest := s.announcements[0]
+		s.announcements = s.announcements[1:]
+		delete(s.byID, oldest.ID)
+	}
+	return announcement.ID
+}
+
+// MarkDelivered records that the announcement was sent to a user
+func (s *Store) MarkDelivered(id, username string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if announcement, exists := s.byID[id]; exists {
+		announcement.recipients[username] = time.Now()
+	}
+}
+
+// Acknowledge records an explicit acknowledgment from a recipient
+func (s *Store) Acknowledge(id, username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	announcement, exists := s.byID[id]
+	if !exists {
+		return fmt.Errorf("announcement '%s' not found", id)
+	}
+	if _, received := announcement.recipients[username]; !received {
+		return fmt.Errorf("announcement '%s' was not sent to you", id)
+	}
+	if _, acked := announcement.acks[username]; !acked {
+		announcement.acks[username] = time.Now()
+	}
+	return nil
+}
+
+// Report returns the compliance report of one announcement
+func (s *Store) Report(id string) (Report, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	announcement, exists := s.byID[id]
+	if !exists {
+		return Report{}, false
+	}
+	return announcement.report(true), true
+}
+
+// Reports returns summary reports of all kept announcements, newest first
+func (s *Store) Reports() []Report {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	reports := make([]Report, 0, len(s.announcements))
+	for i := len(s.announcements) - 1; i >= 0; i-- {
+		reports = append(reports, s.announcements[i].report(false))
+	}
+	return reports
+}
+
+// report builds a report; detailed includes who acked and who is pending (assumes lock is held)
+func (a *Announcement) report(detailed bool) Report {
+	report := Report{
+		ID:           a.ID,
+		Content:      a.Content,
+		CreatedBy:    a.CreatedBy,
+		CreatedAt:    a.CreatedAt,
+		Recipients:   len(a.recipients),
+		Acknowledged: len(a.acks),
+	}
+	if report.Recipients > 0 {
+		report.ComplianceRate = float64(report.Acknowledged) / float64(report.Recipients)
+	}
+	if !detailed {
+		return report
+	}
+
+	for username, at := range a.acks {
+		report.AckedBy = append(report.AckedBy, Ack{Username: username, At: at})
+	}
+	sort.Slice(report.AckedBy, func(i, j int) bool { return report.AckedBy[i].At.Before(report.AckedBy[j].At) })
+
+	for username := range a.recipients {
+		if _, acked := a.acks[username]; !acked {
+			report.Pending = append(report.Pending, username)
+		}
+	}
+	sort.Strings(report.Pending)
+	return report
+}
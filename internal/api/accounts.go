@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+)
+
+// CredentialsRequest is the body of POST /api/register and POST /api/login
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by POST /api/login; the token is sent as session_token when joining over WebSocket
+type LoginResponse struct {
+	Token   string           `json:"token"`
+	Session *account.Session `json:"session"`
+}
+
+// SessionView is a session listed by GET /api/sessions
+type SessionView struct {
+	*account.Session
+	Current bool `json:"current"` // session ของ token ที่ใช้เรียก API นี้
+}
+
+// SetAccounts sets the account service (nil disables registration and login)
+func (h *Handler) SetAccounts(accounts *account.Service) {
+	h.accounts = accounts
+}
+
+// SetProxyPolicy sets how the client IP is read from requests that come through reverse proxies
+func (h *Handler) SetProxyPolicy(policy *security.ProxyPolicy) {
+	h.proxies = policy
+}
+
+// SetConnectionThrottle sets the per-IP throttle that also blocks repeated failed logins
+func (h *Handler) SetConnectionThrottle(throttle *security.ConnectionThrottle) {
+	h.throttle = throttle
+}
+
+// LoginAlerter notifies the online sessions of an account (not guests using the same name)
+type LoginAlerter interface {
+	AlertUser(username, content string) int
+}
+
+// SetLoginLockout sets the per-account and per-IP lockout of failed password logins,
+// where failed logins are alerted to the account's online sessions and lockouts recorded to the audit log (both optional)
+func (h *Handler) SetLoginLockout(lockout *security.LoginLockout, alerter LoginAlerter, audit *moderation.AuditLog) {
+	h.lockout = lockout
+	h.loginAlerter = alerter
+	h.auditLog = audit
+}
+
+// handleRegister handles POST /api/register
+func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil || h.validator == nil {
+		writeError(w, http.StatusServiceUnavailable, "accounts are disabled")
+		return
+	}
+	if h.rejectBlocked(w, r) {
+		return
+	}
+
+	var req CredentialsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	username, err := h.validator.ValidateUsername(req.Username)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	acct, err := h.accounts.Register(username, req.Password)
+	switch {
+	case errors.Is(err, account.ErrInvalidPassword):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case err == account.ErrUsernameTaken:
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to register account")
+	default:
+		writeJSON(w, http.StatusCreated, acct)
+	}
+}
+
+// handleLogin handles POST /api/login.
+// login ที่ผิดนับรวมกับ auth failure ของ WebSocket ใน ConnectionThrottle เดียวกัน และนับแยกต่อ account/IP ใน LoginLockout
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		writeError(w, http.StatusServiceUnavailable, "accounts are disabled")
+		return
+	}
+	if h.rejectBlocked(w, r) {
+		return
+	}
+
+	var req CredentialsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	accountID, ip := userPkg.AccountID(username), h.proxies.ClientIP(r)
+	if h.lockout != nil {
+		// ล็อกอยู่ก็ไม่ตรวจรหัสผ่านเลย ผู้เดาจึงไม่รู้ว่าเดาถูกระหว่างถูกล็อก
+		if locked, retryAfter := h.lockout.Locked(accountID, ip); locked {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			writeError(w, http.StatusTooManyRequests, "too many failed logins, try again later")
+			return
+		}
+	}
+
+	session, token, err := h.accounts.Login(username, req.Password, r.UserAgent())
+	if err == account.ErrInvalidCredentials {
+		if h.throttle != nil {
+			h.throttle.RecordAuthFailure(ip)
+		}
+		if h.lockout != nil {
+			accountLock, ipLock := h.lockout.RecordFailure(accountID, ip)
+			h.reportLoginFailure(username, ip, accountLock, ipLock)
+		}
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to log in")
+		return
+	}
+	if h.throttle != nil {
+		h.throttle.RecordAuthSuccess(ip)
+	}
+	if h.lockout != nil {
+		h.lockout.RecordSuccess(accountID)
+	}
+	writeJSON(w, http.StatusOK, LoginResponse{Token: token, Session: session})
+}
+
+// reportLoginFailure alerts the account's online sessions about a wrong password and audits new lockouts
+func (h *Handler) reportLoginFailure(username, ip string, accountLock, ipLock time.Duration) {
+	if h.loginAlerter != nil {
+		alert := fmt.Sprintf("⚠️ Failed login to your account from %s", ip)
+		if accountLock > 0 {
+			alert += fmt.Sprintf(" - password login is locked for %v (ask an admin to /unlock it if this was you)", accountLock)
+		}
+		h.loginAlerter.AlertUser(username, alert)
+	}
+
+	if h.auditLog == nil {
+		return
+	}
+	// ip ที่ถูกล็อกเก็บไว้ใน Connection เพื่อให้ admin เห็นใน /flags และ /unlock ได้ตรงตัว
+	if accountLock > 0 {
+		h.auditLog.Record(moderation.Flag{Username: username, Connection: ip, Reason: "login_locked",
+			Detail: fmt.Sprintf("account locked for %v", accountLock)})
+	}
+	if ipLock > 0 {
+		h.auditLog.Record(moderation.Flag{Username: username, Connection: ip, Reason: "login_locked",
+			Detail: fmt.Sprintf("ip %s locked for %v", ip, ipLock)})
+	}
+}
+
+// handleLogout handles POST /api/logout (Bearer session token)
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if h.accounts == nil {
+		writeError(w, http.StatusServiceUnavailable, "accounts are disabled")
+		return
+	}
+	if err := h.accounts.Logout(bearerToken(r)); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid or expired session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListSessions handles GET /api/sessions (Bearer session token)
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.requireSession(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := h.accounts.Sessions(current.AccountID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+
+	views := make([]SessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, SessionView{Session: session, Current: session.ID == current.ID})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"username": current.Username,
+		"sessions": views,
+	})
+}
+
+// handleRevokeSession handles DELETE /api/sessions/{id} (Bearer session token)
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.requireSession(w, r)
+	if !ok {
+		return
+	}
+	err := h.accounts.Revoke(current.AccountID, r.PathValue("id"))
+	if err == account.ErrSessionNotFound {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSession checks the Bearer session token and writes an error when it is missing or invalid
+func (h *Handler) requireSession(w http.ResponseWriter, r *http.Request) (*account.Session, bool) {
+	if h.accounts == nil {
+		writeError(w, http.StatusServiceUnavailable, "accounts are disabled")
+		return nil, false
+	}
+	token := bearerToken(r)
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, "missing session token")
+		return nil, false
+	}
+	session, err := h.accounts.Authenticate(token)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid or expired session")
+		return nil, false
+	}
+	return session, true
+}
+
+// rejectBlocked writes 429 when the client IP is blocked by the connection throttle
+func (h *Handler) rejectBlocked(w http.ResponseWriter, r *http.Request) bool {
+	if h.throttle == nil {
+		return false
+	}
+	if blocked, retryAfter := h.throttle.IsBlocked(h.proxies.ClientIP(r)); blocked {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, "too many failed attempts")
+		return true
+	}
+	return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	return strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+)
+
+// รหัสผ่านผิดซ้ำล็อกทั้ง account (จากทุก IP) และ IP (ทุก account) แม้รหัสผ่านถัดไปจะถูก
+func TestLoginLocksAccountAndIPAfterRepeatedFailures(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	cfg.LoginFailureLimit = 3
+	cfg.LoginIPFailureLimit = 5
+	cfg.LoginLockoutBase = time.Minute
+
+	metrics := config.NewServerMetrics()
+	accounts := account.NewService(account.NewInMemoryRepository(), cfg)
+	for _, username := range []string{"alice", "bobby", "carol"} {
+		if _, err := accounts.Register(username, "correct horse battery"); err != nil {
+			t.Fatalf("register %s: %v", username, err)
+		}
+	}
+
+	handler := NewHandler(room.NewService(room.NewInMemoryRepository(), cfg.MaxRooms, cfg.MaxUsersPerRoom, cfg.MaxRoomCapacity, metrics),
+		userPkg.NewService(userPkg.NewInMemoryRepository(), metrics))
+	handler.SetAccounts(accounts)
+	alerts := &recordingAlerter{}
+	audit := moderation.NewAuditLog(10)
+	handler.SetLoginLockout(security.NewLoginLockout(cfg), alerts, audit)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	login := func(username, password, ip string) int {
+		body := `{"username":"` + username + `","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+		req.RemoteAddr = ip + ":40000"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// account: ผิด 3 ครั้งจากคนละ IP แล้วรหัสถูกก็ยังเข้าไม่ได้ ส่วน account อื่นจาก IP เดียวกันยังเข้าได้
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		if code := login("alice", "wrong password", ip); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d = %d, want 401", i+1, code)
+		}
+	}
+	if code := login("alice", "correct horse battery", "192.0.2.4"); code != http.StatusTooManyRequests {
+		t.Fatalf("locked account login = %d, want 429", code)
+	}
+	if code := login("bobby", "correct horse battery", "192.0.2.1"); code != http.StatusOK {
+		t.Fatalf("other account login = %d, want 200", code)
+	}
+	// ทุกครั้งที่ผิดแจ้ง session ของ account พร้อม IP ต้นทาง และการล็อกถูกบันทึกลง audit log
+	if len(alerts.alerts) != 3 || !strings.Contains(alerts.alerts[0], "192.0.2.1") || !strings.Contains(alerts.alerts[2], "locked") {
+		t.Fatalf("alerts = %q, want 3 with the source IP and the lockout", alerts.alerts)
+	}
+	if flags := audit.Recent(10); len(flags) != 1 || flags[0].Reason != "login_locked" || flags[0].Username != "alice" {
+		t.Fatalf("audit log = %+v, want one login_locked for alice", flags)
+	}
+
+	// IP: ผิด 5 ครั้งรวมทุก account จาก IP เดียว แล้ว account ที่ไม่เคยผิดก็เข้าจาก IP นั้นไม่ได้
+	for i, username := range []string{"bobby", "bobby", "nobody", "someone", "bobby"} {
+		if code := login(username, "wrong password", "198.51.100.7"); code != http.StatusUnauthorized {
+			t.Fatalf("ip failure %d = %d, want 401", i+1, code)
+		}
+	}
+	if code := login("carol", "correct horse battery", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("locked ip login = %d, want 429", code)
+	}
+	if code := login("carol", "correct horse battery", "203.0.113.9"); code != http.StatusOK {
+		t.Fatalf("login from another ip = %d, want 200", code)
+	}
+}
+
+// recordingAlerter records alerts sent to alice's sessions
+type recordingAlerter struct {
+	alerts []string
+}
+
+func (a *recordingAlerter) AlertUser(username, content string) int {
+	if username == "alice" {
+		a.alerts = append(a.alerts, content)
+	}
+	return 1
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// maxActivityBuckets limits how many buckets a single request may return
+const maxActivityBuckets = 24 * 31
+
+// ActivityResponse represents the room activity payload
+type ActivityResponse struct {
+	Room    string                      `json:"room"`
+	Bucket  string                      `json:"bucket"`
+	From    time.Time                   `json:"from"`
+	To      time.Time                   `json:"to"`
+	Total   int64                       `json:"total"`
+	Buckets []messagePkg.ActivityBucket `json:"buckets"`
+}
+
+// handleRoomActivity handles GET /api/rooms/{room}/activity?from=&to=&bucket=hour|day
+func (h *Handler) handleRoomActivity(w http.ResponseWriter, r *http.Request) {
+	if h.messageRepo == nil {
+		writeError(w, http.StatusServiceUnavailable, "message persistence is disabled")
+		return
+	}
+
+	roomName := r.PathValue("room")
+	chatRoom, ok := h.readableRoom(w, r, roomName)
+	if !ok {
+		return
+	}
+	if chatRoom.Private {
+		writeError(w, http.StatusForbidden, "room is in privacy mode; analytics are disabled")
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = messagePkg.ActivityBucketHour
+	}
+	if bucket != messagePkg.ActivityBucketHour && bucket != messagePkg.ActivityBucketDay {
+		writeError(w, http.StatusBadRequest, "bucket must be 'hour' or 'day'")
+		return
+	}
+	step := time.Hour
+	if bucket == messagePkg.ActivityBucketDay {
+		step = 24 * time.Hour
+	}
+
+	// ค่าเริ่มต้น: 24 ชั่วโมงล่าสุด
+	to := time.Now().UTC()
+	from := to.Add(-24 * time.Hour)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'from' (expected RFC3339)")
+			return
+		}
+		from = parsed.UTC()
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'to' (expected RFC3339)")
+			return
+		}
+		to = parsed.UTC()
+	}
+
+	from = from.Truncate(step)
+	if !to.After(from) {
+		writeError(w, http.StatusBadRequest, "'to' must be after 'from'")
+		return
+	}
+	if to.Sub(from)/step > maxActivityBuckets {
+		writeError(w, http.StatusBadRequest, "requested range is too large")
+		return
+	}
+
+	buckets, err := h.messageRepo.GetRoomActivity(roomName, from, to, bucket)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// เติม bucket ที่ไม่มีข้อความเป็น 0 เพื่อให้ client วาด sparkline ได้ต่อเนื่อง
+	counts := make(map[int64]int64, len(buckets))
+	for _, b := range buckets {
+		counts[b.Start.UTC().Truncate(step).Unix()] = b.Count
+	}
+
+	response := ActivityResponse{
+		Room:    roomName,
+		Bucket:  bucket,
+		From:    from,
+		To:      to,
+		Buckets: make([]messagePkg.ActivityBucket, 0),
+	}
+	for start := from; start.Before(to); start = start.Add(step) {
+		count := counts[start.Unix()]
+		response.Total += count
+		response.Buckets = append(response.Buckets, messagePkg.ActivityBucket{Start: start, Count: count})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
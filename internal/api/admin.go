@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"realtime-chat/internal/config"
+)
+
+// ConnectionAdmin lists and force-closes WebSocket connections
+type ConnectionAdmin interface {
+	GetAllConnectionsHealth() map[string]*config.ConnectionHealth
+	Disconnect(target string) (string, bool)
+}
+
+// Announcer sends server-wide announcements
+type Announcer interface {
+	Announce(content, createdBy string) (string, int, error)
+}
+
+// ConfigUpdater applies runtime configuration changes
+type ConfigUpdater interface {
+	UpdateConfig(updates map[string]interface{}) error
+	GetConfigSummary() map[string]interface{}
+}
+
+// AdminConnection is one entry of GET /admin/connections
+type AdminConnection struct {
+	Label  string                   `json:"label"`
+	Health *config.ConnectionHealth `json:"health"`
+}
+
+// AnnouncementRequest is the body of POST /admin/announcements
+type AnnouncementRequest struct {
+	Content string `json:"content"`
+	From    string `json:"from"` // ชื่อผู้ประกาศที่แสดงต่อผู้ใช้ (ว่าง = "admin")
+}
+
+// SetAdmin enables the /admin endpoints; any of the controls may be nil
+func (h *Handler) SetAdmin(connections ConnectionAdmin, announcer Announcer, configUpdater ConfigUpdater) {
+	h.connections = connections
+	h.announcer = announcer
+	h.configUpdater = configUpdater
+}
+
+// handleAdminConnections handles GET /admin/connections
+func (h *Handler) handleAdminConnections(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.connections == nil {
+		writeError(w, http.StatusServiceUnavailable, "connection admin is disabled")
+		return
+	}
+
+	health := h.connections.GetAllConnectionsHealth()
+	connections := make([]AdminConnection, 0, len(health))
+	unhealthy := 0
+	for label, stats := range health {
+		connections = append(connections, AdminConnection{Label: label, Health: stats})
+		if !stats.IsHealthy {
+			unhealthy++
+		}
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].Label < connections[j].Label
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"connections": connections,
+		"total":       len(connections),
+		"unhealthy":   unhealthy,
+	})
+}
+
+// handleAdminDisconnect handles DELETE /admin/connections/{id} (connection ID or label)
+func (h *Handler) handleAdminDisconnect(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.connections == nil {
+		writeError(w, http.StatusServiceUnavailable, "connection admin is disabled")
+		return
+	}
+
+	label, ok := h.connections.Disconnect(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "connection not found")
+		return
+	}
+	slog.Info("🛠️ Admin API disconnected connection", "label", label)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"disconnected": label,
+	})
+}
+
+// handleAdminAnnounce handles POST /admin/announcements
+func (h *Handler) handleAdminAnnounce(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.announcer == nil || h.validator == nil {
+		writeError(w, http.StatusServiceUnavailable, "announcements are disabled")
+		return
+	}
+
+	var req AnnouncementRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16384)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	content, err := h.validator.ValidateMessage(req.Content)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	from := strings.TrimSpace(req.From)
+	if from == "" {
+		from = "admin"
+	}
+
+	id, delivered, err := h.announcer.Announce(content, from)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":        id,
+		"delivered": delivered,
+	})
+}
+
+// handleAdminConfig handles GET /admin/config
+func (h *Handler) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.configUpdater == nil {
+		writeError(w, http.StatusServiceUnavailable, "runtime configuration is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.configUpdater.GetConfigSummary())
+}
+
+// handleAdminUpdateConfig handles PATCH /admin/config: a JSON object of runtime settings,
+// e.g. {"rate_limit_messages": 20, "rate_limit_window": "30s"}
+func (h *Handler) handleAdminUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.configUpdater == nil {
+		writeError(w, http.StatusServiceUnavailable, "runtime configuration is disabled")
+		return
+	}
+
+	var updates map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&updates); err != nil || len(updates) == 0 {
+		writeError(w, http.StatusBadRequest, "body must be a non-empty JSON object of settings")
+		return
+	}
+	if err := h.configUpdater.UpdateConfig(updates); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	slog.Info("🛠️ Admin API updated config", "keys", keys)
+	writeJSON(w, http.StatusOK, h.configUpdater.GetConfigSummary())
+}
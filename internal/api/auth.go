@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// SetAPIKeys sets the keys accepted by integration endpoints (empty disables them)
+func (h *Handler) SetAPIKeys(keys []string) {
+	h.apiKeys = nil
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			h.apiKeys = append(h.apiKeys, key)
+		}
+	}
+}
+
+// requireAPIKey checks the X-API-Key header (or a Bearer token) and writes an error when it is missing or wrong
+func (h *Handler) requireAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if len(h.apiKeys) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "API keys are not configured")
+		return false
+	}
+
+	provided := r.Header.Get("X-API-Key")
+	if provided == "" {
+		provided = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if provided == "" {
+		writeError(w, http.StatusUnauthorized, "missing API key")
+		return false
+	}
+
+	// เทียบแบบ constant time ทุก key เพื่อไม่ให้เดา key จากเวลาตอบกลับได้
+	valid := false
+	for _, key := range h.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	if !valid {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return false
+	}
+	return true
+}
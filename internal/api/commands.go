@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"realtime-chat/internal/commands"
+	userPkg "realtime-chat/internal/user"
+)
+
+// CommandCatalog describes the slash commands registered on the server
+type CommandCatalog interface {
+	DescribeCommands(user *userPkg.User) []commands.Info
+}
+
+// SetCommandCatalog enables GET /api/commands
+func (h *Handler) SetCommandCatalog(catalog CommandCatalog) {
+	h.commands = catalog
+}
+
+// handleListCommands handles GET /api/commands: every command available on this server
+// with its arguments and the role needed to run it, for client autocomplete
+func (h *Handler) handleListCommands(w http.ResponseWriter, r *http.Request) {
+	if h.commands == nil {
+		writeError(w, http.StatusServiceUnavailable, "command metadata is not available")
+		return
+	}
+
+	infos := h.commands.DescribeCommands(nil)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"commands": infos,
+		"total":    len(infos),
+	})
+}
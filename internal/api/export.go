@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"realtime-chat/internal/export"
+	messagePkg "realtime-chat/internal/message"
+)
+
+// defaultExportLimit is the number of messages exported when no limit is given
+const defaultExportLimit = 1000
+
+// maxExportMessages limits how many messages a single export may contain
+const maxExportMessages = 10000
+
+// Transcript represents an exported room history
+type Transcript struct {
+	Room       string                `json:"room"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Count      int                   `json:"count"`
+	Messages   []*messagePkg.Message `json:"messages"`
+}
+
+// handleRoomExport handles GET /api/rooms/{room}/export?limit=
+// ถ้าเจ้าของห้องตั้ง export key ไว้ จะได้ไฟล์ที่เข้ารหัสแบบ OpenPGP (armored) แทน JSON
+func (h *Handler) handleRoomExport(w http.ResponseWriter, r *http.Request) {
+	if h.messageRepo == nil {
+		writeError(w, http.StatusServiceUnavailable, "message persistence is disabled")
+		return
+	}
+
+	roomName := r.PathValue("room")
+	room, ok := h.readableRoom(w, r, roomName)
+	if !ok {
+		return
+	}
+
+	limit := defaultExportLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxExportMessages {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxExportMessages))
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := h.messageRepo.GetMessageHistory(roomName, limit)
+	if err != nil {
+		slog.Error("❌ Failed to export room", "room", roomName, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load room history")
+		return
+	}
+	if messages == nil {
+		messages = []*messagePkg.Message{}
+	}
+
+	transcript := Transcript{
+		Room:       roomName,
+		ExportedAt: time.Now().UTC(),
+		Count:      len(messages),
+		Messages:   messages,
+	}
+
+	if room.ExportKey == "" {
+		writeJSON(w, http.StatusOK, transcript)
+		return
+	}
+
+	plaintext, err := json.Marshal(transcript)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode transcript")
+		return
+	}
+
+	filename := roomName + "-transcript.json"
+	ciphertext, err := export.EncryptArmored(plaintext, room.ExportKey, filename)
+	if err != nil {
+		slog.Error("❌ Failed to encrypt room export", "room", roomName, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to encrypt transcript")
+		return
+	}
+
+	slog.Info("🔐 Room exported", "room", roomName, "messages", len(messages), "encrypted", true)
+	w.Header().Set("Content-Type", "application/pgp-encrypted")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s.asc", url.PathEscape(filename)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(ciphertext)
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/attachment"
+	"realtime-chat/internal/changefeed"
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// Handler serves the HTTP JSON API alongside the WebSocket endpoint
+type Handler struct {
+	roomService         room.Service
+	userService         userPkg.Service
+	messageRepo         messagePkg.Repository
+	delivery            DeliveryReporter
+	changes             ChangeReporter
+	frames              FrameReporter
+	backpressure        BackpressureReporter
+	batching            BatchReporter
+	compression         CompressionReporter
+	churn               *security.ChurnLimiter
+	userCleaner         *userPkg.Cleaner
+	retention           *messagePkg.Janitor
+	announcements       *announcement.Store
+	apiKeys             []string
+	validator           *security.InputValidator
+	presence            *userPkg.PresenceStore
+	presenceNotifier    PresenceNotifier
+	maxPresenceDuration time.Duration
+	metrics             *config.ServerMetrics
+	timeline            messagePkg.TimelineRepository
+	uploader            *attachment.Uploader
+	attachmentPoster    AttachmentPoster
+	connections         ConnectionAdmin
+	announcer           Announcer
+	configUpdater       ConfigUpdater
+	commands            CommandCatalog
+	regions             RegionReporter
+	accounts            *account.Service
+	throttle            *security.ConnectionThrottle
+	lockout             *security.LoginLockout
+	loginAlerter        LoginAlerter
+	auditLog            *moderation.AuditLog
+	proxies             *security.ProxyPolicy // nil = ใช้ RemoteAddr ตรงๆ
+}
+
+// DeliveryReporter provides broadcast delivery latency stats
+type DeliveryReporter interface {
+	DeliveryStats() []wsocket.DeliveryStats
+}
+
+// FrameReporter provides oversized outbound payload counters
+type FrameReporter interface {
+	FrameStats() wsocket.FrameStats
+}
+
+// BackpressureReporter provides slow consumer policy and per-connection drop counters
+type BackpressureReporter interface {
+	BackpressureStats() wsocket.BackpressureStats
+}
+
+// BatchReporter provides outbound batching counters
+type BatchReporter interface {
+	BatchStats() wsocket.BatchStats
+}
+
+// CompressionReporter provides permessage-deflate counters
+type CompressionReporter interface {
+	CompressionStats() wsocket.CompressionStats
+}
+
+// ChangeReporter provides counters aggregated from the database change feed
+type ChangeReporter interface {
+	Snapshot() changefeed.CounterSnapshot
+}
+
+// ErrorResponse represents an API error payload
+type ErrorResponse struct {
+	Error   string                 `json:"error"`
+	Code    string                 `json:"code,omitempty"` // เช่น username_too_long (มีเฉพาะ validation error)
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// NewHandler creates a new API handler
+func NewHandler(roomService room.Service, userService userPkg.Service) *Handler {
+	return &Handler{
+		roomService: roomService,
+		userService: userService,
+	}
+}
+
+// SetMessageRepository sets the message repository (nil when persistence is disabled)
+func (h *Handler) SetMessageRepository(repo messagePkg.Repository) {
+	h.messageRepo = repo
+}
+
+// SetDeliveryReporter sets the source of delivery SLO metrics
+func (h *Handler) SetDeliveryReporter(reporter DeliveryReporter) {
+	h.delivery = reporter
+}
+
+// SetFrameReporter sets the source of outbound frame size metrics
+func (h *Handler) SetFrameReporter(reporter FrameReporter) {
+	h.frames = reporter
+}
+
+// SetBackpressureReporter sets the source of slow consumer metrics
+func (h *Handler) SetBackpressureReporter(reporter BackpressureReporter) {
+	h.backpressure = reporter
+}
+
+// SetBatchReporter sets the source of outbound batching metrics
+func (h *Handler) SetBatchReporter(reporter BatchReporter) {
+	h.batching = reporter
+}
+
+// SetCompressionReporter sets the source of compression metrics
+func (h *Handler) SetCompressionReporter(reporter CompressionReporter) {
+	h.compression = reporter
+}
+
+// SetChurnLimiter sets the room switch limiter whose counters are exposed as metrics
+func (h *Handler) SetChurnLimiter(limiter *security.ChurnLimiter) {
+	h.churn = limiter
+}
+
+// SetUserCleaner sets the stale user cleaner whose queue and dead letters are exposed as metrics
+func (h *Handler) SetUserCleaner(cleaner *userPkg.Cleaner) {
+	h.userCleaner = cleaner
+}
+
+// SetRetentionJanitor sets the message retention janitor whose purge counters are exposed as metrics
+func (h *Handler) SetRetentionJanitor(janitor *messagePkg.Janitor) {
+	h.retention = janitor
+}
+
+// SetChangeReporter sets the source of change feed counters
+func (h *Handler) SetChangeReporter(reporter ChangeReporter) {
+	h.changes = reporter
+}
+
+// SetAnnouncements sets the announcement store used for compliance reports
+func (h *Handler) SetAnnouncements(store *announcement.Store) {
+	h.announcements = store
+}
+
+// RegisterRoutes registers API routes on the given mux
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/commands", h.handleListCommands)
+	mux.HandleFunc("GET /api/regions", h.handleRegions)
+	mux.HandleFunc("GET /api/rooms", h.handleListRooms)
+	mux.HandleFunc("POST /api/rooms", h.handleCreateRoom)
+	mux.HandleFunc("DELETE /api/rooms/{room}", h.handleDeleteRoom)
+	mux.HandleFunc("GET /api/rooms/{room}/users", h.handleRoomUsers)
+	mux.HandleFunc("GET /api/rooms/{room}/messages", h.handleRoomMessages)
+	mux.HandleFunc("GET /api/rooms/{room}/activity", h.handleRoomActivity)
+	mux.HandleFunc("GET /api/rooms/{room}/timeline", h.handleRoomTimeline)
+	mux.HandleFunc("GET /api/rooms/{room}/export", h.handleRoomExport)
+	mux.HandleFunc("GET /api/metrics/delivery", h.handleDeliveryMetrics)
+	mux.HandleFunc("GET /api/metrics/changes", h.handleChangeMetrics)
+	mux.HandleFunc("GET /api/metrics/frames", h.handleFrameMetrics)
+	mux.HandleFunc("GET /api/metrics/backpressure", h.handleBackpressureMetrics)
+	mux.HandleFunc("GET /api/metrics/batching", h.handleBatchMetrics)
+	mux.HandleFunc("GET /api/metrics/compression", h.handleCompressionMetrics)
+	mux.HandleFunc("GET /api/metrics/churn", h.handleChurnMetrics)
+	mux.HandleFunc("GET /api/metrics/user-cleanup", h.handleUserCleanupMetrics)
+	mux.HandleFunc("GET /api/metrics/retention", h.handleRetentionMetrics)
+	mux.HandleFunc("GET /metrics", h.handlePrometheusMetrics)
+	mux.HandleFunc("GET /api/announcements", h.handleAnnouncements)
+	mux.HandleFunc("GET /api/announcements/{id}", h.handleAnnouncementReport)
+	mux.HandleFunc("POST /api/register", h.handleRegister)
+	mux.HandleFunc("POST /api/login", h.handleLogin)
+	mux.HandleFunc("POST /api/logout", h.handleLogout)
+	mux.HandleFunc("GET /api/sessions", h.handleListSessions)
+	mux.HandleFunc("DELETE /api/sessions/{id}", h.handleRevokeSession)
+	mux.HandleFunc("PATCH /api/users/{username}/presence", h.handlePatchPresence)
+	mux.HandleFunc("DELETE /api/users/{username}/presence", h.handleDeletePresence)
+	mux.HandleFunc("POST /api/upload", h.handleUpload)
+	mux.HandleFunc("GET /admin/connections", h.handleAdminConnections)
+	mux.HandleFunc("DELETE /admin/connections/{id}", h.handleAdminDisconnect)
+	mux.HandleFunc("POST /admin/announcements", h.handleAdminAnnounce)
+	mux.HandleFunc("GET /admin/config", h.handleAdminConfig)
+	mux.HandleFunc("PATCH /admin/config", h.handleAdminUpdateConfig)
+}
+
+// handleDeliveryMetrics handles GET /api/metrics/delivery
+func (h *Handler) handleDeliveryMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.delivery == nil {
+		writeError(w, http.StatusServiceUnavailable, "delivery sampling is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rooms": h.delivery.DeliveryStats(),
+	})
+}
+
+// handleChangeMetrics handles GET /api/metrics/changes
+func (h *Handler) handleChangeMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.changes == nil {
+		writeError(w, http.StatusServiceUnavailable, "change feed is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.changes.Snapshot())
+}
+
+// handleFrameMetrics handles GET /api/metrics/frames
+func (h *Handler) handleFrameMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.frames == nil {
+		writeError(w, http.StatusServiceUnavailable, "frame metrics are unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.frames.FrameStats())
+}
+
+// handleBackpressureMetrics handles GET /api/metrics/backpressure
+func (h *Handler) handleBackpressureMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.backpressure == nil {
+		writeError(w, http.StatusServiceUnavailable, "backpressure metrics are unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.backpressure.BackpressureStats())
+}
+
+// handleBatchMetrics handles GET /api/metrics/batching
+func (h *Handler) handleBatchMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.batching == nil {
+		writeError(w, http.StatusServiceUnavailable, "batching metrics are unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.batching.BatchStats())
+}
+
+// handleCompressionMetrics handles GET /api/metrics/compression
+func (h *Handler) handleCompressionMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.compression == nil {
+		writeError(w, http.StatusServiceUnavailable, "compression metrics are unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.compression.CompressionStats())
+}
+
+// handleChurnMetrics handles GET /api/metrics/churn
+func (h *Handler) handleChurnMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.churn == nil {
+		writeError(w, http.StatusServiceUnavailable, "room switch limiting is disabled")
+		return
+	}
+	limit, window := h.churn.Limit()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"limit":          limit,
+		"window_seconds": int(window.Seconds()),
+		"stats":          h.churn.Stats(),
+	})
+}
+
+// handleUserCleanupMetrics handles GET /api/metrics/user-cleanup
+func (h *Handler) handleUserCleanupMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.userCleaner == nil {
+		writeError(w, http.StatusServiceUnavailable, "stale user cleanup runs only in MongoDB mode")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.userCleaner.Stats())
+}
+
+// handleRetentionMetrics handles GET /api/metrics/retention
+func (h *Handler) handleRetentionMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.retention == nil {
+		writeError(w, http.StatusServiceUnavailable, "message retention runs only in MongoDB mode")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.retention.Stats())
+}
+
+// handleAnnouncements handles GET /api/announcements
+func (h *Handler) handleAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if h.announcements == nil {
+		writeError(w, http.StatusServiceUnavailable, "announcements are disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"announcements": h.announcements.Reports(),
+	})
+}
+
+// handleAnnouncementReport handles GET /api/announcements/{id}
+func (h *Handler) handleAnnouncementReport(w http.ResponseWriter, r *http.Request) {
+	if h.announcements == nil {
+		writeError(w, http.StatusServiceUnavailable, "announcements are disabled")
+		return
+	}
+	report, exists := h.announcements.Report(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "announcement not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.Error("❌ Failed to write API response", "error", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
+
+// writeValidationError writes a 400 response, with the code and params when err is a security.ValidationError
+func writeValidationError(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	if verr, ok := security.AsValidationError(err); ok {
+		resp.Code = verr.Code
+		resp.Details = verr.Params
+	}
+	writeJSON(w, http.StatusBadRequest, resp)
+}
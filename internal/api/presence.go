@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// maxPresenceMessageLength limits the free-text part of a presence status
+const maxPresenceMessageLength = 100
+
+// PresenceNotifier is told when a user's externally-set presence changes
+type PresenceNotifier interface {
+	PresenceChanged(username string)
+}
+
+// PresenceRequest is the body of PATCH /api/users/{username}/presence.
+// ระบุ until (RFC3339) หรือ expires_in (เช่น "45m") อย่างใดอย่างหนึ่ง
+type PresenceRequest struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	Source    string    `json:"source"`
+	Until     time.Time `json:"until"`
+	ExpiresIn string    `json:"expires_in"`
+}
+
+// SetPresence enables the presence integration endpoints
+func (h *Handler) SetPresence(store *userPkg.PresenceStore, notifier PresenceNotifier, maxDuration time.Duration) {
+	h.presence = store
+	h.presenceNotifier = notifier
+	h.maxPresenceDuration = maxDuration
+}
+
+// handlePatchPresence handles PATCH /api/users/{username}/presence
+func (h *Handler) handlePatchPresence(w http.ResponseWriter, r *http.Request) {
+	if h.presence == nil {
+		writeError(w, http.StatusServiceUnavailable, "presence integration is disabled")
+		return
+	}
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+
+	var req PresenceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	presence, err := h.buildPresence(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	username := r.PathValue("username")
+	h.presence.Set(username, presence)
+	slog.Info("🗓️ Presence scheduled", "username", username, "status", presence.Status,
+		"until", presence.Until.Format(time.RFC3339), "source", presence.Source)
+
+	if h.presenceNotifier != nil {
+		h.presenceNotifier.PresenceChanged(username)
+	}
+
+	current, _ := h.presence.Get(username)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"username": username,
+		"presence": current,
+	})
+}
+
+// handleDeletePresence handles DELETE /api/users/{username}/presence
+func (h *Handler) handleDeletePresence(w http.ResponseWriter, r *http.Request) {
+	if h.presence == nil {
+		writeError(w, http.StatusServiceUnavailable, "presence integration is disabled")
+		return
+	}
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+
+	username := r.PathValue("username")
+	if !h.presence.Clear(username) {
+		writeError(w, http.StatusNotFound, "no presence set for user")
+		return
+	}
+	if h.presenceNotifier != nil {
+		h.presenceNotifier.PresenceChanged(username)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildPresence validates a presence request and resolves its expiry
+func (h *Handler) buildPresence(req PresenceRequest) (userPkg.Presence, error) {
+	if !userPkg.PresenceStatuses[req.Status] {
+		return userPkg.Presence{}, fmt.Errorf("invalid status '%s' (expected available, busy, away, dnd or ooo)", req.Status)
+	}
+	if utf8.RuneCountInString(req.Message) > maxPresenceMessageLength {
+		return userPkg.Presence{}, fmt.Errorf("message is too long (max %d characters)", maxPresenceMessageLength)
+	}
+
+	now := time.Now()
+	until := req.Until
+	if req.ExpiresIn != "" {
+		if !until.IsZero() {
+			return userPkg.Presence{}, fmt.Errorf("specify either 'until' or 'expires_in', not both")
+		}
+		duration, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || duration <= 0 {
+			return userPkg.Presence{}, fmt.Errorf("invalid 'expires_in' (e.g. 45m)")
+		}
+		until = now.Add(duration)
+	}
+	if until.IsZero() {
+		return userPkg.Presence{}, fmt.Errorf("presence requires 'until' or 'expires_in'")
+	}
+	if !until.After(now) {
+		return userPkg.Presence{}, fmt.Errorf("'until' must be in the future")
+	}
+	if h.maxPresenceDuration > 0 && until.Sub(now) > h.maxPresenceDuration {
+		return userPkg.Presence{}, fmt.Errorf("presence cannot last longer than %v", h.maxPresenceDuration)
+	}
+
+	return userPkg.Presence{
+		Status:  req.Status,
+		Message: req.Message,
+		Source:  req.Source,
+		Until:   until,
+	}, nil
+}
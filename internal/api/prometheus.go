@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// SetServerMetrics enables GET /metrics in Prometheus text exposition format
+func (h *Handler) SetServerMetrics(metrics *config.ServerMetrics) {
+	h.metrics = metrics
+}
+
+// promWriter writes metrics in the Prometheus text format (version 0.0.4)
+type promWriter struct {
+	b strings.Builder
+}
+
+// metric writes the HELP/TYPE header and a single unlabelled sample
+func (p *promWriter) metric(name, kind, help string, value float64) {
+	p.header(name, kind, help)
+	p.sample(name, "", value)
+}
+
+// header writes the HELP and TYPE lines of a metric family
+func (p *promWriter) header(name, kind, help string) {
+	fmt.Fprintf(&p.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample; labels is a preformatted label set such as room="general"
+func (p *promWriter) sample(name, labels string, value float64) {
+	if labels != "" {
+		fmt.Fprintf(&p.b, "%s{%s} %g\n", name, labels, value)
+		return
+	}
+	fmt.Fprintf(&p.b, "%s %g\n", name, value)
+}
+
+// promLabelEscaper escapes label values as required by the exposition format
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// handlePrometheusMetrics handles GET /metrics
+func (h *Handler) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.metrics == nil {
+		writeError(w, http.StatusServiceUnavailable, "metrics are disabled")
+		return
+	}
+	m := h.metrics.GetMetrics()
+
+	var p promWriter
+	p.metric("chat_connections_total", "counter", "WebSocket connections accepted since start.", float64(m.TotalConnections))
+	p.metric("chat_connections_active", "gauge", "Currently open WebSocket connections.", float64(m.ActiveConnections))
+	p.metric("chat_messages_total", "counter", "Chat messages sent.", float64(m.TotalMessages))
+	p.metric("chat_commands_total", "counter", "Slash commands executed.", float64(m.TotalCommands))
+	p.metric("chat_rooms_created_total", "counter", "Rooms created.", float64(m.TotalRooms))
+	p.metric("chat_users", "gauge", "Users currently registered.", float64(m.TotalUsers))
+	p.metric("chat_rooms_resident", "gauge", "Rooms held in memory.", float64(m.ResidentRooms))
+	p.metric("chat_rooms_hibernated", "gauge", "Rooms hibernated to disk.", float64(m.HibernatedRooms))
+	p.metric("chat_reclaimed_entries_total", "counter", "Stale in-memory entries pruned by the reaper.", float64(m.ReclaimedEntries))
+	p.metric("chat_purged_messages_total", "counter", "Messages deleted or archived by the retention janitor.", float64(m.PurgedMessages))
+	p.metric("chat_message_rate", "gauge", "Average messages per second since start.", m.MessageRate)
+	p.metric("chat_connection_rate", "gauge", "Average connections per second since start.", m.ConnectionRate)
+	p.metric("chat_uptime_seconds", "gauge", "Seconds since the server started.", time.Since(m.StartTime).Seconds())
+	p.metric("chat_rejected_upgrades_total", "counter", "WebSocket upgrades rejected because the origin is not allowed.", float64(m.RejectedUpgrades))
+	p.metric("chat_quarantined_connections_total", "counter", "Connections quarantined for repeated protocol violations.", float64(m.QuarantinedConnections))
+	p.metric("chat_dropped_frames_total", "counter", "Outbound frames dropped because a client's send queue was full.", float64(m.DroppedFrames))
+	p.metric("chat_slow_consumer_disconnects_total", "counter", "Connections closed by the slow consumer policy.", float64(m.SlowConsumerDisconnects))
+	kinds := make([]string, 0, len(m.ProtocolViolations))
+	for kind := range m.ProtocolViolations {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	p.header("chat_protocol_violations_total", "counter", "Protocol violations by kind.")
+	for _, kind := range kinds {
+		p.sample("chat_protocol_violations_total", fmt.Sprintf(`kind="%s"`, promLabelEscaper.Replace(kind)), float64(m.ProtocolViolations[kind]))
+	}
+	if !m.LastMessageTime.IsZero() {
+		p.metric("chat_last_message_timestamp_seconds", "gauge", "Unix time of the last chat message.", float64(m.LastMessageTime.Unix()))
+	}
+
+	// per-room gauges; ห้อง private ไม่เก็บสถิติ จึงไม่แสดงแยกรายห้อง
+	rooms := h.roomService.GetRooms()
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	p.metric("chat_rooms", "gauge", "Rooms currently open.", float64(len(rooms)))
+	p.header("chat_room_users", "gauge", "Users in a room.")
+	for _, room := range rooms {
+		if !room.Private {
+			p.sample("chat_room_users", fmt.Sprintf(`room="%s"`, promLabelEscaper.Replace(room.Name)), float64(len(room.Users)))
+		}
+	}
+	p.header("chat_room_capacity", "gauge", "Maximum users allowed in a room.")
+	for _, room := range rooms {
+		if !room.Private {
+			p.sample("chat_room_capacity", fmt.Sprintf(`room="%s"`, promLabelEscaper.Replace(room.Name)), float64(room.MaxUsers))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(p.b.String()))
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"realtime-chat/internal/cluster"
+)
+
+// RegionReporter provides the health and latency of each region the server runs in
+type RegionReporter interface {
+	Regions() []cluster.RegionStatus
+}
+
+// SetRegionReporter enables GET /api/regions
+func (h *Handler) SetRegionReporter(reporter RegionReporter) {
+	h.regions = reporter
+}
+
+// handleRegions handles GET /api/regions: per-region health, latency and public endpoints,
+// so clients can connect to the nearest region. ไม่ต้องใช้ API key เพราะ client เรียกก่อน join
+func (h *Handler) handleRegions(w http.ResponseWriter, r *http.Request) {
+	if h.regions == nil {
+		writeError(w, http.StatusServiceUnavailable, "region information is not available")
+		return
+	}
+
+	regions := h.regions.Regions()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"regions": regions,
+		"total":   len(regions),
+	})
+}
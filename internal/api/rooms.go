@@ -0,0 +1,251 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+)
+
+// maxMessagesPageSize limits how many messages GET /api/rooms/{room}/messages returns
+const maxMessagesPageSize = 100
+
+// RoomSummary represents a room in API responses
+type RoomSummary struct {
+	Name              string     `json:"name"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Users             int        `json:"users"`
+	MaxUsers          int        `json:"max_users"`
+	Private           bool       `json:"private"`
+	PasswordProtected bool       `json:"password_protected"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// RoomUser represents a room member in API responses
+type RoomUser struct {
+	Username   string            `json:"username"`
+	JoinedAt   time.Time         `json:"joined_at"`
+	LastActive time.Time         `json:"last_active"`
+	Presence   *userPkg.Presence `json:"presence,omitempty"`
+}
+
+// CreateRoomRequest is the body of POST /api/rooms
+type CreateRoomRequest struct {
+	Name      string `json:"name"`
+	CreatedBy string `json:"created_by"` // ค่าเริ่มต้น "api"
+	MaxUsers  int    `json:"max_users"`
+	TTL       string `json:"ttl"` // เช่น "2h" สำหรับห้องชั่วคราว
+}
+
+// SetValidator sets the input validator used for room names sent to the API
+func (h *Handler) SetValidator(validator *security.InputValidator) {
+	h.validator = validator
+}
+
+// handleListRooms handles GET /api/rooms
+func (h *Handler) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	rooms := h.roomService.GetRooms()
+	summaries := make([]RoomSummary, 0, len(rooms))
+	for _, chatRoom := range rooms {
+		// ห้อง invite-only ไม่แสดงใน API สาธารณะ
+		if chatRoom.IsPrivate {
+			continue
+		}
+		summaries = append(summaries, summarizeRoom(chatRoom, len(h.roomService.GetUsersInRoom(chatRoom.Name))))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rooms": summaries,
+		"total": len(summaries),
+	})
+}
+
+// readableRoom looks up a room whose content a request reads and writes an error when the caller may not read it.
+// ห้อง invite-only/มีรหัสผ่านต้องส่ง Bearer session token ของสมาชิกมา; ห้อง invite-only ตอบ 404 กับคนอื่น
+// เพื่อไม่ให้รู้ว่ามีห้องนี้ ส่วนห้องที่มีรหัสผ่านตอบ 403
+func (h *Handler) readableRoom(w http.ResponseWriter, r *http.Request, roomName string) (*room.Room, bool) {
+	chatRoom, exists := h.roomService.GetRoom(roomName)
+	if !exists {
+		writeError(w, http.StatusNotFound, "room not found")
+		return nil, false
+	}
+	if !chatRoom.Restricted() {
+		return chatRoom, true
+	}
+
+	if h.accounts != nil {
+		if token := bearerToken(r); token != "" {
+			if session, err := h.accounts.Authenticate(token); err == nil && h.roomService.IsMember(roomName, session.Username) {
+				return chatRoom, true
+			}
+		}
+	}
+
+	if chatRoom.IsPrivate {
+		writeError(w, http.StatusNotFound, "room not found")
+	} else {
+		writeError(w, http.StatusForbidden, "room is password-protected; members only")
+	}
+	return nil, false
+}
+
+// handleRoomUsers handles GET /api/rooms/{room}/users
+func (h *Handler) handleRoomUsers(w http.ResponseWriter, r *http.Request) {
+	roomName := r.PathValue("room")
+	if _, ok := h.readableRoom(w, r, roomName); !ok {
+		return
+	}
+
+	members := h.roomService.GetUsersInRoom(roomName)
+	users := make([]RoomUser, 0, len(members))
+	for _, member := range members {
+		user := RoomUser{
+			Username:   member.Username,
+			JoinedAt:   member.JoinedAt,
+			LastActive: member.LastActive,
+		}
+		if h.presence != nil {
+			user.Presence, _ = h.presence.Get(member.Username)
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Username) < strings.ToLower(users[j].Username)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"room":  roomName,
+		"users": users,
+		"total": len(users),
+	})
+}
+
+// handleRoomMessages handles GET /api/rooms/{room}/messages?limit=
+func (h *Handler) handleRoomMessages(w http.ResponseWriter, r *http.Request) {
+	if h.messageRepo == nil {
+		writeError(w, http.StatusServiceUnavailable, "message persistence is disabled")
+		return
+	}
+
+	roomName := r.PathValue("room")
+	if _, ok := h.readableRoom(w, r, roomName); !ok {
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxMessagesPageSize {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := h.messageRepo.GetMessageHistory(roomName, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load messages")
+		return
+	}
+	if messages == nil {
+		messages = []*messagePkg.Message{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"room":     roomName,
+		"messages": messages,
+		"total":    len(messages),
+	})
+}
+
+// handleCreateRoom handles POST /api/rooms (requires an API key)
+func (h *Handler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+	if h.validator == nil {
+		writeError(w, http.StatusServiceUnavailable, "room creation is disabled")
+		return
+	}
+
+	var req CreateRoomRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	roomName, err := h.validator.ValidateRoomName(req.Name)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if _, exists := h.roomService.GetRoom(roomName); exists {
+		writeError(w, http.StatusConflict, "room already exists")
+		return
+	}
+
+	opts := room.CreateOptions{MaxUsers: req.MaxUsers}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid 'ttl' (e.g. 2h)")
+			return
+		}
+		opts.TTL = ttl
+	}
+
+	createdBy := req.CreatedBy
+	if createdBy == "" {
+		createdBy = "api"
+	}
+
+	created, err := h.roomService.CreateRoomWithOptions(roomName, createdBy, opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, summarizeRoom(created, 0))
+}
+
+// handleDeleteRoom handles DELETE /api/rooms/{room} (requires an API key)
+func (h *Handler) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+
+	roomName := r.PathValue("room")
+	if _, exists := h.roomService.GetRoom(roomName); !exists {
+		writeError(w, http.StatusNotFound, "room not found")
+		return
+	}
+
+	if err := h.roomService.ArchiveRoom(roomName); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// summarizeRoom converts a room to its API representation
+func summarizeRoom(chatRoom *room.Room, users int) RoomSummary {
+	return RoomSummary{
+		Name:              chatRoom.Name,
+		CreatedBy:         chatRoom.CreatedBy,
+		CreatedAt:         chatRoom.CreatedAt,
+		Users:             users,
+		MaxUsers:          chatRoom.MaxUsers,
+		Private:           chatRoom.Private,
+		PasswordProtected: chatRoom.PasswordHash != "",
+		ExpiresAt:         chatRoom.ExpiresAt,
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/storage/sqlite"
+	userPkg "realtime-chat/internal/user"
+)
+
+// ห้อง invite-only ตอบ 404 และห้องที่มีรหัสผ่านตอบ 403 กับทุก endpoint ที่อ่านเนื้อหาห้อง เว้นแต่มี session ของสมาชิก
+func TestRestrictedRoomReadsNeedMemberSession(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	metrics := config.NewServerMetrics()
+	roomService := room.NewService(store.Rooms(), cfg.MaxRooms, cfg.MaxUsersPerRoom, cfg.MaxRoomCapacity, metrics)
+	if _, err := roomService.CreateRoom("lobby", "alice"); err != nil {
+		t.Fatalf("create lobby: %v", err)
+	}
+	if _, err := roomService.CreateRoomWithOptions("secret", "alice", room.CreateOptions{InviteOnly: true}); err != nil {
+		t.Fatalf("create secret: %v", err)
+	}
+	if _, err := roomService.CreateRoomWithOptions("locked", "alice", room.CreateOptions{Password: "hunter22"}); err != nil {
+		t.Fatalf("create locked: %v", err)
+	}
+
+	accounts := account.NewService(store.Accounts(), cfg)
+	login := func(username string) string {
+		if _, err := accounts.Register(username, "correct horse battery"); err != nil {
+			t.Fatalf("register %s: %v", username, err)
+		}
+		_, token, err := accounts.Login(username, "correct horse battery", "test")
+		if err != nil {
+			t.Fatalf("login %s: %v", username, err)
+		}
+		return token
+	}
+	aliceToken, bobbyToken := login("alice"), login("bobby")
+
+	handler := NewHandler(roomService, userPkg.NewService(store.Users(), metrics))
+	handler.SetMessageRepository(store.Messages())
+	handler.SetAccounts(accounts)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, endpoint := range []string{"messages", "users", "export", "activity"} {
+		cases := []struct {
+			room  string
+			token string
+			want  int
+		}{
+			{"secret", "", http.StatusNotFound},
+			{"secret", bobbyToken, http.StatusNotFound},
+			{"secret", aliceToken, http.StatusOK},
+			{"locked", "", http.StatusForbidden},
+			{"locked", bobbyToken, http.StatusForbidden},
+			{"locked", aliceToken, http.StatusOK},
+			{"lobby", "", http.StatusOK},
+		}
+		for _, tc := range cases {
+			path := "/api/rooms/" + tc.room + "/" + endpoint
+			if got := get(path, tc.token); got != tc.want {
+				t.Errorf("GET %s (token=%v) = %d, want %d", path, tc.token != "", got, tc.want)
+			}
+		}
+	}
+}
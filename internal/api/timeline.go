@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// TimelineResponse represents a page of a room timeline
+type TimelineResponse struct {
+	Room      string                      `json:"room"`
+	Entries   []*messagePkg.TimelineEntry `json:"entries"`
+	Total     int                         `json:"total"`
+	NextAfter int64                       `json:"next_after,omitempty"` // ส่งเป็น after เพื่อดึงหน้าถัดไป
+}
+
+// SetTimelineRepository enables GET /api/rooms/{room}/timeline
+func (h *Handler) SetTimelineRepository(repo messagePkg.TimelineRepository) {
+	h.timeline = repo
+}
+
+// handleRoomTimeline handles GET /api/rooms/{room}/timeline?after=&before=&limit=
+func (h *Handler) handleRoomTimeline(w http.ResponseWriter, r *http.Request) {
+	if h.timeline == nil {
+		writeError(w, http.StatusServiceUnavailable, "room timeline is disabled")
+		return
+	}
+
+	roomName := r.PathValue("room")
+	chatRoom, ok := h.readableRoom(w, r, roomName)
+	if !ok {
+		return
+	}
+	if chatRoom.Private {
+		writeError(w, http.StatusForbidden, "room is in privacy mode; the timeline is not recorded")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxMessagesPageSize {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+
+	var after, before int64
+	for name, target := range map[string]*int64{"after": &after, "before": &before} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 0 {
+				writeError(w, http.StatusBadRequest, "'"+name+"' must be a sequence number")
+				return
+			}
+			*target = parsed
+		}
+	}
+	if after > 0 && before > 0 {
+		writeError(w, http.StatusBadRequest, "use either 'after' or 'before', not both")
+		return
+	}
+
+	entries, err := h.timeline.GetTimeline(roomName, after, before, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load timeline")
+		return
+	}
+
+	response := TimelineResponse{
+		Room:    roomName,
+		Entries: entries,
+		Total:   len(entries),
+	}
+	if len(entries) > 0 {
+		response.NextAfter = entries[len(entries)-1].Seq
+	}
+	writeJSON(w, http.StatusOK, response)
+}
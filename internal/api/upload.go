@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"realtime-chat/internal/attachment"
+	messagePkg "realtime-chat/internal/message"
+)
+
+// uploadFormMemory is how much of a multipart form is kept in memory; the rest spills to temp files
+const uploadFormMemory = 1 << 20
+
+// AttachmentPoster posts an uploaded attachment to a room as a chat message
+type AttachmentPoster interface {
+	PostAttachment(username, roomName, caption string, file messagePkg.MessageAttachment) (*messagePkg.Message, error)
+}
+
+// SetUploads enables POST /api/upload
+func (h *Handler) SetUploads(uploader *attachment.Uploader, poster AttachmentPoster) {
+	h.uploader = uploader
+	h.attachmentPoster = poster
+}
+
+// handleUpload handles POST /api/upload (requires an API key).
+// multipart form: file, room, username และ caption (ไม่บังคับ)
+func (h *Handler) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if h.uploader == nil || h.attachmentPoster == nil {
+		writeError(w, http.StatusServiceUnavailable, "uploads are disabled")
+		return
+	}
+	if !h.requireAPIKey(w, r) {
+		return
+	}
+
+	// เผื่อขนาดให้ส่วนหัวและ field อื่นของ multipart
+	r.Body = http.MaxBytesReader(w, r.Body, h.uploader.MaxSize()+uploadFormMemory)
+	if err := r.ParseMultipartForm(uploadFormMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	roomName := r.FormValue("room")
+	username := r.FormValue("username")
+	if roomName == "" || username == "" {
+		writeError(w, http.StatusBadRequest, "'room' and 'username' are required")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "missing 'file'")
+		return
+	}
+	defer file.Close()
+	if header.Size > h.uploader.MaxSize() {
+		writeError(w, http.StatusRequestEntityTooLarge, "file is too large")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, h.uploader.MaxSize()+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+
+	// ตรวจห้องก่อนเก็บไฟล์ เพื่อไม่ให้เหลือไฟล์ที่ไม่มีข้อความอ้างถึง
+	if _, exists := h.roomService.GetRoom(roomName); !exists {
+		writeError(w, http.StatusNotFound, "room not found")
+		return
+	}
+
+	stored, err := h.uploader.Upload(r.Context(), header.Filename, data)
+	switch {
+	case errors.Is(err, attachment.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, attachment.ErrTypeNotAllowed):
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case errors.Is(err, attachment.ErrEmptyFile):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		slog.Error("❌ Failed to store upload", "username", username, "error", err)
+		writeError(w, http.StatusBadGateway, "failed to store file")
+		return
+	}
+
+	message, err := h.attachmentPoster.PostAttachment(username, roomName, r.FormValue("caption"), *stored)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"attachment": stored,
+		"message":    message,
+	})
+}
@@ -0,0 +1,56 @@
+package attachment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage stores uploaded files and returns the URL clients download them from
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+	Name() string
+}
+
+// LocalStorage stores files on local disk; main เสิร์ฟ dir ที่ baseURL ผ่าน http.FileServer
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a local disk storage rooted at dir
+func NewLocalStorage(dir, baseURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory %s: %v", dir, err)
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &LocalStorage{dir: dir, baseURL: baseURL}, nil
+}
+
+// Name returns the storage name
+func (s *LocalStorage) Name() string {
+	return "local"
+}
+
+// Put writes data under key, creating parent directories as needed
+func (s *LocalStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", key, err)
+	}
+
+	// เขียนไฟล์ชั่วคราวแล้ว rename เพื่อไม่ให้ client โหลดไฟล์ที่เขียนไม่ครบ
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to store %s: %v", key, err)
+	}
+	return s.baseURL + key, nil
+}
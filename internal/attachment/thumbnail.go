@@ -0,0 +1,93 @@
+package attachment
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// maxThumbnailPixels skips thumbnails for images whose decoded size would use too much memory
+const maxThumbnailPixels = 40_000_000
+
+// thumbnail scales an image down to fit size×size pixels.
+// PNG/GIF ได้ thumbnail เป็น PNG (เก็บความโปร่งใส) ส่วน JPEG เป็น JPEG
+func thumbnail(data []byte, mimeType string, size int) ([]byte, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported image: %v", err)
+	}
+	if config.Width*config.Height > maxThumbnailPixels {
+		return nil, "", fmt.Errorf("image too large for a thumbnail (%dx%d)", config.Width, config.Height)
+	}
+
+	var src image.Image
+	switch mimeType {
+	case "image/png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return nil, "", fmt.Errorf("no thumbnail support for %s", mimeType)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	scaled := scaleDown(src, size)
+	var out bytes.Buffer
+	if mimeType == "image/jpeg" {
+		err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 80})
+		return out.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&out, scaled)
+	return out.Bytes(), "image/png", err
+}
+
+// scaleDown resizes src to fit within size×size by averaging the source pixels of each target pixel
+func scaleDown(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+
+	targetW, targetH := size, height*size/width
+	if height > width {
+		targetW, targetH = width*size/height, size
+	}
+	targetW, targetH = max(targetW, 1), max(targetH, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, targetW, targetH))
+	for y := 0; y < targetH; y++ {
+		y0 := bounds.Min.Y + y*height/targetH
+		y1 := max(bounds.Min.Y+(y+1)*height/targetH, y0+1)
+		for x := 0; x < targetW; x++ {
+			x0 := bounds.Min.X + x*width/targetW
+			x1 := max(bounds.Min.X+(x+1)*width/targetW, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// RGBA() คืนค่า premultiplied 16-bit ต้องแปลงกลับเป็น non-premultiplied 8-bit
+			offset := dst.PixOffset(x, y)
+			if a == 0 {
+				continue
+			}
+			dst.Pix[offset+0] = uint8(r * 0xff / a)
+			dst.Pix[offset+1] = uint8(g * 0xff / a)
+			dst.Pix[offset+2] = uint8(b * 0xff / a)
+			dst.Pix[offset+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,180 @@
+package attachment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// Validation errors; API คืน 413 สำหรับ ErrTooLarge และ 415 สำหรับ ErrTypeNotAllowed
+var (
+	ErrEmptyFile      = errors.New("file is empty")
+	ErrTooLarge       = errors.New("file is too large")
+	ErrTypeNotAllowed = errors.New("file type is not allowed")
+)
+
+// maxFileNameLength limits the original file name kept in the attachment
+const maxFileNameLength = 200
+
+// extensions maps sniffed MIME types to the extension of the stored object
+var extensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+	"application/zip": ".zip",
+}
+
+// Uploader validates uploaded files, stores them and builds message attachments
+type Uploader struct {
+	storage       Storage
+	maxSize       int64
+	allowedTypes  []string
+	thumbnailSize int
+}
+
+// NewUploader creates an uploader; allowedTypes รองรับ wildcard เช่น "image/*"
+func NewUploader(storage Storage, maxSize int64, allowedTypes []string, thumbnailSize int) *Uploader {
+	types := make([]string, 0, len(allowedTypes))
+	for _, allowed := range allowedTypes {
+		if allowed = strings.ToLower(strings.TrimSpace(allowed)); allowed != "" {
+			types = append(types, allowed)
+		}
+	}
+	return &Uploader{
+		storage:       storage,
+		maxSize:       maxSize,
+		allowedTypes:  types,
+		thumbnailSize: thumbnailSize,
+	}
+}
+
+// MaxSize returns the largest accepted file in bytes
+func (u *Uploader) MaxSize() int64 {
+	return u.maxSize
+}
+
+// StorageName returns the name of the configured storage
+func (u *Uploader) StorageName() string {
+	return u.storage.Name()
+}
+
+// Upload validates and stores a file, plus a thumbnail for images.
+// ชนิดไฟล์ตรวจจากเนื้อไฟล์ (sniff) ไม่เชื่อ Content-Type หรือนามสกุลที่ client ส่งมา
+func (u *Uploader) Upload(ctx context.Context, fileName string, data []byte) (*messagePkg.MessageAttachment, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if int64(len(data)) > u.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrTooLarge, len(data), u.maxSize)
+	}
+
+	mimeType := detectType(data)
+	if !u.allowed(mimeType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, mimeType)
+	}
+
+	id, err := newAttachmentID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := fmt.Sprintf("%s/%s%s", now.Format("2006/01"), id, extensions[mimeType])
+
+	url, err := u.storage.Put(ctx, key, mimeType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &messagePkg.MessageAttachment{
+		ID:         id,
+		FileName:   cleanFileName(fileName),
+		FileSize:   int64(len(data)),
+		FileType:   fileCategory(mimeType),
+		MimeType:   mimeType,
+		URL:        url,
+		UploadedAt: now,
+	}
+
+	// thumbnail ไม่สำเร็จไม่ทำให้การอัปโหลดล้มเหลว client แสดงไฟล์ต้นฉบับแทน
+	if attachment.FileType == "image" && u.thumbnailSize > 0 {
+		if thumb, thumbType, err := thumbnail(data, mimeType, u.thumbnailSize); err != nil {
+			slog.Warn("⚠️ No thumbnail for attachment", "attachment_id", id, "type", mimeType, "error", err)
+		} else if thumbURL, err := u.storage.Put(ctx, fmt.Sprintf("%s/%s_thumb%s", now.Format("2006/01"), id, extensions[thumbType]), thumbType, thumb); err != nil {
+			slog.Warn("⚠️ Failed to store thumbnail", "attachment_id", id, "error", err)
+		} else {
+			attachment.ThumbnailURL = &thumbURL
+		}
+	}
+
+	slog.Info("📎 Stored attachment", "attachment_id", id, "type", mimeType, "bytes", len(data), "storage", u.storage.Name())
+	return attachment, nil
+}
+
+// allowed reports whether a MIME type matches the allowlist
+func (u *Uploader) allowed(mimeType string) bool {
+	for _, allowed := range u.allowedTypes {
+		if allowed == mimeType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectType sniffs the MIME type of a file without parameters (e.g. "text/plain")
+func detectType(data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// fileCategory groups MIME types for clients: image, text or file
+func fileCategory(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "text/"):
+		return "text"
+	default:
+		return "file"
+	}
+}
+
+// cleanFileName keeps only the base name of a client-supplied file name
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || !utf8.ValidString(name) {
+		return "file"
+	}
+	if len(name) > maxFileNameLength {
+		name = name[:maxFileNameLength]
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+	}
+	return name
+}
+
+func newAttachmentID() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate attachment ID: %v", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
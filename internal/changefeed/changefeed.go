@@ -0,0 +1,123 @@
+package changefeed
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Operation types reported in change events
+const (
+	OpInsert  = "insert"
+	OpUpdate  = "update"
+	OpReplace = "replace"
+	OpDelete  = "delete"
+)
+
+// Event is a single change to a document in a watched collection
+type Event struct {
+	Collection string    `json:"collection"`
+	Operation  string    `json:"operation"`
+	DocumentID string    `json:"document_id"`
+	Document   bson.Raw  `json:"-"` // เอกสารฉบับล่าสุด (ว่างเมื่อเป็น delete)
+	Time       time.Time `json:"time"`
+}
+
+// Decode unmarshals the changed document into v
+func (e Event) Decode(v interface{}) error {
+	return bson.Unmarshal(e.Document, v)
+}
+
+// Handler consumes change events; called from the feed goroutine so it must not block for long
+type Handler func(event Event)
+
+// ChangeFeed delivers document changes in MongoDB collections to subscribers.
+// ใช้ change streams เมื่อ MongoDB เป็น replica set ไม่เช่นนั้นใช้การ poll แทน
+type ChangeFeed interface {
+	Subscribe(collection string, handler Handler)
+	Start()
+	Stop()
+	Mode() string
+}
+
+// New creates a change feed for the database, preferring change streams when the server supports them
+func New(db *database.MongoDB, pollInterval time.Duration) ChangeFeed {
+	if supportsChangeStreams(db) {
+		slog.Info("📡 Change feed started", "mode", "change_streams")
+		return newStreamFeed(db)
+	}
+	slog.Info("📡 Change feed started, change streams unavailable (standalone server)", "mode", "polling", "interval", pollInterval)
+	return newPollingFeed(db, pollInterval)
+}
+
+// supportsChangeStreams reports whether the server is a replica set member or mongos
+func supportsChangeStreams(db *database.MongoDB) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hello bson.M
+	if err := db.GetClient().Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		slog.Warn("⚠️ Failed to detect MongoDB topology", "error", err)
+		return false
+	}
+	if _, ok := hello["setName"]; ok {
+		return true
+	}
+	return hello["msg"] == "isdbgrid"
+}
+
+// dispatcher fans events out to the handlers subscribed to each collection
+type dispatcher struct {
+	handlers map[string][]Handler // collection -> handlers
+	mutex    sync.RWMutex
+}
+
+// newDispatcher creates a new dispatcher
+func newDispatcher() *dispatcher {
+	return &dispatcher{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers a handler for changes in a collection
+func (d *dispatcher) Subscribe(collection string, handler Handler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.handlers[collection] = append(d.handlers[collection], handler)
+}
+
+// collections returns the names of collections with at least one subscriber
+func (d *dispatcher) collections() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	names := make([]string, 0, len(d.handlers))
+	for name := range d.handlers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// dispatch delivers an event to every handler of its collection
+func (d *dispatcher) dispatch(event Event) {
+	d.mutex.RLock()
+	handlers := d.handlers[event.Collection]
+	d.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		d.safeCall(handler, event)
+	}
+}
+
+// safeCall runs a handler, recovering from panics so one consumer can't stop the feed
+func (d *dispatcher) safeCall(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("❌ Change feed handler panicked", "collection", event.Collection, "operation", event.Operation, "panic", r)
+		}
+	}()
+	handler(event)
+}
@@ -0,0 +1,91 @@
+package changefeed
+
+import (
+	"sync"
+	"time"
+)
+
+// CounterSnapshot holds change counts observed by the feed since startup
+type CounterSnapshot struct {
+	Mode            string                      `json:"mode"`
+	Since           time.Time                   `json:"since"`
+	Operations      map[string]map[string]int64 `json:"operations"`        // collection -> operation -> count
+	MessagesPerRoom map[string]int64            `json:"messages_per_room"` // ข้อความใหม่ต่อห้อง จากทุก node
+	LastEvent       *time.Time                  `json:"last_event,omitempty"`
+}
+
+// Counters aggregates change events into analytics counters.
+// นับจาก change feed จึงรวมการเปลี่ยนแปลงจากทุก node ที่ใช้ database เดียวกัน
+type Counters struct {
+	mode            string
+	since           time.Time
+	operations      map[string]map[string]int64
+	messagesPerRoom map[string]int64
+	lastEvent       time.Time
+	mutex           sync.Mutex
+}
+
+// NewCounters creates counters and subscribes them to the given collections of the feed
+func NewCounters(feed ChangeFeed, collections ...string) *Counters {
+	c := &Counters{
+		mode:            feed.Mode(),
+		since:           time.Now(),
+		operations:      make(map[string]map[string]int64),
+		messagesPerRoom: make(map[string]int64),
+	}
+	for _, collection := range collections {
+		feed.Subscribe(collection, c.Record)
+	}
+	return c
+}
+
+// Record counts a change event
+func (c *Counters) Record(event Event) {
+	roomName := ""
+	if event.Collection == "messages" && event.Operation == OpInsert && event.Document != nil {
+		roomName, _ = event.Document.Lookup("room_name").StringValueOK()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ops, exists := c.operations[event.Collection]
+	if !exists {
+		ops = make(map[string]int64)
+		c.operations[event.Collection] = ops
+	}
+	ops[event.Operation]++
+
+	if roomName != "" {
+		c.messagesPerRoom[roomName]++
+	}
+	c.lastEvent = time.Now()
+}
+
+// Snapshot returns a copy of the counters
+func (c *Counters) Snapshot() CounterSnapshot {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	snapshot := CounterSnapshot{
+		Mode:            c.mode,
+		Since:           c.since,
+		Operations:      make(map[string]map[string]int64, len(c.operations)),
+		MessagesPerRoom: make(map[string]int64, len(c.messagesPerRoom)),
+	}
+	for collection, ops := range c.operations {
+		copied := make(map[string]int64, len(ops))
+		for op, count := range ops {
+			copied[op] = count
+		}
+		snapshot.Operations[collection] = copied
+	}
+	for roomName, count := range c.messagesPerRoom {
+		snapshot.MessagesPerRoom[roomName] = count
+	}
+	if !c.lastEvent.IsZero() {
+		lastEvent := c.lastEvent
+		snapshot.LastEvent = &lastEvent
+	}
+	return snapshot
+}
@@ -0,0 +1,177 @@
+package changefeed
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pollBatchSize caps how many changed documents are read per collection per poll
+const pollBatchSize = 500
+
+// pollTimeFields maps collections to the timestamp used to detect changes.
+// collection ที่ไม่อยู่ในนี้ใช้ updated_at
+var pollTimeFields = map[string]string{
+	"messages": "created_at", // การแก้ไข/ลบข้อความ handler broadcast เอง จึงดูเฉพาะที่สร้างใหม่
+}
+
+// pollingFeed emulates a change feed on standalone servers by polling timestamps.
+// ตรวจจับการลบไม่ได้ และแยก insert/update ได้จาก created_at == updated_at เท่านั้น
+type pollingFeed struct {
+	*dispatcher
+	db       *database.MongoDB
+	interval time.Duration
+	lastSeen map[string]time.Time // collection -> newest timestamp dispatched
+	stop     chan struct{}
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+// newPollingFeed creates a polling-backed feed
+func newPollingFeed(db *database.MongoDB, interval time.Duration) *pollingFeed {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &pollingFeed{
+		dispatcher: newDispatcher(),
+		db:         db,
+		interval:   interval,
+		lastSeen:   make(map[string]time.Time),
+	}
+}
+
+// Mode returns the feed implementation name
+func (f *pollingFeed) Mode() string {
+	return "polling"
+}
+
+// Start begins polling subscribed collections; only changes made after Start are reported
+func (f *pollingFeed) Start() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.stop != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, collection := range f.collections() {
+		f.lastSeen[collection] = now
+	}
+
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	go f.run(f.stop, f.done)
+}
+
+// Stop stops polling and waits for the poller to exit
+func (f *pollingFeed) Stop() {
+	f.mutex.Lock()
+	stop, done := f.stop, f.done
+	f.stop = nil
+	f.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// run polls every interval until stopped
+func (f *pollingFeed) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, collection := range f.collections() {
+				if err := f.poll(collection); err != nil {
+					slog.Warn("⚠️ Change feed poll failed", "collection", collection, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// poll dispatches documents in a collection changed since the last poll
+func (f *pollingFeed) poll(collection string) error {
+	field, exists := pollTimeFields[collection]
+	if !exists {
+		field = "updated_at"
+	}
+
+	f.mutex.Lock()
+	since, exists := f.lastSeen[collection]
+	if !exists {
+		since = time.Now()
+		f.lastSeen[collection] = since
+	}
+	f.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.interval)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: 1}}).
+		SetLimit(pollBatchSize)
+	cursor, err := f.db.GetCollection(collection).Find(ctx, bson.M{field: bson.M{"$gt": since}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	newest := since
+	for cursor.Next(ctx) {
+		doc := make(bson.Raw, len(cursor.Current))
+		copy(doc, cursor.Current)
+
+		changedAt, ok := doc.Lookup(field).TimeOK()
+		if !ok {
+			continue
+		}
+		if changedAt.After(newest) {
+			newest = changedAt
+		}
+
+		operation := OpUpdate
+		if field == "created_at" || doc.Lookup("created_at").Equal(doc.Lookup("updated_at")) {
+			operation = OpInsert
+		}
+
+		f.dispatch(Event{
+			Collection: collection,
+			Operation:  operation,
+			DocumentID: documentID(rawID(doc)),
+			Document:   doc,
+			Time:       changedAt,
+		})
+	}
+
+	f.mutex.Lock()
+	f.lastSeen[collection] = newest
+	f.mutex.Unlock()
+
+	return cursor.Err()
+}
+
+// rawID extracts the _id of a raw document
+func rawID(doc bson.Raw) interface{} {
+	value := doc.Lookup("_id")
+	if oid, ok := value.ObjectIDOK(); ok {
+		return oid
+	}
+	if s, ok := value.StringValueOK(); ok {
+		return s
+	}
+	return value.String()
+}
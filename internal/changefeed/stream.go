@@ -0,0 +1,151 @@
+package changefeed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// streamRetryDelay is how long to wait before reopening a failed change stream
+const streamRetryDelay = 5 * time.Second
+
+// changeDocument is the subset of a change stream event we use
+type changeDocument struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID interface{} `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument bson.Raw            `bson:"fullDocument"`
+	ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+}
+
+// streamFeed watches the database with a single change stream filtered to subscribed collections
+type streamFeed struct {
+	*dispatcher
+	db          *database.MongoDB
+	resumeToken bson.Raw
+	cancel      context.CancelFunc
+	done        chan struct{}
+	mutex       sync.Mutex
+}
+
+// newStreamFeed creates a change-stream backed feed
+func newStreamFeed(db *database.MongoDB) *streamFeed {
+	return &streamFeed{dispatcher: newDispatcher(), db: db}
+}
+
+// Mode returns the feed implementation name
+func (f *streamFeed) Mode() string {
+	return "change_stream"
+}
+
+// Start opens the change stream in the background; subscribe before calling Start
+func (f *streamFeed) Start() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.done = make(chan struct{})
+	go f.run(ctx)
+}
+
+// Stop closes the change stream and waits for the watcher to exit
+func (f *streamFeed) Stop() {
+	f.mutex.Lock()
+	cancel, done := f.cancel, f.done
+	f.cancel = nil
+	f.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// run keeps a change stream open, resuming after errors from the last seen event
+func (f *streamFeed) run(ctx context.Context) {
+	defer close(f.done)
+
+	for {
+		if err := f.watch(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("⚠️ Change stream interrupted, reopening", "retry_in", streamRetryDelay, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamRetryDelay):
+		}
+	}
+}
+
+// watch opens one change stream and dispatches events until it fails or ctx is cancelled
+func (f *streamFeed) watch(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"ns.coll":       bson.M{"$in": f.collections()},
+			"operationType": bson.M{"$in": []string{OpInsert, OpUpdate, OpReplace, OpDelete}},
+		}}},
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if f.resumeToken != nil {
+		opts.SetResumeAfter(f.resumeToken)
+	}
+
+	stream, err := f.db.GetDatabase().Watch(ctx, pipeline, opts)
+	if err != nil {
+		// resume token อาจหลุดจาก oplog ไปแล้ว ให้เริ่มใหม่จากปัจจุบันในรอบถัดไป
+		f.resumeToken = nil
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeDocument
+		if err := stream.Decode(&change); err != nil {
+			slog.Warn("⚠️ Failed to decode change event", "error", err)
+			continue
+		}
+		f.resumeToken = stream.ResumeToken()
+
+		f.dispatch(Event{
+			Collection: change.NS.Coll,
+			Operation:  change.OperationType,
+			DocumentID: documentID(change.DocumentKey.ID),
+			Document:   change.FullDocument,
+			Time:       time.Unix(int64(change.ClusterTime.T), 0),
+		})
+	}
+	return stream.Err()
+}
+
+// documentID renders a document _id as a string
+func documentID(id interface{}) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
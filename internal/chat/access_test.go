@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+// ห้อง invite-only อ่านประวัติ ค้นหา และดูสมาชิกได้เฉพาะผู้ที่ได้รับเชิญ
+func TestRestrictedRoomReadsNeedMembership(t *testing.T) {
+	server := newTestServer(t, nil)
+	alice := server.login(t, "alice")
+	bobby := server.join(t, "bobby")
+
+	alice.send(map[string]interface{}{"type": "command", "content": "/create secret --private"})
+	alice.send(map[string]interface{}{"type": "join_room", "room": "secret"})
+	alice.expect("room_joined")
+	alice.send(map[string]interface{}{"type": "message", "content": "launch codes", "client_msg_id": "m1"})
+	alice.expect("ack")
+
+	reads := []map[string]interface{}{
+		{"type": "get_history", "room": "secret"},
+		{"type": "search_messages", "query": "launch", "room": "secret"},
+		{"type": "search_messages", "query": "launch room:secret"},
+		{"type": "get_users", "room": "secret"},
+		{"type": "subscribe_members", "room": "secret"},
+	}
+	for _, read := range reads {
+		bobby.send(read)
+		reply := bobby.expect("error")
+		if message, _ := reply["message"].(string); !strings.Contains(message, "does not exist") {
+			t.Fatalf("%v: unexpected error %q", read, message)
+		}
+	}
+
+	alice.send(map[string]interface{}{"type": "command", "content": "/invite bobby"})
+	for {
+		if content, _ := bobby.expect("system")["content"].(string); strings.Contains(content, "invited you") {
+			break
+		}
+	}
+	bobby.send(map[string]interface{}{"type": "get_history", "room": "secret"})
+	history := bobby.expect("history")
+	if messages, _ := history["messages"].([]interface{}); len(messages) == 0 {
+		t.Fatalf("invited user got no history: %v", history)
+	}
+}
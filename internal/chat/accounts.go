@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+
+	"realtime-chat/internal/account"
+)
+
+// Coded errors of the account check on join
+const (
+	ErrCodeLoginRequired    = "login_required"   // server ตั้ง require_accounts: ต้องส่ง session_token
+	ErrCodeAccountRequired  = "account_required" // ชื่อนี้มี account แล้ว ต้อง login ก่อน
+	ErrCodeInvalidSession   = "invalid_session"  // session_token หมดอายุหรือถูก revoke
+	ErrCodeAccountCheckFail = "account_unavailable"
+)
+
+// SetAccounts sets the account service that owns registered usernames (nil = every name is first come, first served)
+func (h *Handler) SetAccounts(accounts *account.Service) {
+	h.accounts = accounts
+}
+
+// authenticateJoin checks the session_token of a join frame.
+// ไม่มี token คืน nil, nil; token ผิดคืน coded error
+func (h *Handler) authenticateJoin(token string) (*account.Session, *codedError) {
+	if token == "" || h.accounts == nil {
+		return nil, nil
+	}
+	session, err := h.accounts.Authenticate(token)
+	if err != nil {
+		return nil, &codedError{
+			Code:    ErrCodeInvalidSession,
+			Message: "Session expired or revoked, log in again",
+		}
+	}
+	return session, nil
+}
+
+// checkAccountOwnership reports whether a connection without a session may use username.
+// guest ใช้ได้เฉพาะชื่อที่ยังไม่มีใครสมัคร และไม่ได้เลยเมื่อเปิด require_accounts
+func (h *Handler) checkAccountOwnership(username string, session *account.Session) *codedError {
+	if session != nil {
+		return nil
+	}
+	if h.config.RequireAccounts {
+		return &codedError{
+			Code:    ErrCodeLoginRequired,
+			Message: "This server requires an account: log in with POST /api/login and join with session_token",
+		}
+	}
+	if h.accounts == nil {
+		return nil
+	}
+
+	registered, err := h.accounts.IsRegistered(username)
+	if err != nil {
+		// ตรวจไม่ได้ก็ไม่ให้เข้า ไม่อย่างนั้นใครก็สวมชื่อที่มีเจ้าของได้ระหว่าง database ล่ม
+		slog.Error("❌ Failed to check account", "username", username, "error", err)
+		return &codedError{
+			Code:    ErrCodeAccountCheckFail,
+			Message: "Cannot verify this username right now, try again later",
+		}
+	}
+	if registered {
+		return &codedError{
+			Code:    ErrCodeAccountRequired,
+			Message: fmt.Sprintf("Username '%s' belongs to an account, log in to use it", username),
+		}
+	}
+	return nil
+}
+
+// usernameRegistered reports whether a name belongs to an account (errors count as registered)
+func (h *Handler) usernameRegistered(username string) bool {
+	if h.accounts == nil {
+		return false
+	}
+	registered, err := h.accounts.IsRegistered(username)
+	return registered || err != nil
+}
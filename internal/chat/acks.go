@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// maxClientMsgIDLength limits client_msg_id; clients are expected to send a UUID or similar
+const maxClientMsgIDLength = 64
+
+// ErrCodeInvalidClientMsgID is the error code sent when client_msg_id is too long
+const ErrCodeInvalidClientMsgID = "invalid_client_msg_id"
+
+// messageAck is what the server answered for a client_msg_id
+type messageAck struct {
+	messageID string
+	room      string
+	status    string // persisted หรือ delivered (ไม่ได้บันทึก เช่นห้อง private)
+	timestamp time.Time
+}
+
+// ackCache remembers recent acks per user so a retried client_msg_id gets the original ack instead of a second message.
+// key เป็น account ID + client_msg_id ทำให้ retry หลัง reconnect (connection ใหม่) ยังถูก dedup
+type ackCache struct {
+	acks      map[string]messageAck
+	window    time.Duration
+	lastPrune time.Time
+	mutex     sync.Mutex
+}
+
+// newAckCache creates an ack cache that keeps acks for window
+func newAckCache(window time.Duration) *ackCache {
+	return &ackCache{
+		acks:   make(map[string]messageAck),
+		window: window,
+	}
+}
+
+// Get returns the ack already sent for this client_msg_id within the window
+func (c *ackCache) Get(username, clientMsgID string) (messageAck, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ack, exists := c.acks[ackKey(username, clientMsgID)]
+	if !exists || time.Since(ack.timestamp) > c.window {
+		return messageAck{}, false
+	}
+	return ack, true
+}
+
+// Put records the ack sent for a client_msg_id
+func (c *ackCache) Put(username, clientMsgID string, ack messageAck) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	// ลบ ack ที่หมดช่วง dedup แล้ว อย่างมากนาทีละครั้ง
+	if now.Sub(c.lastPrune) >= time.Minute {
+		c.lastPrune = now
+		for key, existing := range c.acks {
+			if now.Sub(existing.timestamp) > c.window {
+				delete(c.acks, key)
+			}
+		}
+	}
+	c.acks[ackKey(username, clientMsgID)] = ack
+}
+
+func ackKey(username, clientMsgID string) string {
+	return userPkg.AccountID(username) + "\x00" + clientMsgID
+}
+
+// sendAck tells the sender its message was persisted and broadcast
+func (h *Handler) sendAck(conn Connection, clientMsgID string, ack messageAck, duplicate bool) {
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:        "ack",
+		ClientMsgID: clientMsgID,
+		MessageID:   ack.messageID,
+		Room:        ack.room,
+		Status:      ack.status,
+		Duplicate:   duplicate,
+		Timestamp:   ack.timestamp,
+	})
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// maxReactionLength limits a reaction value (one emoji, possibly with modifiers)
+const maxReactionLength = 32
+
+// quickActions maps compact "action" messages to the full message type they stand for.
+// รูปแบบ {type:"action", action, target, value} ลดขนาด frame สำหรับ mobile client บนเน็ตช้า
+//
+//	join    target=<room>                  -> join_room
+//	leave                                  -> leave_room
+//	users   target=<room>                  -> get_users
+//	history target=<room> value=<limit>    -> get_history
+//	say     value=<text>                   -> message
+//	cmd     value=</command ...>           -> command
+//	draft   target=<room> value=<text>     -> save_draft
+//	pref    target=<key>  value=<value>    -> set_pref
+//	react   target=<msgid> value=<emoji>   -> react
+//	ack     target=<probe id>              -> delivery_ack
+var quickActions = map[string]func(msg ClientMessage) ClientMessage{
+	"join":  func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "join_room", Room: msg.Target} },
+	"leave": func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "leave_room"} },
+	"users": func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "get_users", Room: msg.Target} },
+	"history": func(msg ClientMessage) ClientMessage {
+		limit, _ := strconv.Atoi(msg.Value)
+		return ClientMessage{Type: "get_history", Room: msg.Target, Limit: limit}
+	},
+	"say": func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "message", Content: msg.Value} },
+	"cmd": func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "command", Command: msg.Value} },
+	"draft": func(msg ClientMessage) ClientMessage {
+		return ClientMessage{Type: "save_draft", Room: msg.Target, Content: msg.Value}
+	},
+	"pref": func(msg ClientMessage) ClientMessage {
+		return ClientMessage{Type: "set_pref", Key: msg.Target, Value: msg.Value}
+	},
+	"react": func(msg ClientMessage) ClientMessage {
+		return ClientMessage{Type: "react", Target: msg.Target, Value: msg.Value}
+	},
+	"ack": func(msg ClientMessage) ClientMessage { return ClientMessage{Type: "delivery_ack", ProbeID: msg.Target} },
+}
+
+// expandAction converts a compact "action" message into the full message it stands for
+func expandAction(msg ClientMessage) (ClientMessage, error) {
+	expand, exists := quickActions[msg.Action]
+	if !exists {
+		return msg, fmt.Errorf("unknown action '%s'", msg.Action)
+	}
+	return expand(msg), nil
+}
+
+// handleReaction toggles the user's emoji reaction on a message in their current room
+func (h *Handler) handleReaction(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if err := h.toggleReaction(user, msg.Target, msg.Value); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// handleReactCommand handles /react <message_id> <emoji>
+func (h *Handler) handleReactCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: /react <message_id> <emoji>")
+	}
+	return h.toggleReaction(user, args[0], args[1])
+}
+
+// toggleReaction adds the reaction if the user hasn't reacted with that emoji yet, otherwise removes it,
+// then broadcasts the updated counts to everyone in the room (including the reacting user)
+func (h *Handler) toggleReaction(user *userPkg.User, messageID, emoji string) error {
+	if user.CurrentRoom == "" {
+		return fmt.Errorf("you must be in a room to react")
+	}
+	if messageID == "" || emoji == "" || len(emoji) > maxReactionLength || !utf8.ValidString(emoji) {
+		return fmt.Errorf("reaction requires a message ID and a single emoji")
+	}
+	if h.messageRepo == nil {
+		return fmt.Errorf("reactions require message persistence")
+	}
+
+	// react ได้เฉพาะข้อความในห้องที่อยู่ตอนนี้ เพื่อไม่ให้ broadcast ไปผิดห้อง
+	message, err := h.messageRepo.GetMessage(messageID)
+	if err != nil || message.RoomName != user.CurrentRoom {
+		return fmt.Errorf("message '%s' not found in room '%s'", messageID, user.CurrentRoom)
+	}
+
+	added, reactions, err := h.messageRepo.ToggleReaction(messageID, emoji, user.Username)
+	if err != nil {
+		slog.Error("❌ Failed to toggle reaction", "message_id", messageID, "error", err)
+		return fmt.Errorf("failed to update reaction")
+	}
+
+	msgType := "reaction_removed"
+	if added {
+		msgType = "reaction_added"
+	}
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      msgType,
+		Target:    messageID,
+		Content:   emoji,
+		Username:  user.Username,
+		Room:      user.CurrentRoom,
+		Reactions: reactions,
+		Timestamp: time.Now(),
+	}, "", user.CurrentRoom)
+	return nil
+}
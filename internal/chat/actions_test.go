@@ -0,0 +1,23 @@
+package chat
+
+import "testing"
+
+// ผู้รับต้องได้ id ของข้อความใน broadcast จึงจะ react ด้วย quick action ได้
+func TestReactUsingBroadcastMessageID(t *testing.T) {
+	server := newTestServer(t, nil)
+	alice := server.join(t, "alice")
+	bobby := server.join(t, "bobby")
+
+	alice.send(map[string]interface{}{"type": "message", "content": "hello"})
+	received := bobby.expect("message")
+	id, _ := received["id"].(string)
+	if id == "" {
+		t.Fatalf("broadcast message has no id: %v", received)
+	}
+
+	bobby.send(map[string]interface{}{"type": "action", "action": "react", "target": id, "value": "👍"})
+	reaction := alice.expect("reaction_added")
+	if reaction["target"] != id || reaction["username"] != "bobby" {
+		t.Fatalf("unexpected reaction: %v", reaction)
+	}
+}
@@ -0,0 +1,541 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetIPLimiter sets the per-IP connection limiter shown in /stats
+func (s *commandService) SetIPLimiter(limiter *security.IPLimiter) {
+	s.ipLimiter = limiter
+}
+
+// SetConnectionThrottle sets the per-IP connection throttle and registers its admin commands
+func (s *commandService) SetConnectionThrottle(throttle *security.ConnectionThrottle) {
+	s.throttle = throttle
+
+	s.RegisterCommand(&Command{
+		Name:         "blocked",
+		Description:  "List IPs blocked by connection throttling (admin only)",
+		Usage:        "/blocked",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleBlocked,
+	})
+
+	s.RegisterCommand(&Command{
+		Name:         "unblock",
+		Description:  "Unblock an IP blocked by connection throttling (admin only)",
+		Usage:        "/unblock <ip>",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleUnblock,
+	})
+}
+
+func (s *commandService) handleBlocked(conn Connection, args []string) error {
+	if _, err := s.requireAdmin(conn); err != nil {
+		return err
+	}
+
+	blocked := s.throttle.Blocked()
+	stats := s.throttle.Stats()
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🚫 Blocked IPs (%d) - rejected attempts: %d, total blocks: %d\n",
+		len(blocked), stats.RejectedAttempts, stats.TotalBlocks))
+	for _, entry := range blocked {
+		text.WriteString(fmt.Sprintf("• %s - %v remaining (level %d)\n",
+			entry.IP, time.Until(entry.BlockedUntil).Round(time.Second), entry.Level))
+	}
+
+	return replySystem(conn, text.String())
+}
+
+func (s *commandService) handleUnblock(conn Connection, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("IP required. Usage: /unblock <ip>")
+	}
+
+	if _, err := s.requireAdmin(conn); err != nil {
+		return err
+	}
+
+	if !s.throttle.Unblock(args[0]) {
+		return fmt.Errorf("IP '%s' is not blocked", args[0])
+	}
+
+	return replySystem(conn, fmt.Sprintf("✅ IP '%s' unblocked", args[0]))
+}
+
+// SetLoginLockout sets the failed password login lockout and registers the /unlock admin command
+func (s *commandService) SetLoginLockout(lockout *security.LoginLockout) {
+	s.lockout = lockout
+
+	s.RegisterCommand(&Command{
+		Name:         "unlock",
+		Description:  "Unlock password login for an account or IP locked after failed logins (admin only)",
+		Usage:        "/unlock <username|ip>",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleUnlock,
+	})
+}
+
+func (s *commandService) handleUnlock(conn Connection, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("username or IP required. Usage: /unlock <username|ip>")
+	}
+
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	target := args[0]
+	kind, unlocked := "account", false
+	if net.ParseIP(target) != nil {
+		kind, unlocked = "IP", s.lockout.UnlockIP(target)
+	} else {
+		unlocked = s.lockout.UnlockAccount(userPkg.AccountID(target))
+	}
+	if !unlocked {
+		return fmt.Errorf("%s '%s' has no failed logins", kind, target)
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Record(moderation.Flag{Username: admin.Username, Connection: conn.GetLabel(), Reason: "login_unlocked",
+			Detail: fmt.Sprintf("%s %s", strings.ToLower(kind), target)})
+	}
+	return replySystem(conn, fmt.Sprintf("🔓 Password login for %s '%s' unlocked", kind, target))
+}
+
+// SetLatencyRecorder sets the message path latency recorder and registers the /latency admin command
+func (s *commandService) SetLatencyRecorder(recorder *config.LatencyRecorder) {
+	s.latency = recorder
+
+	s.RegisterCommand(&Command{
+		Name:         "latency",
+		Description:  "Show message path latency percentiles per stage (admin only)",
+		Usage:        "/latency",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleLatency,
+	})
+}
+
+func (s *commandService) handleLatency(conn Connection, args []string) error {
+	if _, err := s.requireAdmin(conn); err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	text.WriteString("⏱️ Message path latency (ms):\n")
+	for _, stage := range s.latency.Snapshot() {
+		text.WriteString(fmt.Sprintf("• %-8s n=%d p50=%.2f p95=%.2f p99=%.2f max=%.2f\n",
+			stage.Stage, stage.Count, stage.P50Ms, stage.P95Ms, stage.P99Ms, stage.MaxMs))
+	}
+
+	return replySystem(conn, text.String())
+}
+
+// requireAdmin returns the connection's user if it is an admin (configured or assigned with /setrole)
+func (s *commandService) requireAdmin(conn Connection) (*userPkg.User, error) {
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	if effectiveRole(s.config, s.roles, s.roomService, chatUser, "") < RoleAdmin {
+		return nil, fmt.Errorf("this command is restricted to admins")
+	}
+
+	return chatUser, nil
+}
+
+// replySystem sends a system message to a single connection
+func replySystem(conn Connection, content string) error {
+	return replyCommand(conn, ServerMessage{Content: content})
+}
+
+// replyCommand sends a command response to a single connection. Content is the text shown to the user;
+// structured fields (rooms, users, messages ...) let clients render the same data without parsing text
+func replyCommand(conn Connection, reply ServerMessage) error {
+	reply.Type = "system"
+	reply.Sender = "System"
+	reply.Timestamp = time.Now()
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	return conn.SendMessage(data)
+}
+
+// SetMigrationRunner sets the schema migration runner and registers the /migrations admin command
+func (s *commandService) SetMigrationRunner(runner MigrationRunner) {
+	s.migrations = runner
+
+	s.RegisterCommand(&Command{
+		Name:         "migrations",
+		Description:  "Show schema migration status, preview pending migrations, or apply them (admin only)",
+		Usage:        "/migrations [status|dry-run|apply]",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleMigrations,
+	})
+}
+
+func (s *commandService) handleMigrations(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	var text strings.Builder
+	switch action {
+	case "status":
+		statuses, err := s.migrations.Status()
+		if err != nil {
+			return err
+		}
+		text.WriteString(fmt.Sprintf("🗄️ Schema migrations (%d registered):\n", len(statuses)))
+		for _, status := range statuses {
+			if status.Applied {
+				text.WriteString(fmt.Sprintf("✅ %d %s (applied %s)\n", status.Version, status.Name, admin.FormatTime(*status.AppliedAt, "2006-01-02 15:04")))
+			} else {
+				text.WriteString(fmt.Sprintf("⏳ %d %s - %s\n", status.Version, status.Name, status.Description))
+			}
+		}
+
+	case "dry-run", "apply":
+		dryRun := action == "dry-run"
+		statuses, err := s.migrations.Apply(dryRun)
+		if !dryRun {
+			connLogger(conn).Info("🗄️ Migrations applied", "count", len(statuses))
+		}
+		if err != nil {
+			return fmt.Errorf("%v (%d migrations applied before the failure)", err, len(statuses))
+		}
+		if len(statuses) == 0 {
+			return replySystem(conn, "✅ Schema is up to date, no pending migrations")
+		}
+		if dryRun {
+			text.WriteString(fmt.Sprintf("🔍 %d pending migrations would be applied:\n", len(statuses)))
+		} else {
+			text.WriteString(fmt.Sprintf("✅ Applied %d migrations:\n", len(statuses)))
+		}
+		for _, status := range statuses {
+			text.WriteString(fmt.Sprintf("• %d %s - %s\n", status.Version, status.Name, status.Description))
+		}
+
+	default:
+		return fmt.Errorf("usage: /migrations [status|dry-run|apply]")
+	}
+
+	return replySystem(conn, text.String())
+}
+
+// SetAnnouncements enables admin announcements with delivery/acknowledgment tracking
+func (s *commandService) SetAnnouncements(store *announcement.Store) {
+	s.announcements = store
+
+	s.RegisterCommand(&Command{
+		Name:         "announce",
+		Description:  "Send an announcement to every online user and track acknowledgments (admin only)",
+		Usage:        "/announce <message>",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleAnnounce,
+	})
+
+	s.RegisterCommand(&Command{
+		Name:         "announcements",
+		Description:  "Show acknowledgment compliance of announcements (admin only)",
+		Usage:        "/announcements [id]",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleAnnouncements,
+	})
+}
+
+func (s *commandService) handleAnnounce(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("message required. Usage: /announce <message>")
+	}
+	content := strings.Join(args, " ")
+
+	id, delivered, err := deliverAnnouncement(s.announcements, s.userService, s.wsManager, content, admin.Username)
+	if err != nil {
+		return err
+	}
+	return replySystem(conn, fmt.Sprintf("📢 Announcement %s sent to %d users. Use /announcements %s to track acknowledgments", id, delivered, id))
+}
+
+// deliverAnnouncement creates an announcement and sends it to every online user;
+// returns its ID and how many users it was delivered to
+func deliverAnnouncement(store *announcement.Store, userService UserService, wsManager WebSocketManager, content, createdBy string) (string, int, error) {
+	id := store.Create(content, createdBy)
+	data, err := json.Marshal(ServerMessage{
+		Type:      "announcement",
+		Target:    id,
+		Content:   content,
+		Sender:    "System",
+		Username:  createdBy,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to encode announcement: %v", err)
+	}
+
+	// ส่งตรงถึงผู้ใช้ที่ออนไลน์ทุกคน และบันทึกเฉพาะคนที่ส่งเข้า queue สำเร็จว่าได้รับแล้ว
+	delivered := 0
+	for _, user := range userService.GetAllUsers() {
+		target, exists := wsManager.GetConnection(user.ConnID)
+		if !exists {
+			continue
+		}
+		if err := target.SendMessage(data); err != nil {
+			continue
+		}
+		store.MarkDelivered(id, user.Username)
+		delivered++
+	}
+
+	slog.Info("📢 Announcement sent", "id", id, "by", createdBy, "delivered", delivered)
+	return id, delivered, nil
+}
+
+// Announce sends a server-wide announcement on behalf of an operator (admin HTTP endpoint)
+func (h *Handler) Announce(content, createdBy string) (string, int, error) {
+	if h.announcements == nil {
+		return "", 0, fmt.Errorf("announcements are not enabled")
+	}
+	return deliverAnnouncement(h.announcements, h.userService, h.wsManager, content, createdBy)
+}
+
+func (s *commandService) handleAnnouncements(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	if len(args) == 0 {
+		reports := s.announcements.Reports()
+		text.WriteString(fmt.Sprintf("📢 Announcements (%d):\n", len(reports)))
+		for _, report := range reports {
+			text.WriteString(fmt.Sprintf("• %s [%s] %d/%d acknowledged (%.0f%%) - %s\n",
+				report.ID, admin.FormatTime(report.CreatedAt, "Jan 2 15:04"), report.Acknowledged, report.Recipients,
+				report.ComplianceRate*100, truncateText(report.Content, 40)))
+		}
+		return replySystem(conn, text.String())
+	}
+
+	report, exists := s.announcements.Report(args[0])
+	if !exists {
+		return fmt.Errorf("announcement '%s' not found", args[0])
+	}
+
+	text.WriteString(fmt.Sprintf("📢 Announcement %s by %s at %s:\n%s\n", report.ID, report.CreatedBy,
+		admin.FormatTime(report.CreatedAt, "Jan 2 15:04 MST"), report.Content))
+	text.WriteString(fmt.Sprintf("• Acknowledged: %d/%d (%.0f%%)\n", report.Acknowledged, report.Recipients, report.ComplianceRate*100))
+	for _, ack := range report.AckedBy {
+		text.WriteString(fmt.Sprintf("  ✅ %s at %s\n", ack.Username, admin.FormatTime(ack.At, "15:04:05")))
+	}
+	if len(report.Pending) > 0 {
+		text.WriteString(fmt.Sprintf("• Pending: %s\n", strings.Join(report.Pending, ", ")))
+	}
+	return replySystem(conn, text.String())
+}
+
+// truncateText shortens text to at most max runes for one-line listings
+func truncateText(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
+
+// Limits of the /shutdown countdown
+const (
+	defaultShutdownDelay = 30 * time.Second
+	maxShutdownDelay     = time.Hour
+)
+
+// shutdownTimer holds the countdown of a scheduled /shutdown
+type shutdownTimer struct {
+	stop  func(reason string)
+	timer *time.Timer
+	at    time.Time
+	mutex sync.Mutex
+}
+
+// schedule starts the countdown; false when a shutdown is already scheduled
+func (t *shutdownTimer) schedule(delay time.Duration, reason string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.timer != nil {
+		return false
+	}
+	t.at = time.Now().Add(delay)
+	t.timer = time.AfterFunc(delay, func() { t.stop(reason) })
+	return true
+}
+
+// cancel stops the countdown; false when nothing was scheduled (or it already fired)
+func (t *shutdownTimer) cancel() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.timer == nil || !t.timer.Stop() {
+		return false
+	}
+	t.timer = nil
+	return true
+}
+
+// SetShutdown sets the function that gracefully stops the server and registers the /shutdown admin command
+func (s *commandService) SetShutdown(shutdown func(reason string)) {
+	s.shutdown = &shutdownTimer{stop: shutdown}
+
+	s.RegisterCommand(&Command{
+		Name:         "shutdown",
+		Description:  "Gracefully stop the server after a countdown announced to everyone, or cancel it (admin only)",
+		Usage:        "/shutdown [<delay>] [reason] | /shutdown cancel",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleShutdown,
+	})
+}
+
+func (s *commandService) handleShutdown(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 && args[0] == "cancel" {
+		if !s.shutdown.cancel() {
+			return fmt.Errorf("no shutdown is scheduled")
+		}
+		slog.Info("✅ Scheduled shutdown cancelled", "by", admin.Username)
+		s.broadcastSystemNotice(fmt.Sprintf("✅ Scheduled shutdown cancelled by %s", admin.Username))
+		return nil
+	}
+
+	delay := defaultShutdownDelay
+	if len(args) > 0 {
+		if parsed, err := time.ParseDuration(args[0]); err == nil {
+			delay = parsed
+			args = args[1:]
+		}
+	}
+	if delay < 0 || delay > maxShutdownDelay {
+		return fmt.Errorf("delay must be between 0s and %s", maxShutdownDelay)
+	}
+	reason := strings.Join(args, " ")
+
+	if !s.shutdown.schedule(delay, reason) {
+		return fmt.Errorf("a shutdown is already scheduled for %s, use /shutdown cancel first",
+			admin.FormatTime(s.shutdown.at, "15:04:05"))
+	}
+
+	notice := fmt.Sprintf("⚠️ Server shutting down in %s (by %s)", delay, admin.Username)
+	if reason != "" {
+		notice += ": " + reason
+	}
+	slog.Info("🛑 Shutdown scheduled", "by", admin.Username, "delay", delay, "reason", reason)
+	s.broadcastSystemNotice(notice)
+	return nil
+}
+
+// AlertUser sends a security alert (e.g. failed login) as a system message to the account sessions of a user;
+// guests that joined with the same name don't receive it. returns how many connections received it
+func (s *commandService) AlertUser(username, content string) int {
+	data, err := json.Marshal(ServerMessage{
+		Type:      "system",
+		Content:   content,
+		Sender:    "System",
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return 0
+	}
+
+	delivered := 0
+	for connID, name := range s.wsManager.Usernames() {
+		if !strings.EqualFold(name, username) {
+			continue
+		}
+		conn, exists := s.wsManager.GetConnection(connID)
+		if !exists {
+			continue
+		}
+		if chatUser, ok := conn.GetUser().(*userPkg.User); !ok || chatUser == nil || chatUser.Guest {
+			continue
+		}
+		if s.wsManager.SendMessage(connID, data) == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// broadcastSystemNotice sends a system message to every online user
+func (s *commandService) broadcastSystemNotice(content string) {
+	s.messageService.BroadcastMessage(&messagePkg.Message{
+		Type:      "system",
+		Content:   content,
+		Sender:    "System",
+		Username:  "System",
+		Timestamp: time.Now(),
+	}, "")
+}
+
+// AlertAdmins sends an operational alert (e.g. database outage) to every online admin as a system message;
+// returns how many admins received it. ผู้รับมาจาก connection ในหน่วยความจำ ไม่ใช่ user repository
+// จึงส่งถึงได้แม้ฐานข้อมูลล่ม
+func (s *commandService) AlertAdmins(content string) int {
+	data, err := json.Marshal(ServerMessage{
+		Type:      "system",
+		Content:   content,
+		Sender:    "System",
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return 0
+	}
+
+	delivered := 0
+	for connID := range s.wsManager.Usernames() {
+		conn, exists := s.wsManager.GetConnection(connID)
+		if !exists {
+			continue
+		}
+		chatUser, ok := conn.GetUser().(*userPkg.User)
+		if !ok || chatUser == nil || effectiveRole(s.config, s.roles, s.roomService, chatUser, "") < RoleAdmin {
+			continue
+		}
+		if s.wsManager.SendMessage(connID, data) == nil {
+			delivered++
+		}
+	}
+	slog.Info("🚨 Admin alert sent", "delivered", delivered, "alert", content)
+	return delivered
+}
@@ -0,0 +1,26 @@
+package chat
+
+import (
+	"testing"
+
+	"realtime-chat/internal/config"
+)
+
+// alert ของฐานข้อมูลส่งถึงเฉพาะ admin ที่ login ด้วย account และออนไลน์อยู่
+func TestAlertAdminsReachesOnlyAdmins(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.AdminUsernames = []string{"alice", "mallory"}
+	})
+	alice := server.login(t, "alice")
+	server.join(t, "bobby")
+	server.join(t, "mallory") // ชื่ออยู่ใน admin_usernames แต่เข้ามาเป็น guest
+
+	if delivered := server.commands.AlertAdmins("🚨 MongoDB is unavailable"); delivered != 1 {
+		t.Fatalf("alert delivered to %d connections, want 1", delivered)
+	}
+	for {
+		if alert := alice.expect("system"); alert["content"] == "🚨 MongoDB is unavailable" {
+			break
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// PostAttachment posts a file uploaded through POST /api/upload to a room as a message from username.
+// ข้อความผ่าน path เดียวกับข้อความแชท: บันทึก (ยกเว้นห้อง private), index แล้วกระจายให้ทุกคนในห้อง
+func (h *Handler) PostAttachment(username, roomName, caption string, file messagePkg.MessageAttachment) (*messagePkg.Message, error) {
+	// ไม่มี caption ใช้ชื่อไฟล์เป็นเนื้อหา ให้ client ที่ไม่รู้จัก attachments ยังแสดงอะไรบางอย่างได้
+	content := "📎 " + file.FileName
+	if caption != "" {
+		var err error
+		if content, err = h.validator.ValidateMessage(caption); err != nil {
+			return nil, err
+		}
+	}
+
+	message, err := h.postAs(username, roomName, content, []messagePkg.MessageAttachment{file})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("📎 Attachment posted", "room", roomName, "username", message.Username, "file_id", file.ID, "mime_type", file.MimeType)
+	return message, nil
+}
+
+// postAs saves and broadcasts a message posted on behalf of username by an integration (not a WebSocket connection)
+func (h *Handler) postAs(username, roomName, content string, attachments []messagePkg.MessageAttachment) (*messagePkg.Message, error) {
+	chatRoom, exists := h.roomService.GetRoom(roomName)
+	if !exists {
+		return nil, fmt.Errorf("room '%s' not found", roomName)
+	}
+	validatedName, err := h.validator.ValidateUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := false
+	if h.mirror != nil {
+		if writer := h.mirror.Writer(roomName); writer != "" {
+			if writer != h.config.NodeID {
+				return nil, fmt.Errorf("room '%s' is a broadcast mirror; posts are only accepted on node %s", roomName, writer)
+			}
+			mirrored = true
+		}
+	}
+
+	message := &messagePkg.Message{
+		Type:        "message",
+		Content:     content,
+		Sender:      "api",
+		Username:    validatedName,
+		RoomName:    roomName,
+		Timestamp:   time.Now(),
+		Attachments: attachments,
+	}
+
+	if h.messageRepo != nil && !chatRoom.Private {
+		if h.timeline != nil {
+			if seq, err := h.timeline.NextSeq(roomName); err != nil {
+				slog.Warn("⚠️ Failed to assign timeline sequence", "room", roomName, "username", validatedName, "error", err)
+			} else {
+				message.Seq = seq
+			}
+		}
+		if err := h.messageRepo.SaveMessage(message); err != nil {
+			slog.Warn("⚠️ Failed to save message", "room", roomName, "username", validatedName, "error", err)
+			if mirrored {
+				return nil, fmt.Errorf("failed to post to mirrored room, please retry")
+			}
+		}
+		if h.searchIndex != nil {
+			h.searchIndex.IndexMessage(message)
+		}
+	}
+
+	// ส่งเป็น JSON ของ Message ทั้งก้อน (มี id และ attachments) ห้อง mirror ถูกกระจายจาก change feed แล้ว
+	if !mirrored {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message: %v", err)
+		}
+		h.wsManager.BroadcastToRoom(data, "", roomName)
+	}
+	h.sessions.RecordMissed(message)
+	h.roomService.RecordActivity(roomName)
+	return message, nil
+}
@@ -0,0 +1,264 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// staticDir is the bundled web client, relative to this package
+const staticDir = "../../static"
+
+// ทดสอบ web client ใน static/ กับ server จริงผ่าน headless Chrome:
+// room sidebar, history ตอนเข้าห้อง, typing indicator, reaction และ reconnect/resume
+// ต้องมี Chrome/Chromium (ตั้ง CHROME_PATH ได้) ถ้าไม่มีจะ skip
+func TestWebClientEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("browser test skipped in -short mode")
+	}
+	browser := startBrowser(t)
+
+	server := newTestServer(t, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", server.handler.HandleWebSocket)
+	mux.HandleFunc("/events", server.handler.HandleEvents)
+	mux.Handle("/", http.FileServer(http.Dir(staticDir)))
+	web := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		server.handler.CloseEventStreams()
+		web.Close()
+	})
+
+	alice := browser.open(t, web.URL+"/chat.html")
+	bobby := browser.open(t, web.URL+"/chat.html")
+	for name, page := range map[string]*browserPage{"alice": alice, "bobby": bobby} {
+		page.waitFor(t, "client loaded", `!!window.chatApp`)
+		page.eval(t, fmt.Sprintf(`chatApp.usernameInput.value = %q; chatApp.joinBtn.click()`, name))
+		page.waitFor(t, name+" joined with a resume token", `chatApp.isConnected && !!chatApp.resumeToken`)
+		page.waitFor(t, "room sidebar", `document.getElementById('roomsList').textContent.includes('general')`)
+	}
+
+	// ข้อความสดต้องมี id เพื่อให้ react ได้
+	alice.eval(t, `chatApp.messageInput.value = 'hello from alice'; chatApp.sendBtn.click()`)
+	bobby.waitFor(t, "live message with an id",
+		`[...document.querySelectorAll('#messages .message.other[data-message-id]')].some(el => el.textContent.includes('hello from alice'))`)
+
+	alice.eval(t, `chatApp.messageInput.value = 'typ'; chatApp.messageInput.dispatchEvent(new Event('input'))`)
+	bobby.waitFor(t, "typing indicator", `chatApp.typingIndicator.textContent.includes('alice is typing')`)
+
+	bobby.eval(t, `document.querySelector('#messages .message.other[data-message-id] [data-action=react]').click()`)
+	alice.waitFor(t, "reaction on alice's message", `[...document.querySelectorAll('.reaction-chip')].some(el => el.textContent.includes('👍 1'))`)
+
+	// ห้องใหม่ขึ้นใน sidebar และผู้ที่เข้าห้องทีหลังเห็น history
+	alice.eval(t, `chatApp.messageInput.value = '/create standup'; chatApp.sendBtn.click()`)
+	alice.waitFor(t, "new room in the sidebar", `document.getElementById('roomsList').textContent.includes('standup')`)
+	alice.eval(t, `chatApp.messageInput.value = '/join standup'; chatApp.sendBtn.click()`)
+	alice.waitFor(t, "joined the new room", `chatApp.currentRoom === 'standup'`)
+	alice.eval(t, `chatApp.messageInput.value = 'standup notes'; chatApp.sendBtn.click()`)
+	alice.waitFor(t, "acked message", `!!document.querySelector('#messages .message.own[data-message-id]:not(.pending)')`)
+	bobby.eval(t, `chatApp.messageInput.value = '/join standup'; chatApp.sendBtn.click()`)
+	bobby.waitFor(t, "history on join", `chatApp.currentRoom === 'standup' && document.getElementById('messages').textContent.includes('standup notes')`)
+
+	// connection หลุด (ไม่ใช่ปิดปกติ) client ต่อใหม่ด้วย resume token และได้ข้อความที่พลาดไป
+	alice.eval(t, `chatApp.ws.close(4000)`)
+	alice.waitFor(t, "disconnect", `!chatApp.isConnected`)
+	bobby.eval(t, `chatApp.messageInput.value = 'while you were away'; chatApp.sendBtn.click()`)
+	alice.waitFor(t, "resumed with missed messages",
+		`chatApp.isConnected && chatApp.currentRoom === 'standup' && document.getElementById('messages').textContent.includes('while you were away')`)
+}
+
+// chromeBinary finds a Chrome or Chromium binary: CHROME_PATH, PATH, then the puppeteer download cache
+func chromeBinary() string {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path
+	}
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "chrome", "chrome-headless-shell"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	home, _ := os.UserHomeDir()
+	for _, pattern := range []string{
+		"puppeteer/chrome-headless-shell/*/*/chrome-headless-shell",
+		"puppeteer/chrome/*/*/chrome",
+	} {
+		if matches, _ := filepath.Glob(filepath.Join(home, ".cache", pattern)); len(matches) > 0 {
+			return matches[len(matches)-1]
+		}
+	}
+	return ""
+}
+
+// devtools is a minimal Chrome DevTools Protocol client over the browser endpoint.
+// ใช้ทีละ call จาก goroutine ของ test เท่านั้น event ที่ไม่ได้รอจะถูกข้าม
+type devtools struct {
+	conn   *websocket.Conn
+	nextID int
+}
+
+// browserPage is a tab attached with a flat DevTools session
+type browserPage struct {
+	devtools  *devtools
+	sessionID string
+}
+
+// startBrowser launches headless Chrome with remote debugging on a random port
+func startBrowser(t *testing.T) *devtools {
+	t.Helper()
+
+	binary := chromeBinary()
+	if binary == "" {
+		t.Skip("no Chrome/Chromium found (set CHROME_PATH to run the browser test)")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, binary, "--headless", "--no-sandbox", "--disable-gpu",
+		"--no-first-run", "--remote-debugging-port=0", "--user-data-dir="+t.TempDir(), "about:blank")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatalf("chrome stderr: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		t.Skipf("cannot start %s: %v", binary, err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		cmd.Wait()
+	})
+
+	// Chrome พิมพ์ endpoint ของ DevTools ทาง stderr เมื่อพร้อม
+	endpoint := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if _, url, found := strings.Cut(scanner.Text(), "DevTools listening on "); found {
+				endpoint <- url
+				break
+			}
+		}
+		close(endpoint)
+		for scanner.Scan() {
+		}
+	}()
+
+	var url string
+	select {
+	case url = <-endpoint:
+	case <-time.After(15 * time.Second):
+	}
+	if url == "" {
+		t.Skipf("%s did not start a DevTools endpoint (missing system libraries?)", binary)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial devtools: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &devtools{conn: conn}
+}
+
+// call sends one DevTools command and waits for its result
+func (d *devtools) call(t *testing.T, sessionID, method string, params interface{}, result interface{}) {
+	t.Helper()
+
+	d.nextID++
+	request := map[string]interface{}{"id": d.nextID, "method": method, "params": params}
+	if sessionID != "" {
+		request["sessionId"] = sessionID
+	}
+	if err := d.conn.WriteJSON(request); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+
+	for {
+		var response struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		d.conn.SetReadDeadline(time.Now().Add(15 * time.Second))
+		if err := d.conn.ReadJSON(&response); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if response.ID != d.nextID {
+			continue
+		}
+		if response.Error != nil {
+			t.Fatalf("%s: %s", method, response.Error.Message)
+		}
+		if result != nil {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				t.Fatalf("%s result: %v", method, err)
+			}
+		}
+		return
+	}
+}
+
+// open creates a new tab at url and attaches to it
+func (d *devtools) open(t *testing.T, url string) *browserPage {
+	t.Helper()
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	d.call(t, "", "Target.createTarget", map[string]interface{}{"url": url, "newWindow": true}, &target)
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	d.call(t, "", "Target.attachToTarget", map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &session)
+	return &browserPage{devtools: d, sessionID: session.SessionID}
+}
+
+// eval runs JavaScript in the page and returns its JSON value
+func (p *browserPage) eval(t *testing.T, expression string) interface{} {
+	t.Helper()
+
+	var result struct {
+		Result struct {
+			Value interface{} `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	p.devtools.call(t, p.sessionID, "Runtime.evaluate",
+		map[string]interface{}{"expression": expression, "returnByValue": true}, &result)
+	if result.ExceptionDetails != nil {
+		t.Fatalf("eval %q: %s %s", expression, result.ExceptionDetails.Text, result.ExceptionDetails.Exception.Description)
+	}
+	return result.Result.Value
+}
+
+// waitFor polls a JavaScript condition until it is true
+func (p *browserPage) waitFor(t *testing.T, what, condition string) {
+	t.Helper()
+
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if ok, _ := p.eval(t, `(() => { try { return !!(`+condition+`) } catch (e) { return false } })()`).(bool); ok {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	messages, _ := p.eval(t, `(document.getElementById('messages') || {}).innerText + '\n' + (document.getElementById('notifications') || {}).innerText`).(string)
+	t.Fatalf("timed out waiting for %s; messages:\n%s", what, messages)
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/security"
+)
+
+// ErrCodeRoomSwitchLimit is the error code sent when a user joins or leaves rooms too quickly
+const ErrCodeRoomSwitchLimit = "room_switch_limit"
+
+// codedError is an error with a machine-readable code and structured details for the client.
+// คำสั่งคืนได้แค่ error จึงห่อ code ไว้ให้ handleCommand ส่งต่อใน ServerMessage
+type codedError struct {
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+func (e *codedError) Error() string {
+	return e.Message
+}
+
+// validationFailure turns a security.ValidationError into a coded error; other errors keep only their message
+func validationFailure(err error) *codedError {
+	if verr, ok := security.AsValidationError(err); ok {
+		return &codedError{Code: verr.Code, Message: verr.Message, Details: verr.Params}
+	}
+	return &codedError{Message: err.Error()}
+}
+
+// SetChurnLimiter sets the per-user room switch limiter
+func (s *commandService) SetChurnLimiter(limiter *security.ChurnLimiter) {
+	s.churn = limiter
+}
+
+// SetChurnLimiter sets the per-user room switch limiter
+func (h *Handler) SetChurnLimiter(limiter *security.ChurnLimiter) {
+	h.churn = limiter
+}
+
+// checkRoomSwitch takes a room switch token for the user, returning a coded error when none is left
+func checkRoomSwitch(limiter *security.ChurnLimiter, username string) *codedError {
+	if limiter == nil {
+		return nil
+	}
+
+	allowed, retryAfter := limiter.Allow(username)
+	if allowed {
+		return nil
+	}
+
+	limit, window := limiter.Limit()
+	retryAfter = retryAfter.Round(time.Second)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &codedError{
+		Code:    ErrCodeRoomSwitchLimit,
+		Message: fmt.Sprintf("You are switching rooms too quickly (max %d per %v). Try again in %v", limit, window, retryAfter),
+		Details: map[string]interface{}{
+			"limit":               limit,
+			"window_seconds":      int(window.Seconds()),
+			"retry_after_seconds": int(retryAfter.Seconds()),
+		},
+	}
+}
+
+// sendCodedError sends a coded error to the client
+func (h *Handler) sendCodedError(conn Connection, err *codedError) {
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "error",
+		Message:   err.Message,
+		Code:      err.Code,
+		Details:   err.Details,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+)
+
+// /clone คัดลอกการจำกัดการเข้าถึง ผู้ได้รับเชิญ รายละเอียด role ในห้อง และหมุดไปยังห้องใหม่
+func TestCloneCopiesRoomSettings(t *testing.T) {
+	server := newTestServer(t, nil)
+	alice := server.login(t, "alice")
+
+	if _, err := server.rooms.CreateRoomWithOptions("standup", "alice", room.CreateOptions{InviteOnly: true, Password: "open sesame"}); err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	for _, step := range []error{
+		server.rooms.InviteUser("standup", "alice", "bobby"),
+		server.rooms.SetTopic("standup", "alice", "daily sync"),
+		server.rooms.SetRetention("standup", "alice", 48*time.Hour),
+	} {
+		if step != nil {
+			t.Fatalf("configure room: %v", step)
+		}
+	}
+	server.roles.SetRoom("standup", "carol", userPkg.RoleModerator)
+
+	var pinnedID string
+	for i, content := range []string{"agenda", "notes", "action items"} {
+		msg := &messagePkg.Message{Type: "message", Content: content, Sender: "alice", Username: "alice",
+			RoomName: "standup", Timestamp: time.Now().Add(time.Duration(i) * time.Second)}
+		if err := server.messages.SaveMessage(msg); err != nil {
+			t.Fatalf("save message: %v", err)
+		}
+		if i == 0 {
+			pinnedID = msg.ID
+		}
+	}
+	if err := server.rooms.PinMessage("standup", "alice", pinnedID); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	// คัดลอกแค่ข้อความล่าสุด ข้อความที่ปักหมุดไว้ก่อนหน้าต้องตามไปด้วย
+	alice.send(map[string]interface{}{"type": "command", "content": "/clone standup standup-2 --messages 1"})
+	for {
+		if content, _ := alice.expect("system")["content"].(string); strings.Contains(content, "cloned from") {
+			break
+		}
+	}
+
+	cloned, exists := server.rooms.GetRoom("standup-2")
+	if !exists {
+		t.Fatal("clone was not created")
+	}
+	if !cloned.IsPrivate || cloned.PasswordHash == "" || !cloned.IsMember("bobby") {
+		t.Fatalf("clone access = invite-only %v, password %v, bobby invited %v; want all copied",
+			cloned.IsPrivate, cloned.PasswordHash != "", cloned.IsMember("bobby"))
+	}
+	if server.rooms.AuthorizeJoin("standup-2", "mallory", "open sesame") != nil {
+		t.Fatal("clone does not accept the source room password")
+	}
+	if cloned.Topic != "daily sync" || cloned.Retention != 48*time.Hour {
+		t.Fatalf("clone details = %q, %v", cloned.Topic, cloned.Retention)
+	}
+	if role := server.roles.Room("standup-2", "carol"); role != userPkg.RoleModerator {
+		t.Fatalf("carol in clone = %v, want moderator", role)
+	}
+	if len(cloned.Pinned) != 1 || cloned.Pinned[0].MessageID == pinnedID {
+		t.Fatalf("clone pins = %+v, want a pin of the copied message", cloned.Pinned)
+	}
+	pinned, err := server.messages.GetMessage(cloned.Pinned[0].MessageID)
+	if err != nil || pinned.Content != "agenda" || pinned.RoomName != "standup-2" {
+		t.Fatalf("pinned copy = %+v, %v", pinned, err)
+	}
+	history, err := server.messages.GetMessageHistory("standup-2", 10)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("clone history = %d messages (%v), want the last message and the pinned one", len(history), err)
+	}
+}
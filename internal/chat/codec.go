@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"realtime-chat/internal/msgpack"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// Codec converts the client/server envelope between wire frames and messages.
+// ServerMessage ถูก marshal เป็น JSON ก่อนเข้าคิวเสมอ (FrameGuard, trace, broadcast ทำงานกับ JSON)
+// codec จึงแปลงจาก JSON เป็น wire format ตอน write pump ผ่าน wsocket.FrameEncoder
+type Codec interface {
+	wsocket.FrameEncoder
+	Name() string
+	DecodeFrame(frame []byte, msg *ClientMessage) error
+}
+
+// codecFor returns the codec of a negotiated subprotocol
+func codecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+// jsonCodec is the default codec: frames are JSON text as-is
+type jsonCodec struct{}
+
+// Name returns the codec name
+func (jsonCodec) Name() string { return "json" }
+
+// MessageType returns the WebSocket frame type
+func (jsonCodec) MessageType() int { return websocket.TextMessage }
+
+// EncodeFrame returns the JSON frame unchanged
+func (jsonCodec) EncodeFrame(frame []byte) ([]byte, error) { return frame, nil }
+
+// DecodeFrame parses a JSON envelope
+func (jsonCodec) DecodeFrame(frame []byte, msg *ClientMessage) error {
+	return json.Unmarshal(frame, msg)
+}
+
+// msgpackCodec carries the same envelope as MessagePack in binary frames, for bots that send at high rates
+type msgpackCodec struct{}
+
+// Name returns the codec name
+func (msgpackCodec) Name() string { return "msgpack" }
+
+// MessageType returns the WebSocket frame type
+func (msgpackCodec) MessageType() int { return websocket.BinaryMessage }
+
+// EncodeFrame converts a queued JSON frame to MessagePack.
+// frame ในคิวเป็น JSON ที่ใช้ร่วมกันทุกผู้รับของ broadcast จึงแปลงจาก JSON ที่นี่ที่เดียว
+// frame ที่ไม่ใช่ JSON (เช่นข้อความ plain text จาก broadcast เดิม) ส่งเป็น MessagePack string
+func (msgpackCodec) EncodeFrame(frame []byte) ([]byte, error) {
+	if encoded, err := msgpack.FromJSON(frame); err == nil {
+		return encoded, nil
+	}
+	return msgpack.Marshal(string(frame))
+}
+
+// DecodeFrame decodes a MessagePack map straight into a client message using the json tags of ClientMessage
+func (msgpackCodec) DecodeFrame(frame []byte, msg *ClientMessage) error {
+	if len(frame) == 0 || (frame[0]&0xf0 != 0x80 && frame[0] != 0xde && frame[0] != 0xdf) {
+		return fmt.Errorf("msgpack envelope must be a map")
+	}
+	return msgpack.Decode(frame, msg)
+}
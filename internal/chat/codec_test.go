@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/msgpack"
+)
+
+// envelope ที่ bot ส่งบ่อย มี field หลายชนิด (string, int, slice, pointer, time)
+var codecEnvelope = map[string]interface{}{
+	"type":          "search_messages",
+	"query":         "deploy failed",
+	"room":          "ops",
+	"limit":         50,
+	"ids":           []string{"101", "102", "103"},
+	"start_date":    "2026-03-08T07:00:00Z",
+	"display_name":  "Ops Bot",
+	"client_msg_id": "bot-42",
+}
+
+func encodedEnvelope(tb testing.TB) (jsonFrame, msgpackFrame []byte) {
+	tb.Helper()
+	jsonFrame, err := json.Marshal(codecEnvelope)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	msgpackFrame, err = msgpack.FromJSON(jsonFrame)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return jsonFrame, msgpackFrame
+}
+
+// ทั้งสอง codec ต้องได้ ClientMessage เดียวกันจาก envelope เดียวกัน
+func TestCodecsDecodeSameEnvelope(t *testing.T) {
+	jsonFrame, msgpackFrame := encodedEnvelope(t)
+
+	var fromJSON, fromMsgpack ClientMessage
+	if err := (jsonCodec{}).DecodeFrame(jsonFrame, &fromJSON); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if err := (msgpackCodec{}).DecodeFrame(msgpackFrame, &fromMsgpack); err != nil {
+		t.Fatalf("msgpack: %v", err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromMsgpack) {
+		t.Fatalf("codecs disagree:\n json    %+v\n msgpack %+v", fromJSON, fromMsgpack)
+	}
+	if fromMsgpack.Limit != 50 || len(fromMsgpack.IDs) != 3 || fromMsgpack.StartDate == nil || *fromMsgpack.DisplayName != "Ops Bot" {
+		t.Fatalf("fields not decoded: %+v", fromMsgpack)
+	}
+}
+
+func TestMsgpackDecodeRejectsNonMap(t *testing.T) {
+	frame, _ := msgpack.Marshal("message")
+	var msg ClientMessage
+	if err := (msgpackCodec{}).DecodeFrame(frame, &msg); err == nil {
+		t.Fatal("decoded a string envelope")
+	}
+}
+
+func BenchmarkJSONCodecDecode(b *testing.B) {
+	frame, _ := encodedEnvelope(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var msg ClientMessage
+		if err := (jsonCodec{}).DecodeFrame(frame, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMsgpackCodecDecode(b *testing.B) {
+	_, frame := encodedEnvelope(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var msg ClientMessage
+		if err := (msgpackCodec{}).DecodeFrame(frame, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// serverFrame is a typical queued broadcast frame
+func serverFrame(b *testing.B) []byte {
+	frame, err := json.Marshal(ServerMessage{
+		Type:      "users_list",
+		Room:      "ops",
+		Users:     []string{"alice", "bobby", "carol", "dave"},
+		Total:     4,
+		Message:   "4 users in ops",
+		Timestamp: time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return frame
+}
+
+func BenchmarkJSONCodecEncode(b *testing.B) {
+	frame := serverFrame(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := (jsonCodec{}).EncodeFrame(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMsgpackCodecEncode(b *testing.B) {
+	frame := serverFrame(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := (msgpackCodec{}).EncodeFrame(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// bot ที่ใช้ chat.v1.msgpack ส่งและรับ envelope เป็น binary frame ตลอดทาง
+func TestMsgpackClientRoundTrip(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.EnableMsgpack = true
+	})
+	alice := server.join(t, "alice")
+
+	dialer := websocket.Dialer{Subprotocols: []string{SubprotocolMsgpack}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != SubprotocolMsgpack {
+		t.Fatalf("negotiated %q", conn.Subprotocol())
+	}
+
+	send := func(envelope map[string]interface{}) {
+		frame, err := msgpack.Marshal(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(frameType string) map[string]interface{} {
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if messageType != websocket.BinaryMessage {
+				t.Fatalf("got a text frame %q", data)
+			}
+			value, err := msgpack.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("frame is not msgpack: %v", err)
+			}
+			if envelope, ok := value.(map[string]interface{}); ok && envelope["type"] == frameType {
+				return envelope
+			}
+		}
+	}
+
+	send(map[string]interface{}{"type": "join", "username": "bot"})
+	expect("session")
+
+	send(map[string]interface{}{"type": "message", "content": "from bot"})
+	if got := alice.expect("message"); got["content"] != "from bot" || got["username"] != "bot" {
+		t.Fatalf("alice got %v", got)
+	}
+
+	alice.send(map[string]interface{}{"type": "message", "content": "hi bot"})
+	if got := expect("message"); got["content"] != "hi bot" || got["id"] == nil {
+		t.Fatalf("bot got %v", got)
+	}
+}
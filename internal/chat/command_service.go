@@ -2,13 +2,22 @@ package chat
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/commands"
 	"realtime-chat/internal/config"
 	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/naming"
+	"realtime-chat/internal/presence"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/search"
+	"realtime-chat/internal/security"
 	userPkg "realtime-chat/internal/user"
 )
 
@@ -22,6 +31,24 @@ type commandService struct {
 	config          *config.ServerConfig
 	configManager   *config.ConfigManager
 	messageRepo     MessageRepository
+	searchIndex     SearchIndex
+	throttle        *security.ConnectionThrottle
+	lockout         *security.LoginLockout
+	ipLimiter       *security.IPLimiter
+	churn           *security.ChurnLimiter
+	presenceTracker *presence.Tracker
+	honeypot        *moderation.Honeypot
+	auditLog        *moderation.AuditLog
+	latency         *config.LatencyRecorder
+	roomNames       *naming.Generator
+	metricsHistory  MetricsHistory
+	announcements   *announcement.Store
+	directRepo      messagePkg.DirectMessageRepository
+	mailbox         messagePkg.MailboxRepository // Optional queue for DMs to offline users
+	userRouter      UserRouter                   // Optional cluster routing of DMs to users on other nodes
+	migrations      MigrationRunner
+	roles           *userPkg.RoleStore // Optional roles assigned with /setrole (nil = admins from config, everyone else member)
+	shutdown        *shutdownTimer     // Optional /shutdown countdown
 	commands        map[string]*Command
 }
 
@@ -57,6 +84,11 @@ func (s *commandService) SetMessageRepository(repo MessageRepository) {
 	s.messageRepo = repo
 }
 
+// SetSearchIndex sets the external search index
+func (s *commandService) SetSearchIndex(index SearchIndex) {
+	s.searchIndex = index
+}
+
 // RegisterCommand registers a new command
 func (s *commandService) RegisterCommand(cmd *Command) {
 	s.commands[cmd.Name] = cmd
@@ -75,9 +107,29 @@ func (s *commandService) ExecuteCommand(conn Connection, message string) error {
 
 	// Find and execute command
 	if cmd, exists := s.commands[commandName]; exists {
+		if !s.hasCapability(cmd.Requires) {
+			return fmt.Errorf("/%s is not available on this server", commandName)
+		}
+		// ตรวจสิทธิ์ที่เดียวก่อนเรียก handler ตาม role ของผู้ใช้ในห้องปัจจุบัน
+		if cmd.RequiredRole > RoleGuest {
+			chatUser, _ := conn.GetUser().(*userPkg.User)
+			if chatUser == nil {
+				return fmt.Errorf("user not authenticated")
+			}
+			if role := s.roleOf(chatUser); role < cmd.RequiredRole {
+				connLogger(conn).Warn("⛔ Denied command", "command", commandName, "requires", cmd.RequiredRole.String(), "role", role.String())
+				return fmt.Errorf("/%s requires the %s role (you are %s here)", commandName, cmd.RequiredRole, role)
+			}
+		}
+		connLogger(conn).Debug("⚙️ Command", "command", commandName)
 		return cmd.Handler(conn, args)
 	}
 
+	// คำสั่งกับดัก ตอบเหมือนคำสั่งที่ไม่มีอยู่จริง เพื่อไม่ให้ bot รู้ตัว
+	if s.honeypot != nil && s.honeypot.IsTrapCommand(commandName) {
+		tripHoneypot(s.honeypot, conn, "/"+commandName)
+	}
+
 	return fmt.Errorf("unknown command: /%s", commandName)
 }
 
@@ -115,8 +167,8 @@ func (s *commandService) registerDefaultCommands() {
 	// Join command
 	s.RegisterCommand(&Command{
 		Name:        "join",
-		Description: "Join a room",
-		Usage:       "/join <room_name>",
+		Description: "Join a room (password-protected rooms need the password unless you were invited)",
+		Usage:       "/join <room_name> [password]",
 		Handler:     s.handleJoin,
 	})
 
@@ -130,10 +182,91 @@ func (s *commandService) registerDefaultCommands() {
 
 	// Create command
 	s.RegisterCommand(&Command{
-		Name:        "create",
-		Description: "Create a new room",
-		Usage:       "/create <room_name>",
-		Handler:     s.handleCreate,
+		Name:         "create",
+		Description:  "Create a new room (--private: invite-only and hidden from /rooms, --password: join with a password)",
+		Usage:        "/create <room_name> [--max <users>] [--ttl <duration>] [--private] [--password <password>]",
+		RequiredRole: RoleMember,
+		Handler:      s.handleCreate,
+	})
+
+	// Set room capacity command
+	s.RegisterCommand(&Command{
+		Name:         "setmax",
+		Description:  "Change current room capacity (room owner only)",
+		Usage:        "/setmax <users>",
+		RequiredRole: RoleOwner,
+		Handler:      s.handleSetMax,
+	})
+
+	// Mirror mode command
+	s.RegisterCommand(&Command{
+		Name:         "mirror",
+		Description:  "Make the current room a broadcast mirror written from this node, or switch it back (room owner only)",
+		Usage:        "/mirror on|off",
+		RequiredRole: RoleOwner,
+		Requires:     CapabilityChangeFeed,
+		Handler:      s.handleMirror,
+	})
+
+	// Invite command
+	s.RegisterCommand(&Command{
+		Name:         "invite",
+		Description:  "Let a user join this invite-only or password-protected room (room owner only)",
+		Usage:        "/invite <username>",
+		RequiredRole: RoleOwner,
+		Handler:      s.handleInvite,
+	})
+
+	// Message retention command
+	s.RegisterCommand(&Command{
+		Name:         "retention",
+		Description:  "Show or set how long this room's messages are kept (room owner only)",
+		Usage:        "/retention [<duration>|default]",
+		RequiredRole: RoleOwner,
+		Requires:     CapabilityPersistence,
+		Handler:      s.handleRetention,
+	})
+
+	// Privacy mode command
+	s.RegisterCommand(&Command{
+		Name:         "private",
+		Description:  "Turn privacy mode on or off: messages are delivered but never stored, indexed or counted (room owner only)",
+		Usage:        "/private on|off",
+		RequiredRole: RoleOwner,
+		Handler:      s.handlePrivate,
+	})
+
+	// Timezone command
+	s.RegisterCommand(&Command{
+		Name:        "tz",
+		Description: "Show or set the timezone used for times in command output",
+		Usage:       "/tz [<IANA zone, e.g. Asia/Bangkok>|reset]",
+		Handler:     s.handleTimezone,
+	})
+
+	// Clone command
+	s.RegisterCommand(&Command{
+		Name:         "clone",
+		Description:  "Create a new room with the settings (and optionally recent messages) of an existing room",
+		Usage:        "/clone <source> <dest> [--messages <n>]",
+		RequiredRole: RoleMember,
+		Handler:      s.handleClone,
+	})
+
+	// Direct message commands
+	s.RegisterCommand(&Command{
+		Name:        "msg",
+		Description: "Send a private message to an online user",
+		Usage:       "/msg <user> <text>",
+		Handler:     s.handleMsg,
+	})
+
+	s.RegisterCommand(&Command{
+		Name:        "dm-history",
+		Description: "Show your private conversation with a user",
+		Usage:       "/dm-history <user> [limit]",
+		Requires:    CapabilityDirectHistory,
+		Handler:     s.handleDMHistory,
 	})
 
 	// Stats command
@@ -144,46 +277,121 @@ func (s *commandService) registerDefaultCommands() {
 		Handler:     s.handleStats,
 	})
 
-	// History command (if MongoDB is enabled)
-	if s.config.EnableMongoDB {
-		s.RegisterCommand(&Command{
-			Name:        "history",
-			Description: "Get message history for current room",
-			Usage:       "/history [limit]",
-			Handler:     s.handleHistory,
-		})
+	// History command (needs message persistence)
+	s.RegisterCommand(&Command{
+		Name:        "history",
+		Description: "Get message history for current room",
+		Usage:       "/history [limit]",
+		Requires:    CapabilityPersistence,
+		Handler:     s.handleHistory,
+	})
+
+	// Search command (MongoDB text search or external search index)
+	s.RegisterCommand(&Command{
+		Name:        "search",
+		Description: "Search messages in current room (filters: user:<name> room:<name> exact:<word>)",
+		Usage:       "/search <query>",
+		Requires:    CapabilitySearch,
+		Handler:     s.handleSearch,
+	})
+}
+
+// hasCapability reports whether the server currently provides a capability commands may require
+func (s *commandService) hasCapability(capability string) bool {
+	switch capability {
+	case "":
+		return true
+	case CapabilityPersistence:
+		return s.messageRepo != nil
+	case CapabilitySearch:
+		return s.messageRepo != nil || s.searchIndex != nil
+	case CapabilityChangeFeed:
+		return s.config.EnableChangeFeed && s.messageRepo != nil
+	case CapabilityDirectHistory:
+		return s.directRepo != nil
+	default:
+		return false
+	}
+}
 
-		s.RegisterCommand(&Command{
-			Name:        "search",
-			Description: "Search messages in current room",
-			Usage:       "/search <query>",
-			Handler:     s.handleSearch,
+// DescribeCommands returns autocomplete metadata for the commands available on this server, sorted by name.
+// user != nil กรองเหมือน /help (ตาม role ในห้องปัจจุบัน); nil = ทุกคำสั่งพร้อม role ที่ต้องมี
+func (s *commandService) DescribeCommands(user *userPkg.User) []commands.Info {
+	infos := make([]commands.Info, 0, len(s.commands))
+	for _, cmd := range s.commands {
+		if !s.hasCapability(cmd.Requires) || (user != nil && !s.canSee(cmd, user)) {
+			continue
+		}
+		infos = append(infos, commands.Info{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Usage:       cmd.Usage,
+			Args:        commands.ParseUsage(cmd.Usage),
+			Role:        cmd.RequiredRole.String(),
+			Requires:    cmd.Requires,
 		})
 	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// canSee reports whether a command should be listed for the user in /help
+func (s *commandService) canSee(cmd *Command, user *userPkg.User) bool {
+	if !s.hasCapability(cmd.Requires) {
+		return false
+	}
+
+	if cmd.RequiredRole == RoleGuest {
+		return true
+	}
+	return user != nil && s.roleOf(user) >= cmd.RequiredRole
 }
 
 // Command handlers
 
 func (s *commandService) handleHelp(conn Connection, args []string) error {
+	chatUser, _ := conn.GetUser().(*userPkg.User)
+
+	// แสดงเฉพาะคำสั่งที่ผู้ใช้มีสิทธิ์และ server รองรับ แยกตาม role
+	sections := []struct {
+		role  CommandRole
+		title string
+	}{
+		{RoleMember, "📋 Available Commands:"}, // รวมคำสั่งที่ guest ใช้ได้
+		{RoleModerator, "🧹 Moderator Commands:"},
+		{RoleOwner, "🏠 Room Owner Commands:"},
+		{RoleAdmin, "🛡️ Admin Commands:"},
+	}
+
 	var helpText strings.Builder
-	helpText.WriteString("📋 Available Commands:\n")
+	for _, section := range sections {
+		visible := make([]*Command, 0)
+		for _, cmd := range s.commands {
+			if max(cmd.RequiredRole, RoleMember) == section.role && s.canSee(cmd, chatUser) {
+				visible = append(visible, cmd)
+			}
+		}
+		if len(visible) == 0 {
+			continue
+		}
+		sort.Slice(visible, func(i, j int) bool { return visible[i].Name < visible[j].Name })
 
-	for _, cmd := range s.commands {
-		helpText.WriteString(fmt.Sprintf("• %s - %s\n", cmd.Usage, cmd.Description))
+		if helpText.Len() > 0 {
+			helpText.WriteString("\n")
+		}
+		helpText.WriteString(section.title + "\n")
+		for _, cmd := range visible {
+			helpText.WriteString(fmt.Sprintf("• %s - %s\n", cmd.Usage, cmd.Description))
+		}
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   helpText.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  "",
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: helpText.String(),
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleUsers(conn Connection, args []string) error {
@@ -205,46 +413,66 @@ func (s *commandService) handleUsers(conn Connection, args []string) error {
 	var userList strings.Builder
 	userList.WriteString(fmt.Sprintf("👥 Users in room '%s' (%d users):\n", chatUser.CurrentRoom, len(users)))
 
+	names := make([]string, 0, len(users))
+	members := make([]RoomMember, 0, len(users))
 	for _, u := range users {
-		userList.WriteString(fmt.Sprintf("• %s\n", u.Username))
+		names = append(names, u.Username)
+		member := RoomMember{Username: u.Username, LastActive: u.LastActive}
+		if s.presenceTracker != nil {
+			state := s.presenceTracker.Get(u.Username)
+			member.Status = string(state)
+			userList.WriteString(fmt.Sprintf("• %s %s (%s)\n", presenceIcon(state), u.Username, state))
+		} else {
+			userList.WriteString(fmt.Sprintf("• %s\n", u.Username))
+		}
+		members = append(members, member)
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   userList.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  chatUser.CurrentRoom,
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: userList.String(),
+		Room:    chatUser.CurrentRoom,
+		Users:   names,
+		Members: members,
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleRooms(conn Connection, args []string) error {
-	rooms := s.roomService.GetRooms()
+	username := ""
+	if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+		username = chatUser.Username
+	}
+
+	// ห้อง invite-only แสดงเฉพาะกับสมาชิก
+	rooms := make([]*room.Room, 0)
+	for _, chatRoom := range s.roomService.GetRooms() {
+		if chatRoom.VisibleTo(username) {
+			rooms = append(rooms, chatRoom)
+		}
+	}
+
 	var roomList strings.Builder
 	roomList.WriteString(fmt.Sprintf("🏠 Available rooms (%d rooms):\n", len(rooms)))
 
 	for _, room := range rooms {
 		userCount := len(room.Users)
-		roomList.WriteString(fmt.Sprintf("• %s (%d/%d users)\n", room.Name, userCount, room.MaxUsers))
+		marker := ""
+		if room.IsPrivate {
+			marker += " 🔒"
+		}
+		if room.PasswordHash != "" {
+			marker += " 🔑"
+		}
+		roomList.WriteString(fmt.Sprintf("• %s%s (%d/%d users)\n", room.Name, marker, userCount, room.MaxUsers))
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   roomList.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  "",
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: roomList.String(),
+		Rooms:   summarizeRooms(rooms, username),
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleJoin(conn Connection, args []string) error {
@@ -264,10 +492,29 @@ func (s *commandService) handleJoin(conn Connection, args []string) error {
 
 	roomName := args[0]
 
+	// ห้องกับดัก ตอบเหมือนห้องที่ไม่มีอยู่จริง
+	if s.honeypot != nil && s.honeypot.IsTrapRoom(roomName) {
+		tripHoneypot(s.honeypot, conn, "room:"+roomName)
+		return fmt.Errorf("failed to join room '%s': room '%s' does not exist", roomName, roomName)
+	}
+
+	if err := checkRoomSwitch(s.churn, chatUser.Username); err != nil {
+		return err
+	}
+
+	// ตรวจสิทธิ์ก่อนออกจากห้องเดิม ผู้ใช้ที่ใส่รหัสผิดจะได้ยังอยู่ในห้องเดิม
+	password := ""
+	if len(args) > 1 {
+		password = args[1]
+	}
+	if err := s.roomService.AuthorizeJoin(roomName, chatUser.Username, password); err != nil {
+		return fmt.Errorf("failed to join room '%s': %v", roomName, err)
+	}
+
 	// Leave current room if in one
 	if chatUser.CurrentRoom != "" {
 		if err := s.roomService.LeaveRoom(chatUser, chatUser.CurrentRoom); err != nil {
-			log.Printf("⚠️ Failed to leave current room: %v", err)
+			connLogger(conn).Warn("⚠️ Failed to leave current room", "error", err)
 		}
 	}
 
@@ -276,13 +523,15 @@ func (s *commandService) handleJoin(conn Connection, args []string) error {
 		return fmt.Errorf("failed to join room '%s': %v", roomName, err)
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   fmt.Sprintf("✅ Joined room '%s'", roomName),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  roomName,
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: fmt.Sprintf("✅ Joined room '%s'", roomName),
+		Room:    roomName,
+		Pinned:  pinnedMessages(s.roomService, s.messageRepo, roomName),
+	}
+	if joinedRoom, exists := s.roomService.GetRoom(roomName); exists && joinedRoom.Topic != "" {
+		reply.Content += fmt.Sprintf("\n📌 %s", joinedRoom.Topic)
+		reply.Topic = joinedRoom.Topic
+		reply.Description = joinedRoom.Description
 	}
 
 	// Notify others in the room
@@ -290,15 +539,14 @@ func (s *commandService) handleJoin(conn Connection, args []string) error {
 		Type:      "user_joined",
 		Content:   fmt.Sprintf("%s joined the room", chatUser.Username),
 		Sender:    "System",
-		Username:  "System",
+		Username:  chatUser.Username, // client แสดงและอัปเดตรายชื่อจาก username
 		RoomName:  roomName,
 		Timestamp: time.Now(),
 	}
 
 	s.messageService.BroadcastToRoom(joinMsg, conn.GetID(), roomName)
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		message.Content, message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleLeave(conn Connection, args []string) error {
@@ -318,18 +566,17 @@ func (s *commandService) handleLeave(conn Connection, args []string) error {
 
 	roomName := chatUser.CurrentRoom
 
+	if err := checkRoomSwitch(s.churn, chatUser.Username); err != nil {
+		return err
+	}
+
 	// Leave room
 	if err := s.roomService.LeaveRoom(chatUser, roomName); err != nil {
 		return fmt.Errorf("failed to leave room: %v", err)
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   fmt.Sprintf("✅ Left room '%s'", roomName),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  "",
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: fmt.Sprintf("✅ Left room '%s'", roomName),
 	}
 
 	// Notify others in the room
@@ -337,20 +584,53 @@ func (s *commandService) handleLeave(conn Connection, args []string) error {
 		Type:      "user_left",
 		Content:   fmt.Sprintf("%s left the room", chatUser.Username),
 		Sender:    "System",
-		Username:  "System",
+		Username:  chatUser.Username, // client แสดงและอัปเดตรายชื่อจาก username
 		RoomName:  roomName,
 		Timestamp: time.Now(),
 	}
 
 	s.messageService.BroadcastToRoom(leaveMsg, conn.GetID(), roomName)
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		message.Content, message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleCreate(conn Connection, args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("room name required. Usage: /create <room_name>")
+	args, flags := parseCommandFlags(args)
+	// --private ไม่มีค่า ถ้ามีคำตามมา (เช่น /create --private dev) คำนั้นคือชื่อห้อง
+	if value, exists := flags["private"]; exists && value != "true" {
+		args = append([]string{value}, args...)
+		flags["private"] = "true"
+	}
+	// ห้องชั่วคราวไม่ต้องตั้งชื่อเองได้ ถ้าเปิดใช้ name generator
+	_, temporary := flags["ttl"]
+	if len(args) == 0 && !(temporary && s.roomNames != nil) {
+		return fmt.Errorf("room name required. Usage: /create <room_name> [--max <users>] [--ttl <duration>]")
+	}
+
+	opts := room.CreateOptions{}
+	if value, exists := flags["max"]; exists {
+		maxUsers, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid --max value '%s'", value)
+		}
+		opts.MaxUsers = maxUsers
+	}
+	if value, exists := flags["ttl"]; exists {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid --ttl value '%s' (e.g. 30m, 2h)", value)
+		}
+		if ttl > s.config.MaxRoomTTL {
+			return fmt.Errorf("room TTL cannot exceed %v", s.config.MaxRoomTTL)
+		}
+		opts.TTL = ttl
+	}
+	_, opts.InviteOnly = flags["private"]
+	if value, exists := flags["password"]; exists {
+		if value == "true" {
+			return fmt.Errorf("--password needs a value")
+		}
+		opts.Password = value
 	}
 
 	user := conn.GetUser()
@@ -363,25 +643,491 @@ func (s *commandService) handleCreate(conn Connection, args []string) error {
 		return fmt.Errorf("invalid user type")
 	}
 
-	roomName := args[0]
+	var roomName string
+	if len(args) > 0 {
+		roomName = args[0]
+	} else {
+		generated, err := s.roomNames.Generate(func(name string) bool {
+			_, exists := s.roomService.GetRoom(name)
+			return exists || (s.honeypot != nil && s.honeypot.IsTrapRoom(name))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create room: %v", err)
+		}
+		roomName = generated
+	}
+
+	// ชื่อห้องกับดักสงวนไว้ ห้ามผู้ใช้สร้างทับ
+	if s.honeypot != nil && s.honeypot.IsTrapRoom(roomName) {
+		return fmt.Errorf("failed to create room '%s': room '%s' already exists", roomName, roomName)
+	}
 
 	// Create room
-	_, err := s.roomService.CreateRoom(roomName, chatUser.Username)
+	createdRoom, err := s.roomService.CreateRoomWithOptions(roomName, chatUser.Username, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create room '%s': %v", roomName, err)
 	}
 
-	message := &messagePkg.Message{
+	content := fmt.Sprintf("✅ Room '%s' created successfully (max %d users)", roomName, createdRoom.MaxUsers)
+	if createdRoom.ExpiresAt != nil {
+		content = fmt.Sprintf("✅ Room '%s' created successfully (max %d users, expires in %v)", roomName, createdRoom.MaxUsers, opts.TTL)
+	}
+	if createdRoom.Reactivated {
+		content = fmt.Sprintf("♻️ Room '%s' was archived and has been restored with its history (max %d users)", roomName, createdRoom.MaxUsers)
+		if createdRoom.ExpiresAt != nil {
+			content += fmt.Sprintf("\n⏳ It expires in %v", opts.TTL)
+		}
+		if !strings.EqualFold(createdRoom.CreatedBy, chatUser.Username) && opts != (room.CreateOptions{}) {
+			content += fmt.Sprintf("\n👑 %s still owns it, so the options you gave were not applied", createdRoom.CreatedBy)
+		}
+	}
+	if createdRoom.IsPrivate {
+		content += "\n🔒 The room is invite-only and hidden from /rooms; use /invite <username> inside it"
+	}
+	if createdRoom.PasswordHash != "" {
+		content += "\n🔑 Others join with /join " + roomName + " <password>"
+	}
+
+	reply := ServerMessage{
+		Content: content,
+	}
+
+	return replyCommand(conn, reply)
+}
+
+// SetNameGenerator enables generated names for temporary rooms created without a name
+func (s *commandService) SetNameGenerator(generator *naming.Generator) {
+	s.roomNames = generator
+	if cmd, exists := s.commands["create"]; exists {
+		cmd.Usage = "/create [<room_name>] [--max <users>] [--ttl <duration>] [--private] [--password <password>]"
+	}
+}
+
+// SetMetricsHistory enables hour/day trends in /stats
+func (s *commandService) SetMetricsHistory(history MetricsHistory) {
+	s.metricsHistory = history
+}
+
+func (s *commandService) handleSetMax(conn Connection, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("capacity required. Usage: /setmax <users>")
+	}
+
+	user := conn.GetUser()
+	if user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	chatUser, ok := user.(*userPkg.User)
+	if !ok {
+		return fmt.Errorf("invalid user type")
+	}
+
+	if chatUser.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	maxUsers, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid capacity '%s'", args[0])
+	}
+
+	if err := s.roomService.SetMaxUsers(chatUser.CurrentRoom, chatUser.Username, maxUsers); err != nil {
+		return fmt.Errorf("failed to set capacity: %v", err)
+	}
+
+	reply := ServerMessage{
+		Content: fmt.Sprintf("✅ Room '%s' capacity set to %d users", chatUser.CurrentRoom, maxUsers),
+		Room:    chatUser.CurrentRoom,
+	}
+
+	return replyCommand(conn, reply)
+}
+
+func (s *commandService) handleMirror(conn Connection, args []string) error {
+	if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: /mirror on|off")
+	}
+
+	// fan-out ของห้อง mirror มาจาก change feed จึงต้องเปิด MongoDB และ change feed
+	if !s.config.EnableChangeFeed || s.messageRepo == nil {
+		return fmt.Errorf("mirror mode requires MongoDB with the change feed enabled")
+	}
+
+	user := conn.GetUser()
+	if user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	chatUser, ok := user.(*userPkg.User)
+	if !ok {
+		return fmt.Errorf("invalid user type")
+	}
+
+	if chatUser.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	nodeID := ""
+	if args[0] == "on" {
+		nodeID = s.config.NodeID
+	}
+
+	if err := s.roomService.SetMirrorWriter(chatUser.CurrentRoom, chatUser.Username, nodeID); err != nil {
+		return fmt.Errorf("failed to change mirror mode: %v", err)
+	}
+
+	if nodeID == "" {
+		return replySystem(conn, fmt.Sprintf("✅ Room '%s' is a normal room again", chatUser.CurrentRoom))
+	}
+	return replySystem(conn, fmt.Sprintf("🪞 Room '%s' is now a broadcast mirror; posts are accepted on node %s only", chatUser.CurrentRoom, nodeID))
+}
+
+func (s *commandService) handlePrivate(conn Connection, args []string) error {
+	if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: /private on|off")
+	}
+
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if chatUser.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	private := args[0] == "on"
+	if err := s.roomService.SetPrivate(chatUser.CurrentRoom, chatUser.Username, private); err != nil {
+		return fmt.Errorf("failed to change privacy mode: %v", err)
+	}
+
+	content := fmt.Sprintf("🔓 Room '%s' left privacy mode; new messages are stored again", chatUser.CurrentRoom)
+	if private {
+		content = fmt.Sprintf("🔒 Room '%s' is now in privacy mode; new messages are delivered only, never stored or indexed", chatUser.CurrentRoom)
+	}
+
+	// แจ้งสมาชิกทุกคนในห้อง เพราะเปลี่ยนว่าข้อความของพวกเขาจะถูกเก็บหรือไม่
+	s.messageService.BroadcastToRoom(&messagePkg.Message{
+		Type:      "system",
+		Content:   content,
+		Sender:    "System",
+		Username:  "System",
+		RoomName:  chatUser.CurrentRoom,
+		Timestamp: time.Now(),
+	}, conn.GetID(), chatUser.CurrentRoom)
+
+	return replySystem(conn, content)
+}
+
+func (s *commandService) handleInvite(conn Connection, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("username required. Usage: /invite <username>")
+	}
+
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if chatUser.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	// ใช้ชื่อตามที่ผู้ใช้ลงทะเบียนไว้ถ้าออนไลน์อยู่
+	invitee := args[0]
+	online, isOnline := s.userService.GetUserByName(invitee)
+	if isOnline {
+		invitee = online.Username
+	}
+
+	if err := s.roomService.InviteUser(chatUser.CurrentRoom, chatUser.Username, invitee); err != nil {
+		return fmt.Errorf("failed to invite %s: %v", invitee, err)
+	}
+
+	if isOnline {
+		if inviteeConn, exists := s.wsManager.GetConnection(online.ConnID); exists {
+			replySystem(inviteeConn, fmt.Sprintf("✉️ %s invited you to room '%s'. Use /join %s", chatUser.Username, chatUser.CurrentRoom, chatUser.CurrentRoom))
+		}
+	}
+
+	return replySystem(conn, fmt.Sprintf("✉️ %s can now join room '%s'", invitee, chatUser.CurrentRoom))
+}
+
+func (s *commandService) handleRetention(conn Connection, args []string) error {
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if chatUser.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	chatRoom, exists := s.roomService.GetRoom(chatUser.CurrentRoom)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", chatUser.CurrentRoom)
+	}
+
+	if len(args) == 0 {
+		return replySystem(conn, fmt.Sprintf("🗓️ Messages in '%s' are kept %s", chatRoom.Name, s.describeRetention(chatRoom.Retention)))
+	}
+
+	retention := time.Duration(0)
+	if args[0] != "default" {
+		parsed, err := time.ParseDuration(args[0])
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid retention '%s' (e.g. 72h, 720h or default)", args[0])
+		}
+		retention = parsed
+	}
+
+	if err := s.roomService.SetRetention(chatRoom.Name, chatUser.Username, retention); err != nil {
+		return fmt.Errorf("failed to change retention: %v", err)
+	}
+
+	content := fmt.Sprintf("🗓️ Messages in '%s' are now kept %s", chatRoom.Name, s.describeRetention(retention))
+
+	// สมาชิกควรรู้ว่าข้อความของตัวเองจะถูกลบเมื่อไร
+	s.messageService.BroadcastToRoom(&messagePkg.Message{
 		Type:      "system",
-		Content:   fmt.Sprintf("✅ Room '%s' created successfully", roomName),
+		Content:   content,
 		Sender:    "System",
 		Username:  "System",
-		RoomName:  "",
+		RoomName:  chatRoom.Name,
 		Timestamp: time.Now(),
+	}, conn.GetID(), chatRoom.Name)
+
+	return replySystem(conn, content)
+}
+
+// describeRetention formats a room retention for users (0 = the server default)
+func (s *commandService) describeRetention(retention time.Duration) string {
+	if retention > 0 {
+		return fmt.Sprintf("for %v", retention)
+	}
+	if s.config.MessageRetention > 0 {
+		return fmt.Sprintf("for %v (server default)", s.config.MessageRetention)
+	}
+	return "forever (server default)"
+}
+
+func (s *commandService) handleTimezone(conn Connection, args []string) error {
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if len(args) == 0 {
+		if chatUser.Timezone == "" {
+			return replySystem(conn, fmt.Sprintf("🕒 Times are shown in server time (%s). Use /tz <zone> to change", time.Now().Format("MST -07:00")))
+		}
+		return replySystem(conn, fmt.Sprintf("🕒 Times are shown in %s (now %s)", chatUser.Timezone, chatUser.FormatTime(time.Now(), "15:04 MST")))
+	}
+
+	name := args[0]
+	if name == "reset" {
+		name = ""
+	}
+	if err := chatUser.SetTimezone(name); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return replySystem(conn, "✅ Times are shown in server time again")
+	}
+	return replySystem(conn, fmt.Sprintf("✅ Times are now shown in %s (now %s)", chatUser.Timezone, chatUser.FormatTime(time.Now(), "15:04 MST")))
+}
+
+// timezoneNote labels command output with the user's timezone when one is set
+func timezoneNote(user *userPkg.User) string {
+	if user.Timezone == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (times in %s)", user.Timezone)
+}
+
+func (s *commandService) handleClone(conn Connection, args []string) error {
+	args, flags := parseCommandFlags(args)
+	if len(args) < 2 {
+		return fmt.Errorf("source and destination required. Usage: /clone <source> <dest> [--messages <n>]")
+	}
+
+	messageCount := 0
+	if value, exists := flags["messages"]; exists {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid --messages value '%s'", value)
+		}
+		if count > s.config.MaxCloneMessages {
+			return fmt.Errorf("cannot copy more than %d messages", s.config.MaxCloneMessages)
+		}
+		if count > 0 && s.messageRepo == nil {
+			return fmt.Errorf("copying messages requires message history (MongoDB)")
+		}
+		messageCount = count
+	}
+
+	user := conn.GetUser()
+	if user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	chatUser, ok := user.(*userPkg.User)
+	if !ok {
+		return fmt.Errorf("invalid user type")
+	}
+
+	sourceName, destName := args[0], args[1]
+
+	source, exists := s.roomService.GetRoom(sourceName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", sourceName)
+	}
+
+	// คัดลอกได้เฉพาะเจ้าของห้องต้นทางหรือ admin
+	if effectiveRole(s.config, s.roles, s.roomService, chatUser, sourceName) < RoleOwner {
+		return fmt.Errorf("only the owner of '%s' can clone it", sourceName)
+	}
+
+	if s.honeypot != nil && s.honeypot.IsTrapRoom(destName) {
+		return fmt.Errorf("failed to clone room: room '%s' already exists", destName)
+	}
+
+	// ห้องใหม่เป็นห้องถาวรเสมอ ไม่สืบทอด TTL ของห้องต้นทาง แต่สืบทอดการจำกัดการเข้าถึงตั้งแต่ตอนสร้าง
+	// ถ้าตั้งไม่สำเร็จห้องจะถูก archive ทิ้ง ประวัติของห้องที่จำกัดจึงไม่หลุดไปอยู่ในห้องเปิด
+	cloned, err := s.roomService.CreateRoomWithOptions(destName, chatUser.Username, room.CreateOptions{
+		MaxUsers:     source.MaxUsers,
+		InviteOnly:   source.IsPrivate,
+		PasswordHash: source.PasswordHash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone room: %v", err)
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		message.Content, message.Timestamp.Format(time.RFC3339))))
+	logger := slog.With("source", sourceName, "room", destName, "by", chatUser.Username)
+	s.copyRoomSettings(logger, source, destName, chatUser.Username)
+
+	var copiedIDs map[string]string
+	if messageCount > 0 {
+		copiedIDs, err = s.copyMessages(sourceName, destName, messageCount)
+		if err != nil {
+			logger.Warn("⚠️ Failed to copy messages", "error", err)
+		}
+	}
+	copied := len(copiedIDs)
+	pins := s.copyPins(logger, source, destName, chatUser.Username, copiedIDs)
+	roles := s.roles.CopyRoom(sourceName, destName)
+
+	logger.Info("🧬 Room cloned", "messages", copied, "pins", pins, "roles", roles)
+
+	reply := ServerMessage{
+		Content: fmt.Sprintf("✅ Room '%s' cloned from '%s' (max %d users, %d messages, %d pins and %d room roles copied)",
+			destName, sourceName, cloned.MaxUsers, copied, pins, roles),
+	}
+
+	return replyCommand(conn, reply)
+}
+
+// copyRoomSettings copies the owner-set details and invitations of a room to its clone.
+// ทำต่อจากการสร้างห้องทีละอย่าง อย่างไหนไม่สำเร็จก็แค่ log ไว้ ห้องที่ได้ยังใช้งานได้
+func (s *commandService) copyRoomSettings(logger *slog.Logger, source *room.Room, destName, owner string) {
+	steps := []struct {
+		name  string
+		set   bool
+		apply func() error
+	}{
+		{"privacy mode", source.Private, func() error { return s.roomService.SetPrivate(destName, owner, true) }},
+		{"topic", source.Topic != "", func() error { return s.roomService.SetTopic(destName, owner, source.Topic) }},
+		{"description", source.Description != "", func() error { return s.roomService.SetDescription(destName, owner, source.Description) }},
+		{"retention", source.Retention > 0, func() error { return s.roomService.SetRetention(destName, owner, source.Retention) }},
+		{"export key", source.ExportKey != "", func() error { return s.roomService.SetExportKey(destName, owner, source.ExportKey) }},
+	}
+	for _, step := range steps {
+		if !step.set {
+			continue
+		}
+		if err := step.apply(); err != nil {
+			logger.Warn("⚠️ Failed to copy room setting", "setting", step.name, "error", err)
+		}
+	}
+
+	if !source.Restricted() {
+		return
+	}
+	// ผู้ที่เข้าห้องต้นทางได้ก็เข้าห้องใหม่ได้ รวมถึงเจ้าของห้องต้นทางเมื่อ admin เป็นคน clone
+	invitees := append([]string{source.CreatedBy}, source.InvitedUsers...)
+	for _, username := range invitees {
+		if strings.EqualFold(username, owner) {
+			continue
+		}
+		if err := s.roomService.InviteUser(destName, owner, username); err != nil {
+			logger.Warn("⚠️ Failed to copy room invitation", "user", username, "error", err)
+		}
+	}
+}
+
+// copyPins pins the clone's copies of the source room's pinned messages, in the original pin order.
+// ข้อความที่ปักหมุดแต่ไม่อยู่ในช่วงที่คัดลอกจะถูกคัดลอกเพิ่มทีละข้อความ; returns how many were pinned
+func (s *commandService) copyPins(logger *slog.Logger, source *room.Room, destName, owner string, copiedIDs map[string]string) int {
+	if s.messageRepo == nil || len(source.Pinned) == 0 {
+		return 0
+	}
+
+	pinned := 0
+	for _, pin := range source.Pinned {
+		copyID, exists := copiedIDs[pin.MessageID]
+		if !exists {
+			original, err := s.messageRepo.GetMessage(pin.MessageID)
+			if err != nil || original.IsDeleted || original.RoomName != source.Name {
+				continue
+			}
+			if copyID, err = s.copyMessage(original, destName); err != nil {
+				logger.Warn("⚠️ Failed to copy pinned message", "message_id", pin.MessageID, "error", err)
+				continue
+			}
+		}
+		if err := s.roomService.PinMessage(destName, owner, copyID); err != nil {
+			logger.Warn("⚠️ Failed to copy pin", "message_id", pin.MessageID, "error", err)
+			continue
+		}
+		pinned++
+	}
+	return pinned
+}
+
+// copyMessages copies the last limit messages of a room into another room, keeping original timestamps;
+// returns the IDs of the copies keyed by the original message ID
+func (s *commandService) copyMessages(sourceName, destName string, limit int) (map[string]string, error) {
+	messages, err := s.messageRepo.GetMessageHistory(sourceName, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	copiedIDs := make(map[string]string, len(messages))
+	for _, original := range messages {
+		copyID, err := s.copyMessage(original, destName)
+		if err != nil {
+			return copiedIDs, err
+		}
+		copiedIDs[original.ID] = copyID
+	}
+	return copiedIDs, nil
+}
+
+// copyMessage saves a copy of a message in another room and returns the copy's ID
+func (s *commandService) copyMessage(original *messagePkg.Message, destName string) (string, error) {
+	msg := *original
+	msg.ID = "" // ให้ repository สร้าง ID ใหม่
+	msg.RoomName = destName
+
+	if err := s.messageRepo.SaveMessage(&msg); err != nil {
+		return "", err
+	}
+	if s.searchIndex != nil {
+		s.searchIndex.IndexMessage(&msg)
+	}
+	return msg.ID, nil
 }
 
 func (s *commandService) handleStats(conn Connection, args []string) error {
@@ -398,20 +1144,52 @@ func (s *commandService) handleStats(conn Connection, args []string) error {
 	if s.metrics != nil {
 		stats.WriteString(fmt.Sprintf("• Messages Sent: %d\n", s.metrics.TotalMessages))
 		stats.WriteString(fmt.Sprintf("• Commands Executed: %d\n", s.metrics.TotalCommands))
+		if s.config.EnableRoomHibernation && !s.config.EnableMongoDB {
+			metrics := s.metrics.GetMetrics()
+			stats.WriteString(fmt.Sprintf("• Rooms In Memory: %d (hibernated: %d)\n", metrics.ResidentRooms, metrics.HibernatedRooms))
+		}
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   stats.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  "",
-		Timestamp: time.Now(),
+	if s.throttle != nil {
+		throttleStats := s.throttle.Stats()
+		stats.WriteString(fmt.Sprintf("• Throttled Connections: %d (blocked IPs: %d)\n", throttleStats.RejectedAttempts, throttleStats.BlockedIPs))
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
+	if s.ipLimiter != nil {
+		limiterStats := s.ipLimiter.Stats()
+		stats.WriteString(fmt.Sprintf("• Per-IP Limit: max %d (%d IPs, rejected: %d, bans: %d)\n",
+			s.config.MaxConnectionsPerIP, limiterStats.TrackedIPs, limiterStats.Rejected, limiterStats.Bans))
+	}
+
+	if s.churn != nil {
+		churnStats := s.churn.Stats()
+		stats.WriteString(fmt.Sprintf("• Room Switches: %d allowed, %d rejected (limited users: %d)\n", churnStats.Allowed, churnStats.Rejected, churnStats.LimitedUsers))
+	}
+
+	// แนวโน้มจาก snapshot ที่บันทึกไว้ (รวมช่วงก่อน restart)
+	if s.metricsHistory != nil {
+		for _, window := range []struct {
+			label    string
+			duration time.Duration
+		}{{"Last hour", time.Hour}, {"Last day", 24 * time.Hour}} {
+			trend, err := s.metricsHistory.Trend(window.duration)
+			if err != nil {
+				slog.Warn("⚠️ Failed to load metrics trend", "error", err)
+				break
+			}
+			if trend.Samples == 0 {
+				continue
+			}
+			stats.WriteString(fmt.Sprintf("• %s: %d messages (%.1f/min), %d commands, %d connections, peak %d online\n",
+				window.label, trend.Messages, trend.MessagesPerMinute, trend.Commands, trend.Connections, trend.PeakConnections))
+		}
+	}
+
+	reply := ServerMessage{
+		Content: stats.String(),
+	}
+
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleHistory(conn Connection, args []string) error {
@@ -446,29 +1224,30 @@ func (s *commandService) handleHistory(conn Connection, args []string) error {
 	}
 
 	var history strings.Builder
-	history.WriteString(fmt.Sprintf("📜 Last %d messages in '%s':\n", len(messages), chatUser.CurrentRoom))
+	history.WriteString(fmt.Sprintf("📜 Last %d messages in '%s'%s:\n", len(messages), chatUser.CurrentRoom, timezoneNote(chatUser)))
 
 	for _, msg := range messages {
-		timestamp := msg.Timestamp.Format("15:04:05")
-		history.WriteString(fmt.Sprintf("[%s] %s: %s\n", timestamp, msg.Username, msg.Content))
+		timestamp := chatUser.FormatTime(msg.Timestamp, "15:04:05")
+		content := msg.Content
+		if msg.IsDeleted {
+			content = "🗑️ message deleted"
+		} else if len(msg.EditHistory) > 0 {
+			content += " (edited)"
+		}
+		history.WriteString(fmt.Sprintf("[%s] %s: %s\n", timestamp, msg.Username, content))
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   history.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  chatUser.CurrentRoom,
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content:  history.String(),
+		Room:     chatUser.CurrentRoom,
+		Messages: messages,
 	}
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
+	return replyCommand(conn, reply)
 }
 
 func (s *commandService) handleSearch(conn Connection, args []string) error {
-	if s.messageRepo == nil {
+	if s.messageRepo == nil && s.searchIndex == nil {
 		return fmt.Errorf("message search not available")
 	}
 
@@ -491,33 +1270,119 @@ func (s *commandService) handleSearch(conn Connection, args []string) error {
 	}
 
 	query := strings.Join(args, " ")
-	messages, err := s.messageRepo.SearchMessages(query, chatUser.CurrentRoom, 20)
+	// room: ค้นห้องอื่นได้เฉพาะห้องที่มีสิทธิ์อ่าน
+	if roomName := search.ParseQuery(query).RoomName; roomName != "" {
+		if err := roomReadError(s.roomService, roomName, chatUser.Username); err != nil {
+			return fmt.Errorf("search failed: %v", err)
+		}
+	}
+	results, err := s.searchMessages(query, chatUser.CurrentRoom, 20)
 	if err != nil {
 		return fmt.Errorf("search failed: %v", err)
 	}
 
-	var results strings.Builder
-	results.WriteString(fmt.Sprintf("🔍 Search results for '%s' in '%s' (%d results):\n", query, chatUser.CurrentRoom, len(messages)))
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🔍 Search results for '%s' in '%s' (%d results)%s:\n", query, chatUser.CurrentRoom, len(results), timezoneNote(chatUser)))
 
-	for _, msg := range messages {
-		timestamp := msg.Timestamp.Format("15:04:05")
-		results.WriteString(fmt.Sprintf("[%s] %s: %s\n", timestamp, msg.Username, msg.Content))
+	for _, result := range results {
+		timestamp := chatUser.FormatTime(result.Message.Timestamp, "15:04:05")
+		content := result.Message.Content
+		if len(result.Highlights) > 0 {
+			content = strings.Join(result.Highlights, " … ")
+		}
+		output.WriteString(fmt.Sprintf("[%s] %s: %s\n", timestamp, result.Message.Username, content))
 	}
 
-	if len(messages) == 0 {
-		results.WriteString("No messages found.")
+	if len(results) == 0 {
+		output.WriteString("No messages found.")
 	}
 
-	message := &messagePkg.Message{
-		Type:      "system",
-		Content:   results.String(),
-		Sender:    "System",
-		Username:  "System",
-		RoomName:  chatUser.CurrentRoom,
-		Timestamp: time.Now(),
+	reply := ServerMessage{
+		Content: output.String(),
+		Room:    chatUser.CurrentRoom,
+		Results: results,
+		Total:   len(results),
+	}
+
+	return replyCommand(conn, reply)
+}
+
+// searchMessages queries the external index first and falls back to MongoDB text search
+func (s *commandService) searchMessages(rawQuery, roomName string, limit int) ([]*search.Result, error) {
+	if s.searchIndex != nil {
+		query := search.ParseQuery(rawQuery)
+		if query.RoomName == "" {
+			query.RoomName = roomName
+		}
+		query.Limit = limit
+
+		results, err := s.searchIndex.Search(query)
+		if err == nil {
+			return results, nil
+		}
+		slog.Warn("⚠️ Search index query failed, falling back to MongoDB", "error", err)
+	}
+
+	if s.messageRepo == nil {
+		return nil, fmt.Errorf("message search not available")
+	}
+
+	query := search.ParseQuery(rawQuery)
+	if query.RoomName == "" {
+		query.RoomName = roomName
+	}
+	textQuery := textSearchQuery(query)
+	textQuery.Limit = limit
+
+	scored, _, err := s.messageRepo.SearchMessagesText(textQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*search.Result, 0, len(scored))
+	for _, hit := range scored {
+		results = append(results, &search.Result{Message: hit.Message, Score: hit.Score})
 	}
+	return results, nil
+}
+
+// textSearchQuery converts a parsed search query into a MongoDB text search query.
+// exact: ส่งเป็น phrase ในเครื่องหมายคำพูด ซึ่ง $text จะจับทั้งวลีแทนการจับทีละคำ
+func textSearchQuery(query search.Query) messagePkg.SearchQuery {
+	text := query.Text
+	if !query.Fuzzy && text != "" {
+		text = `"` + strings.ReplaceAll(text, `"`, "") + `"`
+	}
+	return messagePkg.SearchQuery{
+		Text:      text,
+		Username:  query.Username,
+		RoomName:  query.RoomName,
+		StartDate: query.StartDate,
+		EndDate:   query.EndDate,
+		Limit:     query.Limit,
+	}
+}
+
+// parseCommandFlags splits command arguments into positional args and --flag values.
+// flag ที่ไม่มีค่าตามหลัง (เช่น --private) จะได้ค่าเป็น "true"
+func parseCommandFlags(args []string) ([]string, map[string]string) {
+	positional := make([]string, 0, len(args))
+	flags := make(map[string]string)
 
-	return conn.SendMessage([]byte(fmt.Sprintf(`{"type":"system","content":"%s","sender":"System","timestamp":"%s"}`,
-		strings.ReplaceAll(message.Content, "\n", "\\n"),
-		message.Timestamp.Format(time.RFC3339))))
-}
\ No newline at end of file
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			positional = append(positional, args[i])
+			continue
+		}
+
+		name := strings.TrimPrefix(args[i], "--")
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			flags[name] = args[i+1]
+			i++
+		} else {
+			flags[name] = "true"
+		}
+	}
+
+	return positional, flags
+}
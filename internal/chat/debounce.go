@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer delays a per-key action until no new call arrived for the configured wait.
+// ใช้กับ event ที่ client ส่งถี่ๆ (เช่นพิมพ์ทีละตัวอักษร) เพื่อไม่ให้ server ทำงานซ้ำทุกครั้ง
+type debouncer struct {
+	wait   time.Duration
+	timers map[string]*time.Timer
+	mutex  sync.Mutex
+}
+
+// newDebouncer creates a new debouncer
+func newDebouncer(wait time.Duration) *debouncer {
+	return &debouncer{
+		wait:   wait,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Call schedules fn for key, replacing any pending call for the same key
+func (d *debouncer) Call(key string, fn func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if timer, exists := d.timers[key]; exists {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d.wait, func() {
+		d.mutex.Lock()
+		if d.timers[key] == timer {
+			delete(d.timers, key)
+		}
+		d.mutex.Unlock()
+		fn()
+	})
+	d.timers[key] = timer
+}
+
+// Cancel drops any pending call for key
+func (d *debouncer) Cancel(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if timer, exists := d.timers[key]; exists {
+		timer.Stop()
+		delete(d.timers, key)
+	}
+}
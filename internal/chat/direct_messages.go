@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetDirectMessageRepository sets the repository used to persist private conversations
+func (s *commandService) SetDirectMessageRepository(repo messagePkg.DirectMessageRepository) {
+	s.directRepo = repo
+}
+
+// SendDirectMessage delivers a private message to the recipient and echoes it to the sender.
+// ส่งเฉพาะ connection ของสองคนนี้ ไม่ผ่าน broadcast ของห้อง
+func (s *commandService) SendDirectMessage(conn Connection, sender *userPkg.User, recipient, content string) error {
+	if recipient == "" || content == "" {
+		return fmt.Errorf("recipient and message required. Usage: /msg <user> <text>")
+	}
+	if strings.EqualFold(recipient, sender.Username) {
+		return fmt.Errorf("you cannot send a direct message to yourself")
+	}
+	// DM ไม่ผูกกับห้อง จึงดูเฉพาะ role ทั้ง server (direct_message frame ไม่ผ่าน ExecuteCommand)
+	if effectiveRole(s.config, s.roles, s.roomService, sender, "") < RoleMember {
+		return fmt.Errorf("guests cannot send direct messages")
+	}
+
+	target, exists := s.userService.GetUserByName(recipient)
+	var targetConn Connection
+	if exists {
+		targetConn, exists = s.wsManager.GetConnection(target.ConnID)
+	}
+	if !exists {
+		return s.routeDirectMessage(conn, sender, recipient, content)
+	}
+
+	direct := &messagePkg.DirectMessage{
+		ConversationID: messagePkg.ConversationID(sender.Username, target.Username),
+		From:           sender.Username,
+		To:             target.Username,
+		Content:        content,
+		Timestamp:      time.Now(),
+	}
+	s.saveDirectMessage(conn, direct)
+
+	data, err := directMessageFrame(direct)
+	if err != nil {
+		return err
+	}
+
+	if err := targetConn.SendMessage(data); err != nil {
+		return fmt.Errorf("failed to deliver message to '%s'", target.Username)
+	}
+	return conn.SendMessage(data)
+}
+
+// SetUserRouter sets how direct messages reach users connected to other nodes (cluster mode)
+func (s *commandService) SetUserRouter(router UserRouter) {
+	s.userRouter = router
+}
+
+// routeDirectMessage delivers a direct message to a recipient that is not connected here:
+// ผ่าน node อื่นใน cluster (region ไหนก็ได้) ถ้าไม่มี node ไหนมีผู้ใช้คนนี้จึงเก็บลง mailbox
+func (s *commandService) routeDirectMessage(conn Connection, sender *userPkg.User, recipient, content string) error {
+	if s.userRouter == nil {
+		return s.queueDirectMessage(conn, sender, recipient, content)
+	}
+
+	direct := &messagePkg.DirectMessage{
+		ConversationID: messagePkg.ConversationID(sender.Username, recipient),
+		From:           sender.Username,
+		To:             recipient,
+		Content:        content,
+		Timestamp:      time.Now(),
+	}
+	data, err := directMessageFrame(direct)
+	if err != nil {
+		return err
+	}
+	if !s.userRouter.SendToUser(recipient, data) {
+		return s.queueDirectMessage(conn, sender, recipient, content)
+	}
+
+	s.saveDirectMessage(conn, direct)
+	return conn.SendMessage(data)
+}
+
+// DeliverToUser sends a direct message frame routed from another node to the user's connection here
+func (s *commandService) DeliverToUser(username string, payload []byte) bool {
+	target, exists := s.userService.GetUserByName(username)
+	if !exists {
+		return false
+	}
+	return s.wsManager.SendMessage(target.ConnID, payload) == nil
+}
+
+// saveDirectMessage persists a delivered direct message for /dm-history
+func (s *commandService) saveDirectMessage(conn Connection, direct *messagePkg.DirectMessage) {
+	if s.directRepo == nil {
+		return
+	}
+	if err := s.directRepo.SaveDirectMessage(direct); err != nil {
+		// ยังส่งต่อได้ แค่จะไม่ปรากฏใน /dm-history
+		connLogger(conn).Warn("⚠️ Failed to persist direct message", "error", err)
+	}
+}
+
+// directMessageFrame encodes the frame both sides of a direct message receive
+func directMessageFrame(direct *messagePkg.DirectMessage) ([]byte, error) {
+	return json.Marshal(ServerMessage{
+		Type:      "direct_message",
+		Content:   direct.Content,
+		Username:  direct.From,
+		Target:    direct.To,
+		Timestamp: direct.Timestamp,
+	})
+}
+
+// queueDirectMessage stores a direct message in the offline recipient's mailbox and tells the sender
+func (s *commandService) queueDirectMessage(conn Connection, sender *userPkg.User, recipient, content string) error {
+	if s.mailbox == nil {
+		return fmt.Errorf("user '%s' is not online", recipient)
+	}
+
+	direct := &messagePkg.DirectMessage{
+		From:      sender.Username,
+		To:        recipient,
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+	if s.directRepo != nil {
+		if err := s.directRepo.SaveDirectMessage(direct); err != nil {
+			connLogger(conn).Warn("⚠️ Failed to persist direct message", "error", err)
+		}
+	}
+
+	pending := &messagePkg.PendingMessage{
+		Recipient: recipient,
+		Kind:      messagePkg.PendingDirect,
+		From:      sender.Username,
+		MessageID: direct.ID,
+		Content:   content,
+		Timestamp: direct.Timestamp,
+	}
+	if err := s.mailbox.Enqueue(pending); err != nil {
+		connLogger(conn).Warn("⚠️ Failed to queue direct message", "recipient", recipient, "error", err)
+		return fmt.Errorf("user '%s' is not online and the message could not be queued", recipient)
+	}
+
+	return replySystem(conn, fmt.Sprintf("📭 %s is offline; your message will be delivered when they reconnect", recipient))
+}
+
+func (s *commandService) handleMsg(conn Connection, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("recipient and message required. Usage: /msg <user> <text>")
+	}
+
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	return s.SendDirectMessage(conn, chatUser, args[0], strings.Join(args[1:], " "))
+}
+
+func (s *commandService) handleDMHistory(conn Connection, args []string) error {
+	if s.directRepo == nil {
+		return fmt.Errorf("direct message history not available")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("user required. Usage: /dm-history <user> [limit]")
+	}
+
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	limit := 20
+	if len(args) > 1 {
+		if l, err := strconv.Atoi(args[1]); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	// อ่านได้เฉพาะบทสนทนาที่ตัวเองเป็นผู้ร่วม เพราะ key สร้างจากชื่อผู้เรียกเสมอ
+	messages, err := s.directRepo.GetConversation(chatUser.Username, args[0], limit)
+	if err != nil {
+		return fmt.Errorf("failed to get direct messages: %v", err)
+	}
+	if len(messages) == 0 {
+		return replySystem(conn, fmt.Sprintf("💬 No direct messages with '%s'", args[0]))
+	}
+
+	var history strings.Builder
+	history.WriteString(fmt.Sprintf("💬 Last %d direct messages with '%s'%s:\n", len(messages), args[0], timezoneNote(chatUser)))
+	for _, msg := range messages {
+		history.WriteString(fmt.Sprintf("[%s] %s: %s\n", chatUser.FormatTime(msg.Timestamp, "01-02 15:04"), msg.From, msg.Content))
+	}
+
+	return replySystem(conn, history.String())
+}
+
+// handleDirectMessage handles a direct_message client message (target = recipient)
+func (h *Handler) handleDirectMessage(conn Connection, user *userPkg.User, msg ClientMessage) {
+	content, err := h.validator.ValidateMessage(msg.Content)
+	if err == nil {
+		err = h.commandService.SendDirectMessage(conn, user, msg.Target, content)
+	}
+	if err != nil {
+		coded := validationFailure(err)
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   coded.Message,
+			Code:      coded.Code,
+			Details:   coded.Details,
+			Target:    msg.Target,
+			Timestamp: time.Now(),
+		})
+	}
+}
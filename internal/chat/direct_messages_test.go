@@ -0,0 +1,45 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// userRouterFunc adapts a function to UserRouter
+type userRouterFunc func(username string, payload []byte) bool
+
+func (f userRouterFunc) SendToUser(username string, payload []byte) bool {
+	return f(username, payload)
+}
+
+// DM ถึงผู้ใช้ที่ต่อกับ node อื่น (cluster ข้าม region) ส่งผ่าน router และผู้ส่งได้ echo เหมือนส่งใน node เดียวกัน
+func TestDirectMessageRoutedToOtherNode(t *testing.T) {
+	eu := newTestServer(t, nil)
+	us := newTestServer(t, nil)
+	eu.commands.SetUserRouter(userRouterFunc(us.commands.DeliverToUser))
+
+	alice := eu.login(t, "alice")
+	bobby := us.join(t, "bobby")
+
+	alice.send(map[string]interface{}{"type": "command", "content": "/msg bobby over the ocean"})
+	if dm := bobby.expect("direct_message"); dm["content"] != "over the ocean" || dm["username"] != "alice" {
+		t.Fatalf("routed direct message = %v", dm)
+	}
+	if echo := alice.expect("direct_message"); echo["target"] != "bobby" {
+		t.Fatalf("sender echo = %v", echo)
+	}
+
+	// ไม่มี node ไหนมีผู้ใช้คนนี้และไม่มี mailbox
+	alice.send(map[string]interface{}{"type": "command", "content": "/msg carol hello?"})
+	for {
+		alice.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := alice.conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if strings.Contains(string(data), "not online") {
+			break
+		}
+	}
+}
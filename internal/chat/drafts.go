@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// draft is unsent message text a user was composing in a room
+type draft struct {
+	content   string
+	updatedAt time.Time
+}
+
+// draftStore keeps per-(username, room) drafts so a user reconnecting from another
+// device can continue composing. เก็บตาม username เพราะ user ID เปลี่ยนทุกครั้งที่เชื่อมต่อใหม่
+// (ของ guest ถูกล้างเมื่อ session จบ ดู clearUserState)
+type draftStore struct {
+	drafts     map[string]map[string]*draft // username -> roomName -> draft
+	maxLength  int
+	maxPerUser int
+	ttl        time.Duration
+	lastPrune  time.Time
+	mutex      sync.Mutex
+}
+
+// newDraftStore creates a new draft store
+func newDraftStore(maxLength, maxPerUser int, ttl time.Duration) *draftStore {
+	return &draftStore{
+		drafts:     make(map[string]map[string]*draft),
+		maxLength:  maxLength,
+		maxPerUser: maxPerUser,
+		ttl:        ttl,
+	}
+}
+
+// Save stores a draft; empty content deletes it
+func (d *draftStore) Save(username, roomName, content string) error {
+	if len(content) > d.maxLength {
+		return fmt.Errorf("draft too long (max %d characters)", d.maxLength)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	d.pruneLocked(now)
+
+	if content == "" {
+		d.deleteLocked(username, roomName)
+		return nil
+	}
+
+	rooms, exists := d.drafts[username]
+	if !exists {
+		rooms = make(map[string]*draft)
+		d.drafts[username] = rooms
+	}
+
+	// เกินจำนวนห้องที่เก็บได้ ให้ทิ้ง draft ที่เก่าที่สุด
+	if _, exists := rooms[roomName]; !exists && len(rooms) >= d.maxPerUser {
+		oldest := ""
+		for name, existing := range rooms {
+			if oldest == "" || existing.updatedAt.Before(rooms[oldest].updatedAt) {
+				oldest = name
+			}
+		}
+		delete(rooms, oldest)
+	}
+
+	rooms[roomName] = &draft{content: content, updatedAt: now}
+	return nil
+}
+
+// Get returns the draft for a user in a room
+func (d *draftStore) Get(username, roomName string) (string, time.Time, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	existing, exists := d.drafts[username][roomName]
+	if !exists || time.Since(existing.updatedAt) > d.ttl {
+		return "", time.Time{}, false
+	}
+	return existing.content, existing.updatedAt, true
+}
+
+// Clear removes every draft of a user
+func (d *draftStore) Clear(username string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.drafts, username)
+}
+
+// Delete removes the draft for a user in a room (e.g. after the message was sent)
+func (d *draftStore) Delete(username, roomName string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.deleteLocked(username, roomName)
+}
+
+// deleteLocked removes a draft (assumes lock is held)
+func (d *draftStore) deleteLocked(username, roomName string) {
+	rooms, exists := d.drafts[username]
+	if !exists {
+		return
+	}
+	delete(rooms, roomName)
+	if len(rooms) == 0 {
+		delete(d.drafts, username)
+	}
+}
+
+// Reap drops expired drafts; under memory pressure drafts older than a quarter of the TTL go too
+func (d *draftStore) Reap(aggressive bool) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ttl := d.ttl
+	if aggressive {
+		ttl /= 4
+	}
+	d.lastPrune = time.Now()
+	return d.removeOlderLocked(d.lastPrune, ttl)
+}
+
+// pruneLocked drops drafts older than the TTL, at most once a minute (assumes lock is held)
+func (d *draftStore) pruneLocked(now time.Time) {
+	if now.Sub(d.lastPrune) < time.Minute {
+		return
+	}
+	d.lastPrune = now
+	d.removeOlderLocked(now, d.ttl)
+}
+
+// removeOlderLocked removes drafts not updated within ttl, returning how many (assumes lock is held)
+func (d *draftStore) removeOlderLocked(now time.Time, ttl time.Duration) int {
+	count := 0
+	for username, rooms := range d.drafts {
+		for roomName, existing := range rooms {
+			if now.Sub(existing.updatedAt) > ttl {
+				delete(rooms, roomName)
+				count++
+			}
+		}
+		if len(rooms) == 0 {
+			delete(d.drafts, username)
+		}
+	}
+	return count
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// handleEditCommand handles /edit <message_id> <new text>
+func (h *Handler) handleEditCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /edit <message_id> <new text>")
+	}
+
+	content, err := h.validator.ValidateMessage(strings.Join(args[1:], " "))
+	if err != nil {
+		return validationFailure(err)
+	}
+	if violation := h.checkMentionImpact(user, user.CurrentRoom, content); violation != nil {
+		return &codedError{Code: violation.Code, Message: violation.Message, Details: violation.Details}
+	}
+
+	original, err := h.authorizeMessageChange(user, args[0])
+	if err != nil {
+		return err
+	}
+
+	edited, err := h.messageRepo.EditMessage(args[0], content, user.Username)
+	if err != nil {
+		return h.messageChangeError(args[0], err)
+	}
+
+	slog.Info("✏️ Message edited", "by", user.Username, "message_id", args[0], "author", original.Username, "room", original.RoomName, "edit", len(edited.EditHistory))
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "message_edited",
+		Target:    args[0],
+		Content:   edited.Content,
+		Sender:    original.Username,
+		Username:  user.Username,
+		Room:      original.RoomName,
+		Timestamp: time.Now(),
+	}, "", original.RoomName)
+	return nil
+}
+
+// handleDeleteCommand handles /delete <message_id>
+func (h *Handler) handleDeleteCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /delete <message_id>")
+	}
+
+	original, err := h.authorizeMessageChange(user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.messageRepo.SoftDeleteMessage(args[0], user.Username); err != nil {
+		return h.messageChangeError(args[0], err)
+	}
+
+	slog.Info("🗑️ Message deleted", "by", user.Username, "message_id", args[0], "author", original.Username, "room", original.RoomName)
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "message_deleted",
+		Target:    args[0],
+		Sender:    original.Username,
+		Username:  user.Username,
+		Room:      original.RoomName,
+		Timestamp: time.Now(),
+	}, "", original.RoomName)
+	return nil
+}
+
+// authorizeMessageChange loads a message in the user's current room and checks that the user is its author or a moderator
+func (h *Handler) authorizeMessageChange(user *userPkg.User, messageID string) (*messagePkg.Message, error) {
+	if user.CurrentRoom == "" {
+		return nil, fmt.Errorf("you must be in a room to change messages")
+	}
+	if h.messageRepo == nil {
+		return nil, fmt.Errorf("editing messages requires message persistence")
+	}
+
+	// แก้ได้เฉพาะข้อความในห้องที่อยู่ตอนนี้ เพื่อไม่ให้ broadcast ไปผิดห้อง
+	message, err := h.messageRepo.GetMessage(messageID)
+	if err != nil || message.RoomName != user.CurrentRoom || message.IsDeleted {
+		return nil, fmt.Errorf("message '%s' not found in room '%s'", messageID, user.CurrentRoom)
+	}
+	if message.Type != "message" {
+		return nil, fmt.Errorf("only chat messages can be changed")
+	}
+	if message.Username != user.Username && !h.isModerator(user, message.RoomName) {
+		return nil, fmt.Errorf("you can only change your own messages")
+	}
+	return message, nil
+}
+
+// messageChangeError turns a repository error into the error shown to the user
+func (h *Handler) messageChangeError(messageID string, err error) error {
+	if err == messagePkg.ErrMessageNotFound {
+		// ถูกลบไปแล้วระหว่างตรวจสิทธิ์กับการแก้ไข
+		return fmt.Errorf("message '%s' not found", messageID)
+	}
+	slog.Error("❌ Failed to change message", "message_id", messageID, "error", err)
+	return fmt.Errorf("failed to update message")
+}
@@ -1,5 +1,7 @@
 package chat
 
+import userPkg "realtime-chat/internal/user"
+
 // BroadcastMessage represents a message with exclusion info
 type BroadcastMessage struct {
 	Message   interface{}
@@ -7,19 +9,43 @@ type BroadcastMessage struct {
 	RoomName  string // ชื่อห้องที่จะส่งข้อความ (ถ้าว่างจะส่งให้ทุกคน)
 }
 
+// CommandRole is the minimum role needed to run a command (the same levels as user roles)
+type CommandRole = userPkg.Role
+
+// Roles a command may require; ExecuteCommand checks them against the user's role in the current room
+const (
+	RoleGuest     = userPkg.RoleGuest // ทุกคน (ค่าเริ่มต้นของ Command)
+	RoleMember    = userPkg.RoleMember
+	RoleModerator = userPkg.RoleModerator // moderator ทั้ง server หรือของห้องปัจจุบัน
+	RoleOwner     = userPkg.RoleOwner     // เจ้าของห้องปัจจุบัน (หรือ admin)
+	RoleAdmin     = userPkg.RoleAdmin     // admin ของ server เท่านั้น
+)
+
+// Server capabilities a command may depend on
+const (
+	CapabilityPersistence   = "persistence" // มี message repository
+	CapabilitySearch        = "search"      // มี message repository หรือ search index
+	CapabilityChangeFeed    = "change_feed" // เปิด change feed และมี message repository
+	CapabilityDirectHistory = "dm_history"  // มี direct message repository
+)
+
 // Command represents a chat command
 type Command struct {
-	Name        string
-	Description string
-	Usage       string
-	Handler     func(conn Connection, args []string) error
+	Name         string
+	Description  string
+	Usage        string
+	RequiredRole CommandRole // ExecuteCommand ตรวจก่อนเรียก Handler และใช้กรองรายการใน /help
+	Requires     string      // capability ที่ server ต้องมี (ว่าง = ใช้ได้เสมอ)
+	Handler      func(conn Connection, args []string) error
 }
 
 // Connection interface for WebSocket connections
 type Connection interface {
 	GetID() string
+	GetLabel() string         // short label สำหรับ log เช่น alice#ab12
+	GetCorrelationID() string // correlation ID ของข้อความขาเข้าปัจจุบัน
 	GetUser() interface{}
 	SetUser(user interface{})
 	SendMessage(message []byte) error
 	Close() error
-}
\ No newline at end of file
+}
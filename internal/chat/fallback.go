@@ -0,0 +1,203 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// Transports reported in transport frames
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+)
+
+// sseKeepalive is how often an idle fallback stream gets a comment line, so proxies keep it open
+const sseKeepalive = 15 * time.Second
+
+// sseFallback is a Server-Sent Events stream a client holds next to its WebSocket for the same session.
+// ขณะ WebSocket ต่ออยู่ stream นี้ได้แค่ keepalive; เมื่อ WebSocket หลุด ข้อความของห้องที่ปกติจะถูกเก็บไว้รอ resume
+// จะถูกส่งทาง stream นี้แทน ข้อความแต่ละข้อความจึงไปทางเดียวเท่านั้น (exactly once)
+type sseFallback struct {
+	events    chan []byte // frame ที่ encode แล้ว รอเขียนลง stream
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSSEFallback creates a fallback stream that buffers up to size frames
+func newSSEFallback(size int) *sseFallback {
+	if size <= 0 {
+		size = 100
+	}
+	return &sseFallback{
+		events: make(chan []byte, size),
+		done:   make(chan struct{}),
+	}
+}
+
+// send queues a frame without blocking; false means the stream is closed or too slow and the
+// caller must keep the frame for resume instead
+func (f *sseFallback) send(frame interface{}) bool {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return false
+	}
+	select {
+	case <-f.done:
+		return false
+	default:
+	}
+	select {
+	case f.events <- data:
+		return true
+	default:
+		// client อ่านไม่ทัน ปิด stream แล้วให้ข้อความที่เหลือไปทาง resume
+		f.close()
+		return false
+	}
+}
+
+// close ends the stream; safe to call more than once
+func (f *sseFallback) close() {
+	f.closeOnce.Do(func() { close(f.done) })
+}
+
+// transportFrame tells the client which transport delivers its messages now
+func transportFrame(active string) ServerMessage {
+	return ServerMessage{Type: "transport", Status: active, Timestamp: time.Now()}
+}
+
+// AttachFallback binds an SSE stream to the session of a resume token (the token is not used up).
+// stream เดิมของ session (ถ้ามี) ถูกปิด; returns the transport that delivers messages now
+func (s *sessionStore) AttachFallback(token string, fallback *sseFallback) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[token]
+	if !exists || (session.connID == "" && time.Since(session.detachedAt) > s.grace) {
+		return "", errSessionNotFound
+	}
+	if session.fallback != nil {
+		session.fallback.close()
+	}
+	session.fallback = fallback
+	if session.connID != "" {
+		return TransportWebSocket, nil
+	}
+	return TransportSSE, nil
+}
+
+// DetachFallback removes a closed SSE stream from the session that holds it
+func (s *sessionStore) DetachFallback(fallback *sseFallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, session := range s.sessions {
+		if session.fallback == fallback {
+			session.fallback = nil
+		}
+	}
+	fallback.close()
+}
+
+// CloseEventStreams ends every SSE fallback stream (server shutdown); sessions stay resumable
+func (h *Handler) CloseEventStreams() {
+	if h.sessions == nil {
+		return
+	}
+	h.sessions.mutex.Lock()
+	defer h.sessions.mutex.Unlock()
+
+	for _, session := range h.sessions.sessions {
+		if session.fallback != nil {
+			session.fallback.close()
+			session.fallback = nil
+		}
+	}
+}
+
+// handOverFallback moves the SSE stream of a resumed session to the connection's new session
+// and tells the client that the WebSocket delivers messages again
+func (s *sessionStore) handOverFallback(connID string, fallback *sseFallback) {
+	if fallback == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[s.byConn[connID]]
+	if !exists {
+		fallback.close()
+		return
+	}
+	session.fallback = fallback
+	fallback.send(transportFrame(TransportWebSocket))
+}
+
+// deliverFallback sends a room message over the SSE stream of a detached session (assumes lock is held)
+func (session *resumableSession) deliverFallback(message *messagePkg.Message) bool {
+	if session.fallback == nil {
+		return false
+	}
+	if session.fallback.send(message) {
+		return true
+	}
+	session.fallback = nil
+	return false
+}
+
+// HandleEvents serves GET /events?token=<resume token>: the SSE fallback transport of a session.
+// EventSource ตั้ง header ไม่ได้ token จึงมาทาง query; stream อยู่กับ session ข้ามการ resume
+// แม้ token จะเปลี่ยน client จึงไม่ต้องเปิดใหม่ (ถ้าหลุด ให้เปิดใหม่ด้วย token ล่าสุด)
+func (h *Handler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if h.sessions == nil {
+		http.Error(w, "session resume is not enabled", http.StatusNotFound)
+		return
+	}
+	controller := http.NewResponseController(w)
+
+	fallback := newSSEFallback(h.config.MaxResumeMessages)
+	active, err := h.sessions.AttachFallback(r.URL.Query().Get("token"), fallback)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	defer h.sessions.DetachFallback(fallback)
+
+	// stream อยู่ได้นานกว่า write timeout ของ HTTP server
+	controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	first, _ := json.Marshal(transportFrame(active))
+	fmt.Fprintf(w, "retry: 3000\ndata: %s\n\n", first)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+	slog.Info("📡 SSE fallback attached", "ip", h.proxies.ClientIP(r), "active", active)
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case data := <-fallback.events:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-fallback.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
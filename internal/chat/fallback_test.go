@@ -0,0 +1,108 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ข้อความของห้องไปทาง WebSocket หรือ SSE fallback ทางเดียว และสลับกลับเมื่อ resume
+func TestSSEFallbackDeliversOnceAcrossWebSocketDrop(t *testing.T) {
+	server := newTestServer(t, nil)
+	events := httptest.NewServer(http.HandlerFunc(server.handler.HandleEvents))
+	t.Cleanup(events.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	alice := &testClient{t: t, conn: conn}
+	alice.send(map[string]interface{}{"type": "join", "username": "alice"})
+	token, _ := alice.expect("session")["resume_token"].(string)
+	bobby := server.join(t, "bobby")
+
+	if resp, err := http.Get(events.URL + "?token=wrong"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("stream with a bad token = %v, %v; want 401", resp, err)
+	}
+	resp, err := http.Get(events.URL + "?token=" + token)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("open stream = %v, %v", resp, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	stream := sseReader(t, resp)
+	if frame := stream(); frame["type"] != "transport" || frame["status"] != TransportWebSocket {
+		t.Fatalf("first event = %v, want transport websocket", frame)
+	}
+
+	bobby.send(map[string]interface{}{"type": "message", "content": "over the websocket"})
+	if message := alice.expect("message"); message["content"] != "over the websocket" {
+		t.Fatalf("websocket message = %v", message)
+	}
+
+	// WebSocket หลุด: ข้อความถัดไปมาทาง SSE และไม่ถูกเก็บไว้ส่งซ้ำตอน resume
+	conn.Close()
+	if frame := stream(); frame["type"] != "transport" || frame["status"] != TransportSSE {
+		t.Fatalf("event after drop = %v, want transport sse", frame)
+	}
+	bobby.send(map[string]interface{}{"type": "message", "content": "over the fallback"})
+	if frame := stream(); frame["type"] != "message" || frame["content"] != "over the fallback" {
+		t.Fatalf("fallback event = %v", frame)
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	alice = &testClient{t: t, conn: conn}
+	alice.send(map[string]interface{}{"type": "resume", "token": token})
+	if resumed := alice.expect("resumed"); resumed["messages"] != nil {
+		t.Fatalf("resume repeated %v already delivered over SSE", resumed["messages"])
+	}
+	if frame := stream(); frame["type"] != "transport" || frame["status"] != TransportWebSocket {
+		t.Fatalf("event after resume = %v, want transport websocket", frame)
+	}
+	bobby.send(map[string]interface{}{"type": "message", "content": "back on the websocket"})
+	if message := alice.expect("message"); message["content"] != "back on the websocket" {
+		t.Fatalf("websocket message after resume = %v", message)
+	}
+}
+
+// sseReader returns a function that reads the next data event of an SSE response as JSON
+func sseReader(t *testing.T, resp *http.Response) func() map[string]interface{} {
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, found := strings.CutPrefix(scanner.Text(), "data: "); found {
+				lines <- data
+			}
+		}
+		close(lines)
+	}()
+
+	return func() map[string]interface{} {
+		t.Helper()
+		select {
+		case data, ok := <-lines:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+			var frame map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &frame); err != nil {
+				t.Fatalf("event %q: %v", data, err)
+			}
+			return frame
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return nil
+		}
+	}
+}
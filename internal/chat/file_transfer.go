@@ -0,0 +1,193 @@
+package chat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/transfer"
+	userPkg "realtime-chat/internal/user"
+)
+
+// FileChunk is one relayed piece of a file transfer; Data is base64 encoded
+type FileChunk struct {
+	TransferID string `json:"transfer_id"`
+	Seq        int    `json:"seq"`
+	Data       string `json:"data"`
+}
+
+// SetFileTransfers enables user-to-user file transfers relayed through the server
+func (h *Handler) SetFileTransfers(manager *transfer.Manager) {
+	h.transfers = manager
+}
+
+// handleOfferFile offers a file to another online user (target = recipient)
+func (h *Handler) handleOfferFile(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.transfers == nil {
+		h.sendCodedError(conn, &codedError{Code: "file_transfer_disabled", Message: "File transfer is not enabled"})
+		return
+	}
+	if msg.File == nil || msg.Target == "" {
+		h.sendCodedError(conn, &codedError{Code: "invalid_file", Message: "offer_file requires target and file"})
+		return
+	}
+	if strings.EqualFold(msg.Target, user.Username) {
+		h.sendCodedError(conn, &codedError{Code: "invalid_file", Message: "You cannot send a file to yourself"})
+		return
+	}
+
+	recipient, exists := h.userService.GetUserByName(msg.Target)
+	if !exists {
+		h.sendCodedError(conn, &codedError{Code: "user_offline", Message: fmt.Sprintf("User '%s' is not online", msg.Target)})
+		return
+	}
+
+	offer, err := h.transfers.Offer(user.Username, recipient.Username, *msg.File)
+	if err != nil {
+		h.transferError(conn, "", err)
+		return
+	}
+
+	slog.Info("📎 File offered", "transfer_id", offer.ID, "from", user.Username, "to", offer.To, "file", offer.File.Name, "bytes", offer.File.Size)
+	h.sendJSONMessage(conn, ServerMessage{Type: "file_offer_sent", Transfer: &offer, Timestamp: time.Now()})
+	if !h.sendToUser(offer.To, ServerMessage{Type: "file_offer", Transfer: &offer, Timestamp: time.Now()}) {
+		h.transfers.Cancel(offer.ID, user.Username)
+		offer.State = transfer.StateCancelled
+		h.sendJSONMessage(conn, ServerMessage{Type: "file_cancelled", Transfer: &offer, Timestamp: time.Now()})
+	}
+}
+
+// handleFileResponse handles accept_file, decline_file and cancel_file
+func (h *Handler) handleFileResponse(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.transfers == nil {
+		h.sendCodedError(conn, &codedError{Code: "file_transfer_disabled", Message: "File transfer is not enabled"})
+		return
+	}
+
+	var offer transfer.Offer
+	var err error
+	eventType := ""
+	switch msg.Type {
+	case "accept_file":
+		offer, err = h.transfers.Accept(msg.TransferID, user.Username)
+		eventType = "file_accepted"
+	case "decline_file":
+		offer, err = h.transfers.Decline(msg.TransferID, user.Username)
+		eventType = "file_declined"
+	default:
+		offer, err = h.transfers.Cancel(msg.TransferID, user.Username)
+		eventType = "file_cancelled"
+	}
+	if err != nil {
+		h.transferError(conn, msg.TransferID, err)
+		return
+	}
+
+	slog.Info("📎 File transfer updated", "transfer_id", offer.ID, "state", offer.State, "by", user.Username)
+	h.notifyTransfer(eventType, offer)
+}
+
+// handleFileChunk relays the sender's next chunk to the recipient.
+// ผู้ส่งต้องรอ ack ก่อนส่งเกิน window ทำให้ buffer ฝั่ง server ไม่โตตามขนาดไฟล์
+func (h *Handler) handleFileChunk(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.transfers == nil {
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(msg.Data)
+	if err != nil {
+		h.transferError(conn, msg.TransferID, fmt.Errorf("%w: chunk data must be base64", transfer.ErrInvalidFile))
+		return
+	}
+
+	offer, err := h.transfers.Chunk(msg.TransferID, user.Username, msg.Seq, len(data))
+	if err != nil {
+		h.transferError(conn, msg.TransferID, err)
+		return
+	}
+
+	chunk := &FileChunk{TransferID: offer.ID, Seq: msg.Seq, Data: msg.Data}
+	if !h.sendToUser(offer.To, ServerMessage{Type: "file_chunk", Chunk: chunk, Timestamp: time.Now()}) {
+		if cancelled, err := h.transfers.Cancel(offer.ID, user.Username); err == nil {
+			h.notifyTransfer("file_cancelled", cancelled)
+		}
+	}
+}
+
+// handleFileChunkAck records a chunk the recipient received and reports progress to both sides
+func (h *Handler) handleFileChunkAck(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.transfers == nil {
+		return
+	}
+
+	offer, completed, err := h.transfers.Ack(msg.TransferID, user.Username, msg.Seq)
+	if err != nil {
+		h.transferError(conn, msg.TransferID, err)
+		return
+	}
+
+	if completed {
+		slog.Info("📎 File delivered", "transfer_id", offer.ID, "from", offer.From, "to", offer.To, "file", offer.File.Name, "bytes", offer.File.Size)
+		h.notifyTransfer("file_complete", offer)
+		return
+	}
+	h.notifyTransfer("file_progress", offer)
+}
+
+// cancelTransfers cancels the user's transfers when they disconnect and tells the other side
+func (h *Handler) cancelTransfers(username string) {
+	if h.transfers == nil {
+		return
+	}
+	for _, offer := range h.transfers.CancelUser(username) {
+		h.notifyTransfer("file_cancelled", offer)
+	}
+}
+
+// notifyTransfer sends a transfer event to both the sender and the recipient
+func (h *Handler) notifyTransfer(eventType string, offer transfer.Offer) {
+	for _, username := range []string{offer.From, offer.To} {
+		h.sendToUser(username, ServerMessage{Type: eventType, Transfer: &offer, Timestamp: time.Now()})
+	}
+}
+
+// sendToUser sends a message to an online user by name and reports whether it was queued
+func (h *Handler) sendToUser(username string, msg ServerMessage) bool {
+	chatUser, exists := h.userService.GetUserByName(username)
+	if !exists {
+		return false
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("❌ Failed to marshal JSON message", "error", err)
+		return false
+	}
+	return h.wsManager.SendMessage(chatUser.ConnID, data) == nil
+}
+
+// transferError sends a transfer error with a machine-readable code
+func (h *Handler) transferError(conn Connection, transferID string, err error) {
+	code := "file_transfer_error"
+	switch {
+	case errors.Is(err, transfer.ErrNotFound):
+		code = "transfer_not_found"
+	case errors.Is(err, transfer.ErrWindowFull):
+		code = "transfer_window_full"
+	case errors.Is(err, transfer.ErrOutOfOrder):
+		code = "transfer_out_of_order"
+	case errors.Is(err, transfer.ErrNotAllowed):
+		code = "transfer_not_allowed"
+	case errors.Is(err, transfer.ErrInvalidFile):
+		code = "invalid_file"
+	}
+
+	coded := &codedError{Code: code, Message: err.Error()}
+	if transferID != "" {
+		coded.Details = map[string]interface{}{"transfer_id": transferID}
+	}
+	h.sendCodedError(conn, coded)
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/security"
+)
+
+// ErrCodeFrameRateLimit is the error code sent when a connection sends frames faster than its budget
+const ErrCodeFrameRateLimit = "frame_rate_limited"
+
+// expensiveFrameTypes are the client message types that read repositories, run commands or fan out,
+// and take a token from the expensive budget as well as the frame budget
+var expensiveFrameTypes = map[string]bool{
+	"command":           true,
+	"get_history":       true,
+	"get_my_history":    true,
+	"get_thread":        true,
+	"search_messages":   true,
+	"subscribe_search":  true,
+	"suggest_users":     true,
+	"get_users":         true,
+	"subscribe_members": true,
+	"join_room":         true,
+	"leave_room":        true,
+	"create_room":       true,
+	"get_unread_counts": true,
+	"get_notifications": true,
+	"offer_file":        true,
+}
+
+// SetFrameLimiter enables per-connection limiting of raw inbound frames
+func (h *Handler) SetFrameLimiter(limiter *security.FrameLimiter) {
+	h.frameLimiter = limiter
+}
+
+// frameRateLimited tells the client once per limited streak that its frames are being dropped
+func (h *Handler) frameRateLimited(conn Connection, budget *security.FrameBudget, name string, retryAfter time.Duration) {
+	if !budget.ShouldNotify() {
+		return
+	}
+
+	limit, window := budget.Limit(name)
+	retryAfter = retryAfter.Round(time.Second)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	connLogger(conn).Warn("🚦 Frame budget exceeded, dropping frames", "budget", name, "limit", limit, "window", window)
+	h.sendCodedError(conn, &codedError{
+		Code:    ErrCodeFrameRateLimit,
+		Message: fmt.Sprintf("You are sending too fast (max %d %s frames per %v). Try again in %v", limit, name, window, retryAfter),
+		Details: map[string]interface{}{
+			"budget":              name,
+			"limit":               limit,
+			"window_seconds":      int(window.Seconds()),
+			"retry_after_seconds": int(retryAfter.Seconds()),
+		},
+	})
+}
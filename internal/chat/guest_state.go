@@ -0,0 +1,33 @@
+package chat
+
+import userPkg "realtime-chat/internal/user"
+
+// draft, preferences และ snooze เก็บตาม username ซึ่งเป็นตัวตนที่คงที่เฉพาะผู้ที่ login ด้วย account;
+// ชื่อของ guest ใครก็ใช้ต่อได้เมื่อ guest คนเดิมออกไป จึงล้างสถานะเหล่านี้เมื่อ session ของ guest จบ
+
+// clearUserState removes the drafts, preferences and snoozes kept for a username
+func (h *Handler) clearUserState(username string) {
+	h.drafts.Clear(username)
+	h.preferences.Clear(username)
+	h.snoozes.Clear(username)
+}
+
+// endGuestSession clears a closing guest connection's state unless its session stays resumable
+func (h *Handler) endGuestSession(connID string) {
+	connection, exists := h.wsManager.GetConnection(connID)
+	if !exists {
+		return
+	}
+	chatUser, ok := connection.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil || !chatUser.IsAuthenticated || !chatUser.Guest || h.sessions.Attached(connID) {
+		return
+	}
+	h.clearUserState(chatUser.Username)
+}
+
+// expireGuestSession clears a guest's state once its resume grace period has passed
+func (h *Handler) expireGuestSession(session *resumableSession) {
+	if session.guest {
+		h.clearUserState(session.username)
+	}
+}
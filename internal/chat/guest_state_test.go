@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"realtime-chat/internal/config"
+)
+
+// guest คนถัดไปที่ใช้ชื่อเดิมต้องไม่เห็น draft และ preferences ของ guest คนก่อน
+func TestGuestStateClearedWhenSessionEnds(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.EnableSessionResume = false
+	})
+
+	// resume ปิดอยู่ จึงไม่มี session frame ให้ server.join รอ; ชื่อว่างเมื่อ server ปิด connection เดิมเสร็จ
+	joinAlice := func() *testClient {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			client := &testClient{t: t, conn: conn}
+			client.send(map[string]interface{}{"type": "join", "username": "alice"})
+			for {
+				switch client.next()["type"] {
+				case "rooms_list":
+					t.Cleanup(func() { conn.Close() })
+					return client
+				case "error":
+				default:
+					continue
+				}
+				break
+			}
+			conn.Close()
+			if time.Now().After(deadline) {
+				t.Fatal("username was never released")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	first := joinAlice()
+	first.send(map[string]interface{}{"type": "save_draft", "room": "general", "content": "half-written secret"})
+	first.send(map[string]interface{}{"type": "set_pref", "key": "theme", "value": "dark"})
+	first.send(map[string]interface{}{"type": "get_draft", "room": "general"})
+	if draft := first.expect("draft"); draft["content"] != "half-written secret" {
+		t.Fatalf("draft before disconnect = %v", draft["content"])
+	}
+	first.conn.Close()
+
+	second := joinAlice()
+	second.send(map[string]interface{}{"type": "get_draft", "room": "general"})
+	if content, _ := second.expect("draft")["content"].(string); content != "" {
+		t.Fatalf("new guest saw previous guest's draft %q", content)
+	}
+	second.send(map[string]interface{}{"type": "get_prefs"})
+	prefs := second.expect("preferences")
+	if values, _ := prefs["preferences"].(map[string]interface{}); len(values) > 0 {
+		t.Fatalf("new guest saw previous guest's preferences %v", values)
+	}
+}
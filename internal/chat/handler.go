@@ -3,22 +3,41 @@ package chat
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/changefeed"
+	"realtime-chat/internal/commands"
 	"realtime-chat/internal/config"
+	"realtime-chat/internal/export"
 	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/naming"
+	"realtime-chat/internal/presence"
+	"realtime-chat/internal/reaper"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/search"
 	"realtime-chat/internal/security"
+	"realtime-chat/internal/transfer"
 	userPkg "realtime-chat/internal/user"
+	"realtime-chat/internal/version"
 	wsocket "realtime-chat/internal/websocket"
 )
 
 // Handler handles HTTP requests and WebSocket upgrades
 type Handler struct {
 	upgrader       websocket.Upgrader
+	origins        *security.OriginPolicy // origin ที่เปิด WebSocket ได้ (AllowedOrigins)
+	proxies        *security.ProxyPolicy  // หา IP จริงของ client หลัง reverse proxy (TrustedProxies)
+	metrics        *config.ServerMetrics  // Optional counters for rejected upgrades
 	wsManager      WebSocketManager
 	userService    UserService
 	roomService    RoomService
@@ -28,6 +47,39 @@ type Handler struct {
 	rateLimiter    *config.RateLimiter
 	validator      *security.InputValidator
 	messageRepo    MessageRepository // Add message repository
+	threadRepo     messagePkg.ThreadRepository // Optional threaded replies
+	timeline       messagePkg.TimelineRepository // Optional room timeline sequencing
+	searchIndex    SearchIndex       // Optional external search backend
+	suggestDebounce *debouncer       // Debounces @-mention autocomplete requests per connection
+	memberFeed     *memberFeed       // Pushes membership deltas to subscribed connections
+	throttle       *security.ConnectionThrottle // Optional per-IP connection/login throttling
+	ipLimiter      *security.IPLimiter          // Optional per-IP concurrent connection limit
+	churn          *security.ChurnLimiter       // Optional per-user room switch limiting
+	honeypot       *moderation.Honeypot         // Optional trap rooms for bot detection
+	quarantine     *moderation.Quarantine       // Optional protocol violation quarantine
+	frameLimiter   *security.FrameLimiter       // Optional per-connection inbound frame budgets
+	sampler        *roomSampler                 // Optional sampled delivery for very large rooms
+	transfers      *transfer.Manager            // Optional user-to-user file transfers
+	drafts         *draftStore                  // Unsent message drafts per user and room
+	preferences    *preferenceStore             // Per-user UI preferences synced across devices
+	latency        *config.LatencyRecorder      // Optional per-stage timing of the message path
+	liveSearches   *liveSearches                // Standing queries fed by the database change feed
+	changeFeedEnabled bool                      // Live searches only receive matches when a change feed is attached
+	snoozes        *snoozeStore                 // Per-user room notification snoozes
+	typing         *typingTracker               // Debounced ephemeral typing indicators
+	presenceTracker *presence.Tracker           // Optional online/away/offline presence
+	announcements  *announcement.Store          // Optional admin announcements awaiting acknowledgment
+	mailbox        messagePkg.MailboxRepository // Optional offline mailbox for mentions and DMs
+	notifications  messagePkg.NotificationRepository // Optional persisted mention notifications
+	traces         *traceSessions               // admin /trace sessions
+	receipts       messagePkg.ReadReceiptRepository // Optional per-room read receipts
+	presence       *userPkg.PresenceStore       // Optional presence pushed by external systems
+	guestNames     *naming.Generator            // Optional generated names for guests joining without one
+	accounts       *account.Service             // Optional registered accounts that own their usernames
+	roles          *userPkg.RoleStore           // Optional roles assigned with /setrole
+	mirror         *roomMirror                  // Mirror-mode rooms fanned out from the change feed (nil without a feed)
+	sessions       *sessionStore                // Optional resumable sessions of recently disconnected users
+	acks           *ackCache                    // Recent acks by client_msg_id for deduplicating retries
 }
 
 // ClientMessage represents incoming messages from client
@@ -39,6 +91,32 @@ type ClientMessage struct {
 	Command  string `json:"command,omitempty"`
 	Query    string `json:"query,omitempty"`
 	Limit    int    `json:"limit,omitempty"`
+	Offset   int    `json:"offset,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	ProbeID  string `json:"probe_id,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Timezone string `json:"timezone,omitempty"` // IANA timezone ส่งมากับ join เพื่อแสดงเวลาในคำสั่งตามเวลาท้องถิ่น
+	Capabilities []string `json:"capabilities,omitempty"` // ความสามารถที่ client ขอใช้ตอน join เช่น "hb"
+	ParentID string `json:"parent_id,omitempty"` // ข้อความที่ตอบ (message) หรือ thread ที่ขอดู (get_thread)
+	TransferID string `json:"transfer_id,omitempty"`
+	File     *transfer.FileInfo `json:"file,omitempty"` // metadata ของไฟล์ใน offer_file
+	Seq      int    `json:"seq,omitempty"`  // ลำดับ chunk ของ file_chunk/file_chunk_ack เริ่มที่ 0
+	Data     string `json:"data,omitempty"` // ข้อมูล chunk แบบ base64
+	IDs      []string `json:"ids,omitempty"` // pending message ที่อ่านแล้วของ mark_read
+	Token    string `json:"token,omitempty"` // resume token ที่ได้จาก session frame
+	SessionToken string `json:"session_token,omitempty"` // token จาก POST /api/login ส่งมากับ join
+	Password string `json:"password,omitempty"` // รหัสผ่านห้องของ join_room
+	StartDate *time.Time `json:"start_date,omitempty"` // ช่วงเวลาของ search_messages
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	DisplayName *string  `json:"display_name,omitempty"` // set_profile: ไม่ส่ง = คงเดิม, "" = ลบ
+	AvatarURL   *string  `json:"avatar_url,omitempty"`
+	StatusText  *string  `json:"status_text,omitempty"`
+	ClientMsgID string   `json:"client_msg_id,omitempty"` // message: id ของ client ใช้ dedup retry และจับคู่กับ ack
+
+	receivedAt time.Time // เวลาที่อ่าน frame ได้ ใช้วัด latency
 }
 
 // ServerMessage represents outgoing messages to client
@@ -50,19 +128,99 @@ type ServerMessage struct {
 	Room      string                `json:"room,omitempty"`
 	Timestamp time.Time             `json:"timestamp"`
 	Users     []string              `json:"users,omitempty"`
-	Rooms     []string              `json:"rooms,omitempty"`
+	Rooms     []RoomSummary         `json:"rooms,omitempty"`
 	Messages  []*messagePkg.Message   `json:"messages,omitempty"`
+	Results   []*search.Result      `json:"results,omitempty"`
+	Members   []RoomMember          `json:"members,omitempty"`
+	Total     int                   `json:"total,omitempty"`
+	Offset    int                   `json:"offset,omitempty"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 	Message   string                `json:"message,omitempty"`
+	Preferences map[string]string   `json:"preferences,omitempty"`
+	Snoozed   map[string]time.Time  `json:"snoozed,omitempty"`
+	Target    string                `json:"target,omitempty"`
+	Code      string                `json:"code,omitempty"`    // machine-readable error code
+	Details   map[string]interface{} `json:"details,omitempty"` // structured context for the error code
+	Presence  *userPkg.Presence     `json:"presence,omitempty"`
+	Capabilities *RoomCapabilities  `json:"capabilities,omitempty"`
+	Reactions []messagePkg.MessageReaction `json:"reactions,omitempty"`
+	Status    string                `json:"status,omitempty"` // online/away/offline ของ presence_changed
+	Features  []string              `json:"features,omitempty"` // capability ที่ server ยอมรับตอน join
+	Thread    *messagePkg.Thread    `json:"thread,omitempty"`
+	Server    *version.Info         `json:"server,omitempty"` // hello และ /version
+	Transfer  *transfer.Offer       `json:"transfer,omitempty"`
+	Chunk     *FileChunk            `json:"chunk,omitempty"`
+	Missed    []*messagePkg.PendingMessage `json:"missed,omitempty"` // missed_messages ตอน reconnect
+	Trace     *TraceFrame           `json:"trace,omitempty"` // trace_event ของ /trace
+	Unread    map[string]int64      `json:"unread,omitempty"` // room -> unread count ของ unread_counts
+	Notification *messagePkg.Notification `json:"notification,omitempty"` // notification ที่ push ถึงผู้ถูก mention
+	Notifications []*messagePkg.Notification `json:"notifications,omitempty"`
+	ResumeToken string                `json:"resume_token,omitempty"` // ส่งใน resume เมื่อเชื่อมต่อใหม่
+	Topic     string                `json:"topic,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Pinned    []*messagePkg.Message `json:"pinned,omitempty"` // ข้อความที่ปักหมุดของห้อง (room_joined, message_pinned)
+	Commands  []commands.Info       `json:"commands,omitempty"` // metadata สำหรับ autocomplete ของ get_commands
+	ProbeID   string                `json:"probe_id,omitempty"` // probe_echo ของ load test mode
+	Profile   *userPkg.Profile      `json:"profile,omitempty"`  // profile_updated
+	ClientMsgID string              `json:"client_msg_id,omitempty"` // ack และ error ของข้อความที่มี client_msg_id
+	MessageID string                `json:"message_id,omitempty"`    // ack: id ที่ server กำหนดเมื่อบันทึกข้อความ
+	Duplicate bool                  `json:"duplicate,omitempty"`     // ack ของ client_msg_id ที่เคยได้รับแล้ว
+}
+
+// RoomCapabilities tells clients what happens to messages posted in a room
+type RoomCapabilities struct {
+	Private     bool `json:"private"`     // privacy mode: delivery only
+	Persistence bool `json:"persistence"` // ข้อความถูกบันทึกและดูย้อนหลังได้
+	Search      bool `json:"search"`
+	Analytics   bool `json:"analytics"`
+}
+
+// RoomMember represents a member entry in a paginated users_list
+type RoomMember struct {
+	Username   string    `json:"username"`
+	Status     string    `json:"status"`
+	LastActive time.Time `json:"last_active"`
+	Presence   *userPkg.Presence `json:"presence,omitempty"` // สถานะจากระบบภายนอก เช่น calendar
+	DisplayName string   `json:"display_name,omitempty"`
+	AvatarURL   string   `json:"avatar_url,omitempty"`
+	StatusText  string   `json:"status_text,omitempty"`
+	Role        string   `json:"role,omitempty"` // role ในห้องนี้ ว่าง = member
+}
+
+// RoomSummary describes a room in rooms_list so clients can render a room directory
+type RoomSummary struct {
+	Name         string    `json:"name"`
+	UserCount    int       `json:"user_count"`
+	MaxUsers     int       `json:"max_users"`
+	Topic        string    `json:"topic,omitempty"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// summarizeRooms lists the rooms visible to a user, sorted by name
+func summarizeRooms(rooms []*room.Room, username string) []RoomSummary {
+	summaries := make([]RoomSummary, 0, len(rooms))
+	for _, chatRoom := range rooms {
+		// ห้อง invite-only แสดงเฉพาะกับสมาชิก เหมือน /rooms
+		if !chatRoom.VisibleTo(username) {
+			continue
+		}
+		summaries = append(summaries, RoomSummary{
+			Name:         chatRoom.Name,
+			UserCount:    len(chatRoom.Users),
+			MaxUsers:     chatRoom.MaxUsers,
+			Topic:        chatRoom.Topic,
+			LastActivity: chatRoom.LastActivity,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
 }
 
 // NewHandler creates a new HTTP handler
 func NewHandler(wsManager WebSocketManager, userService UserService, roomService RoomService, commandService CommandService, messageService MessageService, cfg *config.ServerConfig) *Handler {
-	return &Handler{
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // อนุญาตให้ทุก origin เชื่อมต่อได้ (สำหรับการพัฒนา)
-			},
-		},
+	h := &Handler{
+		origins:        security.NewOriginPolicy(cfg.AllowedOrigins),
+		proxies:        security.NewProxyPolicy(cfg.TrustedProxies),
 		wsManager:      wsManager,
 		userService:    userService,
 		roomService:    roomService,
@@ -72,7 +230,113 @@ func NewHandler(wsManager WebSocketManager, userService UserService, roomService
 		rateLimiter:    config.NewRateLimiter(cfg),
 		validator:      security.NewInputValidator(cfg),
 		messageRepo:    nil, // Will be set later if MongoDB is enabled
+		suggestDebounce: newDebouncer(cfg.SuggestDebounce),
+		memberFeed:     newMemberFeed(cfg.MemberDeltaInterval),
+		drafts:         newDraftStore(cfg.MaxDraftLength, cfg.MaxDraftsPerUser, cfg.DraftTTL),
+		preferences:    newPreferenceStore(cfg.MaxPreferenceKeys, cfg.MaxPreferenceKeyLength, cfg.MaxPreferenceValueLength),
+		liveSearches:   newLiveSearches(cfg.MaxLiveSearchesPerConnection),
+		snoozes:        newSnoozeStore(),
+		typing:         newTypingTracker(cfg.TypingDebounce),
+		traces:         newTraceSessions(),
+		sessions:       newSessionStore(cfg),
+		sampler:        newRoomSampler(cfg),
+		acks:           newAckCache(cfg.AckDedupWindow),
+	}
+	if h.sessions != nil {
+		h.sessions.onExpire = h.expireGuestSession
 	}
+	h.upgrader.CheckOrigin = h.origins.CheckOrigin
+	h.upgrader.Subprotocols = h.subprotocols()
+	h.upgrader.EnableCompression = cfg.EnableCompression
+
+	// ติดตามการเข้า/ออกห้อง เพื่อส่ง members_delta ให้ผู้ที่ subscribe ไว้
+	roomService.OnMembershipChange(h.memberFeed.Record)
+	roomService.OnMembershipChange(h.clearTypingOnLeave)
+	go h.memberFeed.Run()
+
+	// แจ้งนับถอยหลังและการหมดอายุของห้องชั่วคราว
+	roomService.OnExpiry(h.notifyRoomExpiry)
+
+	// แจ้งทุกคนเมื่อห้องที่ว่างนานถูก archive หรือถูกสร้างซ้ำจนกลับมา
+	roomService.OnArchive(h.notifyRoomArchive)
+
+	// snooze ใช้ store ของ handler ร่วมกับ get_prefs จึงลงทะเบียนคำสั่งจากที่นี่
+	commandService.RegisterCommand(&Command{
+		Name:        "snooze",
+		Description: "Snooze notifications for a room (messages are still delivered), or list snoozes",
+		Usage:       "/snooze [<room> <duration>|<room> off]",
+		Handler:     h.handleSnoozeCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "notifications",
+		Description: "List your mention notifications, or mark them as read",
+		Usage:       "/notifications [unread|read [id...]]",
+		Handler:     h.handleNotificationsCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "react",
+		Description: "Toggle an emoji reaction on a message in your current room",
+		Usage:       "/react <message_id> <emoji>",
+		Requires:    CapabilityPersistence,
+		Handler:     h.handleReactCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "edit",
+		Description: "Edit one of your messages in your current room (moderators can edit any)",
+		Usage:       "/edit <message_id> <new text>",
+		Requires:    CapabilityPersistence,
+		Handler:     h.handleEditCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "delete",
+		Description: "Delete one of your messages in your current room (moderators can delete any)",
+		Usage:       "/delete <message_id>",
+		Requires:    CapabilityPersistence,
+		Handler:     h.handleDeleteCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "status",
+		Description: "Show or set the status text shown next to your name",
+		Usage:       "/status [<text>|--clear]",
+		Handler:     h.handleStatusCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "topic",
+		Description: "Show or set the room topic or description (room owner only)",
+		Usage:       "/topic [<text>|--description <text>|--clear]",
+		Handler:     h.handleTopicCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:         "pin",
+		Description:  "Pin a message in your current room (room owner only)",
+		Usage:        "/pin <message_id>",
+		RequiredRole: RoleOwner,
+		Requires:     CapabilityPersistence,
+		Handler:      h.handlePinCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:         "unpin",
+		Description:  "Unpin a message in your current room (room owner only)",
+		Usage:        "/unpin <message_id>",
+		RequiredRole: RoleOwner,
+		Requires:     CapabilityPersistence,
+		Handler:      h.handleUnpinCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:        "version",
+		Description: "Show server version, protocol versions and enabled features",
+		Usage:       "/version",
+		Handler:     h.handleVersionCommand,
+	})
+	commandService.RegisterCommand(&Command{
+		Name:         "trace",
+		Description:  "Stream verbose events of one user's connection for a few minutes, or list traces (admin only)",
+		Usage:        "/trace [<username> [off]]",
+		RequiredRole: RoleAdmin,
+		Handler:      h.handleTraceCommand,
+	})
+
+	return h
 }
 
 // SetMessageRepository sets the message repository for persistence
@@ -80,31 +344,223 @@ func (h *Handler) SetMessageRepository(repo MessageRepository) {
 	h.messageRepo = repo
 }
 
+// SetSearchIndex sets the external search index used for indexing and search
+func (h *Handler) SetSearchIndex(index SearchIndex) {
+	h.searchIndex = index
+}
+
+// SetIPLimiter sets the per-IP concurrent connection limiter
+func (h *Handler) SetIPLimiter(limiter *security.IPLimiter) {
+	h.ipLimiter = limiter
+}
+
+// SetConnectionThrottle sets the per-IP connection throttle
+func (h *Handler) SetConnectionThrottle(throttle *security.ConnectionThrottle) {
+	h.throttle = throttle
+}
+
+// SetLatencyRecorder sets the recorder for per-stage message path timings
+func (h *Handler) SetLatencyRecorder(recorder *config.LatencyRecorder) {
+	h.latency = recorder
+}
+
+// SetChangeFeed enables live search subscriptions driven by new messages in the database
+func (h *Handler) SetChangeFeed(feed changefeed.ChangeFeed) {
+	h.changeFeedEnabled = true
+	feed.Subscribe("messages", h.liveSearches.HandleChange)
+
+	// ห้อง mirror กระจายข้อความจาก feed แทนการ broadcast ตรงจาก node ที่รับโพสต์
+	h.mirror = newRoomMirror(h.config.NodeID, func(roomName string) string {
+		if room, exists := h.roomService.GetRoom(roomName); exists {
+			return room.MirrorWriter
+		}
+		return ""
+	})
+	feed.Subscribe("rooms", h.mirror.HandleRoomChange)
+	feed.Subscribe("messages", h.fanOutMirrored)
+}
+
+// SetReaper registers the handler's per-connection and per-user state with the background reaper
+func (h *Handler) SetReaper(r *reaper.Reaper) {
+	r.Register("rate_limiter", h.rateLimiter)
+	r.Register("drafts", h.drafts)
+	r.Register("snoozes", h.snoozes)
+	r.Register("typing", h.typing)
+	if h.sampler != nil {
+		r.Register("room_sampler", h.sampler)
+	}
+	if h.sessions != nil {
+		r.Register("resumable_sessions", h.sessions)
+	}
+	r.Register("member_subscriptions", reaper.Func(func(aggressive bool) int {
+		return h.memberFeed.ReapClosed(func(connID string) bool {
+			_, exists := h.wsManager.GetConnection(connID)
+			return exists
+		})
+	}))
+	r.Register("live_searches", reaper.Func(func(aggressive bool) int {
+		return h.liveSearches.ReapClosed(func(connID string) bool {
+			_, exists := h.wsManager.GetConnection(connID)
+			return exists
+		})
+	}))
+}
+
+// SetNameGenerator enables generated names for guests that join without a username
+func (h *Handler) SetNameGenerator(generator *naming.Generator) {
+	h.guestNames = generator
+}
+
+// SetPresence sets the store of externally-set presence merged into users lists
+func (h *Handler) SetPresence(store *userPkg.PresenceStore) {
+	h.presence = store
+}
+
+// PresenceChanged tells the user's current room that their external presence changed
+func (h *Handler) PresenceChanged(username string) {
+	chatUser, exists := h.userService.GetUserByName(username)
+	if !exists || chatUser.CurrentRoom == "" {
+		return
+	}
+
+	message := ServerMessage{
+		Type:      "presence",
+		Username:  username,
+		Room:      chatUser.CurrentRoom,
+		Timestamp: time.Now(),
+	}
+	if h.presence != nil {
+		// nil เมื่อถูกลบหรือหมดอายุ ให้ client กลับไปใช้สถานะปกติ
+		message.Presence, _ = h.presence.Get(username)
+	}
+	h.broadcastJSONToRoom(message, "", chatUser.CurrentRoom)
+}
+
+// SetAnnouncements enables acknowledgment of admin announcements
+func (h *Handler) SetAnnouncements(store *announcement.Store) {
+	h.announcements = store
+}
+
+// SetServerMetrics sets the metrics that rejected WebSocket upgrades are counted in
+func (h *Handler) SetServerMetrics(metrics *config.ServerMetrics) {
+	h.metrics = metrics
+}
+
+// SetHoneypot sets the honeypot used to flag bots joining trap rooms
+func (h *Handler) SetHoneypot(honeypot *moderation.Honeypot) {
+	h.honeypot = honeypot
+}
+
 // HandleWebSocket handles WebSocket connection upgrades
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// หลัง nginx/load balancer RemoteAddr คือ proxy ban และ throttle ต้องใช้ IP ของ client จริง
+	ip := h.proxies.ClientIP(r)
+
+	// ตรวจสอบ IP ที่พยายามเชื่อมต่อถี่เกินไปก่อน upgrade
+	if h.throttle != nil {
+		if allowed, retryAfter := h.throttle.Allow(ip); !allowed {
+			slog.Warn("🚫 Rejected connection attempt", "ip", ip, "retry_after", retryAfter.Round(time.Second))
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			http.Error(w, "too many connection attempts", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	// ตรวจ origin ก่อน upgrade เพื่อ log และนับ request ที่ถูกปฏิเสธ (กัน cross-site WebSocket hijacking)
+	if origin := r.Header.Get("Origin"); !h.origins.Allowed(origin) {
+		slog.Warn("🚫 Rejected WebSocket upgrade: origin not allowed", "ip", ip, "origin", origin)
+		if h.metrics != nil {
+			h.metrics.IncrementRejectedUpgrades()
+		}
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	// IP เดียวเปิด connection ค้างไว้จนเต็ม MaxConnections ไม่ได้ (ชนซ้ำบ่อยจะถูก block)
+	if h.ipLimiter != nil {
+		if !h.ipLimiter.Acquire(ip) {
+			slog.Warn("🚫 Rejected connection: too many connections from one IP", "ip", ip, "limit", h.config.MaxConnectionsPerIP)
+			http.Error(w, "too many connections from your address", http.StatusTooManyRequests)
+			return
+		}
+	}
+
 	// Upgrade HTTP connection เป็น WebSocket
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
+		slog.Warn("❌ Failed to upgrade connection", "ip", ip, "error", err)
+		h.releaseIP(ip)
+		return
+	}
+
+	// hello เป็น frame แรกเสมอ ให้ client ตรวจ version/protocol ก่อน join
+	hello, err := h.helloFrame()
+	if err != nil {
+		slog.Error("❌ Failed to build hello frame", "error", err)
+	}
+
+	// เพิ่ม connection ไปยัง manager ซึ่งเริ่ม write pump ให้ด้วย
+	subprotocol := h.negotiatedSubprotocol(conn.Subprotocol())
+	codec := codecFor(subprotocol)
+	compressed := h.config.EnableCompression && wsocket.CompressionOffered(r)
+	if compressed {
+		// write pump เปิดการบีบอัดเองเฉพาะ frame ที่ถึง compression_threshold
+		conn.EnableWriteCompression(false)
+	}
+	connID, ok := h.wsManager.AddConnection(conn, hello, codec)
+	if !ok {
+		h.releaseIP(ip)
 		return
 	}
+	if compressed {
+		if connection, exists := h.wsManager.GetConnection(connID); exists {
+			if compressor, ok := connection.(compressionConn); ok {
+				compressor.EnableCompression()
+			}
+		}
+	}
+	slog.Info("🔗 New WebSocket connection", "conn_id", connID, "ip", ip, "remote_addr", conn.RemoteAddr().String(), "subprotocol", subprotocol, "compression", compressed)
+
+	go h.handleRead(conn, connID, ip, subprotocol, codec)
+}
 
-	// เพิ่ม connection ไปยัง manager
-	connID := h.wsManager.AddConnection(conn)
-	clientAddr := conn.RemoteAddr().String()
-	log.Printf("🔗 New WebSocket connection: %s (ID: %s)", clientAddr, connID)
+// compressionConn is implemented by connections that can compress large outbound frames
+type compressionConn interface {
+	EnableCompression() bool
+}
 
-	// เริ่ม goroutines สำหรับ read และ write
-	go h.handleRead(conn, connID, clientAddr)
-	go h.handleWrite(conn, connID, clientAddr)
+// releaseIP frees the per-IP connection slot taken in HandleWebSocket
+func (h *Handler) releaseIP(ip string) {
+	if h.ipLimiter != nil {
+		h.ipLimiter.Release(ip)
+	}
 }
 
-// handleRead จัดการการอ่านข้อความจาก client
-func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
+// handleRead จัดการการอ่านข้อความจาก client ตามรูปแบบ frame ของ subprotocol ที่ตกลงกันไว้
+func (h *Handler) handleRead(conn *websocket.Conn, connID, ip, subprotocol string, codec Codec) {
+	logger := slog.With("conn_id", connID, "ip", ip)
 	defer func() {
+		if connection, exists := h.wsManager.GetConnection(connID); exists && h.presenceTracker != nil {
+			if chatUser, ok := connection.GetUser().(*userPkg.User); ok && chatUser != nil && chatUser.IsAuthenticated {
+				// แจ้ง offline ก่อนลบ user ออกจากห้อง
+				h.presenceTracker.Disconnected(chatUser.Username)
+			}
+		}
+		if connection, exists := h.wsManager.GetConnection(connID); exists {
+			if chatUser, ok := connection.GetUser().(*userPkg.User); ok && chatUser != nil {
+				h.cancelTransfers(chatUser.Username)
+			}
+		}
+		h.stopTracesFor(connID)
+		h.memberFeed.Unsubscribe(connID)
+		h.liveSearches.Unsubscribe(connID, "")
+		h.suggestDebounce.Cancel(connID)
+		h.endGuestSession(connID)
+		h.detachSession(connID)
 		h.wsManager.RemoveConnection(connID)
 		conn.Close()
-		log.Printf("🔌 Connection closed: %s (ID: %s)", clientAddr, connID)
+		h.releaseIP(ip)
+		logger.Info("🔌 Connection closed")
 	}()
 
 	// ตั้งค่า read deadline
@@ -115,53 +571,133 @@ func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
 		// อัพเดท health status เมื่อได้รับ pong
 		if connection, exists := h.wsManager.GetConnection(connID); exists {
 			if wsConn, ok := connection.(*wsocket.WebSocketConnection); ok {
-				wsConn.Health.RecordPong()
+				wsConn.RecordPong()
 			}
 		}
 		
 		return nil
 	})
 
+	violations := h.quarantine.Track()
+	budget := h.frameLimiter.Track()
+
 	for {
 		// อ่านข้อความจาก client
 		_, rawMessage, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("❌ WebSocket error from %s: %v", clientAddr, err)
+				logger.Warn("❌ WebSocket error", "error", err)
 			}
 			break
 		}
+		receivedAt := time.Now()
 
 		messageContent := string(rawMessage)
-		log.Printf("📨 Received from %s: %s", clientAddr, messageContent)
 
 		// ดึง connection object
 		connection, exists := h.wsManager.GetConnection(connID)
 		if !exists {
-			log.Printf("❌ Connection not found: %s", connID)
+			logger.Error("❌ Connection not found")
 			break
 		}
 
-		// Try to parse as JSON first
+		// ติด correlation ID ให้ข้อความขาเข้าทุกข้อความ เพื่อไล่ log จาก handler ถึง repository ได้
+		if tracer, ok := connection.(correlationSetter); ok {
+			tracer.SetCorrelationID(wsocket.NewCorrelationID())
+		}
+		logger = slog.With("conn_id", connID, "ip", ip, "label", connection.GetLabel())
+		if wsConn, ok := connection.(*wsocket.WebSocketConnection); ok {
+			wsConn.Trace(wsocket.TraceFrameIn, len(rawMessage), "")
+		}
+
+		// connection ที่ถูกกักกันรอปิดอยู่ ไม่ประมวลผลอะไรอีก
+		if violations.Quarantined() {
+			continue
+		}
+
+		// จำกัดจำนวน frame ก่อนประมวลผลใดๆ ไม่ว่าจะเป็น type อะไร (ข้อความแชทมี rate limiter ของตัวเองอีกชั้น)
+		if allowed, retryAfter := budget.AllowFrame(); !allowed {
+			h.frameRateLimited(connection, budget, security.BudgetFrame, retryAfter)
+			continue
+		}
+		if codec.MessageType() == websocket.BinaryMessage {
+			connLogger(connection).Debug("📨 Received", "bytes", len(rawMessage), "codec", codec.Name())
+		} else {
+			connLogger(connection).Debug("📨 Received", "frame", redactSecrets(messageContent))
+		}
+
+		// subprotocol กำหนดรูปแบบ frame ไม่ต้องเดาว่าเป็น JSON หรือ plain text
+		// isJSON = frame เป็น envelope ที่มี type (JSON หรือ MessagePack)
 		var clientMsg ClientMessage
-		var isJSON bool
-		if err := json.Unmarshal(rawMessage, &clientMsg); err == nil && clientMsg.Type != "" {
-			isJSON = true
+		isJSON := subprotocol != SubprotocolText
+		if isJSON {
+			if err := codec.DecodeFrame(rawMessage, &clientMsg); err != nil || clientMsg.Type == "" {
+				h.protocolViolation(connection, violations, moderation.ViolationMalformedJSON, fmt.Sprintf("Malformed message: expected a %s object with a type", codec.Name()))
+				continue
+			}
 		} else {
-			// Fallback to plain text for backward compatibility
-			clientMsg = ClientMessage{
-				Type:    "message",
-				Content: messageContent,
+			clientMsg = parseTextFrame(messageContent)
+		}
+
+		if expensiveFrameTypes[clientMsg.Type] {
+			if allowed, retryAfter := budget.AllowExpensive(); !allowed {
+				h.frameRateLimited(connection, budget, security.BudgetExpensive, retryAfter)
+				continue
 			}
 		}
 
+		// heartbeat ระดับ application ทำหน้าที่แทน pong จึงไม่ผ่าน rate limit และไม่นับเป็นกิจกรรม
+		if isJSON && clientMsg.Type == "hb" {
+			h.handleAppHeartbeat(conn, connection)
+			continue
+		}
+
+		// นับเป็นกิจกรรมของผู้ใช้ ยกเว้น delivery_ack ที่ client ตอบ probe อัตโนมัติ (connection_timeout ตัด connection ที่ไม่มีกิจกรรม)
+		if recorder, ok := connection.(activityRecorder); ok && clientMsg.Type != "delivery_ack" {
+			recorder.RecordActivity()
+		}
+
 		// ตรวจสอบว่า user authenticated หรือยัง
 		user := connection.GetUser()
+		if user == nil && isJSON && clientMsg.Type == "resume" {
+			if !h.handleResume(connection, ip, clientMsg) {
+				break
+			}
+			continue
+		}
+		if user == nil && isJSON && clientMsg.Type != "join" {
+			h.protocolViolation(connection, violations, moderation.ViolationBeforeAuth, fmt.Sprintf("Join with a username before sending '%s'", clientMsg.Type))
+			continue
+		}
 		if user == nil {
 			// Handle authentication
 			var username string
-			if isJSON && clientMsg.Type == "join" && clientMsg.Username != "" {
+			var accountSession *account.Session
+			if isJSON && clientMsg.Type == "join" {
+				session, cerr := h.authenticateJoin(clientMsg.SessionToken)
+				if cerr != nil {
+					h.sendCodedError(connection, cerr)
+					if h.recordAuthFailure(connection, ip) {
+						break
+					}
+					continue
+				}
+				accountSession = session
+			}
+			if accountSession != nil {
+				// login แล้วใช้ชื่อของ account เสมอ ไม่สนชื่อที่ส่งมา
+				username = accountSession.Username
+			} else if isJSON && clientMsg.Type == "join" && clientMsg.Username != "" {
 				username = clientMsg.Username
+			} else if isJSON && clientMsg.Type == "join" && h.guestNames != nil {
+				// guest ที่ไม่ระบุชื่อ ได้ชื่อสุ่มที่ไม่ซ้ำกับผู้ใช้ที่ออนไลน์อยู่และไม่ใช่ชื่อที่มี account
+				guestName, err := h.guestNames.Generate(func(name string) bool {
+					return !h.userService.IsUsernameAvailable(name) || h.usernameRegistered(name)
+				})
+				if err != nil {
+					connLogger(connection).Warn("⚠️ Failed to generate guest name", "error", err)
+				}
+				username = guestName
 			} else {
 				username = strings.TrimSpace(messageContent)
 			}
@@ -169,11 +705,31 @@ func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
 			// Validate username
 			validatedUsername, err := h.validator.ValidateUsername(username)
 			if err != nil {
-				h.sendJSONMessage(connection, ServerMessage{
-					Type:      "error",
-					Message:   err.Error(),
-					Timestamp: time.Now(),
+				h.sendCodedError(connection, validationFailure(err))
+				if h.recordAuthFailure(connection, ip) {
+					break
+				}
+				continue
+			}
+
+			// ชื่อที่มี account แล้วใช้ได้เฉพาะเจ้าของที่ login
+			if cerr := h.checkAccountOwnership(validatedUsername, accountSession); cerr != nil {
+				h.sendCodedError(connection, cerr)
+				if h.recordAuthFailure(connection, ip) {
+					break
+				}
+				continue
+			}
+
+			// ชื่อของผู้ใช้ที่เพิ่งหลุดถูกจองไว้ให้ resume จนหมด grace period
+			if h.sessions.Reserved(validatedUsername) {
+				h.sendCodedError(connection, &codedError{
+					Code:    ErrCodeNameReserved,
+					Message: fmt.Sprintf("Username '%s' is reserved for a reconnecting session, try again later", validatedUsername),
 				})
+				if h.recordAuthFailure(connection, ip) {
+					break
+				}
 				continue
 			}
 
@@ -185,17 +741,38 @@ func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
 					Message:   fmt.Sprintf("Username already taken: %s", err.Error()),
 					Timestamp: time.Now(),
 				})
+				if h.recordAuthFailure(connection, ip) {
+					break
+				}
 				continue
 			}
 
+			if h.throttle != nil {
+				h.throttle.RecordAuthSuccess(ip)
+			}
+
+			// timezone จาก handshake ใช้แสดงเวลาใน output ของคำสั่ง (/history, /search ...)
+			if isJSON && clientMsg.Timezone != "" {
+				if err := newUser.SetTimezone(clientMsg.Timezone); err != nil {
+					connLogger(connection).Warn("⚠️ Ignoring handshake timezone", "error", err)
+				}
+			}
+
+			// guest ชื่อซ้ำกับ guest คนก่อนได้ จึงเริ่มจาก draft/preferences/snooze ว่าง
+			newUser.Guest = accountSession == nil
+			if newUser.Guest {
+				h.clearUserState(validatedUsername)
+			}
+
 			// เก็บ user ใน connection
 			connection.SetUser(newUser)
 
 			// เข้าห้อง default อัตโนมัติ
 			err = h.roomService.JoinRoom(newUser, "general")
 			if err != nil {
-				log.Printf("❌ Failed to join default room: %v", err)
+				connLogger(connection).Error("❌ Failed to join default room", "error", err)
 			}
+			h.touchPresence(validatedUsername)
 
 			// ส่งข้อความต้อนรับ
 			h.sendJSONMessage(connection, ServerMessage{
@@ -204,16 +781,33 @@ func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
 				Timestamp: time.Now(),
 			})
 
+			if isJSON {
+				h.negotiateCapabilities(connection, clientMsg.Capabilities)
+			}
+
 			// Send initial room and user lists
 			h.sendRoomsList(connection)
 			h.sendUsersList(connection, "general")
 
+			// ส่ง preferences ที่บันทึกไว้จากอุปกรณ์อื่น (ถ้ามี)
+			if len(h.preferences.GetAll(validatedUsername)) > 0 || len(h.snoozes.Active(validatedUsername)) > 0 {
+				h.sendPreferences(connection, newUser)
+			}
+
+			// mention และ DM ที่ส่งมาระหว่าง offline
+			h.deliverMissedMessages(connection, newUser)
+
+			// text client ส่ง resume ไม่ได้ จึงออก token ให้เฉพาะ JSON/MessagePack
+			if isJSON {
+				h.issueResumeToken(connection, newUser, clientMsg.Capabilities)
+			}
+
 			// แจ้งให้คนในห้องเดียวกันรู้ว่ามีคนเข้ามา
 			joinMsg := &messagePkg.Message{
 				Type:      "user_joined",
 				Content:   fmt.Sprintf("%s joined room 'general'", validatedUsername),
 				Sender:    "System",
-				Username:  "System",
+				Username:  validatedUsername, // client แสดงและอัปเดตรายชื่อจาก username
 				RoomName:  "general",
 				Timestamp: time.Now(),
 			}
@@ -223,133 +817,131 @@ func (h *Handler) handleRead(conn *websocket.Conn, connID, clientAddr string) {
 			// User authenticated แล้ว - ประมวลผลข้อความ
 			if chatUser, ok := user.(*userPkg.User); ok && chatUser.IsAuthenticated {
 				h.userService.UpdateLastActive(connID)
+				h.touchPresence(chatUser.Username)
+
+				// action แบบย่อ แปลงเป็นข้อความเต็มก่อน แล้วผ่าน rate limit และ dispatch ตามปกติ
+				if clientMsg.Type == "action" {
+					expanded, err := expandAction(clientMsg)
+					if err != nil {
+						h.sendJSONMessage(connection, ServerMessage{
+							Type:      "error",
+							Message:   err.Error(),
+							Timestamp: time.Now(),
+						})
+						continue
+					}
+					clientMsg = expanded
+				}
+
+				// delivery ack ไม่นับรวมใน rate limit
+				if clientMsg.Type == "delivery_ack" {
+					h.wsManager.RecordDeliveryAck(connID, clientMsg.ProbeID)
+					continue
+				}
+
+				// draft และ preferences เป็นการ sync สถานะของ client จึงไม่นับรวมใน rate limit ของข้อความ
+				switch clientMsg.Type {
+				case "save_draft":
+					h.handleSaveDraft(connection, chatUser, clientMsg)
+					continue
+				case "get_draft":
+					h.handleGetDraft(connection, chatUser, clientMsg)
+					continue
+				case "set_pref":
+					h.handleSetPreference(connection, chatUser, clientMsg)
+					continue
+				case "get_prefs":
+					h.sendPreferences(connection, chatUser)
+					continue
+				case "typing_start", "typing_stop":
+					// typing ถูก debounce ที่ server แล้ว จึงไม่นับรวมใน rate limit ของข้อความ
+					h.handleTyping(connection, chatUser, clientMsg)
+					continue
+				case "ack_announcement":
+					h.handleAckAnnouncement(connection, chatUser, clientMsg)
+					continue
+				case "file_chunk":
+					// chunk ถูกคุมด้วย window ของ transfer อยู่แล้ว
+					h.handleFileChunk(connection, chatUser, clientMsg)
+					continue
+				case "file_chunk_ack":
+					h.handleFileChunkAck(connection, chatUser, clientMsg)
+					continue
+				case "mark_read":
+					h.handleMarkRead(connection, chatUser, clientMsg)
+					continue
+				}
 
 				// Check rate limit
-				if !h.rateLimiter.CheckRateLimit(chatUser.ID) {
-					remaining, _, timeRemaining := h.rateLimiter.GetRateLimitStatus(chatUser.ID)
-					h.sendJSONMessage(connection, ServerMessage{
-						Type:      "error",
-						Message:   fmt.Sprintf("Rate limit exceeded! You can send %d more messages in %v", remaining, timeRemaining.Round(time.Second)),
-						Timestamp: time.Now(),
-					})
+				if !h.checkRateLimit(connection, chatUser.ID) {
 					continue
 				}
 
+				clientMsg.receivedAt = receivedAt
+
 				// Handle different message types
 				switch clientMsg.Type {
 				case "message":
 					h.handleChatMessage(connection, chatUser, clientMsg)
 				case "command":
 					h.handleCommand(connection, chatUser, clientMsg)
+				case "direct_message":
+					h.handleDirectMessage(connection, chatUser, clientMsg)
 				case "join_room":
 					h.handleJoinRoom(connection, chatUser, clientMsg)
 				case "leave_room":
 					h.handleLeaveRoom(connection, chatUser, clientMsg)
 				case "create_room":
 					h.handleCreateRoom(connection, chatUser, clientMsg)
+				case "get_thread":
+					h.handleGetThread(connection, chatUser, clientMsg)
 				case "get_history":
 					h.handleGetHistory(connection, chatUser, clientMsg)
 				case "get_my_history":
 					h.handleGetMyHistory(connection, chatUser, clientMsg)
 				case "search_messages":
 					h.handleSearchMessages(connection, chatUser, clientMsg)
+				case "suggest_users":
+					h.handleSuggestUsers(connection, chatUser, clientMsg)
+				case "get_users":
+					h.handleGetUsers(connection, chatUser, clientMsg)
+				case "subscribe_members":
+					h.handleSubscribeMembers(connection, chatUser, clientMsg)
+				case "unsubscribe_members":
+					h.memberFeed.Unsubscribe(connection.GetID())
+				case "react":
+					h.handleReaction(connection, chatUser, clientMsg)
+				case "set_export_key":
+					h.handleSetExportKey(connection, chatUser, clientMsg)
+				case "subscribe_search":
+					h.handleSubscribeSearch(connection, chatUser, clientMsg)
+				case "unsubscribe_search":
+					h.handleUnsubscribeSearch(connection, clientMsg)
+				case "offer_file":
+					h.handleOfferFile(connection, chatUser, clientMsg)
+				case "accept_file", "decline_file", "cancel_file":
+					h.handleFileResponse(connection, chatUser, clientMsg)
+				case "get_unread_counts":
+					h.handleGetUnreadCounts(connection, chatUser, clientMsg)
+				case "get_notifications":
+					h.handleGetNotifications(connection, chatUser, clientMsg)
+				case "get_commands":
+					h.handleGetCommands(connection, chatUser)
+				case "set_profile":
+					h.handleSetProfile(connection, chatUser, clientMsg)
 				default:
-					// Fallback to plain text message handling
-					if clientMsg.Content != "" {
-						if strings.HasPrefix(clientMsg.Content, "/") {
-							// Handle as command
-							clientMsg.Type = "command"
-							clientMsg.Command = clientMsg.Content
-							h.handleCommand(connection, chatUser, clientMsg)
-						} else {
-							// Handle as regular message
-							h.handleChatMessage(connection, chatUser, clientMsg)
-						}
-					}
+					h.protocolViolation(connection, violations, moderation.ViolationUnsupportedType, fmt.Sprintf("Unsupported message type '%s'", clientMsg.Type))
 				}
 			}
 		}
 	}
 }
 
-// handleWrite จัดการการเขียนข้อความไปยัง client
-func (h *Handler) handleWrite(conn *websocket.Conn, connID, clientAddr string) {
-	// ใช้ heartbeat interval จาก config
-	ticker := time.NewTicker(h.config.HeartbeatInterval)
-	defer func() {
-		ticker.Stop()
-		conn.Close()
-	}()
-
-	// รอให้ connection ถูก register ก่อน
-	time.Sleep(100 * time.Millisecond)
-
-	// ดึง connection object จาก manager
-	connection, exists := h.wsManager.GetConnection(connID)
-	if !exists {
-		log.Printf("❌ Connection not found: %s", connID)
-		return
-	}
-
-	// Get the send channel through type assertion
-	type SendChannelProvider interface {
-		GetSendChannel() chan []byte
-	}
-
-	sendProvider, ok := connection.(SendChannelProvider)
-	if !ok {
-		log.Printf("❌ Connection does not provide send channel: %s", connID)
-		return
-	}
-
-	sendChan := sendProvider.GetSendChannel()
-
-	for {
-		select {
-		case message, ok := <-sendChan:
-			if !ok {
-				// Channel ถูกปิด - connection หมดอายุ
-				conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
-			// ส่งข้อความไปยัง client
-			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("❌ Failed to send message to %s: %v", clientAddr, err)
-				return
-			}
-
-		case <-ticker.C:
-			// ตรวจสอบว่า connection ยังอยู่ใน manager หรือไม่
-			if _, exists := h.wsManager.GetConnection(connID); !exists {
-				// Connection ถูกลบจาก manager แล้ว - หยุดส่ง ping
-				return
-			}
-
-			// ส่ง ping เพื่อ keep connection alive
-			conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
-			
-			// บันทึก ping ใน health tracker
-			if wsConn, ok := connection.(*wsocket.WebSocketConnection); ok {
-				wsConn.Health.RecordPing()
-			}
-			
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("❌ Failed to send ping to %s: %v", clientAddr, err)
-				return
-			}
-			
-			log.Printf("💓 Sent heartbeat ping to %s", clientAddr)
-		}
-	}
-}
-
 // sendSystemMessage sends a system message to a specific connection
 func (h *Handler) sendSystemMessage(conn Connection, message string) {
 	err := conn.SendMessage([]byte(message))
 	if err != nil {
-		log.Printf("❌ Failed to send system message to %s", conn.GetID())
+		connLogger(conn).Warn("❌ Failed to send system message", "error", err)
 	}
 }
 
@@ -357,7 +949,7 @@ func (h *Handler) sendSystemMessage(conn Connection, message string) {
 func (h *Handler) sendErrorMessage(conn Connection, message string) {
 	err := conn.SendMessage([]byte(message))
 	if err != nil {
-		log.Printf("❌ Failed to send error message to %s", conn.GetID())
+		connLogger(conn).Warn("❌ Failed to send error message", "error", err)
 	}
 }
 
@@ -365,13 +957,13 @@ func (h *Handler) sendErrorMessage(conn Connection, message string) {
 func (h *Handler) sendJSONMessage(conn Connection, message ServerMessage) {
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("❌ Failed to marshal JSON message: %v", err)
+		connLogger(conn).Error("❌ Failed to marshal JSON message", "type", message.Type, "error", err)
 		return
 	}
 	
 	err = conn.SendMessage(data)
 	if err != nil {
-		log.Printf("❌ Failed to send JSON message to %s: %v", conn.GetID(), err)
+		connLogger(conn).Warn("❌ Failed to send JSON message", "type", message.Type, "error", err)
 	}
 }
 
@@ -379,7 +971,7 @@ func (h *Handler) sendJSONMessage(conn Connection, message ServerMessage) {
 func (h *Handler) broadcastJSONToRoom(message ServerMessage, excludeID, roomName string) {
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("❌ Failed to marshal JSON broadcast message: %v", err)
+		slog.Error("❌ Failed to marshal JSON broadcast message", "room", roomName, "type", message.Type, "error", err)
 		return
 	}
 	
@@ -388,27 +980,91 @@ func (h *Handler) broadcastJSONToRoom(message ServerMessage, excludeID, roomName
 
 // handleChatMessage handles regular chat messages
 func (h *Handler) handleChatMessage(conn Connection, user *userPkg.User, msg ClientMessage) {
+	// error ของข้อความมี client_msg_id ด้วย ให้ client รู้ว่าข้อความไหนไม่ต้อง retry
+	reject := func(frame ServerMessage) {
+		frame.Type = "error"
+		frame.ClientMsgID = msg.ClientMsgID
+		frame.Timestamp = time.Now()
+		h.sendJSONMessage(conn, frame)
+	}
+
+	// client_msg_id ที่ส่งซ้ำ (retry) ได้ ack เดิมโดยไม่บันทึกหรือกระจายข้อความอีก
+	if msg.ClientMsgID != "" {
+		if len(msg.ClientMsgID) > maxClientMsgIDLength {
+			reject(ServerMessage{
+				Message: fmt.Sprintf("client_msg_id too long (max %d characters)", maxClientMsgIDLength),
+				Code:    ErrCodeInvalidClientMsgID,
+				Details: map[string]interface{}{"max": maxClientMsgIDLength},
+			})
+			return
+		}
+		if ack, exists := h.acks.Get(user.Username, msg.ClientMsgID); exists {
+			h.sendAck(conn, msg.ClientMsgID, ack, true)
+			return
+		}
+	}
+
 	// ตรวจสอบว่าผู้ใช้อยู่ในห้องหรือไม่
 	if user.CurrentRoom == "" {
-		h.sendJSONMessage(conn, ServerMessage{
-			Type:      "error",
-			Message:   "You must be in a room to send messages. Use /join <room> to join a room",
-			Timestamp: time.Now(),
-		})
+		reject(ServerMessage{Message: "You must be in a room to send messages. Use /join <room> to join a room"})
 		return
 	}
 
+	if !msg.receivedAt.IsZero() {
+		h.latency.ObserveSince(config.StageRead, msg.receivedAt)
+	}
+
 	// Validate message content
+	stageStart := time.Now()
 	validatedMessage, err := h.validator.ValidateMessage(msg.Content)
+	h.latency.ObserveSince(config.StageValidate, stageStart)
 	if err != nil {
-		h.sendJSONMessage(conn, ServerMessage{
-			Type:      "error",
-			Message:   err.Error(),
-			Timestamp: time.Now(),
+		coded := validationFailure(err)
+		reject(ServerMessage{Message: coded.Message, Code: coded.Code, Details: coded.Details})
+		return
+	}
+
+	// จำกัดผลกระทบของ mention ก่อนบันทึกและกระจายข้อความ
+	if violation := h.checkMentionImpact(user, user.CurrentRoom, validatedMessage); violation != nil {
+		reject(ServerMessage{
+			Message: violation.Message,
+			Code:    violation.Code,
+			Details: violation.Details,
+			Room:    user.CurrentRoom,
 		})
 		return
 	}
 
+	// ห้อง private ส่งอย่างเดียว ไม่บันทึก ไม่ index
+	private := false
+	if room, exists := h.roomService.GetRoom(user.CurrentRoom); exists {
+		private = room.Private
+	}
+
+	// reply ต้องตอบข้อความที่บันทึกไว้ในห้องเดียวกัน
+	var parent *messagePkg.Message
+	if msg.ParentID != "" {
+		parent, err = h.resolveThreadParent(user, msg.ParentID, private)
+		if err != nil {
+			reject(ServerMessage{Message: err.Error()})
+			return
+		}
+	}
+
+	// ห้อง mirror รับโพสต์ที่ writer node เท่านั้น แล้วทุก node กระจายจาก change feed
+	mirrored := false
+	if h.mirror != nil {
+		if writer := h.mirror.Writer(user.CurrentRoom); writer != "" {
+			if writer != h.config.NodeID {
+				reject(ServerMessage{
+					Message: fmt.Sprintf("Room '%s' is a broadcast mirror; posts are only accepted on node %s", user.CurrentRoom, writer),
+				})
+				return
+			}
+			mirrored = true
+		}
+	}
+
 	// สร้าง message object
 	message := &messagePkg.Message{
 		Type:      "message",
@@ -417,27 +1073,102 @@ func (h *Handler) handleChatMessage(conn Connection, user *userPkg.User, msg Cli
 		Username:  user.Username,
 		RoomName:  user.CurrentRoom,
 		Timestamp: time.Now(),
+		CorrelationID: conn.GetCorrelationID(),
+		Mentions:  parseMentions(validatedMessage).Users,
+		DisplayName: user.DisplayName,
+		AvatarURL: user.AvatarURL,
+	}
+	if parent != nil {
+		message.ParentID = parent.ID
 	}
 
 	// Save message to database if MongoDB is enabled
-	if h.messageRepo != nil {
-		if err := h.messageRepo.SaveMessage(message); err != nil {
-			log.Printf("⚠️ Failed to save message to database: %v", err)
+	if h.messageRepo != nil && !private {
+		h.assignTimelineSeq(conn, message)
+		stageStart = time.Now()
+		err := h.messageRepo.SaveMessage(message)
+		h.latency.ObserveSince(config.StagePersist, stageStart)
+		if err != nil {
+			connLogger(conn).Warn("⚠️ Failed to save message to database", "message_id", message.ID, "error", err)
+			if mirrored {
+				// ไม่ได้บันทึกก็จะไม่มีใน feed ผู้รับจึงไม่ได้รับข้อความ
+				reject(ServerMessage{Message: "Failed to post to mirrored room, please retry"})
+				return
+			}
 		}
 	}
 
+	// Index message asynchronously if an external search backend is configured
+	if h.searchIndex != nil && !private {
+		h.searchIndex.IndexMessage(message)
+	}
+
 	// Create server message for broadcast
 	serverMsg := &messagePkg.Message{
+		ID:        message.ID,
 		Type:      "message",
 		Content:   validatedMessage,
 		Sender:    conn.GetID(),
 		Username:  user.Username,
 		RoomName:  user.CurrentRoom,
 		Timestamp: time.Now(),
+		ParentID:  message.ParentID,
+		Seq:       message.Seq,
+		Mentions:  message.Mentions,
+		DisplayName: user.DisplayName,
+		AvatarURL: user.AvatarURL,
+	}
+	// load test mode: ผู้รับวัด latency ปลายทางจาก probe_id ที่ผู้ส่งแนบมา
+	if h.config.LoadTestMode {
+		serverMsg.ProbeID = msg.ProbeID
+	}
+
+	// ส่งข้อความแล้วถือว่าหยุดพิมพ์ client ลบ indicator เองเมื่อได้รับข้อความ จึงไม่ต้อง broadcast typing_stop
+	h.typing.Stop(user.Username)
+
+	// Broadcast to room (excluding sender); ห้อง mirror ถูกกระจายจาก change feed แล้ว
+	// ห้องที่ใหญ่เกิน sampling threshold ส่งเต็มเฉพาะผู้ร่วมสนทนา ที่เหลือได้ digest
+	if !mirrored && !h.deliverSampled(conn, serverMsg) {
+		stageStart = time.Now()
+		h.wsManager.BroadcastToRoom(serverMsg, conn.GetID(), user.CurrentRoom)
+		h.latency.ObserveSince(config.StageEnqueue, stageStart)
+	}
+	h.sessions.RecordMissed(serverMsg)
+	h.roomService.RecordActivity(user.CurrentRoom)
+
+	// ack หลังบันทึกและกระจายแล้ว; message_id ว่างเมื่อไม่ได้บันทึก (ห้อง private หรือไม่มี database)
+	if msg.ClientMsgID != "" {
+		ack := messageAck{messageID: message.ID, room: user.CurrentRoom, status: "delivered", timestamp: message.Timestamp}
+		if message.ID != "" {
+			ack.status = "persisted"
+		}
+		h.acks.Put(user.Username, msg.ClientMsgID, ack)
+		h.sendAck(conn, msg.ClientMsgID, ack, false)
+	}
+
+	// ตอบผู้ส่งว่า server รับและกระจายข้อความแล้ว (ผู้ส่งไม่ได้รับ broadcast ของตัวเอง)
+	if h.config.LoadTestMode && msg.ProbeID != "" {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "probe_echo",
+			ProbeID:   msg.ProbeID,
+			Target:    message.ID,
+			Room:      user.CurrentRoom,
+			Timestamp: time.Now(),
+		})
+	}
+
+	if parent != nil {
+		h.recordThreadReply(parent, message)
+	}
+
+	// ผู้ถูก mention ที่ offline ได้รับข้อความตอน reconnect (ห้อง private ไม่เก็บ)
+	if !private {
+		h.queueOfflineMentions(conn, message)
+		h.notifyMentions(conn, message)
 	}
 
-	// Broadcast to room (excluding sender)
-	h.wsManager.BroadcastToRoom(serverMsg, conn.GetID(), user.CurrentRoom)
+	// ส่งแล้ว draft ของห้องนี้ไม่จำเป็นอีก
+	h.drafts.Delete(user.Username, user.CurrentRoom)
 }
 
 // handleCommand handles command messages
@@ -467,6 +1198,8 @@ func (h *Handler) handleCommand(conn Connection, user *userPkg.User, msg ClientM
 			// Handle as regular message
 			msg.Type = "message"
 			h.handleChatMessage(conn, user, msg)
+		} else if coded, ok := err.(*codedError); ok {
+			h.sendCodedError(conn, coded)
 		} else if strings.HasPrefix(err.Error(), "unknown command:") {
 			h.sendJSONMessage(conn, ServerMessage{
 				Type:      "error",
@@ -494,6 +1227,32 @@ func (h *Handler) handleJoinRoom(conn Connection, user *userPkg.User, msg Client
 		return
 	}
 
+	// ห้องกับดัก ตอบเหมือนห้องที่ไม่มีอยู่จริง
+	if h.honeypot != nil && h.honeypot.IsTrapRoom(msg.Room) {
+		tripHoneypot(h.honeypot, conn, "room:"+msg.Room)
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to join room: room '%s' does not exist", msg.Room),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := checkRoomSwitch(h.churn, user.Username); err != nil {
+		h.sendCodedError(conn, err)
+		return
+	}
+
+	// ตรวจสิทธิ์ก่อนออกจากห้องเดิม ผู้ใช้ที่ใส่รหัสผิดจะได้ยังอยู่ในห้องเดิม
+	if err := h.roomService.AuthorizeJoin(msg.Room, user.Username, msg.Password); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to join room: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	// Leave current room if in one
 	if user.CurrentRoom != "" {
 		h.roomService.LeaveRoom(user, user.CurrentRoom)
@@ -510,18 +1269,39 @@ func (h *Handler) handleJoinRoom(conn Connection, user *userPkg.User, msg Client
 		return
 	}
 
-	// Send confirmation
-	h.sendJSONMessage(conn, ServerMessage{
-		Type:      "room_joined",
-		Room:      msg.Room,
-		Timestamp: time.Now(),
-	})
+	// Send confirmation พร้อม topic และข้อความที่ปักหมุด
+	joined := ServerMessage{
+		Type:         "room_joined",
+		Room:         msg.Room,
+		Capabilities: h.roomCapabilities(msg.Room),
+		Pinned:       pinnedMessages(h.roomService, h.messageRepo, msg.Room),
+		Timestamp:    time.Now(),
+	}
+	if chatRoom, exists := h.roomService.GetRoom(msg.Room); exists {
+		joined.Topic = chatRoom.Topic
+		joined.Description = chatRoom.Description
+	}
+	h.sendJSONMessage(conn, joined)
 
 	// Update room and user lists
 	h.sendRoomsList(conn)
 	h.sendUsersList(conn, msg.Room)
 }
 
+// roomCapabilities describes how messages in a room are handled on this server
+func (h *Handler) roomCapabilities(roomName string) *RoomCapabilities {
+	private := false
+	if room, exists := h.roomService.GetRoom(roomName); exists {
+		private = room.Private
+	}
+	return &RoomCapabilities{
+		Private:     private,
+		Persistence: h.messageRepo != nil && !private,
+		Search:      (h.messageRepo != nil || h.searchIndex != nil) && !private,
+		Analytics:   h.messageRepo != nil && !private,
+	}
+}
+
 // handleLeaveRoom handles room leaving
 func (h *Handler) handleLeaveRoom(conn Connection, user *userPkg.User, msg ClientMessage) {
 	if user.CurrentRoom == "" {
@@ -533,6 +1313,11 @@ func (h *Handler) handleLeaveRoom(conn Connection, user *userPkg.User, msg Clien
 		return
 	}
 
+	if err := checkRoomSwitch(h.churn, user.Username); err != nil {
+		h.sendCodedError(conn, err)
+		return
+	}
+
 	roomName := user.CurrentRoom
 	err := h.roomService.LeaveRoom(user, roomName)
 	if err != nil {
@@ -562,11 +1347,23 @@ func (h *Handler) handleCreateRoom(conn Connection, user *userPkg.User, msg Clie
 		return
 	}
 
-	// Create room (this would need to be implemented in room service)
-	// For now, just send success message
+	// สร้างผ่านเส้นทางเดียวกับ /create เพื่อให้ตรวจสิทธิ์และชื่อห้องกับดักเหมือนกัน
+	roomName, err := h.validator.ValidateRoomName(msg.Room)
+	if err == nil {
+		err = h.commandService.ExecuteCommand(conn, "/create "+roomName)
+	}
+	if err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to create room: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	h.sendJSONMessage(conn, ServerMessage{
 		Type:      "room_created",
-		Room:      msg.Room,
+		Room:      roomName,
 		Timestamp: time.Now(),
 	})
 
@@ -595,6 +1392,15 @@ func (h *Handler) handleGetHistory(conn Connection, user *userPkg.User, msg Clie
 		roomName = user.CurrentRoom
 	}
 
+	if err := roomReadError(h.roomService, roomName, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to get history: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	messages, err := h.messageRepo.GetMessageHistory(roomName, limit)
 	if err != nil {
 		h.sendJSONMessage(conn, ServerMessage{
@@ -607,6 +1413,7 @@ func (h *Handler) handleGetHistory(conn Connection, user *userPkg.User, msg Clie
 
 	h.sendJSONMessage(conn, ServerMessage{
 		Type:      "history",
+		Room:      roomName,
 		Messages:  messages,
 		Timestamp: time.Now(),
 	})
@@ -645,9 +1452,19 @@ func (h *Handler) handleGetMyHistory(conn Connection, user *userPkg.User, msg Cl
 	})
 }
 
+// handleGetCommands sends the commands the user can run, with argument metadata for autocomplete
+func (h *Handler) handleGetCommands(conn Connection, user *userPkg.User) {
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "commands",
+		Room:      user.CurrentRoom,
+		Commands:  h.commandService.DescribeCommands(user),
+		Timestamp: time.Now(),
+	})
+}
+
 // handleSearchMessages handles message search requests
 func (h *Handler) handleSearchMessages(conn Connection, user *userPkg.User, msg ClientMessage) {
-	if h.messageRepo == nil {
+	if h.messageRepo == nil && h.searchIndex == nil {
 		h.sendJSONMessage(conn, ServerMessage{
 			Type:      "error",
 			Message:   "Message search not available",
@@ -670,7 +1487,70 @@ func (h *Handler) handleSearchMessages(conn Connection, user *userPkg.User, msg
 		roomName = user.CurrentRoom
 	}
 
-	messages, err := h.messageRepo.SearchMessages(msg.Query, roomName, 50)
+	query := search.ParseQuery(msg.Query)
+	if query.RoomName == "" {
+		query.RoomName = roomName
+	}
+	if query.RoomName == "" {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Join a room or give room: to search",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err := roomReadError(h.roomService, query.RoomName, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Search failed: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	query.StartDate = msg.StartDate
+	query.EndDate = msg.EndDate
+
+	// ใช้ search index ก่อน ถ้าใช้ไม่ได้ค่อย fallback ไปที่ MongoDB
+	// (index ภายนอกไม่รองรับการแบ่งหน้า จึงแบ่งหน้าได้เฉพาะ MongoDB text search)
+	if h.searchIndex != nil {
+		query.Limit = 50
+
+		results, err := h.searchIndex.Search(query)
+		if err == nil {
+			messages := make([]*messagePkg.Message, 0, len(results))
+			for _, result := range results {
+				messages = append(messages, result.Message)
+			}
+
+			h.sendJSONMessage(conn, ServerMessage{
+				Type:      "search_results",
+				Messages:  messages,
+				Results:   results,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		connLogger(conn).Warn("⚠️ Search index query failed, falling back to MongoDB", "error", err)
+	}
+
+	if h.messageRepo == nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Message search not available",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	textQuery := textSearchQuery(query)
+	textQuery.Limit = msg.Limit
+	if textQuery.Limit <= 0 {
+		textQuery.Limit = 50
+	}
+	textQuery.Offset = msg.Offset
+	textQuery.Cursor = msg.Cursor
+
+	scored, nextCursor, err := h.messageRepo.SearchMessagesText(textQuery)
 	if err != nil {
 		h.sendJSONMessage(conn, ServerMessage{
 			Type:      "error",
@@ -680,35 +1560,425 @@ func (h *Handler) handleSearchMessages(conn Connection, user *userPkg.User, msg
 		return
 	}
 
+	messages := make([]*messagePkg.Message, 0, len(scored))
+	results := make([]*search.Result, 0, len(scored))
+	for _, hit := range scored {
+		messages = append(messages, hit.Message)
+		results = append(results, &search.Result{Message: hit.Message, Score: hit.Score})
+	}
+
+	offset := msg.Offset
+	if msg.Cursor != "" {
+		offset, _ = strconv.Atoi(msg.Cursor)
+	}
 	h.sendJSONMessage(conn, ServerMessage{
-		Type:      "search_results",
-		Messages:  messages,
-		Timestamp: time.Now(),
+		Type:       "search_results",
+		Messages:   messages,
+		Results:    results,
+		Total:      len(results),
+		Offset:     offset,
+		NextCursor: nextCursor,
+		Timestamp:  time.Now(),
+	})
+}
+
+// handleSuggestUsers returns usernames in the current room matching a prefix (for @-mention autocomplete)
+func (h *Handler) handleSuggestUsers(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if user.CurrentRoom == "" {
+		return
+	}
+
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(msg.Query), "@"))
+	limit := msg.Limit
+	if limit <= 0 || limit > h.config.SuggestMaxResults {
+		limit = h.config.SuggestMaxResults
+	}
+	roomName := user.CurrentRoom
+
+	// client ส่งทุกครั้งที่พิมพ์ ตอบเฉพาะคำขอล่าสุดหลังจากหยุดพิมพ์
+	h.suggestDebounce.Call(conn.GetID(), func() {
+		candidates := make([]*userPkg.User, 0)
+		for _, u := range h.roomService.GetUsersInRoom(roomName) {
+			if u.Username == user.Username {
+				continue
+			}
+			if strings.HasPrefix(strings.ToLower(u.Username), prefix) {
+				candidates = append(candidates, u)
+			}
+		}
+
+		// เรียงตามความเคลื่อนไหวล่าสุด
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].LastActive.After(candidates[j].LastActive)
+		})
+		if len(candidates) > limit {
+			candidates = candidates[:limit]
+		}
+
+		usernames := make([]string, 0, len(candidates))
+		for _, u := range candidates {
+			usernames = append(usernames, u.Username)
+		}
+
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "user_suggestions",
+			Content:   msg.Query,
+			Room:      roomName,
+			Users:     usernames,
+			Timestamp: time.Now(),
+		})
 	})
 }
 
-// sendRoomsList sends the list of available rooms
+// sendRoomsList sends the rooms the connection's user can see, with user counts and last activity
 func (h *Handler) sendRoomsList(conn Connection) {
-	// This would need to be implemented to get actual room list
-	// For now, send a basic list
-	rooms := []string{"general"} // This should come from room service
-	
+	username := ""
+	if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+		username = chatUser.Username
+	}
+
 	h.sendJSONMessage(conn, ServerMessage{
 		Type:      "rooms_list",
-		Rooms:     rooms,
+		Rooms:     summarizeRooms(h.roomService.GetRooms(), username),
 		Timestamp: time.Now(),
 	})
 }
 
-// sendUsersList sends the list of users in a room
-func (h *Handler) sendUsersList(conn Connection, roomName string) {
-	// This would need to be implemented to get actual user list
-	// For now, send empty list
-	users := []string{} // This should come from room service
-	
+// handleGetUsers handles paginated member list requests
+func (h *Handler) handleGetUsers(conn Connection, user *userPkg.User, msg ClientMessage) {
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+	if roomName == "" {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Room name is required",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err := roomReadError(h.roomService, roomName, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	offset := msg.Offset
+	if msg.Cursor != "" {
+		parsed, err := strconv.Atoi(msg.Cursor)
+		if err != nil || parsed < 0 {
+			h.sendJSONMessage(conn, ServerMessage{
+				Type:      "error",
+				Message:   "Invalid cursor",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		offset = parsed
+	}
+
+	h.sendUsersPage(conn, roomName, offset, msg.Limit)
+}
+
+// handleSubscribeMembers subscribes a connection to membership deltas for a room
+func (h *Handler) handleSubscribeMembers(conn Connection, user *userPkg.User, msg ClientMessage) {
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+	if err := roomReadError(h.roomService, roomName, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.memberFeed.Subscribe(conn, roomName)
 	h.sendJSONMessage(conn, ServerMessage{
-		Type:      "users_list",
-		Users:     users,
+		Type:      "members_subscribed",
+		Room:      roomName,
 		Timestamp: time.Now(),
 	})
-}
\ No newline at end of file
+}
+
+// handleSetExportKey sets (content) or clears (empty content) the OpenPGP public key used to encrypt room exports
+func (h *Handler) handleSetExportKey(conn Connection, user *userPkg.User, msg ClientMessage) {
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+
+	armoredKey := strings.TrimSpace(msg.Content)
+	if armoredKey != "" {
+		if err := export.ValidateRecipientKey(armoredKey); err != nil {
+			h.sendJSONMessage(conn, ServerMessage{
+				Type:      "error",
+				Message:   fmt.Sprintf("Failed to set export key: %s", err.Error()),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	if err := h.roomService.SetExportKey(roomName, user.Username, armoredKey); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to set export key: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	message := fmt.Sprintf("🔐 Exports of room '%s' will be encrypted with your public key", roomName)
+	if armoredKey == "" {
+		message = fmt.Sprintf("🔓 Export key of room '%s' removed, exports are no longer encrypted", roomName)
+	}
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "system",
+		Message:   message,
+		Room:      roomName,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleSaveDraft stores the text a user is composing in a room
+func (h *Handler) handleSaveDraft(conn Connection, user *userPkg.User, msg ClientMessage) {
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+	if roomName == "" {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Room is required to save a draft",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := h.drafts.Save(user.Username, roomName, msg.Content); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to save draft: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// handleGetDraft sends the saved draft for a room (empty content if none)
+func (h *Handler) handleGetDraft(conn Connection, user *userPkg.User, msg ClientMessage) {
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+
+	content, updatedAt, exists := h.drafts.Get(user.Username, roomName)
+	if !exists {
+		updatedAt = time.Now()
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "draft",
+		Room:      roomName,
+		Content:   content,
+		Timestamp: updatedAt,
+	})
+}
+
+// handleAckAnnouncement records an explicit acknowledgment of an admin announcement
+func (h *Handler) handleAckAnnouncement(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.announcements == nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Announcements are not enabled",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := h.announcements.Acknowledge(msg.Target, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "announcement_acked",
+		Target:    msg.Target,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleSetPreference stores a single UI preference and replies with the full set
+func (h *Handler) handleSetPreference(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if err := h.preferences.Set(user.Username, msg.Key, msg.Value); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to save preference: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	h.sendPreferences(conn, user)
+}
+
+// sendPreferences sends all UI preferences of a user
+func (h *Handler) sendPreferences(conn Connection, user *userPkg.User) {
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:        "preferences",
+		Preferences: h.preferences.GetAll(user.Username),
+		Snoozed:     h.snoozes.Active(user.Username),
+		Timestamp:   time.Now(),
+	})
+}
+
+// sendUsersList sends the first page of users in a room
+func (h *Handler) sendUsersList(conn Connection, roomName string) {
+	h.sendUsersPage(conn, roomName, 0, 0)
+}
+
+// sendUsersPage sends a page of users in a room, sorted by presence then name
+func (h *Handler) sendUsersPage(conn Connection, roomName string, offset, limit int) {
+	if limit <= 0 || limit > h.config.UsersPageMaxSize {
+		limit = h.config.UsersPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	members := make([]RoomMember, 0)
+	for _, u := range h.roomService.GetUsersInRoom(roomName) {
+		member := RoomMember{
+			Username:   u.Username,
+			Status:     h.memberPresence(u.Username, u.LastActive),
+			LastActive: u.LastActive,
+		}
+		if h.presence != nil {
+			member.Presence, _ = h.presence.Get(u.Username)
+		}
+		if profile := h.userService.GetProfile(u.Username); profile != nil {
+			member.DisplayName = profile.DisplayName
+			member.AvatarURL = profile.AvatarURL
+			member.StatusText = profile.StatusText
+		}
+		if role := h.roleOf(h.connectedUser(u), roomName); role != RoleMember {
+			member.Role = role.String()
+		}
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if rankI, rankJ := presenceRank(members[i].Status), presenceRank(members[j].Status); rankI != rankJ {
+			return rankI < rankJ
+		}
+		return strings.ToLower(members[i].Username) < strings.ToLower(members[j].Username)
+	})
+
+	total := len(members)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := members[offset:end]
+
+	users := make([]string, 0, len(page))
+	for _, m := range page {
+		users = append(users, m.Username)
+	}
+
+	nextCursor := ""
+	if end < total {
+		nextCursor = strconv.Itoa(end)
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:       "users_list",
+		Room:       roomName,
+		Users:      users,
+		Members:    page,
+		Total:      total,
+		Offset:     offset,
+		NextCursor: nextCursor,
+		Timestamp:  time.Now(),
+	})
+}
+
+// recordAuthFailure records a failed login for the IP, reporting whether the connection should be closed
+func (h *Handler) recordAuthFailure(conn Connection, ip string) bool {
+	if h.throttle == nil || !h.throttle.RecordAuthFailure(ip) {
+		return false
+	}
+
+	if closer, ok := conn.(closeMarker); ok {
+		closer.MarkClose(wsocket.CloseThrottled, nil)
+	}
+	return true
+}
+
+// closeMarker is implemented by connections that can carry a server-initiated close code
+type closeMarker interface {
+	MarkClose(code int, notice []byte)
+}
+
+// activityRecorder is implemented by connections that track client activity for the idle reaper
+type activityRecorder interface {
+	RecordActivity()
+}
+
+// correlationSetter is implemented by connections that can carry a per-message correlation ID
+type correlationSetter interface {
+	SetCorrelationID(id string)
+}
+
+// secretPatterns match secrets in logged frames: "password" fields, --password flags, /join <room> <password>
+// and the bearer tokens of join (session_token) and resume (token)
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`("password"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`("(?:session_token|token)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`(--password\s+)[^\s"]+`),
+	regexp.MustCompile(`(/join\s+[^\s"]+\s+)[^\s"]+`),
+}
+
+// roomReadError returns why a user may not read a room's content, or nil if they may.
+// ห้อง invite-only ตอบเหมือนห้องที่ไม่มีอยู่ เหมือนกับ AuthorizeJoin
+func roomReadError(rooms RoomService, roomName, username string) error {
+	if rooms.IsMember(roomName, username) {
+		return nil
+	}
+	if chatRoom, exists := rooms.GetRoom(roomName); exists && !chatRoom.IsPrivate {
+		return fmt.Errorf("room '%s' is password-protected; join it first", roomName)
+	}
+	return fmt.Errorf("room '%s' does not exist", roomName)
+}
+
+// redactSecrets masks passwords and tokens before a frame is written to the log
+func redactSecrets(content string) string {
+	for _, pattern := range secretPatterns {
+		content = pattern.ReplaceAllString(content, "${1}***")
+	}
+	return content
+}
+
+// connLogger returns a structured logger carrying the connection's conn_id, correlation_id, username and room
+func connLogger(conn Connection) *slog.Logger {
+	logger := slog.With("conn_id", conn.GetID())
+	if correlationID := conn.GetCorrelationID(); correlationID != "" {
+		logger = logger.With("correlation_id", correlationID)
+	}
+	if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+		logger = logger.With("username", chatUser.Username, "room", chatUser.CurrentRoom)
+	}
+	return logger
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// CapabilityAppHeartbeat is the join capability for application-level heartbeats.
+// proxy บางตัวตัด WS ping/pong ทิ้ง client จึงส่ง {"type":"hb"} เป็นข้อความปกติแทน
+const CapabilityAppHeartbeat = "hb"
+
+// CapabilityBatch is the join capability for outbound batching: frames may arrive as a JSON array of messages
+const CapabilityBatch = "batch"
+
+// appHeartbeatConn is implemented by connections that can negotiate application-level heartbeats
+type appHeartbeatConn interface {
+	EnableAppHeartbeat()
+	AppHeartbeatEnabled() bool
+}
+
+// batchingConn is implemented by connections that can coalesce outbound frames
+type batchingConn interface {
+	EnableBatching() bool
+}
+
+// negotiateCapabilities enables the join capabilities the server supports and tells the client which were accepted
+func (h *Handler) negotiateCapabilities(conn Connection, requested []string) {
+	if len(requested) == 0 {
+		return
+	}
+
+	accepted := make([]string, 0, len(requested))
+	for _, capability := range requested {
+		switch capability {
+		case CapabilityAppHeartbeat:
+			hb, ok := conn.(appHeartbeatConn)
+			if !ok || !h.config.EnableAppHeartbeat {
+				continue
+			}
+			hb.EnableAppHeartbeat()
+			accepted = append(accepted, capability)
+		case CapabilityBatch:
+			batcher, ok := conn.(batchingConn)
+			if !ok || !h.config.EnableBatching || !batcher.EnableBatching() {
+				continue
+			}
+			accepted = append(accepted, capability)
+		}
+	}
+
+	details := map[string]interface{}{
+		"heartbeat_interval_seconds": int(h.config.HeartbeatInterval.Seconds()),
+		"batch_window_ms":            h.config.BatchWindow.Milliseconds(),
+		"batch_max_size":             h.config.BatchMaxSize,
+	}
+	// multi-region: client ดู region ของ node ที่ต่ออยู่ และเลือก endpoint อื่นได้จาก GET /api/regions
+	if h.config.Region != "" {
+		details["region"] = h.config.Region
+	}
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "capabilities",
+		Features:  accepted,
+		Details:   details,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleAppHeartbeat treats an "hb" message like a pong: it feeds the health tracker and extends the read deadline.
+// ไม่นับเป็นกิจกรรมของผู้ใช้ (presence ยัง away ได้) และไม่นับรวมใน rate limit
+func (h *Handler) handleAppHeartbeat(wsConn *websocket.Conn, conn Connection) {
+	hb, ok := conn.(appHeartbeatConn)
+	if !ok || !hb.AppHeartbeatEnabled() {
+		connLogger(conn).Warn("⚠️ Ignoring heartbeat without negotiated capability")
+		return
+	}
+
+	wsConn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	if healthConn, ok := conn.(*wsocket.WebSocketConnection); ok {
+		healthConn.RecordPong()
+	}
+	h.sendJSONMessage(conn, ServerMessage{Type: "hb", Timestamp: time.Now()})
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/version"
+)
+
+// serverInfo returns the build information and the features enabled on this server.
+// features เปลี่ยนได้ตาม config และ repository ที่ตั้งไว้ตอน start จึงคำนวณใหม่ทุกครั้ง
+func (h *Handler) serverInfo() version.Info {
+	return version.Get(map[string]bool{
+		"persistence":       h.messageRepo != nil,
+		"reactions":         h.messageRepo != nil,
+		"edits":             h.messageRepo != nil,
+		"threads":           h.threadRepo != nil,
+		"search":            h.messageRepo != nil || h.searchIndex != nil,
+		"presence":          h.presenceTracker != nil,
+		"typing":            true,
+		"app_heartbeat":     h.config.EnableAppHeartbeat,
+		"batching":          h.config.EnableBatching,
+		"compression":       h.config.EnableCompression,
+		"room_switch_limit": h.churn != nil,
+		"file_transfer":     h.transfers != nil,
+		"offline_mailbox":   h.mailbox != nil,
+		"notifications":     h.notifications != nil,
+		"session_resume":    h.sessions != nil,
+		"msgpack":           h.config.EnableMsgpack,
+		"room_sampling":     h.sampler != nil,
+		"command_metadata":  true,
+	})
+}
+
+// helloFrame builds the first frame sent on a new connection, before the join handshake
+func (h *Handler) helloFrame() ([]byte, error) {
+	info := h.serverInfo()
+	return json.Marshal(ServerMessage{
+		Type:      "hello",
+		Server:    &info,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleVersionCommand handles /version
+func (h *Handler) handleVersionCommand(conn Connection, args []string) error {
+	info := h.serverInfo()
+	return replyCommand(conn, ServerMessage{
+		Content: fmt.Sprintf("ℹ️ Server %s (commit %s, built %s, %s)\n• Protocol: %d (supported: %s)\n• Features: %s",
+			info.Version, info.Commit, info.BuildTime, info.GoVersion,
+			info.Protocol, strings.Trim(fmt.Sprint(info.Protocols), "[]"),
+			strings.Join(info.EnabledFeatures(), ", ")),
+		Server: &info,
+	})
+}
@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/moderation"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetHoneypot enables honeypot rooms/commands
+func (s *commandService) SetHoneypot(honeypot *moderation.Honeypot) {
+	s.honeypot = honeypot
+}
+
+// SetAuditLog registers the moderation review command for flags recorded to the audit log
+func (s *commandService) SetAuditLog(audit *moderation.AuditLog) {
+	s.auditLog = audit
+
+	s.RegisterCommand(&Command{
+		Name:         "flags",
+		Description:  "Show connections flagged for moderation review and login lockouts (admin only)",
+		Usage:        "/flags [limit]",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleFlags,
+	})
+}
+
+func (s *commandService) handleFlags(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+
+	limit := 20
+	if len(args) > 0 {
+		if l, err := strconv.Atoi(args[0]); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	audit := s.auditLog
+	flags := audit.Recent(limit)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🚩 Flagged for review (%d):\n", len(flags)))
+	for _, flag := range flags {
+		text.WriteString(fmt.Sprintf("• [%s] %s - %s %s (total: %d)\n",
+			admin.FormatTime(flag.Time, time.RFC3339), flag.Connection, flag.Reason, flag.Detail, audit.FlagCount(flag.Username)))
+	}
+
+	return replySystem(conn, text.String())
+}
+
+// tripHoneypot flags the connection when it touches a honeypot trap
+func tripHoneypot(honeypot *moderation.Honeypot, conn Connection, trap string) {
+	username := ""
+	if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+		username = chatUser.Username
+	}
+	honeypot.Trip(username, conn.GetLabel(), trap)
+}
@@ -0,0 +1,236 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/changefeed"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/search"
+	userPkg "realtime-chat/internal/user"
+)
+
+// liveSearch is a standing query whose new matches are pushed to a connection
+type liveSearch struct {
+	id    string
+	query search.Query
+	terms []string // คำค้นตัวพิมพ์เล็ก ต้องพบทุกคำ
+	conn  Connection
+}
+
+// liveSearches matches newly persisted messages (from any node, via the change feed)
+// against standing queries and pushes "search_match" to the subscriber
+type liveSearches struct {
+	subscriptions map[string]map[string]*liveSearch // connID -> subscription ID -> search
+	maxPerConn    int
+	nextID        int
+	mutex         sync.Mutex
+}
+
+// newLiveSearches creates a new live search registry
+func newLiveSearches(maxPerConn int) *liveSearches {
+	return &liveSearches{
+		subscriptions: make(map[string]map[string]*liveSearch),
+		maxPerConn:    maxPerConn,
+	}
+}
+
+// Subscribe registers a standing query for a connection and returns its subscription ID
+func (l *liveSearches) Subscribe(conn Connection, query search.Query) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	searches, exists := l.subscriptions[conn.GetID()]
+	if !exists {
+		searches = make(map[string]*liveSearch)
+		l.subscriptions[conn.GetID()] = searches
+	}
+	if len(searches) >= l.maxPerConn {
+		return "", fmt.Errorf("too many live searches (max %d)", l.maxPerConn)
+	}
+
+	l.nextID++
+	id := "ls" + strconv.Itoa(l.nextID)
+	searches[id] = &liveSearch{
+		id:    id,
+		query: query,
+		terms: strings.Fields(strings.ToLower(query.Text)),
+		conn:  conn,
+	}
+	return id, nil
+}
+
+// Unsubscribe removes one live search of a connection, or all of them when id is empty
+func (l *liveSearches) Unsubscribe(connID, id string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	searches, exists := l.subscriptions[connID]
+	if !exists {
+		return false
+	}
+	if id == "" {
+		delete(l.subscriptions, connID)
+		return true
+	}
+	if _, exists := searches[id]; !exists {
+		return false
+	}
+	delete(searches, id)
+	if len(searches) == 0 {
+		delete(l.subscriptions, connID)
+	}
+	return true
+}
+
+// HandleChange pushes newly inserted messages to every live search they match
+func (l *liveSearches) HandleChange(event changefeed.Event) {
+	if event.Operation != changefeed.OpInsert || event.Document == nil {
+		return
+	}
+
+	var doc messagePkg.MessageDocument
+	if err := event.Decode(&doc); err != nil {
+		slog.Warn("⚠️ Failed to decode message change", "error", err)
+		return
+	}
+	if doc.Type != "message" {
+		return
+	}
+	message := doc.ToMessage()
+	content := strings.ToLower(message.Content)
+
+	l.mutex.Lock()
+	matches := make([]*liveSearch, 0)
+	for _, searches := range l.subscriptions {
+		for _, s := range searches {
+			if s.matches(message, content) {
+				matches = append(matches, s)
+			}
+		}
+	}
+	l.mutex.Unlock()
+
+	for _, s := range matches {
+		data, err := json.Marshal(ServerMessage{
+			Type:      "search_match",
+			Target:    s.id,
+			Room:      message.RoomName,
+			Messages:  []*messagePkg.Message{message},
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			slog.Error("❌ Failed to marshal search match", "error", err)
+			continue
+		}
+		s.conn.SendMessage(data)
+	}
+}
+
+// ReapClosed removes live searches of connections that are no longer open
+func (l *liveSearches) ReapClosed(isOpen func(connID string) bool) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	count := 0
+	for connID, searches := range l.subscriptions {
+		if !isOpen(connID) {
+			count += len(searches)
+			delete(l.subscriptions, connID)
+		}
+	}
+	return count
+}
+
+// matches reports whether a message satisfies the search's room, user and text filters
+func (s *liveSearch) matches(message *messagePkg.Message, content string) bool {
+	if s.query.RoomName != "" && s.query.RoomName != message.RoomName {
+		return false
+	}
+	if s.query.Username != "" && !strings.EqualFold(s.query.Username, message.Username) {
+		return false
+	}
+	for _, term := range s.terms {
+		if !strings.Contains(content, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleSubscribeSearch registers a live search; scoped to the current room unless room: is given
+func (h *Handler) handleSubscribeSearch(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if !h.changeFeedEnabled {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Live search not available",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	query := search.ParseQuery(msg.Query)
+	if query.Text == "" {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Search query is required",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if query.RoomName == "" {
+		query.RoomName = msg.Room
+	}
+	if query.RoomName == "" {
+		query.RoomName = user.CurrentRoom
+	}
+	if err := roomReadError(h.roomService, query.RoomName, user.Username); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	id, err := h.liveSearches.Subscribe(conn, query)
+	if err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "search_subscribed",
+		Target:    id,
+		Room:      query.RoomName,
+		Content:   query.Text,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleUnsubscribeSearch removes a live search (all of them when no ID is given)
+func (h *Handler) handleUnsubscribeSearch(conn Connection, msg ClientMessage) {
+	if !h.liveSearches.Unsubscribe(conn.GetID(), msg.Target) {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   "Live search not found",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "search_unsubscribed",
+		Target:    msg.Target,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/security"
+	userPkg "realtime-chat/internal/user"
+)
+
+// alert ของ login ที่ผิดส่งถึงเฉพาะ session ของ account ไม่ใช่ guest ที่ใช้ชื่อเดียวกัน และ admin /unlock ได้พร้อมบันทึก audit
+func TestLoginAlertsAndUnlock(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	cfg.LoginFailureLimit = 1
+	lockout := security.NewLoginLockout(cfg)
+	audit := moderation.NewAuditLog(10)
+
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.AdminUsernames = []string{"alice"}
+	})
+	server.commands.SetAuditLog(audit)
+	server.commands.SetLoginLockout(lockout)
+
+	alice := server.login(t, "alice")
+	server.join(t, "bobby")
+	if delivered := server.commands.AlertUser("bobby", "⚠️ Failed login"); delivered != 0 {
+		t.Fatalf("alert delivered to %d guest connections, want 0", delivered)
+	}
+	if delivered := server.commands.AlertUser("ALICE", "⚠️ Failed login"); delivered != 1 {
+		t.Fatalf("alert delivered to %d connections, want 1", delivered)
+	}
+	for {
+		if alert := alice.expect("system"); alert["content"] == "⚠️ Failed login" {
+			break
+		}
+	}
+
+	if accountLock, _ := lockout.RecordFailure(userPkg.AccountID("carol"), "192.0.2.1"); accountLock == 0 {
+		t.Fatal("account not locked after reaching the failure limit")
+	}
+	alice.send(map[string]interface{}{"type": "command", "content": "/unlock Carol"})
+	for {
+		if content, _ := alice.expect("system")["content"].(string); strings.Contains(content, "unlocked") {
+			break
+		}
+	}
+	if locked, _ := lockout.Locked(userPkg.AccountID("carol"), "198.51.100.1"); locked {
+		t.Fatal("account still locked after /unlock")
+	}
+	if flags := audit.Recent(1); len(flags) != 1 || flags[0].Reason != "login_unlocked" || flags[0].Username != "alice" {
+		t.Fatalf("audit log = %+v, want login_unlocked by alice", flags)
+	}
+}
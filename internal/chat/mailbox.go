@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetMailbox enables the offline mailbox: mentions and direct messages for users without an
+// active connection are queued and delivered as missed_messages when they reconnect
+func (h *Handler) SetMailbox(repo messagePkg.MailboxRepository) {
+	h.mailbox = repo
+	h.commandService.SetMailbox(repo)
+}
+
+// SetMailbox sets the mailbox used to queue direct messages for offline recipients
+func (s *commandService) SetMailbox(repo messagePkg.MailboxRepository) {
+	s.mailbox = repo
+}
+
+// queueOfflineMentions queues a room message for every mentioned user who is not online
+func (h *Handler) queueOfflineMentions(conn Connection, message *messagePkg.Message) {
+	if h.mailbox == nil {
+		return
+	}
+
+	for _, name := range parseMentions(message.Content).Users {
+		if strings.EqualFold(name, message.Username) || h.isOnline(name) {
+			continue
+		}
+		pending := &messagePkg.PendingMessage{
+			Recipient: name,
+			Kind:      messagePkg.PendingMention,
+			From:      message.Username,
+			RoomName:  message.RoomName,
+			MessageID: message.ID,
+			Content:   message.Content,
+			Timestamp: message.Timestamp,
+		}
+		if err := h.mailbox.Enqueue(pending); err != nil {
+			connLogger(conn).Warn("⚠️ Failed to queue mention for offline user", "recipient", name, "error", err)
+		}
+	}
+}
+
+// isOnline reports whether a user has an active connection (mentions are matched case-insensitively)
+func (h *Handler) isOnline(username string) bool {
+	_, online := h.findOnlineUser(username)
+	return online
+}
+
+// findOnlineUser looks up a connected user by name, ignoring case
+func (h *Handler) findOnlineUser(username string) (*userPkg.User, bool) {
+	if user, exists := h.userService.GetUserByName(username); exists {
+		return user, true
+	}
+	for _, user := range h.userService.GetAllUsers() {
+		if strings.EqualFold(user.Username, username) {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// deliverMissedMessages flushes the user's mailbox after authentication.
+// ข้อความถูกทำเครื่องหมาย delivered ทันที แต่จะยังถูกส่งซ้ำจนกว่า client ส่ง mark_read กลับมา
+func (h *Handler) deliverMissedMessages(conn Connection, user *userPkg.User) {
+	if h.mailbox == nil {
+		return
+	}
+
+	missed, err := h.mailbox.GetUnread(user.Username, h.config.MaxMissedMessages)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to load missed messages", "error", err)
+		return
+	}
+	if len(missed) == 0 {
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "missed_messages",
+		Message:   fmt.Sprintf("📭 You have %d unread message(s) from while you were away", len(missed)),
+		Missed:    missed,
+		Total:     len(missed),
+		Timestamp: time.Now(),
+	})
+
+	ids := make([]string, len(missed))
+	for i, pending := range missed {
+		ids[i] = pending.ID
+	}
+	if err := h.mailbox.MarkDelivered(user.Username, ids); err != nil {
+		connLogger(conn).Warn("⚠️ Failed to mark missed messages delivered", "error", err)
+	}
+	connLogger(conn).Info("📭 Delivered missed messages", "count", len(missed))
+}
+
+// markMissedRead records read receipts for missed messages (ids = pending message IDs)
+func (h *Handler) markMissedRead(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.mailbox == nil {
+		h.sendCodedError(conn, &codedError{Code: "mailbox_disabled", Message: "Offline mailbox is not enabled"})
+		return
+	}
+
+	updated, err := h.mailbox.MarkRead(user.Username, msg.IDs)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to record mailbox read receipts", "error", err)
+		h.sendCodedError(conn, &codedError{Code: "mailbox_error", Message: "Failed to record read receipts"})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "marked_read",
+		Total:     int(updated),
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,178 @@
+package chat
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// MembersDelta represents membership changes in a room since the last push
+type MembersDelta struct {
+	Type      string    `json:"type"`
+	Room      string    `json:"room"`
+	Joined    []string  `json:"joined,omitempty"`
+	Left      []string  `json:"left,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// memberFeed pushes coalesced membership deltas to subscribed connections,
+// so clients with a paginated member list don't need to refetch the whole room
+type memberFeed struct {
+	subscribers map[string]map[string]Connection // roomName -> connID -> Connection
+	pending     map[string]*MembersDelta
+	interval    time.Duration
+	mutex       sync.Mutex
+}
+
+// newMemberFeed creates a new membership feed
+func newMemberFeed(interval time.Duration) *memberFeed {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &memberFeed{
+		subscribers: make(map[string]map[string]Connection),
+		pending:     make(map[string]*MembersDelta),
+		interval:    interval,
+	}
+}
+
+// Run flushes pending deltas periodically
+func (f *memberFeed) Run() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		f.flush()
+	}
+}
+
+// Subscribe subscribes a connection to membership changes of a room
+func (f *memberFeed) Subscribe(conn Connection, roomName string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// หนึ่ง connection subscribe ได้ทีละห้อง
+	f.unsubscribeLocked(conn.GetID())
+
+	if f.subscribers[roomName] == nil {
+		f.subscribers[roomName] = make(map[string]Connection)
+	}
+	f.subscribers[roomName][conn.GetID()] = conn
+}
+
+// Unsubscribe removes a connection from every room feed
+func (f *memberFeed) Unsubscribe(connID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.unsubscribeLocked(connID)
+}
+
+// Record records a membership change (called from room service callbacks)
+func (f *memberFeed) Record(roomName string, user *userPkg.User, joined bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.subscribers[roomName]) == 0 {
+		return
+	}
+
+	delta, exists := f.pending[roomName]
+	if !exists {
+		delta = &MembersDelta{Type: "members_delta", Room: roomName}
+		f.pending[roomName] = delta
+	}
+
+	// เข้าแล้วออกภายในรอบเดียวกัน ให้หักล้างกัน
+	if joined {
+		if !removeString(&delta.Left, user.Username) {
+			delta.Joined = append(delta.Joined, user.Username)
+		}
+	} else {
+		if !removeString(&delta.Joined, user.Username) {
+			delta.Left = append(delta.Left, user.Username)
+		}
+	}
+}
+
+// flush sends pending deltas to subscribers
+func (f *memberFeed) flush() {
+	f.mutex.Lock()
+	pending := f.pending
+	f.pending = make(map[string]*MembersDelta)
+
+	type delivery struct {
+		conns []Connection
+		data  []byte
+	}
+	deliveries := make([]delivery, 0, len(pending))
+
+	for roomName, delta := range pending {
+		if len(delta.Joined) == 0 && len(delta.Left) == 0 {
+			continue
+		}
+		delta.Timestamp = time.Now()
+		data, err := json.Marshal(delta)
+		if err != nil {
+			slog.Error("❌ Failed to marshal members delta", "error", err)
+			continue
+		}
+
+		conns := make([]Connection, 0, len(f.subscribers[roomName]))
+		for _, conn := range f.subscribers[roomName] {
+			conns = append(conns, conn)
+		}
+		deliveries = append(deliveries, delivery{conns: conns, data: data})
+	}
+	f.mutex.Unlock()
+
+	for _, d := range deliveries {
+		for _, conn := range d.conns {
+			conn.SendMessage(d.data)
+		}
+	}
+}
+
+// ReapClosed removes subscriptions of connections that are no longer open.
+// ปกติถูกลบตอน connection ปิดอยู่แล้ว ส่วนนี้กันกรณีหลุดรอด
+func (f *memberFeed) ReapClosed(isOpen func(connID string) bool) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	count := 0
+	for roomName, conns := range f.subscribers {
+		for connID := range conns {
+			if !isOpen(connID) {
+				delete(conns, connID)
+				count++
+			}
+		}
+		if len(conns) == 0 {
+			delete(f.subscribers, roomName)
+		}
+	}
+	return count
+}
+
+// unsubscribeLocked removes a connection from all feeds (assumes lock is held)
+func (f *memberFeed) unsubscribeLocked(connID string) {
+	for roomName, conns := range f.subscribers {
+		delete(conns, connID)
+		if len(conns) == 0 {
+			delete(f.subscribers, roomName)
+		}
+	}
+}
+
+// removeString removes value from the slice, reporting whether it was present
+func removeString(values *[]string, value string) bool {
+	for i, v := range *values {
+		if v == value {
+			*values = append((*values)[:i], (*values)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
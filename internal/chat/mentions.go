@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// Broadcast mentions that notify a whole room
+const (
+	mentionEveryone = "everyone"
+	mentionHere     = "here"
+)
+
+// Error codes for messages rejected by mention impact controls
+const (
+	ErrCodeMentionLimit     = "mention_limit"
+	ErrCodeBroadcastMention = "broadcast_mention_restricted"
+)
+
+// mentionPattern matches @username using the same characters allowed in usernames
+var mentionPattern = regexp.MustCompile(`(^|[^\w@])@([a-zA-Z0-9_\-\p{Thai}]+)`)
+
+// parsedMentions holds the mentions found in a message
+type parsedMentions struct {
+	Users     []string // distinct usernames (lowercase) ตามลำดับที่พบ
+	Broadcast []string // everyone / here
+}
+
+// mentionViolation describes why a message was rejected by mention impact controls
+type mentionViolation struct {
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+// parseMentions extracts distinct user mentions and broadcast mentions from message content
+func parseMentions(content string) parsedMentions {
+	var parsed parsedMentions
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(match[2])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if name == mentionEveryone || name == mentionHere {
+			parsed.Broadcast = append(parsed.Broadcast, name)
+		} else {
+			parsed.Users = append(parsed.Users, name)
+		}
+	}
+	return parsed
+}
+
+// checkMentionImpact enforces the per-message mention cap and restricts @everyone/@here to moderators
+func (h *Handler) checkMentionImpact(user *userPkg.User, roomName, content string) *mentionViolation {
+	mentions := parseMentions(content)
+
+	if len(mentions.Broadcast) > 0 && !h.isModerator(user, roomName) {
+		return &mentionViolation{
+			Code:    ErrCodeBroadcastMention,
+			Message: fmt.Sprintf("Only room moderators can use @%s", mentions.Broadcast[0]),
+			Details: map[string]interface{}{"mentions": mentions.Broadcast},
+		}
+	}
+
+	if limit := h.config.MaxMentionsPerMessage; limit > 0 && len(mentions.Users) > limit {
+		return &mentionViolation{
+			Code:    ErrCodeMentionLimit,
+			Message: fmt.Sprintf("Too many mentions in one message (%d, max %d)", len(mentions.Users), limit),
+			Details: map[string]interface{}{"count": len(mentions.Users), "limit": limit},
+		}
+	}
+
+	return nil
+}
+
+// isModerator reports whether a user may moderate a room (moderator of the room or server-wide, room owner or admin)
+func (h *Handler) isModerator(user *userPkg.User, roomName string) bool {
+	return h.roleOf(user, roomName) >= RoleModerator
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"log/slog"
+	"sync"
+
+	"realtime-chat/internal/changefeed"
+	messagePkg "realtime-chat/internal/message"
+)
+
+// roomMirror tracks which rooms run in mirror mode and which node is their writer.
+// ห้อง mirror (เช่น announcement channel) รับโพสต์ที่ writer node เดียว แล้วทุก node
+// กระจายข้อความให้สมาชิกของตัวเองจาก change feed ของ database ที่ใช้ร่วมกัน
+type roomMirror struct {
+	nodeID  string
+	writers map[string]string // roomName -> writer node ("" = ห้องปกติ), cache ที่ถูก invalidate จาก change feed
+	load    func(roomName string) string
+	mutex   sync.RWMutex
+}
+
+// newRoomMirror creates a mirror registry; load reads a room's writer when it isn't cached
+func newRoomMirror(nodeID string, load func(roomName string) string) *roomMirror {
+	return &roomMirror{
+		nodeID:  nodeID,
+		writers: make(map[string]string),
+		load:    load,
+	}
+}
+
+// Writer returns the writer node of a mirrored room, or "" for a normal room
+func (m *roomMirror) Writer(roomName string) string {
+	m.mutex.RLock()
+	writer, cached := m.writers[roomName]
+	m.mutex.RUnlock()
+	if cached {
+		return writer
+	}
+
+	writer = m.load(roomName)
+	m.mutex.Lock()
+	m.writers[roomName] = writer
+	m.mutex.Unlock()
+	return writer
+}
+
+// HandleRoomChange refreshes the cached writer when a room document changes on any node
+func (m *roomMirror) HandleRoomChange(event changefeed.Event) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if event.Document == nil {
+		// delete ไม่มีเอกสารให้ดูชื่อห้อง จึงล้าง cache ทั้งหมด
+		m.writers = make(map[string]string)
+		return
+	}
+
+	name, ok := event.Document.Lookup("name").StringValueOK()
+	if !ok {
+		return
+	}
+	writer, _ := event.Document.Lookup("mirror_writer").StringValueOK()
+	if previous, cached := m.writers[name]; cached && previous != writer {
+		slog.Info("🪞 Room mirror writer changed", "room", name, "from", previous, "to", writer)
+	}
+	m.writers[name] = writer
+}
+
+// fanOutMirrored delivers a message persisted by the writer node to this node's members of a mirrored room
+func (h *Handler) fanOutMirrored(event changefeed.Event) {
+	if event.Operation != changefeed.OpInsert || event.Document == nil {
+		return
+	}
+
+	roomName, ok := event.Document.Lookup("room_name").StringValueOK()
+	if !ok || h.mirror.Writer(roomName) == "" {
+		return
+	}
+
+	var doc messagePkg.MessageDocument
+	if err := event.Decode(&doc); err != nil {
+		slog.Warn("⚠️ Failed to decode mirrored message", "error", err)
+		return
+	}
+	if doc.Type != "message" {
+		return
+	}
+
+	// ผู้ส่งอยู่บน writer node ซึ่งไม่ broadcast เอง จึงตัดเฉพาะ connection ของผู้ส่ง
+	h.wsManager.BroadcastToRoom(doc.ToMessage(), doc.Sender, roomName)
+}
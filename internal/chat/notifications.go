@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// notification actions of get_notifications and /notifications
+const (
+	notificationsUnread = "unread" // แสดงเฉพาะที่ยังไม่อ่าน
+	notificationsRead   = "read"   // ทำเครื่องหมายอ่านแล้ว (ids ว่าง = ทั้งหมด)
+)
+
+// SetNotificationRepository enables persisted notifications for mentions
+func (h *Handler) SetNotificationRepository(repo messagePkg.NotificationRepository) {
+	h.notifications = repo
+}
+
+// notifyMentions creates a mention notification for every user mentioned in a room message
+// and pushes it to the user's connection when they are online
+func (h *Handler) notifyMentions(conn Connection, message *messagePkg.Message) {
+	if h.notifications == nil || len(message.Mentions) == 0 {
+		return
+	}
+
+	var expiresAt *time.Time
+	if h.config.NotificationTTL > 0 {
+		expiry := message.Timestamp.Add(h.config.NotificationTTL)
+		expiresAt = &expiry
+	}
+
+	for _, name := range message.Mentions {
+		if strings.EqualFold(name, message.Username) {
+			continue
+		}
+
+		notification := &messagePkg.Notification{
+			UserID:  name,
+			Type:    messagePkg.NotificationTypeMention,
+			Title:   fmt.Sprintf("%s mentioned you in %s", message.Username, message.RoomName),
+			Message: message.Content,
+			Data: map[string]interface{}{
+				"room":       message.RoomName,
+				"message_id": message.ID,
+				"from":       message.Username,
+			},
+			CreatedAt: message.Timestamp,
+			ExpiresAt: expiresAt,
+		}
+		if err := h.notifications.Create(notification); err != nil {
+			connLogger(conn).Warn("⚠️ Failed to save mention notification", "recipient", name, "error", err)
+			continue
+		}
+
+		// snooze เงียบเฉพาะการแจ้งเตือนสด notification ยังถูกเก็บไว้ให้ดูทีหลัง
+		target, online := h.findOnlineUser(name)
+		if !online || h.snoozes.IsSnoozed(target.Username, message.RoomName) {
+			continue
+		}
+		data, err := json.Marshal(ServerMessage{
+			Type:         "notification",
+			Notification: notification,
+			Room:         message.RoomName,
+			Timestamp:    time.Now(),
+		})
+		if err != nil {
+			slog.Error("❌ Failed to marshal JSON message", "error", err)
+			continue
+		}
+		if err := h.wsManager.SendMessage(target.ConnID, data); err != nil {
+			connLogger(conn).Warn("⚠️ Failed to push notification", "recipient", target.Username, "error", err)
+		}
+	}
+}
+
+// listNotifications optionally marks notifications read, then loads the user's newest notifications
+// and unread count. action is "", "unread" or "read"
+func (h *Handler) listNotifications(user *userPkg.User, action string, ids []string, limit int) ([]*messagePkg.Notification, int64, int64, error) {
+	var marked int64
+	if action == notificationsRead {
+		var err error
+		if marked, err = h.notifications.MarkRead(user.Username, ids); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	if limit <= 0 || limit > h.config.MaxNotifications {
+		limit = h.config.MaxNotifications
+	}
+	notifications, err := h.notifications.List(user.Username, action == notificationsUnread, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	unread, err := h.notifications.CountUnread(user.Username)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return notifications, unread, marked, nil
+}
+
+// handleGetNotifications lists the user's notifications; action "read" marks ids (or all) as read first
+func (h *Handler) handleGetNotifications(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.notifications == nil {
+		h.sendCodedError(conn, &codedError{Code: "notifications_disabled", Message: "Notifications are not enabled"})
+		return
+	}
+	if msg.Action != "" && msg.Action != notificationsUnread && msg.Action != notificationsRead {
+		h.sendCodedError(conn, &codedError{
+			Code:    "invalid_action",
+			Message: fmt.Sprintf("Unknown action '%s' (use unread or read)", msg.Action),
+		})
+		return
+	}
+
+	notifications, unread, marked, err := h.listNotifications(user, msg.Action, msg.IDs, msg.Limit)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to load notifications", "error", err)
+		h.sendCodedError(conn, &codedError{Code: "notifications_error", Message: "Failed to load notifications"})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:          "notifications",
+		Notifications: notifications,
+		Total:         int(unread),
+		Details:       map[string]interface{}{"marked_read": marked},
+		Timestamp:     time.Now(),
+	})
+}
+
+// handleNotificationsCommand handles /notifications [unread|read [id...]]
+func (h *Handler) handleNotificationsCommand(conn Connection, args []string) error {
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if h.notifications == nil {
+		return fmt.Errorf("notifications are not enabled")
+	}
+
+	action := ""
+	var ids []string
+	if len(args) > 0 {
+		action = args[0]
+		ids = args[1:]
+	}
+	if action != "" && action != notificationsUnread && action != notificationsRead {
+		return fmt.Errorf("usage: /notifications [unread|read [id...]]")
+	}
+
+	notifications, unread, marked, err := h.listNotifications(chatUser, action, ids, 0)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to load notifications", "error", err)
+		return fmt.Errorf("failed to load notifications")
+	}
+
+	var text strings.Builder
+	if action == notificationsRead {
+		fmt.Fprintf(&text, "✅ Marked %d notification(s) as read\n", marked)
+	}
+	if len(notifications) == 0 {
+		text.WriteString("🔔 No notifications")
+	} else {
+		fmt.Fprintf(&text, "🔔 Notifications (%d unread):", unread)
+		for _, notification := range notifications {
+			marker := "•"
+			if !notification.IsRead {
+				marker = "🆕"
+			}
+			fmt.Fprintf(&text, "\n%s [%s] %s %s: %s", marker, notification.ID,
+				chatUser.FormatTime(notification.CreatedAt, "Jan 2 15:04"), notification.Title, notification.Message)
+		}
+	}
+
+	return replyCommand(conn, ServerMessage{
+		Content:       text.String(),
+		Notifications: notifications,
+		Total:         int(unread),
+	})
+}
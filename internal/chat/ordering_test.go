@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"realtime-chat/internal/config"
+)
+
+// ผู้ส่งหลายคนส่งพร้อมกันในห้องเดียว: ผู้รับทุกคนเห็นลำดับเดียวกัน และข้อความของผู้ส่งแต่ละคนเรียงตามที่ส่ง
+func TestRoomOrderUnderConcurrentSenders(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.FanoutMinRecipients = 1 // ให้ broadcast ผ่าน fan-out worker ด้วย
+	})
+
+	const perSender = 20
+	senders := []*testClient{server.join(t, "alice"), server.join(t, "bobby"), server.join(t, "carol")}
+	receivers := []*testClient{server.join(t, "dave"), server.join(t, "erin")}
+
+	var wg sync.WaitGroup
+	for i, sender := range senders {
+		wg.Add(1)
+		go func(name string, sender *testClient) {
+			defer wg.Done()
+			for n := 0; n < perSender; n++ {
+				if err := sender.conn.WriteJSON(map[string]interface{}{"type": "message", "content": fmt.Sprintf("%s %d", name, n)}); err != nil {
+					t.Errorf("send: %v", err)
+					return
+				}
+			}
+		}(fmt.Sprint("sender", i), sender)
+	}
+	wg.Wait()
+
+	orders := make([][]string, len(receivers))
+	for i, receiver := range receivers {
+		next := map[string]int{}
+		for len(orders[i]) < len(senders)*perSender {
+			frame := receiver.expect("message")
+			content, _ := frame["content"].(string)
+			var name string
+			var n int
+			if _, err := fmt.Sscanf(content, "%s %d", &name, &n); err != nil {
+				t.Fatalf("unexpected content %q", content)
+			}
+			if n != next[name] {
+				t.Fatalf("receiver %d got %q, want %s %d next", i, content, name, next[name])
+			}
+			next[name]++
+			orders[i] = append(orders[i], content)
+		}
+	}
+
+	if !reflect.DeepEqual(orders[0], orders[1]) {
+		t.Fatalf("receivers saw different orders:\n%v\n%v", orders[0], orders[1])
+	}
+}
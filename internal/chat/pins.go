@@ -0,0 +1,180 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+)
+
+// handleTopicCommand handles /topic [<text>|--description <text>|--clear]
+func (h *Handler) handleTopicCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if user.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	chatRoom, exists := h.roomService.GetRoom(user.CurrentRoom)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", user.CurrentRoom)
+	}
+
+	if len(args) == 0 {
+		return replySystem(conn, describeTopic(chatRoom))
+	}
+
+	// ไม่ใช้ parseCommandFlags เพราะ description มีหลายคำ
+	description := args[0] == "--description"
+	text := strings.Join(args, " ")
+	if description {
+		text = strings.Join(args[1:], " ")
+	}
+	if args[0] == "--clear" {
+		text = ""
+	} else {
+		validated, err := h.validator.ValidateMessage(text)
+		if err != nil {
+			return validationFailure(err)
+		}
+		text = validated
+	}
+
+	topic, details := text, chatRoom.Description
+	var err error
+	if description {
+		topic, details = chatRoom.Topic, text
+		err = h.roomService.SetDescription(chatRoom.Name, user.Username, text)
+	} else {
+		err = h.roomService.SetTopic(chatRoom.Name, user.Username, text)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:        "topic_changed",
+		Room:        chatRoom.Name,
+		Username:    user.Username,
+		Topic:       topic,
+		Description: details,
+		Timestamp:   time.Now(),
+	}, "", chatRoom.Name)
+	return nil
+}
+
+// describeTopic formats the topic and description of a room for /topic
+func describeTopic(chatRoom *room.Room) string {
+	if chatRoom.Topic == "" && chatRoom.Description == "" {
+		return fmt.Sprintf("📌 Room '%s' has no topic", chatRoom.Name)
+	}
+	topic := chatRoom.Topic
+	if topic == "" {
+		topic = "(none)"
+	}
+	text := fmt.Sprintf("📌 Topic of '%s': %s", chatRoom.Name, topic)
+	if chatRoom.Description != "" {
+		text += "\n" + chatRoom.Description
+	}
+	return text
+}
+
+// handlePinCommand handles /pin <message_id>
+func (h *Handler) handlePinCommand(conn Connection, args []string) error {
+	user, message, err := h.pinTarget(conn, args, "/pin")
+	if err != nil {
+		return err
+	}
+
+	if err := h.roomService.PinMessage(user.CurrentRoom, user.Username, message.ID); err != nil {
+		return err
+	}
+
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "message_pinned",
+		Room:      user.CurrentRoom,
+		Username:  user.Username,
+		Target:    message.ID,
+		Pinned:    pinnedMessages(h.roomService, h.messageRepo, user.CurrentRoom),
+		Timestamp: time.Now(),
+	}, "", user.CurrentRoom)
+	return nil
+}
+
+// handleUnpinCommand handles /unpin <message_id>
+func (h *Handler) handleUnpinCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /unpin <message_id>")
+	}
+	if user.CurrentRoom == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+
+	// ข้อความที่ถูกลบไปแล้วยังถอนหมุดได้ จึงไม่ตรวจกับ message repository
+	if err := h.roomService.UnpinMessage(user.CurrentRoom, user.Username, args[0]); err != nil {
+		return err
+	}
+
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "message_unpinned",
+		Room:      user.CurrentRoom,
+		Username:  user.Username,
+		Target:    args[0], // client ลบ message นี้ออก (pinned ถูกละไว้เมื่อไม่มีหมุดเหลือ)
+		Pinned:    pinnedMessages(h.roomService, h.messageRepo, user.CurrentRoom),
+		Timestamp: time.Now(),
+	}, "", user.CurrentRoom)
+	return nil
+}
+
+// pinTarget returns the user and the message a pin command refers to; only chat messages of the current room can be pinned
+func (h *Handler) pinTarget(conn Connection, args []string, usage string) (*userPkg.User, *messagePkg.Message, error) {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return nil, nil, fmt.Errorf("user not authenticated")
+	}
+	if len(args) != 1 {
+		return nil, nil, fmt.Errorf("usage: %s <message_id>", usage)
+	}
+	if user.CurrentRoom == "" {
+		return nil, nil, fmt.Errorf("you are not in any room")
+	}
+	if h.messageRepo == nil {
+		return nil, nil, fmt.Errorf("pinning messages requires message persistence")
+	}
+
+	message, err := h.messageRepo.GetMessage(args[0])
+	if err != nil || message.RoomName != user.CurrentRoom || message.IsDeleted {
+		return nil, nil, fmt.Errorf("message '%s' not found in room '%s'", args[0], user.CurrentRoom)
+	}
+	if message.Type != "message" {
+		return nil, nil, fmt.Errorf("only chat messages can be pinned")
+	}
+	return user, message, nil
+}
+
+// pinnedMessages loads a room's pinned messages in pin order, skipping messages that were deleted since
+func pinnedMessages(roomService RoomService, messageRepo MessageRepository, roomName string) []*messagePkg.Message {
+	chatRoom, exists := roomService.GetRoom(roomName)
+	if !exists || messageRepo == nil {
+		return nil
+	}
+
+	messages := make([]*messagePkg.Message, 0, len(chatRoom.Pinned))
+	for _, pin := range chatRoom.Pinned {
+		message, err := messageRepo.GetMessage(pin.MessageID)
+		if err != nil || message.IsDeleted {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
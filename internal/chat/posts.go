@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// PostMessage posts a chat message to a room as username for a backend integration (gRPC SendMessage).
+// ผ่านการตรวจสอบและ path บันทึก/กระจายเดียวกับ PostAttachment
+func (h *Handler) PostMessage(username, roomName, content string) (*messagePkg.Message, error) {
+	validatedContent, err := h.validator.ValidateMessage(content)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.postAs(username, roomName, validatedContent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("📨 Message posted through the integration API", "room", roomName, "username", message.Username)
+	return message, nil
+}
+
+// PostSystemMessage broadcasts a system notice to a room; like join/leave notices it is not saved
+func (h *Handler) PostSystemMessage(roomName, content string) error {
+	if _, exists := h.roomService.GetRoom(roomName); !exists {
+		return fmt.Errorf("room '%s' not found", roomName)
+	}
+	validatedContent, err := h.validator.ValidateMessage(content)
+	if err != nil {
+		return err
+	}
+
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "system",
+		Room:      roomName,
+		Message:   validatedContent,
+		Timestamp: time.Now(),
+	}, "", roomName)
+
+	slog.Info("📢 System message posted through the integration API", "room", roomName)
+	return nil
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+)
+
+// preferenceStore keeps a small key-value map of UI preferences per username
+// (theme, notification sounds, compact mode ...) so clients can sync settings across devices;
+// ของ guest ถูกล้างเมื่อ session จบ (ดู clearUserState)
+type preferenceStore struct {
+	prefs          map[string]map[string]string // username -> key -> value
+	maxKeys        int
+	maxKeyLength   int
+	maxValueLength int
+	mutex          sync.RWMutex
+}
+
+// newPreferenceStore creates a new preference store
+func newPreferenceStore(maxKeys, maxKeyLength, maxValueLength int) *preferenceStore {
+	return &preferenceStore{
+		prefs:          make(map[string]map[string]string),
+		maxKeys:        maxKeys,
+		maxKeyLength:   maxKeyLength,
+		maxValueLength: maxValueLength,
+	}
+}
+
+// Set stores a preference; an empty value deletes it
+func (p *preferenceStore) Set(username, key, value string) error {
+	if err := p.validateKey(key); err != nil {
+		return err
+	}
+	if len(value) > p.maxValueLength {
+		return fmt.Errorf("preference value too long (max %d characters)", p.maxValueLength)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	prefs, exists := p.prefs[username]
+	if value == "" {
+		if exists {
+			delete(prefs, key)
+			if len(prefs) == 0 {
+				delete(p.prefs, username)
+			}
+		}
+		return nil
+	}
+
+	if !exists {
+		prefs = make(map[string]string)
+		p.prefs[username] = prefs
+	}
+	if _, exists := prefs[key]; !exists && len(prefs) >= p.maxKeys {
+		return fmt.Errorf("too many preferences (max %d)", p.maxKeys)
+	}
+
+	prefs[key] = value
+	return nil
+}
+
+// GetAll returns a copy of all preferences for a user
+func (p *preferenceStore) GetAll(username string) map[string]string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	prefs := make(map[string]string, len(p.prefs[username]))
+	for key, value := range p.prefs[username] {
+		prefs[key] = value
+	}
+	return prefs
+}
+
+// Clear removes every preference of a user
+func (p *preferenceStore) Clear(username string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.prefs, username)
+}
+
+// validateKey checks that a preference key is short and uses only [a-z0-9_.-]
+func (p *preferenceStore) validateKey(key string) error {
+	if key == "" || len(key) > p.maxKeyLength {
+		return fmt.Errorf("preference key must be 1-%d characters", p.maxKeyLength)
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-') {
+			return fmt.Errorf("preference key may only contain a-z, 0-9, '_', '.' and '-'")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"log/slog"
+	"time"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/presence"
+)
+
+// SetPresenceTracker enables online/away/offline presence and presence_changed events
+func (h *Handler) SetPresenceTracker(tracker *presence.Tracker) {
+	h.presenceTracker = tracker
+	tracker.OnChange(h.notifyPresenceState)
+}
+
+// SetPresenceTracker sets the tracker used to show presence in /users
+func (s *commandService) SetPresenceTracker(tracker *presence.Tracker) {
+	s.presenceTracker = tracker
+}
+
+// PresenceSignals lists the users connected to this node with their activity and connection health.
+// ใช้เป็น source ของ presence.Tracker ตอน sweep
+func (h *Handler) PresenceSignals() []presence.Signal {
+	signals := make([]presence.Signal, 0)
+	for _, u := range h.userService.GetAllUsers() {
+		// ใน MongoDB mode มีผู้ใช้ของ node อื่นด้วย นับเฉพาะที่มี connection อยู่บน node นี้
+		rawHealth, exists := h.wsManager.GetConnectionHealth(u.ConnID)
+		if !exists {
+			continue
+		}
+		healthy := true
+		if health, ok := rawHealth.(*config.ConnectionHealth); ok && health != nil {
+			stats := health.GetStats()
+			healthy = stats.IsHealthy && stats.MissedPongs == 0
+		}
+		signals = append(signals, presence.Signal{
+			Username:   u.Username,
+			LastActive: u.LastActive,
+			Healthy:    healthy,
+		})
+	}
+	return signals
+}
+
+// touchPresence marks the user online after activity
+func (h *Handler) touchPresence(username string) {
+	if h.presenceTracker != nil {
+		h.presenceTracker.Touch(username)
+	}
+}
+
+// notifyPresenceState tells the user's current room that their presence changed
+func (h *Handler) notifyPresenceState(username string, previous, current presence.State) {
+	chatUser, exists := h.userService.GetUserByName(username)
+	if !exists || chatUser.CurrentRoom == "" {
+		return
+	}
+
+	slog.Debug("🟢 Presence changed", "username", username, "from", previous, "to", current)
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "presence_changed",
+		Username:  username,
+		Room:      chatUser.CurrentRoom,
+		Status:    string(current),
+		Timestamp: time.Now(),
+	}, "", chatUser.CurrentRoom)
+}
+
+// memberPresence returns the presence shown for a room member
+func (h *Handler) memberPresence(username string, lastActive time.Time) string {
+	if h.presenceTracker != nil {
+		return string(h.presenceTracker.Get(username))
+	}
+	// ไม่มี tracker ใช้เวลากิจกรรมล่าสุดอย่างเดียว
+	if time.Since(lastActive) < 5*time.Minute {
+		return string(presence.Online)
+	}
+	return string(presence.Away)
+}
+
+// presenceRank orders presence states for member lists (online first)
+func presenceRank(status string) int {
+	switch presence.State(status) {
+	case presence.Online:
+		return 0
+	case presence.Away:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// presenceIcon returns the icon shown next to a username in text output
+func presenceIcon(state presence.State) string {
+	switch state {
+	case presence.Online:
+		return "🟢"
+	case presence.Away:
+		return "🟡"
+	default:
+		return "⚫"
+	}
+}
@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// profileUpdate holds the profile fields a client changes; nil fields are kept
+type profileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	StatusText  *string
+}
+
+// handleSetProfile updates the profile of the user's account; fields left out of the frame are kept
+func (h *Handler) handleSetProfile(conn Connection, user *userPkg.User, msg ClientMessage) {
+	update := profileUpdate{DisplayName: msg.DisplayName, AvatarURL: msg.AvatarURL, StatusText: msg.StatusText}
+	if err := h.saveProfile(conn, user, update); err != nil {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Failed to update profile: %s", err.Error()),
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// handleStatusCommand handles /status [<text>|--clear]
+func (h *Handler) handleStatusCommand(conn Connection, args []string) error {
+	user, ok := conn.GetUser().(*userPkg.User)
+	if !ok || user == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if len(args) == 0 {
+		if user.StatusText == "" {
+			return replySystem(conn, "💬 You have no status. Use /status <text> to set one")
+		}
+		return replySystem(conn, fmt.Sprintf("💬 Your status: %s", user.StatusText))
+	}
+
+	status := strings.Join(args, " ")
+	if args[0] == "--clear" {
+		status = ""
+	}
+	return h.saveProfile(conn, user, profileUpdate{StatusText: &status})
+}
+
+// saveProfile validates and stores a profile update, then tells the user and their room.
+// คนอื่นในห้องได้ profile_updated เพื่อเปลี่ยนชื่อที่แสดงและ avatar โดยไม่ต้องขอ users_list ใหม่
+func (h *Handler) saveProfile(conn Connection, user *userPkg.User, update profileUpdate) error {
+	profile := userPkg.Profile{
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		StatusText:  user.StatusText,
+	}
+
+	// ตรวจเฉพาะ field ที่เปลี่ยน: ค่าเดิมถูก escape ไปแล้ว ตรวจซ้ำจะ escape ซ้อน
+	var err error
+	if update.DisplayName != nil {
+		if profile.DisplayName, err = h.validateProfileText("display name", *update.DisplayName, h.config.MaxDisplayNameLength); err != nil {
+			return err
+		}
+	}
+	if update.StatusText != nil {
+		if profile.StatusText, err = h.validateProfileText("status", *update.StatusText, h.config.MaxStatusTextLength); err != nil {
+			return err
+		}
+	}
+	if update.AvatarURL != nil {
+		if profile.AvatarURL, err = h.validateAvatarURL(*update.AvatarURL); err != nil {
+			return err
+		}
+	}
+
+	saved, err := h.userService.UpdateProfile(user, profile)
+	if err != nil {
+		return err
+	}
+
+	notice := ServerMessage{
+		Type:      "profile_updated",
+		Username:  user.Username,
+		Room:      user.CurrentRoom,
+		Profile:   saved,
+		Timestamp: time.Now(),
+	}
+	h.sendJSONMessage(conn, notice)
+	if user.CurrentRoom != "" {
+		h.broadcastJSONToRoom(notice, conn.GetID(), user.CurrentRoom)
+	}
+	return nil
+}
+
+// validateProfileText collapses whitespace, checks the length and HTML-escapes text like message content
+func (h *Handler) validateProfileText(field, text string, maxLength int) (string, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > maxLength {
+		return "", fmt.Errorf("%s too long (max %d characters)", field, maxLength)
+	}
+	if strings.IndexFunc(text, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%s may not contain control characters", field)
+	}
+	return h.validator.SanitizeHTML(text), nil
+}
+
+// validateAvatarURL checks that an avatar is an http(s) URL; empty removes the avatar
+func (h *Handler) validateAvatarURL(avatarURL string) (string, error) {
+	avatarURL = strings.TrimSpace(avatarURL)
+	if avatarURL == "" {
+		return "", nil
+	}
+	if len(avatarURL) > h.config.MaxAvatarURLLength {
+		return "", fmt.Errorf("avatar URL too long (max %d characters)", h.config.MaxAvatarURLLength)
+	}
+	// client แสดงเป็น <img src> จึงรับเฉพาะ http(s) ไม่ให้ใส่ javascript: หรือ data:
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return "", fmt.Errorf("avatar URL must be an http(s) URL")
+	}
+	return avatarURL, nil
+}
@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"time"
+
+	"realtime-chat/internal/moderation"
+	userPkg "realtime-chat/internal/user"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// SetQuarantine enables quarantining connections that keep violating the protocol
+func (h *Handler) SetQuarantine(quarantine *moderation.Quarantine) {
+	h.quarantine = quarantine
+}
+
+// protocolViolation tells the client what was wrong with its frame and counts the violation.
+// เมื่อครบ limit จะเตือนครั้งสุดท้ายแล้วปิด connection หลัง grace period
+func (h *Handler) protocolViolation(conn Connection, violations *moderation.ViolationTracker, kind, message string) {
+	username := ""
+	if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+		username = chatUser.Username
+	}
+
+	if !violations.Record(username, conn.GetLabel(), kind) {
+		h.sendCodedError(conn, &codedError{Code: kind, Message: message})
+		return
+	}
+
+	grace := h.quarantine.Grace()
+	connLogger(conn).Warn("🚫 Quarantined for protocol violations", "closing_in", grace)
+	h.sendCodedError(conn, &codedError{
+		Code:    "quarantined",
+		Message: "Too many invalid messages. Further input is ignored and the connection will be closed.",
+		Details: map[string]interface{}{"close_in_seconds": int(grace.Seconds())},
+	})
+
+	connID := conn.GetID()
+	time.AfterFunc(grace, func() {
+		if closer, ok := conn.(closeMarker); ok {
+			closer.MarkClose(wsocket.CloseProtocolViolation, nil)
+		}
+		h.wsManager.RemoveConnection(connID)
+	})
+}
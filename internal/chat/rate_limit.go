@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// ErrCodeRateLimited is sent when a user runs out of message tokens
+const ErrCodeRateLimited = "rate_limited"
+
+// rateLimitDetails describes a rate limit status for clients (durations in milliseconds)
+func rateLimitDetails(status config.RateLimitStatus) map[string]interface{} {
+	return map[string]interface{}{
+		"remaining":      status.Remaining,
+		"burst":          status.Burst,
+		"retry_after_ms": status.RetryAfter.Milliseconds(),
+		"full_in_ms":     status.FullIn.Milliseconds(),
+	}
+}
+
+// checkRateLimit takes a message token for the user. When the bucket is empty the client gets
+// a rate_limited error; when only a few tokens remain it gets a rate_limit_status warning once
+func (h *Handler) checkRateLimit(conn Connection, userID string) bool {
+	if !h.rateLimiter.CheckRateLimit(userID) {
+		status := h.rateLimiter.GetRateLimitStatus(userID)
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "error",
+			Message:   fmt.Sprintf("Rate limit exceeded! You can send another message in %v", status.RetryAfter.Round(time.Second)),
+			Code:      ErrCodeRateLimited,
+			Details:   rateLimitDetails(status),
+			Timestamp: time.Now(),
+		})
+		return false
+	}
+
+	if status, warn := h.rateLimiter.ShouldWarn(userID); warn {
+		h.sendJSONMessage(conn, ServerMessage{
+			Type:      "rate_limit_status",
+			Message:   fmt.Sprintf("⏳ Slow down: %d message(s) left before rate limiting", status.Remaining),
+			Details:   rateLimitDetails(status),
+			Timestamp: time.Now(),
+		})
+	}
+	return true
+}
+
+// EvictIdleRateLimits drops token buckets of users who have been idle for the configured TTL
+func (h *Handler) EvictIdleRateLimits() {
+	if count := h.rateLimiter.EvictIdle(); count > 0 {
+		slog.Info("🧹 Evicted idle rate limit entries", "count", count)
+	}
+}
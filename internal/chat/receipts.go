@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetReadReceiptRepository enables per-room read receipts and unread counts
+func (h *Handler) SetReadReceiptRepository(repo messagePkg.ReadReceiptRepository) {
+	h.receipts = repo
+}
+
+// handleMarkRead handles mark_read: ids marks missed messages from the offline mailbox as read,
+// otherwise target is the last message the user has seen in room (default: current room)
+func (h *Handler) handleMarkRead(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if len(msg.IDs) > 0 {
+		h.markMissedRead(conn, user, msg)
+		return
+	}
+	h.markRoomRead(conn, user, msg)
+}
+
+// markRoomRead moves the user's read receipt of a room forward to the given message
+func (h *Handler) markRoomRead(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.receipts == nil || h.messageRepo == nil {
+		h.sendCodedError(conn, &codedError{Code: "receipts_disabled", Message: "Read receipts are not enabled"})
+		return
+	}
+
+	roomName := msg.Room
+	if roomName == "" {
+		roomName = user.CurrentRoom
+	}
+	if roomName == "" || msg.Target == "" {
+		h.sendCodedError(conn, &codedError{Code: "invalid_receipt", Message: "mark_read requires room and target (last seen message ID)"})
+		return
+	}
+
+	message, err := h.messageRepo.GetMessage(msg.Target)
+	if err != nil || message.RoomName != roomName {
+		h.sendCodedError(conn, &codedError{
+			Code:    "invalid_receipt",
+			Message: fmt.Sprintf("Message '%s' not found in room '%s'", msg.Target, roomName),
+			Details: map[string]interface{}{"room": roomName, "message_id": msg.Target},
+		})
+		return
+	}
+
+	receipt := &messagePkg.RoomReadReceipt{
+		ReadReceipt: messagePkg.ReadReceipt{Username: user.Username, ReadAt: time.Now()},
+		RoomName:    roomName,
+		MessageID:   message.ID,
+		MessageTime: message.Timestamp,
+	}
+	if err := h.receipts.MarkRead(receipt); err != nil {
+		connLogger(conn).Warn("⚠️ Failed to save read receipt", "error", err)
+		h.sendCodedError(conn, &codedError{Code: "receipts_error", Message: "Failed to save read receipt"})
+		return
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "marked_read",
+		Room:      roomName,
+		Target:    message.ID,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleGetUnreadCounts returns unread message counts for rooms the user has read before.
+// นับไม่เกิน MaxUnreadCount ต่อห้อง client แสดงเป็น "99+" เมื่อถึงเพดาน
+func (h *Handler) handleGetUnreadCounts(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if h.receipts == nil {
+		h.sendCodedError(conn, &codedError{Code: "receipts_disabled", Message: "Read receipts are not enabled"})
+		return
+	}
+
+	receipts, err := h.receipts.GetReceipts(user.Username)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to load read receipts", "error", err)
+		h.sendCodedError(conn, &codedError{Code: "receipts_error", Message: "Failed to load unread counts"})
+		return
+	}
+
+	limit := int64(h.config.MaxUnreadCount)
+	unread := make(map[string]int64, len(receipts))
+	for _, receipt := range receipts {
+		if msg.Room != "" && receipt.RoomName != msg.Room {
+			continue
+		}
+		// ห้องที่ถูกลบหรือเปลี่ยนเป็น private แล้วไม่มีข้อความใหม่ให้นับ
+		if room, exists := h.roomService.GetRoom(receipt.RoomName); !exists || room.Private {
+			continue
+		}
+		count, err := h.receipts.CountUnread(receipt, limit)
+		if err != nil {
+			connLogger(conn).Warn("⚠️ Failed to count unread messages", "room", receipt.RoomName, "error", err)
+			continue
+		}
+		unread[receipt.RoomName] = count
+	}
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "unread_counts",
+		Unread:    unread,
+		Details:   map[string]interface{}{"limit": limit},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,22 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+// frame ที่ log ระดับ debug ต้องไม่มีรหัสผ่านหรือ token ที่ใช้แทนตัวตนได้
+func TestRedactSecretsMasksPasswordsAndTokens(t *testing.T) {
+	frames := []string{
+		`{"type":"join","session_token":"tok-secret-1"}`,
+		`{"type":"resume","token": "tok-secret-2"}`,
+		`{"type":"join_room","room":"vault","password":"tok-secret-3"}`,
+		`{"type":"command","content":"/join vault tok-secret-4"}`,
+		`{"type":"command","content":"/create vault --password tok-secret-5"}`,
+	}
+	for _, frame := range frames {
+		if redacted := redactSecrets(frame); strings.Contains(redacted, "tok-secret") {
+			t.Errorf("secret left in %s", redacted)
+		}
+	}
+}
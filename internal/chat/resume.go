@@ -0,0 +1,334 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// Error codes of the resume handshake
+const (
+	ErrCodeResumeFailed  = "resume_failed"
+	ErrCodeSessionActive = "session_active"
+	ErrCodeNameReserved  = "username_reserved"
+)
+
+var (
+	errSessionNotFound = errors.New("resume token is invalid or expired")
+	errSessionActive   = errors.New("session is still attached to another connection")
+)
+
+// resumableSession is what a reconnecting client gets back without re-authenticating
+type resumableSession struct {
+	token        string
+	connID       string // connection ปัจจุบัน (ว่างระหว่างรอ resume)
+	username     string
+	guest        bool
+	room         string
+	timezone     string
+	capabilities []string
+	detachedAt   time.Time
+	missed       []*messagePkg.Message // ข้อความในห้องระหว่างหลุด (เก่าสุดก่อน)
+	dropped      int                   // ข้อความที่เกิน buffer
+	fallback     *sseFallback          // SSE stream ที่ client ถือไว้คู่กับ WebSocket (ดู fallback.go)
+}
+
+// sessionStore keeps sessions of disconnected users alive for a grace period so a client can
+// send resume with its token and continue in the same room with the messages it missed
+type sessionStore struct {
+	sessions    map[string]*resumableSession // token -> session
+	byConn      map[string]string            // connID -> token
+	grace       time.Duration
+	maxMessages int
+	onExpire    func(session *resumableSession) // เรียกหลังปลด lock เมื่อ session หมด grace period
+	mutex       sync.Mutex
+}
+
+// newSessionStore creates a session store, or nil when session resume is disabled
+func newSessionStore(cfg *config.ServerConfig) *sessionStore {
+	if !cfg.EnableSessionResume {
+		return nil
+	}
+	return &sessionStore{
+		sessions:    make(map[string]*resumableSession),
+		byConn:      make(map[string]string),
+		grace:       cfg.SessionResumeGrace,
+		maxMessages: cfg.MaxResumeMessages,
+	}
+}
+
+// newResumeToken creates a random, unguessable resume token
+func newResumeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Issue creates a session for an authenticated connection and returns its resume token
+func (s *sessionStore) Issue(connID string, user *userPkg.User, capabilities []string) (string, error) {
+	token, err := newResumeToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// connection หนึ่งมี token เดียว token เก่า (ถ้ามี) ใช้ไม่ได้อีก แต่ SSE stream ยังอยู่กับ session
+	var fallback *sseFallback
+	if old, exists := s.byConn[connID]; exists {
+		if session, exists := s.sessions[old]; exists {
+			fallback = session.fallback
+		}
+		delete(s.sessions, old)
+	}
+	s.sessions[token] = &resumableSession{
+		token:        token,
+		connID:       connID,
+		username:     user.Username,
+		guest:        user.Guest,
+		timezone:     user.Timezone,
+		capabilities: capabilities,
+		fallback:     fallback,
+	}
+	s.byConn[connID] = token
+	return token, nil
+}
+
+// Detach starts the grace period of the connection's session; the user's room is remembered for resume
+func (s *sessionStore) Detach(connID, room string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token, exists := s.byConn[connID]
+	if !exists {
+		return
+	}
+	delete(s.byConn, connID)
+	if session, exists := s.sessions[token]; exists {
+		session.connID = ""
+		session.room = room
+		session.detachedAt = time.Now()
+		if session.fallback != nil && !session.fallback.send(transportFrame(TransportSSE)) {
+			session.fallback = nil
+		}
+	}
+}
+
+// Attached reports whether a connection has a session that will stay resumable after it closes
+func (s *sessionStore) Attached(connID string) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, exists := s.byConn[connID]
+	return exists
+}
+
+// Resume takes a detached session; the token is single-use
+func (s *sessionStore) Resume(token string) (*resumableSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[token]
+	if !exists || (session.connID == "" && time.Since(session.detachedAt) > s.grace) {
+		return nil, errSessionNotFound
+	}
+	if session.connID != "" {
+		return nil, errSessionActive
+	}
+	delete(s.sessions, token)
+	return session, nil
+}
+
+// Reserved reports whether a username belongs to a session waiting to be resumed
+func (s *sessionStore) Reserved(username string) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.connID == "" && now.Sub(session.detachedAt) <= s.grace && strings.EqualFold(session.username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordMissed buffers a room message for every detached session in that room;
+// sessions with an SSE fallback get it over the stream instead
+func (s *sessionStore) RecordMissed(message *messagePkg.Message) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, session := range s.sessions {
+		if session.connID != "" || session.room != message.RoomName || session.username == message.Username {
+			continue
+		}
+		if session.deliverFallback(message) {
+			continue
+		}
+		if len(session.missed) >= s.maxMessages {
+			// เก็บข้อความล่าสุดไว้ ที่เก่ากว่าดูได้จาก history
+			session.missed = session.missed[1:]
+			session.dropped++
+		}
+		session.missed = append(session.missed, message)
+	}
+}
+
+// Reap removes sessions whose grace period has passed
+func (s *sessionStore) Reap(aggressive bool) int {
+	s.mutex.Lock()
+	now := time.Now()
+	var expired []*resumableSession
+	for token, session := range s.sessions {
+		if session.connID == "" && now.Sub(session.detachedAt) > s.grace {
+			delete(s.sessions, token)
+			expired = append(expired, session)
+			if session.fallback != nil {
+				session.fallback.close()
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	if s.onExpire != nil {
+		for _, session := range expired {
+			s.onExpire(session)
+		}
+	}
+	return len(expired)
+}
+
+// issueResumeToken gives a newly authenticated connection its resume token
+func (h *Handler) issueResumeToken(conn Connection, user *userPkg.User, capabilities []string) {
+	if h.sessions == nil {
+		return
+	}
+	token, err := h.sessions.Issue(conn.GetID(), user, capabilities)
+	if err != nil {
+		connLogger(conn).Warn("⚠️ Failed to issue resume token", "error", err)
+		return
+	}
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:        "session",
+		ResumeToken: token,
+		Details:     map[string]interface{}{"grace_seconds": int(h.config.SessionResumeGrace.Seconds())},
+		Timestamp:   time.Now(),
+	})
+}
+
+// detachSession keeps the session of a closing connection resumable for the grace period
+func (h *Handler) detachSession(connID string) {
+	if h.sessions == nil {
+		return
+	}
+	connection, exists := h.wsManager.GetConnection(connID)
+	if !exists {
+		return
+	}
+	if chatUser, ok := connection.GetUser().(*userPkg.User); ok && chatUser != nil && chatUser.IsAuthenticated {
+		h.sessions.Detach(connID, chatUser.CurrentRoom)
+	}
+}
+
+// handleResume authenticates a new connection from a resume token instead of a join:
+// the user gets the same name, room and capabilities back plus the room messages sent while away.
+// Returns false when the connection should be closed (too many failed attempts)
+func (h *Handler) handleResume(conn Connection, ip string, msg ClientMessage) bool {
+	if h.sessions == nil {
+		h.sendCodedError(conn, &codedError{Code: ErrCodeResumeFailed, Message: "Session resume is not enabled, please join again"})
+		return true
+	}
+
+	session, err := h.sessions.Resume(msg.Token)
+	if err != nil {
+		coded := &codedError{Code: ErrCodeResumeFailed, Message: fmt.Sprintf("Cannot resume: %v, please join again", err)}
+		if errors.Is(err, errSessionActive) {
+			// connection เดิมยังไม่ถูกตรวจพบว่าหลุด ให้ client ลองใหม่หลังจากนั้น
+			coded.Code = ErrCodeSessionActive
+			coded.Details = map[string]interface{}{"retry_after_seconds": int(h.config.PongTimeout.Seconds())}
+			h.sendCodedError(conn, coded)
+			return true
+		}
+		h.sendCodedError(conn, coded)
+		return !h.recordAuthFailure(conn, ip)
+	}
+
+	chatUser, err := h.userService.RegisterUser(conn.GetID(), session.username)
+	if err != nil {
+		h.sendCodedError(conn, &codedError{Code: ErrCodeResumeFailed, Message: fmt.Sprintf("Cannot resume: %v, please join again", err)})
+		return true
+	}
+	if h.throttle != nil {
+		h.throttle.RecordAuthSuccess(ip)
+	}
+	if session.timezone != "" {
+		if err := chatUser.SetTimezone(session.timezone); err != nil {
+			connLogger(conn).Warn("⚠️ Ignoring stored timezone", "error", err)
+		}
+	}
+	chatUser.Guest = session.guest
+	conn.SetUser(chatUser)
+
+	// ห้องเดิมอาจถูกลบหรือหมดอายุระหว่างหลุด ให้กลับไปห้อง general
+	roomName := session.room
+	if _, exists := h.roomService.GetRoom(roomName); !exists {
+		roomName = "general"
+	}
+	if err := h.roomService.JoinRoom(chatUser, roomName); err != nil {
+		connLogger(conn).Error("❌ Failed to rejoin room on resume", "room", roomName, "error", err)
+	}
+	h.touchPresence(chatUser.Username)
+	h.negotiateCapabilities(conn, session.capabilities)
+
+	h.sendJSONMessage(conn, ServerMessage{
+		Type:      "resumed",
+		Username:  chatUser.Username,
+		Room:      roomName,
+		Messages:  session.missed,
+		Total:     len(session.missed) + session.dropped,
+		Message:   fmt.Sprintf("Welcome back %s! You are in room '%s'", chatUser.Username, roomName),
+		Details:   map[string]interface{}{"dropped": session.dropped, "away_seconds": int(time.Since(session.detachedAt).Seconds())},
+		Timestamp: time.Now(),
+	})
+	h.issueResumeToken(conn, chatUser, session.capabilities)
+	h.sessions.handOverFallback(conn.GetID(), session.fallback)
+
+	h.sendRoomsList(conn)
+	h.sendUsersList(conn, roomName)
+	h.deliverMissedMessages(conn, chatUser)
+
+	h.wsManager.BroadcastToRoom(&messagePkg.Message{
+		Type:      "user_joined",
+		Content:   fmt.Sprintf("%s rejoined room '%s'", chatUser.Username, roomName),
+		Sender:    "System",
+		Username:  chatUser.Username, // client แสดงและอัปเดตรายชื่อจาก username
+		RoomName:  roomName,
+		Timestamp: time.Now(),
+	}, conn.GetID(), roomName)
+
+	connLogger(conn).Info("🔁 Session resumed", "away", time.Since(session.detachedAt).Round(time.Second), "missed", len(session.missed))
+	return true
+}
@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"realtime-chat/internal/config"
+	userPkg "realtime-chat/internal/user"
+)
+
+// effectiveRole returns a user's role in a room: the highest of admin (config), the server-wide role,
+// room ownership and the role assigned in that room.
+// guest ทั้ง server ยังเป็น guest ในทุกห้อง ยกเว้น admin จาก config.
+// สิทธิ์ทั้งหมดผูกกับชื่อ จึงให้เฉพาะผู้ที่ login ด้วย account; ชื่อของ guest ใครก็ใช้ได้ จึงได้ไม่เกิน member
+func effectiveRole(cfg *config.ServerConfig, roles *userPkg.RoleStore, rooms RoomService, user *userPkg.User, roomName string) CommandRole {
+	username := user.Username
+	if user.Guest {
+		if roles.Global(username) == RoleGuest || (roomName != "" && roles.Room(roomName, username) == RoleGuest) {
+			return RoleGuest
+		}
+		return RoleMember
+	}
+	if cfg.IsAdmin(username) {
+		return RoleAdmin
+	}
+	role := roles.Global(username)
+	if role == RoleAdmin || role == RoleGuest || roomName == "" {
+		return role
+	}
+
+	if room, exists := rooms.GetRoom(roomName); exists && room.CreatedBy == username {
+		return RoleOwner
+	}
+	roomRole := roles.Room(roomName, username)
+	if roomRole == RoleGuest && role == RoleMember {
+		return RoleGuest
+	}
+	if roomRole > role {
+		return roomRole
+	}
+	return role
+}
+
+// SetRoles sets the role store and registers the /setrole admin command
+func (s *commandService) SetRoles(roles *userPkg.RoleStore) {
+	s.roles = roles
+
+	s.RegisterCommand(&Command{
+		Name:         "setrole",
+		Description:  "Show or set a user's role server-wide, or in the current room with --room (admin only)",
+		Usage:        "/setrole <user> [guest|member|moderator|admin] [--room]",
+		RequiredRole: RoleAdmin,
+		Handler:      s.handleSetRole,
+	})
+}
+
+// roleOf returns the user's effective role in their current room
+func (s *commandService) roleOf(user *userPkg.User) CommandRole {
+	return effectiveRole(s.config, s.roles, s.roomService, user, user.CurrentRoom)
+}
+
+func (s *commandService) handleSetRole(conn Connection, args []string) error {
+	admin, err := s.requireAdmin(conn)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("username required. Usage: /setrole <user> [guest|member|moderator|admin] [--room]")
+	}
+
+	username := strings.TrimPrefix(args[0], "@")
+	inRoom := false
+	var roleArgs []string
+	for _, arg := range args[1:] {
+		if arg == "--room" {
+			inRoom = true
+			continue
+		}
+		roleArgs = append(roleArgs, arg)
+	}
+
+	if len(roleArgs) == 0 {
+		text := fmt.Sprintf("🎭 %s: %s server-wide", username, s.roles.Global(username))
+		if s.config.IsAdmin(username) {
+			text = fmt.Sprintf("🎭 %s: admin (configured)", username)
+		}
+		target := &userPkg.User{Username: username}
+		if online, exists := s.userService.GetUserByName(username); exists {
+			if targetConn, exists := s.wsManager.GetConnection(online.ConnID); exists {
+				if connUser, ok := targetConn.GetUser().(*userPkg.User); ok && connUser != nil {
+					target = connUser
+				}
+			}
+		}
+		if target.Guest {
+			text += " (online as a guest: roles apply only after logging in)"
+		}
+		if admin.CurrentRoom != "" {
+			text += fmt.Sprintf(", %s in '%s'", effectiveRole(s.config, s.roles, s.roomService, target, admin.CurrentRoom), admin.CurrentRoom)
+		}
+		return replySystem(conn, text)
+	}
+
+	role, err := userPkg.ParseRole(roleArgs[0])
+	if err != nil {
+		return err
+	}
+	if role == RoleOwner {
+		return fmt.Errorf("room owners are whoever created the room and cannot be assigned")
+	}
+	if s.config.IsAdmin(username) {
+		return fmt.Errorf("'%s' is an admin in the server config; change admin_usernames instead", username)
+	}
+
+	scope := "server-wide"
+	if inRoom {
+		if admin.CurrentRoom == "" {
+			return fmt.Errorf("you are not in any room")
+		}
+		if role == RoleAdmin {
+			return fmt.Errorf("admin is a server-wide role, drop --room")
+		}
+		s.roles.SetRoom(admin.CurrentRoom, username, role)
+		scope = fmt.Sprintf("in room '%s'", admin.CurrentRoom)
+	} else {
+		s.roles.SetGlobal(username, role)
+	}
+	slog.Info("🎭 Role changed", "by", admin.Username, "username", username, "role", role.String(), "scope", scope)
+
+	// แจ้งเจ้าตัวถ้าออนไลน์อยู่
+	if target, exists := s.userService.GetUserByName(username); exists {
+		if targetConn, exists := s.wsManager.GetConnection(target.ConnID); exists {
+			replySystem(targetConn, fmt.Sprintf("🎭 %s made you %s %s", admin.Username, role, scope))
+		}
+	}
+	return replySystem(conn, fmt.Sprintf("🎭 %s is now %s %s", username, role, scope))
+}
+
+// SetRoles sets the role store used for moderation checks outside commands
+func (h *Handler) SetRoles(roles *userPkg.RoleStore) {
+	h.roles = roles
+}
+
+// connectedUser returns the connection's copy of a user (which knows whether it is a guest), or u when offline
+func (h *Handler) connectedUser(u *userPkg.User) *userPkg.User {
+	if conn, exists := h.wsManager.GetConnection(u.ConnID); exists {
+		if chatUser, ok := conn.GetUser().(*userPkg.User); ok && chatUser != nil {
+			return chatUser
+		}
+	}
+	return u
+}
+
+// roleOf returns the user's effective role in a room
+func (h *Handler) roleOf(user *userPkg.User, roomName string) CommandRole {
+	return effectiveRole(h.config, h.roles, h.roomService, user, roomName)
+}
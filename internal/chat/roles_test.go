@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"realtime-chat/internal/config"
+)
+
+// ชื่อใน admin_usernames ที่เข้ามาเป็น guest ไม่ได้สิทธิ์ admin ส่วนเจ้าของ account ได้
+func TestAdminRightsNeedAccountSession(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.ServerConfig) {
+		cfg.AdminUsernames = []string{"alice", "mallory"}
+	})
+	mallory := server.join(t, "mallory")
+	mallory.send(map[string]interface{}{"type": "command", "content": "/setrole mallory admin"})
+	if message, _ := mallory.expect("error")["message"].(string); !strings.Contains(message, "requires the admin role") {
+		t.Fatalf("guest admin name got %q", message)
+	}
+
+	alice := server.login(t, "alice")
+	alice.send(map[string]interface{}{"type": "command", "content": "/setrole bobby moderator"})
+	for {
+		if content, _ := alice.expect("system")["content"].(string); strings.Contains(content, "bobby is now moderator") {
+			break
+		}
+	}
+}
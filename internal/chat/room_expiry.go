@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+)
+
+// notifyRoomExpiry sends countdown and expiry notices for a temporary room to its members.
+// ส่งตรงถึงแต่ละ connection แทน BroadcastToRoom เพราะตอนหมดอายุสมาชิกจะถูกย้ายออกทันที
+func (h *Handler) notifyRoomExpiry(roomName string, members []*userPkg.User, remaining time.Duration, expired bool) {
+	message := ServerMessage{
+		Type:      "room_expiring",
+		Room:      roomName,
+		Message:   fmt.Sprintf("⏳ Room '%s' expires in %v", roomName, remaining.Round(time.Second)),
+		Timestamp: time.Now(),
+	}
+	if expired {
+		message.Type = "room_expired"
+		message.Message = fmt.Sprintf("⌛ Room '%s' has expired and was archived. Use /join <room> to continue chatting", roomName)
+	}
+
+	for _, member := range members {
+		// คำเตือนนับถอยหลังเป็น notification ข้ามได้เมื่อ snooze ไว้ ส่วนการหมดอายุต้องแจ้งเสมอ
+		if !expired && h.snoozes.IsSnoozed(member.Username, roomName) {
+			continue
+		}
+		if conn, exists := h.wsManager.GetConnection(member.ConnID); exists {
+			h.sendJSONMessage(conn, message)
+		}
+	}
+}
+
+// notifyRoomArchive tells every connection that an idle room was archived or an archived room is back,
+// so clients can update their room lists. ห้อง invite-only ไม่ประกาศ เพราะชื่อห้องซ่อนจากคนนอกอยู่แล้ว
+func (h *Handler) notifyRoomArchive(chatRoom *room.Room, archived bool) {
+	if chatRoom.IsPrivate {
+		return
+	}
+
+	message := ServerMessage{
+		Type:      "room_archived",
+		Room:      chatRoom.Name,
+		Message:   fmt.Sprintf("🗄️ Room '%s' was archived after being empty for a while. Use /create %s to bring it back", chatRoom.Name, chatRoom.Name),
+		Timestamp: time.Now(),
+	}
+	if !archived {
+		message.Type = "room_reactivated"
+		message.Message = fmt.Sprintf("♻️ Room '%s' is back", chatRoom.Name)
+	}
+	h.broadcastJSONToRoom(message, "", "")
+}
@@ -0,0 +1,250 @@
+package chat
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+)
+
+// Delivery modes announced with delivery_mode
+const (
+	DeliveryFull    = "full"
+	DeliverySampled = "sampled"
+)
+
+// roomDigest is the messages coalesced for the passive members of a sampled room
+type roomDigest struct {
+	messages []*messagePkg.Message // ล่าสุดไม่เกิน MaxDigestMessages
+	total    int                   // ข้อความทั้งหมดตั้งแต่ digest ก่อน
+}
+
+// sampledRoom is the sampling state of one room
+type sampledRoom struct {
+	lastPost map[string]time.Time // username (lowercase) -> เวลาที่โพสต์ล่าสุด
+	sampled  bool
+	digest   roomDigest
+}
+
+// roomSampler decides which rooms are in sampled delivery mode and buffers their digests.
+// ห้องที่สมาชิกถึง threshold ส่งข้อความเต็มเฉพาะผู้ที่โพสต์ภายใน active window และผู้ถูก mention
+// สมาชิกที่เหลือได้รับ digest เป็นรอบ เพื่อลด fan-out ช่วงที่ห้องคึกคัก
+type roomSampler struct {
+	threshold    int
+	activeWindow time.Duration
+	maxDigest    int
+	rooms        map[string]*sampledRoom
+	mutex        sync.Mutex
+}
+
+// newRoomSampler creates a sampler, or nil when sampled delivery is disabled
+func newRoomSampler(cfg *config.ServerConfig) *roomSampler {
+	if !cfg.EnableRoomSampling || cfg.SamplingThreshold <= 0 {
+		return nil
+	}
+	return &roomSampler{
+		threshold:    cfg.SamplingThreshold,
+		activeWindow: cfg.SamplingActiveWindow,
+		maxDigest:    cfg.MaxDigestMessages,
+		rooms:        make(map[string]*sampledRoom),
+	}
+}
+
+// recordPost marks a user as an active participant of a room and updates the room's mode.
+// คืน leftover digest เมื่อห้องกลับเป็น full delivery เพื่อส่งให้สมาชิกก่อนเปลี่ยนโหมด
+func (s *roomSampler) recordPost(roomName, username string, members int, now time.Time) (sampled, changed bool, leftover roomDigest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, exists := s.rooms[roomName]
+	if !exists {
+		state = &sampledRoom{lastPost: make(map[string]time.Time)}
+		s.rooms[roomName] = state
+	}
+	state.lastPost[strings.ToLower(username)] = now
+
+	sampled = members >= s.threshold
+	if sampled == state.sampled {
+		return sampled, false, roomDigest{}
+	}
+	state.sampled = sampled
+	if !sampled {
+		leftover = state.digest
+		state.digest = roomDigest{}
+	}
+	return sampled, true, leftover
+}
+
+// activeUsers returns the users who posted in the room within the active window
+func (s *roomSampler) activeUsers(roomName string, now time.Time) map[string]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	active := make(map[string]bool)
+	if state, exists := s.rooms[roomName]; exists {
+		for username, posted := range state.lastPost {
+			if now.Sub(posted) <= s.activeWindow {
+				active[username] = true
+			}
+		}
+	}
+	return active
+}
+
+// addToDigest buffers a message for the room's next digest
+func (s *roomSampler) addToDigest(roomName string, message *messagePkg.Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, exists := s.rooms[roomName]
+	if !exists {
+		return
+	}
+	state.digest.total++
+	state.digest.messages = append(state.digest.messages, message)
+	if len(state.digest.messages) > s.maxDigest {
+		state.digest.messages = state.digest.messages[len(state.digest.messages)-s.maxDigest:]
+	}
+}
+
+// takeDigests removes and returns the pending digests of all rooms
+func (s *roomSampler) takeDigests() map[string]roomDigest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	digests := make(map[string]roomDigest)
+	for roomName, state := range s.rooms {
+		if state.digest.total > 0 {
+			digests[roomName] = state.digest
+			state.digest = roomDigest{}
+		}
+	}
+	return digests
+}
+
+// Reap drops participants outside the active window and rooms with nothing left to track
+func (s *roomSampler) Reap(aggressive bool) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	count := 0
+	for roomName, state := range s.rooms {
+		for username, posted := range state.lastPost {
+			if now.Sub(posted) > s.activeWindow {
+				delete(state.lastPost, username)
+				count++
+			}
+		}
+		if len(state.lastPost) == 0 && state.digest.total == 0 {
+			delete(s.rooms, roomName)
+		}
+	}
+	return count
+}
+
+// deliverSampled delivers a chat message in a room at or above the sampling threshold.
+// คืนค่า false เมื่อห้องยังส่งแบบปกติ ผู้เรียกจึง broadcast ตามเดิม
+func (h *Handler) deliverSampled(conn Connection, message *messagePkg.Message) bool {
+	if h.sampler == nil {
+		return false
+	}
+
+	now := time.Now()
+	members := h.roomService.GetUsersInRoom(message.RoomName)
+	sampled, changed, leftover := h.sampler.recordPost(message.RoomName, message.Username, len(members), now)
+	if changed {
+		if leftover.total > 0 {
+			h.sendDigest(message.RoomName, leftover)
+		}
+		h.announceDeliveryMode(message.RoomName, sampled, len(members))
+	}
+	if !sampled {
+		return false
+	}
+
+	// @everyone/@here ถูกจำกัดสิทธิ์อยู่แล้ว ส่งเต็มให้ทุกคนตามความตั้งใจของผู้ส่ง
+	mentions := parseMentions(message.Content)
+	if len(mentions.Broadcast) > 0 {
+		return false
+	}
+	mentioned := make(map[string]bool, len(mentions.Users))
+	for _, username := range mentions.Users {
+		mentioned[username] = true
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		connLogger(conn).Error("❌ Failed to marshal sampled message", "error", err)
+		return false
+	}
+	active := h.sampler.activeUsers(message.RoomName, now)
+	delivered := 0
+	for _, member := range members {
+		if member.ConnID == conn.GetID() {
+			continue
+		}
+		username := strings.ToLower(member.Username)
+		if active[username] || mentioned[username] {
+			h.wsManager.SendMessage(member.ConnID, data)
+			delivered++
+		}
+	}
+	h.sampler.addToDigest(message.RoomName, message)
+	// ไม่ผ่าน BroadcastToRoom ผู้ติดตามห้อง (gRPC stream) จึงต้องได้รับแยก
+	h.wsManager.NotifyRoomObservers(data, message.RoomName)
+	connLogger(conn).Debug("📉 Sampled delivery", "room", message.RoomName, "streamed", delivered, "members", len(members)-1)
+	return true
+}
+
+// FlushDigests sends the coalesced messages of sampled rooms to their passive members (run by the scheduler)
+func (h *Handler) FlushDigests() {
+	if h.sampler == nil {
+		return
+	}
+	for roomName, digest := range h.sampler.takeDigests() {
+		h.sendDigest(roomName, digest)
+	}
+}
+
+// sendDigest sends a digest to members who are not active participants (they already got every message)
+func (h *Handler) sendDigest(roomName string, digest roomDigest) {
+	data, err := json.Marshal(ServerMessage{
+		Type:      "digest",
+		Room:      roomName,
+		Messages:  digest.messages,
+		Total:     digest.total,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		slog.Error("❌ Failed to marshal digest", "room", roomName, "error", err)
+		return
+	}
+
+	active := h.sampler.activeUsers(roomName, time.Now())
+	for _, member := range h.roomService.GetUsersInRoom(roomName) {
+		if !active[strings.ToLower(member.Username)] {
+			h.wsManager.SendMessage(member.ConnID, data)
+		}
+	}
+}
+
+// announceDeliveryMode tells a room's members that delivery switched between full and sampled
+func (h *Handler) announceDeliveryMode(roomName string, sampled bool, members int) {
+	mode := DeliveryFull
+	if sampled {
+		mode = DeliverySampled
+	}
+	slog.Info("📉 Room delivery mode switched", "room", roomName, "mode", mode, "members", members, "threshold", h.sampler.threshold)
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "delivery_mode",
+		Room:      roomName,
+		Status:    mode,
+		Details:   map[string]interface{}{"members": members, "threshold": h.sampler.threshold},
+		Timestamp: time.Now(),
+	}, "", roomName)
+}
@@ -0,0 +1,242 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/storage/sqlite"
+	userPkg "realtime-chat/internal/user"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// testServer runs the chat handler behind a real websocket.Manager and SQLite store, wired like main.go
+type testServer struct {
+	*httptest.Server
+	handler  *Handler
+	commands CommandService
+	accounts *account.Service
+	manager  *wsocket.Manager
+	rooms    room.Service
+	roles    *userPkg.RoleStore
+	messages messagePkg.Repository
+}
+
+// newTestServer starts a server; configure may adjust the default config before anything is created
+func newTestServer(t *testing.T, configure func(cfg *config.ServerConfig)) *testServer {
+	t.Helper()
+
+	cfg := config.DefaultServerConfig()
+	cfg.EnableRateLimit = false
+	if configure != nil {
+		configure(cfg)
+	}
+
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+
+	metrics := config.NewServerMetrics()
+	userService := userPkg.NewService(store.Users(), metrics)
+	roomService := room.NewService(store.Rooms(), cfg.MaxRooms, cfg.MaxUsersPerRoom, cfg.MaxRoomCapacity, metrics)
+	manager := wsocket.NewManager(cfg, userService, &testRoomAdapter{roomService}, metrics)
+	roomService.OnMembershipChange(func(roomName string, u *userPkg.User, joined bool) {
+		manager.TrackRoomMembership(roomName, u.ConnID, joined)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		manager.Run(ctx)
+		close(stopped)
+	}()
+
+	adapter := &testManagerAdapter{manager}
+	messageService := NewMessageService(adapter)
+	commandService := NewCommandService(userService, roomService, messageService, adapter, metrics, cfg, nil)
+	handler := NewHandler(adapter, userService, roomService, commandService, messageService, cfg)
+	commandService.SetMessageRepository(store.Messages())
+	handler.SetMessageRepository(store.Messages())
+	accounts := account.NewService(store.Accounts(), cfg)
+	handler.SetAccounts(accounts)
+	roles := userPkg.NewRoleStore()
+	handler.SetRoles(roles)
+	commandService.SetRoles(roles)
+
+	server := &testServer{
+		Server:   httptest.NewServer(http.HandlerFunc(handler.HandleWebSocket)),
+		handler:  handler,
+		commands: commandService,
+		accounts: accounts,
+		manager:  manager,
+		rooms:    roomService,
+		roles:    roles,
+		messages: store.Messages(),
+	}
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+		<-stopped
+		store.Close()
+	})
+	return server
+}
+
+// join connects a client and waits until it is in the general room
+func (s *testServer) join(t *testing.T, username string) *testClient {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client := &testClient{t: t, conn: conn}
+	t.Cleanup(func() { conn.Close() })
+
+	client.send(map[string]interface{}{"type": "join", "username": username})
+	client.expect("session")
+	return client
+}
+
+// login registers an account, logs in and joins with its session token; only account users get roles
+func (s *testServer) login(t *testing.T, username string) *testClient {
+	t.Helper()
+
+	if _, err := s.accounts.Register(username, "correct horse battery"); err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+	_, token, err := s.accounts.Login(username, "correct horse battery", "test")
+	if err != nil {
+		t.Fatalf("login %s: %v", username, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client := &testClient{t: t, conn: conn}
+	t.Cleanup(func() { conn.Close() })
+
+	client.send(map[string]interface{}{"type": "join", "session_token": token})
+	client.expect("session")
+	return client
+}
+
+// testClient is a JSON client of the test server
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// send writes v as a JSON frame
+func (c *testClient) send(v interface{}) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(v); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+// next returns the next JSON object frame, skipping plain-text prompts
+func (c *testClient) next() map[string]interface{} {
+	c.t.Helper()
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("read: %v", err)
+		}
+		var frame map[string]interface{}
+		if json.Unmarshal(data, &frame) == nil {
+			return frame
+		}
+	}
+}
+
+// expect reads frames until one of the given type arrives
+func (c *testClient) expect(frameType string) map[string]interface{} {
+	c.t.Helper()
+	for {
+		if frame := c.next(); frame["type"] == frameType {
+			return frame
+		}
+	}
+}
+
+// testRoomAdapter adapts room.Service to websocket.RoomService
+type testRoomAdapter struct {
+	roomService room.Service
+}
+
+func (r *testRoomAdapter) LeaveRoom(user interface{}, roomName string) error {
+	if chatUser, ok := user.(*userPkg.User); ok {
+		return r.roomService.LeaveRoom(chatUser, roomName)
+	}
+	return nil
+}
+
+// testManagerAdapter adapts websocket.Manager to WebSocketManager
+type testManagerAdapter struct {
+	manager *wsocket.Manager
+}
+
+func (a *testManagerAdapter) AddConnection(conn interface{}, hello []byte, encoder wsocket.FrameEncoder) (string, bool) {
+	if wsConn, ok := conn.(*websocket.Conn); ok {
+		return a.manager.AddConnection(wsConn, hello, encoder)
+	}
+	return "", false
+}
+
+func (a *testManagerAdapter) SendMessage(connID string, message []byte) error {
+	return a.manager.SendMessage(connID, message)
+}
+
+func (a *testManagerAdapter) SendUntraced(connID string, message []byte) error {
+	return a.manager.SendUntraced(connID, message)
+}
+
+func (a *testManagerAdapter) RemoveConnection(connID string) {
+	a.manager.RemoveConnection(connID)
+}
+
+func (a *testManagerAdapter) GetConnection(connID string) (Connection, bool) {
+	conn, exists := a.manager.GetConnection(connID)
+	return conn, exists
+}
+
+func (a *testManagerAdapter) Usernames() map[string]string {
+	return a.manager.Usernames()
+}
+
+func (a *testManagerAdapter) BroadcastMessage(message interface{}, excludeID string) {
+	a.manager.BroadcastMessage(message, excludeID)
+}
+
+func (a *testManagerAdapter) BroadcastToRoom(message interface{}, excludeID, roomName string) {
+	a.manager.BroadcastToRoom(message, excludeID, roomName)
+}
+
+func (a *testManagerAdapter) GetConnectionHealth(connID string) (interface{}, bool) {
+	health, exists := a.manager.GetConnectionHealth(connID)
+	return health, exists
+}
+
+func (a *testManagerAdapter) RecordDeliveryAck(connID, probeID string) {
+	a.manager.RecordDeliveryAck(connID, probeID)
+}
+
+func (a *testManagerAdapter) NotifyRoomObservers(message interface{}, roomName string) {
+	a.manager.NotifyRoomObservers(message, roomName)
+}
@@ -1,9 +1,22 @@
 package chat
 
 import (
+	"time"
+
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/commands"
+	"realtime-chat/internal/config"
 	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/metricstore"
+	"realtime-chat/internal/migration"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/naming"
+	"realtime-chat/internal/presence"
 	"realtime-chat/internal/room"
+	"realtime-chat/internal/search"
+	"realtime-chat/internal/security"
 	userPkg "realtime-chat/internal/user"
+	wsocket "realtime-chat/internal/websocket"
 )
 
 // UserService interface for user operations
@@ -15,17 +28,36 @@ type UserService interface {
 	IsUsernameAvailable(username string) bool
 	GetAllUsers() []*userPkg.User
 	UpdateLastActive(connID string)
+	GetProfile(username string) *userPkg.Profile
+	UpdateProfile(user *userPkg.User, profile userPkg.Profile) (*userPkg.Profile, error)
 }
 
 // RoomService interface for room operations
 type RoomService interface {
 	CreateRoom(name, creatorUsername string) (*room.Room, error)
+	CreateRoomWithOptions(name, creatorUsername string, opts room.CreateOptions) (*room.Room, error)
+	SetMaxUsers(roomName, requestedBy string, maxUsers int) error
+	SetExportKey(roomName, requestedBy, armoredKey string) error
+	SetMirrorWriter(roomName, requestedBy, nodeID string) error
+	SetPrivate(roomName, requestedBy string, private bool) error
+	InviteUser(roomName, requestedBy, username string) error
+	AuthorizeJoin(roomName, username, password string) error
+	IsMember(roomName, username string) bool
+	SetRetention(roomName, requestedBy string, retention time.Duration) error
+	SetTopic(roomName, requestedBy, topic string) error
+	SetDescription(roomName, requestedBy, description string) error
+	PinMessage(roomName, requestedBy, messageID string) error
+	UnpinMessage(roomName, requestedBy, messageID string) error
 	JoinRoom(user *userPkg.User, roomName string) error
 	LeaveRoom(user *userPkg.User, roomName string) error
 	GetRoom(name string) (*room.Room, bool)
 	GetRooms() []*room.Room
 	GetUsersInRoom(roomName string) []*userPkg.User
 	GetRoomCount() int
+	RecordActivity(roomName string)
+	OnMembershipChange(callback room.MembershipCallback)
+	OnExpiry(callback room.ExpiryCallback)
+	OnArchive(callback room.ArchiveCallback)
 }
 
 // CommandService interface for command processing
@@ -33,7 +65,46 @@ type CommandService interface {
 	RegisterCommand(cmd *Command)
 	ExecuteCommand(conn Connection, message string) error
 	GetCommands() map[string]*Command
+	DescribeCommands(user *userPkg.User) []commands.Info
 	SetMessageRepository(repo MessageRepository)
+	SetSearchIndex(index SearchIndex)
+	SetConnectionThrottle(throttle *security.ConnectionThrottle)
+	SetIPLimiter(limiter *security.IPLimiter)
+	SetChurnLimiter(limiter *security.ChurnLimiter)
+	SetPresenceTracker(tracker *presence.Tracker)
+	SetHoneypot(honeypot *moderation.Honeypot)
+	SetAuditLog(audit *moderation.AuditLog)
+	SetLoginLockout(lockout *security.LoginLockout)
+	SetNameGenerator(generator *naming.Generator)
+	SetMetricsHistory(history MetricsHistory)
+	SetAnnouncements(store *announcement.Store)
+	SetDirectMessageRepository(repo messagePkg.DirectMessageRepository)
+	SetMailbox(repo messagePkg.MailboxRepository)
+	SetMigrationRunner(runner MigrationRunner)
+	SendDirectMessage(conn Connection, sender *userPkg.User, recipient, content string) error
+	SetLatencyRecorder(recorder *config.LatencyRecorder)
+	SetRoles(roles *userPkg.RoleStore)
+	SetShutdown(shutdown func(reason string))
+	AlertAdmins(content string) int
+	AlertUser(username, content string) int
+	SetUserRouter(router UserRouter)
+	DeliverToUser(username string, payload []byte) bool
+}
+
+// UserRouter interface for delivering frames to users connected to other nodes
+type UserRouter interface {
+	SendToUser(username string, payload []byte) bool
+}
+
+// MetricsHistory interface for persisted metrics trends
+type MetricsHistory interface {
+	Trend(window time.Duration) (metricstore.Trend, error)
+}
+
+// MigrationRunner interface for schema migration status and application
+type MigrationRunner interface {
+	Status() ([]migration.Status, error)
+	Apply(dryRun bool) ([]migration.Status, error)
 }
 
 // MessageService interface for message broadcasting
@@ -42,24 +113,29 @@ type MessageService interface {
 	BroadcastToRoom(message *messagePkg.Message, excludeID, roomName string)
 }
 
-// MessageRepository interface for message persistence
-type MessageRepository interface {
-	SaveMessage(message *messagePkg.Message) error
-	GetMessageHistory(roomName string, limit int) ([]*messagePkg.Message, error)
-	GetRecentMessages(limit int) ([]*messagePkg.Message, error)
-	GetUserMessageHistory(username string, limit int) ([]*messagePkg.Message, error)
-	GetMessageCount(roomName string) (int64, error)
-	SearchMessages(query string, roomName string, limit int) ([]*messagePkg.Message, error)
+// MessageRepository is the message persistence interface shared by every storage backend
+// (alias ของ message.Repository เพื่อไม่ให้ interface ซ้ำกันสองที่)
+type MessageRepository = messagePkg.Repository
+
+// SearchIndex interface for the optional external search backend
+type SearchIndex interface {
+	IndexMessage(message *messagePkg.Message)
+	Search(query search.Query) ([]*search.Result, error)
 }
 
 // WebSocketManager interface for WebSocket connection management
 type WebSocketManager interface {
-	AddConnection(conn interface{}) string
+	AddConnection(conn interface{}, hello []byte, encoder wsocket.FrameEncoder) (string, bool)
+	SendMessage(connID string, message []byte) error
+	SendUntraced(connID string, message []byte) error
 	RemoveConnection(connID string)
 	GetConnection(connID string) (Connection, bool)
+	Usernames() map[string]string
 	BroadcastMessage(message interface{}, excludeID string)
 	BroadcastToRoom(message interface{}, excludeID, roomName string)
 	GetConnectionHealth(connID string) (interface{}, bool)
+	RecordDeliveryAck(connID, probeID string)
+	NotifyRoomObservers(message interface{}, roomName string)
 }
 
 // messageService implements MessageService
@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// snoozeStore keeps per-user room snoozes. ระหว่าง snooze จะไม่สร้าง notification ของห้องนั้น
+// แต่ข้อความยังส่งถึงตามปกติ เก็บตาม username จึงยังอยู่หลัง reconnect (ยกเว้น guest ดู clearUserState)
+type snoozeStore struct {
+	snoozes map[string]map[string]time.Time // username -> roomName -> until
+	mutex   sync.RWMutex
+}
+
+// newSnoozeStore creates a new snooze store
+func newSnoozeStore() *snoozeStore {
+	return &snoozeStore{snoozes: make(map[string]map[string]time.Time)}
+}
+
+// Snooze suppresses notifications for a room until the given time
+func (s *snoozeStore) Snooze(username, roomName string, until time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rooms, exists := s.snoozes[username]
+	if !exists {
+		rooms = make(map[string]time.Time)
+		s.snoozes[username] = rooms
+	}
+	rooms[roomName] = until
+}
+
+// Resume removes a snooze, reporting whether one was active
+func (s *snoozeStore) Resume(username, roomName string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	until, exists := s.snoozes[username][roomName]
+	if !exists {
+		return false
+	}
+	delete(s.snoozes[username], roomName)
+	if len(s.snoozes[username]) == 0 {
+		delete(s.snoozes, username)
+	}
+	return time.Now().Before(until)
+}
+
+// Clear removes every snooze of a user
+func (s *snoozeStore) Clear(username string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.snoozes, username)
+}
+
+// IsSnoozed reports whether notifications for a room are currently snoozed for a user
+func (s *snoozeStore) IsSnoozed(username, roomName string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	until, exists := s.snoozes[username][roomName]
+	return exists && time.Now().Before(until)
+}
+
+// Active returns the user's unexpired snoozes
+func (s *snoozeStore) Active(username string) map[string]time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+	active := make(map[string]time.Time, len(s.snoozes[username]))
+	for roomName, until := range s.snoozes[username] {
+		if now.Before(until) {
+			active[roomName] = until
+		}
+	}
+	return active
+}
+
+// Reap drops expired snoozes
+func (s *snoozeStore) Reap(aggressive bool) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	count := 0
+	for username, rooms := range s.snoozes {
+		for roomName, until := range rooms {
+			if !now.Before(until) {
+				delete(rooms, roomName)
+				count++
+			}
+		}
+		if len(rooms) == 0 {
+			delete(s.snoozes, username)
+		}
+	}
+	return count
+}
+
+// handleSnoozeCommand handles /snooze [<room> <duration>|<room> off]
+func (h *Handler) handleSnoozeCommand(conn Connection, args []string) error {
+	chatUser, ok := conn.GetUser().(*userPkg.User)
+	if !ok || chatUser == nil {
+		return fmt.Errorf("user not authenticated")
+	}
+
+	if len(args) == 0 {
+		return replySystem(conn, h.describeSnoozes(chatUser))
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /snooze <room> <duration>|off")
+	}
+
+	roomName := args[0]
+	if args[1] == "off" {
+		if !h.snoozes.Resume(chatUser.Username, roomName) {
+			return fmt.Errorf("notifications for '%s' are not snoozed", roomName)
+		}
+		h.sendPreferences(conn, chatUser)
+		return replySystem(conn, fmt.Sprintf("🔔 Notifications for '%s' resumed", roomName))
+	}
+
+	if _, exists := h.roomService.GetRoom(roomName); !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration '%s' (e.g. 30m, 8h)", args[1])
+	}
+	if duration > h.config.MaxSnoozeDuration {
+		return fmt.Errorf("snooze duration cannot exceed %v", h.config.MaxSnoozeDuration)
+	}
+
+	until := time.Now().Add(duration)
+	h.snoozes.Snooze(chatUser.Username, roomName, until)
+	h.sendPreferences(conn, chatUser)
+	return replySystem(conn, fmt.Sprintf("🔕 Notifications for '%s' snoozed until %s (messages are still delivered)",
+		roomName, chatUser.FormatTime(until, "Jan 2 15:04 MST")))
+}
+
+// describeSnoozes lists a user's active snoozes
+func (h *Handler) describeSnoozes(user *userPkg.User) string {
+	active := h.snoozes.Active(user.Username)
+	if len(active) == 0 {
+		return "🔔 No rooms snoozed. Usage: /snooze <room> <duration>|off"
+	}
+
+	rooms := make([]string, 0, len(active))
+	for roomName := range active {
+		rooms = append(rooms, roomName)
+	}
+	sort.Strings(rooms)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🔕 Snoozed rooms (%d):\n", len(rooms)))
+	for _, roomName := range rooms {
+		text.WriteString(fmt.Sprintf("• %s until %s\n", roomName, user.FormatTime(active[roomName], "Jan 2 15:04 MST")))
+	}
+	return text.String()
+}
@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"strings"
+)
+
+// WebSocket subprotocols (Sec-WebSocket-Protocol) that select the inbound frame format
+const (
+	SubprotocolJSON    = "chat.v1.json"    // ทุก frame เป็น JSON envelope ที่มี type
+	SubprotocolText    = "chat.v1.text"    // legacy: frame แรกเป็นชื่อผู้ใช้, /... เป็นคำสั่ง, นอกนั้นเป็นข้อความ
+	SubprotocolMsgpack = "chat.v1.msgpack" // envelope เดียวกับ JSON แต่เข้ารหัสเป็น MessagePack ใน binary frame
+)
+
+// supportedSubprotocols lists the subprotocols in server preference order
+var supportedSubprotocols = []string{SubprotocolJSON, SubprotocolText}
+
+// subprotocols returns the subprotocols this server negotiates; msgpack เปิดเฉพาะเมื่อ EnableMsgpack
+func (h *Handler) subprotocols() []string {
+	if !h.config.EnableMsgpack {
+		return supportedSubprotocols
+	}
+	return append([]string{SubprotocolMsgpack}, supportedSubprotocols...)
+}
+
+// negotiatedSubprotocol returns the frame format of a connection.
+// client ที่ไม่ได้ขอ subprotocol ใช้ค่า DefaultSubprotocol จาก config
+func (h *Handler) negotiatedSubprotocol(selected string) string {
+	if selected != "" {
+		return selected
+	}
+	switch h.config.DefaultSubprotocol {
+	case SubprotocolText:
+		return SubprotocolText
+	case SubprotocolMsgpack:
+		if h.config.EnableMsgpack {
+			return SubprotocolMsgpack
+		}
+	}
+	return SubprotocolJSON
+}
+
+// parseTextFrame converts a legacy plain-text frame into a client message
+func parseTextFrame(content string) ClientMessage {
+	if strings.HasPrefix(strings.TrimSpace(content), "/") {
+		return ClientMessage{Type: "command", Content: strings.TrimSpace(content)}
+	}
+	return ClientMessage{Type: "message", Content: content}
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"log/slog"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+	userPkg "realtime-chat/internal/user"
+)
+
+// SetTimelineRepository enables the room timeline: messages get a per-room sequence number and
+// joins/leaves are recorded as room events
+func (h *Handler) SetTimelineRepository(repo messagePkg.TimelineRepository) {
+	h.timeline = repo
+	h.roomService.OnMembershipChange(h.recordMembershipEvent)
+}
+
+// assignTimelineSeq gives a message the next sequence number of its room before it is saved
+func (h *Handler) assignTimelineSeq(conn Connection, message *messagePkg.Message) {
+	if h.timeline == nil {
+		return
+	}
+	seq, err := h.timeline.NextSeq(message.RoomName)
+	if err != nil {
+		// ข้อความยังบันทึกได้ตามปกติ แค่จะไม่ปรากฏใน timeline
+		connLogger(conn).Warn("⚠️ Failed to assign timeline sequence", "error", err)
+		return
+	}
+	message.Seq = seq
+}
+
+// recordMembershipEvent records a join or leave in the room's timeline (private rooms are not recorded)
+func (h *Handler) recordMembershipEvent(roomName string, user *userPkg.User, joined bool) {
+	if room, exists := h.roomService.GetRoom(roomName); !exists || room.Private {
+		return
+	}
+
+	event := &messagePkg.RoomEvent{
+		RoomName:  roomName,
+		Type:      messagePkg.TimelineLeave,
+		Username:  user.Username,
+		Timestamp: time.Now(),
+	}
+	if joined {
+		event.Type = messagePkg.TimelineJoin
+	}
+	if err := h.timeline.RecordEvent(event); err != nil {
+		slog.Warn("⚠️ Failed to record timeline event", "event", event.Type, "username", user.Username, "room", roomName, "error", err)
+	}
+}
@@ -0,0 +1,242 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// traceBuffer is how many events may wait for delivery to the admin before new ones are dropped
+const traceBuffer = 256
+
+// TraceFrame is one trace event streamed to the admin running /trace
+type TraceFrame struct {
+	ID         string             `json:"id"` // correlation ID ของ trace ใช้ค้นใน log
+	Connection string             `json:"connection"`
+	Event      wsocket.TraceEvent `json:"event"`
+	Dropped    int                `json:"dropped,omitempty"` // event ที่ทิ้งไปก่อนหน้านี้เพราะ buffer เต็ม
+}
+
+// traceSession streams the events of one traced connection to one admin
+type traceSession struct {
+	id       string
+	target   *wsocket.WebSocketConnection
+	admin    string // connID ของ admin
+	username string
+	expires  time.Time
+	events   chan wsocket.TraceEvent
+	done     chan struct{}
+	dropped  int
+	mutex    sync.Mutex
+}
+
+// traceSessions tracks active /trace sessions by traced connection ID
+type traceSessions struct {
+	sessions map[string]*traceSession
+	mutex    sync.Mutex
+}
+
+// newTraceSessions creates an empty trace session registry
+func newTraceSessions() *traceSessions {
+	return &traceSessions{sessions: make(map[string]*traceSession)}
+}
+
+// record is the TraceFunc of a session. ไม่ block ผู้เรียก (write pump / handleRead) เด็ดขาด
+func (s *traceSession) record(event wsocket.TraceEvent) {
+	select {
+	case s.events <- event:
+	default:
+		s.mutex.Lock()
+		s.dropped++
+		s.mutex.Unlock()
+	}
+}
+
+// takeDropped returns and resets the number of dropped events
+func (s *traceSession) takeDropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// handleTraceCommand handles /trace [<username> [off]]
+func (h *Handler) handleTraceCommand(conn Connection, args []string) error {
+	admin, ok := conn.GetUser().(*userPkg.User)
+	if !ok || admin == nil || h.roleOf(admin, "") < RoleAdmin {
+		return fmt.Errorf("only admins can trace connections")
+	}
+
+	if len(args) == 0 {
+		return replySystem(conn, h.describeTraces())
+	}
+
+	target, exists := h.userService.GetUserByName(args[0])
+	if !exists {
+		return fmt.Errorf("user '%s' is not online", args[0])
+	}
+
+	if len(args) > 1 && strings.EqualFold(args[1], "off") {
+		if !h.stopTrace(target.ConnID, "stopped by "+admin.Username) {
+			return fmt.Errorf("'%s' is not being traced", target.Username)
+		}
+		return replySystem(conn, fmt.Sprintf("🔬 Stopped tracing %s", target.Username))
+	}
+
+	if target.ConnID == conn.GetID() {
+		return fmt.Errorf("you cannot trace your own connection")
+	}
+	connection, exists := h.wsManager.GetConnection(target.ConnID)
+	if !exists {
+		return fmt.Errorf("user '%s' is not online", target.Username)
+	}
+	wsConn, ok := connection.(*wsocket.WebSocketConnection)
+	if !ok {
+		return fmt.Errorf("connection of '%s' does not support tracing", target.Username)
+	}
+
+	session, err := h.startTrace(wsConn, conn.GetID(), target.Username)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("🔬 Trace started", "trace_id", session.id, "admin", admin.Username, "target", wsConn.GetLabel(), "duration", h.config.TraceDuration)
+	return replySystem(conn, fmt.Sprintf("🔬 Tracing %s for %v (trace %s). Use /trace %s off to stop",
+		target.Username, h.config.TraceDuration, session.id, target.Username))
+}
+
+// startTrace attaches a tracer to a connection; only one admin can trace a connection at a time
+func (h *Handler) startTrace(target *wsocket.WebSocketConnection, adminConnID, username string) (*traceSession, error) {
+	h.traces.mutex.Lock()
+	defer h.traces.mutex.Unlock()
+
+	if existing, exists := h.traces.sessions[target.ID]; exists {
+		return nil, fmt.Errorf("'%s' is already being traced (trace %s)", username, existing.id)
+	}
+
+	session := &traceSession{
+		id:       wsocket.NewCorrelationID(),
+		target:   target,
+		admin:    adminConnID,
+		username: username,
+		expires:  time.Now().Add(h.config.TraceDuration),
+		events:   make(chan wsocket.TraceEvent, traceBuffer),
+		done:     make(chan struct{}),
+	}
+	h.traces.sessions[target.ID] = session
+	target.SetTracer(session.record)
+
+	go h.streamTrace(session)
+	return session, nil
+}
+
+// streamTrace delivers a session's events to logs and to the admin until it is stopped or expires
+func (h *Handler) streamTrace(session *traceSession) {
+	expiry := time.NewTimer(time.Until(session.expires))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case event := <-session.events:
+			slog.Info("🔬 Trace event", "trace_id", session.id, "target", session.target.GetLabel(), "kind", event.Kind,
+				"bytes", event.Bytes, "queue", event.QueueDepth, "queue_cap", event.QueueCap, "healthy", event.Healthy, "detail", event.Detail)
+
+			data, err := json.Marshal(ServerMessage{
+				Type: "trace_event",
+				Trace: &TraceFrame{
+					ID:         session.id,
+					Connection: session.target.GetLabel(),
+					Event:      event,
+					Dropped:    session.takeDropped(),
+				},
+				Timestamp: time.Now(),
+			})
+			if err != nil {
+				continue
+			}
+			// admin ออกไปแล้ว ไม่มีใครรับ จึงหยุด trace
+			if err := h.wsManager.SendUntraced(session.admin, data); err != nil {
+				h.stopTrace(session.target.ID, "admin disconnected")
+			}
+		case <-expiry.C:
+			h.stopTrace(session.target.ID, "expired")
+		case <-session.done:
+			return
+		}
+	}
+}
+
+// stopTrace detaches the tracer of a connection and tells the admin why
+func (h *Handler) stopTrace(connID, reason string) bool {
+	h.traces.mutex.Lock()
+	session, exists := h.traces.sessions[connID]
+	if exists {
+		delete(h.traces.sessions, connID)
+	}
+	h.traces.mutex.Unlock()
+
+	if !exists {
+		return false
+	}
+
+	session.target.SetTracer(nil)
+	close(session.done)
+	slog.Info("🔬 Trace stopped", "trace_id", session.id, "target", session.target.GetLabel(), "reason", reason)
+
+	data, err := json.Marshal(ServerMessage{
+		Type:      "trace_stopped",
+		Message:   fmt.Sprintf("🔬 Trace of %s stopped: %s", session.username, reason),
+		Trace:     &TraceFrame{ID: session.id, Connection: session.target.GetLabel(), Dropped: session.takeDropped()},
+		Timestamp: time.Now(),
+	})
+	if err == nil {
+		h.wsManager.SendUntraced(session.admin, data)
+	}
+	return true
+}
+
+// stopTracesFor ends traces that involve a closing connection, as target or as admin
+func (h *Handler) stopTracesFor(connID string) {
+	h.traces.mutex.Lock()
+	var targets []string
+	for targetID, session := range h.traces.sessions {
+		if targetID == connID || session.admin == connID {
+			targets = append(targets, targetID)
+		}
+	}
+	h.traces.mutex.Unlock()
+
+	for _, targetID := range targets {
+		reason := "admin disconnected"
+		if targetID == connID {
+			reason = "connection closed"
+		}
+		h.stopTrace(targetID, reason)
+	}
+}
+
+// describeTraces lists active trace sessions
+func (h *Handler) describeTraces() string {
+	h.traces.mutex.Lock()
+	defer h.traces.mutex.Unlock()
+
+	if len(h.traces.sessions) == 0 {
+		return "🔬 No active traces. Usage: /trace <username> [off]"
+	}
+
+	lines := make([]string, 0, len(h.traces.sessions))
+	for _, session := range h.traces.sessions {
+		lines = append(lines, fmt.Sprintf("• %s (trace %s) - %v remaining",
+			session.target.GetLabel(), session.id, time.Until(session.expires).Round(time.Second)))
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("🔬 Active traces (%d):\n%s", len(lines), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,134 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// typingState is the last typing indicator broadcast for a user
+type typingState struct {
+	room     string
+	lastSent time.Time
+}
+
+// typingTracker debounces typing indicators per user. ขณะพิมพ์ client ส่ง typing_start ได้ทุก keystroke
+// แต่จะ broadcast ซ้ำในห้องเดิมไม่เกินหนึ่งครั้งต่อ interval (ทำหน้าที่เป็น keepalive ให้ client ด้วย)
+type typingTracker struct {
+	states   map[string]*typingState // username -> state
+	interval time.Duration
+	mutex    sync.Mutex
+}
+
+// newTypingTracker creates a new typing tracker
+func newTypingTracker(interval time.Duration) *typingTracker {
+	return &typingTracker{
+		states:   make(map[string]*typingState),
+		interval: interval,
+	}
+}
+
+// Start records that the user is typing in a room, reporting whether typing_start should be broadcast
+func (t *typingTracker) Start(username, roomName string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	if state, exists := t.states[username]; exists && state.room == roomName && now.Sub(state.lastSent) < t.interval {
+		return false
+	}
+	t.states[username] = &typingState{room: roomName, lastSent: now}
+	return true
+}
+
+// Stop clears the user's typing state, returning the room that should get typing_stop ("" if not typing)
+func (t *typingTracker) Stop(username string) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, exists := t.states[username]
+	if !exists {
+		return ""
+	}
+	delete(t.states, username)
+	return state.room
+}
+
+// StopIn clears the user's typing state only if they were typing in the given room
+func (t *typingTracker) StopIn(username, roomName string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if state, exists := t.states[username]; !exists || state.room != roomName {
+		return false
+	}
+	delete(t.states, username)
+	return true
+}
+
+// Reap drops typing states not refreshed for a while (client closed without typing_stop)
+func (t *typingTracker) Reap(aggressive bool) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	cutoff := time.Now().Add(-3 * t.interval)
+	count := 0
+	for username, state := range t.states {
+		if state.lastSent.Before(cutoff) {
+			delete(t.states, username)
+			count++
+		}
+	}
+	return count
+}
+
+// handleTyping handles typing_start/typing_stop. Typing events are ephemeral: never persisted or indexed,
+// only forwarded to the other users in the room
+func (h *Handler) handleTyping(conn Connection, user *userPkg.User, msg ClientMessage) {
+	if msg.Type == "typing_stop" {
+		h.stopTyping(user.Username, conn.GetID())
+		return
+	}
+
+	if user.CurrentRoom == "" || !h.typing.Start(user.Username, user.CurrentRoom) {
+		return
+	}
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "typing_start",
+		Username:  user.Username,
+		Room:      user.CurrentRoom,
+		Timestamp: time.Now(),
+	}, conn.GetID(), user.CurrentRoom)
+}
+
+// stopTyping broadcasts typing_stop if the user was typing
+func (h *Handler) stopTyping(username, excludeID string) {
+	roomName := h.typing.Stop(username)
+	if roomName == "" {
+		return
+	}
+	h.broadcastJSONToRoom(ServerMessage{
+		Type:      "typing_stop",
+		Username:  username,
+		Room:      roomName,
+		Timestamp: time.Now(),
+	}, excludeID, roomName)
+}
+
+// clearTypingOnLeave stops a user's typing indicator when they leave the room they were typing in
+func (h *Handler) clearTypingOnLeave(roomName string, user *userPkg.User, joined bool) {
+	if joined || user == nil {
+		return
+	}
+	if !h.typing.StopIn(user.Username, roomName) {
+		return
+	}
+	// callback นี้ถูกเรียกขณะ room service ถือ lock อยู่ จึง broadcast แยก goroutine
+	go h.broadcastJSONToRoom(ServerMessage{
+		Type:      "typing_stop",
+		Username:  user.Username,
+		Room:      roomName,
+		Timestamp: time.Now(),
+	}, "", roomName)
+}
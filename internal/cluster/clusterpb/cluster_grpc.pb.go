@@ -0,0 +1,289 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: clusterpb/cluster.proto
+
+package clusterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Cluster_Gossip_FullMethodName       = "/cluster.Cluster/Gossip"
+	Cluster_Publish_FullMethodName      = "/cluster.Cluster/Publish"
+	Cluster_Deliver_FullMethodName      = "/cluster.Cluster/Deliver"
+	Cluster_SyncInterest_FullMethodName = "/cluster.Cluster/SyncInterest"
+	Cluster_DeliverUser_FullMethodName  = "/cluster.Cluster/DeliverUser"
+)
+
+// ClusterClient is the client API for Cluster service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Cluster is the node-to-node service of cluster mode.
+// ทุก call ต้องมี metadata x-cluster-secret ตรงกับ cluster_secret (ถ้าตั้งไว้)
+type ClusterClient interface {
+	// Gossip exchanges member lists; the receiver merges the sender's view and answers with its own
+	Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipResponse, error)
+	// Publish hands a room broadcast to the node that owns the room, which fans it out
+	Publish(ctx context.Context, in *RoomFrame, opts ...grpc.CallOption) (*Ack, error)
+	// Deliver sends a broadcast to the local connections of the receiving node
+	Deliver(ctx context.Context, in *RoomFrame, opts ...grpc.CallOption) (*Ack, error)
+	// SyncInterest tells a room owner which of its rooms have members on the calling node
+	SyncInterest(ctx context.Context, in *InterestRequest, opts ...grpc.CallOption) (*Ack, error)
+	// DeliverUser sends a frame to a user's connections on the receiving node (direct messages)
+	DeliverUser(ctx context.Context, in *UserFrame, opts ...grpc.CallOption) (*DeliverResult, error)
+}
+
+type clusterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClusterClient(cc grpc.ClientConnInterface) ClusterClient {
+	return &clusterClient{cc}
+}
+
+func (c *clusterClient) Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GossipResponse)
+	err := c.cc.Invoke(ctx, Cluster_Gossip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) Publish(ctx context.Context, in *RoomFrame, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Cluster_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) Deliver(ctx context.Context, in *RoomFrame, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Cluster_Deliver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SyncInterest(ctx context.Context, in *InterestRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Cluster_SyncInterest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) DeliverUser(ctx context.Context, in *UserFrame, opts ...grpc.CallOption) (*DeliverResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeliverResult)
+	err := c.cc.Invoke(ctx, Cluster_DeliverUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClusterServer is the server API for Cluster service.
+// All implementations must embed UnimplementedClusterServer
+// for forward compatibility.
+//
+// Cluster is the node-to-node service of cluster mode.
+// ทุก call ต้องมี metadata x-cluster-secret ตรงกับ cluster_secret (ถ้าตั้งไว้)
+type ClusterServer interface {
+	// Gossip exchanges member lists; the receiver merges the sender's view and answers with its own
+	Gossip(context.Context, *GossipRequest) (*GossipResponse, error)
+	// Publish hands a room broadcast to the node that owns the room, which fans it out
+	Publish(context.Context, *RoomFrame) (*Ack, error)
+	// Deliver sends a broadcast to the local connections of the receiving node
+	Deliver(context.Context, *RoomFrame) (*Ack, error)
+	// SyncInterest tells a room owner which of its rooms have members on the calling node
+	SyncInterest(context.Context, *InterestRequest) (*Ack, error)
+	// DeliverUser sends a frame to a user's connections on the receiving node (direct messages)
+	DeliverUser(context.Context, *UserFrame) (*DeliverResult, error)
+	mustEmbedUnimplementedClusterServer()
+}
+
+// UnimplementedClusterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedClusterServer struct{}
+
+func (UnimplementedClusterServer) Gossip(context.Context, *GossipRequest) (*GossipResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Gossip not implemented")
+}
+func (UnimplementedClusterServer) Publish(context.Context, *RoomFrame) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedClusterServer) Deliver(context.Context, *RoomFrame) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method Deliver not implemented")
+}
+func (UnimplementedClusterServer) SyncInterest(context.Context, *InterestRequest) (*Ack, error) {
+	return nil, status.Error(codes.Unimplemented, "method SyncInterest not implemented")
+}
+func (UnimplementedClusterServer) DeliverUser(context.Context, *UserFrame) (*DeliverResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeliverUser not implemented")
+}
+func (UnimplementedClusterServer) mustEmbedUnimplementedClusterServer() {}
+func (UnimplementedClusterServer) testEmbeddedByValue()                 {}
+
+// UnsafeClusterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClusterServer will
+// result in compilation errors.
+type UnsafeClusterServer interface {
+	mustEmbedUnimplementedClusterServer()
+}
+
+func RegisterClusterServer(s grpc.ServiceRegistrar, srv ClusterServer) {
+	// If the following call panics, it indicates UnimplementedClusterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Cluster_ServiceDesc, srv)
+}
+
+func _Cluster_Gossip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).Gossip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_Gossip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).Gossip(ctx, req.(*GossipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomFrame)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).Publish(ctx, req.(*RoomFrame))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_Deliver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomFrame)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).Deliver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_Deliver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).Deliver(ctx, req.(*RoomFrame))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SyncInterest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InterestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SyncInterest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SyncInterest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SyncInterest(ctx, req.(*InterestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_DeliverUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserFrame)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).DeliverUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_DeliverUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).DeliverUser(ctx, req.(*UserFrame))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Cluster_ServiceDesc is the grpc.ServiceDesc for Cluster service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Cluster_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cluster.Cluster",
+	HandlerType: (*ClusterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Gossip",
+			Handler:    _Cluster_Gossip_Handler,
+		},
+		{
+			MethodName: "Publish",
+			Handler:    _Cluster_Publish_Handler,
+		},
+		{
+			MethodName: "Deliver",
+			Handler:    _Cluster_Deliver_Handler,
+		},
+		{
+			MethodName: "SyncInterest",
+			Handler:    _Cluster_SyncInterest_Handler,
+		},
+		{
+			MethodName: "DeliverUser",
+			Handler:    _Cluster_DeliverUser_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "clusterpb/cluster.proto",
+}
@@ -0,0 +1,243 @@
+package cluster
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"time"
+
+	"realtime-chat/internal/cluster/clusterpb"
+)
+
+// gossipFanout is how many known members each node gossips with per round
+const gossipFanout = 3
+
+// member is what a node knows about another node
+type member struct {
+	id        string
+	addr      string
+	region    string
+	endpoint  string
+	heartbeat uint64
+	lastSeen  time.Time     // เวลาที่ heartbeat ของ node นั้นเพิ่มขึ้นครั้งล่าสุด (นาฬิกาของเราเอง)
+	rtt       time.Duration // round-trip ของ gossip จากเราไป node นั้น (เฉลี่ยแบบ EWMA, 0 = ยังไม่ได้วัด)
+}
+
+// MemberStatus describes a cluster member for status pages and logs
+type MemberStatus struct {
+	ID        string    `json:"id"`
+	Addr      string    `json:"addr"`
+	Region    string    `json:"region,omitempty"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Self      bool      `json:"self"`
+	LastSeen  time.Time `json:"last_seen"`
+	LatencyMS float64   `json:"latency_ms,omitempty"`
+}
+
+// Members returns the live members of the cluster, including this node
+func (n *Node) Members() []MemberStatus {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	members := make([]MemberStatus, 0, len(n.members))
+	for _, m := range n.members {
+		members = append(members, MemberStatus{
+			ID:        m.id,
+			Addr:      m.addr,
+			Region:    m.region,
+			Endpoint:  m.endpoint,
+			Self:      m.id == n.id,
+			LastSeen:  m.lastSeen,
+			LatencyMS: milliseconds(m.rtt),
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// gossipRound bumps our heartbeat, exchanges member lists with a few members (and seeds not yet
+// seen) and drops members that have been silent longer than the node timeout
+func (n *Node) gossipRound() {
+	n.mutex.Lock()
+	self := n.members[n.id]
+	self.heartbeat++
+	self.lastSeen = time.Now()
+	view := n.viewLocked()
+	targets := n.gossipTargetsLocked()
+	n.mutex.Unlock()
+
+	for _, addr := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), n.config.ClusterGossipInterval)
+		sent := time.Now()
+		resp, err := n.peer(addr).client.Gossip(ctx, &clusterpb.GossipRequest{From: n.id, Members: view})
+		rtt := time.Since(sent)
+		cancel()
+		if err != nil {
+			// node ที่ติดต่อไม่ได้จะถูกลบเองเมื่อครบ node timeout
+			continue
+		}
+		if resp.GetFrom() == n.id {
+			n.dropSeed(addr)
+			continue
+		}
+		n.merge(resp.GetMembers())
+		n.recordRTT(resp.GetFrom(), rtt)
+	}
+
+	n.reap()
+}
+
+// recordRTT folds a gossip round-trip into the latency estimate of a member
+func (n *Node) recordRTT(id string, rtt time.Duration) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	m, exists := n.members[id]
+	if !exists {
+		return
+	}
+	if m.rtt == 0 {
+		m.rtt = rtt
+		return
+	}
+	// ค่าใหม่มีน้ำหนัก 1/5 ค่าที่กระโดดครั้งเดียวจึงไม่ทำให้ region ดูไกลทันที
+	m.rtt = (4*m.rtt + rtt) / 5
+}
+
+// dropSeed forgets a seed address that turned out to be this node
+func (n *Node) dropSeed(addr string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for i, seed := range n.seeds {
+		if seed == addr {
+			n.seeds = append(n.seeds[:i], n.seeds[i+1:]...)
+			slog.Info("ℹ️ Cluster seed is this node, ignoring it", "addr", addr)
+			break
+		}
+	}
+	n.closePeerLocked(addr)
+}
+
+// viewLocked returns our member list for gossip (assumes mutex is held)
+func (n *Node) viewLocked() []*clusterpb.Member {
+	view := make([]*clusterpb.Member, 0, len(n.members))
+	for _, m := range n.members {
+		view = append(view, &clusterpb.Member{Id: m.id, Addr: m.addr, Heartbeat: m.heartbeat, Region: m.region, Endpoint: m.endpoint})
+	}
+	return view
+}
+
+// gossipTargetsLocked picks random members plus every seed whose node we have not heard from (assumes mutex is held)
+func (n *Node) gossipTargetsLocked() []string {
+	known := make(map[string]bool, len(n.members))
+	others := make([]string, 0, len(n.members))
+	for _, m := range n.members {
+		known[m.addr] = true
+		if m.id != n.id {
+			others = append(others, m.addr)
+		}
+	}
+
+	rand.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	if len(others) > gossipFanout {
+		others = others[:gossipFanout]
+	}
+	for _, seed := range n.seeds {
+		if !known[seed] {
+			others = append(others, seed)
+		}
+	}
+	return others
+}
+
+// merge applies a member list received from another node; newer heartbeats win
+func (n *Node) merge(members []*clusterpb.Member) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	changed := false
+	now := time.Now()
+	for _, remote := range members {
+		id := remote.GetId()
+		if id == "" {
+			continue
+		}
+		if id == n.id {
+			if remote.GetAddr() != n.addr && !n.warnedDuplicate {
+				n.warnedDuplicate = true
+				slog.Warn("⚠️ Another node uses this node_id, every node needs its own CHAT_NODE_ID", "node_id", id, "addr", remote.GetAddr())
+			}
+			continue
+		}
+
+		// ข่าวเก่าของ node ที่ถูกลบไปแล้วต้องไม่ทำให้มันกลับมา
+		if t, dead := n.dead[id]; dead && remote.GetHeartbeat() <= t.heartbeat {
+			continue
+		}
+
+		existing, exists := n.members[id]
+		if !exists {
+			n.members[id] = &member{
+				id:        id,
+				addr:      remote.GetAddr(),
+				region:    remote.GetRegion(),
+				endpoint:  remote.GetEndpoint(),
+				heartbeat: remote.GetHeartbeat(),
+				lastSeen:  now,
+			}
+			delete(n.dead, id)
+			slog.Info("🤝 Node joined the cluster", "node_id", id, "addr", remote.GetAddr(), "region", remote.GetRegion())
+			changed = true
+			continue
+		}
+		if remote.GetHeartbeat() > existing.heartbeat {
+			existing.heartbeat = remote.GetHeartbeat()
+			existing.addr = remote.GetAddr()
+			existing.region = remote.GetRegion()
+			existing.endpoint = remote.GetEndpoint()
+			existing.lastSeen = now
+		}
+	}
+
+	if changed {
+		n.rebuildRingLocked()
+	}
+}
+
+// reap removes members whose heartbeat has not advanced within the node timeout
+func (n *Node) reap() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	now := time.Now()
+	changed := false
+	for id, m := range n.members {
+		if id == n.id || now.Sub(m.lastSeen) <= n.config.ClusterNodeTimeout {
+			continue
+		}
+		delete(n.members, id)
+		n.dead[id] = tombstone{heartbeat: m.heartbeat, at: now}
+		n.closePeerLocked(m.addr)
+		slog.Warn("💀 Node left the cluster", "node_id", id, "addr", m.addr, "silent_for", now.Sub(m.lastSeen).Round(time.Second))
+		changed = true
+	}
+
+	// tombstone เก็บไว้พอให้ข่าวเก่าหมดไปจาก cluster แล้วลบทิ้ง
+	for id, t := range n.dead {
+		if now.Sub(t.at) > 10*n.config.ClusterNodeTimeout {
+			delete(n.dead, id)
+		}
+	}
+
+	if changed {
+		n.rebuildRingLocked()
+	}
+}
+
+// tombstone remembers the last heartbeat of a removed member
+type tombstone struct {
+	heartbeat uint64
+	at        time.Time
+}
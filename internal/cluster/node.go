@@ -0,0 +1,344 @@
+// Package cluster runs several chat servers as one: each node owns a partition of the rooms by
+// consistent hashing, and room broadcasts are forwarded to the owner over gRPC, which delivers
+// them to every node that has members in the room.
+//
+// เจ้าของห้องเป็นจุดเดียวที่กระจายข้อความของห้องนั้น ทุก node จึงเห็นข้อความของห้องในลำดับเดียวกัน
+// สมาชิกของ cluster รู้กันเองผ่าน gossip (ตั้ง seed ด้วย cluster_peers) เมื่อ node เข้า/ออก ring ถูกสร้างใหม่
+// และแต่ละ node แจ้งห้องที่มีสมาชิกอยู่ให้เจ้าของคนใหม่ทันที
+//
+// ข้อมูลห้อง ผู้ใช้ และข้อความยังอยู่ใน storage backend จึงต้องใช้ backend ที่ทุก node ใช้ร่วมกัน (mongodb หรือ postgres)
+package cluster
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative clusterpb/cluster.proto
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"realtime-chat/internal/cluster/clusterpb"
+	"realtime-chat/internal/config"
+)
+
+// Deliverer delivers a broadcast to the connections on this node (roomName "" = every connection)
+type Deliverer func(roomName string, payload []byte, excludeID string)
+
+// Node is this server's membership in the cluster
+type Node struct {
+	config      *config.ServerConfig
+	id          string
+	addr        string // address ที่ node อื่นใช้ติดต่อเรา
+	seeds       []string
+	deliver     Deliverer
+	localRooms  func() []string
+	deliverUser UserDeliverer // nil = DM จาก node อื่นส่งไม่ถึงใครบน node นี้
+
+	members         map[string]*member
+	dead            map[string]tombstone
+	ring            *Ring
+	peers           map[string]*peer                // addr -> gRPC client
+	interest        map[string]map[string]time.Time // room (ที่เราเป็นเจ้าของ) -> node ID -> เวลาที่แจ้งล่าสุด
+	warnedDuplicate bool
+	mutex           sync.RWMutex
+
+	server      *grpc.Server
+	serverCreds credentials.TransportCredentials // TLS เมื่อ server ตั้ง tls_* ไว้ ไม่อย่างนั้น plaintext
+	clientCreds credentials.TransportCredentials
+	listener    net.Listener
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// New creates a cluster node; deliver sends frames to local connections and localRooms lists
+// the rooms that have members on this node
+func New(cfg *config.ServerConfig, deliver Deliverer, localRooms func() []string) (*Node, error) {
+	// ไม่มี secret ใครก็ตามที่ต่อถึง cluster_addr ส่ง Publish/Deliver ปลอมเข้าห้องได้
+	if cfg.ClusterSecret == "" {
+		return nil, fmt.Errorf("cluster_secret is required when cluster mode is enabled (set CHAT_CLUSTER_SECRET)")
+	}
+	addr, err := advertiseAddr(cfg)
+	if err != nil {
+		return nil, err
+	}
+	serverCreds, clientCreds, err := transportCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		config:      cfg,
+		id:          cfg.NodeID,
+		addr:        addr,
+		deliver:     deliver,
+		localRooms:  localRooms,
+		serverCreds: serverCreds,
+		clientCreds: clientCreds,
+		dead:        make(map[string]tombstone),
+		peers:       make(map[string]*peer),
+		interest:    make(map[string]map[string]time.Time),
+		stop:        make(chan struct{}),
+	}
+	for _, seed := range cfg.ClusterPeers {
+		if seed != "" && seed != addr {
+			n.seeds = append(n.seeds, seed)
+		}
+	}
+
+	// heartbeat เริ่มจากเวลาปัจจุบัน node ที่ restart จึงมี heartbeat มากกว่าค่าเก่าเสมอ และไม่ติด tombstone
+	n.members = map[string]*member{
+		n.id: {id: n.id, addr: addr, region: cfg.Region, endpoint: cfg.PublicURL, heartbeat: uint64(time.Now().UnixMilli()), lastSeen: time.Now()},
+	}
+	n.rebuildRingLocked()
+	return n, nil
+}
+
+// advertiseAddr returns the address other nodes dial, defaulting to the hostname and the cluster port
+func advertiseAddr(cfg *config.ServerConfig) (string, error) {
+	if cfg.ClusterAdvertiseAddr != "" {
+		return cfg.ClusterAdvertiseAddr, nil
+	}
+	_, port, err := net.SplitHostPort(cfg.ClusterAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid cluster_addr %q: %v", cfg.ClusterAddr, err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cluster_advertise_addr: %v", err)
+	}
+	return net.JoinHostPort(hostname, port), nil
+}
+
+// ID returns this node's ID
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Start serves the cluster gRPC service and starts gossiping with the seeds
+func (n *Node) Start() error {
+	listener, err := net.Listen("tcp", n.config.ClusterAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on cluster_addr %s: %v", n.config.ClusterAddr, err)
+	}
+	n.listener = listener
+	n.server = grpc.NewServer(grpc.Creds(n.serverCreds), grpc.UnaryInterceptor(secretInterceptor(n.config.ClusterSecret)))
+	clusterpb.RegisterClusterServer(n.server, &server{node: n})
+
+	n.wg.Add(2)
+	go func() {
+		defer n.wg.Done()
+		if err := n.server.Serve(listener); err != nil {
+			slog.Error("❌ Cluster gRPC server stopped", "error", err)
+		}
+	}()
+	go n.run()
+
+	slog.Info("🕸️ Cluster node listening", "node_id", n.id, "listen", n.config.ClusterAddr, "advertised", n.addr, "seeds", n.seeds)
+	return nil
+}
+
+// Stop leaves the cluster: stops gossiping, closes peer connections and the gRPC server
+func (n *Node) Stop() {
+	close(n.stop)
+	if n.server != nil {
+		n.server.GracefulStop()
+	}
+	n.wg.Wait()
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for addr := range n.peers {
+		n.closePeerLocked(addr)
+	}
+}
+
+// run gossips and refreshes room interest every gossip interval
+func (n *Node) run() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.ClusterGossipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.gossipRound()
+			n.syncInterest()
+		}
+	}
+}
+
+// Owner returns the node that owns a room
+func (n *Node) Owner(roomName string) string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.ring.Owner(roomName)
+}
+
+// BroadcastToRoom sends a broadcast to every node through the room's owner (roomName "" = every connection)
+func (n *Node) BroadcastToRoom(roomName string, payload []byte, excludeID string) {
+	frame := &clusterpb.RoomFrame{Room: roomName, ExcludeId: excludeID, Payload: payload, Origin: n.id}
+
+	// ส่งทุกคนไม่มีเจ้าของ ส่งตรงไปทุก node
+	if roomName == "" {
+		n.fanOutAll(frame, "")
+		return
+	}
+
+	n.mutex.RLock()
+	owner := n.ring.Owner(roomName)
+	ownerAddr := n.members[owner].addr
+	n.mutex.RUnlock()
+
+	if owner == n.id {
+		n.fanOut(frame)
+		return
+	}
+	n.peer(ownerAddr).enqueue(sendPublish, frame)
+}
+
+// Interest registers a room that just got a member on this node with the room's owner,
+// so the next broadcast reaches us without waiting for the periodic sync
+func (n *Node) Interest(roomName string) {
+	n.mutex.RLock()
+	owner := n.ring.Owner(roomName)
+	ownerAddr := n.members[owner].addr
+	n.mutex.RUnlock()
+
+	if owner == n.id {
+		n.addInterest(n.id, []string{roomName})
+		return
+	}
+	go n.sendInterest(ownerAddr, []string{roomName})
+}
+
+// fanOut delivers a frame of a room we own to every node with members in it, and to the origin
+func (n *Node) fanOut(frame *clusterpb.RoomFrame) {
+	cutoff := time.Now().Add(-n.config.ClusterNodeTimeout)
+
+	n.mutex.RLock()
+	var targets []string
+	includesOrigin := false
+	for nodeID, refreshed := range n.interest[frame.GetRoom()] {
+		m, alive := n.members[nodeID]
+		if !alive || nodeID == n.id || refreshed.Before(cutoff) {
+			continue
+		}
+		if nodeID == frame.GetOrigin() {
+			includesOrigin = true
+		}
+		targets = append(targets, m.addr)
+	}
+	// node ต้นทางอาจยังแจ้ง interest มาไม่ถึง แต่ผู้ส่งอยู่ที่นั่นแน่นอน
+	if origin, alive := n.members[frame.GetOrigin()]; alive && !includesOrigin && frame.GetOrigin() != n.id {
+		targets = append(targets, origin.addr)
+	}
+	n.mutex.RUnlock()
+
+	n.deliver(frame.GetRoom(), frame.GetPayload(), frame.GetExcludeId())
+	for _, addr := range targets {
+		n.peer(addr).enqueue(sendDeliver, frame)
+	}
+}
+
+// fanOutAll delivers a frame locally and to every other live node except skipID.
+// ใช้กับข้อความถึงทุกคน และเป็นทางสำรองเมื่อติดต่อเจ้าของห้องไม่ได้
+func (n *Node) fanOutAll(frame *clusterpb.RoomFrame, skipID string) {
+	n.mutex.RLock()
+	var targets []string
+	for id, m := range n.members {
+		if id != n.id && id != skipID {
+			targets = append(targets, m.addr)
+		}
+	}
+	n.mutex.RUnlock()
+
+	n.deliver(frame.GetRoom(), frame.GetPayload(), frame.GetExcludeId())
+	for _, addr := range targets {
+		n.peer(addr).enqueue(sendDeliver, frame)
+	}
+}
+
+// addInterest records that nodeID has members in rooms
+func (n *Node) addInterest(nodeID string, rooms []string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	now := time.Now()
+	for _, roomName := range rooms {
+		nodes, exists := n.interest[roomName]
+		if !exists {
+			nodes = make(map[string]time.Time)
+			n.interest[roomName] = nodes
+		}
+		nodes[nodeID] = now
+	}
+}
+
+// syncInterest tells each owner which of its rooms have members here, and forgets interest
+// that was not refreshed in time (members left or the room moved to another owner)
+func (n *Node) syncInterest() {
+	byOwner := make(map[string][]string)
+
+	n.mutex.RLock()
+	for _, roomName := range n.localRooms() {
+		owner := n.ring.Owner(roomName)
+		byOwner[n.members[owner].addr] = append(byOwner[n.members[owner].addr], roomName)
+	}
+	n.mutex.RUnlock()
+
+	for addr, rooms := range byOwner {
+		if addr == n.addr {
+			n.addInterest(n.id, rooms)
+			continue
+		}
+		n.sendInterest(addr, rooms)
+	}
+
+	cutoff := time.Now().Add(-n.config.ClusterNodeTimeout)
+	n.mutex.Lock()
+	for roomName, nodes := range n.interest {
+		for nodeID, refreshed := range nodes {
+			if refreshed.Before(cutoff) {
+				delete(nodes, nodeID)
+			}
+		}
+		if len(nodes) == 0 || n.ring.Owner(roomName) != n.id {
+			delete(n.interest, roomName)
+		}
+	}
+	n.mutex.Unlock()
+}
+
+// sendInterest sends a SyncInterest call to the node at addr
+func (n *Node) sendInterest(addr string, rooms []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.ClusterGossipInterval)
+	defer cancel()
+
+	if _, err := n.peer(addr).client.SyncInterest(ctx, &clusterpb.InterestRequest{NodeId: n.id, Rooms: rooms}); err != nil {
+		slog.Warn("⚠️ Failed to register rooms with owner", "owner", addr, "rooms", len(rooms), "error", err)
+	}
+}
+
+// rebuildRingLocked rebuilds the hash ring from the live members (assumes mutex is held)
+func (n *Node) rebuildRingLocked() {
+	ids := make([]string, 0, len(n.members))
+	for id := range n.members {
+		ids = append(ids, id)
+	}
+	n.ring = NewRing(ids, n.config.ClusterVirtualNodes)
+
+	if len(ids) > 1 {
+		slog.Info("🔀 Cluster ring rebuilt", "nodes", n.ring.Nodes())
+		// แจ้งห้องของเราให้เจ้าของคนใหม่ทันที ไม่รอรอบถัดไป
+		go n.syncInterest()
+	}
+}
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"realtime-chat/internal/config"
+)
+
+// cluster ที่ไม่มี cluster_secret ต้องไม่เริ่ม และ call ที่ไม่มี secret ที่ถูกต้องต้องถูกปฏิเสธ
+func TestClusterRequiresSecret(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	cfg.EnableCluster = true
+	cfg.ClusterAdvertiseAddr = "127.0.0.1:7070"
+	if _, err := New(cfg, func(string, []byte, string) {}, func() []string { return nil }); err == nil {
+		t.Fatal("cluster node started without cluster_secret")
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/cluster.Cluster/Deliver"}
+	cases := []struct {
+		secret string
+		sent   []string
+		ok     bool
+	}{
+		{"s3cret", []string{secretHeader, "s3cret"}, true},
+		{"s3cret", []string{secretHeader, "guess"}, false},
+		{"s3cret", nil, false},
+		{"", []string{secretHeader, ""}, false},
+	}
+	for _, tc := range cases {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(tc.sent...))
+		_, err := secretInterceptor(tc.secret)(ctx, nil, info, handler)
+		if (err == nil) != tc.ok {
+			t.Errorf("secret %q with %v: err = %v, want ok=%v", tc.secret, tc.sent, err, tc.ok)
+		}
+	}
+}
+
+// สอง node คนละ region: /api/regions เห็นทั้งสอง region พร้อม latency และ DM ถึงผู้ใช้บน node อื่นผ่าน cluster
+func TestRegionsAndUserRouting(t *testing.T) {
+	newNode := func(id, region string) *Node {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		addr := listener.Addr().String()
+		listener.Close()
+
+		cfg := config.DefaultServerConfig()
+		cfg.EnableCluster = true
+		cfg.NodeID = id
+		cfg.Region = region
+		cfg.PublicURL = "wss://" + region + ".chat.example.com/ws"
+		cfg.ClusterAddr = addr
+		cfg.ClusterAdvertiseAddr = addr
+		cfg.ClusterSecret = "s3cret"
+		cfg.ClusterGossipInterval = 50 * time.Millisecond
+		cfg.ClusterNodeTimeout = time.Second
+		node, err := New(cfg, func(string, []byte, string) {}, func() []string { return nil })
+		if err != nil {
+			t.Fatalf("new node: %v", err)
+		}
+		return node
+	}
+
+	eu := newNode("eu-1", "eu-west")
+	us := newNode("us-1", "us-east")
+	us.seeds = []string{eu.addr}
+	received := make(chan string, 1)
+	us.SetUserDeliverer(func(username string, payload []byte) bool {
+		if username != "bobby" {
+			return false
+		}
+		received <- string(payload)
+		return true
+	})
+	for _, node := range []*Node{eu, us} {
+		if err := node.Start(); err != nil {
+			t.Fatalf("start %s: %v", node.ID(), err)
+		}
+		t.Cleanup(node.Stop)
+	}
+
+	// รอจน eu เห็น us และวัด RTT ไปหาได้แล้ว
+	deadline := time.Now().Add(5 * time.Second)
+	var regions []RegionStatus
+	for time.Now().Before(deadline) {
+		regions = eu.Regions()
+		if len(regions) == 2 && regions[1].LatencyMS != nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("regions = %+v, want eu-west and us-east", regions)
+	}
+	local, remote := regions[0], regions[1]
+	if local.Region != "eu-west" || !local.Local || local.LatencyMS == nil || *local.LatencyMS != 0 {
+		t.Errorf("local region = %+v, want eu-west first with latency 0", local)
+	}
+	if remote.Region != "us-east" || remote.Local || remote.Status != RegionUp || remote.Healthy != 1 || remote.LatencyMS == nil {
+		t.Errorf("remote region = %+v, want healthy us-east with a measured latency", remote)
+	}
+	if len(remote.Endpoints) != 1 || remote.Endpoints[0] != "wss://us-east.chat.example.com/ws" {
+		t.Errorf("us-east endpoints = %v", remote.Endpoints)
+	}
+
+	if !eu.SendToUser("bobby", []byte(`{"type":"direct_message"}`)) {
+		t.Fatal("direct message to a user on the other node was not delivered")
+	}
+	if got := <-received; got != `{"type":"direct_message"}` {
+		t.Errorf("delivered payload = %s", got)
+	}
+	if eu.SendToUser("nobody", []byte(`{}`)) {
+		t.Error("SendToUser reported delivery for a user on no node")
+	}
+}
@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"realtime-chat/internal/cluster/clusterpb"
+)
+
+// peerQueueSize bounds the frames waiting for one node; when full, new frames are dropped
+const peerQueueSize = 4096
+
+// sendKind is the RPC used for a queued frame
+type sendKind int
+
+const (
+	sendPublish sendKind = iota // ส่งให้เจ้าของห้องกระจายต่อ
+	sendDeliver                 // ส่งให้ node ปลายทางส่งถึง connection ของตัวเอง
+)
+
+type outbound struct {
+	kind  sendKind
+	frame *clusterpb.RoomFrame
+}
+
+// peer is the gRPC client of another node. Frames go through one queue per peer so a room's
+// frames arrive in the order they were sent.
+type peer struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client clusterpb.ClusterClient
+	queue  chan outbound
+	done   chan struct{}
+}
+
+// peer returns the client for addr, connecting lazily
+func (n *Node) peer(addr string) *peer {
+	n.mutex.RLock()
+	p, exists := n.peers[addr]
+	n.mutex.RUnlock()
+	if exists {
+		return p
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if p, exists := n.peers[addr]; exists {
+		return p
+	}
+
+	// grpc.NewClient ไม่ได้ต่อทันที จึงไม่ error แม้ node ปลายทางยังไม่ขึ้น
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(n.clientCreds),
+		grpc.WithUnaryInterceptor(secretClientInterceptor(n.config.ClusterSecret)))
+	if err != nil {
+		slog.Error("❌ Invalid cluster peer address", "addr", addr, "error", err)
+	}
+	p = &peer{
+		addr:  addr,
+		conn:  conn,
+		queue: make(chan outbound, peerQueueSize),
+		done:  make(chan struct{}),
+	}
+	if conn != nil {
+		p.client = clusterpb.NewClusterClient(conn)
+	} else {
+		p.client = unreachableClient{}
+	}
+	n.peers[addr] = p
+	go n.sendLoop(p)
+	return p
+}
+
+// closePeerLocked stops a peer's queue and closes its connection (assumes mutex is held)
+func (n *Node) closePeerLocked(addr string) {
+	p, exists := n.peers[addr]
+	if !exists {
+		return
+	}
+	delete(n.peers, addr)
+	close(p.done)
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// enqueue queues a frame for the peer, dropping it when the queue is full
+func (p *peer) enqueue(kind sendKind, frame *clusterpb.RoomFrame) {
+	select {
+	case p.queue <- outbound{kind: kind, frame: frame}:
+	case <-p.done:
+	default:
+		slog.Warn("⚠️ Cluster queue is full, dropping frame", "peer", p.addr, "room", frame.GetRoom())
+	}
+}
+
+// sendLoop sends a peer's queued frames one at a time
+func (n *Node) sendLoop(p *peer) {
+	for {
+		select {
+		case <-p.done:
+			return
+		case item := <-p.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), n.config.ClusterNodeTimeout)
+			var err error
+			if item.kind == sendPublish {
+				_, err = p.client.Publish(ctx, item.frame)
+			} else {
+				_, err = p.client.Deliver(ctx, item.frame)
+			}
+			cancel()
+
+			if err == nil {
+				continue
+			}
+			if item.kind == sendPublish {
+				// เจ้าของห้องไม่ตอบ ส่งตรงทุก node แทน ดีกว่าข้อความหาย (ลำดับอาจไม่ตรงกันชั่วคราว)
+				slog.Warn("⚠️ Room owner unreachable, delivering directly", "owner", p.addr, "room", item.frame.GetRoom(), "error", err)
+				n.fanOutAll(item.frame, n.memberAt(p.addr))
+				continue
+			}
+			slog.Warn("⚠️ Failed to deliver room frame", "peer", p.addr, "room", item.frame.GetRoom(), "error", err)
+		}
+	}
+}
+
+// memberAt returns the ID of the member at addr ("" when unknown)
+func (n *Node) memberAt(addr string) string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	for id, m := range n.members {
+		if m.addr == addr {
+			return id
+		}
+	}
+	return ""
+}
+
+// secretClientInterceptor attaches the cluster secret to every outgoing call
+func secretClientInterceptor(secret string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if secret != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, secretHeader, secret)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// unreachableClient stands in for a peer whose address could not be parsed
+type unreachableClient struct{}
+
+func (unreachableClient) Gossip(context.Context, *clusterpb.GossipRequest, ...grpc.CallOption) (*clusterpb.GossipResponse, error) {
+	return nil, errUnreachable
+}
+
+func (unreachableClient) Publish(context.Context, *clusterpb.RoomFrame, ...grpc.CallOption) (*clusterpb.Ack, error) {
+	return nil, errUnreachable
+}
+
+func (unreachableClient) Deliver(context.Context, *clusterpb.RoomFrame, ...grpc.CallOption) (*clusterpb.Ack, error) {
+	return nil, errUnreachable
+}
+
+func (unreachableClient) SyncInterest(context.Context, *clusterpb.InterestRequest, ...grpc.CallOption) (*clusterpb.Ack, error) {
+	return nil, errUnreachable
+}
+
+func (unreachableClient) DeliverUser(context.Context, *clusterpb.UserFrame, ...grpc.CallOption) (*clusterpb.DeliverResult, error) {
+	return nil, errUnreachable
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"realtime-chat/internal/cluster/clusterpb"
+	"realtime-chat/internal/config"
+)
+
+// defaultRegion groups nodes that have no region configured
+const defaultRegion = "default"
+
+// Region health values
+const (
+	RegionUp       = "up"
+	RegionDegraded = "degraded" // บาง node ใน region เงียบไป
+	RegionDown     = "down"
+)
+
+// RegionStatus is the health of one region as seen from the node that answers.
+// client ใช้เลือก endpoint ที่ใกล้ที่สุด: latency คือ round-trip ของ gossip จาก node นี้ ไม่ใช่จาก client
+// จึงควรวัด RTT ไปยัง endpoints เองเมื่อเลือกครั้งแรก
+type RegionStatus struct {
+	Region    string   `json:"region"`
+	Local     bool     `json:"local"` // region ของ node ที่ตอบ
+	Status    string   `json:"status"`
+	Nodes     int      `json:"nodes"`
+	Healthy   int      `json:"healthy"`
+	LatencyMS *float64 `json:"latency_ms"` // RTT ไป node ที่ใกล้ที่สุดใน region (null = ยังไม่ได้วัด)
+	Endpoints []string `json:"endpoints"`
+}
+
+// UserDeliverer delivers a frame to a user's connections on this node; false when the user is not here
+type UserDeliverer func(username string, payload []byte) bool
+
+// SetUserDeliverer sets how frames routed to a user (direct messages) reach local connections
+func (n *Node) SetUserDeliverer(deliver UserDeliverer) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.deliverUser = deliver
+}
+
+// deliverLocalUser passes a user frame to the local deliverer
+func (n *Node) deliverLocalUser(username string, payload []byte) bool {
+	n.mutex.RLock()
+	deliver := n.deliverUser
+	n.mutex.RUnlock()
+	return deliver != nil && deliver(username, payload)
+}
+
+// SendToUser delivers a frame to a user connected to another node, in any region.
+// ไม่รู้ว่าผู้ใช้อยู่ node ไหน จึงถามทุก node พร้อมกันและตอบทันทีที่มี node หนึ่งส่งถึง;
+// false = ไม่มี node ไหนมีผู้ใช้คนนี้ (ผู้เรียกเก็บลง mailbox แทนได้)
+func (n *Node) SendToUser(username string, payload []byte) bool {
+	n.mutex.RLock()
+	var targets []string
+	for id, m := range n.members {
+		if id != n.id {
+			targets = append(targets, m.addr)
+		}
+	}
+	n.mutex.RUnlock()
+	if len(targets) == 0 {
+		return false
+	}
+
+	frame := &clusterpb.UserFrame{Username: username, Payload: payload, Origin: n.id}
+	results := make(chan bool, len(targets))
+	for _, addr := range targets {
+		go func(p *peer) {
+			ctx, cancel := context.WithTimeout(context.Background(), n.config.ClusterNodeTimeout)
+			defer cancel()
+			resp, err := p.client.DeliverUser(ctx, frame)
+			if err != nil {
+				slog.Warn("⚠️ Failed to route frame to user", "peer", p.addr, "username", username, "error", err)
+			}
+			results <- err == nil && resp.GetDelivered()
+		}(n.peer(addr))
+	}
+	for range targets {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
+
+// Regions returns the health of every region in the cluster, this node's region first
+func (n *Node) Regions() []RegionStatus {
+	healthyAfter := time.Now().Add(-3 * n.config.ClusterGossipInterval)
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	local := regionName(n.members[n.id].region)
+	byRegion := make(map[string]*RegionStatus)
+	for _, m := range n.members {
+		name := regionName(m.region)
+		status, exists := byRegion[name]
+		if !exists {
+			status = &RegionStatus{Region: name, Local: name == local, Endpoints: []string{}}
+			byRegion[name] = status
+		}
+		status.Nodes++
+		if m.endpoint != "" {
+			status.Endpoints = append(status.Endpoints, m.endpoint)
+		}
+
+		self := m.id == n.id
+		if !self && m.lastSeen.Before(healthyAfter) {
+			continue
+		}
+		status.Healthy++
+		// region ของเราเองวัดเป็น 0 เพราะตอบจาก node นี้
+		latency := milliseconds(m.rtt)
+		if !self && m.rtt == 0 {
+			continue
+		}
+		if status.LatencyMS == nil || latency < *status.LatencyMS {
+			status.LatencyMS = &latency
+		}
+	}
+
+	regions := make([]RegionStatus, 0, len(byRegion))
+	for _, status := range byRegion {
+		switch {
+		case status.Healthy == 0:
+			status.Status = RegionDown
+		case status.Healthy < status.Nodes:
+			status.Status = RegionDegraded
+		default:
+			status.Status = RegionUp
+		}
+		sort.Strings(status.Endpoints)
+		regions = append(regions, *status)
+	}
+	sortRegions(regions)
+	return regions
+}
+
+// Standalone reports the single region of a server that runs without cluster mode
+type Standalone struct {
+	region   string
+	endpoint string
+}
+
+// NewStandalone creates the region report of a single server
+func NewStandalone(cfg *config.ServerConfig) *Standalone {
+	return &Standalone{region: cfg.Region, endpoint: cfg.PublicURL}
+}
+
+// Regions returns this server's region, always up
+func (s *Standalone) Regions() []RegionStatus {
+	latency := 0.0
+	endpoints := []string{}
+	if s.endpoint != "" {
+		endpoints = append(endpoints, s.endpoint)
+	}
+	return []RegionStatus{{
+		Region:    regionName(s.region),
+		Local:     true,
+		Status:    RegionUp,
+		Nodes:     1,
+		Healthy:   1,
+		LatencyMS: &latency,
+		Endpoints: endpoints,
+	}}
+}
+
+// regionName maps an unset region to defaultRegion
+func regionName(region string) string {
+	if region == "" {
+		return defaultRegion
+	}
+	return region
+}
+
+// sortRegions puts the local region first, then the rest by name
+func sortRegions(regions []RegionStatus) {
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Local != regions[j].Local {
+			return regions[i].Local
+		}
+		return regions[i].Region < regions[j].Region
+	})
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// Ring maps room names to nodes with consistent hashing.
+// แต่ละ node มีหลายจุด (virtual nodes) บน ring เพื่อให้ห้องกระจายสม่ำเสมอ
+// และเมื่อ node เข้า/ออก ห้องที่ย้ายเจ้าของมีเพียงส่วนของ node นั้น
+type Ring struct {
+	points []uint64          // เรียงจากน้อยไปมาก
+	owners map[uint64]string // point -> node ID
+	nodes  []string
+}
+
+// NewRing builds a ring with virtualNodes points per node
+func NewRing(nodeIDs []string, virtualNodes int) *Ring {
+	if virtualNodes < 1 {
+		virtualNodes = 1
+	}
+
+	ring := &Ring{
+		owners: make(map[uint64]string, len(nodeIDs)*virtualNodes),
+		nodes:  append([]string(nil), nodeIDs...),
+	}
+	sort.Strings(ring.nodes)
+
+	for _, nodeID := range ring.nodes {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(nodeID + "#" + strconv.Itoa(i))
+			// ชนกันแทบไม่เกิด แต่ถ้าเกิดให้ node ที่ชื่อน้อยกว่าได้ไป ผลจะเหมือนกันทุก node
+			if _, taken := ring.owners[point]; taken {
+				continue
+			}
+			ring.owners[point] = nodeID
+			ring.points = append(ring.points, point)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// Owner returns the node that owns a room ("" when the ring is empty)
+func (r *Ring) Owner(roomName string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	key := hashKey(roomName)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= key })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Nodes returns the node IDs on the ring, sorted
+func (r *Ring) Nodes() []string {
+	return r.nodes
+}
+
+// hashKey places a key on the ring.
+// ใช้ SHA-256 แทน FNV เพราะชื่อ virtual node ต่างกันแค่ตัวท้าย FNV จะกระจายได้ไม่ดี
+func hashKey(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
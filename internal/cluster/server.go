@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"realtime-chat/internal/cluster/clusterpb"
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/security"
+)
+
+// secretHeader is the gRPC metadata key carrying cluster_secret
+const secretHeader = "x-cluster-secret"
+
+// errUnreachable is returned for peers whose address cannot be dialed
+var errUnreachable = errors.New("cluster peer address is invalid")
+
+// server implements clusterpb.ClusterServer for the other nodes
+type server struct {
+	clusterpb.UnimplementedClusterServer
+	node *Node
+}
+
+// Gossip merges the caller's member list and answers with ours
+func (s *server) Gossip(ctx context.Context, req *clusterpb.GossipRequest) (*clusterpb.GossipResponse, error) {
+	s.node.merge(req.GetMembers())
+
+	s.node.mutex.RLock()
+	defer s.node.mutex.RUnlock()
+	return &clusterpb.GossipResponse{Members: s.node.viewLocked(), From: s.node.id}, nil
+}
+
+// Publish fans out a broadcast for a room this node owns.
+// ถ้า ring ของผู้ส่งยังไม่ตรงกับเรา (ช่วง rebalance) ก็กระจายให้อยู่ดี ข้อความไม่หาย
+func (s *server) Publish(ctx context.Context, frame *clusterpb.RoomFrame) (*clusterpb.Ack, error) {
+	s.node.fanOut(frame)
+	return &clusterpb.Ack{}, nil
+}
+
+// Deliver sends a broadcast to this node's connections
+func (s *server) Deliver(ctx context.Context, frame *clusterpb.RoomFrame) (*clusterpb.Ack, error) {
+	s.node.deliver(frame.GetRoom(), frame.GetPayload(), frame.GetExcludeId())
+	return &clusterpb.Ack{}, nil
+}
+
+// SyncInterest records the rooms that have members on the calling node
+func (s *server) SyncInterest(ctx context.Context, req *clusterpb.InterestRequest) (*clusterpb.Ack, error) {
+	if req.GetNodeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "node_id is required")
+	}
+	s.node.addInterest(req.GetNodeId(), req.GetRooms())
+	return &clusterpb.Ack{}, nil
+}
+
+// DeliverUser sends a frame to the user's connections on this node
+func (s *server) DeliverUser(ctx context.Context, frame *clusterpb.UserFrame) (*clusterpb.DeliverResult, error) {
+	if frame.GetUsername() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+	return &clusterpb.DeliverResult{Delivered: s.node.deliverLocalUser(frame.GetUsername(), frame.GetPayload())}, nil
+}
+
+// secretInterceptor rejects calls that do not carry cluster_secret
+func secretInterceptor(secret string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(secretHeader)
+		if secret == "" || len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(secret)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid cluster secret")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// transportCredentials returns the credentials of the cluster listener and of calls to peers.
+// ถ้า server ตั้ง tls_* ไว้ node คุยกันผ่าน TLS ด้วย certificate เดียวกับ HTTPS (peer ถูกตรวจกับ system roots
+// ตามชื่อใน cluster_peers/cluster_advertise_addr) ไม่อย่างนั้นเป็น plaintext ที่ยังต้องมี cluster_secret
+func transportCredentials(cfg *config.ServerConfig) (credentials.TransportCredentials, credentials.TransportCredentials, error) {
+	tlsSetup, err := security.NewTLSSetup(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tlsSetup == nil {
+		return insecure.NewCredentials(), insecure.NewCredentials(), nil
+	}
+	listenerConfig, err := tlsSetup.ListenerConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials.NewTLS(listenerConfig), credentials.NewTLS(&tls.Config{MinVersion: listenerConfig.MinVersion}), nil
+}
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"strings"
+)
+
+// Argument types used by clients to pick an autocomplete source
+const (
+	ArgString    = "string"
+	ArgText      = "text" // ข้อความยาวจนจบบรรทัด
+	ArgRoom      = "room"
+	ArgUser      = "user"
+	ArgMessageID = "message_id"
+	ArgDuration  = "duration" // Go duration เช่น 30m, 72h
+	ArgNumber    = "number"
+	ArgEmoji     = "emoji"
+	ArgPassword  = "password"
+	ArgIP        = "ip"
+	ArgChoice    = "choice" // ค่าได้เฉพาะใน Choices
+	ArgFlag      = "flag"   // --flag ที่ไม่มีค่า
+)
+
+// Arg describes one argument of a slash command
+type Arg struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Flag     string   `json:"flag,omitempty"`     // เช่น --max; ว่าง = positional
+	Choices  []string `json:"choices,omitempty"`  // ค่าคงที่ที่ใช้ได้ (เช่น on/off หรือ default)
+	Variadic bool     `json:"variadic,omitempty"` // รับได้หลายค่า
+}
+
+// Info is the machine-readable description of a slash command used for autocomplete
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Usage       string `json:"usage"`
+	Args        []Arg  `json:"args"`
+	Role        string `json:"role"`               // role ต่ำสุดที่ใช้ได้: guest, member, moderator, owner หรือ admin
+	Requires    string `json:"requires,omitempty"` // capability ที่ server ต้องมี
+}
+
+// ParseUsage derives argument metadata from a usage string such as
+// "/create <room_name> [--max <users>] [--private]" so commands don't have to declare it twice
+func ParseUsage(usage string) []Arg {
+	tokens := splitTopLevel(usage, ' ')
+	args := make([]Arg, 0, len(tokens))
+	for i, token := range tokens {
+		if i == 0 && strings.HasPrefix(token, "/") {
+			continue // ชื่อคำสั่ง
+		}
+		if arg, ok := parseToken(token); ok {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// parseToken parses one top-level usage token: <arg>, [optional], --flag or a|b choices
+func parseToken(token string) (Arg, bool) {
+	required := true
+	if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
+		required = false
+		token = strings.TrimSpace(token[1 : len(token)-1])
+	}
+	if token == "" {
+		return Arg{}, false
+	}
+
+	// --flag [<value>]
+	if strings.HasPrefix(token, "--") {
+		parts := strings.SplitN(token, " ", 2)
+		arg := Arg{Name: strings.TrimPrefix(parts[0], "--"), Flag: parts[0], Required: required, Type: ArgFlag}
+		if len(parts) == 2 {
+			arg.Type = parseAlternatives(parts[1]).Type
+		}
+		return arg, true
+	}
+
+	arg := parseAlternatives(token)
+	arg.Required = required
+	return arg, true
+}
+
+// parseAlternatives parses "<duration>|default", "on|off", "limit" or "<room> <duration>" into one argument;
+// only the first word of each alternative is described
+func parseAlternatives(token string) Arg {
+	var arg Arg
+	alternatives := splitTopLevel(token, '|')
+	if len(alternatives) == 1 && !strings.ContainsAny(token, "<[ ") {
+		// คำเดี่ยวเช่น [limit] หรือ [password] คือชื่อ argument ไม่ใช่ค่าคงที่
+		return Arg{Name: token, Type: argType(token)}
+	}
+	for _, alternative := range alternatives {
+		words := splitTopLevel(alternative, ' ')
+		if len(words) == 0 {
+			continue
+		}
+		word := words[0]
+		if strings.HasPrefix(word, "<") && strings.HasSuffix(word, ">") {
+			if arg.Name == "" {
+				name := strings.TrimSuffix(strings.TrimSpace(word[1:len(word)-1]), "...")
+				if cut := strings.IndexAny(name, ",("); cut > 0 {
+					name = strings.TrimSpace(name[:cut]) // "<IANA zone, e.g. Asia/Bangkok>"
+				}
+				arg.Name = name
+				arg.Type = argType(name)
+				arg.Variadic = strings.HasSuffix(word, "...>")
+			}
+			continue
+		}
+		if strings.HasSuffix(word, "...") {
+			if arg.Name == "" {
+				arg.Name = strings.TrimSuffix(word, "...")
+				arg.Type = argType(arg.Name)
+				arg.Variadic = true
+			}
+			continue
+		}
+		if !strings.HasPrefix(word, "[") && !strings.HasPrefix(word, "--") {
+			arg.Choices = append(arg.Choices, word)
+		}
+	}
+
+	if arg.Name == "" {
+		arg.Name = "option"
+		arg.Type = ArgChoice
+	}
+	return arg
+}
+
+// argType guesses an argument type from its usage name
+func argType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "message_id":
+		return ArgMessageID
+	case strings.Contains(name, "room") || name == "source" || name == "dest":
+		return ArgRoom
+	case name == "user" || name == "username":
+		return ArgUser
+	case name == "duration" || name == "ttl":
+		return ArgDuration
+	case name == "limit" || name == "n" || name == "users":
+		return ArgNumber
+	case name == "emoji":
+		return ArgEmoji
+	case name == "password":
+		return ArgPassword
+	case name == "ip":
+		return ArgIP
+	case name == "text" || name == "message" || name == "query" || strings.HasSuffix(name, " text"):
+		return ArgText
+	}
+	return ArgString
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside <...> and [...]
+func splitTopLevel(s string, sep rune) []string {
+	var parts []string
+	var current strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '<', '[':
+			depth++
+		case '>', ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		if r == sep && depth == 0 {
+			if part := strings.TrimSpace(current.String()); part != "" {
+				parts = append(parts, part)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if part := strings.TrimSpace(current.String()); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
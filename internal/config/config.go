@@ -1,10 +1,13 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -110,16 +113,32 @@ type ServerConfig struct {
 	MaxConnections      int           `json:"max_connections"`
 	MaxRooms            int           `json:"max_rooms"`
 	MaxUsersPerRoom     int           `json:"max_users_per_room"`
+	MaxRoomCapacity     int           `json:"max_room_capacity"`
 	HeartbeatInterval   time.Duration `json:"heartbeat_interval"`
 	ReadTimeout         time.Duration `json:"read_timeout"`
 	WriteTimeout        time.Duration `json:"write_timeout"`
 	PongTimeout         time.Duration `json:"pong_timeout"`
+	EnableAppHeartbeat  bool          `json:"enable_app_heartbeat"` // ให้ client ใช้ข้อความ "hb" แทน ping ได้ (proxy ที่ตัด WS ping ทิ้ง)
 	ConnectionTimeout   time.Duration `json:"connection_timeout"`
 	BroadcastBuffer     int           `json:"broadcast_buffer"`
+	MaxOutboundFrameSize int          `json:"max_outbound_frame_size"` // bytes, 0 = ไม่จำกัด
+	SendQueueSize       int           `json:"send_queue_size"`         // outbound frame ที่รอเขียนได้ต่อ connection
+	SlowConsumerPolicy  string        `json:"slow_consumer_policy"`    // drop_oldest, drop_newest หรือ disconnect เมื่อคิวเต็ม
+	SlowConsumerMaxDrops int          `json:"slow_consumer_max_drops"` // drop ติดกันก่อนปิด connection, 0 = ไม่ปิด
+	FanoutWorkers       int           `json:"fanout_workers"`          // worker ที่แบ่ง connection กันส่ง broadcast ขนาดใหญ่, 0 หรือ 1 = ส่งทีละ connection
+	FanoutMinRecipients int           `json:"fanout_min_recipients"`   // broadcast ที่มีผู้รับน้อยกว่านี้ส่งใน loop เดียว (แบ่งงานไม่คุ้ม)
+	EnableBatching      bool          `json:"enable_batching"`         // client ที่ขอ capability "batch" ได้รับ frame ที่ถี่รวมเป็น JSON array
+	BatchWindow         time.Duration `json:"batch_window"`            // เวลาที่รอ frame ถัดไปหลัง frame แรกของ batch
+	BatchMaxSize        int           `json:"batch_max_size"`          // frame สูงสุดต่อ batch
+	EnableCompression   bool          `json:"enable_compression"`      // ตกลง permessage-deflate กับ client ที่เสนอมา
+	CompressionThreshold int          `json:"compression_threshold"`   // bytes, frame ที่เล็กกว่านี้ส่งโดยไม่บีบอัด
+	CompressionLevel    int           `json:"compression_level"`       // flate level -2..9 (1 = เร็วสุด)
 	EnableMetrics       bool          `json:"enable_metrics"`
 	EnableHealthCheck   bool          `json:"enable_health_check"`
 	HealthCheckInterval time.Duration `json:"health_check_interval"`
 	Port                string        `json:"port"`
+	LogLevel            string        `json:"log_level"`  // debug, info, warn หรือ error
+	LogFormat           string        `json:"log_format"` // text หรือ json
 	
 	// Security settings
 	MaxMessageLength    int           `json:"max_message_length"`
@@ -128,8 +147,23 @@ type ServerConfig struct {
 	RateLimitMessages   int           `json:"rate_limit_messages"`
 	RateLimitWindow     time.Duration `json:"rate_limit_window"`
 	EnableRateLimit     bool          `json:"enable_rate_limit"`
+	RateLimitBurst      int           `json:"rate_limit_burst"`        // ขนาด bucket: ส่งติดกันได้สูงสุดเท่านี้
+	RateLimitIdleTTL    time.Duration `json:"rate_limit_idle_ttl"`     // ลบ bucket ของผู้ใช้ที่ไม่ส่งข้อความนานเกินนี้
+	RateLimitWarnRemaining int        `json:"rate_limit_warn_remaining"` // ส่ง rate_limit_status เมื่อเหลือ token เท่านี้ (0 = ไม่แจ้ง)
+	MaxMentionsPerMessage int         `json:"max_mentions_per_message"`
+	EnableGuestNames    bool          `json:"enable_guest_names"`
+	GuestNameLocale     string        `json:"guest_name_locale"`
+	UsernamePattern     string        `json:"username_pattern"`       // regex ที่ username ทั้งชื่อต้อง match
+	RoomNamePattern     string        `json:"room_name_pattern"`      // regex ที่ชื่อห้องทั้งชื่อต้อง match
+	ReservedNames       []string      `json:"reserved_names"`         // username ที่ห้ามใช้ (ไม่สนตัวพิมพ์) ไม่ยกเว้น admin_usernames
+	UnicodeNormalization string       `json:"unicode_normalization"`  // nfc, nfkc หรือ none
+	StripInvisibleChars bool          `json:"strip_invisible_chars"`  // ลบ zero-width, bidi override และ control characters
 	
 	// Database settings
+	StorageBackend      string        `json:"storage_backend"`    // memory, mongodb, postgres หรือ sqlite (ว่าง = ตาม enable_mongodb)
+	PostgresDSN         string        `json:"postgres_dsn"`
+	PostgresMaxConns    int           `json:"postgres_max_conns"`
+	SQLitePath          string        `json:"sqlite_path"`        // ไฟล์ database เมื่อ storage_backend = sqlite
 	EnableMongoDB       bool          `json:"enable_mongodb"`
 	MongoURI            string        `json:"mongo_uri"`
 	MongoDatabase       string        `json:"mongo_database"`
@@ -137,24 +171,290 @@ type ServerConfig struct {
 	MongoPingTimeout    time.Duration `json:"mongo_ping_timeout"`
 	MongoMaxPoolSize    uint64        `json:"mongo_max_pool_size"`
 	MongoMinPoolSize    uint64        `json:"mongo_min_pool_size"`
+	
+	// Outage handling settings
+	MongoBreakerThreshold    int           `json:"mongo_breaker_threshold"`
+	MongoBreakerCooldown     time.Duration `json:"mongo_breaker_cooldown"`
+	MongoHealthCheckInterval time.Duration `json:"mongo_health_check_interval"`
+	MessageBufferSize        int           `json:"message_buffer_size"`
+	MongoSlowThreshold       time.Duration `json:"mongo_slow_threshold"`
+	
+	// Write-ahead journal settings
+	JournalDir               string        `json:"journal_dir"`
+	JournalSegmentSize       int64         `json:"journal_segment_size"`
+	JournalReconcileInterval time.Duration `json:"journal_reconcile_interval"`
+	
+	// Search index settings
+	EnableSearchIndex        bool          `json:"enable_search_index"`
+	ElasticsearchURL         string        `json:"elasticsearch_url"`
+	ElasticsearchIndex       string        `json:"elasticsearch_index"`
+	SearchIndexQueueSize     int           `json:"search_index_queue_size"`
+	
+	// Autocomplete settings
+	SuggestDebounce          time.Duration `json:"suggest_debounce"`
+	TypingDebounce           time.Duration `json:"typing_debounce"`
+	PresenceAwayAfter        time.Duration `json:"presence_away_after"`
+	PresenceSweepInterval    time.Duration `json:"presence_sweep_interval"`
+	
+	// Stale user cleanup settings (MongoDB mode)
+	UserCleanupMaxAttempts   int           `json:"user_cleanup_max_attempts"`
+	UserCleanupBackoff       time.Duration `json:"user_cleanup_backoff"`
+	UserSweepInterval        time.Duration `json:"user_sweep_interval"`
+	NodeHeartbeatTimeout     time.Duration `json:"node_heartbeat_timeout"`
+	SuggestMaxResults        int           `json:"suggest_max_results"`
+	
+	// Member list settings
+	UsersPageSize            int           `json:"users_page_size"`
+	UsersPageMaxSize         int           `json:"users_page_max_size"`
+	MemberDeltaInterval      time.Duration `json:"member_delta_interval"`
+	
+	// Room hibernation settings (in-memory mode only)
+	EnableRoomHibernation    bool          `json:"enable_room_hibernation"`
+	RoomHibernationDir       string        `json:"room_hibernation_dir"`
+	RoomIdleTimeout          time.Duration `json:"room_idle_timeout"`
+	RoomHibernationInterval  time.Duration `json:"room_hibernation_interval"`
+	
+	// Reconnect guidance settings
+	ReconnectMinBackoff      time.Duration `json:"reconnect_min_backoff"`
+	ReconnectMaxBackoff      time.Duration `json:"reconnect_max_backoff"`
+	ReconnectJitter          float64       `json:"reconnect_jitter"`
+	ReconnectRatePerSecond   int           `json:"reconnect_rate_per_second"`
+	
+	// Connection throttling settings (per IP)
+	EnableConnectionThrottle bool          `json:"enable_connection_throttle"`
+	ConnectAttemptLimit      int           `json:"connect_attempt_limit"`
+	ConnectAttemptWindow     time.Duration `json:"connect_attempt_window"`
+	AuthFailureLimit         int           `json:"auth_failure_limit"`
+	ConnectBlockBase         time.Duration `json:"connect_block_base"`
+	ConnectBlockMax          time.Duration `json:"connect_block_max"`
+	MaxConnectionsPerIP      int           `json:"max_connections_per_ip"` // connection พร้อมกันต่อ IP, 0 = ไม่จำกัด
+	IPViolationLimit         int           `json:"ip_violation_limit"`     // ชน per-IP limit กี่ครั้งใน connect_attempt_window แล้ว block, 0 = ไม่ block
+	
+	// Room switch (join/leave churn) limiting settings (per user)
+	EnableChurnLimit         bool          `json:"enable_churn_limit"`
+	RoomSwitchLimit          int           `json:"room_switch_limit"`
+	RoomSwitchWindow         time.Duration `json:"room_switch_window"`
+	
+	// Sampled delivery settings (ห้องขนาดใหญ่มาก)
+	EnableRoomSampling       bool          `json:"enable_room_sampling"`
+	SamplingThreshold        int           `json:"sampling_threshold"`     // จำนวนสมาชิกที่เริ่มใช้ sampled delivery
+	SamplingActiveWindow     time.Duration `json:"sampling_active_window"` // โพสต์ภายในช่วงนี้ถือว่าเป็นผู้ร่วมสนทนา
+	DigestInterval           time.Duration `json:"digest_interval"`
+	MaxDigestMessages        int           `json:"max_digest_messages"`
+	
+	// Inbound frame limiting settings (per connection, ทุก type)
+	EnableFrameRateLimit     bool          `json:"enable_frame_rate_limit"`
+	FrameRateLimit           int           `json:"frame_rate_limit"`
+	ExpensiveFrameRateLimit  int           `json:"expensive_frame_rate_limit"`
+	FrameRateWindow          time.Duration `json:"frame_rate_window"`
+	
+	// Admin settings
+	AdminUsernames           []string      `json:"admin_usernames"` // ต้องมี account แล้ว และได้สิทธิ์เฉพาะตอน login ด้วย account นั้น
+	
+	// Integration API settings
+	APIKeys                  []string      `json:"api_keys"` // key สำหรับระบบภายนอก (เช่น calendar) ที่เรียก API
+	DefaultSubprotocol       string        `json:"default_subprotocol"` // ใช้กับ client ที่ไม่ส่ง Sec-WebSocket-Protocol
+	EnableMsgpack            bool          `json:"enable_msgpack"` // เปิด subprotocol chat.v1.msgpack (binary MessagePack envelope)
+	AllowedOrigins           []string      `json:"allowed_origins"` // origin ที่เปิด WebSocket ได้ รองรับ "*" และ "*.example.com"
+	TrustedProxies           []string      `json:"trusted_proxies"` // IP/CIDR ของ reverse proxy ที่เชื่อ X-Forwarded-For ได้ (ว่าง = ใช้ address ที่ต่อเข้ามาตรงๆ)
+	MaxPresenceDuration      time.Duration `json:"max_presence_duration"`
+	
+	// Honeypot settings (for public deployments)
+	EnableHoneypots          bool          `json:"enable_honeypots"`
+	HoneypotRooms            []string      `json:"honeypot_rooms"`
+	HoneypotCommands         []string      `json:"honeypot_commands"`
+	ModerationLogSize        int           `json:"moderation_log_size"`
+	
+	// Protocol violation quarantine settings (per connection)
+	EnableQuarantine         bool          `json:"enable_quarantine"`
+	ProtocolViolationLimit   int           `json:"protocol_violation_limit"`
+	QuarantineGrace          time.Duration `json:"quarantine_grace"` // เวลาที่เพิกเฉยต่อ input ก่อนปิด connection
+	
+	// Delivery SLO sampling settings
+	EnableDeliverySampling   bool          `json:"enable_delivery_sampling"`
+	DeliverySampleInterval   time.Duration `json:"delivery_sample_interval"`
+	DeliverySampleSize       int           `json:"delivery_sample_size"`
+	DeliveryProbeTimeout     time.Duration `json:"delivery_probe_timeout"`
+	DeliveryWindowSize       int           `json:"delivery_window_size"`
+	LoadTestMode             bool          `json:"load_test_mode"` // ส่ง probe_id ของข้อความต่อไปกับ broadcast ตอบ probe_echo และปิด connection throttle (cmd/loadgen)
+	
+	// Temporary room settings
+	MaxRoomTTL               time.Duration   `json:"max_room_ttl"`
+	RoomExpiryCheckInterval  time.Duration   `json:"room_expiry_check_interval"`
+	RoomExpiryWarnings       []time.Duration `json:"room_expiry_warnings"`
+	
+	// Room lifecycle settings
+	RoomArchiveAfter         time.Duration `json:"room_archive_after"`         // 0 = ไม่ archive ห้องที่ว่างอัตโนมัติ
+	RoomArchiveInterval      time.Duration `json:"room_archive_interval"`
+	ReactivateArchivedRooms  bool          `json:"reactivate_archived_rooms"`  // /create ชื่อห้องที่ถูก archive = ดึงห้องเดิมกลับมา
+	
+	// Room cloning settings
+	MaxCloneMessages         int           `json:"max_clone_messages"`
+	
+	// File transfer settings (relayed between users)
+	EnableFileTransfer       bool          `json:"enable_file_transfer"`
+	MaxFileSize              int64         `json:"max_file_size"`       // bytes
+	FileChunkSize            int           `json:"file_chunk_size"`     // bytes ต่อ chunk ก่อน base64
+	FileTransferWindow       int           `json:"file_transfer_window"` // chunk ที่ค้าง ack ได้พร้อมกัน
+	FileTransferIdleTimeout  time.Duration `json:"file_transfer_idle_timeout"`
+	
+	// Attachment upload settings (POST /api/upload, เก็บไฟล์ไว้ที่ server)
+	EnableUploads            bool          `json:"enable_uploads"`
+	MaxUploadSize            int64         `json:"max_upload_size"`      // bytes
+	AllowedUploadTypes       []string      `json:"allowed_upload_types"` // MIME type ที่ตรวจจากเนื้อไฟล์ รองรับ "image/*"
+	UploadStorage            string        `json:"upload_storage"`       // "local" หรือ "s3"
+	UploadDir                string        `json:"upload_dir"`
+	UploadBaseURL            string        `json:"upload_base_url"`      // prefix ของ URL ไฟล์ใน local storage
+	ThumbnailSize            int           `json:"thumbnail_size"`       // ด้านยาวสุดของ thumbnail (pixels)
+	S3Endpoint               string        `json:"s3_endpoint"`          // เช่น https://s3.ap-southeast-1.amazonaws.com หรือ MinIO
+	S3Bucket                 string        `json:"s3_bucket"`
+	S3Region                 string        `json:"s3_region"`
+	S3AccessKey              string        `json:"-"`
+	S3SecretKey              string        `json:"-"`
+	S3PublicURL              string        `json:"s3_public_url"`        // prefix ของ URL ที่ client ใช้ (ว่าง = endpoint/bucket)
+	
+	// Offline mailbox settings (mentions และ DM ถึงผู้ใช้ที่ offline)
+	EnableOfflineMailbox     bool          `json:"enable_offline_mailbox"`
+	MaxMissedMessages        int           `json:"max_missed_messages"` // ส่งได้สูงสุดต่อการ reconnect หนึ่งครั้ง
+	
+	// Session resume settings (reconnect ภายใน grace period ได้ห้องเดิมและข้อความที่พลาดไป)
+	EnableSessionResume      bool          `json:"enable_session_resume"`
+	SessionResumeGrace       time.Duration `json:"session_resume_grace"`
+	MaxResumeMessages        int           `json:"max_resume_messages"` // ข้อความที่ buffer ไว้ต่อ session ที่หลุด
+	
+	// Notification settings (mention notifications ที่เก็บไว้ใน MongoDB)
+	EnableNotifications      bool          `json:"enable_notifications"`
+	NotificationTTL          time.Duration `json:"notification_ttl"`
+	MaxNotifications         int           `json:"max_notifications"` // จำนวนสูงสุดต่อการขอ get_notifications
+	
+	// Message retention settings (ลบหรือย้ายข้อความเก่าออกจาก MongoDB)
+	MessageRetention         time.Duration `json:"message_retention"`          // ค่า default ของทุกห้อง (0 = เก็บตลอดไป); ห้องตั้งค่าของตัวเองได้ด้วย /retention
+	RetentionCheckInterval   time.Duration `json:"retention_check_interval"`
+	RetentionBatchSize       int           `json:"retention_batch_size"`       // ข้อความต่อการลบหนึ่งครั้ง
+	RetentionArchive         bool          `json:"retention_archive"`          // ย้ายไป messages_archive แทนการลบทิ้ง
+	
+	// Read receipt settings
+	MaxUnreadCount           int           `json:"max_unread_count"` // เพดานการนับ unread ต่อห้อง
+	
+	// Draft sync settings
+	MaxDraftLength           int           `json:"max_draft_length"`
+	MaxDraftsPerUser         int           `json:"max_drafts_per_user"`
+	DraftTTL                 time.Duration `json:"draft_ttl"`
+	
+	// Message acknowledgement settings
+	AckDedupWindow           time.Duration `json:"ack_dedup_window"` // client_msg_id ซ้ำภายในช่วงนี้ได้ ack เดิมโดยไม่ส่งข้อความซ้ำ
+	
+	// User preference settings
+	MaxPreferenceKeys        int           `json:"max_preference_keys"`
+	MaxPreferenceKeyLength   int           `json:"max_preference_key_length"`
+	MaxPreferenceValueLength int           `json:"max_preference_value_length"`
+	MaxSnoozeDuration        time.Duration `json:"max_snooze_duration"`
+
+	// User profile settings
+	MaxDisplayNameLength     int           `json:"max_display_name_length"`
+	MaxStatusTextLength      int           `json:"max_status_text_length"`
+	MaxAvatarURLLength       int           `json:"max_avatar_url_length"`
+
+	// Account settings (POST /api/register และ /api/login)
+	RequireAccounts          bool          `json:"require_accounts"`    // true = ต้อง login ก่อน join ทุกครั้ง ไม่มี guest
+	MinPasswordLength        int           `json:"min_password_length"`
+	AccountSessionTTL        time.Duration `json:"account_session_ttl"` // อายุของ session token หลัง login
+	LoginFailureLimit        int           `json:"login_failure_limit"`    // รหัสผ่านผิดติดกันต่อ account ก่อนถูกล็อก
+	LoginIPFailureLimit      int           `json:"login_ip_failure_limit"` // รหัสผ่านผิดต่อ IP (ทุก account รวมกัน) ก่อนถูกล็อก
+	LoginLockoutBase         time.Duration `json:"login_lockout_base"`
+	LoginLockoutMax          time.Duration `json:"login_lockout_max"`
+	
+	// Latency instrumentation settings
+	EnableLatencyMetrics     bool          `json:"enable_latency_metrics"`
+	
+	// State reaper settings
+	ReaperInterval           time.Duration `json:"reaper_interval"`
+	ReaperHeapThreshold      uint64        `json:"reaper_heap_threshold"`
+	
+	// Change feed settings
+	EnableChangeFeed         bool          `json:"enable_change_feed"`
+	ChangeFeedPollInterval   time.Duration `json:"change_feed_poll_interval"`
+	MaxLiveSearchesPerConnection int       `json:"max_live_searches_per_connection"`
+	
+	// Metrics history settings
+	MetricsSnapshotInterval  time.Duration `json:"metrics_snapshot_interval"`
+	MetricsRetention         time.Duration `json:"metrics_retention"`
+	MetricsFile              string        `json:"metrics_file"`
+	
+	// Schema migration settings
+	AutoMigrate              bool          `json:"auto_migrate"` // apply pending MongoDB migrations at startup
+	
+	// Announcement settings
+	MaxAnnouncements         int           `json:"max_announcements"`
+	
+	// Connection trace settings (/trace)
+	TraceDuration            time.Duration `json:"trace_duration"`
+	
+	// Room mirror settings
+	NodeID                   string        `json:"node_id"` // ชื่อ node นี้ ใช้กำหนด writer ของห้อง mirror และเป็น ID ใน cluster
+	
+	// Cluster settings: แต่ละ node เป็นเจ้าของห้องส่วนหนึ่งตาม consistent hash
+	EnableCluster            bool          `json:"enable_cluster"`
+	ClusterAddr              string        `json:"cluster_addr"`           // gRPC listen address ของ node นี้
+	ClusterAdvertiseAddr     string        `json:"cluster_advertise_addr"` // address ที่ node อื่นใช้ติดต่อ (ว่าง = hostname + port ของ cluster_addr)
+	ClusterPeers             []string      `json:"cluster_peers"`          // seed nodes (host:port) ใช้เข้าร่วม cluster ครั้งแรก
+	ClusterSecret            string        `json:"-"`                      // shared secret ที่ทุก node ต้องส่งมากับ gRPC call
+	ClusterGossipInterval    time.Duration `json:"cluster_gossip_interval"`
+	ClusterNodeTimeout       time.Duration `json:"cluster_node_timeout"`   // ไม่ได้ข่าวจาก node นานเท่านี้ถือว่าออกจาก cluster
+	ClusterVirtualNodes      int           `json:"cluster_virtual_nodes"`  // จุดบน hash ring ต่อ node
+	Region                   string        `json:"region"`                 // region ของ node นี้ เช่น "eu-west" (ประกาศใน capabilities และ /api/regions)
+	PublicURL                string        `json:"public_url"`             // URL ที่ client ใช้ต่อเข้า node นี้ เช่น "wss://eu.chat.example.com/ws"
+
+	// gRPC integration API: ให้ service ภายในส่งข้อความ สร้างห้อง และติดตามห้องโดยไม่ต้องใช้ WebSocket
+	EnableGRPC               bool          `json:"enable_grpc"`
+	GRPCAddr                 string        `json:"grpc_addr"` // ใช้ api_keys เดียวกับ HTTP API
+
+	// TLS settings: ตั้ง cert/key หรือเปิด autocert แล้ว server รับ https:// และ wss:// แทน
+	TLSCertFile              string        `json:"tls_cert_file"`
+	TLSKeyFile               string        `json:"tls_key_file"`
+	TLSAutocert              bool          `json:"tls_autocert"`             // ขอ certificate จาก Let's Encrypt (ACME) อัตโนมัติ
+	TLSAutocertHosts         []string      `json:"tls_autocert_hosts"`       // host ที่อนุญาตให้ขอ certificate (บังคับเมื่อใช้ autocert)
+	TLSAutocertEmail         string        `json:"tls_autocert_email"`       // ติดต่อเรื่อง certificate (ไม่บังคับ)
+	TLSAutocertCacheDir      string        `json:"tls_autocert_cache_dir"`   // เก็บ certificate ไว้ใช้หลัง restart
+	TLSMinVersion            string        `json:"tls_min_version"`          // 1.2 หรือ 1.3
+	TLSRedirectAddr          string        `json:"tls_redirect_addr"`        // listen HTTP แล้ว redirect ไป HTTPS เช่น ":80" (ว่าง = ปิด)
 }
 
+// DefaultNamePattern allows letters, digits, _, - and Thai characters in usernames and room names
+const DefaultNamePattern = `^[a-zA-Z0-9_\-\p{Thai}]+$`
+
 // DefaultServerConfig returns default server configuration
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
 		MaxConnections:      1000,
 		MaxRooms:            100,
 		MaxUsersPerRoom:     50,
+		MaxRoomCapacity:     500,               // เพดานสูงสุดที่เจ้าของห้องตั้งได้
 		HeartbeatInterval:   30 * time.Second,  // ลดลงเพื่อตรวจสอบบ่อยขึ้น
 		ReadTimeout:         60 * time.Second,
 		WriteTimeout:        10 * time.Second,
 		PongTimeout:         60 * time.Second,  // เวลารอ pong response
-		ConnectionTimeout:   5 * time.Minute,  // timeout สำหรับ inactive connections
+		EnableAppHeartbeat:  true,
+		ConnectionTimeout:   5 * time.Minute,  // ตัด connection ที่ client ไม่ส่งอะไรมา (ไม่นับ heartbeat) นานเกินนี้, 0 = ไม่ตัด
 		BroadcastBuffer:     256,
+		MaxOutboundFrameSize: 64 * 1024,        // payload ที่ใหญ่กว่านี้ (เช่น /history ยาวๆ) จะถูกแบ่งเป็น chunk
+		SendQueueSize:       256,
+		SlowConsumerPolicy:  "drop_oldest",     // client ช้าชั่วคราวเสียข้อความเก่าแทนที่จะหลุดทันที
+		SlowConsumerMaxDrops: 256,              // ค้างนานจน drop ติดกันเท่าคิวทั้งคิวจึงปิด
+		FanoutWorkers:       4,
+		FanoutMinRecipients: 256,
+		EnableBatching:      true,
+		BatchWindow:         20 * time.Millisecond,
+		BatchMaxSize:        50,
+		EnableCompression:   false, // แลก CPU กับ bandwidth เปิดเมื่อ history/ผลค้นหาใหญ่จนกิน bandwidth
+		CompressionThreshold: 1024,
+		CompressionLevel:    1,
 		EnableMetrics:       true,
 		EnableHealthCheck:   true,
 		HealthCheckInterval: 30 * time.Second,  // ตรวจสอบ health ทุก 30 วินาที
 		Port:                ":9090",
+		LogLevel:            "info",
+		LogFormat:           "text",
 		
 		// Security settings
 		MaxMessageLength:    1000,              // จำกัดความยาวข้อความ
@@ -163,8 +463,22 @@ func DefaultServerConfig() *ServerConfig {
 		RateLimitMessages:   10,                // จำกัด 10 ข้อความ
 		RateLimitWindow:     1 * time.Minute,   // ต่อ 1 นาที
 		EnableRateLimit:     true,              // เปิดใช้ rate limiting
+		RateLimitBurst:      10,                // token เติมตามอัตรา messages/window แต่สะสมได้ไม่เกิน burst
+		RateLimitIdleTTL:    10 * time.Minute,
+		RateLimitWarnRemaining: 2,
+		MaxMentionsPerMessage: 10,             // จำนวน @username ที่ไม่ซ้ำกันต่อข้อความ (0 = ไม่จำกัด)
+		EnableGuestNames:    true,              // join โดยไม่ระบุ username จะได้ชื่อสุ่ม (ใช้กับชื่อห้องชั่วคราวด้วย)
+		GuestNameLocale:     "en",              // "en" หรือ "th"
+		UsernamePattern:     DefaultNamePattern,
+		RoomNamePattern:     DefaultNamePattern,
+		ReservedNames:       []string{"system", "admin", "administrator", "root", "server", "moderator"}, // "System" เป็นผู้สร้างห้อง default
+		UnicodeNormalization: "nfc",            // nfkc รวมตัวอักษรหน้าตาคล้ายกันด้วย แต่เปลี่ยนชื่อไทยที่มีสระอำ
+		StripInvisibleChars: true,
 		
 		// Database settings
+		StorageBackend:      "",                // ใช้ enable_mongodb เลือกระหว่าง mongodb กับ memory
+		PostgresMaxConns:    20,
+		SQLitePath:          "data/chat.db",
 		EnableMongoDB:       false,             // ปิดใช้ MongoDB โดยค่าเริ่มต้น
 		MongoURI:            "mongodb://localhost:27017",
 		MongoDatabase:       "realtime_chat",
@@ -172,7 +486,263 @@ func DefaultServerConfig() *ServerConfig {
 		MongoPingTimeout:    5 * time.Second,
 		MongoMaxPoolSize:    100,
 		MongoMinPoolSize:    5,
+		
+		// Outage handling settings
+		MongoBreakerThreshold:    3,                // ล้มเหลว 3 ครั้งติดกันถือว่า DB ล่ม
+		MongoBreakerCooldown:     15 * time.Second, // รอก่อนลองเชื่อมต่อใหม่
+		MongoHealthCheckInterval: 10 * time.Second,
+		MessageBufferSize:        1000,             // จำนวนข้อความที่เก็บไว้ระหว่าง DB ล่ม
+		MongoSlowThreshold:       2 * time.Second,  // บันทึกช้ากว่านี้ถือว่า DB มีปัญหา
+		
+		// Write-ahead journal settings
+		JournalDir:               "data/journal",   // ว่างไว้เพื่อปิดการใช้ journal
+		JournalSegmentSize:       4 * 1024 * 1024,
+		JournalReconcileInterval: 30 * time.Second,
+		
+		// Search index settings
+		EnableSearchIndex:        false,            // ปิดไว้ ใช้ MongoDB text search แทน
+		ElasticsearchURL:         "http://localhost:9200",
+		ElasticsearchIndex:       "chat_messages",
+		SearchIndexQueueSize:     1000,
+		
+		// Autocomplete settings
+		SuggestDebounce:          150 * time.Millisecond, // รอให้ผู้ใช้หยุดพิมพ์ก่อนตอบ
+		TypingDebounce:           3 * time.Second,        // broadcast typing_start ซ้ำได้ไม่เกินทุก 3 วินาทีต่อผู้ใช้
+		PresenceAwayAfter:        5 * time.Minute,        // ไม่มีกิจกรรม 5 นาทีถือว่า away
+		PresenceSweepInterval:    30 * time.Second,
+		
+		// Stale user cleanup settings
+		UserCleanupMaxAttempts:   5,                // retry ลบผู้ใช้ 5 ครั้งก่อนย้ายไป dead letter
+		UserCleanupBackoff:       2 * time.Second,  // รอครั้งแรก แล้วเพิ่มเป็นสองเท่าทุกครั้ง
+		UserSweepInterval:        1 * time.Minute,
+		NodeHeartbeatTimeout:     3 * time.Minute,  // node ที่ไม่ส่ง heartbeat นานกว่านี้ถือว่าตายแล้ว
+		SuggestMaxResults:        10,
+		
+		// Member list settings
+		UsersPageSize:            50,
+		UsersPageMaxSize:         200,
+		MemberDeltaInterval:      1 * time.Second, // รวม join/leave แล้วส่งทุก 1 วินาที
+		
+		// Room hibernation settings
+		EnableRoomHibernation:    true,
+		RoomHibernationDir:       "data/rooms",
+		RoomIdleTimeout:          30 * time.Minute, // ไม่มีคนและไม่มีข้อความนานเท่านี้จะถูกพักไว้บน disk
+		RoomHibernationInterval:  5 * time.Minute,
+		
+		// Reconnect guidance settings
+		ReconnectMinBackoff:      1 * time.Second,
+		ReconnectMaxBackoff:      30 * time.Second,
+		ReconnectJitter:          0.5,  // สุ่ม ±50% เพื่อไม่ให้ client reconnect พร้อมกัน
+		ReconnectRatePerSecond:   200,  // จำนวน reconnect ต่อวินาทีที่ server รับไหว
+		
+		// Connection throttling settings
+		EnableConnectionThrottle: true,
+		ConnectAttemptLimit:      20,               // เชื่อมต่อได้ 20 ครั้ง
+		ConnectAttemptWindow:     1 * time.Minute,  // ต่อ 1 นาที ต่อ IP
+		AuthFailureLimit:         5,                // ตั้งชื่อผิด/ชื่อซ้ำติดกัน 5 ครั้งจะถูก block
+		ConnectBlockBase:         30 * time.Second, // block ครั้งแรก แล้วเพิ่มเป็นสองเท่าทุกครั้ง
+		ConnectBlockMax:          1 * time.Hour,
+		MaxConnectionsPerIP:      20,               // NAT/office หลายคนใช้ IP เดียวกันได้
+		IPViolationLimit:         10,
+		
+		// Room switch limiting settings
+		EnableChurnLimit:         true,
+		RoomSwitchLimit:          10,              // join/leave ได้ 10 ครั้ง
+		RoomSwitchWindow:         1 * time.Minute, // ต่อ 1 นาที ต่อผู้ใช้ (token คืนทีละน้อยตลอดช่วง)
+		
+		// Sampled delivery settings
+		EnableRoomSampling:       false,
+		SamplingThreshold:        500,              // ห้องที่มีสมาชิกตั้งแต่ 500 คน
+		SamplingActiveWindow:     5 * time.Minute,
+		DigestInterval:           30 * time.Second, // สมาชิกที่ไม่ได้ร่วมสนทนาได้ digest ทุก 30 วินาที
+		MaxDigestMessages:        20,               // ข้อความล่าสุดใน digest (นับรวมทั้งหมดใน total)
+		
+		// Inbound frame limiting settings
+		EnableFrameRateLimit:     true,
+		FrameRateLimit:           300,              // frame ใดก็ได้ 300 frame (เผื่อ file chunk และ typing)
+		ExpensiveFrameRateLimit:  30,               // history/search/คำสั่ง 30 ครั้ง
+		FrameRateWindow:          10 * time.Second, // ต่อ 10 วินาที ต่อ connection
+		
+		// Admin settings
+		AdminUsernames:           []string{},
+		
+		// Integration API settings
+		APIKeys:                  []string{},       // ว่าง = ปิด endpoint ที่ต้องใช้ API key
+		DefaultSubprotocol:       "chat.v1.json",   // ตั้งเป็น chat.v1.text สำหรับ client รุ่นเก่าที่ส่ง plain text
+		EnableMsgpack:            false,            // เปิดสำหรับ bot ที่ส่งข้อความปริมาณสูง
+		AllowedOrigins:           []string{"*"},    // ควรระบุ origin จริงใน production
+		TrustedProxies:           []string{},
+		MaxPresenceDuration:      24 * time.Hour,   // presence จากระบบภายนอกอยู่ได้นานสุดเท่านี้
+		
+		// Honeypot settings
+		EnableHoneypots:          false,            // เปิดใช้กับ deployment สาธารณะ
+		HoneypotRooms:            []string{"admin", "staff-only", "free-giveaway"}, // ไม่แสดงใน /rooms
+		HoneypotCommands:         []string{"sudo", "debug", "op"},                  // ไม่แสดงใน /help
+		ModerationLogSize:        1000,
+		
+		// Protocol violation quarantine settings
+		EnableQuarantine:         true,
+		ProtocolViolationLimit:   5,
+		QuarantineGrace:          5 * time.Second,
+		
+		// Delivery SLO sampling settings
+		EnableDeliverySampling:   true,
+		DeliverySampleInterval:   10 * time.Second, // สุ่มวัดไม่เกิน 1 broadcast ต่อ 10 วินาทีต่อห้อง
+		DeliverySampleSize:       5,                // ขอ ack จาก 5 connection ต่อครั้ง
+		DeliveryProbeTimeout:     10 * time.Second, // ไม่ ack ภายในเวลานี้นับเป็น timeout
+		DeliveryWindowSize:       500,              // เก็บ latency ล่าสุด 500 ค่าต่อห้อง
+		LoadTestMode:             false,
+		
+		// Temporary room settings
+		MaxRoomTTL:               7 * 24 * time.Hour, // /create --ttl ตั้งได้ไม่เกิน 7 วัน
+		RoomExpiryCheckInterval:  15 * time.Second,
+		RoomExpiryWarnings:       []time.Duration{10 * time.Minute, 1 * time.Minute}, // เตือนสมาชิกก่อนหมดอายุ
+		
+		// Room lifecycle settings
+		RoomArchiveAfter:         7 * 24 * time.Hour, // ไม่มีคนและไม่มีข้อความนานเท่านี้จะถูก archive
+		RoomArchiveInterval:      10 * time.Minute,
+		ReactivateArchivedRooms:  true,
+		
+		// Room cloning settings
+		MaxCloneMessages:         100,              // /clone --messages คัดลอกได้ไม่เกินเท่านี้
+		
+		// File transfer settings
+		EnableFileTransfer:       true,
+		MaxFileSize:              10 * 1024 * 1024, // 10MB
+		FileChunkSize:            32 * 1024,        // base64 แล้วยังเล็กกว่า MaxOutboundFrameSize
+		FileTransferWindow:       8,
+		FileTransferIdleTimeout:  2 * time.Minute,  // offer ที่ไม่มีคนตอบหรือ transfer ที่หยุดนิ่งถูกทิ้ง
+		
+		// Attachment upload settings (ต้องตั้ง API key)
+		EnableUploads:            false,
+		MaxUploadSize:            10 * 1024 * 1024, // 10MB
+		AllowedUploadTypes:       []string{"image/*", "application/pdf", "text/plain"},
+		UploadStorage:            "local",
+		UploadDir:                "./uploads",
+		UploadBaseURL:            "/uploads/",
+		ThumbnailSize:            256,
+		S3Region:                 "us-east-1",
+		
+		// Offline mailbox settings (ต้องเปิด MongoDB)
+		EnableOfflineMailbox:     true,
+		MaxMissedMessages:        100,              // ที่เหลือส่งตอน reconnect ครั้งถัดไปหลัง mark_read
+		
+		// Session resume settings
+		EnableSessionResume:      true,
+		SessionResumeGrace:       2 * time.Minute,  // ชื่อผู้ใช้ถูกจองไว้ให้ resume ตลอดช่วงนี้
+		MaxResumeMessages:        100,
+		
+		// Notification settings (ต้องเปิด MongoDB)
+		EnableNotifications:      true,
+		NotificationTTL:          30 * 24 * time.Hour,
+		MaxNotifications:         50,
+		
+		// Message retention settings (ต้องเปิด MongoDB)
+		MessageRetention:         0,                // เก็บตลอดไปจนกว่าจะตั้งค่า
+		RetentionCheckInterval:   1 * time.Hour,
+		RetentionBatchSize:       1000,             // ลบทีละชุดเล็กๆ ไม่ให้ค้าง DB นาน
+		RetentionArchive:         false,
+		
+		// Read receipt settings
+		MaxUnreadCount:           100,              // เกินนี้ badge แสดง "99+" ไม่ต้องนับทั้งห้อง
+		
+		// Draft sync settings
+		MaxDraftLength:           2000,             // ยาวกว่าข้อความได้เล็กน้อย เผื่อกำลังตัดต่อ
+		MaxDraftsPerUser:         20,               // เกินนี้จะทิ้ง draft ที่เก่าที่สุด
+		DraftTTL:                 7 * 24 * time.Hour,
+		
+		// Message acknowledgement settings
+		AckDedupWindow:           5 * time.Minute,  // นานกว่า retry ของ client ที่หลุดแล้วเชื่อมต่อใหม่
+		
+		// User preference settings
+		MaxPreferenceKeys:        32,               // theme, sounds, compact mode ... ไม่ควรต้องใช้มากกว่านี้
+		MaxPreferenceKeyLength:   64,
+		MaxPreferenceValueLength: 256,
+		MaxSnoozeDuration:        7 * 24 * time.Hour,
+
+		// User profile settings
+		MaxDisplayNameLength:     32,
+		MaxStatusTextLength:      140,
+		MaxAvatarURLLength:       512,              // ส่งไปกับทุกข้อความ จึงไม่ควรยาวเกินไป
+
+		// Account settings
+		RequireAccounts:          false,            // guest ยังเข้าได้ แต่ใช้ชื่อที่มี account แล้วไม่ได้
+		MinPasswordLength:        8,
+		AccountSessionTTL:        30 * 24 * time.Hour,
+		LoginFailureLimit:        5,
+		LoginIPFailureLimit:      20,               // สูงกว่าต่อ account เพราะหลายคนอาจอยู่หลัง NAT เดียวกัน
+		LoginLockoutBase:         30 * time.Second, // ล็อกครั้งแรก แล้วเพิ่มเป็นสองเท่าทุกครั้งที่ถูกล็อกซ้ำ
+		LoginLockoutMax:          1 * time.Hour,
+		
+		// Latency instrumentation settings
+		EnableLatencyMetrics:     true,             // จับเวลาแต่ละขั้นของข้อความ ดูผลด้วย /latency
+		
+		// State reaper settings
+		ReaperInterval:           1 * time.Minute,
+		ReaperHeapThreshold:      512 * 1024 * 1024, // heap เกินนี้จะล้าง state แบบ aggressive (0 = ปิด)
+		
+		// Change feed settings
+		EnableChangeFeed:         true,             // ใช้ได้เมื่อเปิด MongoDB เท่านั้น
+		ChangeFeedPollInterval:   2 * time.Second,  // ใช้เมื่อ MongoDB ไม่ใช่ replica set (ไม่มี change streams)
+		MaxLiveSearchesPerConnection: 5,
+		
+		// Metrics history settings
+		MetricsSnapshotInterval:  1 * time.Minute,
+		MetricsRetention:         7 * 24 * time.Hour,
+		MetricsFile:              "data/metrics.jsonl", // ใช้เมื่อไม่มี MongoDB (ว่างไว้เพื่อปิด)
+		
+		// Schema migration settings
+		AutoMigrate:              true,             // ปิดเพื่อรันเองด้วย /migrations apply หลังตรวจ dry-run
+		
+		// Announcement settings
+		MaxAnnouncements:         50,               // เก็บรายงานการรับทราบของประกาศล่าสุดไว้เท่านี้
+		
+		// Connection trace settings
+		TraceDuration:            5 * time.Minute,  // trace หยุดเองเพื่อไม่ให้ log ท่วมถ้าลืมปิด
+		
+		// Room mirror settings
+		NodeID:                   defaultNodeID(), // ต้องไม่ซ้ำกันในแต่ละ node ที่ใช้ database เดียวกัน
+		
+		// Cluster settings
+		EnableCluster:            false,
+		ClusterAddr:              ":7070",
+		ClusterGossipInterval:    1 * time.Second,
+		ClusterNodeTimeout:       10 * time.Second,
+		ClusterVirtualNodes:      128,
+
+		// gRPC integration API
+		EnableGRPC:               false,
+		GRPCAddr:                 ":9090",
+
+		// TLS settings
+		TLSAutocert:              false,
+		TLSAutocertHosts:         []string{},
+		TLSAutocertCacheDir:      "./autocert-cache",
+		TLSMinVersion:            "1.2",
+	}
+}
+
+// defaultNodeID returns the hostname, which is unique per node in most deployments
+func defaultNodeID() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "node-1"
+}
+
+// TLSEnabled reports whether the server serves HTTPS/WSS (a cert/key pair or autocert is configured)
+func (c *ServerConfig) TLSEnabled() bool {
+	return c.TLSAutocert || (c.TLSCertFile != "" && c.TLSKeyFile != "")
+}
+
+// IsAdmin reports whether a username is configured as a server admin
+func (c *ServerConfig) IsAdmin(username string) bool {
+	for _, admin := range c.AdminUsernames {
+		if admin == username {
+			return true
+		}
 	}
+	return false
 }
 
 // ServerMetrics holds server performance metrics
@@ -183,6 +753,16 @@ type ServerMetrics struct {
 	TotalCommands       int64     `json:"total_commands"`
 	TotalRooms          int64     `json:"total_rooms"`
 	TotalUsers          int64     `json:"total_users"`
+	ResidentRooms       int64     `json:"resident_rooms"`
+	HibernatedRooms     int64     `json:"hibernated_rooms"`
+	ReclaimedEntries    int64     `json:"reclaimed_entries"`
+	PurgedMessages      int64     `json:"purged_messages"` // ข้อความที่ถูกลบ/ย้ายตาม retention
+	ProtocolViolations  map[string]int64 `json:"protocol_violations"` // kind -> จำนวนครั้ง
+	QuarantinedConnections int64  `json:"quarantined_connections"`
+	RejectedUpgrades    int64     `json:"rejected_upgrades"` // WebSocket upgrade จาก origin ที่ไม่อยู่ใน allowlist
+	FramesRateLimited   map[string]int64 `json:"frames_rate_limited"` // budget -> frame ที่ถูกทิ้ง
+	DroppedFrames       int64     `json:"dropped_frames"` // outbound frame ที่ทิ้งเพราะคิวของ client เต็ม
+	SlowConsumerDisconnects int64 `json:"slow_consumer_disconnects"`
 	StartTime           time.Time `json:"start_time"`
 	LastMessageTime     time.Time `json:"last_message_time"`
 	MessageRate         float64   `json:"message_rate"`
@@ -193,7 +773,9 @@ type ServerMetrics struct {
 // NewServerMetrics creates new server metrics
 func NewServerMetrics() *ServerMetrics {
 	return &ServerMetrics{
-		StartTime: time.Now(),
+		StartTime:          time.Now(),
+		ProtocolViolations: make(map[string]int64),
+		FramesRateLimited:  make(map[string]int64),
 	}
 }
 
@@ -248,6 +830,79 @@ func (sm *ServerMetrics) DecrementUsers() {
 	sm.TotalUsers--
 }
 
+// SetRoomResidency records how many rooms are in memory vs hibernated
+func (sm *ServerMetrics) SetRoomResidency(resident, hibernated int) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.ResidentRooms = int64(resident)
+	sm.HibernatedRooms = int64(hibernated)
+}
+
+// AddReclaimedEntries records stale in-memory entries pruned by the reaper
+func (sm *ServerMetrics) AddReclaimedEntries(count int) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.ReclaimedEntries += int64(count)
+}
+
+// AddPurgedMessages records messages deleted or archived by the retention janitor
+func (sm *ServerMetrics) AddPurgedMessages(count int) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.PurgedMessages += int64(count)
+}
+
+// RecordProtocolViolation counts a protocol violation of the given kind
+func (sm *ServerMetrics) RecordProtocolViolation(kind string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.ProtocolViolations[kind]++
+}
+
+// IncrementQuarantined counts a connection quarantined for protocol violations
+func (sm *ServerMetrics) IncrementQuarantined() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.QuarantinedConnections++
+}
+
+// IncrementRejectedUpgrades counts a WebSocket upgrade rejected because of its origin
+func (sm *ServerMetrics) IncrementRejectedUpgrades() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.RejectedUpgrades++
+}
+
+// RecordFrameRateLimited counts an inbound frame dropped because its budget was empty
+func (sm *ServerMetrics) RecordFrameRateLimited(budget string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.FramesRateLimited[budget]++
+}
+
+// RecordDroppedFrame counts an outbound frame dropped because a connection's queue was full
+func (sm *ServerMetrics) RecordDroppedFrame() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.DroppedFrames++
+}
+
+// IncrementSlowConsumerDisconnects counts a connection closed by the slow consumer policy
+func (sm *ServerMetrics) IncrementSlowConsumerDisconnects() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.SlowConsumerDisconnects++
+}
+
+// RestoreTotals seeds cumulative counters persisted before a restart
+func (sm *ServerMetrics) RestoreTotals(connections, messages, commands int64) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.TotalConnections += connections
+	sm.TotalMessages += messages
+	sm.TotalCommands += commands
+}
+
 // GetMetrics returns current metrics with calculated rates
 func (sm *ServerMetrics) GetMetrics() *ServerMetrics {
 	sm.mutex.RLock()
@@ -258,6 +913,15 @@ func (sm *ServerMetrics) GetMetrics() *ServerMetrics {
 	messageRate := float64(sm.TotalMessages) / uptime
 	connectionRate := float64(sm.TotalConnections) / uptime
 	
+	violations := make(map[string]int64, len(sm.ProtocolViolations))
+	for kind, count := range sm.ProtocolViolations {
+		violations[kind] = count
+	}
+	framesLimited := make(map[string]int64, len(sm.FramesRateLimited))
+	for budget, count := range sm.FramesRateLimited {
+		framesLimited[budget] = count
+	}
+	
 	return &ServerMetrics{
 		TotalConnections:  sm.TotalConnections,
 		ActiveConnections: sm.ActiveConnections,
@@ -265,6 +929,16 @@ func (sm *ServerMetrics) GetMetrics() *ServerMetrics {
 		TotalCommands:     sm.TotalCommands,
 		TotalRooms:        sm.TotalRooms,
 		TotalUsers:        sm.TotalUsers,
+		ResidentRooms:     sm.ResidentRooms,
+		HibernatedRooms:   sm.HibernatedRooms,
+		ReclaimedEntries:  sm.ReclaimedEntries,
+		PurgedMessages:    sm.PurgedMessages,
+		ProtocolViolations: violations,
+		QuarantinedConnections: sm.QuarantinedConnections,
+		RejectedUpgrades:  sm.RejectedUpgrades,
+		FramesRateLimited: framesLimited,
+		DroppedFrames:     sm.DroppedFrames,
+		SlowConsumerDisconnects: sm.SlowConsumerDisconnects,
 		StartTime:         sm.StartTime,
 		LastMessageTime:   sm.LastMessageTime,
 		MessageRate:       messageRate,
@@ -272,18 +946,29 @@ func (sm *ServerMetrics) GetMetrics() *ServerMetrics {
 	}
 }
 
-// RateLimiter manages rate limiting per user
+// RateLimiter manages rate limiting per user with a token bucket:
+// bucket เติม RateLimitMessages token ต่อ RateLimitWindow และสะสมได้ไม่เกิน RateLimitBurst
 type RateLimiter struct {
 	limits map[string]*UserRateLimit
 	mutex  sync.RWMutex
 	config *ServerConfig
 }
 
-// UserRateLimit tracks rate limiting for a specific user
+// UserRateLimit tracks the token bucket of a specific user
 type UserRateLimit struct {
-	MessageCount int
-	WindowStart  time.Time
-	mutex        sync.Mutex
+	Tokens     float64
+	LastRefill time.Time
+	LastSeen   time.Time
+	warned     bool // ส่ง rate_limit_status แล้วในรอบที่ token ใกล้หมดนี้
+	mutex      sync.Mutex
+}
+
+// RateLimitStatus describes the remaining message budget of a user
+type RateLimitStatus struct {
+	Remaining  int
+	Burst      int
+	RetryAfter time.Duration // จนกว่าจะได้ token ถัดไป (0 = ส่งได้ทันที)
+	FullIn     time.Duration // จนกว่า bucket จะเต็ม
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -294,71 +979,160 @@ func NewRateLimiter(config *ServerConfig) *RateLimiter {
 	}
 }
 
-// CheckRateLimit checks if a user can send a message
+// burst returns the bucket capacity (falls back to RateLimitMessages for old configs)
+func (rl *RateLimiter) burst() float64 {
+	if rl.config.RateLimitBurst > 0 {
+		return float64(rl.config.RateLimitBurst)
+	}
+	return float64(rl.config.RateLimitMessages)
+}
+
+// refillRate returns tokens added per second
+func (rl *RateLimiter) refillRate() float64 {
+	if rl.config.RateLimitWindow <= 0 {
+		return 0
+	}
+	return float64(rl.config.RateLimitMessages) / rl.config.RateLimitWindow.Seconds()
+}
+
+// refill adds the tokens earned since the last refill; caller holds userLimit.mutex
+func (rl *RateLimiter) refill(userLimit *UserRateLimit, now time.Time) {
+	elapsed := now.Sub(userLimit.LastRefill).Seconds()
+	if elapsed > 0 {
+		userLimit.Tokens = math.Min(rl.burst(), userLimit.Tokens+elapsed*rl.refillRate())
+		userLimit.LastRefill = now
+	}
+}
+
+// status computes the user's status; caller holds userLimit.mutex after refill
+func (rl *RateLimiter) status(userLimit *UserRateLimit) RateLimitStatus {
+	status := RateLimitStatus{
+		Remaining: int(userLimit.Tokens),
+		Burst:     int(rl.burst()),
+	}
+	if rate := rl.refillRate(); rate > 0 {
+		if userLimit.Tokens < 1 {
+			status.RetryAfter = time.Duration((1 - userLimit.Tokens) / rate * float64(time.Second))
+		}
+		status.FullIn = time.Duration((rl.burst() - userLimit.Tokens) / rate * float64(time.Second))
+	}
+	return status
+}
+
+// CheckRateLimit checks if a user can send a message and takes a token if so
 func (rl *RateLimiter) CheckRateLimit(userID string) bool {
 	if !rl.config.EnableRateLimit {
 		return true
 	}
 
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
 	now := time.Now()
-	
-	// Get or create user rate limit
+
+	rl.mutex.Lock()
 	userLimit, exists := rl.limits[userID]
 	if !exists {
 		userLimit = &UserRateLimit{
-			MessageCount: 0,
-			WindowStart:  now,
+			Tokens:     rl.burst(),
+			LastRefill: now,
 		}
 		rl.limits[userID] = userLimit
 	}
+	rl.mutex.Unlock()
 
 	userLimit.mutex.Lock()
 	defer userLimit.mutex.Unlock()
 
-	// Check if window has expired
-	if now.Sub(userLimit.WindowStart) > rl.config.RateLimitWindow {
-		// Reset window
-		userLimit.MessageCount = 0
-		userLimit.WindowStart = now
-	}
-
-	// Check if user has exceeded limit
-	if userLimit.MessageCount >= rl.config.RateLimitMessages {
+	rl.refill(userLimit, now)
+	userLimit.LastSeen = now
+	if userLimit.Tokens < 1 {
 		return false
 	}
-
-	// Increment message count
-	userLimit.MessageCount++
+	userLimit.Tokens--
 	return true
 }
 
 // GetRateLimitStatus returns current rate limit status for a user
-func (rl *RateLimiter) GetRateLimitStatus(userID string) (int, int, time.Duration) {
+func (rl *RateLimiter) GetRateLimitStatus(userID string) RateLimitStatus {
 	rl.mutex.RLock()
-	defer rl.mutex.RUnlock()
+	userLimit, exists := rl.limits[userID]
+	rl.mutex.RUnlock()
+
+	if !exists {
+		return RateLimitStatus{Remaining: int(rl.burst()), Burst: int(rl.burst())}
+	}
+
+	userLimit.mutex.Lock()
+	defer userLimit.mutex.Unlock()
 
+	rl.refill(userLimit, time.Now())
+	return rl.status(userLimit)
+}
+
+// ShouldWarn reports the user's status once each time their remaining tokens drop to
+// RateLimitWarnRemaining; the warning re-arms after the bucket refills above it
+func (rl *RateLimiter) ShouldWarn(userID string) (RateLimitStatus, bool) {
+	threshold := rl.config.RateLimitWarnRemaining
+	if !rl.config.EnableRateLimit || threshold <= 0 {
+		return RateLimitStatus{}, false
+	}
+
+	rl.mutex.RLock()
 	userLimit, exists := rl.limits[userID]
+	rl.mutex.RUnlock()
 	if !exists {
-		return 0, rl.config.RateLimitMessages, rl.config.RateLimitWindow
+		return RateLimitStatus{}, false
 	}
 
 	userLimit.mutex.Lock()
 	defer userLimit.mutex.Unlock()
 
-	remaining := rl.config.RateLimitMessages - userLimit.MessageCount
-	if remaining < 0 {
-		remaining = 0
+	rl.refill(userLimit, time.Now())
+	status := rl.status(userLimit)
+	if status.Remaining > threshold {
+		userLimit.warned = false
+		return status, false
+	}
+	if userLimit.warned {
+		return status, false
 	}
+	userLimit.warned = true
+	return status, true
+}
 
-	timeRemaining := rl.config.RateLimitWindow - time.Since(userLimit.WindowStart)
-	if timeRemaining < 0 {
-		timeRemaining = 0
+// EvictIdle removes buckets of users who have not sent a message for RateLimitIdleTTL
+func (rl *RateLimiter) EvictIdle() int {
+	return rl.evict(rl.config.RateLimitIdleTTL)
+}
+
+// Reap removes idle rate limit entries (user IDs change on every connection, so entries of
+// disconnected users would otherwise stay forever). Aggressive mode also drops every bucket
+// that has refilled completely, since a full bucket is the same as a new one
+func (rl *RateLimiter) Reap(aggressive bool) int {
+	if aggressive {
+		return rl.evict(0)
 	}
+	return rl.EvictIdle()
+}
+
+// evict drops buckets idle for longer than idleTTL that are also full again,
+// so evicting never gives a user more tokens than they would have had
+func (rl *RateLimiter) evict(idleTTL time.Duration) int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := time.Now()
+	count := 0
+	for userID, userLimit := range rl.limits {
+		userLimit.mutex.Lock()
+		rl.refill(userLimit, now)
+		idle := now.Sub(userLimit.LastSeen) >= idleTTL && userLimit.Tokens >= rl.burst()
+		userLimit.mutex.Unlock()
 
-	return remaining, rl.config.RateLimitMessages, timeRemaining
+		if idle {
+			delete(rl.limits, userID)
+			count++
+		}
+	}
+	return count
 }
 
 // ConfigLoader handles loading configuration from various sources
@@ -427,6 +1201,13 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 			config.MaxConnections = val
 		}
 	}
+
+	// 0 = ไม่จำกัด connection ต่อ IP
+	if maxPerIP := os.Getenv("CHAT_MAX_CONNECTIONS_PER_IP"); maxPerIP != "" {
+		if val, err := strconv.Atoi(maxPerIP); err == nil && val >= 0 {
+			config.MaxConnectionsPerIP = val
+		}
+	}
 	
 	if maxRooms := os.Getenv("CHAT_MAX_ROOMS"); maxRooms != "" {
 		if val, err := strconv.Atoi(maxRooms); err == nil {
@@ -440,6 +1221,70 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 		}
 	}
 
+	// Backpressure settings
+	if queueSize := os.Getenv("CHAT_SEND_QUEUE_SIZE"); queueSize != "" {
+		if val, err := strconv.Atoi(queueSize); err == nil {
+			config.SendQueueSize = val
+		}
+	}
+
+	if policy := os.Getenv("CHAT_SLOW_CONSUMER_POLICY"); policy != "" {
+		config.SlowConsumerPolicy = policy
+	}
+
+	if maxDrops := os.Getenv("CHAT_SLOW_CONSUMER_MAX_DROPS"); maxDrops != "" {
+		if val, err := strconv.Atoi(maxDrops); err == nil {
+			config.SlowConsumerMaxDrops = val
+		}
+	}
+
+	// Fan-out settings
+	if workers := os.Getenv("CHAT_FANOUT_WORKERS"); workers != "" {
+		if val, err := strconv.Atoi(workers); err == nil && val >= 0 {
+			config.FanoutWorkers = val
+		}
+	}
+
+	if minRecipients := os.Getenv("CHAT_FANOUT_MIN_RECIPIENTS"); minRecipients != "" {
+		if val, err := strconv.Atoi(minRecipients); err == nil && val >= 0 {
+			config.FanoutMinRecipients = val
+		}
+	}
+
+	// Batching settings
+	if enableBatching := os.Getenv("CHAT_ENABLE_BATCHING"); enableBatching != "" {
+		config.EnableBatching = enableBatching == "true"
+	}
+
+	if batchWindow := os.Getenv("CHAT_BATCH_WINDOW"); batchWindow != "" {
+		if val, err := time.ParseDuration(batchWindow); err == nil && val > 0 {
+			config.BatchWindow = val
+		}
+	}
+
+	if batchMaxSize := os.Getenv("CHAT_BATCH_MAX_SIZE"); batchMaxSize != "" {
+		if val, err := strconv.Atoi(batchMaxSize); err == nil && val > 0 {
+			config.BatchMaxSize = val
+		}
+	}
+
+	// Compression settings
+	if enableCompression := os.Getenv("CHAT_ENABLE_COMPRESSION"); enableCompression != "" {
+		config.EnableCompression = enableCompression == "true"
+	}
+
+	if threshold := os.Getenv("CHAT_COMPRESSION_THRESHOLD"); threshold != "" {
+		if val, err := strconv.Atoi(threshold); err == nil && val >= 0 {
+			config.CompressionThreshold = val
+		}
+	}
+
+	if level := os.Getenv("CHAT_COMPRESSION_LEVEL"); level != "" {
+		if val, err := strconv.Atoi(level); err == nil {
+			config.CompressionLevel = val
+		}
+	}
+
 	// Timeout settings
 	if heartbeat := os.Getenv("CHAT_HEARTBEAT_INTERVAL"); heartbeat != "" {
 		if val, err := time.ParseDuration(heartbeat); err == nil {
@@ -472,6 +1317,65 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 		}
 	}
 	
+	if interval := os.Getenv("CHAT_HEALTH_CHECK_INTERVAL"); interval != "" {
+		if val, err := time.ParseDuration(interval); err == nil && val > 0 {
+			config.HealthCheckInterval = val
+		}
+	}
+	
+	// 0 = ไม่ตัด connection ที่ไม่มีกิจกรรม
+	if timeout := os.Getenv("CHAT_CONNECTION_TIMEOUT"); timeout != "" {
+		if val, err := time.ParseDuration(timeout); err == nil && val >= 0 {
+			config.ConnectionTimeout = val
+		}
+	}
+	
+	// 0 = ไม่ archive ห้องที่ว่างอัตโนมัติ
+	if after := os.Getenv("CHAT_ROOM_ARCHIVE_AFTER"); after != "" {
+		if val, err := time.ParseDuration(after); err == nil && val >= 0 {
+			config.RoomArchiveAfter = val
+		}
+	}
+	
+	if interval := os.Getenv("CHAT_ROOM_ARCHIVE_INTERVAL"); interval != "" {
+		if val, err := time.ParseDuration(interval); err == nil && val > 0 {
+			config.RoomArchiveInterval = val
+		}
+	}
+	
+	if reactivate := os.Getenv("CHAT_REACTIVATE_ARCHIVED_ROOMS"); reactivate != "" {
+		config.ReactivateArchivedRooms = reactivate == "true"
+	}
+	
+	if window := os.Getenv("CHAT_ACK_DEDUP_WINDOW"); window != "" {
+		if val, err := time.ParseDuration(window); err == nil && val > 0 {
+			config.AckDedupWindow = val
+		}
+	}
+	
+	if pattern := os.Getenv("CHAT_USERNAME_PATTERN"); pattern != "" {
+		config.UsernamePattern = pattern
+	}
+	
+	if pattern := os.Getenv("CHAT_ROOM_NAME_PATTERN"); pattern != "" {
+		config.RoomNamePattern = pattern
+	}
+	
+	// ตั้งเป็น "-" เพื่อไม่สงวนชื่อใดเลย
+	if reserved := os.Getenv("CHAT_RESERVED_NAMES"); reserved == "-" {
+		config.ReservedNames = []string{}
+	} else if reserved != "" {
+		config.ReservedNames = strings.Split(reserved, ",")
+	}
+	
+	if normalization := os.Getenv("CHAT_UNICODE_NORMALIZATION"); normalization != "" {
+		config.UnicodeNormalization = strings.ToLower(normalization)
+	}
+	
+	if strip := os.Getenv("CHAT_STRIP_INVISIBLE_CHARS"); strip != "" {
+		config.StripInvisibleChars = strip == "true"
+	}
+	
 	if rateLimitMsg := os.Getenv("CHAT_RATE_LIMIT_MESSAGES"); rateLimitMsg != "" {
 		if val, err := strconv.Atoi(rateLimitMsg); err == nil {
 			config.RateLimitMessages = val
@@ -483,6 +1387,266 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 			config.RateLimitWindow = val
 		}
 	}
+	
+	if rateLimitBurst := os.Getenv("CHAT_RATE_LIMIT_BURST"); rateLimitBurst != "" {
+		if val, err := strconv.Atoi(rateLimitBurst); err == nil && val > 0 {
+			config.RateLimitBurst = val
+		}
+	}
+	
+	if rateLimitIdleTTL := os.Getenv("CHAT_RATE_LIMIT_IDLE_TTL"); rateLimitIdleTTL != "" {
+		if val, err := time.ParseDuration(rateLimitIdleTTL); err == nil && val > 0 {
+			config.RateLimitIdleTTL = val
+		}
+	}
+	
+	if warnRemaining := os.Getenv("CHAT_RATE_LIMIT_WARN_REMAINING"); warnRemaining != "" {
+		if val, err := strconv.Atoi(warnRemaining); err == nil && val >= 0 {
+			config.RateLimitWarnRemaining = val
+		}
+	}
+
+	if enableAppHeartbeat := os.Getenv("CHAT_ENABLE_APP_HEARTBEAT"); enableAppHeartbeat != "" {
+		config.EnableAppHeartbeat = enableAppHeartbeat == "true"
+	}
+	
+	if enableFileTransfer := os.Getenv("CHAT_ENABLE_FILE_TRANSFER"); enableFileTransfer != "" {
+		config.EnableFileTransfer = enableFileTransfer == "true"
+	}
+	
+	if maxFileSize := os.Getenv("CHAT_MAX_FILE_SIZE"); maxFileSize != "" {
+		if size, err := strconv.ParseInt(maxFileSize, 10, 64); err == nil && size > 0 {
+			config.MaxFileSize = size
+		}
+	}
+	
+	if enableUploads := os.Getenv("CHAT_ENABLE_UPLOADS"); enableUploads != "" {
+		config.EnableUploads = enableUploads == "true"
+	}
+	
+	if maxUploadSize := os.Getenv("CHAT_MAX_UPLOAD_SIZE"); maxUploadSize != "" {
+		if size, err := strconv.ParseInt(maxUploadSize, 10, 64); err == nil && size > 0 {
+			config.MaxUploadSize = size
+		}
+	}
+	
+	if uploadTypes := os.Getenv("CHAT_ALLOWED_UPLOAD_TYPES"); uploadTypes != "" {
+		config.AllowedUploadTypes = strings.Split(uploadTypes, ",")
+	}
+	
+	if uploadStorage := os.Getenv("CHAT_UPLOAD_STORAGE"); uploadStorage != "" {
+		config.UploadStorage = uploadStorage
+	}
+	
+	if uploadDir := os.Getenv("CHAT_UPLOAD_DIR"); uploadDir != "" {
+		config.UploadDir = uploadDir
+	}
+	
+	if uploadBaseURL := os.Getenv("CHAT_UPLOAD_BASE_URL"); uploadBaseURL != "" {
+		config.UploadBaseURL = uploadBaseURL
+	}
+	
+	if thumbnailSize := os.Getenv("CHAT_THUMBNAIL_SIZE"); thumbnailSize != "" {
+		if size, err := strconv.Atoi(thumbnailSize); err == nil && size > 0 {
+			config.ThumbnailSize = size
+		}
+	}
+	
+	// S3 credentials มาจาก environment เท่านั้น ไม่ถูก serialize ลง config
+	if endpoint := os.Getenv("CHAT_S3_ENDPOINT"); endpoint != "" {
+		config.S3Endpoint = endpoint
+	}
+	if bucket := os.Getenv("CHAT_S3_BUCKET"); bucket != "" {
+		config.S3Bucket = bucket
+	}
+	if region := os.Getenv("CHAT_S3_REGION"); region != "" {
+		config.S3Region = region
+	}
+	if accessKey := os.Getenv("CHAT_S3_ACCESS_KEY"); accessKey != "" {
+		config.S3AccessKey = accessKey
+	}
+	if secretKey := os.Getenv("CHAT_S3_SECRET_KEY"); secretKey != "" {
+		config.S3SecretKey = secretKey
+	}
+	if publicURL := os.Getenv("CHAT_S3_PUBLIC_URL"); publicURL != "" {
+		config.S3PublicURL = publicURL
+	}
+	
+	if enableOfflineMailbox := os.Getenv("CHAT_ENABLE_OFFLINE_MAILBOX"); enableOfflineMailbox != "" {
+		config.EnableOfflineMailbox = enableOfflineMailbox == "true"
+	}
+	
+	if enableSessionResume := os.Getenv("CHAT_ENABLE_SESSION_RESUME"); enableSessionResume != "" {
+		config.EnableSessionResume = enableSessionResume == "true"
+	}
+	
+	if requireAccounts := os.Getenv("CHAT_REQUIRE_ACCOUNTS"); requireAccounts != "" {
+		config.RequireAccounts = requireAccounts == "true"
+	}
+	
+	if minPassword := os.Getenv("CHAT_MIN_PASSWORD_LENGTH"); minPassword != "" {
+		if length, err := strconv.Atoi(minPassword); err == nil && length > 0 {
+			config.MinPasswordLength = length
+		}
+	}
+	
+	if sessionTTL := os.Getenv("CHAT_ACCOUNT_SESSION_TTL"); sessionTTL != "" {
+		if ttl, err := time.ParseDuration(sessionTTL); err == nil && ttl > 0 {
+			config.AccountSessionTTL = ttl
+		}
+	}
+	
+	if failureLimit := os.Getenv("CHAT_LOGIN_FAILURE_LIMIT"); failureLimit != "" {
+		if val, err := strconv.Atoi(failureLimit); err == nil && val > 0 {
+			config.LoginFailureLimit = val
+		}
+	}
+	
+	if ipFailureLimit := os.Getenv("CHAT_LOGIN_IP_FAILURE_LIMIT"); ipFailureLimit != "" {
+		if val, err := strconv.Atoi(ipFailureLimit); err == nil && val > 0 {
+			config.LoginIPFailureLimit = val
+		}
+	}
+	
+	if lockoutBase := os.Getenv("CHAT_LOGIN_LOCKOUT_BASE"); lockoutBase != "" {
+		if val, err := time.ParseDuration(lockoutBase); err == nil && val > 0 {
+			config.LoginLockoutBase = val
+		}
+	}
+	
+	if lockoutMax := os.Getenv("CHAT_LOGIN_LOCKOUT_MAX"); lockoutMax != "" {
+		if val, err := time.ParseDuration(lockoutMax); err == nil && val > 0 {
+			config.LoginLockoutMax = val
+		}
+	}
+	
+	if resumeGrace := os.Getenv("CHAT_SESSION_RESUME_GRACE"); resumeGrace != "" {
+		if grace, err := time.ParseDuration(resumeGrace); err == nil && grace > 0 {
+			config.SessionResumeGrace = grace
+		}
+	}
+	
+	if maxResumeMessages := os.Getenv("CHAT_MAX_RESUME_MESSAGES"); maxResumeMessages != "" {
+		if limit, err := strconv.Atoi(maxResumeMessages); err == nil && limit > 0 {
+			config.MaxResumeMessages = limit
+		}
+	}
+	
+	if enableNotifications := os.Getenv("CHAT_ENABLE_NOTIFICATIONS"); enableNotifications != "" {
+		config.EnableNotifications = enableNotifications == "true"
+	}
+	
+	if notificationTTL := os.Getenv("CHAT_NOTIFICATION_TTL"); notificationTTL != "" {
+		if ttl, err := time.ParseDuration(notificationTTL); err == nil && ttl > 0 {
+			config.NotificationTTL = ttl
+		}
+	}
+	
+	if maxNotifications := os.Getenv("CHAT_MAX_NOTIFICATIONS"); maxNotifications != "" {
+		if limit, err := strconv.Atoi(maxNotifications); err == nil && limit > 0 {
+			config.MaxNotifications = limit
+		}
+	}
+	
+	if messageRetention := os.Getenv("CHAT_MESSAGE_RETENTION"); messageRetention != "" {
+		if retention, err := time.ParseDuration(messageRetention); err == nil && retention >= 0 {
+			config.MessageRetention = retention
+		}
+	}
+	
+	if retentionInterval := os.Getenv("CHAT_RETENTION_CHECK_INTERVAL"); retentionInterval != "" {
+		if interval, err := time.ParseDuration(retentionInterval); err == nil && interval > 0 {
+			config.RetentionCheckInterval = interval
+		}
+	}
+	
+	if retentionBatch := os.Getenv("CHAT_RETENTION_BATCH_SIZE"); retentionBatch != "" {
+		if size, err := strconv.Atoi(retentionBatch); err == nil && size > 0 {
+			config.RetentionBatchSize = size
+		}
+	}
+	
+	if retentionArchive := os.Getenv("CHAT_RETENTION_ARCHIVE"); retentionArchive != "" {
+		config.RetentionArchive = retentionArchive == "true"
+	}
+	
+	if enableQuarantine := os.Getenv("CHAT_ENABLE_QUARANTINE"); enableQuarantine != "" {
+		config.EnableQuarantine = enableQuarantine == "true"
+	}
+	
+	if violationLimit := os.Getenv("CHAT_PROTOCOL_VIOLATION_LIMIT"); violationLimit != "" {
+		if limit, err := strconv.Atoi(violationLimit); err == nil && limit > 0 {
+			config.ProtocolViolationLimit = limit
+		}
+	}
+	
+	if enableSampling := os.Getenv("CHAT_ENABLE_ROOM_SAMPLING"); enableSampling != "" {
+		config.EnableRoomSampling = enableSampling == "true"
+	}
+	
+	if threshold := os.Getenv("CHAT_SAMPLING_THRESHOLD"); threshold != "" {
+		if val, err := strconv.Atoi(threshold); err == nil && val > 0 {
+			config.SamplingThreshold = val
+		}
+	}
+	
+	if activeWindow := os.Getenv("CHAT_SAMPLING_ACTIVE_WINDOW"); activeWindow != "" {
+		if val, err := time.ParseDuration(activeWindow); err == nil {
+			config.SamplingActiveWindow = val
+		}
+	}
+	
+	if digestInterval := os.Getenv("CHAT_DIGEST_INTERVAL"); digestInterval != "" {
+		if val, err := time.ParseDuration(digestInterval); err == nil {
+			config.DigestInterval = val
+		}
+	}
+	
+	if enableFrameLimit := os.Getenv("CHAT_ENABLE_FRAME_RATE_LIMIT"); enableFrameLimit != "" {
+		config.EnableFrameRateLimit = enableFrameLimit == "true"
+	}
+	
+	if frameLimit := os.Getenv("CHAT_FRAME_RATE_LIMIT"); frameLimit != "" {
+		if val, err := strconv.Atoi(frameLimit); err == nil {
+			config.FrameRateLimit = val
+		}
+	}
+	
+	if expensiveLimit := os.Getenv("CHAT_EXPENSIVE_FRAME_RATE_LIMIT"); expensiveLimit != "" {
+		if val, err := strconv.Atoi(expensiveLimit); err == nil {
+			config.ExpensiveFrameRateLimit = val
+		}
+	}
+	
+	if frameWindow := os.Getenv("CHAT_FRAME_RATE_WINDOW"); frameWindow != "" {
+		if val, err := time.ParseDuration(frameWindow); err == nil {
+			config.FrameRateWindow = val
+		}
+	}
+	
+	if enableChurn := os.Getenv("CHAT_ENABLE_CHURN_LIMIT"); enableChurn != "" {
+		config.EnableChurnLimit = enableChurn == "true"
+	}
+	
+	if switchLimit := os.Getenv("CHAT_ROOM_SWITCH_LIMIT"); switchLimit != "" {
+		if val, err := strconv.Atoi(switchLimit); err == nil {
+			config.RoomSwitchLimit = val
+		}
+	}
+	
+	if switchWindow := os.Getenv("CHAT_ROOM_SWITCH_WINDOW"); switchWindow != "" {
+		if val, err := time.ParseDuration(switchWindow); err == nil {
+			config.RoomSwitchWindow = val
+		}
+	}
+
+	if logLevel := os.Getenv("CHAT_LOG_LEVEL"); logLevel != "" {
+		config.LogLevel = logLevel
+	}
+
+	if logFormat := os.Getenv("CHAT_LOG_FORMAT"); logFormat != "" {
+		config.LogFormat = logFormat
+	}
 
 	// Feature flags
 	if enableMetrics := os.Getenv("CHAT_ENABLE_METRICS"); enableMetrics != "" {
@@ -497,7 +1661,29 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 		config.EnableRateLimit = enableRateLimit == "true"
 	}
 
+	if loadTestMode := os.Getenv("CHAT_LOAD_TEST_MODE"); loadTestMode != "" {
+		config.LoadTestMode = loadTestMode == "true"
+	}
+
 	// Database settings
+	if backend := os.Getenv("CHAT_STORAGE_BACKEND"); backend != "" {
+		config.StorageBackend = backend
+	}
+	
+	if postgresDSN := os.Getenv("CHAT_POSTGRES_DSN"); postgresDSN != "" {
+		config.PostgresDSN = postgresDSN
+	}
+	
+	if postgresMaxConns := os.Getenv("CHAT_POSTGRES_MAX_CONNS"); postgresMaxConns != "" {
+		if val, err := strconv.Atoi(postgresMaxConns); err == nil {
+			config.PostgresMaxConns = val
+		}
+	}
+	
+	if sqlitePath := os.Getenv("CHAT_SQLITE_PATH"); sqlitePath != "" {
+		config.SQLitePath = sqlitePath
+	}
+	
 	if enableMongo := os.Getenv("CHAT_ENABLE_MONGODB"); enableMongo != "" {
 		config.EnableMongoDB = enableMongo == "true"
 	}
@@ -521,6 +1707,127 @@ func (cl *ConfigLoader) loadFromEnv(config *ServerConfig) {
 			config.MongoPingTimeout = val
 		}
 	}
+
+	// Admin settings
+	if admins := os.Getenv("CHAT_ADMIN_USERNAMES"); admins != "" {
+		config.AdminUsernames = strings.Split(admins, ",")
+	}
+	
+	if apiKeys := os.Getenv("CHAT_API_KEYS"); apiKeys != "" {
+		config.APIKeys = strings.Split(apiKeys, ",")
+	}
+	
+	if subprotocol := os.Getenv("CHAT_DEFAULT_SUBPROTOCOL"); subprotocol != "" {
+		config.DefaultSubprotocol = subprotocol
+	}
+	
+	if enableMsgpack := os.Getenv("CHAT_ENABLE_MSGPACK"); enableMsgpack != "" {
+		config.EnableMsgpack = enableMsgpack == "true"
+	}
+	
+	if origins := os.Getenv("CHAT_ALLOWED_ORIGINS"); origins != "" {
+		config.AllowedOrigins = strings.Split(origins, ",")
+	}
+
+	if proxies := os.Getenv("CHAT_TRUSTED_PROXIES"); proxies != "" {
+		config.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	// Search settings
+	if enableSearch := os.Getenv("CHAT_ENABLE_SEARCH_INDEX"); enableSearch != "" {
+		config.EnableSearchIndex = enableSearch == "true"
+	}
+	
+	if esURL := os.Getenv("CHAT_ELASTICSEARCH_URL"); esURL != "" {
+		config.ElasticsearchURL = esURL
+	}
+
+	// Cluster settings
+	if nodeID := os.Getenv("CHAT_NODE_ID"); nodeID != "" {
+		config.NodeID = nodeID
+	}
+	
+	if enableCluster := os.Getenv("CHAT_ENABLE_CLUSTER"); enableCluster != "" {
+		config.EnableCluster = enableCluster == "true"
+	}
+	
+	if addr := os.Getenv("CHAT_CLUSTER_ADDR"); addr != "" {
+		config.ClusterAddr = addr
+	}
+	
+	if advertise := os.Getenv("CHAT_CLUSTER_ADVERTISE_ADDR"); advertise != "" {
+		config.ClusterAdvertiseAddr = advertise
+	}
+	
+	if peers := os.Getenv("CHAT_CLUSTER_PEERS"); peers != "" {
+		config.ClusterPeers = strings.Split(peers, ",")
+	}
+	
+	if secret := os.Getenv("CHAT_CLUSTER_SECRET"); secret != "" {
+		config.ClusterSecret = secret
+	}
+	
+	if interval := os.Getenv("CHAT_CLUSTER_GOSSIP_INTERVAL"); interval != "" {
+		if val, err := time.ParseDuration(interval); err == nil && val > 0 {
+			config.ClusterGossipInterval = val
+		}
+	}
+	
+	if timeout := os.Getenv("CHAT_CLUSTER_NODE_TIMEOUT"); timeout != "" {
+		if val, err := time.ParseDuration(timeout); err == nil && val > 0 {
+			config.ClusterNodeTimeout = val
+		}
+	}
+	
+	if region := os.Getenv("CHAT_REGION"); region != "" {
+		config.Region = region
+	}
+	
+	if publicURL := os.Getenv("CHAT_PUBLIC_URL"); publicURL != "" {
+		config.PublicURL = publicURL
+	}
+
+	// gRPC integration API
+	if enableGRPC := os.Getenv("CHAT_ENABLE_GRPC"); enableGRPC != "" {
+		config.EnableGRPC = enableGRPC == "true"
+	}
+
+	if addr := os.Getenv("CHAT_GRPC_ADDR"); addr != "" {
+		config.GRPCAddr = addr
+	}
+
+	// TLS settings
+	if certFile := os.Getenv("CHAT_TLS_CERT_FILE"); certFile != "" {
+		config.TLSCertFile = certFile
+	}
+
+	if keyFile := os.Getenv("CHAT_TLS_KEY_FILE"); keyFile != "" {
+		config.TLSKeyFile = keyFile
+	}
+
+	if autocert := os.Getenv("CHAT_TLS_AUTOCERT"); autocert != "" {
+		config.TLSAutocert = autocert == "true"
+	}
+
+	if hosts := os.Getenv("CHAT_TLS_AUTOCERT_HOSTS"); hosts != "" {
+		config.TLSAutocertHosts = strings.Split(hosts, ",")
+	}
+
+	if email := os.Getenv("CHAT_TLS_AUTOCERT_EMAIL"); email != "" {
+		config.TLSAutocertEmail = email
+	}
+
+	if cacheDir := os.Getenv("CHAT_TLS_AUTOCERT_CACHE_DIR"); cacheDir != "" {
+		config.TLSAutocertCacheDir = cacheDir
+	}
+
+	if minVersion := os.Getenv("CHAT_TLS_MIN_VERSION"); minVersion != "" {
+		config.TLSMinVersion = minVersion
+	}
+
+	if redirectAddr := os.Getenv("CHAT_TLS_REDIRECT_ADDR"); redirectAddr != "" {
+		config.TLSRedirectAddr = redirectAddr
+	}
 }
 
 // SaveConfig saves current configuration to file
@@ -541,30 +1848,32 @@ func (cl *ConfigLoader) SaveConfig(config *ServerConfig) error {
 	return nil
 }
 
-// WatchConfig watches for configuration file changes (basic implementation)
-func (cl *ConfigLoader) WatchConfig(callback func(*ServerConfig)) error {
+// WatchConfig polls the config file and calls callback when it changes, until ctx is cancelled (basic implementation)
+func (cl *ConfigLoader) WatchConfig(ctx context.Context, callback func(*ServerConfig)) error {
 	// This is a basic implementation - in production you might use fsnotify
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		
-		var lastModTime time.Time
-		if stat, err := os.Stat(cl.configPath); err == nil {
-			lastModTime = stat.ModTime()
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	var lastModTime time.Time
+	if stat, err := os.Stat(cl.configPath); err == nil {
+		lastModTime = stat.ModTime()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
 		}
-		
-		for range ticker.C {
-			if stat, err := os.Stat(cl.configPath); err == nil {
-				if stat.ModTime().After(lastModTime) {
-					lastModTime = stat.ModTime()
-					if newConfig, err := cl.LoadConfig(); err == nil {
-						fmt.Println("🔄 Configuration file changed, reloading...")
-						callback(newConfig)
-					}
+
+		if stat, err := os.Stat(cl.configPath); err == nil {
+			if stat.ModTime().After(lastModTime) {
+				lastModTime = stat.ModTime()
+				if newConfig, err := cl.LoadConfig(); err == nil {
+					fmt.Println("🔄 Configuration file changed, reloading...")
+					callback(newConfig)
 				}
 			}
 		}
-	}()
-	
-	return nil
-}
\ No newline at end of file
+	}
+}
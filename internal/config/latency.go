@@ -0,0 +1,154 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// Message path stages recorded by the LatencyRecorder, in pipeline order
+const (
+	StageRead     = "read"     // อ่าน frame จนถึงเริ่มประมวลผล (parse, auth, rate limit)
+	StageValidate = "validate" // ตรวจสอบเนื้อหาข้อความ
+	StagePersist  = "persist"  // บันทึกลง database
+	StageEnqueue  = "enqueue"  // ส่งเข้า broadcast channel
+	StageFanout   = "fanout"   // รอใน broadcast channel + กระจายไปยัง Send ของผู้รับ
+	StageWrite    = "write"    // เขียน frame ลง socket ของผู้รับ
+)
+
+// latencyStages lists the stages in pipeline order for reporting
+var latencyStages = []string{StageRead, StageValidate, StagePersist, StageEnqueue, StageFanout, StageWrite}
+
+// latencyBuckets is the number of histogram buckets; bucket i holds samples up to latencyBase << i
+const latencyBuckets = 24
+
+// latencyBase is the upper bound of the first histogram bucket (ช่วงสุดท้ายครอบคลุม ~84 วินาที)
+const latencyBase = 10 * time.Microsecond
+
+// StageLatency holds latency percentiles for one stage of the message path
+type StageLatency struct {
+	Stage string  `json:"stage"`
+	Count int64   `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// latencyHistogram is an exponential-bucket histogram of durations
+type latencyHistogram struct {
+	buckets [latencyBuckets]int64
+	count   int64
+	max     time.Duration
+}
+
+// LatencyRecorder records per-stage timings of the inbound message path into histograms
+type LatencyRecorder struct {
+	stages map[string]*latencyHistogram
+	mutex  sync.Mutex
+}
+
+// NewLatencyRecorder creates a new latency recorder
+func NewLatencyRecorder() *LatencyRecorder {
+	recorder := &LatencyRecorder{stages: make(map[string]*latencyHistogram)}
+	for _, stage := range latencyStages {
+		recorder.stages[stage] = &latencyHistogram{}
+	}
+	return recorder
+}
+
+// Observe records a duration for a stage (safe to call on a nil recorder)
+func (r *LatencyRecorder) Observe(stage string, d time.Duration) {
+	if r == nil {
+		return
+	}
+
+	bucket := 0
+	for bucket < latencyBuckets-1 && d > latencyBase<<uint(bucket) {
+		bucket++
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	histogram, exists := r.stages[stage]
+	if !exists {
+		histogram = &latencyHistogram{}
+		r.stages[stage] = histogram
+	}
+	histogram.buckets[bucket]++
+	histogram.count++
+	if d > histogram.max {
+		histogram.max = d
+	}
+}
+
+// ObserveSince records the time elapsed since start for a stage
+func (r *LatencyRecorder) ObserveSince(stage string, start time.Time) {
+	if r == nil {
+		return
+	}
+	r.Observe(stage, time.Since(start))
+}
+
+// Snapshot returns p50/p95/p99 per stage in pipeline order.
+// ค่า percentile ประมาณจาก bucket จึงคลาดเคลื่อนได้ไม่เกินความกว้างของ bucket
+func (r *LatencyRecorder) Snapshot() []StageLatency {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	snapshot := make([]StageLatency, 0, len(latencyStages))
+	for _, stage := range latencyStages {
+		snapshot = append(snapshot, r.stageLocked(stage))
+	}
+	return snapshot
+}
+
+// Stage returns the percentiles of a single stage, including stages recorded outside the message path
+// (เช่น delivery และ ack ของ cmd/loadgen)
+func (r *LatencyRecorder) Stage(stage string) StageLatency {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.stageLocked(stage)
+}
+
+// stageLocked summarizes one stage (assumes lock is held)
+func (r *LatencyRecorder) stageLocked(stage string) StageLatency {
+	entry := StageLatency{Stage: stage}
+	histogram, exists := r.stages[stage]
+	if !exists || histogram.count == 0 {
+		return entry
+	}
+	entry.Count = histogram.count
+	entry.P50Ms = histogram.percentileMs(0.50)
+	entry.P95Ms = histogram.percentileMs(0.95)
+	entry.P99Ms = histogram.percentileMs(0.99)
+	entry.MaxMs = float64(histogram.max.Microseconds()) / 1000
+	return entry
+}
+
+// percentileMs estimates the p-th percentile in milliseconds by interpolating within buckets
+func (h *latencyHistogram) percentileMs(p float64) float64 {
+	target := int64(p*float64(h.count) + 0.5)
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative int64
+	for i, count := range h.buckets {
+		if cumulative+count >= target {
+			// ประมาณค่าภายใน bucket แบบเส้นตรงระหว่างขอบล่างและขอบบน
+			upper := latencyBase << uint(i)
+			lower := upper / 2
+			if i == 0 {
+				lower = 0
+			}
+			if upper > h.max {
+				upper = h.max
+			}
+			value := lower + time.Duration(float64(upper-lower)*float64(target-cumulative)/float64(count))
+			return float64(value.Microseconds()) / 1000
+		}
+		cumulative += count
+	}
+	return float64(h.max.Microseconds()) / 1000
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -33,8 +34,12 @@ func (cm *ConfigManager) Initialize() error {
 	cm.config = config
 	cm.mutex.Unlock()
 
-	// Start watching for changes
-	return cm.loader.WatchConfig(cm.onConfigChange)
+	return nil
+}
+
+// Watch reloads the configuration when the file changes, until ctx is cancelled
+func (cm *ConfigManager) Watch(ctx context.Context) error {
+	return cm.loader.WatchConfig(ctx, cm.onConfigChange)
 }
 
 // GetConfig returns current configuration (thread-safe)
@@ -52,10 +57,12 @@ func (cm *ConfigManager) UpdateConfig(updates map[string]interface{}) error {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
-	// Apply updates to current config
-	if err := cm.applyUpdates(updates); err != nil {
+	// Apply updates to a copy first so a bad key or value leaves the current config untouched
+	updated := *cm.config
+	if err := applyUpdates(&updated, updates); err != nil {
 		return fmt.Errorf("failed to apply config updates: %v", err)
 	}
+	cm.config = &updated
 
 	// Save to file
 	if err := cm.loader.SaveConfig(cm.config); err != nil {
@@ -92,65 +99,86 @@ func (cm *ConfigManager) notifyCallbacks() {
 	}
 }
 
-// applyUpdates applies configuration updates
-func (cm *ConfigManager) applyUpdates(updates map[string]interface{}) error {
+// applyUpdates applies configuration updates; a value of the wrong type is an error
+func applyUpdates(config *ServerConfig, updates map[string]interface{}) error {
 	for key, value := range updates {
+		var ok bool
 		switch key {
 		case "max_connections":
-			if val, ok := value.(float64); ok {
-				cm.config.MaxConnections = int(val)
-			}
+			ok = setPositiveInt(&config.MaxConnections, value)
 		case "max_rooms":
-			if val, ok := value.(float64); ok {
-				cm.config.MaxRooms = int(val)
-			}
+			ok = setPositiveInt(&config.MaxRooms, value)
 		case "max_users_per_room":
-			if val, ok := value.(float64); ok {
-				cm.config.MaxUsersPerRoom = int(val)
-			}
+			ok = setPositiveInt(&config.MaxUsersPerRoom, value)
+		case "max_room_capacity":
+			ok = setPositiveInt(&config.MaxRoomCapacity, value)
 		case "heartbeat_interval":
-			if val, ok := value.(string); ok {
-				if duration, err := time.ParseDuration(val); err == nil {
-					cm.config.HeartbeatInterval = duration
-				}
-			}
+			ok = setDuration(&config.HeartbeatInterval, value)
 		case "max_message_length":
-			if val, ok := value.(float64); ok {
-				cm.config.MaxMessageLength = int(val)
-			}
+			ok = setPositiveInt(&config.MaxMessageLength, value)
 		case "max_username_length":
-			if val, ok := value.(float64); ok {
-				cm.config.MaxUsernameLength = int(val)
-			}
+			ok = setPositiveInt(&config.MaxUsernameLength, value)
 		case "rate_limit_messages":
-			if val, ok := value.(float64); ok {
-				cm.config.RateLimitMessages = int(val)
-			}
+			ok = setPositiveInt(&config.RateLimitMessages, value)
 		case "rate_limit_window":
-			if val, ok := value.(string); ok {
-				if duration, err := time.ParseDuration(val); err == nil {
-					cm.config.RateLimitWindow = duration
-				}
-			}
+			ok = setDuration(&config.RateLimitWindow, value)
 		case "enable_metrics":
-			if val, ok := value.(bool); ok {
-				cm.config.EnableMetrics = val
-			}
+			config.EnableMetrics, ok = value.(bool)
 		case "enable_health_check":
-			if val, ok := value.(bool); ok {
-				cm.config.EnableHealthCheck = val
-			}
+			config.EnableHealthCheck, ok = value.(bool)
 		case "enable_rate_limit":
-			if val, ok := value.(bool); ok {
-				cm.config.EnableRateLimit = val
-			}
+			config.EnableRateLimit, ok = value.(bool)
 		default:
 			return fmt.Errorf("unknown configuration key: %s", key)
 		}
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %v", key, value)
+		}
 	}
 	return nil
 }
 
+// setPositiveInt sets an int from a JSON number greater than zero
+func setPositiveInt(field *int, value interface{}) bool {
+	val, ok := value.(float64)
+	if !ok || val <= 0 || val != float64(int(val)) {
+		return false
+	}
+	*field = int(val)
+	return true
+}
+
+// setDuration sets a duration from a positive duration string such as "30s"
+func setDuration(field *time.Duration, value interface{}) bool {
+	val, ok := value.(string)
+	if !ok {
+		return false
+	}
+	duration, err := time.ParseDuration(val)
+	if err != nil || duration <= 0 {
+		return false
+	}
+	*field = duration
+	return true
+}
+
+// ApplyRuntime copies the settings that UpdateConfig can change into a live config.
+// ค่าอื่น (port, database ...) ต้อง restart จึงไม่ถูกคัดลอก
+func (c *ServerConfig) ApplyRuntime(updated *ServerConfig) {
+	c.MaxConnections = updated.MaxConnections
+	c.MaxRooms = updated.MaxRooms
+	c.MaxUsersPerRoom = updated.MaxUsersPerRoom
+	c.MaxRoomCapacity = updated.MaxRoomCapacity
+	c.HeartbeatInterval = updated.HeartbeatInterval
+	c.MaxMessageLength = updated.MaxMessageLength
+	c.MaxUsernameLength = updated.MaxUsernameLength
+	c.RateLimitMessages = updated.RateLimitMessages
+	c.RateLimitWindow = updated.RateLimitWindow
+	c.EnableMetrics = updated.EnableMetrics
+	c.EnableHealthCheck = updated.EnableHealthCheck
+	c.EnableRateLimit = updated.EnableRateLimit
+}
+
 // GetConfigSummary returns a summary of current configuration
 func (cm *ConfigManager) GetConfigSummary() map[string]interface{} {
 	config := cm.GetConfig()
@@ -161,6 +189,7 @@ func (cm *ConfigManager) GetConfigSummary() map[string]interface{} {
 			"max_connections":     config.MaxConnections,
 			"max_rooms":          config.MaxRooms,
 			"max_users_per_room": config.MaxUsersPerRoom,
+			"max_room_capacity":  config.MaxRoomCapacity,
 		},
 		"timeouts": map[string]interface{}{
 			"heartbeat_interval": config.HeartbeatInterval.String(),
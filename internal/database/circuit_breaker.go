@@ -0,0 +1,135 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned by repositories that fail fast while the breaker is open
+var ErrUnavailable = errors.New("database temporarily unavailable")
+
+// BreakerState represents the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed - ฐานข้อมูลทำงานปกติ ส่ง request ได้
+	BreakerClosed BreakerState = iota
+	// BreakerOpen - ฐานข้อมูลล่ม ไม่ส่ง request จนกว่าจะครบ cooldown
+	BreakerOpen
+	// BreakerHalfOpen - ครบ cooldown แล้ว ยอมให้ลองส่ง request เพื่อทดสอบ
+	BreakerHalfOpen
+)
+
+// String returns a human readable state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker tracks database failures and short-circuits calls during an outage
+type CircuitBreaker struct {
+	state        BreakerState
+	failures     int
+	threshold    int
+	cooldown     time.Duration
+	openedAt     time.Time
+	mutex        sync.Mutex
+	stateChanges []func(from, to BreakerState)
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		state:     BreakerClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// OnStateChange registers a callback invoked whenever the breaker changes state
+func (cb *CircuitBreaker) OnStateChange(callback func(from, to BreakerState)) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.stateChanges = append(cb.stateChanges, callback)
+}
+
+// Allow reports whether a call to the database should be attempted
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		// ครบ cooldown แล้ว ลองส่ง request อีกครั้ง
+		if time.Since(cb.openedAt) >= cb.cooldown {
+			cb.setState(BreakerHalfOpen)
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordSuccess records a successful database call
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures = 0
+	if cb.state != BreakerClosed {
+		cb.setState(BreakerClosed)
+	}
+}
+
+// RecordFailure records a failed database call
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures++
+
+	// ถ้าทดสอบตอน half-open แล้วยังล้มเหลว ให้กลับไป open ทันที
+	if cb.state == BreakerHalfOpen || (cb.state == BreakerClosed && cb.failures >= cb.threshold) {
+		cb.openedAt = time.Now()
+		cb.setState(BreakerOpen)
+	}
+}
+
+// State returns the current breaker state
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.state
+}
+
+// IsOpen reports whether calls should fail fast. ต่างจาก Allow ตรงที่ไม่เปลี่ยนเป็น half-open เอง
+// (health monitor เป็นผู้ทดสอบและปิด breaker) จึงใช้กับ repository ที่แยกไม่ออกว่า error มาจากผู้ใช้หรือฐานข้อมูล
+func (cb *CircuitBreaker) IsOpen() bool {
+	return cb.State() == BreakerOpen
+}
+
+// setState changes state and notifies callbacks (assumes lock is held)
+func (cb *CircuitBreaker) setState(to BreakerState) {
+	from := cb.state
+	if from == to {
+		return
+	}
+	cb.state = to
+
+	for _, callback := range cb.stateChanges {
+		go callback(from, to)
+	}
+}
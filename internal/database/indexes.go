@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MissingIndexes returns the expected indexes that do not exist yet, as "collection.index_name"
+func (db *MongoDB) MissingIndexes() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var missing []string
+	for _, spec := range indexSpecs() {
+		cursor, err := db.GetCollection(spec.collection).Indexes().List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s indexes: %v", spec.label, err)
+		}
+		var existing []struct {
+			Name string `bson:"name"`
+		}
+		if err := cursor.All(ctx, &existing); err != nil {
+			return nil, fmt.Errorf("failed to decode %s indexes: %v", spec.label, err)
+		}
+
+		names := make(map[string]bool, len(existing))
+		for _, index := range existing {
+			names[index.Name] = true
+		}
+		for _, model := range spec.indexes {
+			name := indexName(model.Keys)
+			if !names[name] {
+				missing = append(missing, spec.collection+"."+name)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// indexName returns the default name MongoDB gives an index, e.g. "room_name_1_timestamp_-1"
+func indexName(keys interface{}) string {
+	document, ok := keys.(bson.D)
+	if !ok {
+		return fmt.Sprint(keys)
+	}
+	parts := make([]string, 0, len(document)*2)
+	for _, element := range document {
+		parts = append(parts, element.Key, fmt.Sprint(element.Value))
+	}
+	return strings.Join(parts, "_")
+}
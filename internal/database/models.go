@@ -3,7 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,6 +19,11 @@ type MongoConfig struct {
 	PingTimeout    time.Duration `json:"ping_timeout"`
 	MaxPoolSize    uint64        `json:"max_pool_size"`
 	MinPoolSize    uint64        `json:"min_pool_size"`
+
+	// Circuit breaker settings
+	BreakerThreshold    int           `json:"breaker_threshold"`
+	BreakerCooldown     time.Duration `json:"breaker_cooldown"`
+	HealthCheckInterval time.Duration `json:"health_check_interval"`
 }
 
 // DefaultMongoConfig returns default MongoDB configuration
@@ -30,6 +35,10 @@ func DefaultMongoConfig() *MongoConfig {
 		PingTimeout:    5 * time.Second,
 		MaxPoolSize:    100,
 		MinPoolSize:    5,
+
+		BreakerThreshold:    3,
+		BreakerCooldown:     15 * time.Second,
+		HealthCheckInterval: 10 * time.Second,
 	}
 }
 
@@ -38,6 +47,7 @@ type MongoDB struct {
 	client   *mongo.Client
 	database *mongo.Database
 	config   *MongoConfig
+	breaker  *CircuitBreaker
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -72,9 +82,19 @@ func NewMongoDB(config *MongoConfig) (*MongoDB, error) {
 		client:   client,
 		database: client.Database(config.Database),
 		config:   config,
+		breaker:  NewCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
 	}
 
-	log.Printf("✅ Connected to MongoDB: %s/%s", config.URI, config.Database)
+	db.breaker.OnStateChange(func(from, to BreakerState) {
+		switch to {
+		case BreakerOpen:
+			slog.Error("🚨 ALERT: MongoDB unavailable, switching to degraded mode", "breaker_from", from.String(), "breaker_to", to.String())
+		case BreakerClosed:
+			slog.Info("✅ MongoDB recovered", "breaker_from", from.String(), "breaker_to", to.String())
+		}
+	})
+
+	slog.Info("✅ Connected to MongoDB", "uri", config.URI, "database", config.Database)
 	return db, nil
 }
 
@@ -92,75 +112,225 @@ func (db *MongoDB) Close() error {
 		return fmt.Errorf("failed to disconnect from MongoDB: %v", err)
 	}
 
-	log.Println("✅ Disconnected from MongoDB")
+	slog.Info("✅ Disconnected from MongoDB")
 	return nil
 }
 
-// CreateIndexes creates necessary indexes for collections
-func (db *MongoDB) CreateIndexes() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// indexSpec lists the indexes of one collection
+type indexSpec struct {
+	collection string
+	label      string // ใช้ในข้อความ error
+	indexes    []mongo.IndexModel
+}
 
-	// User indexes
-	userCollection := db.GetCollection("users")
-	userIndexes := []mongo.IndexModel{
+// indexSpecs returns every index the server relies on; CreateIndexes creates them and MissingIndexes checks them
+func indexSpecs() []indexSpec {
+	return []indexSpec{
+		// User indexes
 		{
-			Keys:    bson.D{{Key: "username", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			collection: "users",
+			label:      "user",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "conn_id", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "joined_at", Value: 1}},
+				},
+			},
 		},
+		// Room indexes
 		{
-			Keys:    bson.D{{Key: "conn_id", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			collection: "rooms",
+			label:      "room",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "name", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "is_active", Value: 1}},
+				},
+			},
 		},
-	}
-
-	if _, err := userCollection.Indexes().CreateMany(ctx, userIndexes); err != nil {
-		return fmt.Errorf("failed to create user indexes: %v", err)
-	}
-
-	// Room indexes
-	roomCollection := db.GetCollection("rooms")
-	roomIndexes := []mongo.IndexModel{
+		// Message indexes
 		{
-			Keys:    bson.D{{Key: "name", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			collection: "messages",
+			label:      "message",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "room_name", Value: 1},
+						{Key: "timestamp", Value: -1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "username", Value: 1},
+						{Key: "timestamp", Value: -1},
+					},
+				},
+				{
+					Keys: bson.D{{Key: "content", Value: "text"}},
+				},
+				{
+					Keys:    bson.D{{Key: "parent_id", Value: 1}, {Key: "timestamp", Value: 1}},
+					Options: options.Index().SetSparse(true),
+				},
+				{
+					Keys:    bson.D{{Key: "room_name", Value: 1}, {Key: "seq", Value: 1}},
+					Options: options.Index().SetSparse(true),
+				},
+				{
+					// retention ของค่า default ค้นตามเวลาข้ามทุกห้อง
+					Keys: bson.D{{Key: "timestamp", Value: 1}},
+				},
+			},
 		},
+		// Archived message indexes (ข้อความที่พ้น retention เมื่อเปิด retention_archive)
 		{
-			Keys: bson.D{{Key: "is_active", Value: 1}},
+			collection: "messages_archive",
+			label:      "archived message",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "room_name", Value: 1},
+						{Key: "timestamp", Value: -1},
+					},
+				},
+			},
 		},
-	}
-
-	if _, err := roomCollection.Indexes().CreateMany(ctx, roomIndexes); err != nil {
-		return fmt.Errorf("failed to create room indexes: %v", err)
-	}
-
-	// Message indexes
-	messageCollection := db.GetCollection("messages")
-	messageIndexes := []mongo.IndexModel{
+		// Thread indexes (หนึ่ง thread ต่อข้อความต้นทาง)
 		{
-			Keys: bson.D{
-				{Key: "room_name", Value: 1},
-				{Key: "timestamp", Value: -1},
+			collection: "threads",
+			label:      "thread",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "parent_id", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
 			},
 		},
+		// Room timeline event indexes (seq ไม่ซ้ำภายในห้อง)
 		{
-			Keys: bson.D{
-				{Key: "username", Value: 1},
-				{Key: "timestamp", Value: -1},
+			collection: "room_events",
+			label:      "room event",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "room_name", Value: 1}, {Key: "seq", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
 			},
 		},
+		// Direct message indexes
 		{
-			Keys: bson.D{{Key: "content", Value: "text"}},
+			collection: "direct_messages",
+			label:      "direct message",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "conversation_id", Value: 1},
+						{Key: "timestamp", Value: -1},
+					},
+				},
+			},
+		},
+		// Read receipt indexes (หนึ่ง receipt ต่อผู้ใช้ต่อห้อง)
+		{
+			collection: "read_receipts",
+			label:      "read receipt",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "username", Value: 1}, {Key: "room_name", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+		// Offline mailbox indexes (ข้อความค้างเกิน 30 วันถูกลบอัตโนมัติ)
+		{
+			collection: "pending_messages",
+			label:      "pending message",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "recipient", Value: 1},
+						{Key: "timestamp", Value: 1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "timestamp", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+				},
+			},
+		},
+		// Notification indexes (ลบอัตโนมัติเมื่อถึง expires_at)
+		{
+			collection: "notifications",
+			label:      "notification",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "user_id", Value: 1},
+						{Key: "created_at", Value: -1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			},
+		},
+		// Account indexes (_id คือ account ID จาก username จึงไม่ต้องมี unique index ของ username)
+		{
+			collection: "accounts",
+			label:      "account",
+			indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{{Key: "username", Value: 1}},
+				},
+			},
+		},
+		// Account session indexes (ลบอัตโนมัติเมื่อถึง expires_at)
+		{
+			collection: "account_sessions",
+			label:      "account session",
+			indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "token_hash", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "account_id", Value: 1}},
+				},
+				{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			},
 		},
 	}
+}
+
+// CreateIndexes creates necessary indexes for collections
+func (db *MongoDB) CreateIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	if _, err := messageCollection.Indexes().CreateMany(ctx, messageIndexes); err != nil {
-		return fmt.Errorf("failed to create message indexes: %v", err)
+	for _, spec := range indexSpecs() {
+		if _, err := db.GetCollection(spec.collection).Indexes().CreateMany(ctx, spec.indexes); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %v", spec.label, err)
+		}
 	}
 
-	log.Println("✅ MongoDB indexes created successfully")
+	slog.Info("✅ MongoDB indexes created successfully")
 	return nil
 }
+
 // GetDatabase returns the database instance
 func (db *MongoDB) GetDatabase() *mongo.Database {
 	return db.database
@@ -181,4 +351,34 @@ func (db *MongoDB) HealthCheck() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Breaker returns the circuit breaker guarding this connection
+func (db *MongoDB) Breaker() *CircuitBreaker {
+	return db.breaker
+}
+
+// MonitorHealth periodically pings MongoDB and drives breaker state transitions until ctx is cancelled
+func (db *MongoDB) MonitorHealth(ctx context.Context) error {
+	interval := db.config.HealthCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := db.HealthCheck(); err != nil {
+			db.breaker.RecordFailure()
+		} else if db.breaker.State() != BreakerClosed {
+			db.breaker.RecordSuccess()
+		}
+	}
+}
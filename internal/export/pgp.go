@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bytes"
+	_ "crypto/sha256" // ลงทะเบียน hash ที่ openpgp ใช้เลือกให้ตรงกับ preference ของ key
+	_ "crypto/sha512"
+	"fmt"
+	"strings"
+	"time"
+
+	// golang.org/x/crypto/openpgp ถูก deprecate แล้ว ใช้ fork ที่ยังดูแลอยู่ซึ่ง API เหมือนกัน
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// maxKeyLength limits the size of an armored public key a room owner may register
+const maxKeyLength = 16 * 1024
+
+// ValidateRecipientKey checks that an armored OpenPGP public key can be used for encryption
+func ValidateRecipientKey(armored string) error {
+	// ลองเข้ารหัสข้อมูลว่างดู เพื่อให้แน่ใจว่ามี subkey ที่ใช้เข้ารหัสได้จริง
+	_, err := EncryptArmored(nil, armored, "")
+	return err
+}
+
+// EncryptArmored encrypts plaintext to the given armored OpenPGP public key and returns ASCII-armored ciphertext.
+// เข้ารหัสด้วย public key ของเจ้าของห้อง ผู้ดูแล server จึงไม่เห็นเนื้อหาของ transcript
+func EncryptArmored(plaintext []byte, armoredKey, filename string) ([]byte, error) {
+	recipients, err := readRecipients(armoredKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	armorWriter, err := armor.Encode(&out, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start armor encoding: %v", err)
+	}
+
+	hints := &openpgp.FileHints{IsBinary: true, FileName: filename, ModTime: time.Now()}
+	plainWriter, err := openpgp.Encrypt(armorWriter, recipients, nil, hints, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt transcript: %v", err)
+	}
+	if _, err := plainWriter.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt transcript: %v", err)
+	}
+	if err := plainWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt transcript: %v", err)
+	}
+	if err := armorWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish armor encoding: %v", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// readRecipients parses an armored public key ring, requiring at least one key that can encrypt
+func readRecipients(armored string) (openpgp.EntityList, error) {
+	if len(armored) > maxKeyLength {
+		return nil, fmt.Errorf("public key too large (max %d bytes)", maxKeyLength)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenPGP public key: %v", err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey != nil {
+			return nil, fmt.Errorf("expected a public key, got a private key")
+		}
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no public key found")
+	}
+
+	return entities, nil
+}
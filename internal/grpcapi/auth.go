@@ -0,0 +1,58 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// unaryAuth rejects unary calls without a valid API key
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth rejects streams without a valid API key
+func (s *Server) streamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authenticate checks the x-api-key metadata (or a Bearer token), like requireAPIKey of the HTTP API
+func (s *Server) authenticate(ctx context.Context) error {
+	if len(s.apiKeys) == 0 {
+		return status.Error(codes.Unavailable, "API keys are not configured")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	provided := ""
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		provided = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		provided = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if provided == "" {
+		return status.Error(codes.Unauthenticated, "missing API key")
+	}
+
+	// เทียบแบบ constant time ทุก key เพื่อไม่ให้เดา key จากเวลาตอบกลับได้
+	valid := false
+	for _, key := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	if !valid {
+		return status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package grpcapi serves the chat operations over gRPC for internal services that want to
+// post messages, create rooms or follow a room without opening a WebSocket.
+//
+// ใช้ service เดียวกับ HTTP API และ WebSocket (room service, validator, path บันทึก/กระจายข้อความของ chat)
+// และใช้ api_keys ชุดเดียวกับ HTTP API ในการยืนยันตัวตน
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative chatpb/chat.proto
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/grpcapi/chatpb"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/security"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+// MessagePoster posts messages into rooms through the chat handler
+type MessagePoster interface {
+	PostMessage(username, roomName, content string) (*messagePkg.Message, error)
+	PostSystemMessage(roomName, content string) error
+}
+
+// RoomObserverRegistry delivers a room's broadcasts to a stream
+type RoomObserverRegistry interface {
+	ObserveRoom(roomName string, observer wsocket.RoomObserver) func()
+}
+
+// Server implements chatpb.ChatServer
+type Server struct {
+	chatpb.UnimplementedChatServer
+
+	config      *config.ServerConfig
+	roomService room.Service
+	validator   *security.InputValidator
+	poster      MessagePoster
+	observers   RoomObserverRegistry
+	messageRepo messagePkg.Repository // optional: history ของ StreamRoomMessages
+	apiKeys     []string
+
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// NewServer creates the gRPC API server
+func NewServer(cfg *config.ServerConfig, roomService room.Service, validator *security.InputValidator, poster MessagePoster, observers RoomObserverRegistry) *Server {
+	s := &Server{
+		config:      cfg,
+		roomService: roomService,
+		validator:   validator,
+		poster:      poster,
+		observers:   observers,
+	}
+	for _, key := range cfg.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			s.apiKeys = append(s.apiKeys, key)
+		}
+	}
+	return s
+}
+
+// SetMessageRepository enables the history option of StreamRoomMessages
+func (s *Server) SetMessageRepository(repo messagePkg.Repository) {
+	s.messageRepo = repo
+}
+
+// Start listens on grpc_addr and serves in the background.
+// ถ้า server ตั้ง tls_* ไว้ gRPC ใช้ certificate เดียวกับ HTTPS เพื่อไม่ให้ API key วิ่งเป็น plaintext
+func (s *Server) Start() error {
+	creds := insecure.NewCredentials()
+	tlsSetup, err := security.NewTLSSetup(s.config)
+	if err != nil {
+		return err
+	}
+	if tlsSetup != nil {
+		listenerConfig, err := tlsSetup.ListenerConfig()
+		if err != nil {
+			return err
+		}
+		creds = credentials.NewTLS(listenerConfig)
+	}
+
+	listener, err := net.Listen("tcp", s.config.GRPCAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on grpc_addr %s: %v", s.config.GRPCAddr, err)
+	}
+	s.listener = listener
+	s.server = grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	chatpb.RegisterChatServer(s.server, s)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			slog.Error("❌ gRPC API server stopped", "error", err)
+		}
+	}()
+
+	slog.Info("🔌 gRPC API listening", "addr", s.config.GRPCAddr, "tls", tlsSetup != nil)
+	return nil
+}
+
+// Stop ends open streams and stops the server
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	// stream ของ StreamRoomMessages ไม่จบเอง GracefulStop จะรอตลอดไป จึงปิดทันที
+	s.server.Stop()
+}
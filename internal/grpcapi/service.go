@@ -0,0 +1,243 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"realtime-chat/internal/grpcapi/chatpb"
+	"realtime-chat/internal/room"
+	"realtime-chat/internal/security"
+	wsocket "realtime-chat/internal/websocket"
+)
+
+const (
+	// maxStreamHistory limits the history a stream can ask for, like GET /api/rooms/{room}/messages
+	maxStreamHistory = 100
+
+	// streamBufferSize is how many events may wait for a slow stream before it is closed
+	streamBufferSize = 256
+)
+
+// SendMessage posts a chat message as a user, or a system notice
+func (s *Server) SendMessage(ctx context.Context, req *chatpb.SendMessageRequest) (*chatpb.SendMessageResponse, error) {
+	if _, exists := s.roomService.GetRoom(req.GetRoom()); !exists {
+		return nil, status.Errorf(codes.NotFound, "room '%s' not found", req.GetRoom())
+	}
+
+	if req.GetSystem() {
+		if err := s.poster.PostSystemMessage(req.GetRoom(), req.GetContent()); err != nil {
+			return nil, invalidArgument(err)
+		}
+		return &chatpb.SendMessageResponse{Timestamp: timestamppb.Now()}, nil
+	}
+
+	message, err := s.poster.PostMessage(req.GetUsername(), req.GetRoom(), req.GetContent())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	return &chatpb.SendMessageResponse{
+		Id:        message.ID,
+		Seq:       message.Seq,
+		Timestamp: timestamppb.New(message.Timestamp),
+	}, nil
+}
+
+// CreateRoom creates a room with the same rules as POST /api/rooms
+func (s *Server) CreateRoom(ctx context.Context, req *chatpb.CreateRoomRequest) (*chatpb.Room, error) {
+	roomName, err := s.validator.ValidateRoomName(req.GetName())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if _, exists := s.roomService.GetRoom(roomName); exists {
+		return nil, status.Errorf(codes.AlreadyExists, "room '%s' already exists", roomName)
+	}
+
+	opts := room.CreateOptions{MaxUsers: int(req.GetMaxUsers())}
+	if req.GetTtl() != "" {
+		ttl, err := time.ParseDuration(req.GetTtl())
+		if err != nil || ttl <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid 'ttl' (e.g. 2h)")
+		}
+		opts.TTL = ttl
+	}
+
+	createdBy := req.GetCreatedBy()
+	if createdBy == "" {
+		createdBy = "api"
+	}
+
+	created, err := s.roomService.CreateRoomWithOptions(roomName, createdBy, opts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	slog.Info("🏠 Room created through the gRPC API", "room", roomName, "by", createdBy)
+	return roomToProto(created, 0), nil
+}
+
+// ListRooms lists the rooms that are not invite-only, sorted by name
+func (s *Server) ListRooms(ctx context.Context, req *chatpb.ListRoomsRequest) (*chatpb.ListRoomsResponse, error) {
+	resp := &chatpb.ListRoomsResponse{}
+	for _, chatRoom := range s.roomService.GetRooms() {
+		// ห้อง invite-only ไม่แสดง เหมือน GET /api/rooms
+		if chatRoom.IsPrivate {
+			continue
+		}
+		resp.Rooms = append(resp.Rooms, roomToProto(chatRoom, len(s.roomService.GetUsersInRoom(chatRoom.Name))))
+	}
+	sort.Slice(resp.Rooms, func(i, j int) bool { return resp.Rooms[i].Name < resp.Rooms[j].Name })
+	return resp, nil
+}
+
+// StreamRoomMessages sends the requested history, then every broadcast to the room until the caller cancels.
+// stream ที่รับไม่ทันจนคิวเต็มถูกปิดด้วย ResourceExhausted ให้ต่อใหม่และขอ history ย้อนหลัง
+func (s *Server) StreamRoomMessages(req *chatpb.StreamRoomMessagesRequest, stream chatpb.Chat_StreamRoomMessagesServer) error {
+	roomName := req.GetRoom()
+	if _, exists := s.roomService.GetRoom(roomName); !exists {
+		return status.Errorf(codes.NotFound, "room '%s' not found", roomName)
+	}
+
+	events := make(chan *chatpb.RoomEvent, streamBufferSize)
+	lagged := make(chan struct{})
+	var lagOnce sync.Once
+
+	// ลงทะเบียนก่อนส่ง history ข้อความที่เข้ามาระหว่างนั้นจึงไม่หาย
+	stopObserving := s.observers.ObserveRoom(roomName, func(roomName string, message *wsocket.Message) {
+		select {
+		case events <- roomEvent(roomName, message):
+		default:
+			lagOnce.Do(func() { close(lagged) })
+		}
+	})
+	defer stopObserving()
+
+	if req.GetHistory() > 0 && s.messageRepo != nil {
+		limit := int(req.GetHistory())
+		if limit > maxStreamHistory {
+			limit = maxStreamHistory
+		}
+		messages, err := s.messageRepo.GetMessageHistory(roomName, limit)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to load messages")
+		}
+		for _, message := range messages {
+			payload, _ := json.Marshal(message)
+			event := &chatpb.RoomEvent{
+				Type:      message.Type,
+				Id:        message.ID,
+				Room:      roomName,
+				Username:  message.Username,
+				Content:   message.Content,
+				Timestamp: timestamppb.New(message.Timestamp),
+				Payload:   payload,
+				History:   true,
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+
+	slog.Info("📡 gRPC stream opened", "room", roomName)
+	defer slog.Info("📡 gRPC stream closed", "room", roomName)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-lagged:
+			return status.Error(codes.ResourceExhausted, "stream fell behind the room; reconnect with history to catch up")
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// roomEvent converts a room broadcast to a stream event
+func roomEvent(roomName string, message *wsocket.Message) *chatpb.RoomEvent {
+	event := &chatpb.RoomEvent{
+		Type:     message.Type,
+		Room:     roomName,
+		Username: message.Username,
+		Content:  message.Content,
+		Payload:  []byte(message.Formatted()),
+	}
+	if !message.Timestamp.IsZero() {
+		event.Timestamp = timestamppb.New(message.Timestamp)
+	}
+
+	// frame JSON ที่ encode มาแล้ว (ServerMessage หรือ Message) ดึง field หลักออกมาให้ผู้ติดตามไม่ต้อง parse เอง
+	if message.Type == "json" {
+		var frame struct {
+			Type      string    `json:"type"`
+			ID        string    `json:"id"`
+			Username  string    `json:"username"`
+			Content   string    `json:"content"`
+			Message   string    `json:"message"`
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if err := json.Unmarshal(event.Payload, &frame); err == nil {
+			event.Type = frame.Type
+			event.Id = frame.ID
+			event.Username = frame.Username
+			event.Content = frame.Content
+			if event.Content == "" {
+				event.Content = frame.Message
+			}
+			if !frame.Timestamp.IsZero() {
+				event.Timestamp = timestamppb.New(frame.Timestamp)
+			}
+		}
+	}
+	return event
+}
+
+// roomToProto converts a room to its gRPC representation
+func roomToProto(chatRoom *room.Room, users int) *chatpb.Room {
+	converted := &chatpb.Room{
+		Name:              chatRoom.Name,
+		CreatedBy:         chatRoom.CreatedBy,
+		CreatedAt:         timestamppb.New(chatRoom.CreatedAt),
+		Users:             int32(users),
+		MaxUsers:          int32(chatRoom.MaxUsers),
+		Private:           chatRoom.Private,
+		PasswordProtected: chatRoom.PasswordHash != "",
+	}
+	if chatRoom.ExpiresAt != nil {
+		converted.ExpiresAt = timestamppb.New(*chatRoom.ExpiresAt)
+	}
+	return converted
+}
+
+// invalidArgument converts an error to InvalidArgument; validation errors carry their code as ErrorInfo
+func invalidArgument(err error) error {
+	verr, ok := security.AsValidationError(err)
+	if !ok {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	metadata := make(map[string]string, len(verr.Params))
+	for key, value := range verr.Params {
+		metadata[key] = fmt.Sprint(value)
+	}
+	st, detailErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason:   verr.Code,
+		Domain:   "realtime-chat",
+		Metadata: metadata,
+	})
+	if detailErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return st.Err()
+}
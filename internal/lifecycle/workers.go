@@ -0,0 +1,89 @@
+// Package lifecycle runs long-lived background workers (e.g. the WebSocket manager loop and the
+// config file watcher) under one context so shutdown can stop them and wait for them together.
+// งานที่รันเป็นรอบตามเวลาให้ใช้ scheduler แทน
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Workers is a group of background workers that share one cancellable context.
+// worker ตัวหนึ่งจบหรือ error ไม่ทำให้ตัวอื่นหยุด (ไม่ได้ใช้ errgroup.WithContext) มีแค่ Stop ที่ยกเลิก context
+type Workers struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	group   errgroup.Group
+	running map[string]struct{}
+	mutex   sync.Mutex
+}
+
+// New creates an empty worker group
+func New() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]struct{}),
+	}
+}
+
+// Context returns the context passed to workers; it is cancelled by Stop
+func (w *Workers) Context() context.Context {
+	return w.ctx
+}
+
+// Go starts a named worker; run must return once ctx is cancelled. Panics are recovered and logged
+func (w *Workers) Go(name string, run func(ctx context.Context) error) {
+	w.mutex.Lock()
+	w.running[name] = struct{}{}
+	w.mutex.Unlock()
+
+	w.group.Go(func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("worker '%s' panicked: %v", name, r)
+				slog.Error("❌ Worker panicked", "worker", name, "panic", r)
+			}
+			w.mutex.Lock()
+			delete(w.running, name)
+			w.mutex.Unlock()
+		}()
+
+		if err := run(w.ctx); err != nil {
+			slog.Error("❌ Worker stopped with error", "worker", name, "error", err)
+			return fmt.Errorf("worker '%s': %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Stop cancels the workers' context and waits up to timeout for them to return.
+// คืน error ตัวแรกที่ worker คืนมา หรือ error timeout พร้อมชื่อ worker ที่ยังไม่จบ
+func (w *Workers) Stop(timeout time.Duration) error {
+	w.cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- w.group.Wait() }()
+
+	select {
+	case err := <-done:
+		slog.Info("🧵 Background workers stopped")
+		return err
+	case <-time.After(timeout):
+		w.mutex.Lock()
+		names := make([]string, 0, len(w.running))
+		for name := range w.running {
+			names = append(names, name)
+		}
+		w.mutex.Unlock()
+		sort.Strings(names)
+		return fmt.Errorf("workers still running after %v: %v", timeout, names)
+	}
+}
@@ -0,0 +1,123 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DirectMessage represents a private message between two users
+type DirectMessage struct {
+	ID             string    `json:"id,omitempty"`
+	ConversationID string    `json:"conversation_id"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	Content        string    `json:"content"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// DirectMessageDocument represents a direct message stored in MongoDB
+type DirectMessageDocument struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationID string             `bson:"conversation_id"`
+	From           string             `bson:"from"`
+	To             string             `bson:"to"`
+	Content        string             `bson:"content"`
+	Timestamp      time.Time          `bson:"timestamp"`
+}
+
+// DirectMessageRepository persists private conversations
+type DirectMessageRepository interface {
+	SaveDirectMessage(message *DirectMessage) error
+	GetConversation(userA, userB string, limit int) ([]*DirectMessage, error) // เรียงจากเก่าไปใหม่
+}
+
+// ConversationID returns the key shared by both participants, independent of who sent the message
+func ConversationID(userA, userB string) string {
+	a, b := strings.ToLower(userA), strings.ToLower(userB)
+	if a > b {
+		a, b = b, a
+	}
+	return a + ":" + b
+}
+
+// MongoDirectMessageRepository implements DirectMessageRepository using MongoDB
+type MongoDirectMessageRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDirectMessageRepository creates a new MongoDB direct message repository
+func NewMongoDirectMessageRepository(db *database.MongoDB) DirectMessageRepository {
+	return &MongoDirectMessageRepository{
+		collection: db.GetCollection("direct_messages"),
+	}
+}
+
+// SaveDirectMessage saves a direct message to MongoDB
+func (r *MongoDirectMessageRepository) SaveDirectMessage(message *DirectMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message.ConversationID = ConversationID(message.From, message.To)
+	doc := &DirectMessageDocument{
+		ConversationID: message.ConversationID,
+		From:           message.From,
+		To:             message.To,
+		Content:        message.Content,
+		Timestamp:      message.Timestamp,
+	}
+
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to save direct message: %v", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		message.ID = oid.Hex()
+	}
+	return nil
+}
+
+// GetConversation retrieves the latest messages between two users
+func (r *MongoDirectMessageRepository) GetConversation(userA, userB string, limit int) ([]*DirectMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"conversation_id": ConversationID(userA, userB)}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []DirectMessageDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode conversation: %v", err)
+	}
+
+	// ดึงล่าสุดก่อนเพื่อใช้ limit แล้วกลับลำดับให้อ่านจากเก่าไปใหม่
+	messages := make([]*DirectMessage, len(docs))
+	for i, doc := range docs {
+		messages[len(docs)-1-i] = &DirectMessage{
+			ID:             doc.ID.Hex(),
+			ConversationID: doc.ConversationID,
+			From:           doc.From,
+			To:             doc.To,
+			Content:        doc.Content,
+			Timestamp:      doc.Timestamp,
+		}
+	}
+	return messages, nil
+}
@@ -15,6 +15,17 @@ type Message struct {
 	Username  string    `json:"username"`
 	RoomName  string    `json:"room_name"`
 	Timestamp time.Time `json:"timestamp"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Reactions []MessageReaction `json:"reactions,omitempty"`
+	EditHistory []MessageEdit `json:"edit_history,omitempty"`
+	IsDeleted bool              `json:"is_deleted,omitempty"`
+	ParentID  string            `json:"parent_id,omitempty"` // ข้อความที่ตอบ (thread reply)
+	Seq       int64             `json:"seq,omitempty"`       // ลำดับใน timeline ของห้อง (0 = ไม่ได้บันทึก timeline)
+	ProbeID   string            `json:"probe_id,omitempty"`  // latency probe ของ load test mode (ส่งต่อเท่านั้น ไม่บันทึก)
+	Attachments []MessageAttachment `json:"attachments,omitempty"` // ไฟล์ที่อัปโหลดผ่าน POST /api/upload
+	Mentions  []string          `json:"mentions,omitempty"`  // username (lowercase) ที่ถูก @mention
+	DisplayName string          `json:"display_name,omitempty"` // profile ของผู้ส่งตอนส่ง (ส่งต่อเท่านั้น ไม่บันทึก)
+	AvatarURL string            `json:"avatar_url,omitempty"`
 }
 
 // EnhancedMessage represents an enhanced message with additional features
@@ -68,6 +79,7 @@ type MessageEdit struct {
 	PreviousContent string    `json:"previous_content" bson:"previous_content"`
 	EditedAt        time.Time `json:"edited_at" bson:"edited_at"`
 	EditReason      string    `json:"edit_reason,omitempty" bson:"edit_reason,omitempty"`
+	EditedBy        string    `json:"edited_by,omitempty" bson:"edited_by,omitempty"` // ผู้แก้ไข (ผู้เขียนหรือ moderator)
 }
 
 // MessageStatus represents the delivery and read status of a message
@@ -115,6 +127,13 @@ type SearchQuery struct {
 	HasAttachment bool       `json:"has_attachment"`
 	Limit         int        `json:"limit"`
 	Offset        int        `json:"offset"`
+	Cursor        string     `json:"cursor,omitempty"` // next_cursor ของหน้าก่อน (ใช้แทน Offset)
+}
+
+// ScoredMessage is a text search hit with its relevance score
+type ScoredMessage struct {
+	Message *Message `json:"message"`
+	Score   float64  `json:"score"`
 }
 
 // MessageFilter represents filtering criteria for messages
@@ -145,6 +164,18 @@ type MessageStats struct {
 	GeneratedAt      time.Time                `json:"generated_at"`
 }
 
+// Activity bucket sizes
+const (
+	ActivityBucketHour = "hour"
+	ActivityBucketDay  = "day"
+)
+
+// ActivityBucket represents the number of messages sent within a time bucket
+type ActivityBucket struct {
+	Start time.Time `json:"start" bson:"_id"`
+	Count int64     `json:"count" bson:"count"`
+}
+
 // ReactionStats represents statistics for reactions
 type ReactionStats struct {
 	Emoji string `json:"emoji"`
@@ -186,6 +217,16 @@ type MessageDocument struct {
 	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
 	Sender    string             `bson:"sender" json:"sender"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	CorrelationID string         `bson:"correlation_id,omitempty" json:"correlation_id,omitempty"`
+	Reactions []MessageReaction  `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	EditHistory []MessageEdit    `bson:"edit_history,omitempty" json:"edit_history,omitempty"`
+	IsDeleted bool               `bson:"is_deleted,omitempty" json:"is_deleted,omitempty"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	ParentID  *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Seq       int64              `bson:"seq,omitempty" json:"seq,omitempty"`
+	Attachments []MessageAttachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Mentions  []string           `bson:"mentions,omitempty" json:"mentions,omitempty"`
 }
 
 // EnhancedMessageDocument represents the MongoDB document structure for enhanced messages
@@ -251,6 +292,8 @@ func (m *Message) ToEnhancedMessage() *EnhancedMessage {
 		RoomName:  m.RoomName,
 		Timestamp: m.Timestamp,
 		Sender:    m.Sender,
+		Attachments: m.Attachments,
+		Mentions:  m.Mentions,
 		Status: MessageStatus{
 			Sent: now,
 		},
@@ -269,6 +312,7 @@ func (m *Message) FromEnhancedMessage(enhanced *EnhancedMessage) {
 	m.RoomName = enhanced.RoomName
 	m.Timestamp = enhanced.Timestamp
 	m.Sender = enhanced.Sender
+	m.Mentions = enhanced.Mentions
 }
 
 // ToMessage converts MessageDocument to basic Message (backward compatibility)
@@ -281,7 +325,22 @@ func (doc *MessageDocument) ToMessage() *Message {
 		RoomName:  doc.RoomName,
 		Timestamp: doc.Timestamp,
 		Sender:    doc.Sender,
+		Reactions: doc.Reactions,
+		EditHistory: doc.EditHistory,
+		IsDeleted: doc.IsDeleted,
+		ParentID:  parentHex(doc.ParentID),
+		Seq:       doc.Seq,
+		Attachments: doc.Attachments,
+		Mentions:  doc.Mentions,
+	}
+}
+
+// parentHex returns the hex ID of an optional parent message
+func parentHex(id *primitive.ObjectID) string {
+	if id == nil {
+		return ""
 	}
+	return id.Hex()
 }
 
 // FromMessage converts basic Message to MessageDocument
@@ -292,6 +351,8 @@ func (doc *MessageDocument) FromMessage(msg *Message) {
 	doc.RoomName = msg.RoomName
 	doc.Timestamp = msg.Timestamp
 	doc.Sender = msg.Sender
+	doc.Attachments = msg.Attachments
+	doc.Mentions = msg.Mentions
 	doc.CreatedAt = time.Now()
 
 	if msg.ID != "" {
@@ -299,4 +360,28 @@ func (doc *MessageDocument) FromMessage(msg *Message) {
 			doc.ID = oid
 		}
 	}
-}
\ No newline at end of file
+}
+
+// ToggleReactionUser adds or removes username from the emoji's reaction, dropping reactions nobody uses anymore.
+// ใช้กับ backend ที่อ่าน-แก้-เขียน reactions ทั้งชุดภายใน transaction (SQL)
+func ToggleReactionUser(reactions []MessageReaction, emoji, username string) (bool, []MessageReaction) {
+	for i := range reactions {
+		if reactions[i].Emoji != emoji {
+			continue
+		}
+		for j, user := range reactions[i].Users {
+			if user == username {
+				reactions[i].Users = append(reactions[i].Users[:j], reactions[i].Users[j+1:]...)
+				reactions[i].Count = len(reactions[i].Users)
+				if reactions[i].Count == 0 {
+					reactions = append(reactions[:i], reactions[i+1:]...)
+				}
+				return false, reactions
+			}
+		}
+		reactions[i].Users = append(reactions[i].Users, username)
+		reactions[i].Count = len(reactions[i].Users)
+		return true, reactions
+	}
+	return true, append(reactions, MessageReaction{Emoji: emoji, Users: []string{username}, Count: 1, CreatedAt: time.Now()})
+}
@@ -0,0 +1,238 @@
+package message
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	journalPrefix     = "journal-"
+	journalSuffix     = ".log"
+	journalDoneSuffix = ".done"
+)
+
+// Journal is a local append-only write-ahead log for messages that could not be persisted.
+// ข้อความถูกเขียนลง segment files และ sync ลง disk ทุกครั้ง เพื่อจำกัดข้อมูลที่หายเมื่อ server crash
+type Journal struct {
+	dir          string
+	segmentSize  int64
+	current      *os.File
+	currentName  string
+	currentBytes int64
+	mutex        sync.Mutex
+}
+
+// NewJournal opens (or creates) a journal directory
+func NewJournal(dir string, segmentSize int64) (*Journal, error) {
+	if segmentSize <= 0 {
+		segmentSize = 4 * 1024 * 1024
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %v", err)
+	}
+
+	return &Journal{
+		dir:         dir,
+		segmentSize: segmentSize,
+	}, nil
+}
+
+// Append writes a message to the current segment
+func (j *Journal) Append(message *Message) error {
+	// กำหนด ID ล่วงหน้า เพื่อให้การ replay ซ้ำไม่สร้างข้อความซ้ำในฐานข้อมูล
+	if message.ID == "" {
+		message.ID = primitive.NewObjectID().Hex()
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %v", err)
+	}
+	data = append(data, '\n')
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.current == nil || j.currentBytes+int64(len(data)) > j.segmentSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.current.Write(data)
+	j.currentBytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %v", err)
+	}
+
+	if err := j.current.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %v", err)
+	}
+
+	return nil
+}
+
+// Drain replays every sealed segment into the repository and marks it complete
+func (j *Journal) Drain(repo Repository) (int, error) {
+	// ปิด segment ปัจจุบันก่อน เพื่อให้ข้อความล่าสุดถูก drain ด้วย
+	j.mutex.Lock()
+	if j.current != nil && j.currentBytes > 0 {
+		if err := j.seal(); err != nil {
+			j.mutex.Unlock()
+			return 0, err
+		}
+	}
+	j.mutex.Unlock()
+
+	segments, err := j.pendingSegments()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, segment := range segments {
+		count, err := j.drainSegment(segment, repo)
+		total += count
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// RunReconciler periodically drains the journal into the repository while shouldRun allows it,
+// until ctx is cancelled (run as a lifecycle worker)
+func (j *Journal) RunReconciler(ctx context.Context, repo Repository, interval time.Duration, shouldRun func() bool) error {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if shouldRun != nil && !shouldRun() {
+			continue
+		}
+		if count, err := j.Drain(repo); err != nil {
+			slog.Warn("⚠️ Journal reconcile stopped", "reconciled", count, "error", err)
+		} else if count > 0 {
+			slog.Info("✅ Journal reconciled into database", "messages", count)
+		}
+	}
+}
+
+// rotate seals the current segment and opens a new one (assumes lock is held)
+func (j *Journal) rotate() error {
+	if j.current != nil {
+		if err := j.seal(); err != nil {
+			return err
+		}
+	}
+
+	name := filepath.Join(j.dir, fmt.Sprintf("%s%d%s", journalPrefix, time.Now().UnixNano(), journalSuffix))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal segment: %v", err)
+	}
+
+	j.current = file
+	j.currentName = name
+	j.currentBytes = 0
+	return nil
+}
+
+// seal closes the current segment so it can be drained (assumes lock is held)
+func (j *Journal) seal() error {
+	if err := j.current.Close(); err != nil {
+		return fmt.Errorf("failed to close journal segment: %v", err)
+	}
+	j.current = nil
+	j.currentName = ""
+	j.currentBytes = 0
+	return nil
+}
+
+// pendingSegments lists sealed segments in creation order
+func (j *Journal) pendingSegments() ([]string, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal directory: %v", err)
+	}
+
+	j.mutex.Lock()
+	active := j.currentName
+	j.mutex.Unlock()
+
+	segments := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, journalPrefix) || !strings.HasSuffix(name, journalSuffix) {
+			continue
+		}
+		path := filepath.Join(j.dir, name)
+		if path == active {
+			continue
+		}
+		segments = append(segments, path)
+	}
+
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// drainSegment replays a single segment and renames it to .done on success
+func (j *Journal) drainSegment(path string, repo Repository) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal segment: %v", err)
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			// entry ที่เขียนไม่ครบ (crash ระหว่างเขียน) ข้ามไป
+			slog.Warn("⚠️ Skipping corrupt journal entry", "file", filepath.Base(path), "error", err)
+			continue
+		}
+
+		if err := repo.SaveMessage(&message); err != nil {
+			file.Close()
+			return count, fmt.Errorf("failed to replay %s: %v", filepath.Base(path), err)
+		}
+		count++
+	}
+	file.Close()
+
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read journal segment: %v", err)
+	}
+
+	if err := os.Rename(path, path+journalDoneSuffix); err != nil {
+		return count, fmt.Errorf("failed to mark segment complete: %v", err)
+	}
+
+	return count, nil
+}
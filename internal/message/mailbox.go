@@ -0,0 +1,199 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Pending message kinds
+const (
+	PendingMention = "mention"
+	PendingDirect  = "direct"
+)
+
+// PendingMessage is a message queued for a user who was offline when it was sent
+type PendingMessage struct {
+	ID          string     `json:"id"`
+	Recipient   string     `json:"recipient"`
+	Kind        string     `json:"kind"`
+	From        string     `json:"from"`
+	RoomName    string     `json:"room_name,omitempty"`  // ว่างสำหรับ direct message
+	MessageID   string     `json:"message_id,omitempty"` // ข้อความต้นทางในห้อง (ถ้าบันทึกไว้)
+	Content     string     `json:"content"`
+	Timestamp   time.Time  `json:"timestamp"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+}
+
+// PendingMessageDocument represents a pending message stored in MongoDB
+type PendingMessageDocument struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Recipient   string             `bson:"recipient"` // lowercase
+	Kind        string             `bson:"kind"`
+	From        string             `bson:"from"`
+	RoomName    string             `bson:"room_name,omitempty"`
+	MessageID   string             `bson:"message_id,omitempty"`
+	Content     string             `bson:"content"`
+	Timestamp   time.Time          `bson:"timestamp"`
+	DeliveredAt *time.Time         `bson:"delivered_at,omitempty"`
+	ReadAt      *time.Time         `bson:"read_at,omitempty"`
+}
+
+// MailboxRepository stores messages for offline users until they reconnect and read them
+type MailboxRepository interface {
+	Enqueue(message *PendingMessage) error
+	// GetUnread returns messages the recipient has not marked as read, oldest first
+	GetUnread(recipient string, limit int) ([]*PendingMessage, error)
+	MarkDelivered(recipient string, ids []string) error
+	// MarkRead records read receipts and returns how many messages were updated
+	MarkRead(recipient string, ids []string) (int64, error)
+}
+
+// MongoMailboxRepository implements MailboxRepository using MongoDB
+type MongoMailboxRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMailboxRepository creates a new MongoDB mailbox repository
+func NewMongoMailboxRepository(db *database.MongoDB) MailboxRepository {
+	return &MongoMailboxRepository{
+		collection: db.GetCollection("pending_messages"),
+	}
+}
+
+// Enqueue saves a message to the recipient's mailbox
+func (r *MongoMailboxRepository) Enqueue(message *PendingMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	doc := &PendingMessageDocument{
+		Recipient: strings.ToLower(message.Recipient),
+		Kind:      message.Kind,
+		From:      message.From,
+		RoomName:  message.RoomName,
+		MessageID: message.MessageID,
+		Content:   message.Content,
+		Timestamp: message.Timestamp,
+	}
+
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to queue pending message: %v", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		message.ID = oid.Hex()
+	}
+	return nil
+}
+
+// GetUnread retrieves the oldest unread messages of a recipient.
+// ข้อความที่ส่งไปแล้วแต่ยังไม่ถูกอ่านจะถูกส่งซ้ำตอน reconnect ครั้งถัดไป
+func (r *MongoMailboxRepository) GetUnread(recipient string, limit int) ([]*PendingMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"recipient": strings.ToLower(recipient),
+		"read_at":   bson.M{"$exists": false},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending messages: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []PendingMessageDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode pending messages: %v", err)
+	}
+
+	messages := make([]*PendingMessage, len(docs))
+	for i := range docs {
+		messages[i] = docs[i].ToPendingMessage()
+	}
+	return messages, nil
+}
+
+// MarkDelivered records that messages were sent to the recipient's connection
+func (r *MongoMailboxRepository) MarkDelivered(recipient string, ids []string) error {
+	objectIDs := toObjectIDs(ids)
+	if len(objectIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":          bson.M{"$in": objectIDs},
+		"recipient":    strings.ToLower(recipient),
+		"delivered_at": bson.M{"$exists": false},
+	}
+	if _, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"delivered_at": time.Now()}}); err != nil {
+		return fmt.Errorf("failed to mark pending messages delivered: %v", err)
+	}
+	return nil
+}
+
+// MarkRead records read receipts; only the recipient's own messages are updated
+func (r *MongoMailboxRepository) MarkRead(recipient string, ids []string) (int64, error) {
+	objectIDs := toObjectIDs(ids)
+	if len(objectIDs) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":       bson.M{"$in": objectIDs},
+		"recipient": strings.ToLower(recipient),
+		"read_at":   bson.M{"$exists": false},
+	}
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read_at": time.Now()}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark pending messages read: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// ToPendingMessage converts PendingMessageDocument to PendingMessage
+func (doc *PendingMessageDocument) ToPendingMessage() *PendingMessage {
+	return &PendingMessage{
+		ID:          doc.ID.Hex(),
+		Recipient:   doc.Recipient,
+		Kind:        doc.Kind,
+		From:        doc.From,
+		RoomName:    doc.RoomName,
+		MessageID:   doc.MessageID,
+		Content:     doc.Content,
+		Timestamp:   doc.Timestamp,
+		DeliveredAt: doc.DeliveredAt,
+		ReadAt:      doc.ReadAt,
+	}
+}
+
+// toObjectIDs converts hex IDs, skipping invalid ones
+func toObjectIDs(ids []string) []primitive.ObjectID {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, oid)
+		}
+	}
+	return objectIDs
+}
@@ -3,6 +3,8 @@ package message
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"realtime-chat/internal/database"
@@ -39,11 +41,30 @@ func (r *MongoRepository) SaveMessage(message *Message) error {
 		Timestamp: message.Timestamp,
 		Sender:    message.Sender,
 		CreatedAt: now,
+		CorrelationID: message.CorrelationID,
+		Seq:       message.Seq,
+		Attachments: message.Attachments,
+		Mentions:  message.Mentions,
+	}
+
+	// ใช้ ID ที่กำหนดไว้ล่วงหน้า (เช่นจาก journal) เพื่อให้การบันทึกซ้ำไม่สร้างเอกสารซ้ำ
+	if message.ID != "" {
+		if oid, err := primitive.ObjectIDFromHex(message.ID); err == nil {
+			messageDoc.ID = oid
+		}
+	}
+	if message.ParentID != "" {
+		if oid, err := primitive.ObjectIDFromHex(message.ParentID); err == nil {
+			messageDoc.ParentID = &oid
+		}
 	}
 
 	result, err := r.collection.InsertOne(ctx, messageDoc)
 	if err != nil {
-		return fmt.Errorf("failed to save message: %v", err)
+		if mongo.IsDuplicateKeyError(err) && !messageDoc.ID.IsZero() {
+			return nil
+		}
+		return fmt.Errorf("failed to save message [%s]: %v", message.CorrelationID, err)
 	}
 
 	// Update message with MongoDB ID
@@ -288,4 +309,254 @@ func (r *MongoRepository) SearchMessages(query string, roomName string, limit in
 	}
 
 	return messages, nil
-}
\ No newline at end of file
+}
+// maxTextSearchLimit caps a page of SearchMessagesText
+const maxTextSearchLimit = 100
+
+// SearchMessagesText searches messages with the MongoDB text index, best matches first.
+// ต่างจาก SearchMessages ที่ใช้ $regex (สแกนทั้ง collection) ตรงที่ใช้ index "content" และจัดอันดับด้วย textScore
+// ข้อความที่ถูกลบไม่ถูกค้น; nextCursor ว่างเมื่อไม่มีหน้าถัดไป
+func (r *MongoRepository) SearchMessagesText(query SearchQuery) ([]*ScoredMessage, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, "", fmt.Errorf("search text is required")
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > maxTextSearchLimit {
+		limit = maxTextSearchLimit
+	}
+	offset := query.Offset
+	if query.Cursor != "" {
+		parsed, err := strconv.Atoi(query.Cursor)
+		if err != nil || parsed < 0 {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+		offset = parsed
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := bson.M{
+		"$text":      bson.M{"$search": query.Text},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	if query.RoomName != "" {
+		filter["room_name"] = query.RoomName
+	}
+	if query.Username != "" {
+		filter["username"] = query.Username
+	}
+	if query.MessageType != "" {
+		filter["type"] = query.MessageType
+	}
+	if query.HasAttachment {
+		filter["attachments.0"] = bson.M{"$exists": true}
+	}
+	if query.StartDate != nil || query.EndDate != nil {
+		timeRange := bson.M{}
+		if query.StartDate != nil {
+			timeRange["$gte"] = *query.StartDate
+		}
+		if query.EndDate != nil {
+			timeRange["$lte"] = *query.EndDate
+		}
+		filter["timestamp"] = timeRange
+	}
+
+	// ขอเกินมาหนึ่งรายการเพื่อรู้ว่ามีหน้าถัดไปหรือไม่
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "timestamp", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search messages: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]*ScoredMessage, 0, limit)
+	for cursor.Next(ctx) {
+		var scored struct {
+			MessageDocument `bson:",inline"`
+			Score           float64 `bson:"score"`
+		}
+		if err := cursor.Decode(&scored); err != nil {
+			continue
+		}
+		results = append(results, &ScoredMessage{Message: scored.MessageDocument.ToMessage(), Score: scored.Score})
+	}
+
+	nextCursor := ""
+	if len(results) > limit {
+		results = results[:limit]
+		nextCursor = strconv.Itoa(offset + limit)
+	}
+	return results, nextCursor, nil
+}
+
+// ErrMessageNotFound is returned when a reaction or edit targets a message that doesn't exist (or was deleted)
+var ErrMessageNotFound = fmt.Errorf("message not found")
+
+// ToggleReaction adds the user's emoji reaction to a message, or removes it if already present.
+// ใช้ update แบบ atomic ทีละขั้น จึงไม่ต้องอ่าน-แก้-เขียนทั้งเอกสาร
+func (r *MongoRepository) ToggleReaction(messageID, emoji, username string) (bool, []MessageReaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return false, nil, ErrMessageNotFound
+	}
+
+	added := true
+
+	// มี emoji นี้อยู่แล้วแต่ผู้ใช้ยังไม่ได้กด
+	joinExisting := func() (*mongo.UpdateResult, error) {
+		return r.collection.UpdateOne(ctx,
+			bson.M{"_id": objID, "reactions": bson.M{"$elemMatch": bson.M{"emoji": emoji, "users": bson.M{"$ne": username}}}},
+			bson.M{"$addToSet": bson.M{"reactions.$.users": username}, "$inc": bson.M{"reactions.$.count": 1}})
+	}
+	result, err := joinExisting()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to add reaction: %v", err)
+	}
+
+	if result.ModifiedCount == 0 {
+		// ยังไม่มีใครกด emoji นี้
+		result, err = r.collection.UpdateOne(ctx,
+			bson.M{"_id": objID, "reactions.emoji": bson.M{"$ne": emoji}},
+			bson.M{"$push": bson.M{"reactions": MessageReaction{Emoji: emoji, Users: []string{username}, Count: 1, CreatedAt: time.Now()}}})
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to add reaction: %v", err)
+		}
+	}
+
+	if result.ModifiedCount == 0 {
+		// ผู้ใช้กดไว้แล้ว จึงเป็นการเอาออก
+		added = false
+		result, err = r.collection.UpdateOne(ctx,
+			bson.M{"_id": objID, "reactions": bson.M{"$elemMatch": bson.M{"emoji": emoji, "users": username}}},
+			bson.M{"$pull": bson.M{"reactions.$.users": username}, "$inc": bson.M{"reactions.$.count": -1}})
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to remove reaction: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			// อาจมีคนอื่นเพิ่ง push emoji นี้ระหว่างขั้นตอน ลองเข้าร่วมอีกครั้ง
+			if result, err = joinExisting(); err != nil {
+				return false, nil, fmt.Errorf("failed to add reaction: %v", err)
+			}
+			if result.ModifiedCount == 0 {
+				return false, nil, ErrMessageNotFound
+			}
+			added = true
+		} else if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": objID},
+			bson.M{"$pull": bson.M{"reactions": bson.M{"count": bson.M{"$lte": 0}}}}); err != nil {
+			return false, nil, fmt.Errorf("failed to remove reaction: %v", err)
+		}
+	}
+
+	var messageDoc MessageDocument
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&messageDoc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil, ErrMessageNotFound
+		}
+		return false, nil, fmt.Errorf("failed to load reactions: %v", err)
+	}
+	return added, messageDoc.Reactions, nil
+}
+
+// EditMessage replaces a message's content and appends the previous content to its edit history.
+// ใช้ pipeline update เพื่อให้การเก็บเนื้อหาเดิมและการแก้ไขเกิดใน operation เดียว (ไม่มี edit หายเมื่อแก้พร้อมกัน)
+func (r *MongoRepository) EditMessage(messageID, content, editedBy string) (*Message, error) {
+	return r.rewriteMessage(messageID, bson.M{"content": content}, MessageEdit{EditedBy: editedBy})
+}
+
+// SoftDeleteMessage marks a message as deleted, keeping its last content in the edit history as an audit trail
+func (r *MongoRepository) SoftDeleteMessage(messageID, deletedBy string) (*Message, error) {
+	now := time.Now()
+	return r.rewriteMessage(messageID,
+		bson.M{"content": "", "is_deleted": true, "deleted_at": now},
+		MessageEdit{EditedBy: deletedBy, EditReason: "deleted"})
+}
+
+// rewriteMessage applies changes to a message that isn't deleted yet, recording the previous content as an edit
+func (r *MongoRepository) rewriteMessage(messageID string, changes bson.M, edit MessageEdit) (*Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	now := time.Now()
+	entry := bson.M{"previous_content": "$content", "edited_at": now, "edited_by": edit.EditedBy}
+	if edit.EditReason != "" {
+		entry["edit_reason"] = edit.EditReason
+	}
+	set := bson.M{
+		"edit_history": bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$edit_history", bson.A{}}}, bson.A{entry}}},
+		"updated_at":   now,
+	}
+	for field, value := range changes {
+		// ค่าที่เป็น string ใน pipeline ที่ขึ้นต้นด้วย $ จะถูกตีความเป็น field path จึงต้องห่อด้วย $literal
+		set[field] = bson.M{"$literal": value}
+	}
+
+	var messageDoc MessageDocument
+	err = r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objID, "is_deleted": bson.M{"$ne": true}},
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&messageDoc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to update message: %v", err)
+	}
+	return messageDoc.ToMessage(), nil
+}
+
+// GetRoomActivity returns message counts grouped into hour or day buckets
+func (r *MongoRepository) GetRoomActivity(roomName string, from, to time.Time, bucket string) ([]ActivityBucket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if bucket != ActivityBucketDay {
+		bucket = ActivityBucketHour
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"room_name": roomName,
+			"timestamp": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": bucket}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate room activity: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []ActivityBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode room activity: %v", err)
+	}
+
+	return buckets, nil
+}
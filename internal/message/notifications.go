@@ -0,0 +1,155 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationRepository stores per-user notifications (mentions, replies, ...)
+type NotificationRepository interface {
+	Create(notification *Notification) error
+	// List returns the user's newest notifications first
+	List(userID string, unreadOnly bool, limit int) ([]*Notification, error)
+	// CountUnread returns how many notifications the user has not read
+	CountUnread(userID string) (int64, error)
+	// MarkRead marks notifications as read (all unread when ids is empty) and returns how many were updated
+	MarkRead(userID string, ids []string) (int64, error)
+}
+
+// MongoNotificationRepository implements NotificationRepository using MongoDB
+type MongoNotificationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoNotificationRepository creates a new MongoDB notification repository
+func NewMongoNotificationRepository(db *database.MongoDB) NotificationRepository {
+	return &MongoNotificationRepository{
+		collection: db.GetCollection("notifications"),
+	}
+}
+
+// Create saves a notification and fills in its ID
+func (r *MongoNotificationRepository) Create(notification *Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+
+	doc := &NotificationDocument{
+		UserID:    strings.ToLower(notification.UserID),
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Message:   notification.Message,
+		Data:      notification.Data,
+		CreatedAt: notification.CreatedAt,
+		ExpiresAt: notification.ExpiresAt,
+	}
+
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %v", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		notification.ID = oid.Hex()
+	}
+	return nil
+}
+
+// List retrieves the newest notifications of a user
+func (r *MongoNotificationRepository) List(userID string, unreadOnly bool, limit int) ([]*Notification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": strings.ToLower(userID)}
+	if unreadOnly {
+		filter["is_read"] = false
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []NotificationDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode notifications: %v", err)
+	}
+
+	notifications := make([]*Notification, len(docs))
+	for i := range docs {
+		notifications[i] = docs[i].ToNotification()
+	}
+	return notifications, nil
+}
+
+// CountUnread counts the user's unread notifications
+func (r *MongoNotificationRepository) CountUnread(userID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"user_id": strings.ToLower(userID),
+		"is_read": false,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %v", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks notifications as read; only the user's own notifications are updated
+func (r *MongoNotificationRepository) MarkRead(userID string, ids []string) (int64, error) {
+	filter := bson.M{
+		"user_id": strings.ToLower(userID),
+		"is_read": false,
+	}
+	if len(ids) > 0 {
+		objectIDs := toObjectIDs(ids)
+		if len(objectIDs) == 0 {
+			return 0, nil
+		}
+		filter["_id"] = bson.M{"$in": objectIDs}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"is_read": true, "read_at": time.Now()}}
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// ToNotification converts NotificationDocument to Notification
+func (doc *NotificationDocument) ToNotification() *Notification {
+	return &Notification{
+		ID:        doc.ID.Hex(),
+		UserID:    doc.UserID,
+		Type:      doc.Type,
+		Title:     doc.Title,
+		Message:   doc.Message,
+		Data:      doc.Data,
+		IsRead:    doc.IsRead,
+		ReadAt:    doc.ReadAt,
+		CreatedAt: doc.CreatedAt,
+		ExpiresAt: doc.ExpiresAt,
+	}
+}
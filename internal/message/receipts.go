@@ -0,0 +1,112 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoomReadReceipt records the last message a user has seen in a room
+type RoomReadReceipt struct {
+	ReadReceipt `bson:",inline"`
+	RoomName    string    `json:"room_name" bson:"room_name"`
+	MessageID   string    `json:"message_id" bson:"message_id"`
+	MessageTime time.Time `json:"message_time" bson:"message_time"` // timestamp ของข้อความ ใช้นับข้อความที่ใหม่กว่า
+}
+
+// ReadReceiptRepository persists per-room read receipts and counts unread messages
+type ReadReceiptRepository interface {
+	// MarkRead moves the user's receipt forward; an older message than the current receipt is ignored
+	MarkRead(receipt *RoomReadReceipt) error
+	GetReceipts(username string) ([]*RoomReadReceipt, error)
+	// CountUnread counts messages from other users newer than the receipt, stopping at limit
+	CountUnread(receipt *RoomReadReceipt, limit int64) (int64, error)
+}
+
+// MongoReadReceiptRepository implements ReadReceiptRepository using MongoDB
+type MongoReadReceiptRepository struct {
+	receipts *mongo.Collection
+	messages *mongo.Collection
+}
+
+// NewMongoReadReceiptRepository creates a new MongoDB read receipt repository
+func NewMongoReadReceiptRepository(db *database.MongoDB) ReadReceiptRepository {
+	return &MongoReadReceiptRepository{
+		receipts: db.GetCollection("read_receipts"),
+		messages: db.GetCollection("messages"),
+	}
+}
+
+// MarkRead upserts the receipt of (username, room) if it moves forward
+func (r *MongoReadReceiptRepository) MarkRead(receipt *RoomReadReceipt) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"username":     receipt.Username,
+		"room_name":    receipt.RoomName,
+		"message_time": bson.M{"$lt": receipt.MessageTime},
+	}
+	update := bson.M{"$set": bson.M{
+		"message_id":   receipt.MessageID,
+		"message_time": receipt.MessageTime,
+		"read_at":      receipt.ReadAt,
+	}}
+
+	_, err := r.receipts.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// มี receipt ที่ใหม่กว่าอยู่แล้ว upsert จึงชน unique index (username, room_name) - ไม่ใช่ error
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to save read receipt: %v", err)
+	}
+	return nil
+}
+
+// GetReceipts returns all room receipts of a user
+func (r *MongoReadReceiptRepository) GetReceipts(username string) ([]*RoomReadReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.receipts.Find(ctx, bson.M{"username": username})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read receipts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var receipts []*RoomReadReceipt
+	if err := cursor.All(ctx, &receipts); err != nil {
+		return nil, fmt.Errorf("failed to decode read receipts: %v", err)
+	}
+	return receipts, nil
+}
+
+// CountUnread counts newer messages in the receipt's room that were not sent by the reader
+func (r *MongoReadReceiptRepository) CountUnread(receipt *RoomReadReceipt, limit int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"room_name":  receipt.RoomName,
+		"timestamp":  bson.M{"$gt": receipt.MessageTime},
+		"username":   bson.M{"$ne": receipt.Username},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	opts := options.Count()
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	count, err := r.messages.CountDocuments(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread messages: %v", err)
+	}
+	return count, nil
+}
@@ -1,5 +1,7 @@
 package message
 
+import "time"
+
 // Repository interface for message persistence
 type Repository interface {
 	// Basic message operations
@@ -16,6 +18,17 @@ type Repository interface {
 	
 	// Search operations
 	SearchMessages(query string, roomName string, limit int) ([]*Message, error)
+	SearchMessagesText(query SearchQuery) (results []*ScoredMessage, nextCursor string, err error)
+	
+	// Reaction operations
+	ToggleReaction(messageID, emoji, username string) (added bool, reactions []MessageReaction, err error)
+	
+	// Edit operations (เก็บเนื้อหาเดิมไว้ใน edit history)
+	EditMessage(messageID, content, editedBy string) (*Message, error)
+	SoftDeleteMessage(messageID, deletedBy string) (*Message, error)
+	
+	// Analytics operations
+	GetRoomActivity(roomName string, from, to time.Time, bucket string) ([]ActivityBucket, error)
 }
 
 // EnhancedRepository interface for enhanced message operations
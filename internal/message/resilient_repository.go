@@ -0,0 +1,260 @@
+package message
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/database"
+)
+
+// ResilientRepository wraps a Repository with a circuit breaker.
+// ระหว่างที่ฐานข้อมูลล่ม ข้อความจะถูกเก็บใน buffer และส่งเข้าฐานข้อมูลอีกครั้งเมื่อกลับมาใช้งานได้
+type ResilientRepository struct {
+	Repository
+	breaker       *database.CircuitBreaker
+	journal       *Journal
+	slowThreshold time.Duration
+	buffer        []*Message
+	bufferSize    int
+	dropped       int64
+	mutex         sync.Mutex
+}
+
+// NewResilientRepository creates a repository that degrades gracefully during outages
+func NewResilientRepository(repo Repository, breaker *database.CircuitBreaker, bufferSize int) *ResilientRepository {
+	if bufferSize <= 0 {
+		bufferSize = 1000
+	}
+
+	r := &ResilientRepository{
+		Repository: repo,
+		breaker:    breaker,
+		buffer:     make([]*Message, 0),
+		bufferSize: bufferSize,
+	}
+
+	breaker.OnStateChange(func(from, to database.BreakerState) {
+		if to == database.BreakerClosed {
+			r.replay()
+		}
+	})
+
+	return r
+}
+
+// SetJournal enables the write-ahead journal instead of the in-memory buffer
+func (r *ResilientRepository) SetJournal(journal *Journal, slowThreshold time.Duration) {
+	r.journal = journal
+	r.slowThreshold = slowThreshold
+}
+
+// SaveMessage saves a message or buffers it while the database is unavailable
+func (r *ResilientRepository) SaveMessage(message *Message) error {
+	if !r.breaker.Allow() {
+		r.bufferMessage(message)
+		return nil
+	}
+
+	start := time.Now()
+	if err := r.Repository.SaveMessage(message); err != nil {
+		r.breaker.RecordFailure()
+		r.bufferMessage(message)
+		slog.Warn("⚠️ Message buffered after save failure", "correlation_id", message.CorrelationID, "message_id", message.ID, "error", err)
+		return nil
+	}
+
+	// DB ตอบช้าเกินไป นับเป็นความล้มเหลว เพื่อให้ข้อความถัดไปไปที่ journal แทน
+	if r.journal != nil && r.slowThreshold > 0 && time.Since(start) > r.slowThreshold {
+		slog.Warn("🐢 Slow message save, counting as breaker failure", "correlation_id", message.CorrelationID, "message_id", message.ID, "duration", time.Since(start))
+		r.breaker.RecordFailure()
+		return nil
+	}
+
+	r.breaker.RecordSuccess()
+	return nil
+}
+
+// GetMessageHistory fails fast while the database is unavailable
+func (r *ResilientRepository) GetMessageHistory(roomName string, limit int) ([]*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	messages, err := r.Repository.GetMessageHistory(roomName, limit)
+	r.record(err)
+	return messages, err
+}
+
+// GetRecentMessages fails fast while the database is unavailable
+func (r *ResilientRepository) GetRecentMessages(limit int) ([]*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	messages, err := r.Repository.GetRecentMessages(limit)
+	r.record(err)
+	return messages, err
+}
+
+// GetUserMessageHistory fails fast while the database is unavailable
+func (r *ResilientRepository) GetUserMessageHistory(username string, limit int) ([]*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	messages, err := r.Repository.GetUserMessageHistory(username, limit)
+	r.record(err)
+	return messages, err
+}
+
+// GetMessageCount fails fast while the database is unavailable
+func (r *ResilientRepository) GetMessageCount(roomName string) (int64, error) {
+	if !r.breaker.Allow() {
+		return 0, errDatabaseUnavailable
+	}
+	count, err := r.Repository.GetMessageCount(roomName)
+	r.record(err)
+	return count, err
+}
+
+// SearchMessages fails fast while the database is unavailable
+func (r *ResilientRepository) SearchMessages(query string, roomName string, limit int) ([]*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	messages, err := r.Repository.SearchMessages(query, roomName, limit)
+	r.record(err)
+	return messages, err
+}
+
+// SearchMessagesText fails fast while the database is unavailable
+func (r *ResilientRepository) SearchMessagesText(query SearchQuery) ([]*ScoredMessage, string, error) {
+	if !r.breaker.Allow() {
+		return nil, "", errDatabaseUnavailable
+	}
+	results, nextCursor, err := r.Repository.SearchMessagesText(query)
+	r.record(err)
+	return results, nextCursor, err
+}
+
+// ToggleReaction fails fast while the database is unavailable
+func (r *ResilientRepository) ToggleReaction(messageID, emoji, username string) (bool, []MessageReaction, error) {
+	if !r.breaker.Allow() {
+		return false, nil, errDatabaseUnavailable
+	}
+	added, reactions, err := r.Repository.ToggleReaction(messageID, emoji, username)
+	if err == ErrMessageNotFound {
+		// ID ผิดเป็นความผิดของผู้ใช้ ไม่ใช่ฐานข้อมูลล่ม
+		r.breaker.RecordSuccess()
+		return added, reactions, err
+	}
+	r.record(err)
+	return added, reactions, err
+}
+
+// EditMessage fails fast while the database is unavailable
+func (r *ResilientRepository) EditMessage(messageID, content, editedBy string) (*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	message, err := r.Repository.EditMessage(messageID, content, editedBy)
+	r.recordEdit(err)
+	return message, err
+}
+
+// SoftDeleteMessage fails fast while the database is unavailable
+func (r *ResilientRepository) SoftDeleteMessage(messageID, deletedBy string) (*Message, error) {
+	if !r.breaker.Allow() {
+		return nil, errDatabaseUnavailable
+	}
+	message, err := r.Repository.SoftDeleteMessage(messageID, deletedBy)
+	r.recordEdit(err)
+	return message, err
+}
+
+// recordEdit records the outcome of an edit; a missing message is the user's mistake, not an outage
+func (r *ResilientRepository) recordEdit(err error) {
+	if err == ErrMessageNotFound {
+		r.breaker.RecordSuccess()
+		return
+	}
+	r.record(err)
+}
+
+// BufferedCount returns the number of messages waiting to be replayed
+func (r *ResilientRepository) BufferedCount() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.buffer)
+}
+
+// record feeds the result of a database call into the breaker
+func (r *ResilientRepository) record(err error) {
+	if err != nil {
+		r.breaker.RecordFailure()
+	} else {
+		r.breaker.RecordSuccess()
+	}
+}
+
+// bufferMessage keeps a message in the journal (or memory) until the database recovers
+func (r *ResilientRepository) bufferMessage(message *Message) {
+	if r.journal != nil {
+		err := r.journal.Append(message)
+		if err == nil {
+			return
+		}
+		slog.Error("❌ Failed to journal message, keeping in memory", "correlation_id", message.CorrelationID, "message_id", message.ID, "error", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// buffer เต็ม ทิ้งข้อความที่เก่าที่สุด
+	if len(r.buffer) >= r.bufferSize {
+		r.buffer = r.buffer[1:]
+		r.dropped++
+		slog.Warn("⚠️ Message buffer full, dropped oldest message", "total_dropped", r.dropped)
+	}
+
+	r.buffer = append(r.buffer, message)
+}
+
+// replay writes buffered messages back into the database
+func (r *ResilientRepository) replay() {
+	if r.journal != nil {
+		if count, err := r.journal.Drain(r.Repository); err != nil {
+			slog.Error("❌ Journal replay stopped", "replayed", count, "error", err)
+			r.breaker.RecordFailure()
+			return
+		} else if count > 0 {
+			slog.Info("✅ Replayed journaled messages", "count", count)
+		}
+	}
+
+	r.mutex.Lock()
+	pending := r.buffer
+	r.buffer = make([]*Message, 0)
+	r.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	slog.Info("🔄 Replaying buffered messages to database", "count", len(pending))
+
+	for i, message := range pending {
+		if err := r.Repository.SaveMessage(message); err != nil {
+			slog.Error("❌ Replay failed", "replayed", i, "pending", len(pending), "error", err)
+
+			// นำข้อความที่เหลือกลับเข้า buffer แล้วรอรอบถัดไป
+			r.mutex.Lock()
+			r.buffer = append(pending[i:], r.buffer...)
+			r.mutex.Unlock()
+			r.breaker.RecordFailure()
+			return
+		}
+	}
+
+	slog.Info("✅ Replayed buffered messages", "count", len(pending))
+}
+
+var errDatabaseUnavailable = database.ErrUnavailable
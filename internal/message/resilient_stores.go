@@ -0,0 +1,225 @@
+package message
+
+import "realtime-chat/internal/database"
+
+// Breaker-guarded wrappers for the MongoDB-only repositories (direct messages, threads, timeline,
+// read receipts, mailbox, notifications). ระหว่างที่ breaker เปิดทุก call ตอบ errDatabaseUnavailable ทันที
+// แทนที่จะรอ timeout; ไม่ป้อนผลกลับเข้า breaker เพราะ error บางส่วนเป็นความผิดของผู้ใช้ (health monitor เป็นผู้เปิด/ปิด)
+
+// ResilientDirectMessageRepository wraps a DirectMessageRepository with the circuit breaker
+type ResilientDirectMessageRepository struct {
+	DirectMessageRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientDirectMessageRepository creates a direct message repository that fails fast during outages
+func NewResilientDirectMessageRepository(repo DirectMessageRepository, breaker *database.CircuitBreaker) *ResilientDirectMessageRepository {
+	return &ResilientDirectMessageRepository{DirectMessageRepository: repo, breaker: breaker}
+}
+
+// SaveDirectMessage fails fast while the database is unavailable
+func (r *ResilientDirectMessageRepository) SaveDirectMessage(message *DirectMessage) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.DirectMessageRepository.SaveDirectMessage(message)
+}
+
+// GetConversation fails fast while the database is unavailable
+func (r *ResilientDirectMessageRepository) GetConversation(userA, userB string, limit int) ([]*DirectMessage, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.DirectMessageRepository.GetConversation(userA, userB, limit)
+}
+
+// ResilientThreadRepository wraps a ThreadRepository with the circuit breaker
+type ResilientThreadRepository struct {
+	ThreadRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientThreadRepository creates a thread repository that fails fast during outages
+func NewResilientThreadRepository(repo ThreadRepository, breaker *database.CircuitBreaker) *ResilientThreadRepository {
+	return &ResilientThreadRepository{ThreadRepository: repo, breaker: breaker}
+}
+
+// RecordReply fails fast while the database is unavailable
+func (r *ResilientThreadRepository) RecordReply(parent, reply *Message) (*Thread, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.ThreadRepository.RecordReply(parent, reply)
+}
+
+// GetThread fails fast while the database is unavailable
+func (r *ResilientThreadRepository) GetThread(parentID string) (*Thread, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.ThreadRepository.GetThread(parentID)
+}
+
+// GetReplies fails fast while the database is unavailable
+func (r *ResilientThreadRepository) GetReplies(parentID string, limit int) ([]*Message, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.ThreadRepository.GetReplies(parentID, limit)
+}
+
+// ResilientTimelineRepository wraps a TimelineRepository with the circuit breaker
+type ResilientTimelineRepository struct {
+	TimelineRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientTimelineRepository creates a timeline repository that fails fast during outages
+func NewResilientTimelineRepository(repo TimelineRepository, breaker *database.CircuitBreaker) *ResilientTimelineRepository {
+	return &ResilientTimelineRepository{TimelineRepository: repo, breaker: breaker}
+}
+
+// NextSeq fails fast while the database is unavailable
+func (r *ResilientTimelineRepository) NextSeq(roomName string) (int64, error) {
+	if r.breaker.IsOpen() {
+		return 0, errDatabaseUnavailable
+	}
+	return r.TimelineRepository.NextSeq(roomName)
+}
+
+// RecordEvent fails fast while the database is unavailable
+func (r *ResilientTimelineRepository) RecordEvent(event *RoomEvent) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.TimelineRepository.RecordEvent(event)
+}
+
+// GetTimeline fails fast while the database is unavailable
+func (r *ResilientTimelineRepository) GetTimeline(roomName string, after, before int64, limit int) ([]*TimelineEntry, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.TimelineRepository.GetTimeline(roomName, after, before, limit)
+}
+
+// ResilientReadReceiptRepository wraps a ReadReceiptRepository with the circuit breaker
+type ResilientReadReceiptRepository struct {
+	ReadReceiptRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientReadReceiptRepository creates a read receipt repository that fails fast during outages
+func NewResilientReadReceiptRepository(repo ReadReceiptRepository, breaker *database.CircuitBreaker) *ResilientReadReceiptRepository {
+	return &ResilientReadReceiptRepository{ReadReceiptRepository: repo, breaker: breaker}
+}
+
+// MarkRead fails fast while the database is unavailable
+func (r *ResilientReadReceiptRepository) MarkRead(receipt *RoomReadReceipt) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.ReadReceiptRepository.MarkRead(receipt)
+}
+
+// GetReceipts fails fast while the database is unavailable
+func (r *ResilientReadReceiptRepository) GetReceipts(username string) ([]*RoomReadReceipt, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.ReadReceiptRepository.GetReceipts(username)
+}
+
+// CountUnread fails fast while the database is unavailable
+func (r *ResilientReadReceiptRepository) CountUnread(receipt *RoomReadReceipt, limit int64) (int64, error) {
+	if r.breaker.IsOpen() {
+		return 0, errDatabaseUnavailable
+	}
+	return r.ReadReceiptRepository.CountUnread(receipt, limit)
+}
+
+// ResilientMailboxRepository wraps a MailboxRepository with the circuit breaker
+type ResilientMailboxRepository struct {
+	MailboxRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientMailboxRepository creates a mailbox repository that fails fast during outages
+func NewResilientMailboxRepository(repo MailboxRepository, breaker *database.CircuitBreaker) *ResilientMailboxRepository {
+	return &ResilientMailboxRepository{MailboxRepository: repo, breaker: breaker}
+}
+
+// Enqueue fails fast while the database is unavailable
+func (r *ResilientMailboxRepository) Enqueue(message *PendingMessage) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.MailboxRepository.Enqueue(message)
+}
+
+// GetUnread fails fast while the database is unavailable
+func (r *ResilientMailboxRepository) GetUnread(recipient string, limit int) ([]*PendingMessage, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.MailboxRepository.GetUnread(recipient, limit)
+}
+
+// MarkDelivered fails fast while the database is unavailable
+func (r *ResilientMailboxRepository) MarkDelivered(recipient string, ids []string) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.MailboxRepository.MarkDelivered(recipient, ids)
+}
+
+// MarkRead fails fast while the database is unavailable
+func (r *ResilientMailboxRepository) MarkRead(recipient string, ids []string) (int64, error) {
+	if r.breaker.IsOpen() {
+		return 0, errDatabaseUnavailable
+	}
+	return r.MailboxRepository.MarkRead(recipient, ids)
+}
+
+// ResilientNotificationRepository wraps a NotificationRepository with the circuit breaker
+type ResilientNotificationRepository struct {
+	NotificationRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientNotificationRepository creates a notification repository that fails fast during outages
+func NewResilientNotificationRepository(repo NotificationRepository, breaker *database.CircuitBreaker) *ResilientNotificationRepository {
+	return &ResilientNotificationRepository{NotificationRepository: repo, breaker: breaker}
+}
+
+// Create fails fast while the database is unavailable
+func (r *ResilientNotificationRepository) Create(notification *Notification) error {
+	if r.breaker.IsOpen() {
+		return errDatabaseUnavailable
+	}
+	return r.NotificationRepository.Create(notification)
+}
+
+// List fails fast while the database is unavailable
+func (r *ResilientNotificationRepository) List(userID string, unreadOnly bool, limit int) ([]*Notification, error) {
+	if r.breaker.IsOpen() {
+		return nil, errDatabaseUnavailable
+	}
+	return r.NotificationRepository.List(userID, unreadOnly, limit)
+}
+
+// CountUnread fails fast while the database is unavailable
+func (r *ResilientNotificationRepository) CountUnread(userID string) (int64, error) {
+	if r.breaker.IsOpen() {
+		return 0, errDatabaseUnavailable
+	}
+	return r.NotificationRepository.CountUnread(userID)
+}
+
+// MarkRead fails fast while the database is unavailable
+func (r *ResilientNotificationRepository) MarkRead(userID string, ids []string) (int64, error) {
+	if r.breaker.IsOpen() {
+		return 0, errDatabaseUnavailable
+	}
+	return r.NotificationRepository.MarkRead(userID, ids)
+}
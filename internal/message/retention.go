@@ -0,0 +1,243 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultRetentionScope is the stats key for rooms that use the server default retention
+const defaultRetentionScope = "*"
+
+// RetentionScope selects the rooms a purge applies to
+type RetentionScope struct {
+	Room         string   // ห้องเดียว (ว่าง = ทุกห้อง)
+	ExcludeRooms []string // ห้องที่มี retention ของตัวเอง จึงไม่ใช้ค่า default
+}
+
+// RetentionStore removes messages older than a cutoff, one batch at a time
+type RetentionStore interface {
+	PurgeBatch(scope RetentionScope, before time.Time, batchSize int) (int, error)
+}
+
+// RetentionPolicy returns the rooms that override the default retention (room name -> retention)
+type RetentionPolicy func() map[string]time.Duration
+
+// RetentionStats holds retention janitor counters
+type RetentionStats struct {
+	DefaultRetention string           `json:"default_retention"` // "0s" = เก็บตลอดไป
+	Archive          bool             `json:"archive"`
+	Purged           int64            `json:"purged"`
+	PurgedByRoom     map[string]int64 `json:"purged_by_room"` // "*" = ห้องที่ใช้ค่า default
+	Runs             int64            `json:"runs"`
+	LastRun          time.Time        `json:"last_run"`
+	LastDuration     string           `json:"last_duration"`
+	LastError        string           `json:"last_error,omitempty"`
+}
+
+// Janitor deletes (or archives) messages older than their room's retention window.
+// ลบทีละ batch ด้วย _id เพื่อไม่ให้ DeleteMany ก้อนใหญ่ล็อก collection นาน
+// และห้องที่ตั้ง retention เองใช้ค่าของห้อง ห้องอื่นใช้ค่า default ของ server
+type Janitor struct {
+	store   RetentionStore
+	policy  RetentionPolicy
+	config  *config.ServerConfig
+	metrics *config.ServerMetrics
+	purged  int64
+	byScope map[string]int64
+	runs    int64
+	lastRun time.Time
+	lastDur time.Duration
+	lastErr string
+	mutex   sync.Mutex
+}
+
+// NewJanitor creates a retention janitor; policy may be nil when no room has its own retention
+func NewJanitor(store RetentionStore, policy RetentionPolicy, cfg *config.ServerConfig, metrics *config.ServerMetrics) *Janitor {
+	return &Janitor{
+		store:   store,
+		policy:  policy,
+		config:  cfg,
+		metrics: metrics,
+		byScope: make(map[string]int64),
+	}
+}
+
+// Run purges expired messages of every room; meant to be called by the scheduler
+func (j *Janitor) Run() {
+	started := time.Now()
+	overrides := map[string]time.Duration{}
+	if j.policy != nil {
+		overrides = j.policy()
+	}
+
+	total := 0
+	var firstErr error
+	rooms := make([]string, 0, len(overrides))
+	for roomName, retention := range overrides {
+		rooms = append(rooms, roomName)
+		count, err := j.purge(roomName, RetentionScope{Room: roomName}, started.Add(-retention))
+		total += count
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// ห้องที่ไม่ได้ตั้งค่าเอง (รวมห้องที่ถูก archive ไปแล้ว) ใช้ค่า default
+	if retention := j.config.MessageRetention; retention > 0 {
+		count, err := j.purge(defaultRetentionScope, RetentionScope{ExcludeRooms: rooms}, started.Add(-retention))
+		total += count
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	j.mutex.Lock()
+	j.runs++
+	j.lastRun = started
+	j.lastDur = time.Since(started)
+	j.lastErr = ""
+	if firstErr != nil {
+		j.lastErr = firstErr.Error()
+	}
+	j.mutex.Unlock()
+
+	if firstErr != nil {
+		slog.Warn("⚠️ Message retention run incomplete", "error", firstErr)
+	}
+	if total > 0 {
+		slog.Info("🗓️ Purged messages past retention", "count", total, "took", time.Since(started).Round(time.Millisecond))
+	}
+}
+
+// purge removes batches until a short batch shows nothing older than the cutoff is left
+func (j *Janitor) purge(key string, scope RetentionScope, before time.Time) (int, error) {
+	batchSize := j.config.RetentionBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	total := 0
+	for {
+		count, err := j.store.PurgeBatch(scope, before, batchSize)
+		if count > 0 {
+			total += count
+			j.record(key, count)
+		}
+		if err != nil {
+			return total, fmt.Errorf("room %s: %v", key, err)
+		}
+		if count < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// record adds purged messages to the counters
+func (j *Janitor) record(key string, count int) {
+	j.mutex.Lock()
+	j.purged += int64(count)
+	j.byScope[key] += int64(count)
+	j.mutex.Unlock()
+
+	if j.metrics != nil {
+		j.metrics.AddPurgedMessages(count)
+	}
+}
+
+// Stats returns retention counters
+func (j *Janitor) Stats() RetentionStats {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	stats := RetentionStats{
+		DefaultRetention: j.config.MessageRetention.String(),
+		Archive:          j.config.RetentionArchive,
+		Purged:           j.purged,
+		PurgedByRoom:     make(map[string]int64, len(j.byScope)),
+		Runs:             j.runs,
+		LastRun:          j.lastRun,
+		LastDuration:     j.lastDur.String(),
+		LastError:        j.lastErr,
+	}
+	for key, count := range j.byScope {
+		stats.PurgedByRoom[key] = count
+	}
+	return stats
+}
+
+// MongoRetentionStore purges messages from MongoDB, optionally copying them to messages_archive first
+type MongoRetentionStore struct {
+	messages *mongo.Collection
+	archive  *mongo.Collection // nil = ลบทิ้ง
+}
+
+// NewMongoRetentionStore creates a MongoDB retention store
+func NewMongoRetentionStore(db *database.MongoDB, archive bool) *MongoRetentionStore {
+	store := &MongoRetentionStore{messages: db.GetCollection("messages")}
+	if archive {
+		store.archive = db.GetCollection("messages_archive")
+	}
+	return store
+}
+
+// PurgeBatch deletes (or archives) up to batchSize of the oldest messages in scope older than before
+func (s *MongoRetentionStore) PurgeBatch(scope RetentionScope, before time.Time, batchSize int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"timestamp": bson.M{"$lt": before}}
+	if scope.Room != "" {
+		filter["room_name"] = scope.Room
+	} else if len(scope.ExcludeRooms) > 0 {
+		filter["room_name"] = bson.M{"$nin": scope.ExcludeRooms}
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"timestamp": 1}).SetLimit(int64(batchSize))
+	if s.archive == nil {
+		findOptions.SetProjection(bson.M{"_id": 1})
+	}
+	cursor, err := s.messages.Find(ctx, filter, findOptions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired messages: %v", err)
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, fmt.Errorf("failed to decode expired messages: %v", err)
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]interface{}, 0, len(docs))
+	archived := make([]interface{}, 0, len(docs))
+	now := time.Now()
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"])
+		if s.archive != nil {
+			doc["archived_at"] = now
+			archived = append(archived, doc)
+		}
+	}
+
+	// คัดลอกก่อนลบ ถ้ารอบก่อนคัดลอกแล้วแต่ลบไม่สำเร็จ _id ซ้ำถือว่าคัดลอกแล้ว
+	if s.archive != nil {
+		if _, err := s.archive.InsertMany(ctx, archived, options.InsertMany().SetOrdered(false)); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return 0, fmt.Errorf("failed to archive messages: %v", err)
+		}
+	}
+
+	result, err := s.messages.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %v", err)
+	}
+	return int(result.DeletedCount), nil
+}
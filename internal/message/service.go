@@ -2,7 +2,7 @@ package message
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -53,7 +53,7 @@ func (s *service) SendMessage(message *Message) error {
 		return fmt.Errorf("failed to save message: %v", err)
 	}
 
-	log.Printf("💬 Message sent by %s in room %s: %s", message.Username, message.RoomName, message.Content)
+	slog.Debug("💬 Message sent", "username", message.Username, "room", message.RoomName, "message_id", message.ID)
 	return nil
 }
 
@@ -81,7 +81,7 @@ func (s *service) UpdateMessage(message *Message) error {
 		return fmt.Errorf("failed to update message: %v", err)
 	}
 
-	log.Printf("✏️ Message updated by %s: %s", message.Username, message.Content)
+	slog.Debug("✏️ Message updated", "username", message.Username, "message_id", message.ID)
 	return nil
 }
 
@@ -95,7 +95,7 @@ func (s *service) DeleteMessage(messageID string) error {
 		return fmt.Errorf("failed to delete message: %v", err)
 	}
 
-	log.Printf("🗑️ Message deleted: %s", messageID)
+	slog.Debug("🗑️ Message deleted", "message_id", messageID)
 	return nil
 }
 
@@ -0,0 +1,143 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ThreadRepository keeps thread summaries and lists the replies to a message
+type ThreadRepository interface {
+	RecordReply(parent, reply *Message) (*Thread, error)
+	GetThread(parentID string) (*Thread, error)                // nil ถ้ายังไม่มีใครตอบ
+	GetReplies(parentID string, limit int) ([]*Message, error) // เรียงจากเก่าไปใหม่
+}
+
+// MongoThreadRepository implements ThreadRepository using MongoDB
+type MongoThreadRepository struct {
+	threads  *mongo.Collection
+	messages *mongo.Collection
+}
+
+// NewMongoThreadRepository creates a new MongoDB thread repository
+func NewMongoThreadRepository(db *database.MongoDB) ThreadRepository {
+	return &MongoThreadRepository{
+		threads:  db.GetCollection("threads"),
+		messages: db.GetCollection("messages"),
+	}
+}
+
+// RecordReply creates the parent's thread on its first reply and updates the reply count and last reply time.
+// ใช้ upsert ครั้งเดียวต่อ reply ทำให้ reply_count ถูกต้องแม้มีหลายคนตอบพร้อมกันจากหลาย node
+func (r *MongoThreadRepository) RecordReply(parent, reply *Message) (*Thread, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	parentID, err := primitive.ObjectIDFromHex(parent.ID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$inc":      bson.M{"reply_count": 1},
+		"$max":      bson.M{"last_reply_at": reply.Timestamp},
+		"$set":      bson.M{"updated_at": now},
+		"$addToSet": bson.M{"participants": reply.Username},
+		"$setOnInsert": bson.M{
+			"room_name":  parent.RoomName,
+			"created_by": parent.Username,
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc ThreadDocument
+	err = r.threads.FindOneAndUpdate(ctx, bson.M{"parent_id": parentID}, update, opts).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		// reply แรกสองอันสร้าง thread พร้อมกัน อีกอันสร้างเสร็จแล้วจึงลองใหม่เป็น update ธรรมดา
+		err = r.threads.FindOneAndUpdate(ctx, bson.M{"parent_id": parentID}, update, opts).Decode(&doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update thread: %v", err)
+	}
+	return doc.ToThread(), nil
+}
+
+// GetThread retrieves the thread summary of a message
+func (r *MongoThreadRepository) GetThread(parentID string) (*Thread, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	var doc ThreadDocument
+	err = r.threads.FindOne(ctx, bson.M{"parent_id": objID}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get thread: %v", err)
+	}
+	return doc.ToThread(), nil
+}
+
+// GetReplies retrieves the earliest replies to a message
+func (r *MongoThreadRepository) GetReplies(parentID string, limit int) ([]*Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.messages.Find(ctx, bson.M{"parent_id": objID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replies: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []MessageDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode replies: %v", err)
+	}
+
+	replies := make([]*Message, 0, len(docs))
+	for i := range docs {
+		replies = append(replies, docs[i].ToMessage())
+	}
+	return replies, nil
+}
+
+// ToThread converts ThreadDocument to Thread
+func (doc *ThreadDocument) ToThread() *Thread {
+	return &Thread{
+		ID:           doc.ID.Hex(),
+		ParentID:     doc.ParentID.Hex(),
+		RoomName:     doc.RoomName,
+		CreatedBy:    doc.CreatedBy,
+		ReplyCount:   doc.ReplyCount,
+		LastReplyAt:  doc.LastReplyAt,
+		Participants: doc.Participants,
+		CreatedAt:    doc.CreatedAt,
+		UpdatedAt:    doc.UpdatedAt,
+	}
+}
@@ -0,0 +1,211 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Timeline entry types
+const (
+	TimelineMessage = "message"
+	TimelineJoin    = "join"
+	TimelineLeave   = "leave"
+)
+
+// RoomEvent is a non-message event in a room's timeline, such as a join or a leave
+type RoomEvent struct {
+	ID        string    `json:"id"`
+	RoomName  string    `json:"room_name"`
+	Seq       int64     `json:"seq"`
+	Type      string    `json:"type"`
+	Username  string    `json:"username"`
+	Detail    string    `json:"detail,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RoomEventDocument represents the MongoDB document structure for room events
+type RoomEventDocument struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomName  string             `bson:"room_name"`
+	Seq       int64              `bson:"seq"`
+	Type      string             `bson:"type"`
+	Username  string             `bson:"username"`
+	Detail    string             `bson:"detail,omitempty"`
+	Timestamp time.Time          `bson:"timestamp"`
+}
+
+// TimelineEntry is one item of a room timeline: either a message or a room event
+type TimelineEntry struct {
+	Seq       int64      `json:"seq"`
+	Type      string     `json:"type"`
+	Username  string     `json:"username"`
+	Timestamp time.Time  `json:"timestamp"`
+	Message   *Message   `json:"message,omitempty"`
+	Event     *RoomEvent `json:"event,omitempty"`
+}
+
+// TimelineRepository assigns per-room sequence numbers and reads messages and room events as one feed
+type TimelineRepository interface {
+	NextSeq(roomName string) (int64, error)
+	RecordEvent(event *RoomEvent) error
+	// GetTimeline returns entries with seq > after (or seq < before when before > 0), oldest first
+	GetTimeline(roomName string, after, before int64, limit int) ([]*TimelineEntry, error)
+}
+
+// MongoTimelineRepository implements TimelineRepository using MongoDB
+type MongoTimelineRepository struct {
+	sequences *mongo.Collection
+	events    *mongo.Collection
+	messages  *mongo.Collection
+}
+
+// NewMongoTimelineRepository creates a new MongoDB timeline repository
+func NewMongoTimelineRepository(db *database.MongoDB) TimelineRepository {
+	return &MongoTimelineRepository{
+		sequences: db.GetCollection("room_sequences"),
+		events:    db.GetCollection("room_events"),
+		messages:  db.GetCollection("messages"),
+	}
+}
+
+// NextSeq atomically allocates the next sequence number of a room.
+// ใช้ counter เดียวกันทั้งข้อความและ event ลำดับจึงถูกต้องแม้มีหลาย node เขียนห้องเดียวกัน
+func (r *MongoTimelineRepository) NextSeq(roomName string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := r.sequences.FindOneAndUpdate(ctx, bson.M{"_id": roomName}, bson.M{"$inc": bson.M{"seq": int64(1)}}, opts).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate sequence for room %s: %v", roomName, err)
+	}
+	return counter.Seq, nil
+}
+
+// RecordEvent assigns the next sequence number to an event and saves it
+func (r *MongoTimelineRepository) RecordEvent(event *RoomEvent) error {
+	seq, err := r.NextSeq(event.RoomName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	doc := &RoomEventDocument{
+		RoomName:  event.RoomName,
+		Seq:       seq,
+		Type:      event.Type,
+		Username:  event.Username,
+		Detail:    event.Detail,
+		Timestamp: event.Timestamp,
+	}
+	result, err := r.events.InsertOne(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to save room event: %v", err)
+	}
+
+	event.Seq = seq
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		event.ID = oid.Hex()
+	}
+	return nil
+}
+
+// GetTimeline merges messages and room events of a room ordered by sequence number
+func (r *MongoTimelineRepository) GetTimeline(roomName string, after, before int64, limit int) ([]*TimelineEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 50
+	}
+
+	// แต่ละ collection ดึงมาไม่เกิน limit แล้วค่อย merge ตาม seq จึงได้หน้าเดียวกับการ query collection รวม
+	seqRange := bson.M{"$gt": after}
+	sortOrder := 1
+	if before > 0 {
+		seqRange = bson.M{"$lt": before, "$gt": int64(0)}
+		sortOrder = -1
+	}
+	filter := bson.M{"room_name": roomName, "seq": seqRange}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: sortOrder}}).SetLimit(int64(limit))
+
+	var messageDocs []MessageDocument
+	cursor, err := r.messages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timeline messages: %v", err)
+	}
+	if err := cursor.All(ctx, &messageDocs); err != nil {
+		return nil, fmt.Errorf("failed to decode timeline messages: %v", err)
+	}
+
+	var eventDocs []RoomEventDocument
+	cursor, err = r.events.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room events: %v", err)
+	}
+	if err := cursor.All(ctx, &eventDocs); err != nil {
+		return nil, fmt.Errorf("failed to decode room events: %v", err)
+	}
+
+	entries := make([]*TimelineEntry, 0, len(messageDocs)+len(eventDocs))
+	for i := range messageDocs {
+		message := messageDocs[i].ToMessage()
+		entries = append(entries, &TimelineEntry{
+			Seq:       message.Seq,
+			Type:      TimelineMessage,
+			Username:  message.Username,
+			Timestamp: message.Timestamp,
+			Message:   message,
+		})
+	}
+	for i := range eventDocs {
+		event := eventDocs[i].ToRoomEvent()
+		entries = append(entries, &TimelineEntry{
+			Seq:       event.Seq,
+			Type:      event.Type,
+			Username:  event.Username,
+			Timestamp: event.Timestamp,
+			Event:     event,
+		})
+	}
+
+	if before > 0 {
+		// หน้าก่อน before: เก็บ limit รายการที่ใหม่ที่สุด
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Seq > entries[j].Seq })
+		if len(entries) > limit {
+			entries = entries[:limit]
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// ToRoomEvent converts RoomEventDocument to RoomEvent
+func (doc *RoomEventDocument) ToRoomEvent() *RoomEvent {
+	return &RoomEvent{
+		ID:        doc.ID.Hex(),
+		RoomName:  doc.RoomName,
+		Seq:       doc.Seq,
+		Type:      doc.Type,
+		Username:  doc.Username,
+		Detail:    doc.Detail,
+		Timestamp: doc.Timestamp,
+	}
+}
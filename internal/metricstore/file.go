@@ -0,0 +1,146 @@
+package metricstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore keeps snapshots in a local JSON-lines file for deployments without MongoDB.
+// โหลดทั้งไฟล์ไว้ในหน่วยความจำ (retention จำกัดขนาดอยู่แล้ว) และเขียนใหม่ทั้งไฟล์ตอน prune
+type FileStore struct {
+	path      string
+	snapshots []Snapshot
+	mutex     sync.Mutex
+}
+
+// NewFileStore opens (or creates) a metrics history file
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create metrics directory: %v", err)
+	}
+
+	store := &FileStore{path: path}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metrics file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			// บรรทัดที่เขียนไม่ครบตอน crash ข้ามไป
+			continue
+		}
+		store.snapshots = append(store.snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics file: %v", err)
+	}
+	return store, nil
+}
+
+// Append writes a snapshot to the end of the file
+func (s *FileStore) Append(snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics snapshot: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	return nil
+}
+
+// Since returns a node's snapshots taken at or after since, oldest first
+func (s *FileStore) Since(nodeID string, since time.Time) ([]Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := make([]Snapshot, 0)
+	for _, snapshot := range s.snapshots {
+		if snapshot.NodeID == nodeID && !snapshot.Time.Before(since) {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+// Latest returns a node's most recent snapshot
+func (s *FileStore) Latest(nodeID string) (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i].NodeID == nodeID {
+			snapshot := s.snapshots[i]
+			return &snapshot, nil
+		}
+	}
+	return nil, nil
+}
+
+// Prune drops snapshots older than before and rewrites the file
+func (s *FileStore) Prune(before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	kept := make([]Snapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		if !snapshot.Time.Before(before) {
+			kept = append(kept, snapshot)
+		}
+	}
+	pruned := len(s.snapshots) - len(kept)
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	// เขียนไฟล์ชั่วคราวแล้ว rename เพื่อไม่ให้ไฟล์เสียถ้า crash กลางทาง
+	tmpPath := s.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewrite metrics file: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, snapshot := range kept {
+		if err := encoder.Encode(snapshot); err != nil {
+			file.Close()
+			return 0, fmt.Errorf("failed to rewrite metrics file: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to rewrite metrics file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to rewrite metrics file: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return 0, fmt.Errorf("failed to replace metrics file: %v", err)
+	}
+
+	s.snapshots = kept
+	return pruned, nil
+}
@@ -0,0 +1,92 @@
+package metricstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore stores snapshots in the "metrics" collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a MongoDB-backed metrics store
+func NewMongoStore(db *database.MongoDB) (*MongoStore, error) {
+	store := &MongoStore{collection: db.GetCollection("metrics")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := store.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "node_id", Value: 1}, {Key: "time", Value: 1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics index: %v", err)
+	}
+	return store, nil
+}
+
+// Append inserts a snapshot
+func (s *MongoStore) Append(snapshot Snapshot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.collection.InsertOne(ctx, snapshot); err != nil {
+		return fmt.Errorf("failed to save metrics snapshot: %v", err)
+	}
+	return nil
+}
+
+// Since returns a node's snapshots taken at or after since, oldest first
+func (s *MongoStore) Since(nodeID string, since time.Time) ([]Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"node_id": nodeID, "time": bson.M{"$gte": since}}
+	cursor, err := s.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "time", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics history: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]Snapshot, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode metrics history: %v", err)
+	}
+	return snapshots, nil
+}
+
+// Latest returns a node's most recent snapshot
+func (s *MongoStore) Latest(nodeID string) (*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var snapshot Snapshot
+	opts := options.FindOne().SetSort(bson.D{{Key: "time", Value: -1}})
+	if err := s.collection.FindOne(ctx, bson.M{"node_id": nodeID}, opts).Decode(&snapshot); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load latest metrics snapshot: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// Prune deletes snapshots older than before
+func (s *MongoStore) Prune(before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"time": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune metrics history: %v", err)
+	}
+	return int(result.DeletedCount), nil
+}
@@ -0,0 +1,138 @@
+package metricstore
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// Snapshot is a point-in-time copy of the cumulative server counters
+type Snapshot struct {
+	NodeID            string    `json:"node_id" bson:"node_id"`
+	Time              time.Time `json:"time" bson:"time"`
+	TotalConnections  int64     `json:"total_connections" bson:"total_connections"`
+	ActiveConnections int64     `json:"active_connections" bson:"active_connections"`
+	TotalMessages     int64     `json:"total_messages" bson:"total_messages"`
+	TotalCommands     int64     `json:"total_commands" bson:"total_commands"`
+	TotalRooms        int64     `json:"total_rooms" bson:"total_rooms"`
+	TotalUsers        int64     `json:"total_users" bson:"total_users"`
+}
+
+// Store persists metrics snapshots
+type Store interface {
+	Append(snapshot Snapshot) error
+	Since(nodeID string, since time.Time) ([]Snapshot, error) // เรียงตามเวลาจากเก่าไปใหม่
+	Latest(nodeID string) (*Snapshot, error)                  // nil เมื่อยังไม่มี snapshot
+	Prune(before time.Time) (int, error)
+}
+
+// Trend summarises counter changes within a time window
+type Trend struct {
+	Window            time.Duration `json:"window"`
+	Samples           int           `json:"samples"`
+	Messages          int64         `json:"messages"`
+	Commands          int64         `json:"commands"`
+	Connections       int64         `json:"connections"`
+	PeakConnections   int64         `json:"peak_connections"`
+	MessagesPerMinute float64       `json:"messages_per_minute"`
+}
+
+// History snapshots ServerMetrics into a Store and answers trend queries.
+// เก็บค่าสะสม จึงกู้ตัวนับกลับมาได้หลัง restart และหา delta ในช่วงเวลาได้
+type History struct {
+	store     Store
+	metrics   *config.ServerMetrics
+	nodeID    string
+	retention time.Duration
+}
+
+// NewHistory creates a metrics history for this node
+func NewHistory(store Store, metrics *config.ServerMetrics, nodeID string, retention time.Duration) *History {
+	return &History{
+		store:     store,
+		metrics:   metrics,
+		nodeID:    nodeID,
+		retention: retention,
+	}
+}
+
+// Restore seeds cumulative counters from the last persisted snapshot so a restart doesn't reset them
+func (h *History) Restore() error {
+	latest, err := h.store.Latest(h.nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to load last metrics snapshot: %v", err)
+	}
+	if latest == nil {
+		return nil
+	}
+
+	h.metrics.RestoreTotals(latest.TotalConnections, latest.TotalMessages, latest.TotalCommands)
+	slog.Info("📈 Restored metrics counters", "snapshot", latest.Time.Format(time.RFC3339), "messages", latest.TotalMessages, "commands", latest.TotalCommands)
+	return nil
+}
+
+// Record persists the current counters and drops snapshots older than the retention
+func (h *History) Record() {
+	current := h.metrics.GetMetrics()
+	snapshot := Snapshot{
+		NodeID:            h.nodeID,
+		Time:              time.Now(),
+		TotalConnections:  current.TotalConnections,
+		ActiveConnections: current.ActiveConnections,
+		TotalMessages:     current.TotalMessages,
+		TotalCommands:     current.TotalCommands,
+		TotalRooms:        current.TotalRooms,
+		TotalUsers:        current.TotalUsers,
+	}
+
+	if err := h.store.Append(snapshot); err != nil {
+		slog.Warn("⚠️ Failed to persist metrics snapshot", "error", err)
+		return
+	}
+
+	if h.retention > 0 {
+		if pruned, err := h.store.Prune(time.Now().Add(-h.retention)); err != nil {
+			slog.Warn("⚠️ Failed to prune metrics history", "error", err)
+		} else if pruned > 0 {
+			slog.Info("🧹 Pruned metrics snapshots", "count", pruned, "older_than", h.retention)
+		}
+	}
+}
+
+// Trend compares the live counters with the oldest snapshot inside the window
+func (h *History) Trend(window time.Duration) (Trend, error) {
+	trend := Trend{Window: window}
+
+	snapshots, err := h.store.Since(h.nodeID, time.Now().Add(-window))
+	if err != nil {
+		return trend, err
+	}
+
+	current := h.metrics.GetMetrics()
+	trend.Samples = len(snapshots)
+	trend.PeakConnections = current.ActiveConnections
+	if len(snapshots) == 0 {
+		return trend, nil
+	}
+
+	oldest := snapshots[0]
+	for _, snapshot := range snapshots {
+		if snapshot.ActiveConnections > trend.PeakConnections {
+			trend.PeakConnections = snapshot.ActiveConnections
+		}
+	}
+
+	trend.Messages = current.TotalMessages - oldest.TotalMessages
+	trend.Commands = current.TotalCommands - oldest.TotalCommands
+	trend.Connections = current.TotalConnections - oldest.TotalConnections
+	// ตัวนับที่ไม่ได้กู้คืน (เช่น Restore ล้มเหลว) ทำให้ค่าติดลบได้
+	if trend.Messages < 0 || trend.Commands < 0 || trend.Connections < 0 {
+		trend.Messages, trend.Commands, trend.Connections = 0, 0, 0
+	}
+	if minutes := time.Since(oldest.Time).Minutes(); minutes > 0 {
+		trend.MessagesPerMinute = float64(trend.Messages) / minutes
+	}
+	return trend, nil
+}
@@ -0,0 +1,233 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// lockTTL bounds how long a crashed node can hold the migration lock
+	lockTTL = 10 * time.Minute
+	// migrationTimeout bounds a single migration
+	migrationTimeout = 5 * time.Minute
+)
+
+// Migration is a versioned schema change registered in code
+type Migration struct {
+	Version     int
+	Name        string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the document stored for an applied migration
+type Record struct {
+	Version    int       `bson:"_id" json:"version"`
+	Name       string    `bson:"name" json:"name"`
+	AppliedAt  time.Time `bson:"applied_at" json:"applied_at"`
+	AppliedBy  string    `bson:"applied_by" json:"applied_by"` // node ID
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// Status describes one registered migration and whether it has been applied
+type Status struct {
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// lockDocument is the single document in the migration_lock collection
+type lockDocument struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Runner applies pending migrations in version order, one node at a time
+type Runner struct {
+	db         *database.MongoDB
+	nodeID     string
+	migrations []Migration
+}
+
+// NewRunner creates a migration runner; migrations are sorted by version
+func NewRunner(db *database.MongoDB, nodeID string, migrations []Migration) (*Runner, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version <= 0 || m.Up == nil {
+			return nil, fmt.Errorf("migration %d (%s) must have a positive version and an Up function", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+
+	return &Runner{db: db, nodeID: nodeID, migrations: sorted}, nil
+}
+
+// Status lists every registered migration with its applied state
+func (r *Runner) Status() ([]Status, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name, Description: m.Description}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Apply runs pending migrations under the cluster-wide lock and returns the ones it ran.
+// dryRun=true คืนรายการที่จะรันโดยไม่แตะฐานข้อมูลและไม่ล็อก
+func (r *Runner) Apply(dryRun bool) ([]Status, error) {
+	if dryRun {
+		return r.pending()
+	}
+
+	if err := r.acquireLock(); err != nil {
+		return nil, err
+	}
+	defer r.releaseLock()
+
+	// อ่านสถานะหลังได้ล็อก เพราะ node อื่นอาจเพิ่งรันไปแล้ว
+	pending, err := r.pending()
+	if err != nil {
+		return nil, err
+	}
+
+	records := r.db.GetCollection("schema_migrations")
+	ran := make([]Status, 0, len(pending))
+	for _, status := range pending {
+		m := r.find(status.Version)
+
+		started := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+		err := m.Up(ctx, r.db.GetDatabase())
+		cancel()
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+
+		record := Record{
+			Version:    m.Version,
+			Name:       m.Name,
+			AppliedAt:  time.Now(),
+			AppliedBy:  r.nodeID,
+			DurationMs: time.Since(started).Milliseconds(),
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = records.InsertOne(ctx, record)
+		cancel()
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) ran but could not be recorded: %v", m.Version, m.Name, err)
+		}
+
+		slog.Info("🗄️ Applied migration", "version", m.Version, "name", m.Name, "duration_ms", record.DurationMs)
+		status.Applied = true
+		status.AppliedAt = &record.AppliedAt
+		ran = append(ran, status)
+	}
+	return ran, nil
+}
+
+// pending returns registered migrations that have not been applied, in version order
+func (r *Runner) pending() ([]Status, error) {
+	statuses, err := r.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Status, 0)
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status)
+		}
+	}
+	return pending, nil
+}
+
+// applied loads the records of applied migrations keyed by version
+func (r *Runner) applied() (map[int]Record, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.db.GetCollection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %v", err)
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// find returns the registered migration with the given version
+func (r *Runner) find(version int) Migration {
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return m
+		}
+	}
+	return Migration{}
+}
+
+// acquireLock takes the migration lock, or fails if another live node holds it.
+// upsert จะชน _id ซ้ำเมื่อมี node อื่นถือล็อกที่ยังไม่หมดอายุ
+func (r *Runner) acquireLock() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": "migrations", "expires_at": bson.M{"$lt": now}}
+	update := bson.M{"$set": lockDocument{ID: "migrations", Owner: r.nodeID, ExpiresAt: now.Add(lockTTL)}}
+
+	_, err := r.db.GetCollection("migration_lock").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		var holder lockDocument
+		r.db.GetCollection("migration_lock").FindOne(ctx, bson.M{"_id": "migrations"}).Decode(&holder)
+		return fmt.Errorf("migrations are locked by node %s until %s", holder.Owner, holder.ExpiresAt.Format(time.RFC3339))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	return nil
+}
+
+// releaseLock drops the lock if this node still holds it
+func (r *Runner) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := r.db.GetCollection("migration_lock").DeleteOne(ctx, bson.M{"_id": "migrations", "owner": r.nodeID}); err != nil {
+		slog.Warn("⚠️ Failed to release migration lock", "error", err)
+	}
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Builtin returns the schema migrations shipped with the server.
+// เพิ่ม migration ใหม่ต่อท้ายด้วย version ถัดไปเสมอ ห้ามแก้ของที่ deploy ไปแล้ว
+func Builtin() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Name:        "change_feed_polling_indexes",
+			Description: "Index created_at/updated_at used by the polling change feed",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				indexes := map[string]string{
+					"messages": "created_at",
+					"rooms":    "updated_at",
+					"users":    "updated_at",
+				}
+				for collection, field := range indexes {
+					model := mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}}
+					if _, err := db.Collection(collection).Indexes().CreateOne(ctx, model); err != nil {
+						return fmt.Errorf("failed to index %s.%s: %v", collection, field, err)
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
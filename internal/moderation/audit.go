@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Flag represents a connection flagged for moderation review
+type Flag struct {
+	Time       time.Time `json:"time"`
+	Username   string    `json:"username"`
+	Connection string    `json:"connection"` // connection label เช่น alice#ab12
+	Reason     string    `json:"reason"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// AuditLog keeps recent moderation flags in memory for admin review
+type AuditLog struct {
+	entries []Flag
+	maxSize int
+	counts  map[string]int // username -> จำนวนครั้งที่ถูก flag
+	mutex   sync.RWMutex
+}
+
+// NewAuditLog creates a new audit log that keeps up to maxSize recent flags
+func NewAuditLog(maxSize int) *AuditLog {
+	if maxSize <= 0 {
+		maxSize = 1000
+	}
+	return &AuditLog{
+		entries: make([]Flag, 0),
+		maxSize: maxSize,
+		counts:  make(map[string]int),
+	}
+}
+
+// Record adds a flag to the log
+func (a *AuditLog) Record(flag Flag) {
+	if flag.Time.IsZero() {
+		flag.Time = time.Now()
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// log เต็ม ทิ้ง entry ที่เก่าที่สุด
+	if len(a.entries) >= a.maxSize {
+		a.entries = a.entries[1:]
+	}
+	a.entries = append(a.entries, flag)
+	a.counts[flag.Username]++
+
+	slog.Warn("🚩 Flagged for review", "username", flag.Username, "connection", flag.Connection, "reason", flag.Reason, "detail", flag.Detail)
+}
+
+// Recent returns up to limit most recent flags, newest first
+func (a *AuditLog) Recent(limit int) []Flag {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if limit <= 0 || limit > len(a.entries) {
+		limit = len(a.entries)
+	}
+
+	flags := make([]Flag, 0, limit)
+	for i := len(a.entries) - 1; i >= 0 && len(flags) < limit; i-- {
+		flags = append(flags, a.entries[i])
+	}
+	return flags
+}
+
+// FlagCount returns how many times a user has been flagged
+func (a *AuditLog) FlagCount(username string) int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.counts[username]
+}
@@ -0,0 +1,52 @@
+package moderation
+
+import "strings"
+
+// Honeypot holds trap rooms and commands that are never advertised to humans.
+// ห้องและคำสั่งเหล่านี้ไม่แสดงใน /rooms หรือ /help ผู้ที่ใช้จึงมักเป็น bot ที่สุ่มชื่อหรือ scrape ข้อมูล
+type Honeypot struct {
+	rooms    map[string]bool
+	commands map[string]bool
+	audit    *AuditLog
+}
+
+// NewHoneypot creates a new honeypot
+func NewHoneypot(rooms, commands []string, audit *AuditLog) *Honeypot {
+	h := &Honeypot{
+		rooms:    make(map[string]bool),
+		commands: make(map[string]bool),
+		audit:    audit,
+	}
+	for _, room := range rooms {
+		h.rooms[strings.ToLower(room)] = true
+	}
+	for _, command := range commands {
+		h.commands[strings.ToLower(strings.TrimPrefix(command, "/"))] = true
+	}
+	return h
+}
+
+// IsTrapRoom reports whether a room name is a honeypot room
+func (h *Honeypot) IsTrapRoom(roomName string) bool {
+	return h.rooms[strings.ToLower(roomName)]
+}
+
+// IsTrapCommand reports whether a command name is a honeypot command
+func (h *Honeypot) IsTrapCommand(command string) bool {
+	return h.commands[strings.ToLower(command)]
+}
+
+// Trip flags a connection that touched a trap for moderation review
+func (h *Honeypot) Trip(username, connection, trap string) {
+	h.audit.Record(Flag{
+		Username:   username,
+		Connection: connection,
+		Reason:     "honeypot",
+		Detail:     trap,
+	})
+}
+
+// Audit returns the audit log that honeypot trips are recorded to
+func (h *Honeypot) Audit() *AuditLog {
+	return h.audit
+}
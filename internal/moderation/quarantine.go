@@ -0,0 +1,107 @@
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// Protocol violation kinds
+const (
+	ViolationMalformedJSON   = "malformed_json"   // frame ที่ดูเป็น JSON แต่ parse ไม่ได้
+	ViolationUnsupportedType = "unsupported_type" // type ที่ server ไม่รู้จัก
+	ViolationBeforeAuth      = "before_auth"      // frame อื่นที่ไม่ใช่ join ก่อน login
+)
+
+// Quarantine decides when a connection has sent too many protocol violations.
+// connection ที่ถูกกักกันจะถูกเพิกเฉยต่อ input ทั้งหมดจนครบ grace period แล้วจึงถูกปิด
+type Quarantine struct {
+	limit   int
+	grace   time.Duration
+	audit   *AuditLog
+	metrics *config.ServerMetrics
+}
+
+// NewQuarantine creates a quarantine that trips after limit violations on one connection
+func NewQuarantine(limit int, grace time.Duration, audit *AuditLog, metrics *config.ServerMetrics) *Quarantine {
+	if limit <= 0 {
+		limit = 5
+	}
+	return &Quarantine{
+		limit:   limit,
+		grace:   grace,
+		audit:   audit,
+		metrics: metrics,
+	}
+}
+
+// Grace returns how long a quarantined connection is kept open before it is closed
+func (q *Quarantine) Grace() time.Duration {
+	return q.grace
+}
+
+// Track starts counting violations for a new connection; a nil quarantine returns a nil tracker that never trips
+func (q *Quarantine) Track() *ViolationTracker {
+	if q == nil {
+		return nil
+	}
+	return &ViolationTracker{
+		quarantine: q,
+		counts:     make(map[string]int),
+	}
+}
+
+// ViolationTracker counts the protocol violations of one connection.
+// ใช้จาก read loop ของ connection นั้นเท่านั้น จึงไม่ต้องมี lock
+type ViolationTracker struct {
+	quarantine  *Quarantine
+	counts      map[string]int
+	total       int
+	quarantined bool
+}
+
+// Record counts a violation and reports whether it put the connection into quarantine
+func (t *ViolationTracker) Record(username, connection, kind string) bool {
+	if t == nil || t.quarantined {
+		return false
+	}
+
+	t.counts[kind]++
+	t.total++
+	if t.quarantine.metrics != nil {
+		t.quarantine.metrics.RecordProtocolViolation(kind)
+	}
+	if t.total < t.quarantine.limit {
+		return false
+	}
+
+	t.quarantined = true
+	if t.quarantine.metrics != nil {
+		t.quarantine.metrics.IncrementQuarantined()
+	}
+	t.quarantine.audit.Record(Flag{
+		Username:   username,
+		Connection: connection,
+		Reason:     "protocol_violation",
+		Detail:     t.summary(),
+	})
+	return true
+}
+
+// Quarantined reports whether the connection is quarantined
+func (t *ViolationTracker) Quarantined() bool {
+	return t != nil && t.quarantined
+}
+
+// summary formats the violation counts, e.g. "before_auth=2 malformed_json=3"
+func (t *ViolationTracker) summary() string {
+	kinds := make([]string, 0, len(t.counts))
+	for kind, count := range t.counts {
+		kinds = append(kinds, fmt.Sprintf("%s=%d", kind, count))
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, " ")
+}
@@ -0,0 +1,418 @@
+package msgpack
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Decode decodes one MessagePack value into v (a non-nil pointer), the way encoding/json does:
+// struct fields are matched by their json tag (or field name, case-insensitively), unknown keys are
+// skipped, nil leaves the target unchanged for pointers/maps/slices, and strings are decoded into
+// encoding.TextUnmarshaler types such as time.Time.
+// ถอดรหัสตรงเข้า struct โดยไม่ผ่าน map ทั่วไปหรือ JSON
+func Decode(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("msgpack: Decode requires a non-nil pointer, got %T", v)
+	}
+	d := &decoder{data: data}
+	if err := d.into(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// into decodes the next value into target
+func (d *decoder) into(target reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errors.New("msgpack: nesting too deep")
+	}
+	if d.pos >= len(d.data) {
+		return errShort
+	}
+	code := d.data[d.pos]
+
+	if code == 0xc0 {
+		d.pos++
+		switch target.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			target.Set(reflect.Zero(target.Type()))
+		}
+		return nil
+	}
+
+	if target.Kind() == reflect.Pointer {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return d.into(target.Elem(), depth)
+	}
+
+	if target.CanAddr() && target.Addr().Type().Implements(textUnmarshalerType) && isStr(code) {
+		text, err := d.stringValue()
+		if err != nil {
+			return err
+		}
+		return target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if target.NumMethod() != 0 {
+			return fmt.Errorf("%w: decode into %s", ErrUnsupported, target.Type())
+		}
+		value, err := d.value(depth)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			target.Set(reflect.ValueOf(value))
+		}
+		return nil
+
+	case reflect.String:
+		if !isStr(code) {
+			return d.mismatch(code, target)
+		}
+		text, err := d.stringValue()
+		target.SetString(text)
+		return err
+
+	case reflect.Bool:
+		if code != 0xc2 && code != 0xc3 {
+			return d.mismatch(code, target)
+		}
+		d.pos++
+		target.SetBool(code == 0xc3)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.number(code, target)
+		if err != nil {
+			return err
+		}
+		i, ok := n.toInt()
+		if !ok || target.OverflowInt(i) {
+			return fmt.Errorf("msgpack: %v overflows %s", n, target.Type())
+		}
+		target.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := d.number(code, target)
+		if err != nil {
+			return err
+		}
+		u, ok := n.toUint()
+		if !ok || target.OverflowUint(u) {
+			return fmt.Errorf("msgpack: %v overflows %s", n, target.Type())
+		}
+		target.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		n, err := d.number(code, target)
+		if err != nil {
+			return err
+		}
+		target.SetFloat(n.toFloat())
+		return nil
+
+	case reflect.Slice:
+		if target.Type().Elem().Kind() == reflect.Uint8 && (isBin(code) || isStr(code)) {
+			value, err := d.value(depth)
+			if err != nil {
+				return err
+			}
+			switch raw := value.(type) {
+			case []byte:
+				target.SetBytes(raw)
+			case string:
+				target.SetBytes([]byte(raw))
+			}
+			return nil
+		}
+		n, err := d.containerLen(code, 0x90, 0xdc)
+		if err != nil {
+			return d.mismatch(code, target)
+		}
+		if n > len(d.data)-d.pos {
+			return errShort
+		}
+		slice := reflect.MakeSlice(target.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := d.into(slice.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: map key %s", ErrUnsupported, target.Type().Key())
+		}
+		n, err := d.containerLen(code, 0x80, 0xde)
+		if err != nil {
+			return d.mismatch(code, target)
+		}
+		if n*2 > len(d.data)-d.pos {
+			return errShort
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMapWithSize(target.Type(), n))
+		}
+		for i := 0; i < n; i++ {
+			key, err := d.key()
+			if err != nil {
+				return err
+			}
+			value := reflect.New(target.Type().Elem()).Elem()
+			if err := d.into(value, depth+1); err != nil {
+				return err
+			}
+			target.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), value)
+		}
+		return nil
+
+	case reflect.Struct:
+		n, err := d.containerLen(code, 0x80, 0xde)
+		if err != nil {
+			return d.mismatch(code, target)
+		}
+		if n*2 > len(d.data)-d.pos {
+			return errShort
+		}
+		fields := fieldsOf(target.Type())
+		for i := 0; i < n; i++ {
+			key, err := d.key()
+			if err != nil {
+				return err
+			}
+			index, ok := fields.lookup(key)
+			if !ok {
+				// key ที่ struct ไม่รู้จักข้ามไปเหมือน encoding/json
+				if _, err := d.value(depth + 1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.into(target.FieldByIndex(index), depth+1); err != nil {
+				return fmt.Errorf("msgpack: field %q: %w", key, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: decode into %s", ErrUnsupported, target.Type())
+}
+
+// mismatch reports a value whose format cannot be stored in target
+func (d *decoder) mismatch(code byte, target reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode format 0x%02x into %s", code, target.Type())
+}
+
+// stringValue reads a str value (the caller has checked the format with isStr)
+func (d *decoder) stringValue() (string, error) {
+	code := d.data[d.pos]
+	d.pos++
+	if code&0xe0 == 0xa0 {
+		return d.str(int(code & 0x1f))
+	}
+	n, err := d.uint(1 << (code - 0xd9))
+	if err != nil {
+		return "", err
+	}
+	return d.str(int(n))
+}
+
+// key reads a map key, which must be a string
+func (d *decoder) key() (string, error) {
+	if d.pos >= len(d.data) {
+		return "", errShort
+	}
+	if !isStr(d.data[d.pos]) {
+		return "", fmt.Errorf("%w: map key format 0x%02x", ErrUnsupported, d.data[d.pos])
+	}
+	return d.stringValue()
+}
+
+// number is a decoded integer or float; exactly one of the fields is meaningful (see kind)
+type number struct {
+	kind byte // 'i' int64, 'u' uint64 เกิน MaxInt64, 'f' float64
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// String formats the number for errors
+func (n number) String() string {
+	switch n.kind {
+	case 'u':
+		return fmt.Sprint(n.u)
+	case 'f':
+		return fmt.Sprint(n.f)
+	}
+	return fmt.Sprint(n.i)
+}
+
+// number reads an integer or float value without boxing it
+func (d *decoder) number(code byte, target reflect.Value) (number, error) {
+	if !isNumber(code) {
+		return number{}, d.mismatch(code, target)
+	}
+	d.pos++
+	switch {
+	case code <= 0x7f:
+		return number{kind: 'i', i: int64(code)}, nil
+	case code >= 0xe0:
+		return number{kind: 'i', i: int64(int8(code))}, nil
+	case code == 0xca:
+		n, err := d.uint(4)
+		return number{kind: 'f', f: float64(math.Float32frombits(uint32(n)))}, err
+	case code == 0xcb:
+		n, err := d.uint(8)
+		return number{kind: 'f', f: math.Float64frombits(n)}, err
+	case code >= 0xcc && code <= 0xcf:
+		n, err := d.uint(1 << (code - 0xcc))
+		if n > math.MaxInt64 {
+			return number{kind: 'u', u: n}, err
+		}
+		return number{kind: 'i', i: int64(n)}, err
+	}
+	// 0xd0-0xd3: signed 8/16/32/64-bit
+	size := 1 << (code - 0xd0)
+	n, err := d.uint(size)
+	switch size {
+	case 1:
+		return number{kind: 'i', i: int64(int8(n))}, err
+	case 2:
+		return number{kind: 'i', i: int64(int16(n))}, err
+	case 4:
+		return number{kind: 'i', i: int64(int32(n))}, err
+	}
+	return number{kind: 'i', i: int64(n)}, err
+}
+
+// containerLen reads an array or map header; fix is the fix-format base and code16 the 16-bit format
+func (d *decoder) containerLen(code, fix, code16 byte) (int, error) {
+	switch {
+	case code&0xf0 == fix:
+		d.pos++
+		return int(code & 0x0f), nil
+	case code == code16 || code == code16+1:
+		d.pos++
+		n, err := d.uint(2 << (code - code16))
+		return int(n), err
+	}
+	return 0, ErrUnsupported
+}
+
+func isStr(code byte) bool {
+	return code&0xe0 == 0xa0 || (code >= 0xd9 && code <= 0xdb)
+}
+
+func isBin(code byte) bool {
+	return code >= 0xc4 && code <= 0xc6
+}
+
+func isNumber(code byte) bool {
+	return code <= 0x7f || code >= 0xe0 || (code >= 0xca && code <= 0xd3)
+}
+
+func (n number) toInt() (int64, bool) {
+	switch n.kind {
+	case 'u':
+		return 0, false
+	case 'f':
+		return int64(n.f), n.f == math.Trunc(n.f) && n.f >= math.MinInt64 && n.f <= math.MaxInt64
+	}
+	return n.i, true
+}
+
+func (n number) toUint() (uint64, bool) {
+	switch n.kind {
+	case 'u':
+		return n.u, true
+	case 'f':
+		return uint64(n.f), n.f == math.Trunc(n.f) && n.f >= 0 && n.f <= math.MaxUint64
+	}
+	return uint64(n.i), n.i >= 0
+}
+
+func (n number) toFloat() float64 {
+	switch n.kind {
+	case 'u':
+		return float64(n.u)
+	case 'f':
+		return n.f
+	}
+	return float64(n.i)
+}
+
+// structFields maps the keys of a struct type to field indexes
+type structFields struct {
+	exact  map[string][]int
+	folded map[string][]int // ชื่อตัวพิมพ์เล็ก สำหรับจับคู่แบบไม่สนตัวพิมพ์เหมือน encoding/json
+}
+
+// lookup finds the field for a key, preferring an exact match
+func (f *structFields) lookup(key string) ([]int, bool) {
+	if index, ok := f.exact[key]; ok {
+		return index, true
+	}
+	index, ok := f.folded[strings.ToLower(key)]
+	return index, ok
+}
+
+var fieldCache sync.Map // reflect.Type -> *structFields
+
+// fieldsOf returns the decodable fields of a struct type (exported, json tag not "-", embedded structs flattened)
+func fieldsOf(t reflect.Type) *structFields {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(*structFields)
+	}
+	fields := &structFields{exact: make(map[string][]int), folded: make(map[string][]int)}
+	collectFields(t, nil, fields)
+	cached, _ := fieldCache.LoadOrStore(t, fields)
+	return cached.(*structFields)
+}
+
+func collectFields(t reflect.Type, parent []int, fields *structFields) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, index, fields)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, exists := fields.exact[name]; !exists {
+			fields.exact[name] = index
+		}
+		if _, exists := fields.folded[strings.ToLower(name)]; !exists {
+			fields.folded[strings.ToLower(name)] = index
+		}
+	}
+}
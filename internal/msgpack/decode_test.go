@@ -0,0 +1,120 @@
+package msgpack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type testEnvelope struct {
+	Type      string            `json:"type"`
+	Limit     int               `json:"limit,omitempty"`
+	Score     float64           `json:"score,omitempty"`
+	Urgent    bool              `json:"urgent,omitempty"`
+	IDs       []string          `json:"ids,omitempty"`
+	File      *testFile         `json:"file,omitempty"`
+	StartDate *time.Time        `json:"start_date,omitempty"`
+	Display   *string           `json:"display_name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Data      []byte            `json:"data,omitempty"`
+	Extra     interface{}       `json:"extra,omitempty"`
+	Ignored   string            `json:"-"`
+	Untagged  string
+	internal  string
+}
+
+func TestDecodeStruct(t *testing.T) {
+	frame, err := Marshal(map[string]interface{}{
+		"type":         "search_messages",
+		"limit":        int64(20),
+		"score":        1.5,
+		"urgent":       true,
+		"ids":          []interface{}{"a", "b"},
+		"file":         map[string]interface{}{"name": "a.png", "size": int64(300000)},
+		"start_date":   "2026-03-08T07:00:00Z",
+		"display_name": "",
+		"labels":       map[string]interface{}{"k": "v"},
+		"data":         []byte{1, 2, 3},
+		"extra":        map[string]interface{}{"nested": []interface{}{int64(1)}},
+		"Ignored":      "x",
+		"untagged":     "case-insensitive",
+		"unknown":      map[string]interface{}{"skipped": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got testEnvelope
+	if err := Decode(frame, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	start := time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)
+	empty := ""
+	want := testEnvelope{
+		Type:      "search_messages",
+		Limit:     20,
+		Score:     1.5,
+		Urgent:    true,
+		IDs:       []string{"a", "b"},
+		File:      &testFile{Name: "a.png", Size: 300000},
+		StartDate: &start,
+		Display:   &empty,
+		Labels:    map[string]string{"k": "v"},
+		Data:      []byte{1, 2, 3},
+		Extra:     map[string]interface{}{"nested": []interface{}{int64(1)}},
+		Untagged:  "case-insensitive",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode mismatch:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	encode := func(v interface{}) []byte {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"string into int", encode(map[string]interface{}{"limit": "20"}), "limit"},
+		{"fraction into int", encode(map[string]interface{}{"limit": 1.5}), "overflows"},
+		{"int into string", encode(map[string]interface{}{"type": int64(1)}), "type"},
+		{"non-string key", []byte{0x81, 0x01, 0x01}, "map key"},
+		{"truncated", encode(map[string]interface{}{"type": "message"})[:5], "end of data"},
+		{"trailing bytes", append(encode(map[string]interface{}{}), 0xc0), "trailing"},
+		{"huge map header", []byte{0xdf, 0xff, 0xff, 0xff, 0xff}, "end of data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testEnvelope
+			err := Decode(tt.data, &got)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Decode error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeNilKeepsZero(t *testing.T) {
+	frame, _ := Marshal(map[string]interface{}{"type": "get_users", "file": nil, "ids": nil})
+	var got testEnvelope
+	if err := Decode(frame, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "get_users" || got.File != nil || got.IDs != nil {
+		t.Fatalf("unexpected %+v", got)
+	}
+}
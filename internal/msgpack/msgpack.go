@@ -0,0 +1,321 @@
+// Package msgpack implements the subset of MessagePack needed to carry the chat envelope:
+// nil, bool, integers, floats, strings, binary, arrays and maps with string keys.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrUnsupported is returned for values and formats this package does not handle (e.g. ext types)
+var ErrUnsupported = errors.New("msgpack: unsupported type")
+
+// maxDepth limits nesting when decoding untrusted input
+const maxDepth = 32
+
+// Marshal encodes a generic value as produced by encoding/json (map[string]interface{}, []interface{},
+// string, float64, json.Number, bool, nil) plus integers and []byte
+func Marshal(v interface{}) ([]byte, error) {
+	return appendValue(make([]byte, 0, 256), v)
+}
+
+// FromJSON converts a JSON document to MessagePack; integers stay integers
+func FromJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return Marshal(v)
+}
+
+// Unmarshal decodes one MessagePack value into generic Go values: map[string]interface{},
+// []interface{}, string, []byte, int64, uint64, float64, bool or nil
+func Unmarshal(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+	return v, nil
+}
+
+func appendValue(b []byte, v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if value {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int:
+		return appendInt(b, int64(value)), nil
+	case int64:
+		return appendInt(b, value), nil
+	case uint64:
+		if value <= math.MaxInt64 {
+			return appendInt(b, int64(value)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), value), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(value)), nil
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return appendInt(b, i), nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendValue(b, f)
+	case string:
+		return append(appendHeader(b, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb), value...), nil
+	case []byte:
+		return append(appendHeader(b, len(value), 0, 0, 0xc4, 0xc5, 0xc6), value...), nil
+	case []interface{}:
+		b = appendHeader(b, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		var err error
+		for _, item := range value {
+			if b, err = appendValue(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		// เรียง key ให้ผลลัพธ์คงที่ (map ของ Go ไม่มีลำดับ)
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b = appendHeader(b, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		var err error
+		for _, key := range keys {
+			b, _ = appendValue(b, key)
+			if b, err = appendValue(b, value[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupported, v)
+	}
+}
+
+// appendHeader writes a length header: fix format below fixLimit (if any), then 8/16/32-bit variants
+func appendHeader(b []byte, n int, fixBase byte, fixLimit int, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixLimit:
+		return append(b, fixBase|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+	}
+}
+
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+// decoder reads MessagePack values from a byte slice
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+var errShort = errors.New("msgpack: unexpected end of data")
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errShort
+	}
+	chunk := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return chunk, nil
+}
+
+func (d *decoder) uint(size int) (uint64, error) {
+	chunk, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(chunk[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(chunk)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(chunk)), nil
+	default:
+		return binary.BigEndian.Uint64(chunk), nil
+	}
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("msgpack: nesting too deep")
+	}
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := head[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return d.str(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return d.array(int(code&0x0f), depth)
+	case code&0xf0 == 0x80:
+		return d.mapValue(int(code&0x0f), depth)
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce:
+		n, err := d.uint(1 << (code - 0xcc))
+		return int64(n), err
+	case 0xcf:
+		n, err := d.uint(8)
+		if err == nil && n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, err
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), chunk...), nil
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n), depth)
+	}
+	return nil, fmt.Errorf("%w: format 0x%02x", ErrUnsupported, code)
+}
+
+func (d *decoder) str(n int) (string, error) {
+	chunk, err := d.next(n)
+	if err != nil {
+		return "", err
+	}
+	return string(chunk), nil
+}
+
+func (d *decoder) array(n, depth int) ([]interface{}, error) {
+	// ทุก element ใช้อย่างน้อย 1 byte จึงปฏิเสธ header ที่อ้างจำนวนเกินข้อมูลที่มีก่อนจอง memory
+	if n > len(d.data)-d.pos {
+		return nil, errShort
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *decoder) mapValue(n, depth int) (map[string]interface{}, error) {
+	if n*2 > len(d.data)-d.pos {
+		return nil, errShort
+	}
+	values := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map key %T", ErrUnsupported, key)
+		}
+		value, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
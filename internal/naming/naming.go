@@ -0,0 +1,124 @@
+package naming
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAttempts is how many random names are tried per suffix width before giving up
+const maxAttempts = 20
+
+// Wordlist holds curated words for one locale and how to combine them into a name
+type Wordlist struct {
+	Adjectives []string
+	Nouns      []string
+	Combine    func(adjective, noun string, number int) string
+}
+
+// wordlists are curated by hand: คำกลางๆ เป็นมิตร ไม่มีคำที่ตีความเป็นคำหยาบหรือเสียดสีได้
+var wordlists = map[string]Wordlist{
+	"en": {
+		Adjectives: []string{
+			"brave", "calm", "clever", "cosy", "curious", "eager", "gentle", "happy", "jolly", "kind",
+			"lucky", "mellow", "merry", "nimble", "polite", "quick", "quiet", "sunny", "swift", "witty",
+		},
+		Nouns: []string{
+			"badger", "beaver", "falcon", "ferret", "gecko", "heron", "koala", "lemur", "lynx", "marten",
+			"otter", "owl", "panda", "puffin", "robin", "salmon", "sparrow", "tapir", "walrus", "wren",
+		},
+		Combine: func(adjective, noun string, number int) string {
+			return fmt.Sprintf("%s-%s-%d", adjective, noun, number)
+		},
+	},
+	"th": {
+		Adjectives: []string{
+			"ใจดี", "ขยัน", "ร่าเริง", "สดใส", "อารมณ์ดี", "ว่องไว", "สุภาพ", "ช่างคิด", "อ่อนโยน", "กล้าหาญ",
+		},
+		Nouns: []string{
+			"แมว", "นกฮูก", "กระต่าย", "ช้าง", "ปลาทอง", "เต่า", "นกแก้ว", "หมีแพนด้า", "กระรอก", "ม้าน้ำ",
+		},
+		// ภาษาไทยวางคำขยายไว้หลังคำนาม
+		Combine: func(adjective, noun string, number int) string {
+			return fmt.Sprintf("%s%s-%d", noun, adjective, number)
+		},
+	},
+}
+
+// blockedFragments catches unlucky combinations across word boundaries (checked without separators)
+var blockedFragments = []string{"ass", "cum", "fag", "fuck", "kkk", "nazi", "rape", "sex", "shit", "tit"}
+
+// blockedNumbers are suffixes with common offensive connotations
+var blockedNumbers = map[int]bool{14: true, 69: true, 88: true, 420: true, 666: true, 1488: true}
+
+// Generator creates friendly, profanity-safe, collision-free names for guests and temporary rooms
+type Generator struct {
+	words  Wordlist
+	locale string
+	rand   *rand.Rand
+	mutex  sync.Mutex
+}
+
+// NewGenerator creates a generator for a locale ("en", "th"); unknown locales fall back to English
+func NewGenerator(locale string) *Generator {
+	words, exists := wordlists[locale]
+	if !exists {
+		locale = "en"
+		words = wordlists[locale]
+	}
+	return &Generator{
+		words:  words,
+		locale: locale,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Locale returns the locale the generator uses
+func (g *Generator) Locale() string {
+	return g.locale
+}
+
+// Generate returns a name for which taken reports false.
+// ลองตัวเลข 2 หลักก่อน ถ้าชนบ่อยค่อยขยายเป็น 4 หลัก
+func (g *Generator) Generate(taken func(name string) bool) (string, error) {
+	for _, width := range []int{100, 10000} {
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			name := g.candidate(width)
+			if name != "" && !taken(name) {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("could not generate a unique name")
+}
+
+// candidate builds one random name, or "" when it fails the safety checks
+func (g *Generator) candidate(width int) string {
+	g.mutex.Lock()
+	adjective := g.words.Adjectives[g.rand.Intn(len(g.words.Adjectives))]
+	noun := g.words.Nouns[g.rand.Intn(len(g.words.Nouns))]
+	number := width/10 + g.rand.Intn(width-width/10)
+	g.mutex.Unlock()
+
+	if blockedNumbers[number] {
+		return ""
+	}
+	name := g.words.Combine(adjective, noun, number)
+	if !isSafe(name) {
+		return ""
+	}
+	return name
+}
+
+// isSafe reports whether a name contains no blocked fragment once separators are removed
+func isSafe(name string) bool {
+	compact := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	for _, fragment := range blockedFragments {
+		if strings.Contains(compact, fragment) {
+			return false
+		}
+	}
+	return true
+}
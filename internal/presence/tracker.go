@@ -0,0 +1,131 @@
+package presence
+
+import (
+	"sync"
+	"time"
+)
+
+// State is a user's presence derived from activity and connection health
+type State string
+
+// Presence states
+const (
+	Online  State = "online"
+	Away    State = "away"    // เชื่อมต่ออยู่แต่ไม่มีกิจกรรม หรือ connection ไม่ตอบ ping
+	Offline State = "offline" // ไม่มี connection บน node นี้
+)
+
+// Signal is what the tracker knows about one connected user at sweep time
+type Signal struct {
+	Username   string
+	LastActive time.Time
+	Healthy    bool // connection ตอบ ping ล่าสุดครบ
+}
+
+// Source lists the users connected to this node (provided by the chat layer to avoid import cycle)
+type Source func() []Signal
+
+// ChangeFunc is called after a user's presence changes
+type ChangeFunc func(username string, previous, current State)
+
+// Tracker keeps the presence state of connected users and reports changes.
+// สถานะเปลี่ยนเป็น online ทันทีเมื่อมีกิจกรรม ส่วน away ถูกตรวจจากการ sweep เป็นระยะ
+type Tracker struct {
+	source    Source
+	awayAfter time.Duration
+	states    map[string]State
+	listeners []ChangeFunc
+	mutex     sync.Mutex
+}
+
+// NewTracker creates a presence tracker; users idle for awayAfter become away
+func NewTracker(source Source, awayAfter time.Duration) *Tracker {
+	return &Tracker{
+		source:    source,
+		awayAfter: awayAfter,
+		states:    make(map[string]State),
+	}
+}
+
+// OnChange registers a callback for presence changes
+func (t *Tracker) OnChange(callback ChangeFunc) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.listeners = append(t.listeners, callback)
+}
+
+// Get returns a user's current presence (offline if not connected)
+func (t *Tracker) Get(username string) State {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if state, exists := t.states[username]; exists {
+		return state
+	}
+	return Offline
+}
+
+// Touch marks a user online after activity
+func (t *Tracker) Touch(username string) {
+	t.set(username, Online)
+}
+
+// Disconnected marks a user offline and stops tracking them
+func (t *Tracker) Disconnected(username string) {
+	t.set(username, Offline)
+}
+
+// Sweep re-derives the presence of every connected user; tracked users no longer connected become offline
+func (t *Tracker) Sweep() {
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, signal := range t.source() {
+		seen[signal.Username] = true
+		t.set(signal.Username, t.derive(signal, now))
+	}
+
+	t.mutex.Lock()
+	gone := make([]string, 0)
+	for username := range t.states {
+		if !seen[username] {
+			gone = append(gone, username)
+		}
+	}
+	t.mutex.Unlock()
+
+	for _, username := range gone {
+		t.set(username, Offline)
+	}
+}
+
+// derive computes the presence of a connected user
+func (t *Tracker) derive(signal Signal, now time.Time) State {
+	if !signal.Healthy || now.Sub(signal.LastActive) >= t.awayAfter {
+		return Away
+	}
+	return Online
+}
+
+// set updates a user's state and notifies listeners if it changed
+func (t *Tracker) set(username string, state State) {
+	t.mutex.Lock()
+	previous, exists := t.states[username]
+	if !exists {
+		previous = Offline
+	}
+	if state == Offline {
+		delete(t.states, username)
+	} else {
+		t.states[username] = state
+	}
+	listeners := t.listeners
+	t.mutex.Unlock()
+
+	if previous == state {
+		return
+	}
+	// เรียก callback นอก lock เพราะ callback จะ broadcast ไปยังห้อง
+	for _, callback := range listeners {
+		callback(username, previous, state)
+	}
+}
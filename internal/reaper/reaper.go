@@ -0,0 +1,119 @@
+package reaper
+
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// Reapable is in-memory state that can drop expired entries.
+// aggressive=true เมื่อหน่วยความจำเกิน threshold ให้ทิ้ง entry ที่ยังไม่หมดอายุแต่ไม่จำเป็นได้ด้วย
+type Reapable interface {
+	Reap(aggressive bool) int
+}
+
+// Func adapts a plain function to the Reapable interface
+type Func func(aggressive bool) int
+
+// Reap calls f(aggressive)
+func (f Func) Reap(aggressive bool) int {
+	return f(aggressive)
+}
+
+// Stats holds counters for reaped entries
+type Stats struct {
+	Runs           int64            `json:"runs"`
+	AggressiveRuns int64            `json:"aggressive_runs"`
+	Reclaimed      map[string]int64 `json:"reclaimed"`
+	LastRun        time.Time        `json:"last_run"`
+	LastHeapBytes  uint64           `json:"last_heap_bytes"`
+}
+
+// target is a named reapable registered with the reaper
+type target struct {
+	name     string
+	reapable Reapable
+}
+
+// Reaper periodically prunes stale in-memory state from every registered component
+type Reaper struct {
+	targets       []target
+	heapThreshold uint64 // bytes, 0 = ไม่เคยเข้าโหมด aggressive
+	metrics       *config.ServerMetrics
+	stats         Stats
+	mutex         sync.Mutex
+}
+
+// New creates a new reaper
+func New(heapThreshold uint64, metrics *config.ServerMetrics) *Reaper {
+	return &Reaper{
+		heapThreshold: heapThreshold,
+		metrics:       metrics,
+		stats:         Stats{Reclaimed: make(map[string]int64)},
+	}
+}
+
+// Register adds a component whose stale entries should be pruned
+func (r *Reaper) Register(name string, reapable Reapable) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.targets = append(r.targets, target{name: name, reapable: reapable})
+}
+
+// Run prunes every registered component once, aggressively if heap usage is above the threshold
+func (r *Reaper) Run() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	aggressive := r.heapThreshold > 0 && mem.HeapAlloc >= r.heapThreshold
+
+	r.mutex.Lock()
+	targets := make([]target, len(r.targets))
+	copy(targets, r.targets)
+	r.mutex.Unlock()
+
+	total := 0
+	reclaimed := make(map[string]int, len(targets))
+	for _, t := range targets {
+		count := t.reapable.Reap(aggressive)
+		reclaimed[t.name] = count
+		total += count
+	}
+
+	r.mutex.Lock()
+	r.stats.Runs++
+	if aggressive {
+		r.stats.AggressiveRuns++
+	}
+	for name, count := range reclaimed {
+		r.stats.Reclaimed[name] += int64(count)
+	}
+	r.stats.LastRun = time.Now()
+	r.stats.LastHeapBytes = mem.HeapAlloc
+	r.mutex.Unlock()
+
+	if r.metrics != nil && total > 0 {
+		r.metrics.AddReclaimedEntries(total)
+	}
+
+	if aggressive {
+		slog.Warn("🧹 Reaper reclaimed entries under memory pressure", "heap_mb", mem.HeapAlloc>>20, "total", total, "reclaimed", reclaimed)
+	} else if total > 0 {
+		slog.Info("🧹 Reaper reclaimed entries", "total", total, "reclaimed", reclaimed)
+	}
+}
+
+// Stats returns a copy of the reaper counters
+func (r *Reaper) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := r.stats
+	stats.Reclaimed = make(map[string]int64, len(r.stats.Reclaimed))
+	for name, count := range r.stats.Reclaimed {
+		stats.Reclaimed[name] = count
+	}
+	return stats
+}
@@ -1,6 +1,7 @@
 package room
 
 import (
+	"strings"
 	"time"
 
 	userPkg "realtime-chat/internal/user"
@@ -14,4 +15,63 @@ type Room struct {
 	CreatedBy string                     `json:"created_by"`
 	MaxUsers  int                        `json:"max_users"`
 	IsActive  bool                       `json:"is_active"`
+	LastActivity time.Time               `json:"last_activity"`
+	ExpiresAt *time.Time                 `json:"expires_at,omitempty"` // nil = ห้องถาวร
+	ExportKey string                     `json:"export_key,omitempty"` // OpenPGP public key ของเจ้าของห้อง ใช้เข้ารหัส transcript ที่ export
+	MirrorWriter string                  `json:"mirror_writer,omitempty"` // node ที่รับโพสต์ของห้อง mirror (ว่าง = ห้องปกติ)
+	Private   bool                       `json:"private,omitempty"`       // privacy mode: ส่งอย่างเดียว ไม่บันทึก/ไม่ index/ไม่เก็บสถิติ
+	IsPrivate bool                       `json:"is_private,omitempty"`    // invite-only: ซ่อนจาก /rooms และเข้าได้เฉพาะผู้ที่ได้รับเชิญ (คนละเรื่องกับ Private)
+	PasswordHash string                  `json:"password_hash,omitempty"` // bcrypt hash ของรหัสผ่านห้อง (ว่าง = ไม่มีรหัสผ่าน); Room ไม่ถูกส่งให้ client ตรงๆ
+	InvitedUsers []string                `json:"invited_users,omitempty"` // ผู้ที่เข้าห้องได้โดยไม่ต้องใช้รหัสผ่าน (ได้รับเชิญ หรือเคยใส่รหัสถูกแล้ว)
+	Retention time.Duration              `json:"retention,omitempty"`     // อายุข้อความของห้องนี้ (0 = ใช้ค่า default ของ server)
+	Topic     string                     `json:"topic,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Pinned    []PinnedMessage            `json:"pinned,omitempty"` // ข้อความที่ปักหมุด เรียงตามเวลาที่ปัก
+	Reactivated bool                     `json:"-"` // ห้องที่ archive ไว้ถูกดึงกลับมาโดย CreateRoomWithOptions (ไม่ได้สร้างใหม่)
+}
+
+// PinnedMessage is a message pinned to a room by its owner
+type PinnedMessage struct {
+	MessageID string    `json:"message_id" bson:"message_id"`
+	PinnedBy  string    `json:"pinned_by" bson:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at" bson:"pinned_at"`
+}
+
+// IsPinned reports whether a message is pinned to the room
+func (r *Room) IsPinned(messageID string) bool {
+	for _, pin := range r.Pinned {
+		if pin.MessageID == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+// Restricted reports whether joining the room needs an invitation or a password
+func (r *Room) Restricted() bool {
+	return r.IsPrivate || r.PasswordHash != ""
+}
+
+// IsMember reports whether a user may enter a restricted room without a password:
+// the owner, invited users and users already in the room
+func (r *Room) IsMember(username string) bool {
+	if strings.EqualFold(r.CreatedBy, username) {
+		return true
+	}
+	for _, invited := range r.InvitedUsers {
+		if strings.EqualFold(invited, username) {
+			return true
+		}
+	}
+	for _, user := range r.Users {
+		if strings.EqualFold(user.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleTo reports whether the room is listed for a user; invite-only rooms are hidden from non-members
+func (r *Room) VisibleTo(username string) bool {
+	return !r.IsPrivate || r.IsMember(username)
 }
\ No newline at end of file
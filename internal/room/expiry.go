@@ -0,0 +1,112 @@
+package room
+
+import (
+	"log/slog"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// ExpiryCallback is invoked with the current members when a temporary room is about to
+// expire (expired=false) and right before it is archived (expired=true)
+type ExpiryCallback func(roomName string, members []*userPkg.User, remaining time.Duration, expired bool)
+
+// OnExpiry registers a callback for temporary room countdown and expiry events
+func (s *service) OnExpiry(callback ExpiryCallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expiryListeners = append(s.expiryListeners, callback)
+}
+
+// CheckExpiries sends countdown warnings and archives temporary rooms whose TTL has passed.
+// อ่านเวลาหมดอายุจาก repository ทุกครั้ง จึงยังทำงานถูกต้องหลัง restart
+func (s *service) CheckExpiries(warnings []time.Duration) int {
+	now := time.Now()
+	expired := 0
+
+	for _, room := range s.repo.GetActiveRooms() {
+		if room.ExpiresAt == nil {
+			continue
+		}
+
+		remaining := room.ExpiresAt.Sub(now)
+		if remaining <= 0 {
+			if s.expireRoom(room.Name) {
+				expired++
+			}
+			continue
+		}
+
+		threshold, ok := warningThreshold(warnings, remaining)
+		if !ok || !s.markWarned(room.Name, threshold) {
+			continue
+		}
+
+		s.notifyExpiry(room.Name, s.repo.GetUsersInRoom(room.Name), remaining, false)
+	}
+
+	return expired
+}
+
+// expireRoom notifies members, moves them out and archives the room
+func (s *service) expireRoom(roomName string) bool {
+	members := s.repo.GetUsersInRoom(roomName)
+
+	// แจ้งก่อนย้ายออก เพื่อให้ผู้รับยังรู้ว่าตัวเองอยู่ห้องไหน
+	s.notifyExpiry(roomName, members, 0, true)
+
+	for _, user := range members {
+		if err := s.repo.LeaveRoom(user, roomName); err != nil {
+			slog.Warn("⚠️ Failed to remove user from expired room", "username", user.Username, "room", roomName, "error", err)
+			continue
+		}
+		s.notifyMembership(roomName, user, false)
+	}
+
+	if err := s.repo.DeactivateRoom(roomName); err != nil {
+		slog.Error("❌ Failed to archive expired room", "room", roomName, "error", err)
+		return false
+	}
+
+	s.mutex.Lock()
+	delete(s.warned, roomName)
+	s.mutex.Unlock()
+
+	slog.Info("⌛ Room expired and was archived", "room", roomName, "members_moved", len(members))
+	return true
+}
+
+// markWarned records that a warning threshold was sent, reporting false if it (or a closer one) already was
+func (s *service) markWarned(roomName string, threshold time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if last, exists := s.warned[roomName]; exists && last <= threshold {
+		return false
+	}
+	s.warned[roomName] = threshold
+	return true
+}
+
+// notifyExpiry notifies all expiry listeners
+func (s *service) notifyExpiry(roomName string, members []*userPkg.User, remaining time.Duration, expired bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, callback := range s.expiryListeners {
+		callback(roomName, members, remaining, expired)
+	}
+}
+
+// warningThreshold returns the smallest warning offset that remaining has already crossed
+func warningThreshold(warnings []time.Duration, remaining time.Duration) (time.Duration, bool) {
+	var threshold time.Duration
+	found := false
+	for _, warning := range warnings {
+		if remaining <= warning && (!found || warning < threshold) {
+			threshold = warning
+			found = true
+		}
+	}
+	return threshold, found
+}
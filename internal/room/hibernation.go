@@ -0,0 +1,201 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/config"
+	userPkg "realtime-chat/internal/user"
+)
+
+// HibernationStats holds counters for idle room hibernation
+type HibernationStats struct {
+	ResidentRooms   int   `json:"resident_rooms"`
+	HibernatedRooms int   `json:"hibernated_rooms"`
+	Hibernations    int64 `json:"hibernations"`
+	Rehydrations    int64 `json:"rehydrations"`
+}
+
+// EnableHibernation enables idle room hibernation, storing room state under dir.
+// ห้องที่ถูกพักไว้จากรอบก่อน (ยังมีไฟล์อยู่) จะถูกนับเป็นห้องที่พักไว้ทันที
+func (r *InMemoryRepository) EnableHibernation(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create hibernation directory: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read hibernation directory: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.hibernateDir = dir
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		if _, exists := r.rooms[name]; !exists {
+			r.hibernated[name] = true
+		}
+	}
+
+	return nil
+}
+
+// HibernateIdle moves rooms with no members and no activity within idleAfter to disk
+func (r *InMemoryRepository) HibernateIdle(idleAfter time.Duration) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.hibernateDir == "" {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-idleAfter)
+	count := 0
+	for name, room := range r.rooms {
+		// ห้อง general เป็นห้องเริ่มต้นของทุกคน ไม่ต้องพัก
+		// ห้องที่ archive แล้วไม่ต้องพัก เพราะไม่มีใครเข้าได้อีก
+		if name == "general" || !room.IsActive || len(room.Users) > 0 || room.LastActivity.After(cutoff) {
+			continue
+		}
+
+		if err := r.writeHibernated(room); err != nil {
+			slog.Warn("⚠️ Failed to hibernate room", "room", name, "error", err)
+			continue
+		}
+
+		delete(r.rooms, name)
+		r.hibernated[name] = true
+		r.hibernations++
+		count++
+	}
+
+	return count
+}
+
+// RunHibernator periodically hibernates idle rooms and reports residency metrics until ctx is cancelled
+func (r *InMemoryRepository) RunHibernator(ctx context.Context, idleAfter, interval time.Duration, metrics *config.ServerMetrics) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if count := r.HibernateIdle(idleAfter); count > 0 {
+			slog.Info("💤 Hibernated idle rooms", "rooms", count)
+		}
+
+		if metrics != nil {
+			stats := r.HibernationStats()
+			metrics.SetRoomResidency(stats.ResidentRooms, stats.HibernatedRooms)
+		}
+	}
+}
+
+// HibernationStats returns resident vs hibernated room counters
+func (r *InMemoryRepository) HibernationStats() HibernationStats {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return HibernationStats{
+		ResidentRooms:   len(r.rooms),
+		HibernatedRooms: len(r.hibernated),
+		Hibernations:    r.hibernations,
+		Rehydrations:    r.rehydrations,
+	}
+}
+
+// roomLocked returns a room, rehydrating it from disk if hibernated (assumes write lock is held)
+func (r *InMemoryRepository) roomLocked(name string) (*Room, bool) {
+	if room, exists := r.rooms[name]; exists {
+		return room, true
+	}
+	if !r.hibernated[name] {
+		return nil, false
+	}
+
+	room, err := r.readHibernated(name)
+	if err != nil {
+		slog.Error("❌ Failed to rehydrate room", "room", name, "error", err)
+		return nil, false
+	}
+
+	room.LastActivity = time.Now()
+	r.rooms[name] = room
+	delete(r.hibernated, name)
+	r.rehydrations++
+
+	if err := os.Remove(r.hibernationPath(name)); err != nil {
+		slog.Warn("⚠️ Failed to remove hibernation file", "room", name, "error", err)
+	}
+
+	slog.Info("☀️ Room rehydrated", "room", name)
+	return room, true
+}
+
+// peekHibernatedLocked reads hibernated rooms without rehydrating them (assumes lock is held)
+func (r *InMemoryRepository) peekHibernatedLocked() []*Room {
+	rooms := make([]*Room, 0, len(r.hibernated))
+	for name := range r.hibernated {
+		room, err := r.readHibernated(name)
+		if err != nil {
+			slog.Warn("⚠️ Failed to read hibernated room", "room", name, "error", err)
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// writeHibernated serializes a room to its hibernation file
+func (r *InMemoryRepository) writeHibernated(room *Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	// เขียนไฟล์ชั่วคราวก่อนแล้วค่อย rename เพื่อไม่ให้ได้ไฟล์ครึ่งๆ กลางๆ
+	path := r.hibernationPath(room.Name)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readHibernated loads a room from its hibernation file
+func (r *InMemoryRepository) readHibernated(name string) (*Room, error) {
+	data, err := os.ReadFile(r.hibernationPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var room Room
+	if err := json.Unmarshal(data, &room); err != nil {
+		return nil, err
+	}
+	room.Users = make(map[string]*userPkg.User)
+	return &room, nil
+}
+
+// hibernationPath returns the file path used to store a hibernated room
+func (r *InMemoryRepository) hibernationPath(name string) string {
+	return filepath.Join(r.hibernateDir, url.PathEscape(name)+".json")
+}
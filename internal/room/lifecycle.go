@@ -0,0 +1,100 @@
+package room
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrRoomNotArchived is returned by Repository.ReactivateRoom when there is no archived room with that name
+var ErrRoomNotArchived = errors.New("room is not archived")
+
+// ArchiveCallback is invoked after an idle room was archived (archived=true) or an archived room was
+// reactivated by someone creating it again (archived=false)
+type ArchiveCallback func(room *Room, archived bool)
+
+// OnArchive registers a callback for automatic archive and reactivation events
+func (s *service) OnArchive(callback ArchiveCallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.archiveListeners = append(s.archiveListeners, callback)
+}
+
+// SetReactivateArchived controls whether creating a room with the name of an archived room restores it.
+// ปิดไว้ ชื่อห้องที่ถูก archive จะใช้สร้างใหม่ไม่ได้ (เหมือนเดิม); ตั้งตอนเริ่ม server ก่อนรับ connection
+func (s *service) SetReactivateArchived(enabled bool) {
+	s.reactivateArchived = enabled
+}
+
+// ArchiveIdle archives rooms that have had no members and no messages for longer than after.
+// ไม่แตะห้อง general, ห้องของระบบ และห้องชั่วคราว (มี TTL ของตัวเองอยู่แล้ว)
+func (s *service) ArchiveIdle(after time.Duration) int {
+	if after <= 0 {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-after)
+	archived := 0
+
+	for _, room := range s.repo.GetActiveRooms() {
+		if room.Name == "general" || room.CreatedBy == "System" || room.ExpiresAt != nil {
+			continue
+		}
+		if !room.LastActivity.Before(cutoff) || len(s.repo.GetUsersInRoom(room.Name)) > 0 {
+			continue
+		}
+
+		if err := s.repo.DeactivateRoom(room.Name); err != nil {
+			slog.Error("❌ Failed to archive idle room", "room", room.Name, "error", err)
+			continue
+		}
+
+		s.mutex.Lock()
+		delete(s.warned, room.Name)
+		s.mutex.Unlock()
+
+		slog.Info("🗄️ Room archived without members or messages", "room", room.Name, "idle", time.Since(room.LastActivity).Round(time.Second))
+		s.notifyArchive(room, true)
+		archived++
+	}
+
+	return archived
+}
+
+// reactivate restores an archived room for someone creating it again.
+// เจ้าของเดิมได้ตั้งค่าตาม opts ใหม่; คนอื่นได้ห้องกลับมาตามที่เจ้าของเคยตั้งไว้ และเจ้าของยังเป็นคนเดิม
+func (s *service) reactivate(name, creatorUsername string, opts CreateOptions) (*Room, error) {
+	room, err := s.repo.ReactivateRoom(name)
+	if err != nil {
+		return nil, err
+	}
+	room.Reactivated = true
+
+	if strings.EqualFold(room.CreatedBy, creatorUsername) {
+		if opts.MaxUsers != 0 {
+			if err := s.repo.UpdateMaxUsers(name, opts.MaxUsers); err != nil {
+				s.repo.DeactivateRoom(name)
+				return nil, err
+			}
+			room.MaxUsers = opts.MaxUsers
+		}
+		if err := s.applyCreateOptions(room, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	slog.Info("♻️ Room reactivated", "room", name, "by", creatorUsername, "owner", room.CreatedBy)
+	s.notifyArchive(room, false)
+	return room, nil
+}
+
+// notifyArchive notifies all archive listeners
+func (s *service) notifyArchive(room *Room, archived bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, callback := range s.archiveListeners {
+		callback(room, archived)
+	}
+}
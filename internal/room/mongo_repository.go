@@ -37,6 +37,17 @@ type RoomDocument struct {
 	IsActive    bool               `bson:"is_active" json:"is_active"`
 	UserCount   int                `bson:"user_count" json:"user_count"`
 	LastMessage time.Time          `bson:"last_message" json:"last_message"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	ExportKey   string             `bson:"export_key,omitempty" json:"export_key,omitempty"`
+	MirrorWriter string            `bson:"mirror_writer,omitempty" json:"mirror_writer,omitempty"`
+	Private     bool               `bson:"private,omitempty" json:"private,omitempty"`
+	IsPrivate   bool               `bson:"is_private,omitempty" json:"is_private,omitempty"`
+	PasswordHash string            `bson:"password_hash,omitempty" json:"-"`
+	InvitedUsers []string          `bson:"invited_users,omitempty" json:"invited_users,omitempty"`
+	Retention   time.Duration      `bson:"retention,omitempty" json:"retention,omitempty"`
+	Topic       string             `bson:"topic,omitempty" json:"topic,omitempty"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Pinned      []PinnedMessage    `bson:"pinned,omitempty" json:"pinned,omitempty"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
@@ -49,6 +60,18 @@ func (doc *RoomDocument) ToRoom() *Room {
 		CreatedBy: doc.CreatedBy,
 		MaxUsers:  doc.MaxUsers,
 		IsActive:  doc.IsActive,
+		LastActivity: doc.LastMessage,
+		ExpiresAt: doc.ExpiresAt,
+		ExportKey: doc.ExportKey,
+		MirrorWriter: doc.MirrorWriter,
+		Private:   doc.Private,
+		IsPrivate: doc.IsPrivate,
+		PasswordHash: doc.PasswordHash,
+		InvitedUsers: doc.InvitedUsers,
+		Retention: doc.Retention,
+		Topic:     doc.Topic,
+		Description: doc.Description,
+		Pinned:    doc.Pinned,
 	}
 }
 
@@ -59,6 +82,17 @@ func (doc *RoomDocument) FromRoom(room *Room) {
 	doc.CreatedBy = room.CreatedBy
 	doc.MaxUsers = room.MaxUsers
 	doc.IsActive = room.IsActive
+	doc.ExpiresAt = room.ExpiresAt
+	doc.ExportKey = room.ExportKey
+	doc.MirrorWriter = room.MirrorWriter
+	doc.Private = room.Private
+	doc.IsPrivate = room.IsPrivate
+	doc.PasswordHash = room.PasswordHash
+	doc.InvitedUsers = room.InvitedUsers
+	doc.Retention = room.Retention
+	doc.Topic = room.Topic
+	doc.Description = room.Description
+	doc.Pinned = room.Pinned
 	doc.UserCount = len(room.Users)
 	doc.UpdatedAt = time.Now()
 }
@@ -147,6 +181,17 @@ func (r *MongoRepository) GetByName(name string) (*Room, bool) {
 		CreatedBy: roomDoc.CreatedBy,
 		MaxUsers:  roomDoc.MaxUsers,
 		IsActive:  roomDoc.IsActive,
+		ExpiresAt: roomDoc.ExpiresAt,
+		ExportKey: roomDoc.ExportKey,
+		MirrorWriter: roomDoc.MirrorWriter,
+		Private:   roomDoc.Private,
+		IsPrivate: roomDoc.IsPrivate,
+		PasswordHash: roomDoc.PasswordHash,
+		InvitedUsers: roomDoc.InvitedUsers,
+		Retention: roomDoc.Retention,
+		Topic:     roomDoc.Topic,
+		Description: roomDoc.Description,
+		Pinned:    roomDoc.Pinned,
 	}
 
 	return room, true
@@ -184,6 +229,17 @@ func (r *MongoRepository) GetActiveRooms() []*Room {
 			CreatedBy: roomDoc.CreatedBy,
 			MaxUsers:  roomDoc.MaxUsers,
 			IsActive:  roomDoc.IsActive,
+			ExpiresAt: roomDoc.ExpiresAt,
+			ExportKey: roomDoc.ExportKey,
+			MirrorWriter: roomDoc.MirrorWriter,
+			Private:   roomDoc.Private,
+			IsPrivate: roomDoc.IsPrivate,
+			PasswordHash: roomDoc.PasswordHash,
+			InvitedUsers: roomDoc.InvitedUsers,
+			Retention: roomDoc.Retention,
+			Topic:     roomDoc.Topic,
+			Description: roomDoc.Description,
+			Pinned:    roomDoc.Pinned,
 		}
 		rooms = append(rooms, room)
 	}
@@ -223,6 +279,17 @@ func (r *MongoRepository) GetAll() []*Room {
 			CreatedBy: roomDoc.CreatedBy,
 			MaxUsers:  roomDoc.MaxUsers,
 			IsActive:  roomDoc.IsActive,
+			ExpiresAt: roomDoc.ExpiresAt,
+			ExportKey: roomDoc.ExportKey,
+			MirrorWriter: roomDoc.MirrorWriter,
+			Private:   roomDoc.Private,
+			IsPrivate: roomDoc.IsPrivate,
+			PasswordHash: roomDoc.PasswordHash,
+			InvitedUsers: roomDoc.InvitedUsers,
+			Retention: roomDoc.Retention,
+			Topic:     roomDoc.Topic,
+			Description: roomDoc.Description,
+			Pinned:    roomDoc.Pinned,
 		}
 		rooms = append(rooms, room)
 	}
@@ -407,4 +474,304 @@ func (r *MongoRepository) DeactivateRoom(roomName string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// UpdateMaxUsers changes the capacity of a room
+func (r *MongoRepository) UpdateMaxUsers(roomName string, maxUsers int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"max_users":  maxUsers,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update room capacity: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetExpiry sets the time at which a temporary room is archived
+func (r *MongoRepository) SetExpiry(roomName string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"expires_at": expiresAt,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set room expiry: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetExportKey sets (or clears) the public key used to encrypt exported transcripts
+func (r *MongoRepository) SetExportKey(roomName, armoredKey string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"export_key": armoredKey,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set export key: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetPrivate turns privacy mode of a room on or off
+func (r *MongoRepository) SetPrivate(roomName string, private bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"private":    private,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set privacy mode: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetAccess sets whether a room is invite-only and its password hash (empty = no password)
+func (r *MongoRepository) SetAccess(roomName string, inviteOnly bool, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_private":    inviteOnly,
+			"password_hash": passwordHash,
+			"updated_at":    time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set room access: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// AddInvite adds a user to a room's invitation list
+func (r *MongoRepository) AddInvite(roomName, username string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$addToSet": bson.M{"invited_users": username},
+		"$set":      bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to invite user: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetTopic sets a room's topic and description
+func (r *MongoRepository) SetTopic(roomName, topic, description string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"topic":       topic,
+			"description": description,
+			"updated_at":  time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set topic: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// AddPin pins a message to a room; pinning the same message twice has no effect
+func (r *MongoRepository) AddPin(roomName string, pin PinnedMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"name": roomName, "is_active": true, "pinned.message_id": bson.M{"$ne": pin.MessageID}}
+	update := bson.M{
+		"$push": bson.M{"pinned": pin},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to pin message: %v", err)
+	}
+
+	return nil
+}
+
+// RemovePin unpins a message from a room
+func (r *MongoRepository) RemovePin(roomName, messageID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$pull": bson.M{"pinned": bson.M{"message_id": messageID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to unpin message: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetRetention sets how long a room's messages are kept (0 = server default)
+func (r *MongoRepository) SetRetention(roomName string, retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"retention":  retention,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set retention: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// SetMirrorWriter sets (or clears) the node that accepts posts for a mirrored room
+func (r *MongoRepository) SetMirrorWriter(roomName, nodeID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"mirror_writer": nodeID,
+			"updated_at":    time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"name": roomName, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to set mirror writer: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("room not found")
+	}
+
+	return nil
+}
+
+// ReactivateRoom brings an archived room back with no members and no expiry
+func (r *MongoRepository) ReactivateRoom(roomName string) (*Room, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"is_active":    true,
+			"user_count":   0,
+			"last_message": now,
+			"updated_at":   now,
+		},
+		"$unset": bson.M{"expires_at": ""},
+	}
+
+	var doc RoomDocument
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"name": roomName, "is_active": false}, update, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRoomNotArchived
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reactivate room: %v", err)
+	}
+
+	return doc.ToRoom(), nil
+}
+
+// Touch records message activity in a room.
+// เขียนอย่างมากนาทีละครั้งต่อห้อง เพื่อไม่ให้ทุกข้อความต้อง UPDATE rooms
+func (r *MongoRepository) Touch(roomName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"name":         roomName,
+		"is_active":    true,
+		"last_message": bson.M{"$lt": now.Add(-time.Minute)},
+	}
+	r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_message": now}})
+}
@@ -2,6 +2,7 @@ package room
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,18 +19,39 @@ type Repository interface {
 	GetRoomCount() int
 	JoinRoom(user *userPkg.User, roomName string) error
 	LeaveRoom(user *userPkg.User, roomName string) error
+	UpdateMaxUsers(roomName string, maxUsers int) error
+	SetExpiry(roomName string, expiresAt time.Time) error
+	SetExportKey(roomName, armoredKey string) error
+	SetMirrorWriter(roomName, nodeID string) error
+	SetPrivate(roomName string, private bool) error
+	SetAccess(roomName string, inviteOnly bool, passwordHash string) error
+	AddInvite(roomName, username string) error
+	SetRetention(roomName string, retention time.Duration) error
+	SetTopic(roomName, topic, description string) error
+	AddPin(roomName string, pin PinnedMessage) error
+	RemovePin(roomName, messageID string) error
+	DeactivateRoom(roomName string) error
+	ReactivateRoom(roomName string) (*Room, error)
+	Touch(roomName string)
 }
 
 // InMemoryRepository implements Repository using in-memory storage
 type InMemoryRepository struct {
 	rooms map[string]*Room
 	mutex sync.RWMutex
+
+	// hibernation: ห้องที่ว่างนานจะถูกย้ายไปเก็บบน disk (ดู hibernation.go)
+	hibernateDir string
+	hibernated   map[string]bool
+	hibernations int64
+	rehydrations int64
 }
 
 // NewInMemoryRepository creates a new in-memory room repository
 func NewInMemoryRepository() *InMemoryRepository {
 	repo := &InMemoryRepository{
-		rooms: make(map[string]*Room),
+		rooms:      make(map[string]*Room),
+		hibernated: make(map[string]bool),
 	}
 
 	// สร้างห้อง default
@@ -40,6 +62,7 @@ func NewInMemoryRepository() *InMemoryRepository {
 		CreatedBy: "System",
 		MaxUsers:  100,
 		IsActive:  true,
+		LastActivity: time.Now(),
 	}
 	repo.rooms["general"] = defaultRoom
 
@@ -51,7 +74,7 @@ func (r *InMemoryRepository) Create(name, creatorUsername string, maxUsers int)
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.rooms[name]; exists {
+	if _, exists := r.rooms[name]; exists || r.hibernated[name] {
 		return nil, fmt.Errorf("room '%s' already exists", name)
 	}
 
@@ -62,6 +85,7 @@ func (r *InMemoryRepository) Create(name, creatorUsername string, maxUsers int)
 		CreatedBy: creatorUsername,
 		MaxUsers:  maxUsers,
 		IsActive:  true,
+		LastActivity: time.Now(),
 	}
 
 	r.rooms[name] = room
@@ -71,9 +95,22 @@ func (r *InMemoryRepository) Create(name, creatorUsername string, maxUsers int)
 // GetByName gets a room by name
 func (r *InMemoryRepository) GetByName(name string) (*Room, bool) {
 	r.mutex.RLock()
-	defer r.mutex.RUnlock()
 	room, exists := r.rooms[name]
-	return room, exists
+	hibernated := r.hibernated[name]
+	r.mutex.RUnlock()
+
+	if exists || !hibernated {
+		// ห้องที่ถูก archive แล้วไม่นับว่ามีอยู่ (เหมือน MongoDB ที่ค้นเฉพาะ is_active)
+		if exists && !room.IsActive {
+			return nil, false
+		}
+		return room, exists
+	}
+
+	// ห้องถูกพักไว้ ปลุกกลับมาเมื่อมีการเข้าถึง
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.roomLocked(name)
 }
 
 // GetAll returns all rooms
@@ -81,11 +118,11 @@ func (r *InMemoryRepository) GetAll() []*Room {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	rooms := make([]*Room, 0, len(r.rooms))
+	rooms := make([]*Room, 0, len(r.rooms)+len(r.hibernated))
 	for _, room := range r.rooms {
 		rooms = append(rooms, room)
 	}
-	return rooms
+	return append(rooms, r.peekHibernatedLocked()...)
 }
 
 // GetActiveRooms returns all active rooms
@@ -93,13 +130,14 @@ func (r *InMemoryRepository) GetActiveRooms() []*Room {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	rooms := make([]*Room, 0, len(r.rooms))
+	rooms := make([]*Room, 0, len(r.rooms)+len(r.hibernated))
 	for _, room := range r.rooms {
 		if room.IsActive {
 			rooms = append(rooms, room)
 		}
 	}
-	return rooms
+	// ห้องที่พักไว้ยังนับเป็นห้องที่ใช้งานอยู่ แต่ไม่ต้องปลุกขึ้นมาแค่เพื่อแสดงรายชื่อ
+	return append(rooms, r.peekHibernatedLocked()...)
 }
 
 // GetUsersInRoom returns all users in a specific room
@@ -124,7 +162,7 @@ func (r *InMemoryRepository) GetRoomCount() int {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	count := 0
+	count := len(r.hibernated)
 	for _, room := range r.rooms {
 		if room.IsActive {
 			count++
@@ -138,8 +176,8 @@ func (r *InMemoryRepository) JoinRoom(user *userPkg.User, roomName string) error
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, exists := r.rooms[roomName]
-	if !exists {
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
 		return fmt.Errorf("room '%s' does not exist", roomName)
 	}
 
@@ -170,6 +208,10 @@ func (r *InMemoryRepository) LeaveRoom(user *userPkg.User, roomName string) erro
 func (r *InMemoryRepository) leaveRoomInternal(user *userPkg.User, roomName string) error {
 	room, exists := r.rooms[roomName]
 	if !exists {
+		// ห้องที่พักไว้ไม่มีผู้ใช้อยู่แล้ว
+		if r.hibernated[roomName] {
+			return nil
+		}
 		return fmt.Errorf("room '%s' does not exist", roomName)
 	}
 
@@ -179,4 +221,221 @@ func (r *InMemoryRepository) leaveRoomInternal(user *userPkg.User, roomName stri
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// UpdateMaxUsers changes the capacity of a room
+func (r *InMemoryRepository) UpdateMaxUsers(roomName string, maxUsers int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.MaxUsers = maxUsers
+	return nil
+}
+
+// SetExpiry sets the time at which a temporary room is archived
+func (r *InMemoryRepository) SetExpiry(roomName string, expiresAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.ExpiresAt = &expiresAt
+	return nil
+}
+
+// SetExportKey sets (or clears) the public key used to encrypt exported transcripts
+func (r *InMemoryRepository) SetExportKey(roomName, armoredKey string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.ExportKey = armoredKey
+	return nil
+}
+
+// SetMirrorWriter sets (or clears) the node that accepts posts for a mirrored room
+func (r *InMemoryRepository) SetMirrorWriter(roomName, nodeID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.MirrorWriter = nodeID
+	return nil
+}
+
+// SetPrivate turns privacy mode of a room on or off
+func (r *InMemoryRepository) SetPrivate(roomName string, private bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.Private = private
+	return nil
+}
+
+// SetAccess sets whether a room is invite-only and its password hash (empty = no password)
+func (r *InMemoryRepository) SetAccess(roomName string, inviteOnly bool, passwordHash string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.IsPrivate = inviteOnly
+	room.PasswordHash = passwordHash
+	return nil
+}
+
+// AddInvite adds a user to a room's invitation list
+func (r *InMemoryRepository) AddInvite(roomName, username string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	for _, invited := range room.InvitedUsers {
+		if strings.EqualFold(invited, username) {
+			return nil
+		}
+	}
+	room.InvitedUsers = append(room.InvitedUsers, username)
+	return nil
+}
+
+// SetRetention sets how long a room's messages are kept (0 = server default)
+func (r *InMemoryRepository) SetRetention(roomName string, retention time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.Retention = retention
+	return nil
+}
+
+// SetTopic sets a room's topic and description
+func (r *InMemoryRepository) SetTopic(roomName, topic, description string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	room.Topic = topic
+	room.Description = description
+	return nil
+}
+
+// AddPin pins a message to a room; pinning the same message twice has no effect
+func (r *InMemoryRepository) AddPin(roomName string, pin PinnedMessage) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if !room.IsPinned(pin.MessageID) {
+		room.Pinned = append(room.Pinned, pin)
+	}
+	return nil
+}
+
+// RemovePin unpins a message from a room
+func (r *InMemoryRepository) RemovePin(roomName, messageID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists || !room.IsActive {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	pinned := make([]PinnedMessage, 0, len(room.Pinned))
+	for _, pin := range room.Pinned {
+		if pin.MessageID != messageID {
+			pinned = append(pinned, pin)
+		}
+	}
+	room.Pinned = pinned
+	return nil
+}
+
+// DeactivateRoom archives a room and removes its members
+func (r *InMemoryRepository) DeactivateRoom(roomName string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.roomLocked(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	for _, user := range room.Users {
+		if user.CurrentRoom == roomName {
+			user.CurrentRoom = ""
+		}
+	}
+	room.Users = make(map[string]*userPkg.User)
+	room.IsActive = false
+	return nil
+}
+
+// ReactivateRoom brings an archived room back with no members and no expiry.
+// คืน ErrRoomNotArchived ถ้าไม่มีห้องชื่อนี้ที่ถูก archive ไว้
+func (r *InMemoryRepository) ReactivateRoom(roomName string) (*Room, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, exists := r.rooms[roomName]
+	if !exists || room.IsActive {
+		return nil, ErrRoomNotArchived
+	}
+
+	room.Users = make(map[string]*userPkg.User)
+	room.ExpiresAt = nil
+	room.LastActivity = time.Now()
+	room.IsActive = true
+	return room, nil
+}
+
+// Touch records message activity in a room
+func (r *InMemoryRepository) Touch(roomName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if room, exists := r.rooms[roomName]; exists {
+		room.LastActivity = time.Now()
+	}
+}
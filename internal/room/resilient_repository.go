@@ -0,0 +1,197 @@
+package room
+
+import (
+	"time"
+
+	"realtime-chat/internal/database"
+	userPkg "realtime-chat/internal/user"
+)
+
+// ResilientRepository wraps a Repository with the database circuit breaker.
+// ระหว่างที่ breaker เปิด ทุก call ตอบทันทีแทนที่จะรอ timeout ของ MongoDB; error เช่นห้องเต็มหรือชื่อซ้ำ
+// เป็นความผิดของผู้ใช้ จึงไม่ป้อนผลกลับเข้า breaker (health monitor เป็นผู้เปิด/ปิด)
+type ResilientRepository struct {
+	Repository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientRepository creates a room repository that fails fast during outages
+func NewResilientRepository(repo Repository, breaker *database.CircuitBreaker) *ResilientRepository {
+	return &ResilientRepository{Repository: repo, breaker: breaker}
+}
+
+// Create fails fast while the database is unavailable
+func (r *ResilientRepository) Create(name, creatorUsername string, maxUsers int) (*Room, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.Create(name, creatorUsername, maxUsers)
+}
+
+// GetByName finds nothing while the database is unavailable
+func (r *ResilientRepository) GetByName(name string) (*Room, bool) {
+	if r.breaker.IsOpen() {
+		return nil, false
+	}
+	return r.Repository.GetByName(name)
+}
+
+// GetAll returns no rooms while the database is unavailable
+func (r *ResilientRepository) GetAll() []*Room {
+	if r.breaker.IsOpen() {
+		return []*Room{}
+	}
+	return r.Repository.GetAll()
+}
+
+// GetActiveRooms returns no rooms while the database is unavailable
+func (r *ResilientRepository) GetActiveRooms() []*Room {
+	if r.breaker.IsOpen() {
+		return []*Room{}
+	}
+	return r.Repository.GetActiveRooms()
+}
+
+// GetUsersInRoom returns no users while the database is unavailable
+func (r *ResilientRepository) GetUsersInRoom(roomName string) []*userPkg.User {
+	if r.breaker.IsOpen() {
+		return []*userPkg.User{}
+	}
+	return r.Repository.GetUsersInRoom(roomName)
+}
+
+// GetRoomCount returns 0 while the database is unavailable
+func (r *ResilientRepository) GetRoomCount() int {
+	if r.breaker.IsOpen() {
+		return 0
+	}
+	return r.Repository.GetRoomCount()
+}
+
+// JoinRoom fails fast while the database is unavailable
+func (r *ResilientRepository) JoinRoom(user *userPkg.User, roomName string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.JoinRoom(user, roomName)
+}
+
+// LeaveRoom fails fast while the database is unavailable
+func (r *ResilientRepository) LeaveRoom(user *userPkg.User, roomName string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.LeaveRoom(user, roomName)
+}
+
+// UpdateMaxUsers fails fast while the database is unavailable
+func (r *ResilientRepository) UpdateMaxUsers(roomName string, maxUsers int) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.UpdateMaxUsers(roomName, maxUsers)
+}
+
+// SetExpiry fails fast while the database is unavailable
+func (r *ResilientRepository) SetExpiry(roomName string, expiresAt time.Time) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetExpiry(roomName, expiresAt)
+}
+
+// SetExportKey fails fast while the database is unavailable
+func (r *ResilientRepository) SetExportKey(roomName, armoredKey string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetExportKey(roomName, armoredKey)
+}
+
+// SetMirrorWriter fails fast while the database is unavailable
+func (r *ResilientRepository) SetMirrorWriter(roomName, nodeID string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetMirrorWriter(roomName, nodeID)
+}
+
+// SetPrivate fails fast while the database is unavailable
+func (r *ResilientRepository) SetPrivate(roomName string, private bool) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetPrivate(roomName, private)
+}
+
+// SetAccess fails fast while the database is unavailable
+func (r *ResilientRepository) SetAccess(roomName string, inviteOnly bool, passwordHash string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetAccess(roomName, inviteOnly, passwordHash)
+}
+
+// AddInvite fails fast while the database is unavailable
+func (r *ResilientRepository) AddInvite(roomName, username string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.AddInvite(roomName, username)
+}
+
+// SetRetention fails fast while the database is unavailable
+func (r *ResilientRepository) SetRetention(roomName string, retention time.Duration) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetRetention(roomName, retention)
+}
+
+// SetTopic fails fast while the database is unavailable
+func (r *ResilientRepository) SetTopic(roomName, topic, description string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.SetTopic(roomName, topic, description)
+}
+
+// AddPin fails fast while the database is unavailable
+func (r *ResilientRepository) AddPin(roomName string, pin PinnedMessage) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.AddPin(roomName, pin)
+}
+
+// RemovePin fails fast while the database is unavailable
+func (r *ResilientRepository) RemovePin(roomName, messageID string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.RemovePin(roomName, messageID)
+}
+
+// DeactivateRoom fails fast while the database is unavailable
+func (r *ResilientRepository) DeactivateRoom(roomName string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.DeactivateRoom(roomName)
+}
+
+// ReactivateRoom fails fast while the database is unavailable
+func (r *ResilientRepository) ReactivateRoom(roomName string) (*Room, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.ReactivateRoom(roomName)
+}
+
+// Touch is skipped while the database is unavailable
+func (r *ResilientRepository) Touch(roomName string) {
+	if r.breaker.IsOpen() {
+		return
+	}
+	r.Repository.Touch(roomName)
+}
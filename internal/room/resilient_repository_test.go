@@ -0,0 +1,34 @@
+package room
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"realtime-chat/internal/database"
+)
+
+// ระหว่างที่ breaker เปิด repository ต้องตอบทันทีโดยไม่แตะ repository จริง และกลับมาใช้ได้เมื่อ breaker ปิด
+func TestResilientRepositoryFailsFastWhileOpen(t *testing.T) {
+	breaker := database.NewCircuitBreaker(1, time.Hour)
+	repo := NewResilientRepository(NewInMemoryRepository(), breaker)
+	if _, err := repo.Create("lobby", "alice", 10); err != nil {
+		t.Fatalf("create while closed: %v", err)
+	}
+
+	breaker.RecordFailure()
+	if _, err := repo.Create("dev", "alice", 10); !errors.Is(err, database.ErrUnavailable) {
+		t.Fatalf("create while open = %v, want ErrUnavailable", err)
+	}
+	if _, exists := repo.GetByName("lobby"); exists {
+		t.Fatal("GetByName reached the database while the breaker is open")
+	}
+	if err := repo.SetTopic("lobby", "topic", ""); !errors.Is(err, database.ErrUnavailable) {
+		t.Fatalf("SetTopic while open = %v, want ErrUnavailable", err)
+	}
+
+	breaker.RecordSuccess()
+	if _, exists := repo.GetByName("lobby"); !exists {
+		t.Fatal("room missing after the breaker closed")
+	}
+}
@@ -2,7 +2,12 @@ package room
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
 
 	"realtime-chat/internal/config"
 	userPkg "realtime-chat/internal/user"
@@ -11,58 +16,183 @@ import (
 // Service handles room business logic
 type Service interface {
 	CreateRoom(name, creatorUsername string) (*Room, error)
+	CreateRoomWithOptions(name, creatorUsername string, opts CreateOptions) (*Room, error)
+	SetMaxUsers(roomName, requestedBy string, maxUsers int) error
+	SetExportKey(roomName, requestedBy, armoredKey string) error
+	SetMirrorWriter(roomName, requestedBy, nodeID string) error
+	SetPrivate(roomName, requestedBy string, private bool) error
+	InviteUser(roomName, requestedBy, username string) error
+	AuthorizeJoin(roomName, username, password string) error
+	IsMember(roomName, username string) bool
+	SetRetention(roomName, requestedBy string, retention time.Duration) error
+	SetTopic(roomName, requestedBy, topic string) error
+	SetDescription(roomName, requestedBy, description string) error
+	PinMessage(roomName, requestedBy, messageID string) error
+	UnpinMessage(roomName, requestedBy, messageID string) error
+	ArchiveRoom(roomName string) error
 	JoinRoom(user *userPkg.User, roomName string) error
 	LeaveRoom(user *userPkg.User, roomName string) error
 	GetRoom(name string) (*Room, bool)
 	GetRooms() []*Room
 	GetUsersInRoom(roomName string) []*userPkg.User
 	GetRoomCount() int
+	RecordActivity(roomName string)
+	CheckExpiries(warnings []time.Duration) int
+	OnMembershipChange(callback MembershipCallback)
+	OnExpiry(callback ExpiryCallback)
+	ArchiveIdle(after time.Duration) int
+	SetReactivateArchived(enabled bool)
+	OnArchive(callback ArchiveCallback)
+}
+
+// CreateOptions holds optional per-room settings given at creation time
+type CreateOptions struct {
+	MaxUsers int           // 0 = ใช้ค่า default ของ server
+	TTL      time.Duration // 0 = ห้องถาวร, มากกว่า 0 = archive อัตโนมัติเมื่อครบเวลา
+	InviteOnly bool        // ซ่อนห้องจาก /rooms และให้เข้าได้เฉพาะผู้ที่ได้รับเชิญ
+	Password string        // ว่าง = ไม่มีรหัสผ่าน; เก็บเป็น bcrypt hash เท่านั้น
+	PasswordHash string    // bcrypt hash ที่มีอยู่แล้ว (เช่นตอน /clone) ใช้เมื่อไม่ได้ระบุ Password
 }
 
+// Limits for room details set by owners
+const (
+	MaxTopicLength       = 200  // ตัวอักษร
+	MaxDescriptionLength = 1000 // ตัวอักษร
+	MaxPinnedMessages    = 10
+)
+
+// MembershipCallback is invoked after a user joins (joined=true) or leaves a room
+type MembershipCallback func(roomName string, user *userPkg.User, joined bool)
+
 // service implements Service
 type service struct {
 	repo      Repository
 	maxRooms  int
 	maxUsers  int
+	capacity  int
 	metrics   *config.ServerMetrics
+	listeners []MembershipCallback
+	expiryListeners []ExpiryCallback
+	archiveListeners []ArchiveCallback
+	reactivateArchived bool // สร้างห้องชื่อเดิมซ้ำ = ดึงห้องที่ archive ไว้กลับมา
+	warned    map[string]time.Duration // roomName -> ระยะเตือนล่าสุดที่ส่งไปแล้ว
+	mutex     sync.RWMutex
 }
 
 // NewService creates a new room service
-func NewService(repo Repository, maxRooms, maxUsers int, metrics *config.ServerMetrics) Service {
+func NewService(repo Repository, maxRooms, maxUsers, capacity int, metrics *config.ServerMetrics) Service {
+	if capacity < maxUsers {
+		capacity = maxUsers
+	}
 	return &service{
 		repo:     repo,
 		maxRooms: maxRooms,
 		maxUsers: maxUsers,
+		capacity: capacity,
 		metrics:  metrics,
+		warned:   make(map[string]time.Duration),
 	}
 }
 
-// CreateRoom creates a new room
+// CreateRoom creates a new room with default settings
 func (s *service) CreateRoom(name, creatorUsername string) (*Room, error) {
+	return s.CreateRoomWithOptions(name, creatorUsername, CreateOptions{})
+}
+
+// CreateRoomWithOptions creates a new room with per-room settings
+func (s *service) CreateRoomWithOptions(name, creatorUsername string, opts CreateOptions) (*Room, error) {
 	// ตรวจสอบ room limits
 	if s.repo.GetRoomCount() >= s.maxRooms {
 		return nil, fmt.Errorf("server room limit reached (%d/%d)", s.repo.GetRoomCount(), s.maxRooms)
 	}
 
-	room, err := s.repo.Create(name, creatorUsername, s.maxUsers)
+	maxUsers := s.maxUsers
+	if opts.MaxUsers != 0 {
+		if err := s.validateCapacity(opts.MaxUsers); err != nil {
+			return nil, err
+		}
+		maxUsers = opts.MaxUsers
+	}
+
+	if s.reactivateArchived {
+		room, err := s.reactivate(name, creatorUsername, opts)
+		if err != ErrRoomNotArchived {
+			return room, err
+		}
+	}
+
+	room, err := s.repo.Create(name, creatorUsername, maxUsers)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("🏠 Room '%s' created by %s (%d/%d rooms)", name, creatorUsername, s.repo.GetRoomCount(), s.maxRooms)
+	if err := s.applyCreateOptions(room, opts); err != nil {
+		return nil, err
+	}
+
+	slog.Info("🏠 Room created", "room", name, "by", creatorUsername, "rooms", s.repo.GetRoomCount(), "max_rooms", s.maxRooms)
 	s.metrics.IncrementRooms()
 	return room, nil
 }
 
-// JoinRoom adds a user to a room
+// applyCreateOptions applies the TTL and access options of a new (or reactivated) room.
+// ถ้าตั้งค่าไม่สำเร็จจะ archive ห้องทิ้ง เพื่อไม่ให้ห้องที่ขอให้ล็อก/หมดอายุกลายเป็นห้องถาวรที่เปิดอยู่
+func (s *service) applyCreateOptions(room *Room, opts CreateOptions) error {
+	name := room.Name
+	if opts.TTL > 0 {
+		expiresAt := time.Now().Add(opts.TTL)
+		if err := s.repo.SetExpiry(name, expiresAt); err != nil {
+			// ไม่ปล่อยให้ห้องชั่วคราวกลายเป็นห้องถาวร
+			s.repo.DeactivateRoom(name)
+			return fmt.Errorf("failed to set room expiry: %v", err)
+		}
+		room.ExpiresAt = &expiresAt
+		slog.Info("⏳ Room will expire", "room", name, "expires_at", expiresAt.Format(time.RFC3339))
+	}
+
+	if opts.InviteOnly || opts.Password != "" || opts.PasswordHash != "" {
+		passwordHash := opts.PasswordHash
+		if opts.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+			if err != nil {
+				s.repo.DeactivateRoom(name)
+				return fmt.Errorf("invalid room password: %v", err)
+			}
+			passwordHash = string(hash)
+		}
+		// ไม่ปล่อยให้ห้องที่ขอให้ล็อกไว้กลายเป็นห้องเปิด
+		if err := s.repo.SetAccess(name, opts.InviteOnly, passwordHash); err != nil {
+			s.repo.DeactivateRoom(name)
+			return fmt.Errorf("failed to restrict room access: %v", err)
+		}
+		room.IsPrivate = opts.InviteOnly
+		room.PasswordHash = passwordHash
+		slog.Info("🔐 Room access restricted", "room", name, "invite_only", opts.InviteOnly, "password", passwordHash != "")
+	}
+	return nil
+}
+
+// JoinRoom adds a user to a room; restricted rooms only admit members (see AuthorizeJoin)
 func (s *service) JoinRoom(user *userPkg.User, roomName string) error {
+	previousRoom := user.CurrentRoom
+
+	if err := s.AuthorizeJoin(roomName, user.Username, ""); err != nil {
+		return err
+	}
+
 	err := s.repo.JoinRoom(user, roomName)
 	if err != nil {
 		return err
 	}
 
 	room, _ := s.repo.GetByName(roomName)
-	log.Printf("🚪 User %s joined room '%s' (%d/%d users)", user.Username, roomName, len(room.Users), room.MaxUsers)
+	slog.Info("🚪 User joined room", "username", user.Username, "room", roomName, "users", len(room.Users), "max_users", room.MaxUsers)
+
+	// repository ย้ายผู้ใช้ออกจากห้องเก่าให้อัตโนมัติ แจ้ง listener ด้วย
+	if previousRoom != "" && previousRoom != roomName {
+		s.notifyMembership(previousRoom, user, false)
+	}
+	s.notifyMembership(roomName, user, true)
 	return nil
 }
 
@@ -74,7 +204,342 @@ func (s *service) LeaveRoom(user *userPkg.User, roomName string) error {
 	}
 
 	room, _ := s.repo.GetByName(roomName)
-	log.Printf("🚪 User %s left room '%s' (%d/%d users)", user.Username, roomName, len(room.Users), room.MaxUsers)
+	slog.Info("🚪 User left room", "username", user.Username, "room", roomName, "users", len(room.Users), "max_users", room.MaxUsers)
+
+	// นับเวลาว่างของห้อง (archive อัตโนมัติ) จากคนสุดท้ายที่ออก ไม่ใช่จากข้อความล่าสุด
+	if len(room.Users) == 0 {
+		s.repo.Touch(roomName)
+	}
+
+	s.notifyMembership(roomName, user, false)
+	return nil
+}
+
+// SetMaxUsers changes a room's capacity (room owner only)
+func (s *service) SetMaxUsers(roomName, requestedBy string, maxUsers int) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can change capacity")
+	}
+
+	if err := s.validateCapacity(maxUsers); err != nil {
+		return err
+	}
+
+	if maxUsers < len(room.Users) {
+		return fmt.Errorf("room currently has %d users, capacity cannot be lower", len(room.Users))
+	}
+
+	if err := s.repo.UpdateMaxUsers(roomName, maxUsers); err != nil {
+		return err
+	}
+
+	slog.Info("🏠 Room capacity changed", "room", roomName, "max_users", maxUsers, "by", requestedBy)
+	return nil
+}
+
+// SetExportKey sets the public key used to encrypt a room's exported transcripts (room owner only).
+// ไม่ตรวจสอบรูปแบบ key ที่นี่ ผู้เรียกต้องตรวจด้วย export.ValidateRecipientKey ก่อน
+func (s *service) SetExportKey(roomName, requestedBy, armoredKey string) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can set the export key")
+	}
+
+	if err := s.repo.SetExportKey(roomName, armoredKey); err != nil {
+		return err
+	}
+
+	if armoredKey == "" {
+		slog.Info("🔓 Room export key cleared", "room", roomName, "by", requestedBy)
+	} else {
+		slog.Info("🔐 Room export key set", "room", roomName, "by", requestedBy)
+	}
+	return nil
+}
+
+// SetMirrorWriter switches a room into mirror mode with nodeID as its only writer,
+// or back to a normal room when nodeID is empty (room owner only)
+func (s *service) SetMirrorWriter(roomName, requestedBy, nodeID string) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can change mirror mode")
+	}
+
+	// ห้อง mirror กระจายข้อความจากฐานข้อมูล จึงใช้กับห้อง private ที่ไม่บันทึกข้อความไม่ได้
+	if nodeID != "" && room.Private {
+		return fmt.Errorf("room '%s' is in privacy mode; mirrored rooms need message persistence", roomName)
+	}
+
+	if err := s.repo.SetMirrorWriter(roomName, nodeID); err != nil {
+		return err
+	}
+
+	if nodeID == "" {
+		slog.Info("🪞 Room mirror mode disabled", "room", roomName, "by", requestedBy)
+	} else {
+		slog.Info("🪞 Room mirror mode enabled", "room", roomName, "by", requestedBy, "writer_node", nodeID)
+	}
+	return nil
+}
+
+// SetPrivate turns privacy mode on or off: a private room's messages are delivered but never
+// persisted, indexed or counted in analytics (room owner only)
+func (s *service) SetPrivate(roomName, requestedBy string, private bool) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can change privacy mode")
+	}
+
+	if private && room.MirrorWriter != "" {
+		return fmt.Errorf("room '%s' is a broadcast mirror; turn mirror mode off first", roomName)
+	}
+
+	if err := s.repo.SetPrivate(roomName, private); err != nil {
+		return err
+	}
+
+	if private {
+		slog.Info("🔒 Room privacy mode enabled", "room", roomName, "by", requestedBy)
+	} else {
+		slog.Info("🔓 Room privacy mode disabled", "room", roomName, "by", requestedBy)
+	}
+	return nil
+}
+
+// InviteUser lets a user enter an invite-only or password-protected room without the password (room owner only)
+func (s *service) InviteUser(roomName, requestedBy, username string) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can invite users")
+	}
+
+	if !room.Restricted() {
+		return fmt.Errorf("room '%s' is open to everyone", roomName)
+	}
+
+	if room.IsMember(username) {
+		return fmt.Errorf("%s can already join room '%s'", username, roomName)
+	}
+
+	if err := s.repo.AddInvite(roomName, username); err != nil {
+		return err
+	}
+
+	slog.Info("✉️ User invited to room", "room", roomName, "username", username, "by", requestedBy)
+	return nil
+}
+
+// AuthorizeJoin checks that a user may join a room. Members (owner, invited users, users already
+// inside) always may; others need the room password. A correct password is remembered by adding
+// the user to the invitation list, so later joins and session resume don't ask again.
+// ห้อง invite-only ที่ไม่มีรหัสผ่านตอบเหมือนห้องที่ไม่มีอยู่ เพื่อไม่ให้รู้ว่ามีห้องนี้
+func (s *service) AuthorizeJoin(roomName, username, password string) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists || !room.Restricted() || room.IsMember(username) {
+		// ห้องที่ไม่มีอยู่ให้ repository เป็นผู้ตอบ error
+		return nil
+	}
+
+	if room.PasswordHash == "" {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+	if password == "" {
+		return fmt.Errorf("room '%s' requires a password", roomName)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(room.PasswordHash), []byte(password)) != nil {
+		return fmt.Errorf("incorrect password for room '%s'", roomName)
+	}
+
+	if err := s.repo.AddInvite(roomName, username); err != nil {
+		return fmt.Errorf("failed to grant access to room '%s': %v", roomName, err)
+	}
+	return nil
+}
+
+// IsMember reports whether a user may read a room's content (history, search, members, export).
+// ห้องเปิดอ่านได้ทุกคน ห้อง invite-only/มีรหัสผ่านอ่านได้เฉพาะเจ้าของ ผู้ได้รับเชิญ และผู้ที่อยู่ในห้อง;
+// ห้องที่ไม่มีอยู่ตอบ false
+func (s *service) IsMember(roomName, username string) bool {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return false
+	}
+	return !room.Restricted() || room.IsMember(username)
+}
+
+// SetRetention sets how long a room's messages are kept before the retention janitor purges them;
+// 0 returns the room to the server default (room owner only)
+func (s *service) SetRetention(roomName, requestedBy string, retention time.Duration) error {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return fmt.Errorf("only the room owner can change message retention")
+	}
+
+	if retention < 0 {
+		return fmt.Errorf("retention cannot be negative")
+	}
+
+	if err := s.repo.SetRetention(roomName, retention); err != nil {
+		return err
+	}
+
+	if retention == 0 {
+		slog.Info("🗓️ Room message retention reset to server default", "room", roomName, "by", requestedBy)
+	} else {
+		slog.Info("🗓️ Room message retention changed", "room", roomName, "retention", retention, "by", requestedBy)
+	}
+	return nil
+}
+
+// SetTopic sets the room topic shown to members on join; "" clears it (room owner only)
+func (s *service) SetTopic(roomName, requestedBy, topic string) error {
+	room, err := s.ownedRoom(roomName, requestedBy, "change the topic")
+	if err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(topic) > MaxTopicLength {
+		return fmt.Errorf("topic is too long (max %d characters)", MaxTopicLength)
+	}
+
+	if err := s.repo.SetTopic(roomName, topic, room.Description); err != nil {
+		return err
+	}
+
+	slog.Info("📌 Room topic changed", "room", roomName, "by", requestedBy)
+	return nil
+}
+
+// SetDescription sets the longer room description; "" clears it (room owner only)
+func (s *service) SetDescription(roomName, requestedBy, description string) error {
+	room, err := s.ownedRoom(roomName, requestedBy, "change the description")
+	if err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return fmt.Errorf("description is too long (max %d characters)", MaxDescriptionLength)
+	}
+
+	if err := s.repo.SetTopic(roomName, room.Topic, description); err != nil {
+		return err
+	}
+
+	slog.Info("📌 Room description changed", "room", roomName, "by", requestedBy)
+	return nil
+}
+
+// PinMessage pins a message to the room (room owner only); the caller checks that the message belongs to the room
+func (s *service) PinMessage(roomName, requestedBy, messageID string) error {
+	room, err := s.ownedRoom(roomName, requestedBy, "pin messages")
+	if err != nil {
+		return err
+	}
+
+	if room.IsPinned(messageID) {
+		return fmt.Errorf("message '%s' is already pinned", messageID)
+	}
+	if len(room.Pinned) >= MaxPinnedMessages {
+		return fmt.Errorf("room '%s' already has %d pinned messages, unpin one first", roomName, MaxPinnedMessages)
+	}
+
+	pin := PinnedMessage{MessageID: messageID, PinnedBy: requestedBy, PinnedAt: time.Now()}
+	if err := s.repo.AddPin(roomName, pin); err != nil {
+		return err
+	}
+
+	slog.Info("📌 Message pinned", "room", roomName, "message_id", messageID, "by", requestedBy)
+	return nil
+}
+
+// UnpinMessage removes a pinned message from the room (room owner only)
+func (s *service) UnpinMessage(roomName, requestedBy, messageID string) error {
+	room, err := s.ownedRoom(roomName, requestedBy, "unpin messages")
+	if err != nil {
+		return err
+	}
+
+	if !room.IsPinned(messageID) {
+		return fmt.Errorf("message '%s' is not pinned", messageID)
+	}
+
+	if err := s.repo.RemovePin(roomName, messageID); err != nil {
+		return err
+	}
+
+	slog.Info("📌 Message unpinned", "room", roomName, "message_id", messageID, "by", requestedBy)
+	return nil
+}
+
+// ownedRoom returns a room if requestedBy owns it; action completes the "only the room owner can ..." error
+func (s *service) ownedRoom(roomName, requestedBy, action string) (*Room, error) {
+	room, exists := s.repo.GetByName(roomName)
+	if !exists {
+		return nil, fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if room.CreatedBy != requestedBy {
+		return nil, fmt.Errorf("only the room owner can %s", action)
+	}
+	return room, nil
+}
+
+// ArchiveRoom archives an empty room; the default room and rooms with members are refused
+func (s *service) ArchiveRoom(roomName string) error {
+	if roomName == "general" {
+		return fmt.Errorf("the default room cannot be deleted")
+	}
+
+	if _, exists := s.repo.GetByName(roomName); !exists {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+
+	if members := len(s.repo.GetUsersInRoom(roomName)); members > 0 {
+		return fmt.Errorf("room '%s' still has %d members", roomName, members)
+	}
+
+	if err := s.repo.DeactivateRoom(roomName); err != nil {
+		return fmt.Errorf("failed to archive room: %v", err)
+	}
+
+	s.mutex.Lock()
+	delete(s.warned, roomName)
+	s.mutex.Unlock()
+
+	slog.Info("🗑️ Room archived", "room", roomName)
+	return nil
+}
+
+// validateCapacity checks a requested capacity against the server ceiling
+func (s *service) validateCapacity(maxUsers int) error {
+	if maxUsers < 1 || maxUsers > s.capacity {
+		return fmt.Errorf("room capacity must be between 1 and %d", s.capacity)
+	}
 	return nil
 }
 
@@ -96,4 +561,26 @@ func (s *service) GetUsersInRoom(roomName string) []*userPkg.User {
 // GetRoomCount returns the number of active rooms
 func (s *service) GetRoomCount() int {
 	return s.repo.GetRoomCount()
-}
\ No newline at end of file
+}
+
+// RecordActivity marks a room as recently active (used for idle hibernation)
+func (s *service) RecordActivity(roomName string) {
+	s.repo.Touch(roomName)
+}
+
+// OnMembershipChange registers a callback for join/leave events
+func (s *service) OnMembershipChange(callback MembershipCallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, callback)
+}
+
+// notifyMembership notifies all membership listeners
+func (s *service) notifyMembership(roomName string, user *userPkg.User, joined bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, callback := range s.listeners {
+		callback(roomName, user, joined)
+	}
+}
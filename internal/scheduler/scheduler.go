@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// job is a named task that runs on a fixed interval
+type job struct {
+	name     string
+	interval time.Duration
+	run      func()
+}
+
+// Scheduler runs periodic background jobs and stops them together on shutdown.
+// งานแต่ละตัวรันใน goroutine ของตัวเอง งานที่ panic จะไม่ทำให้ server ล่ม
+type Scheduler struct {
+	jobs    []*job
+	started bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// New creates a new scheduler
+func New() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// Every registers a job that runs every interval (starts immediately if the scheduler is running)
+func (s *Scheduler) Every(name string, interval time.Duration, run func()) {
+	if interval <= 0 {
+		slog.Warn("⚠️ Scheduler job ignored, interval must be positive", "job", name)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j := &job{name: name, interval: interval, run: run}
+	s.jobs = append(s.jobs, j)
+	if s.started {
+		s.startLocked(j)
+	}
+}
+
+// Start starts all registered jobs
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, j := range s.jobs {
+		s.startLocked(j)
+	}
+	slog.Info("⏰ Scheduler started", "jobs", len(s.jobs))
+}
+
+// Stop stops all jobs and waits for running ones to finish
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.started {
+		s.mutex.Unlock()
+		return
+	}
+	s.started = false
+	close(s.stop)
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	slog.Info("⏰ Scheduler stopped")
+}
+
+// startLocked launches the goroutine for a job (assumes lock is held)
+func (s *Scheduler) startLocked(j *job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.runJob(j)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// runJob runs a single job iteration, recovering from panics
+func (s *Scheduler) runJob(j *job) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("❌ Scheduler job panicked", "job", j.name, "panic", r)
+		}
+	}()
+	j.run()
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// ElasticsearchBackend implements Backend using the Elasticsearch REST API
+type ElasticsearchBackend struct {
+	baseURL string
+	index   string
+	client  *http.Client
+}
+
+// NewElasticsearchBackend creates a new Elasticsearch backend and ensures the index exists
+func NewElasticsearchBackend(baseURL, index string) (*ElasticsearchBackend, error) {
+	backend := &ElasticsearchBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		index:   index,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+
+	if err := backend.ensureIndex(); err != nil {
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+// Index stores a message in Elasticsearch
+func (b *ElasticsearchBackend) Index(message *messagePkg.Message) error {
+	method, path := http.MethodPost, fmt.Sprintf("/%s/_doc", b.index)
+	if message.ID != "" {
+		method, path = http.MethodPut, fmt.Sprintf("/%s/_doc/%s", b.index, message.ID)
+	}
+
+	_, err := b.do(method, path, message)
+	return err
+}
+
+// Search runs a fuzzy, filtered full-text query with highlighting
+func (b *ElasticsearchBackend) Search(query Query) ([]*Result, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	match := map[string]interface{}{
+		"query":    query.Text,
+		"operator": "and",
+	}
+	if query.Fuzzy {
+		match["fuzziness"] = "AUTO"
+	}
+
+	filters := make([]interface{}, 0)
+	if query.RoomName != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"room_name": query.RoomName}})
+	}
+	if query.Username != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"username": query.Username}})
+	}
+	if query.StartDate != nil || query.EndDate != nil {
+		dateRange := map[string]interface{}{}
+		if query.StartDate != nil {
+			dateRange["gte"] = query.StartDate.Format(time.RFC3339)
+		}
+		if query.EndDate != nil {
+			dateRange["lte"] = query.EndDate.Format(time.RFC3339)
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"timestamp": dateRange}})
+	}
+
+	body := map[string]interface{}{
+		"size": limit,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   map[string]interface{}{"match": map[string]interface{}{"content": match}},
+				"filter": filters,
+			},
+		},
+		"highlight": map[string]interface{}{
+			"pre_tags":  []string{"<mark>"},
+			"post_tags": []string{"</mark>"},
+			"fields":    map[string]interface{}{"content": map[string]interface{}{}},
+		},
+	}
+
+	data, err := b.do(http.MethodPost, fmt.Sprintf("/%s/_search", b.index), body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				ID        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Source    messagePkg.Message  `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %v", err)
+	}
+
+	results := make([]*Result, 0, len(response.Hits.Hits))
+	for _, hit := range response.Hits.Hits {
+		message := hit.Source
+		if message.ID == "" {
+			message.ID = hit.ID
+		}
+		results = append(results, &Result{
+			Message:    &message,
+			Score:      hit.Score,
+			Highlights: hit.Highlight["content"],
+		})
+	}
+
+	return results, nil
+}
+
+// ensureIndex creates the index with keyword mappings for filter fields
+func (b *ElasticsearchBackend) ensureIndex() error {
+	req, err := http.NewRequest(http.MethodHead, b.baseURL+"/"+b.index, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Elasticsearch: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	mapping := map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"content":   map[string]interface{}{"type": "text"},
+				"username":  map[string]interface{}{"type": "keyword"},
+				"room_name": map[string]interface{}{"type": "keyword"},
+				"type":      map[string]interface{}{"type": "keyword"},
+				"sender":    map[string]interface{}{"type": "keyword"},
+				"timestamp": map[string]interface{}{"type": "date"},
+			},
+		},
+	}
+
+	if _, err := b.do(http.MethodPut, "/"+b.index, mapping); err != nil {
+		return fmt.Errorf("failed to create search index: %v", err)
+	}
+	return nil
+}
+
+// do sends a JSON request to Elasticsearch
+func (b *ElasticsearchBackend) do(method, path string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, b.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("elasticsearch returned %d: %s", resp.StatusCode, string(data))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// Query represents a search request against the external index
+type Query struct {
+	Text      string     `json:"text"`
+	RoomName  string     `json:"room_name,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Fuzzy     bool       `json:"fuzzy"`
+	Limit     int        `json:"limit"`
+}
+
+// Result represents a single search hit
+type Result struct {
+	Message    *messagePkg.Message `json:"message"`
+	Score      float64             `json:"score"`
+	Highlights []string            `json:"highlights,omitempty"`
+}
+
+// Backend is implemented by external search engines
+type Backend interface {
+	Index(message *messagePkg.Message) error
+	Search(query Query) ([]*Result, error)
+}
+
+// Indexer indexes messages asynchronously so the chat path never waits on the search engine
+type Indexer struct {
+	backend Backend
+	queue   chan *messagePkg.Message
+}
+
+// NewIndexer creates a new asynchronous indexer
+func NewIndexer(backend Backend, queueSize int) *Indexer {
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+	return &Indexer{
+		backend: backend,
+		queue:   make(chan *messagePkg.Message, queueSize),
+	}
+}
+
+// Run processes the indexing queue
+func (i *Indexer) Run() {
+	for message := range i.queue {
+		if err := i.backend.Index(message); err != nil {
+			slog.Warn("⚠️ Failed to index message", "error", err)
+		}
+	}
+}
+
+// IndexMessage queues a message for indexing
+func (i *Indexer) IndexMessage(message *messagePkg.Message) {
+	// คัดลอกข้อความ เพื่อไม่ให้ถูกแก้ไขระหว่างรอใน queue
+	copied := *message
+
+	select {
+	case i.queue <- &copied:
+	default:
+		slog.Warn("⚠️ Search index queue is full, dropping message")
+	}
+}
+
+// Search runs a query against the backend
+func (i *Indexer) Search(query Query) ([]*Result, error) {
+	return i.backend.Search(query)
+}
+
+// ParseQuery extracts filters (user:, room:) from free text typed by a user
+func ParseQuery(raw string) Query {
+	query := Query{Fuzzy: true}
+	terms := make([]string, 0)
+
+	for _, field := range strings.Fields(raw) {
+		switch {
+		case strings.HasPrefix(field, "user:") && len(field) > len("user:"):
+			query.Username = strings.TrimPrefix(field, "user:")
+		case strings.HasPrefix(field, "room:") && len(field) > len("room:"):
+			query.RoomName = strings.TrimPrefix(field, "room:")
+		case strings.HasPrefix(field, "exact:"):
+			query.Fuzzy = false
+			if term := strings.TrimPrefix(field, "exact:"); term != "" {
+				terms = append(terms, term)
+			}
+		default:
+			terms = append(terms, field)
+		}
+	}
+
+	query.Text = strings.Join(terms, " ")
+	return query
+}
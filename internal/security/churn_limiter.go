@@ -0,0 +1,125 @@
+package security
+
+import (
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// ChurnStats holds room switch limiting counters
+type ChurnStats struct {
+	TrackedUsers int   `json:"tracked_users"`
+	Allowed      int64 `json:"allowed"`
+	Rejected     int64 `json:"rejected"`
+	LimitedUsers int   `json:"limited_users"` // ผู้ใช้ที่ token หมดอยู่ตอนนี้
+}
+
+// churnBucket is a token bucket for one user
+type churnBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// ChurnLimiter limits how often a user may join or leave rooms using a token bucket per username.
+// แยกจาก RateLimiter ของข้อความ เพราะ /join–/leave ถี่ๆ ไปกระทบ room repository และ broadcast สมาชิก
+type ChurnLimiter struct {
+	buckets map[string]*churnBucket
+	config  *config.ServerConfig
+	mutex   sync.Mutex
+
+	allowed  int64
+	rejected int64
+}
+
+// NewChurnLimiter creates a new room switch limiter
+func NewChurnLimiter(cfg *config.ServerConfig) *ChurnLimiter {
+	return &ChurnLimiter{
+		buckets: make(map[string]*churnBucket),
+		config:  cfg,
+	}
+}
+
+// Allow takes a token for a room switch by the user.
+// ถ้า token หมดจะคืนเวลาที่ต้องรอจนได้ token ถัดไป
+func (l *ChurnLimiter) Allow(username string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.refillLocked(username, now)
+
+	if bucket.tokens < 1 {
+		l.rejected++
+		wait := time.Duration((1 - bucket.tokens) / l.ratePerSecond() * float64(time.Second))
+		return false, wait
+	}
+
+	bucket.tokens--
+	l.allowed++
+	return true, 0
+}
+
+// Limit returns the configured number of room switches and the window they refill over
+func (l *ChurnLimiter) Limit() (int, time.Duration) {
+	return l.config.RoomSwitchLimit, l.config.RoomSwitchWindow
+}
+
+// Stats returns room switch limiting counters
+func (l *ChurnLimiter) Stats() ChurnStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	stats := ChurnStats{
+		TrackedUsers: len(l.buckets),
+		Allowed:      l.allowed,
+		Rejected:     l.rejected,
+	}
+	now := time.Now()
+	for username := range l.buckets {
+		if l.refillLocked(username, now).tokens < 1 {
+			stats.LimitedUsers++
+		}
+	}
+	return stats
+}
+
+// Reap removes buckets that have refilled completely, since they behave the same as a new bucket
+func (l *ChurnLimiter) Reap(aggressive bool) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	capacity := float64(l.config.RoomSwitchLimit)
+	count := 0
+	for username := range l.buckets {
+		if l.refillLocked(username, now).tokens >= capacity {
+			delete(l.buckets, username)
+			count++
+		}
+	}
+	return count
+}
+
+// refillLocked returns the user's bucket with tokens added for the time elapsed (assumes lock is held)
+func (l *ChurnLimiter) refillLocked(username string, now time.Time) *churnBucket {
+	capacity := float64(l.config.RoomSwitchLimit)
+	bucket, exists := l.buckets[username]
+	if !exists {
+		bucket = &churnBucket{tokens: capacity, lastRefill: now}
+		l.buckets[username] = bucket
+		return bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.lastRefill).Seconds() * l.ratePerSecond()
+	if bucket.tokens > capacity {
+		bucket.tokens = capacity
+	}
+	bucket.lastRefill = now
+	return bucket
+}
+
+// ratePerSecond is how many tokens are refilled per second
+func (l *ChurnLimiter) ratePerSecond() float64 {
+	return float64(l.config.RoomSwitchLimit) / l.config.RoomSwitchWindow.Seconds()
+}
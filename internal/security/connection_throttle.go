@@ -0,0 +1,265 @@
+package security
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// BlockedIP represents an IP that is currently blocked from connecting
+type BlockedIP struct {
+	IP           string    `json:"ip"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Level        int       `json:"level"`
+}
+
+// ThrottleStats holds connection throttling counters
+type ThrottleStats struct {
+	TrackedIPs       int   `json:"tracked_ips"`
+	BlockedIPs       int   `json:"blocked_ips"`
+	RejectedAttempts int64 `json:"rejected_attempts"`
+	TotalBlocks      int64 `json:"total_blocks"`
+}
+
+// ipState tracks connection attempts and auth failures for one IP
+type ipState struct {
+	attempts     []time.Time
+	authFailures int
+	level        int // จำนวนครั้งที่ถูก block ติดกัน ใช้คำนวณ backoff แบบ exponential
+	blockedUntil time.Time
+	lastSeen     time.Time
+}
+
+// ConnectionThrottle limits WebSocket upgrade attempts and failed logins per IP.
+// แยกจาก RateLimiter ที่จำกัดจำนวนข้อความต่อผู้ใช้
+type ConnectionThrottle struct {
+	states map[string]*ipState
+	config *config.ServerConfig
+	mutex  sync.Mutex
+
+	rejected  int64
+	blocks    int64
+	lastPrune time.Time
+}
+
+// NewConnectionThrottle creates a new connection throttle
+func NewConnectionThrottle(cfg *config.ServerConfig) *ConnectionThrottle {
+	return &ConnectionThrottle{
+		states: make(map[string]*ipState),
+		config: cfg,
+	}
+}
+
+// Allow records a connection attempt and reports whether it may proceed.
+// ถ้าไม่อนุญาตจะคืนเวลาที่ต้องรอก่อนลองใหม่
+func (t *ConnectionThrottle) Allow(ip string) (bool, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	state := t.stateLocked(ip, now)
+
+	if now.Before(state.blockedUntil) {
+		t.rejected++
+		return false, state.blockedUntil.Sub(now)
+	}
+
+	// เก็บเฉพาะ attempt ที่อยู่ใน window
+	cutoff := now.Add(-t.config.ConnectAttemptWindow)
+	recent := state.attempts[:0]
+	for _, attempt := range state.attempts {
+		if attempt.After(cutoff) {
+			recent = append(recent, attempt)
+		}
+	}
+	state.attempts = append(recent, now)
+
+	if len(state.attempts) > t.config.ConnectAttemptLimit {
+		t.blockLocked(ip, state, now, "too many connection attempts")
+		t.rejected++
+		return false, state.blockedUntil.Sub(now)
+	}
+
+	return true, 0
+}
+
+// IsBlocked reports whether an IP is blocked without counting an attempt (ใช้กับ login ผ่าน HTTP API)
+func (t *ConnectionThrottle) IsBlocked(ip string) (bool, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, exists := t.states[ip]
+	if !exists {
+		return false, 0
+	}
+	now := time.Now()
+	if now.Before(state.blockedUntil) {
+		return true, state.blockedUntil.Sub(now)
+	}
+	return false, 0
+}
+
+// RecordAuthFailure records a failed authentication attempt, reporting whether the IP is now blocked
+func (t *ConnectionThrottle) RecordAuthFailure(ip string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	state := t.stateLocked(ip, now)
+	state.authFailures++
+
+	if state.authFailures >= t.config.AuthFailureLimit {
+		t.blockLocked(ip, state, now, "repeated authentication failures")
+		return true
+	}
+	return false
+}
+
+// RecordAuthSuccess clears the auth failure count for an IP
+func (t *ConnectionThrottle) RecordAuthSuccess(ip string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if state, exists := t.states[ip]; exists {
+		state.authFailures = 0
+	}
+}
+
+// Block blocks an IP with the same backoff as throttled attempts (used by IPLimiter)
+func (t *ConnectionThrottle) Block(ip, reason string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.blockLocked(ip, t.stateLocked(ip, now), now, reason)
+}
+
+// Unblock removes a block (and backoff history) for an IP
+func (t *ConnectionThrottle) Unblock(ip string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, exists := t.states[ip]
+	if !exists || !time.Now().Before(state.blockedUntil) {
+		return false
+	}
+
+	delete(t.states, ip)
+	slog.Info("🔓 IP unblocked", "ip", ip)
+	return true
+}
+
+// Blocked returns all currently blocked IPs, soonest expiry first
+func (t *ConnectionThrottle) Blocked() []BlockedIP {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	blocked := make([]BlockedIP, 0)
+	for ip, state := range t.states {
+		if now.Before(state.blockedUntil) {
+			blocked = append(blocked, BlockedIP{IP: ip, BlockedUntil: state.blockedUntil, Level: state.level})
+		}
+	}
+
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].BlockedUntil.Before(blocked[j].BlockedUntil)
+	})
+	return blocked
+}
+
+// Stats returns connection throttling counters
+func (t *ConnectionThrottle) Stats() ThrottleStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	stats := ThrottleStats{
+		TrackedIPs:       len(t.states),
+		RejectedAttempts: t.rejected,
+		TotalBlocks:      t.blocks,
+	}
+	for _, state := range t.states {
+		if now.Before(state.blockedUntil) {
+			stats.BlockedIPs++
+		}
+	}
+	return stats
+}
+
+// Reap removes idle, unblocked IP states. Under memory pressure it also forgets backoff
+// history of IPs idle for longer than the attempt window
+func (t *ConnectionThrottle) Reap(aggressive bool) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.lastPrune = now
+
+	idleAfter := t.config.ConnectBlockMax
+	if aggressive {
+		idleAfter = t.config.ConnectAttemptWindow
+	}
+
+	count := 0
+	for ip, state := range t.states {
+		if now.After(state.blockedUntil) && now.Sub(state.lastSeen) > idleAfter {
+			delete(t.states, ip)
+			count++
+		}
+	}
+	return count
+}
+
+// stateLocked returns the state for an IP, creating it if needed (assumes lock is held)
+func (t *ConnectionThrottle) stateLocked(ip string, now time.Time) *ipState {
+	state, exists := t.states[ip]
+	if !exists {
+		// ล้าง state เก่าที่ไม่ได้ใช้นานแล้ว เพื่อไม่ให้ map โตไม่สิ้นสุด
+		t.pruneLocked(now)
+		state = &ipState{}
+		t.states[ip] = state
+	}
+
+	// ไม่มีปัญหามานานพอ ให้ลดระดับ backoff กลับเป็นศูนย์
+	if state.level > 0 && now.Sub(state.lastSeen) > t.config.ConnectBlockMax {
+		state.level = 0
+	}
+	state.lastSeen = now
+	return state
+}
+
+// blockLocked blocks an IP with exponential backoff (assumes lock is held)
+func (t *ConnectionThrottle) blockLocked(ip string, state *ipState, now time.Time, reason string) {
+	duration := t.config.ConnectBlockMax
+	if state.level < 16 {
+		if backoff := t.config.ConnectBlockBase << uint(state.level); backoff > 0 && backoff < duration {
+			duration = backoff
+		}
+	}
+
+	state.blockedUntil = now.Add(duration)
+	state.level++
+	state.attempts = nil
+	state.authFailures = 0
+	t.blocks++
+
+	slog.Warn("🚫 IP blocked", "ip", ip, "duration", duration, "reason", reason, "level", state.level)
+}
+
+// pruneLocked removes states that are no longer blocked and have been idle (assumes lock is held)
+func (t *ConnectionThrottle) pruneLocked(now time.Time) {
+	if now.Sub(t.lastPrune) < time.Minute {
+		return
+	}
+	t.lastPrune = now
+
+	for ip, state := range t.states {
+		if now.After(state.blockedUntil) && now.Sub(state.lastSeen) > t.config.ConnectBlockMax {
+			delete(t.states, ip)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package security
+
+import (
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// Frame budgets
+const (
+	BudgetFrame     = "frame"     // ทุก frame ขาเข้า ไม่ว่าจะเป็น type ใด
+	BudgetExpensive = "expensive" // type ที่ต้องอ่าน repository หรือ broadcast (history, search, คำสั่ง ฯลฯ)
+)
+
+// FrameLimiter limits raw inbound frames per connection with token buckets.
+// ต่างจาก RateLimiter ที่นับเฉพาะข้อความแชท: ทุก frame ใช้ token จาก frame budget
+// และ type ที่แพงใช้ token จาก expensive budget อีกหนึ่ง token
+type FrameLimiter struct {
+	config  *config.ServerConfig
+	metrics *config.ServerMetrics
+}
+
+// NewFrameLimiter creates a new inbound frame limiter
+func NewFrameLimiter(cfg *config.ServerConfig, metrics *config.ServerMetrics) *FrameLimiter {
+	return &FrameLimiter{config: cfg, metrics: metrics}
+}
+
+// Track starts the budgets of a new connection; a nil limiter returns a nil budget that allows everything
+func (l *FrameLimiter) Track() *FrameBudget {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	return &FrameBudget{
+		limiter:   l,
+		frames:    newTokenBucket(l.config.FrameRateLimit, l.config.FrameRateWindow, now),
+		expensive: newTokenBucket(l.config.ExpensiveFrameRateLimit, l.config.FrameRateWindow, now),
+	}
+}
+
+// FrameBudget holds the token buckets of one connection.
+// ใช้จาก read loop ของ connection นั้นเท่านั้น จึงไม่ต้องมี lock
+type FrameBudget struct {
+	limiter   *FrameLimiter
+	frames    *tokenBucket
+	expensive *tokenBucket
+	notified  bool // แจ้ง client ไปแล้วในช่วงที่ถูกจำกัดรอบนี้
+}
+
+// AllowFrame takes a token for any inbound frame, returning how long to wait when none is left
+func (b *FrameBudget) AllowFrame() (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	return b.take(BudgetFrame, b.frames)
+}
+
+// AllowExpensive takes a token for a frame of an expensive type
+func (b *FrameBudget) AllowExpensive() (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	return b.take(BudgetExpensive, b.expensive)
+}
+
+// ShouldNotify reports whether the client should be told it is being limited.
+// แจ้งครั้งเดียวต่อช่วงที่ถูกจำกัด เพื่อไม่ให้ client ที่ flood ได้ error กลับไปทุก frame
+func (b *FrameBudget) ShouldNotify() bool {
+	if b == nil || b.notified {
+		return false
+	}
+	b.notified = true
+	return true
+}
+
+// Limit returns the configured size of a budget and the window it refills over
+func (b *FrameBudget) Limit(budget string) (int, time.Duration) {
+	if budget == BudgetExpensive {
+		return b.limiter.config.ExpensiveFrameRateLimit, b.limiter.config.FrameRateWindow
+	}
+	return b.limiter.config.FrameRateLimit, b.limiter.config.FrameRateWindow
+}
+
+func (b *FrameBudget) take(budget string, bucket *tokenBucket) (bool, time.Duration) {
+	if allowed, wait := bucket.take(time.Now()); !allowed {
+		if b.limiter.metrics != nil {
+			b.limiter.metrics.RecordFrameRateLimited(budget)
+		}
+		return false, wait
+	}
+	b.notified = false
+	return true, 0
+}
+
+// tokenBucket refills capacity tokens evenly over window
+type tokenBucket struct {
+	tokens     float64
+	capacity   float64
+	perSecond  float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(capacity int, window time.Duration, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		tokens:     float64(capacity),
+		capacity:   float64(capacity),
+		perSecond:  float64(capacity) / window.Seconds(),
+		lastRefill: now,
+	}
+}
+
+// take removes one token, or returns the time until the next token is available
+func (t *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	t.tokens += now.Sub(t.lastRefill).Seconds() * t.perSecond
+	if t.tokens > t.capacity {
+		t.tokens = t.capacity
+	}
+	t.lastRefill = now
+
+	if t.tokens < 1 {
+		return false, time.Duration((1 - t.tokens) / t.perSecond * float64(time.Second))
+	}
+	t.tokens--
+	return true, 0
+}
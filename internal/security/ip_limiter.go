@@ -0,0 +1,109 @@
+package security
+
+import (
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// IPLimiterStats holds per-IP concurrent connection counters
+type IPLimiterStats struct {
+	TrackedIPs  int   `json:"tracked_ips"`
+	Connections int   `json:"connections"`
+	Rejected    int64 `json:"rejected"`
+	Bans        int64 `json:"bans"`
+}
+
+// IPLimiter caps concurrent WebSocket connections per IP so one client cannot use up MaxConnections.
+// IP ที่ชน limit ซ้ำเกิน ip_violation_limit ครั้งภายใน connect_attempt_window ถูก block ผ่าน ConnectionThrottle
+// (ใช้ backoff, /blocked และ /unblock ชุดเดียวกัน)
+type IPLimiter struct {
+	config     *config.ServerConfig
+	throttle   *ConnectionThrottle // nil = ปฏิเสธอย่างเดียว ไม่ block
+	active     map[string]int      // ip -> connections ที่เปิดอยู่
+	violations map[string][]time.Time
+	rejected   int64
+	bans       int64
+	mutex      sync.Mutex
+}
+
+// NewIPLimiter creates a per-IP connection limiter; throttle may be nil
+func NewIPLimiter(cfg *config.ServerConfig, throttle *ConnectionThrottle) *IPLimiter {
+	return &IPLimiter{
+		config:     cfg,
+		throttle:   throttle,
+		active:     make(map[string]int),
+		violations: make(map[string][]time.Time),
+	}
+}
+
+// Acquire reserves a connection slot for an IP; call Release when the connection closes
+func (l *IPLimiter) Acquire(ip string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.config.MaxConnectionsPerIP <= 0 || l.active[ip] < l.config.MaxConnectionsPerIP {
+		l.active[ip]++
+		return true
+	}
+
+	l.rejected++
+	now := time.Now()
+	cutoff := now.Add(-l.config.ConnectAttemptWindow)
+	recent := l.violations[ip][:0]
+	for _, violation := range l.violations[ip] {
+		if violation.After(cutoff) {
+			recent = append(recent, violation)
+		}
+	}
+	recent = append(recent, now)
+	l.violations[ip] = recent
+
+	if l.throttle != nil && l.config.IPViolationLimit > 0 && len(recent) >= l.config.IPViolationLimit {
+		delete(l.violations, ip)
+		l.bans++
+		l.throttle.Block(ip, "kept opening connections over the per-IP limit")
+	}
+	return false
+}
+
+// Release frees a connection slot taken by Acquire
+func (l *IPLimiter) Release(ip string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active[ip] <= 1 {
+		delete(l.active, ip)
+		return
+	}
+	l.active[ip]--
+}
+
+// Stats returns per-IP limiter counters
+func (l *IPLimiter) Stats() IPLimiterStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	stats := IPLimiterStats{TrackedIPs: len(l.active), Rejected: l.rejected, Bans: l.bans}
+	for _, count := range l.active {
+		stats.Connections += count
+	}
+	return stats
+}
+
+// Reap forgets violations older than the attempt window (connection counts are released by Release)
+func (l *IPLimiter) Reap(aggressive bool) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	cutoff := time.Now().Add(-l.config.ConnectAttemptWindow)
+	count := 0
+	for ip, violations := range l.violations {
+		if len(violations) == 0 || !violations[len(violations)-1].After(cutoff) {
+			delete(l.violations, ip)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,145 @@
+package security
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// lockoutState tracks failed password logins for one account or one IP
+type lockoutState struct {
+	failures    int
+	level       int // จำนวนครั้งที่ถูกล็อกติดกัน ใช้คำนวณ backoff แบบ exponential
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// LoginLockout locks password logins per account and per IP after repeated failures, with exponential backoff.
+// ต่อ account กันการเดารหัสผ่านของคนหนึ่งจากหลาย IP; ต่อ IP กันการไล่เดาหลาย account จากที่เดียว
+// (แยกจาก ConnectionThrottle ซึ่งอาจถูกปิดไว้ เช่นใน load test)
+type LoginLockout struct {
+	accounts map[string]*lockoutState
+	ips      map[string]*lockoutState
+	config   *config.ServerConfig
+	mutex    sync.Mutex
+}
+
+// NewLoginLockout creates a new login lockout
+func NewLoginLockout(cfg *config.ServerConfig) *LoginLockout {
+	return &LoginLockout{
+		accounts: make(map[string]*lockoutState),
+		ips:      make(map[string]*lockoutState),
+		config:   cfg,
+	}
+}
+
+// Locked reports whether logins to an account or from an IP are locked, and for how long
+func (l *LoginLockout) Locked(accountID, ip string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, state := range []*lockoutState{l.accounts[accountID], l.ips[ip]} {
+		if state != nil && now.Before(state.lockedUntil) && state.lockedUntil.Sub(now) > wait {
+			wait = state.lockedUntil.Sub(now)
+		}
+	}
+	return wait > 0, wait
+}
+
+// RecordFailure records a wrong password for an account from an IP and returns how long each one
+// was locked by this failure (0 = not locked now)
+func (l *LoginLockout) RecordFailure(accountID, ip string) (accountLock, ipLock time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	accountLock = l.failLocked(l.accounts, accountID, l.config.LoginFailureLimit, now, "account")
+	ipLock = l.failLocked(l.ips, ip, l.config.LoginIPFailureLimit, now, "ip")
+	return accountLock, ipLock
+}
+
+// UnlockAccount clears the lockout and failures of an account; returns false if it had none
+func (l *LoginLockout) UnlockAccount(accountID string) bool {
+	return l.unlock(l.accounts, accountID)
+}
+
+// UnlockIP clears the lockout and failures of an IP; returns false if it had none
+func (l *LoginLockout) UnlockIP(ip string) bool {
+	return l.unlock(l.ips, ip)
+}
+
+func (l *LoginLockout) unlock(states map[string]*lockoutState, key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := states[key]; !exists {
+		return false
+	}
+	delete(states, key)
+	slog.Info("🔓 Password login unlocked", "key", key)
+	return true
+}
+
+// RecordSuccess clears the failures of an account after a correct password.
+// failure ของ IP ไม่ถูกล้าง ไม่อย่างนั้นผู้โจมตีที่มี account ของตัวเองจะ login สลับเพื่อรีเซ็ตได้
+func (l *LoginLockout) RecordSuccess(accountID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.accounts, accountID)
+}
+
+// Reap removes unlocked states without failures for longer than the maximum lockout
+func (l *LoginLockout) Reap(aggressive bool) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	idleAfter := l.config.LoginLockoutMax
+	if aggressive {
+		idleAfter = l.config.LoginLockoutBase
+	}
+	now := time.Now()
+	count := 0
+	for _, states := range []map[string]*lockoutState{l.accounts, l.ips} {
+		for key, state := range states {
+			if now.After(state.lockedUntil) && now.Sub(state.lastFailure) > idleAfter {
+				delete(states, key)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// failLocked counts one failure for a key and locks it once the limit is reached, returning the lock duration (assumes lock is held)
+func (l *LoginLockout) failLocked(states map[string]*lockoutState, key string, limit int, now time.Time, kind string) time.Duration {
+	state, exists := states[key]
+	if !exists {
+		state = &lockoutState{}
+		states[key] = state
+	}
+	// ไม่ผิดมานานพอ ให้เริ่มนับ backoff ใหม่
+	if now.Sub(state.lastFailure) > l.config.LoginLockoutMax {
+		state.failures, state.level = 0, 0
+	}
+	state.lastFailure = now
+	state.failures++
+	if state.failures < limit {
+		return 0
+	}
+
+	duration := l.config.LoginLockoutMax
+	if state.level < 16 {
+		if backoff := l.config.LoginLockoutBase << uint(state.level); backoff > 0 && backoff < duration {
+			duration = backoff
+		}
+	}
+	state.lockedUntil = now.Add(duration)
+	state.level++
+	state.failures = 0
+	slog.Warn("🔒 Password login locked", kind, key, "duration", duration, "level", state.level)
+	return duration
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originRule is one parsed entry of the origin allowlist
+type originRule struct {
+	scheme    string // ว่าง = ทุก scheme
+	host      string // host[:port] หรือ suffix ของ subdomain เช่น ".example.com"
+	subdomain bool
+}
+
+// OriginPolicy decides which browser origins may open a WebSocket connection.
+//
+// Allowlist entries:
+//
+//	"*"                        ทุก origin
+//	"https://chat.example.com" ตรงทั้ง scheme และ host[:port]
+//	"chat.example.com"         host ตรง ทุก scheme
+//	"*.example.com"            ทุก subdomain ของ example.com (ไม่รวม example.com เอง)
+//	"https://*.example.com"    ทุก subdomain ผ่าน https เท่านั้น
+type OriginPolicy struct {
+	allowAll bool
+	rules    []originRule
+}
+
+// NewOriginPolicy creates an origin policy from an allowlist
+func NewOriginPolicy(allowed []string) *OriginPolicy {
+	policy := &OriginPolicy{}
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			policy.allowAll = true
+			continue
+		}
+
+		var rule originRule
+		if scheme, rest, found := strings.Cut(entry, "://"); found {
+			rule.scheme = scheme
+			entry = rest
+		}
+		entry = strings.TrimSuffix(entry, "/")
+		if strings.HasPrefix(entry, "*.") {
+			rule.subdomain = true
+			entry = entry[1:]
+		}
+		rule.host = entry
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy
+}
+
+// Allowed reports whether an Origin header value is on the allowlist.
+// request ที่ไม่มี Origin (client ที่ไม่ใช่ browser เช่น bot หรือ CLI) ผ่านเสมอ เพราะ CSWSH เกิดจาก browser เท่านั้น
+func (p *OriginPolicy) Allowed(origin string) bool {
+	if origin == "" || p.allowAll {
+		return true
+	}
+
+	parsed, err := url.Parse(strings.ToLower(origin))
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	for _, rule := range p.rules {
+		if rule.scheme != "" && rule.scheme != parsed.Scheme {
+			continue
+		}
+		if rule.subdomain {
+			if strings.HasSuffix(parsed.Hostname(), rule.host) || strings.HasSuffix(parsed.Host, rule.host) {
+				return true
+			}
+			continue
+		}
+		if parsed.Host == rule.host {
+			return true
+		}
+		// entry ที่ไม่ระบุ scheme และ port ใช้ได้กับทุก port
+		if rule.scheme == "" && !strings.Contains(rule.host, ":") && parsed.Hostname() == rule.host {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin adapts the policy to websocket.Upgrader.CheckOrigin
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	return p.Allowed(r.Header.Get("Origin"))
+}
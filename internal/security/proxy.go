@@ -0,0 +1,102 @@
+package security
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProxyPolicy resolves the real client IP of requests that arrive through trusted reverse proxies.
+//
+// X-Forwarded-For / X-Real-IP เชื่อได้เฉพาะเมื่อ request มาจาก proxy ที่ไว้ใจ (trusted_proxies)
+// ไม่อย่างนั้น client ปลอม header เพื่อหลบ ban หรือ rate limit ได้
+type ProxyPolicy struct {
+	trusted []*net.IPNet
+}
+
+// NewProxyPolicy creates a proxy policy from a list of IPs and CIDRs (e.g. "10.0.0.0/8", "127.0.0.1")
+func NewProxyPolicy(trusted []string) *ProxyPolicy {
+	policy := &ProxyPolicy{}
+	for _, entry := range trusted {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := ParseIPOrCIDR(entry)
+		if err != nil {
+			slog.Warn("⚠️ Ignoring invalid trusted proxy", "entry", entry, "error", err)
+			continue
+		}
+		policy.trusted = append(policy.trusted, network)
+	}
+	return policy
+}
+
+// ParseIPOrCIDR parses a CIDR, or a single IP as a /32 (/128 for IPv6) network
+func ParseIPOrCIDR(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, &net.ParseError{Type: "IP address", Text: entry}
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// ClientIP returns the IP of the client that sent a request (without port).
+// ไล่ X-Forwarded-For จากขวาไปซ้าย ข้าม proxy ที่ไว้ใจ ตัวแรกที่ไม่ใช่ proxy คือ client
+// (ค่าทางซ้ายกว่านั้น client เขียนเองได้ จึงไม่ใช้)
+func (p *ProxyPolicy) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if p == nil || !p.isTrusted(remote) {
+		return remote
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// hop ที่อ่านไม่ออก ไล่ต่อไม่ได้ ใช้ตัวล่าสุดที่เชื่อได้
+				break
+			}
+			client = hop
+			if !p.isTrusted(hop) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remote
+}
+
+// isTrusted reports whether an IP belongs to a trusted proxy
+func (p *ProxyPolicy) isTrusted(value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package security
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"realtime-chat/internal/config"
+)
+
+// TLSSetup is the HTTPS configuration of the server built from the tls_* settings
+type TLSSetup struct {
+	Config   *tls.Config
+	CertFile string // ว่างเมื่อใช้ autocert (certificate มาจาก Config.GetCertificate)
+	KeyFile  string
+	autocert *autocert.Manager
+}
+
+// NewTLSSetup builds the TLS configuration; it returns nil when TLS is not configured
+func NewTLSSetup(cfg *config.ServerConfig) (*TLSSetup, error) {
+	if !cfg.TLSEnabled() {
+		return nil, nil
+	}
+
+	minVersion, err := ParseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.TLSAutocert {
+		// โหลดไว้ก่อนเพื่อให้ cert/key ที่ผิดล้มตั้งแต่เริ่ม ไม่ใช่ตอน client แรกต่อเข้ามา
+		if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+		}
+		return &TLSSetup{
+			Config:   &tls.Config{MinVersion: minVersion},
+			CertFile: cfg.TLSCertFile,
+			KeyFile:  cfg.TLSKeyFile,
+		}, nil
+	}
+
+	hosts := make([]string, 0, len(cfg.TLSAutocertHosts))
+	for _, host := range cfg.TLSAutocertHosts {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	// ไม่มี allowlist ใครก็ชี้ domain มาที่เราแล้วให้เราขอ certificate จน rate limit ของ ACME เต็มได้
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("tls_autocert_hosts is required when tls_autocert is enabled")
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Email:      cfg.TLSAutocertEmail,
+	}
+	if cfg.TLSAutocertCacheDir != "" {
+		manager.Cache = autocert.DirCache(cfg.TLSAutocertCacheDir)
+	}
+
+	// TLSConfig ของ autocert รองรับ tls-alpn-01 challenge บน port HTTPS ด้วย
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.MinVersion = minVersion
+	return &TLSSetup{Config: tlsConfig, autocert: manager}, nil
+}
+
+// ListenerConfig returns a tls.Config with the certificate loaded, for listeners other than the HTTP server
+// (cluster และ gRPC API ใช้ certificate เดียวกับ HTTPS)
+func (t *TLSSetup) ListenerConfig() (*tls.Config, error) {
+	config := t.Config.Clone()
+	if t.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// RedirectHandler redirects plain HTTP to HTTPS on httpsPort; with autocert it also answers http-01 challenges
+func (t *TLSSetup) RedirectHandler(httpsPort string) http.Handler {
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+
+	if t.autocert != nil {
+		return t.autocert.HTTPHandler(redirect)
+	}
+	return redirect
+}
+
+// ParseTLSVersion converts a tls_min_version value ("1.2" or "1.3") to its crypto/tls constant
+func ParseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.TrimSpace(version), "TLS") {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls_min_version %q (use 1.2 or 1.3)", version)
+	}
+}
@@ -1,111 +1,312 @@
 package security
 
 import (
+	"errors"
 	"fmt"
 	"html"
+	"log/slog"
 	"regexp"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
+	"golang.org/x/text/unicode/norm"
+
 	"realtime-chat/internal/config"
 )
 
+// Fields and reasons of a ValidationError; the code sent to clients is "<field>_<reason>"
+const (
+	FieldUsername = "username"
+	FieldRoomName = "room_name"
+	FieldMessage  = "message"
+	FieldCommand  = "command"
+
+	ReasonEmpty        = "empty"
+	ReasonTooLong      = "too_long"
+	ReasonInvalidChars = "invalid_characters"
+	ReasonNotAllowed   = "not_allowed" // อยู่ใน reserved_names
+	ReasonSpam         = "spam"
+	ReasonNotCommand   = "not_command"
+)
+
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// ValidationError is a rejected input with a machine-readable code, so clients can show their own translation
+type ValidationError struct {
+	Code    string                 `json:"code"` // เช่น username_too_long
+	Field   string                 `json:"field"`
+	Reason  string                 `json:"reason"`
+	Message string                 `json:"message"`          // ข้อความภาษาอังกฤษสำหรับ client ที่ไม่แปลเอง
+	Params  map[string]interface{} `json:"params,omitempty"` // ค่าที่ใช้ในข้อความ เช่น max
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func newValidationError(field, reason, message string, params map[string]interface{}) *ValidationError {
+	return &ValidationError{
+		Code:    field + "_" + reason,
+		Field:   field,
+		Reason:  reason,
+		Message: message,
+		Params:  params,
+	}
+}
+
+// AsValidationError returns the ValidationError wrapped in err, if there is one
+func AsValidationError(err error) (*ValidationError, bool) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr, true
+	}
+	return nil, false
+}
+
+// ValidationRules are the configurable parts of InputValidator
+type ValidationRules struct {
+	UsernamePattern string   // regex ที่ username ทั้งชื่อต้อง match
+	RoomNamePattern string   // regex ที่ชื่อห้องทั้งชื่อต้อง match
+	ReservedNames   []string // username ที่ห้ามใช้ ไม่สนตัวพิมพ์
+	Normalization   string   // nfc, nfkc หรือ none; nfkc ใช้กับชื่อเท่านั้น ข้อความใช้ nfc
+	StripInvisible  bool     // ลบ control และ format characters (zero-width, bidi override, BOM)
+}
+
+// RulesFromConfig returns the validation rules set in the server config
+func RulesFromConfig(cfg *config.ServerConfig) ValidationRules {
+	return ValidationRules{
+		UsernamePattern: cfg.UsernamePattern,
+		RoomNamePattern: cfg.RoomNamePattern,
+		ReservedNames:   cfg.ReservedNames,
+		Normalization:   cfg.UnicodeNormalization,
+		StripInvisible:  cfg.StripInvisibleChars,
+	}
+}
+
 // InputValidator handles input validation and sanitization
 type InputValidator struct {
-	config *config.ServerConfig
+	config        *config.ServerConfig
+	rules         ValidationRules
+	usernameRegex *regexp.Regexp
+	roomNameRegex *regexp.Regexp
+	reserved      map[string]bool
+	nameForm      *norm.Form // nil = ไม่ normalize
 }
 
-// NewInputValidator creates a new input validator
+// NewInputValidator creates a new input validator using the rules in the config.
+// pattern ที่ compile ไม่ได้จะใช้ค่า default แทน (selftest รายงานเป็น fail)
 func NewInputValidator(config *config.ServerConfig) *InputValidator {
-	return &InputValidator{
-		config: config,
+	rules := RulesFromConfig(config)
+	validator, err := NewInputValidatorWithRules(config, rules)
+	if err != nil {
+		slog.Warn("⚠️ Invalid validation rules, using the default patterns", "error", err)
+		rules.UsernamePattern = defaultPattern(rules.UsernamePattern)
+		rules.RoomNamePattern = defaultPattern(rules.RoomNamePattern)
+		rules.Normalization = "nfc"
+		validator, _ = NewInputValidatorWithRules(config, rules)
+	}
+	return validator
+}
+
+// NewInputValidatorWithRules creates an input validator, returning an error when a pattern or the normalization form is invalid
+func NewInputValidatorWithRules(cfg *config.ServerConfig, rules ValidationRules) (*InputValidator, error) {
+	v := &InputValidator{
+		config:   cfg,
+		rules:    rules,
+		reserved: make(map[string]bool, len(rules.ReservedNames)),
+	}
+
+	var err error
+	if v.usernameRegex, err = regexp.Compile(rules.UsernamePattern); err != nil {
+		return nil, fmt.Errorf("invalid username pattern: %v", err)
+	}
+	if v.roomNameRegex, err = regexp.Compile(rules.RoomNamePattern); err != nil {
+		return nil, fmt.Errorf("invalid room name pattern: %v", err)
+	}
+
+	switch strings.ToLower(rules.Normalization) {
+	case "nfc":
+		form := norm.NFC
+		v.nameForm = &form
+	case "nfkc":
+		form := norm.NFKC
+		v.nameForm = &form
+	case "none":
+	default:
+		return nil, fmt.Errorf("unknown unicode normalization %q (use nfc, nfkc or none)", rules.Normalization)
+	}
+
+	for _, name := range rules.ReservedNames {
+		if name = strings.TrimSpace(name); name != "" {
+			v.reserved[strings.ToLower(v.normalizeName(name))] = true
+		}
+	}
+	return v, nil
+}
+
+// defaultPattern returns pattern when it compiles and the default name pattern otherwise
+func defaultPattern(pattern string) string {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return config.DefaultNamePattern
+	}
+	return pattern
+}
+
+// IsReserved reports whether a username is in the reserved list.
+// ไม่ยกเว้นชื่อใน admin_usernames: ใครก็สมัครหรือเข้าด้วยชื่อนั้นก่อน admin ตัวจริงได้
+func (v *InputValidator) IsReserved(username string) bool {
+	name := strings.ToLower(v.normalizeName(strings.TrimSpace(username)))
+	return v.reserved[name]
+}
+
+// normalizeName strips invisible characters and applies the configured normalization form
+func (v *InputValidator) normalizeName(name string) string {
+	if v.rules.StripInvisible {
+		name = stripInvisible(name, false)
 	}
+	if v.nameForm != nil {
+		name = v.nameForm.String(name)
+	}
+	return name
+}
+
+// normalizeMessage is normalizeName for message text: ZWJ is kept for emoji sequences and NFKC is never applied
+func (v *InputValidator) normalizeMessage(message string) string {
+	if v.rules.StripInvisible {
+		message = stripInvisible(message, true)
+	}
+	if v.nameForm != nil {
+		message = norm.NFC.String(message)
+	}
+	return message
+}
+
+// stripInvisible removes control characters and format characters (zero-width space/joiner, bidi overrides, BOM).
+// whitespace ยังเก็บไว้ให้ขั้นตอนตัดช่องว่างจัดการ
+func stripInvisible(s string, keepJoiner bool) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return r
+		case keepJoiner && r == '\u200D': // zero-width joiner ของ emoji เช่น 👨‍👩‍👧
+			return r
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, s)
 }
 
 // ValidateUsername validates and sanitizes username input
 func (v *InputValidator) ValidateUsername(username string) (string, error) {
-	// Trim whitespace
-	username = strings.TrimSpace(username)
-	
+	// Strip invisible characters, normalize and trim whitespace
+	username = strings.TrimSpace(v.normalizeName(username))
+
 	// Check if empty
 	if username == "" {
-		return "", fmt.Errorf("username cannot be empty")
+		return "", newValidationError(FieldUsername, ReasonEmpty, "username cannot be empty", nil)
 	}
-	
+
 	// Check length
 	if utf8.RuneCountInString(username) > v.config.MaxUsernameLength {
-		return "", fmt.Errorf("username too long (max %d characters)", v.config.MaxUsernameLength)
+		return "", newValidationError(FieldUsername, ReasonTooLong,
+			fmt.Sprintf("username too long (max %d characters)", v.config.MaxUsernameLength),
+			map[string]interface{}{"max": v.config.MaxUsernameLength})
 	}
-	
-	// Check for invalid characters (only allow alphanumeric, underscore, hyphen, and Thai characters)
-	validUsername := regexp.MustCompile(`^[a-zA-Z0-9_\-\p{Thai}]+$`)
-	if !validUsername.MatchString(username) {
-		return "", fmt.Errorf("username contains invalid characters (only letters, numbers, _, - allowed)")
+
+	// Check for invalid characters (default: letters, numbers, _, - and Thai characters)
+	if !v.usernameRegex.MatchString(username) {
+		return "", newValidationError(FieldUsername, ReasonInvalidChars,
+			v.charsetMessage("username", v.rules.UsernamePattern, "only letters, numbers, _, - allowed"),
+			map[string]interface{}{"pattern": v.rules.UsernamePattern})
 	}
-	
+
+	// ชื่อที่สงวนไว้ เช่น System ซึ่งเป็นผู้สร้างห้อง default
+	if v.IsReserved(username) {
+		return "", newValidationError(FieldUsername, ReasonNotAllowed,
+			fmt.Sprintf("username '%s' is reserved", username),
+			map[string]interface{}{"username": username})
+	}
+
 	// Sanitize HTML
 	username = html.EscapeString(username)
-	
+
 	return username, nil
 }
 
 // ValidateMessage validates and sanitizes message content
 func (v *InputValidator) ValidateMessage(message string) (string, error) {
+	message = v.normalizeMessage(message)
+
 	// Check if empty
 	if strings.TrimSpace(message) == "" {
-		return "", fmt.Errorf("message cannot be empty")
+		return "", newValidationError(FieldMessage, ReasonEmpty, "message cannot be empty", nil)
 	}
-	
+
 	// Check length
 	if utf8.RuneCountInString(message) > v.config.MaxMessageLength {
-		return "", fmt.Errorf("message too long (max %d characters)", v.config.MaxMessageLength)
+		return "", newValidationError(FieldMessage, ReasonTooLong,
+			fmt.Sprintf("message too long (max %d characters)", v.config.MaxMessageLength),
+			map[string]interface{}{"max": v.config.MaxMessageLength})
 	}
-	
+
 	// Remove excessive whitespace
 	message = strings.TrimSpace(message)
-	message = regexp.MustCompile(`\s+`).ReplaceAllString(message, " ")
-	
+	message = whitespacePattern.ReplaceAllString(message, " ")
+
 	// Sanitize HTML to prevent XSS
 	message = html.EscapeString(message)
-	
+
 	// Check for spam patterns (repeated characters)
 	if v.isSpamMessage(message) {
-		return "", fmt.Errorf("message appears to be spam")
+		return "", newValidationError(FieldMessage, ReasonSpam, "message appears to be spam", nil)
 	}
-	
+
 	return message, nil
 }
 
 // ValidateRoomName validates and sanitizes room name
 func (v *InputValidator) ValidateRoomName(roomName string) (string, error) {
-	// Trim whitespace
-	roomName = strings.TrimSpace(roomName)
-	
+	// Strip invisible characters, normalize and trim whitespace
+	roomName = strings.TrimSpace(v.normalizeName(roomName))
+
 	// Check if empty
 	if roomName == "" {
-		return "", fmt.Errorf("room name cannot be empty")
+		return "", newValidationError(FieldRoomName, ReasonEmpty, "room name cannot be empty", nil)
 	}
-	
+
 	// Check length
 	if utf8.RuneCountInString(roomName) > v.config.MaxRoomNameLength {
-		return "", fmt.Errorf("room name too long (max %d characters)", v.config.MaxRoomNameLength)
+		return "", newValidationError(FieldRoomName, ReasonTooLong,
+			fmt.Sprintf("room name too long (max %d characters)", v.config.MaxRoomNameLength),
+			map[string]interface{}{"max": v.config.MaxRoomNameLength})
 	}
-	
-	// Check for invalid characters (no spaces, only alphanumeric, underscore, hyphen)
-	validRoomName := regexp.MustCompile(`^[a-zA-Z0-9_\-\p{Thai}]+$`)
-	if !validRoomName.MatchString(roomName) {
-		return "", fmt.Errorf("room name contains invalid characters (no spaces, only letters, numbers, _, - allowed)")
+
+	// Check for invalid characters (default: no spaces, only letters, numbers, _, - and Thai characters)
+	if !v.roomNameRegex.MatchString(roomName) {
+		return "", newValidationError(FieldRoomName, ReasonInvalidChars,
+			v.charsetMessage("room name", v.rules.RoomNamePattern, "no spaces, only letters, numbers, _, - allowed"),
+			map[string]interface{}{"pattern": v.rules.RoomNamePattern})
 	}
-	
+
 	// Convert to lowercase for consistency
 	roomName = strings.ToLower(roomName)
-	
+
 	// Sanitize HTML
 	roomName = html.EscapeString(roomName)
-	
+
 	return roomName, nil
 }
 
+// charsetMessage describes an invalid_characters error; the hint only matches the default pattern
+func (v *InputValidator) charsetMessage(field, pattern, defaultHint string) string {
+	if pattern == config.DefaultNamePattern {
+		return fmt.Sprintf("%s contains invalid characters (%s)", field, defaultHint)
+	}
+	return fmt.Sprintf("%s contains characters that are not allowed on this server", field)
+}
+
 // isSpamMessage checks if a message appears to be spam
 func (v *InputValidator) isSpamMessage(message string) bool {
 	// Check for excessive repeated characters (simple approach)
@@ -118,7 +319,7 @@ func (v *InputValidator) isSpamMessage(message string) bool {
 			}
 		}
 	}
-	
+
 	// Check for excessive repeated words
 	words := strings.Fields(message)
 	if len(words) > 5 {
@@ -130,7 +331,7 @@ func (v *InputValidator) isSpamMessage(message string) bool {
 			}
 		}
 	}
-	
+
 	return false
 }
 
@@ -143,19 +344,20 @@ func (v *InputValidator) SanitizeHTML(input string) string {
 func (v *InputValidator) ValidateCommand(command string) (string, error) {
 	// Trim whitespace
 	command = strings.TrimSpace(command)
-	
+
 	// Check if it starts with /
 	if !strings.HasPrefix(command, "/") {
-		return "", fmt.Errorf("command must start with /")
+		return "", newValidationError(FieldCommand, ReasonNotCommand, "command must start with /", nil)
 	}
-	
+
 	// Check length (commands should be shorter)
 	if utf8.RuneCountInString(command) > 100 {
-		return "", fmt.Errorf("command too long")
+		return "", newValidationError(FieldCommand, ReasonTooLong, "command too long",
+			map[string]interface{}{"max": 100})
 	}
-	
+
 	// Sanitize HTML
 	command = html.EscapeString(command)
-	
+
 	return command, nil
-}
\ No newline at end of file
+}
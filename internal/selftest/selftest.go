@@ -0,0 +1,512 @@
+package selftest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/database"
+	"realtime-chat/internal/logging"
+	"realtime-chat/internal/security"
+	"realtime-chat/internal/storage"
+)
+
+// Check results
+const (
+	StatusPass = "PASS"
+	StatusWarn = "WARN"
+	StatusFail = "FAIL"
+	StatusSkip = "SKIP"
+)
+
+// staticAssets are the files the test client needs under the static directory
+var staticAssets = []string{"index.html", "chat.html", "chat.js", "styles.css"}
+
+// Check is the result of one self-test check
+type Check struct {
+	Group  string `json:"group"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the result of a self-test run
+type Report struct {
+	Checks   []Check       `json:"checks"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Options selects what the self-test checks
+type Options struct {
+	Config       *config.ServerConfig
+	MongoEnabled bool              // ตามที่ตั้งค่าไว้ (cfg.EnableMongoDB ถูกปิดเมื่อ fallback เป็น in-memory)
+	MongoDB      *database.MongoDB // nil = เชื่อมต่อไม่ได้
+	MongoErr     error             // error ตอนเชื่อมต่อ MongoDB ถ้ามี
+	StaticDir    string
+	CheckPort    bool // ตรวจว่า port ว่าง (ต้องรันก่อนเริ่ม server)
+}
+
+// Run runs every check and returns the report
+func Run(opts Options) *Report {
+	report := &Report{Started: time.Now()}
+	report.add(validateConfig(opts.Config)...)
+	report.add(checkMongoDB(opts)...)
+	report.add(checkStatic(opts.StaticDir)...)
+	if opts.CheckPort {
+		report.add(checkPort(opts.Config.Port))
+	}
+	report.Duration = time.Since(report.Started)
+	return report
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts returns the number of checks per status
+func (r *Report) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, check := range r.Checks {
+		counts[check.Status]++
+	}
+	return counts
+}
+
+// Print writes the report as an aligned table followed by a summary line
+func (r *Report) Print(w io.Writer) {
+	nameWidth := 0
+	for _, check := range r.Checks {
+		if width := len(check.Group) + len(check.Name) + 1; width > nameWidth {
+			nameWidth = width
+		}
+	}
+
+	fmt.Fprintln(w, "🩺 Startup self-test")
+	for _, check := range r.Checks {
+		name := check.Group + "." + check.Name
+		fmt.Fprintf(w, "  [%s] %-*s %s\n", check.Status, nameWidth, name, check.Detail)
+	}
+
+	counts := r.Counts()
+	result := StatusPass
+	if r.Failed() {
+		result = StatusFail
+	}
+	fmt.Fprintf(w, "🩺 Self-test %s: %d passed, %d warnings, %d failed, %d skipped (%v)\n", result,
+		counts[StatusPass], counts[StatusWarn], counts[StatusFail], counts[StatusSkip], r.Duration.Round(time.Millisecond))
+}
+
+func (r *Report) add(checks ...Check) {
+	r.Checks = append(r.Checks, checks...)
+}
+
+// validateConfig checks that timeouts, sizes and buffers are in usable ranges
+func validateConfig(cfg *config.ServerConfig) []Check {
+	var checks []Check
+	positive := func(name string, ok bool, value interface{}) {
+		check := Check{Group: "config", Name: name, Status: StatusPass, Detail: fmt.Sprint(value)}
+		if !ok {
+			check.Status = StatusFail
+			check.Detail = fmt.Sprintf("%v: must be greater than zero", value)
+		}
+		checks = append(checks, check)
+	}
+
+	positive("heartbeat_interval", cfg.HeartbeatInterval > 0, cfg.HeartbeatInterval)
+	positive("read_timeout", cfg.ReadTimeout > 0, cfg.ReadTimeout)
+	positive("write_timeout", cfg.WriteTimeout > 0, cfg.WriteTimeout)
+	positive("health_check_interval", cfg.HealthCheckInterval > 0, cfg.HealthCheckInterval)
+	positive("max_connections", cfg.MaxConnections > 0, cfg.MaxConnections)
+	positive("max_message_length", cfg.MaxMessageLength > 0, cfg.MaxMessageLength)
+	positive("message_buffer_size", cfg.MessageBufferSize > 0, cfg.MessageBufferSize)
+
+	// pong ต้องมีเวลารอมากกว่ารอบ ping ไม่เช่นนั้น connection ปกติจะถูกตัดว่า unhealthy
+	pong := Check{Group: "config", Name: "pong_timeout", Status: StatusPass, Detail: fmt.Sprintf("%v (heartbeat %v)", cfg.PongTimeout, cfg.HeartbeatInterval)}
+	if cfg.PongTimeout <= cfg.HeartbeatInterval {
+		pong.Status = StatusFail
+		pong.Detail = fmt.Sprintf("%v must be longer than heartbeat_interval %v", cfg.PongTimeout, cfg.HeartbeatInterval)
+	}
+	checks = append(checks, pong)
+
+	// idle reaper ทำงานใน health check loop
+	idle := Check{Group: "config", Name: "connection_timeout", Status: StatusPass, Detail: fmt.Sprintf("%v (checked every %v)", cfg.ConnectionTimeout, cfg.HealthCheckInterval)}
+	switch {
+	case cfg.ConnectionTimeout == 0:
+		idle.Detail = "disabled, idle connections stay open"
+	case cfg.ConnectionTimeout < 0:
+		idle.Status = StatusFail
+		idle.Detail = fmt.Sprintf("%v must not be negative", cfg.ConnectionTimeout)
+	case !cfg.EnableHealthCheck:
+		idle.Status = StatusWarn
+		idle.Detail = "enable_health_check is off, idle connections are never closed"
+	}
+	checks = append(checks, idle)
+
+	// archive ห้องที่ว่างนานใน background job room-archive
+	archive := Check{Group: "config", Name: "room_archive", Status: StatusPass,
+		Detail: fmt.Sprintf("empty rooms archived after %v (checked every %v), reactivate on /create: %v", cfg.RoomArchiveAfter, cfg.RoomArchiveInterval, cfg.ReactivateArchivedRooms)}
+	switch {
+	case cfg.RoomArchiveAfter == 0:
+		archive.Detail = "disabled, empty rooms are kept forever"
+	case cfg.RoomArchiveAfter < 0 || cfg.RoomArchiveInterval <= 0:
+		archive.Status = StatusFail
+		archive.Detail = fmt.Sprintf("room_archive_after %v must not be negative and room_archive_interval %v must be positive", cfg.RoomArchiveAfter, cfg.RoomArchiveInterval)
+	case cfg.EnableRoomHibernation && cfg.RoomArchiveAfter <= cfg.RoomIdleTimeout:
+		archive.Status = StatusWarn
+		archive.Detail = fmt.Sprintf("room_archive_after %v is not longer than room_idle_timeout %v, rooms are archived before they hibernate", cfg.RoomArchiveAfter, cfg.RoomIdleTimeout)
+	}
+	checks = append(checks, archive)
+
+	if cfg.EnableRateLimit {
+		positive("rate_limit", cfg.RateLimitMessages > 0 && cfg.RateLimitWindow > 0 && cfg.RateLimitBurst > 0 && cfg.RateLimitIdleTTL > 0,
+			fmt.Sprintf("%d messages / %v, burst %d", cfg.RateLimitMessages, cfg.RateLimitWindow, cfg.RateLimitBurst))
+	}
+
+	if cfg.EnableRoomSampling {
+		positive("room_sampling", cfg.SamplingThreshold > 0 && cfg.DigestInterval > 0 && cfg.MaxDigestMessages > 0,
+			fmt.Sprintf("%d members, digest every %v", cfg.SamplingThreshold, cfg.DigestInterval))
+	}
+
+	if cfg.EnableFrameRateLimit {
+		positive("frame_rate_limit", cfg.FrameRateLimit > 0 && cfg.ExpensiveFrameRateLimit > 0 && cfg.FrameRateWindow > 0,
+			fmt.Sprintf("%d frames, %d expensive / %v", cfg.FrameRateLimit, cfg.ExpensiveFrameRateLimit, cfg.FrameRateWindow))
+	}
+
+	if cfg.EnableSessionResume {
+		positive("session_resume", cfg.SessionResumeGrace > 0 && cfg.MaxResumeMessages > 0,
+			fmt.Sprintf("grace %v, %d buffered messages", cfg.SessionResumeGrace, cfg.MaxResumeMessages))
+	}
+
+	if cfg.EnableNotifications {
+		positive("notifications", cfg.NotificationTTL > 0 && cfg.MaxNotifications > 0,
+			fmt.Sprintf("%d per page, kept %v", cfg.MaxNotifications, cfg.NotificationTTL))
+	}
+
+	if cfg.EnableMongoDB {
+		positive("message_retention", cfg.MessageRetention >= 0 && cfg.RetentionCheckInterval > 0 && cfg.RetentionBatchSize > 0,
+			fmt.Sprintf("default %v, every %v in batches of %d", cfg.MessageRetention, cfg.RetentionCheckInterval, cfg.RetentionBatchSize))
+	}
+
+	if cfg.EnableFileTransfer {
+		chunk := Check{Group: "config", Name: "file_chunk_size", Status: StatusPass, Detail: fmt.Sprintf("%d bytes", cfg.FileChunkSize)}
+		// chunk ถูก base64 (โต ~4/3) ก่อนส่ง ต้องยังไม่เกิน frame limit
+		if cfg.FileChunkSize <= 0 || (cfg.MaxOutboundFrameSize > 0 && cfg.FileChunkSize*4/3 >= cfg.MaxOutboundFrameSize) {
+			chunk.Status = StatusFail
+			chunk.Detail = fmt.Sprintf("%d bytes must be positive and fit max_outbound_frame_size %d after base64", cfg.FileChunkSize, cfg.MaxOutboundFrameSize)
+		}
+		checks = append(checks, chunk)
+	}
+
+	logs := Check{Group: "config", Name: "logging", Status: StatusPass, Detail: fmt.Sprintf("level %s, format %s", cfg.LogLevel, cfg.LogFormat)}
+	if _, err := logging.New(io.Discard, cfg.LogLevel, cfg.LogFormat); err != nil {
+		logs.Status = StatusFail
+		logs.Detail = err.Error()
+	}
+	checks = append(checks, logs)
+
+	backend, err := storage.Backend(cfg)
+	store := Check{Group: "config", Name: "storage_backend", Status: StatusPass, Detail: backend}
+	if err != nil {
+		store.Status = StatusFail
+		store.Detail = err.Error()
+	}
+	checks = append(checks, store)
+	if err == nil && backend != storage.BackendMongoDB {
+		checks = append(checks, Check{Group: "config", Name: "mongo_only_features", Status: StatusWarn,
+			Detail: "disabled on " + backend + ": " + strings.Join(storage.MongoOnlyFeatures(cfg), ", ")})
+	}
+
+	subprotocol := Check{Group: "config", Name: "default_subprotocol", Status: StatusPass, Detail: cfg.DefaultSubprotocol}
+	switch {
+	case cfg.DefaultSubprotocol == "chat.v1.msgpack" && !cfg.EnableMsgpack:
+		subprotocol.Status = StatusFail
+		subprotocol.Detail = "chat.v1.msgpack requires enable_msgpack"
+	case cfg.DefaultSubprotocol != "chat.v1.json" && cfg.DefaultSubprotocol != "chat.v1.text" && cfg.DefaultSubprotocol != "chat.v1.msgpack":
+		subprotocol.Status = StatusFail
+		subprotocol.Detail = fmt.Sprintf("%q must be chat.v1.json, chat.v1.text or chat.v1.msgpack", cfg.DefaultSubprotocol)
+	}
+	checks = append(checks, subprotocol)
+
+	if cfg.EnableUploads {
+		uploads := Check{Group: "config", Name: "upload_storage", Status: StatusPass, Detail: cfg.UploadStorage}
+		switch {
+		case cfg.UploadStorage == "s3" && (cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == ""):
+			uploads.Status = StatusFail
+			uploads.Detail = "s3 requires CHAT_S3_ENDPOINT, CHAT_S3_BUCKET, CHAT_S3_ACCESS_KEY and CHAT_S3_SECRET_KEY"
+		case cfg.UploadStorage != "s3" && cfg.UploadStorage != "local":
+			uploads.Status = StatusFail
+			uploads.Detail = fmt.Sprintf("%q must be local or s3", cfg.UploadStorage)
+		case len(cfg.APIKeys) == 0:
+			uploads.Status = StatusWarn
+			uploads.Detail = cfg.UploadStorage + ": POST /api/upload needs CHAT_API_KEYS"
+		}
+		checks = append(checks, uploads)
+	}
+
+	backpressure := Check{Group: "config", Name: "slow_consumer_policy", Status: StatusPass,
+		Detail: fmt.Sprintf("%s, queue %d, close after %d drops", cfg.SlowConsumerPolicy, cfg.SendQueueSize, cfg.SlowConsumerMaxDrops)}
+	switch {
+	case cfg.SlowConsumerPolicy != "drop_oldest" && cfg.SlowConsumerPolicy != "drop_newest" && cfg.SlowConsumerPolicy != "disconnect":
+		backpressure.Status = StatusFail
+		backpressure.Detail = fmt.Sprintf("%q must be drop_oldest, drop_newest or disconnect", cfg.SlowConsumerPolicy)
+	case cfg.SendQueueSize <= 0 || cfg.SlowConsumerMaxDrops < 0:
+		backpressure.Status = StatusFail
+		backpressure.Detail = fmt.Sprintf("send_queue_size %d must be positive and slow_consumer_max_drops %d not negative", cfg.SendQueueSize, cfg.SlowConsumerMaxDrops)
+	case cfg.SlowConsumerPolicy != "disconnect" && cfg.SlowConsumerMaxDrops == 0:
+		backpressure.Status = StatusWarn
+		backpressure.Detail = cfg.SlowConsumerPolicy + ": stalled clients are never closed and keep a full queue"
+	}
+	checks = append(checks, backpressure)
+
+	fanout := Check{Group: "config", Name: "fanout", Status: StatusPass,
+		Detail: fmt.Sprintf("%d workers for broadcasts to %d+ connections", cfg.FanoutWorkers, cfg.FanoutMinRecipients)}
+	switch {
+	case cfg.FanoutWorkers < 0 || cfg.FanoutMinRecipients < 0:
+		fanout.Status = StatusFail
+		fanout.Detail = fmt.Sprintf("fanout_workers %d and fanout_min_recipients %d must not be negative", cfg.FanoutWorkers, cfg.FanoutMinRecipients)
+	case cfg.FanoutWorkers <= 1:
+		fanout.Detail = "disabled, broadcasts are sent from the manager loop"
+	case cfg.FanoutWorkers > 4*runtime.NumCPU():
+		fanout.Status = StatusWarn
+		fanout.Detail = fmt.Sprintf("%d workers on %d CPUs: extra workers only add scheduling overhead", cfg.FanoutWorkers, runtime.NumCPU())
+	}
+	checks = append(checks, fanout)
+
+	if cfg.EnableBatching {
+		batching := Check{Group: "config", Name: "batching", Status: StatusPass,
+			Detail: fmt.Sprintf("up to %d frames per %s", cfg.BatchMaxSize, cfg.BatchWindow)}
+		switch {
+		case cfg.BatchWindow <= 0 || cfg.BatchMaxSize <= 1:
+			batching.Status = StatusWarn
+			batching.Detail = fmt.Sprintf("batch_window %s and batch_max_size %d leave nothing to batch; batching is off", cfg.BatchWindow, cfg.BatchMaxSize)
+		case cfg.BatchWindow > 100*time.Millisecond:
+			batching.Status = StatusWarn
+			batching.Detail = fmt.Sprintf("batch_window %s delays every batched message by up to that long", cfg.BatchWindow)
+		}
+		checks = append(checks, batching)
+	}
+
+	if cfg.EnableCompression {
+		compression := Check{Group: "config", Name: "compression", Status: StatusPass,
+			Detail: fmt.Sprintf("permessage-deflate level %d for frames of %d+ bytes", cfg.CompressionLevel, cfg.CompressionThreshold)}
+		switch {
+		case cfg.CompressionLevel < -2 || cfg.CompressionLevel > 9:
+			compression.Status = StatusFail
+			compression.Detail = fmt.Sprintf("compression_level %d must be between -2 and 9", cfg.CompressionLevel)
+		case cfg.CompressionThreshold < 128:
+			compression.Status = StatusWarn
+			compression.Detail = fmt.Sprintf("compression_threshold %d compresses small frames that barely shrink", cfg.CompressionThreshold)
+		}
+		checks = append(checks, compression)
+	}
+
+	accounts := Check{Group: "config", Name: "accounts", Status: StatusPass,
+		Detail: fmt.Sprintf("require %t, min password %d, sessions last %s", cfg.RequireAccounts, cfg.MinPasswordLength, cfg.AccountSessionTTL)}
+	switch {
+	case cfg.MinPasswordLength <= 0 || cfg.AccountSessionTTL <= 0:
+		accounts.Status = StatusFail
+		accounts.Detail = fmt.Sprintf("min_password_length %d and account_session_ttl %s must be positive", cfg.MinPasswordLength, cfg.AccountSessionTTL)
+	case cfg.MinPasswordLength > 72:
+		accounts.Status = StatusFail
+		accounts.Detail = fmt.Sprintf("min_password_length %d exceeds the 72 byte bcrypt limit", cfg.MinPasswordLength)
+	case cfg.RequireAccounts && backend == storage.BackendMemory:
+		accounts.Status = StatusWarn
+		accounts.Detail = "accounts are required but memory storage loses them on restart"
+	}
+	checks = append(checks, accounts)
+
+	validation := Check{Group: "config", Name: "input_validation", Status: StatusPass,
+		Detail: fmt.Sprintf("%d reserved names, normalization %s, strip invisible %t",
+			len(cfg.ReservedNames), cfg.UnicodeNormalization, cfg.StripInvisibleChars)}
+	if _, err := security.NewInputValidatorWithRules(cfg, security.RulesFromConfig(cfg)); err != nil {
+		validation.Status = StatusFail
+		validation.Detail = err.Error()
+	}
+	checks = append(checks, validation)
+
+	origins := Check{Group: "config", Name: "allowed_origins", Status: StatusPass, Detail: strings.Join(cfg.AllowedOrigins, ",")}
+	for _, origin := range cfg.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			origins.Status = StatusWarn
+			origins.Detail = "* allows every origin; set CHAT_ALLOWED_ORIGINS in production"
+		}
+	}
+	checks = append(checks, origins)
+
+	if len(cfg.TrustedProxies) > 0 {
+		proxies := Check{Group: "config", Name: "trusted_proxies", Status: StatusPass, Detail: strings.Join(cfg.TrustedProxies, ",")}
+		for _, entry := range cfg.TrustedProxies {
+			network, err := security.ParseIPOrCIDR(strings.TrimSpace(entry))
+			if err != nil {
+				proxies.Status = StatusFail
+				proxies.Detail = fmt.Sprintf("invalid trusted proxy %q (use an IP or CIDR)", entry)
+				break
+			}
+			if ones, _ := network.Mask.Size(); ones == 0 {
+				// เชื่อทุก address = ใครก็ปลอม X-Forwarded-For หลบ ban ได้
+				proxies.Status = StatusWarn
+				proxies.Detail = fmt.Sprintf("%s trusts every address, clients can spoof X-Forwarded-For", entry)
+			}
+		}
+		checks = append(checks, proxies)
+	}
+
+	if cfg.EnableCluster {
+		cluster := Check{Group: "config", Name: "cluster", Status: StatusPass,
+			Detail: fmt.Sprintf("node %s on %s, %d seeds, gossip every %v, node timeout %v",
+				cfg.NodeID, cfg.ClusterAddr, len(cfg.ClusterPeers), cfg.ClusterGossipInterval, cfg.ClusterNodeTimeout)}
+		_, _, addrErr := net.SplitHostPort(cfg.ClusterAddr)
+		switch {
+		case cfg.ClusterSecret == "":
+			// cluster.New ไม่ยอมเริ่มเช่นกัน: ใครก็ตามที่ต่อถึง cluster_addr ส่งข้อความปลอมเข้าห้องได้
+			cluster.Status = StatusFail
+			cluster.Detail = "cluster_secret is required, anything that reaches cluster_addr could inject messages; set CHAT_CLUSTER_SECRET"
+		case cfg.NodeID == "" || addrErr != nil:
+			cluster.Status = StatusFail
+			cluster.Detail = fmt.Sprintf("node_id must be set and cluster_addr %q must be host:port", cfg.ClusterAddr)
+		case cfg.ClusterNodeTimeout < 3*cfg.ClusterGossipInterval || cfg.ClusterVirtualNodes < 1:
+			cluster.Status = StatusFail
+			cluster.Detail = fmt.Sprintf("cluster_node_timeout %v must be at least 3 gossip intervals (%v) and cluster_virtual_nodes positive",
+				cfg.ClusterNodeTimeout, cfg.ClusterGossipInterval)
+		case backend == storage.BackendMemory || backend == storage.BackendSQLite:
+			// ข้อความยังถึงกันข้าม node แต่ห้องและผู้ใช้ไม่เห็นกัน
+			cluster.Status = StatusWarn
+			cluster.Detail = backend + " storage is per node, rooms and users are not shared; use mongodb or postgres"
+		case !cfg.TLSEnabled():
+			cluster.Status = StatusWarn
+			cluster.Detail = "cluster traffic (including cluster_secret) is plaintext; configure tls_cert_file/tls_key_file to encrypt it"
+		}
+		checks = append(checks, cluster)
+	}
+
+	if cfg.EnableGRPC {
+		grpcCheck := Check{Group: "config", Name: "grpc", Status: StatusPass,
+			Detail: fmt.Sprintf("gRPC API on %s with %d API keys", cfg.GRPCAddr, len(cfg.APIKeys))}
+		_, grpcPort, addrErr := net.SplitHostPort(cfg.GRPCAddr)
+		_, clusterPort, _ := net.SplitHostPort(cfg.ClusterAddr)
+		switch {
+		case addrErr != nil:
+			grpcCheck.Status = StatusFail
+			grpcCheck.Detail = fmt.Sprintf("grpc_addr %q must be host:port", cfg.GRPCAddr)
+		case cfg.EnableCluster && grpcPort == clusterPort:
+			grpcCheck.Status = StatusFail
+			grpcCheck.Detail = fmt.Sprintf("grpc_addr %s uses the same port as cluster_addr %s", cfg.GRPCAddr, cfg.ClusterAddr)
+		case len(cfg.APIKeys) == 0:
+			// ทุก call ถูกปฏิเสธจนกว่าจะตั้ง key
+			grpcCheck.Status = StatusWarn
+			grpcCheck.Detail = "api_keys is empty, every gRPC call is rejected; set CHAT_API_KEYS"
+		case !cfg.TLSEnabled():
+			grpcCheck.Status = StatusWarn
+			grpcCheck.Detail = "gRPC API is plaintext, API keys travel unencrypted; configure tls_cert_file/tls_key_file"
+		}
+		checks = append(checks, grpcCheck)
+	}
+
+	if cfg.TLSEnabled() {
+		tlsCheck := Check{Group: "config", Name: "tls", Status: StatusPass,
+			Detail: fmt.Sprintf("serving https/wss with certificate %s, min TLS %s", cfg.TLSCertFile, cfg.TLSMinVersion)}
+		if cfg.TLSAutocert {
+			tlsCheck.Detail = fmt.Sprintf("serving https/wss with autocert for %s, min TLS %s", strings.Join(cfg.TLSAutocertHosts, ","), cfg.TLSMinVersion)
+		}
+		_, redirectPort, _ := net.SplitHostPort(cfg.TLSRedirectAddr)
+		if _, err := security.NewTLSSetup(cfg); err != nil {
+			tlsCheck.Status = StatusFail
+			tlsCheck.Detail = err.Error()
+		} else if cfg.TLSAutocert && strings.TrimPrefix(cfg.Port, ":") != "443" && redirectPort != "80" {
+			// ACME ตรวจผ่าน port 443 (tls-alpn-01) หรือ 80 (http-01) เท่านั้น
+			tlsCheck.Status = StatusWarn
+			tlsCheck.Detail = "autocert needs port 443 or tls_redirect_addr on port 80 to answer ACME challenges"
+		}
+		checks = append(checks, tlsCheck)
+	} else if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		checks = append(checks, Check{Group: "config", Name: "tls", Status: StatusFail,
+			Detail: "tls_cert_file and tls_key_file must be set together; serving plain HTTP"})
+	}
+
+	if cfg.LoadTestMode {
+		checks = append(checks, Check{Group: "config", Name: "load_test_mode", Status: StatusWarn,
+			Detail: "probe_id echo on and connection throttling off for cmd/loadgen; disable outside load tests"})
+	}
+
+	return checks
+}
+
+// checkMongoDB checks the connection and that every index exists
+func checkMongoDB(opts Options) []Check {
+	if !opts.MongoEnabled {
+		return []Check{{Group: "mongodb", Name: "connection", Status: StatusSkip, Detail: "MongoDB disabled, using in-memory repositories"}}
+	}
+	if opts.MongoDB == nil {
+		detail := "not connected"
+		if opts.MongoErr != nil {
+			detail = opts.MongoErr.Error()
+		}
+		return []Check{{Group: "mongodb", Name: "connection", Status: StatusFail, Detail: detail}}
+	}
+
+	checks := []Check{{Group: "mongodb", Name: "connection", Status: StatusPass, Detail: opts.Config.MongoDatabase}}
+	if err := opts.MongoDB.HealthCheck(); err != nil {
+		checks[0].Status = StatusFail
+		checks[0].Detail = err.Error()
+		return checks
+	}
+
+	missing, err := opts.MongoDB.MissingIndexes()
+	switch {
+	case err != nil:
+		checks = append(checks, Check{Group: "mongodb", Name: "indexes", Status: StatusFail, Detail: err.Error()})
+	case len(missing) > 0:
+		checks = append(checks, Check{Group: "mongodb", Name: "indexes", Status: StatusFail, Detail: "missing " + strings.Join(missing, ", ")})
+	default:
+		checks = append(checks, Check{Group: "mongodb", Name: "indexes", Status: StatusPass, Detail: "all present"})
+	}
+	return checks
+}
+
+// checkStatic checks that the test client's files are present
+func checkStatic(dir string) []Check {
+	var missing []string
+	for _, name := range staticAssets {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.IsDir() {
+			missing = append(missing, name)
+		}
+	}
+
+	check := Check{Group: "static", Name: "assets", Status: StatusPass, Detail: fmt.Sprintf("%d files in %s", len(staticAssets), dir)}
+	if len(missing) > 0 {
+		// server ยังทำงานได้ แค่หน้า test client จะใช้ไม่ได้
+		check.Status = StatusWarn
+		check.Detail = fmt.Sprintf("missing %s in %s", strings.Join(missing, ", "), dir)
+	}
+	return []Check{check}
+}
+
+// checkPort checks that the listen address is free
+func checkPort(port string) Check {
+	if !strings.HasPrefix(port, ":") && !strings.Contains(port, ":") {
+		port = ":" + port
+	}
+
+	check := Check{Group: "network", Name: "port", Status: StatusPass, Detail: port + " available"}
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("%s unavailable: %v", port, err)
+		return check
+	}
+	listener.Close()
+	return check
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/account"
+
+	"github.com/lib/pq"
+)
+
+// AccountRepository implements account.Repository using PostgreSQL
+type AccountRepository struct {
+	db *sql.DB
+}
+
+// CreateAccount inserts a new account
+func (r *AccountRepository) CreateAccount(acct *account.Account) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO accounts (id, username, password_hash, created_at) VALUES ($1, $2, $3, $4)`,
+		acct.ID, acct.Username, acct.PasswordHash, acct.CreatedAt)
+	if err != nil {
+		// primary key ตัดสินแทนการเช็คก่อน insert จึงไม่มี race ระหว่างสองคนที่สมัครชื่อเดียวกัน
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return account.ErrUsernameTaken
+		}
+		return fmt.Errorf("failed to create account: %v", err)
+	}
+	return nil
+}
+
+// GetAccount returns an account by ID
+func (r *AccountRepository) GetAccount(id string) (*account.Account, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	acct := &account.Account{ID: id}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT username, password_hash, created_at FROM accounts WHERE id = $1`, id).
+		Scan(&acct.Username, &acct.PasswordHash, &acct.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, account.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	return acct, nil
+}
+
+// CreateSession inserts a new session
+func (r *AccountRepository) CreateSession(session *account.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO account_sessions (id, account_id, username, token_hash, user_agent, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		session.ID, session.AccountID, session.Username, session.TokenHash, session.UserAgent,
+		session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+// GetSession returns the session with this token hash
+func (r *AccountRepository) GetSession(tokenHash string) (*account.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, `
+		SELECT id, account_id, username, token_hash, user_agent, created_at, expires_at
+		FROM account_sessions WHERE token_hash = $1`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, account.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+	return session, nil
+}
+
+// ListSessions returns the account's unexpired sessions, newest first; expired ones are deleted
+func (r *AccountRepository) ListSessions(accountID string) ([]*account.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM account_sessions WHERE account_id = $1 AND expires_at <= now()`, accountID); err != nil {
+		return nil, fmt.Errorf("failed to delete expired sessions: %v", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, account_id, username, token_hash, user_agent, created_at, expires_at
+		FROM account_sessions WHERE account_id = $1 ORDER BY created_at DESC`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []*account.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteSession removes one of the account's sessions
+func (r *AccountRepository) DeleteSession(accountID, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM account_sessions WHERE id = $1 AND account_id = $2`, sessionID, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return account.ErrSessionNotFound
+	}
+	return nil
+}
+
+// scanSession reads one account_sessions row
+func scanSession(row rowScanner) (*account.Session, error) {
+	session := &account.Session{}
+	if err := row.Scan(&session.ID, &session.AccountID, &session.Username, &session.TokenHash,
+		&session.UserAgent, &session.CreatedAt, &session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
@@ -0,0 +1,66 @@
+-- ตารางหลักของ chat: users, rooms และ messages
+
+CREATE TABLE IF NOT EXISTS users (
+    id               BIGSERIAL PRIMARY KEY,
+    username         TEXT        NOT NULL UNIQUE,
+    conn_id          TEXT        NOT NULL UNIQUE,
+    current_room     TEXT        NOT NULL DEFAULT '',
+    joined_at        TIMESTAMPTZ NOT NULL,
+    last_active      TIMESTAMPTZ NOT NULL,
+    is_authenticated BOOLEAN     NOT NULL DEFAULT TRUE,
+    node_id          TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS users_current_room_idx ON users (current_room) WHERE is_authenticated;
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id            BIGSERIAL PRIMARY KEY,
+    name          TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    created_by    TEXT        NOT NULL,
+    max_users     INTEGER     NOT NULL,
+    is_active     BOOLEAN     NOT NULL DEFAULT TRUE,
+    last_activity TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ,
+    export_key    TEXT        NOT NULL DEFAULT '',
+    mirror_writer TEXT        NOT NULL DEFAULT '',
+    private       BOOLEAN     NOT NULL DEFAULT FALSE,
+    is_private    BOOLEAN     NOT NULL DEFAULT FALSE,
+    password_hash TEXT        NOT NULL DEFAULT '',
+    invited_users TEXT[]      NOT NULL DEFAULT '{}',
+    retention     BIGINT      NOT NULL DEFAULT 0, -- nanoseconds (time.Duration)
+    topic         TEXT        NOT NULL DEFAULT '',
+    description   TEXT        NOT NULL DEFAULT '',
+    pinned        JSONB       NOT NULL DEFAULT '[]',
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- ห้องที่ถูก archive แล้วเก็บไว้ได้ ชื่อจึงซ้ำได้เฉพาะห้องที่ไม่ active
+CREATE UNIQUE INDEX IF NOT EXISTS rooms_active_name_idx ON rooms (name) WHERE is_active;
+
+CREATE TABLE IF NOT EXISTS messages (
+    id             BIGSERIAL PRIMARY KEY,
+    type           TEXT        NOT NULL,
+    content        TEXT        NOT NULL DEFAULT '',
+    sender         TEXT        NOT NULL DEFAULT '',
+    username       TEXT        NOT NULL DEFAULT '',
+    room_name      TEXT        NOT NULL DEFAULT '',
+    timestamp      TIMESTAMPTZ NOT NULL,
+    correlation_id TEXT        NOT NULL DEFAULT '',
+    reactions      JSONB       NOT NULL DEFAULT '[]',
+    edit_history   JSONB       NOT NULL DEFAULT '[]',
+    is_deleted     BOOLEAN     NOT NULL DEFAULT FALSE,
+    deleted_at     TIMESTAMPTZ,
+    parent_id      BIGINT,
+    seq            BIGINT      NOT NULL DEFAULT 0,
+    attachments    JSONB       NOT NULL DEFAULT '[]',
+    mentions       TEXT[]      NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS messages_room_timestamp_idx ON messages (room_name, timestamp DESC);
+CREATE INDEX IF NOT EXISTS messages_username_timestamp_idx ON messages (username, timestamp DESC);
+CREATE INDEX IF NOT EXISTS messages_timestamp_idx ON messages (timestamp);
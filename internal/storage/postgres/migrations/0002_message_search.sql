@@ -0,0 +1,8 @@
+-- full-text search ของข้อความ (ใช้แทน text index ของ MongoDB)
+-- ใช้ config 'simple' เพราะข้อความมีหลายภาษา (ไทย/อังกฤษ) และไม่ต้องการ stemming
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS search TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+
+CREATE INDEX IF NOT EXISTS messages_search_idx ON messages USING GIN (search);
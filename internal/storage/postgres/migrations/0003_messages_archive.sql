@@ -0,0 +1,7 @@
+-- ข้อความที่พ้น retention จะถูกย้ายมาที่นี่เมื่อเปิด retention_archive
+
+CREATE TABLE IF NOT EXISTS messages_archive (
+    LIKE messages INCLUDING DEFAULTS,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
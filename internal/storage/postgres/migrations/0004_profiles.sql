@@ -0,0 +1,9 @@
+-- profile ผูกกับ account (ไม่ใช่ session) แถวใน users ถูกลบเมื่อ disconnect แต่ profile อยู่ต่อ
+
+CREATE TABLE IF NOT EXISTS profiles (
+    account_id   TEXT        PRIMARY KEY,
+    display_name TEXT        NOT NULL DEFAULT '',
+    avatar_url   TEXT        NOT NULL DEFAULT '',
+    status_text  TEXT        NOT NULL DEFAULT '',
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
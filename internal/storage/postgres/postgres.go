@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"realtime-chat/internal/account"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+
+	_ "github.com/lib/pq" // PostgreSQL driver สำหรับ database/sql
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock key that lets only one node apply migrations at a time
+const migrationLockID = 4786
+
+// Config holds PostgreSQL connection settings
+type Config struct {
+	DSN             string
+	MaxOpenConns    int
+	ConnectTimeout  time.Duration
+	ConnMaxLifetime time.Duration
+}
+
+// Store is the PostgreSQL storage backend: users, rooms and messages in one database
+type Store struct {
+	db       *sql.DB
+	users    *UserRepository
+	rooms    *RoomRepository
+	messages *MessageRepository
+	profiles *ProfileRepository
+	accounts *AccountRepository
+}
+
+// Open connects to PostgreSQL and applies pending migrations
+func Open(cfg Config) (*Store, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("postgres DSN is required")
+	}
+
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %v", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxOpenConns / 2)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	timeout := cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %v", err)
+	}
+
+	applied, err := migrate(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(applied) > 0 {
+		slog.Info("✅ Applied PostgreSQL migrations", "migrations", applied)
+	}
+
+	return &Store{
+		db:       db,
+		users:    &UserRepository{db: db},
+		rooms:    &RoomRepository{db: db},
+		messages: &MessageRepository{db: db},
+		profiles: &ProfileRepository{db: db},
+		accounts: &AccountRepository{db: db},
+	}, nil
+}
+
+// Name returns the backend name
+func (s *Store) Name() string {
+	return "postgres"
+}
+
+// Users returns the user repository
+func (s *Store) Users() userPkg.Repository {
+	return s.users
+}
+
+// Rooms returns the room repository
+func (s *Store) Rooms() room.Repository {
+	return s.rooms
+}
+
+// Messages returns the message repository
+func (s *Store) Messages() messagePkg.Repository {
+	return s.messages
+}
+
+// Profiles returns the profile repository
+func (s *Store) Profiles() userPkg.ProfileRepository {
+	return s.profiles
+}
+
+// Accounts returns the account repository
+func (s *Store) Accounts() account.Repository {
+	return s.accounts
+}
+
+// RetentionStore returns the store used by the message retention janitor
+func (s *Store) RetentionStore(archive bool) messagePkg.RetentionStore {
+	return &retentionStore{db: s.db, archive: archive}
+}
+
+// SetNodeID sets the node recorded on users created by this server
+func (s *Store) SetNodeID(nodeID string) {
+	s.users.nodeID = nodeID
+}
+
+// Ping checks the database connection
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the connection pool
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies the embedded migrations that aren't recorded in schema_migrations yet, in file name order.
+// ใช้ advisory lock เพื่อให้หลาย node ที่ start พร้อมกันไม่รัน migration ซ้อนกัน
+func migrate(ctx context.Context, db *sql.DB) ([]string, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire migration connection: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("failed to lock migrations: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	var applied []string
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+
+		var exists bool
+		if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&exists); err != nil {
+			return applied, fmt.Errorf("failed to check migration %s: %v", version, err)
+		}
+		if exists {
+			continue
+		}
+
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %v", version, err)
+		}
+
+		// แต่ละไฟล์รันใน transaction เดียวกับการบันทึก version จึงไม่ค้างครึ่งทาง
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return applied, fmt.Errorf("failed to begin migration %s: %v", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %v", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("failed to record migration %s: %v", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, fmt.Errorf("failed to commit migration %s: %v", version, err)
+		}
+		applied = append(applied, version)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,600 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	messagePkg "realtime-chat/internal/message"
+)
+
+// messageColumns is the column list scanned by scanMessage
+const messageColumns = `id, type, content, sender, username, room_name, timestamp, correlation_id,
+	reactions, edit_history, is_deleted, parent_id, seq, attachments, mentions`
+
+// maxTextSearchLimit caps a page of SearchMessagesText (เท่ากับ MongoDB)
+const maxTextSearchLimit = 100
+
+// MessageRepository implements message.Repository using SQLite
+type MessageRepository struct {
+	db *sql.DB
+}
+
+// parseID converts a message ID to its row id; IDs from another backend never match
+func parseID(messageID string) (int64, bool) {
+	id, err := strconv.ParseInt(messageID, 10, 64)
+	return id, err == nil && id > 0
+}
+
+// scanMessage reads a row selected with messageColumns, followed by any extra destinations
+func scanMessage(row rowScanner, extra ...interface{}) (*messagePkg.Message, error) {
+	var (
+		id          int64
+		timestamp   int64
+		message     = &messagePkg.Message{}
+		reactions   string
+		editHistory string
+		parentID    sql.NullInt64
+		attachments string
+		mentions    string
+	)
+	dest := append([]interface{}{&id, &message.Type, &message.Content, &message.Sender, &message.Username,
+		&message.RoomName, &timestamp, &message.CorrelationID, &reactions, &editHistory,
+		&message.IsDeleted, &parentID, &message.Seq, &attachments, &mentions}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	message.ID = strconv.FormatInt(id, 10)
+	message.Timestamp = fromNanos(timestamp)
+	if parentID.Valid {
+		message.ParentID = strconv.FormatInt(parentID.Int64, 10)
+	}
+	for _, field := range []struct {
+		raw  string
+		dest interface{}
+	}{{reactions, &message.Reactions}, {editHistory, &message.EditHistory}, {attachments, &message.Attachments}, {mentions, &message.Mentions}} {
+		if err := json.Unmarshal([]byte(field.raw), field.dest); err != nil {
+			return nil, fmt.Errorf("invalid message %s: %v", message.ID, err)
+		}
+	}
+	// ให้ JSON ที่ส่งออกเหมือน MongoDB (omitempty เมื่อไม่มีข้อมูล)
+	if len(message.Reactions) == 0 {
+		message.Reactions = nil
+	}
+	if len(message.EditHistory) == 0 {
+		message.EditHistory = nil
+	}
+	if len(message.Attachments) == 0 {
+		message.Attachments = nil
+	}
+	if len(message.Mentions) == 0 {
+		message.Mentions = nil
+	}
+	return message, nil
+}
+
+// queryMessages runs a query selecting messageColumns
+func (r *MessageRepository) queryMessages(action, query string, args ...interface{}) ([]*messagePkg.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %v", action, err)
+	}
+	defer rows.Close()
+
+	var messages []*messagePkg.Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to %s: %v", action, err)
+	}
+	return messages, nil
+}
+
+// jsonArray encodes a slice as a JSON array ('[]' when empty)
+func jsonArray(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	if string(encoded) == "null" {
+		return "[]", nil
+	}
+	return string(encoded), nil
+}
+
+// SaveMessage saves a message
+func (r *MessageRepository) SaveMessage(message *messagePkg.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attachments, err := jsonArray(message.Attachments)
+	if err != nil {
+		return fmt.Errorf("failed to save message [%s]: %v", message.CorrelationID, err)
+	}
+	mentions, err := jsonArray(message.Mentions)
+	if err != nil {
+		return fmt.Errorf("failed to save message [%s]: %v", message.CorrelationID, err)
+	}
+	var parentID sql.NullInt64
+	if id, ok := parseID(message.ParentID); ok {
+		parentID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	// ใช้ ID ที่กำหนดไว้ล่วงหน้า (เช่นจาก journal) เพื่อให้การบันทึกซ้ำไม่สร้างแถวซ้ำ
+	var id sql.NullInt64
+	if preset, ok := parseID(message.ID); ok {
+		id = sql.NullInt64{Int64: preset, Valid: true}
+	}
+
+	now := nanos(time.Now())
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO messages (id, type, content, sender, username, room_name, timestamp, correlation_id, parent_id, seq, attachments, mentions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		id, message.Type, message.Content, message.Sender, message.Username, message.RoomName, nanos(message.Timestamp),
+		message.CorrelationID, parentID, message.Seq, attachments, mentions, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save message [%s]: %v", message.CorrelationID, err)
+	}
+
+	if !id.Valid {
+		inserted, _ := result.LastInsertId()
+		message.ID = strconv.FormatInt(inserted, 10)
+	}
+	return nil
+}
+
+// GetMessage retrieves a single message by ID
+func (r *MessageRepository) GetMessage(messageID string) (*messagePkg.Message, error) {
+	id, ok := parseID(messageID)
+	if !ok {
+		return nil, fmt.Errorf("invalid message ID: %s", messageID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := scanMessage(r.db.QueryRowContext(ctx, `SELECT `+messageColumns+` FROM messages WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("message not found")
+		}
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+	return message, nil
+}
+
+// UpdateMessage updates an existing message
+func (r *MessageRepository) UpdateMessage(message *messagePkg.Message) error {
+	id, ok := parseID(message.ID)
+	if !ok {
+		return fmt.Errorf("invalid message ID: %s", message.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE messages SET content = ?, type = ?, timestamp = ?, updated_at = ? WHERE id = ?`,
+		message.Content, message.Type, nanos(message.Timestamp), nanos(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("message not found")
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message by ID
+func (r *MessageRepository) DeleteMessage(messageID string) error {
+	id, ok := parseID(messageID)
+	if !ok {
+		return fmt.Errorf("invalid message ID: %s", messageID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("message not found")
+	}
+	return nil
+}
+
+// GetMessageHistory retrieves message history for a room, oldest first
+func (r *MessageRepository) GetMessageHistory(roomName string, limit int) ([]*messagePkg.Message, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return r.queryMessages("retrieve message history", `
+		SELECT * FROM (
+			SELECT `+messageColumns+` FROM messages WHERE room_name = ? ORDER BY timestamp DESC LIMIT ?
+		) ORDER BY timestamp ASC`, roomName, limit)
+}
+
+// GetRecentMessages retrieves recent messages across all rooms
+func (r *MessageRepository) GetRecentMessages(limit int) ([]*messagePkg.Message, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return r.queryMessages("retrieve recent messages",
+		`SELECT `+messageColumns+` FROM messages ORDER BY timestamp DESC LIMIT ?`, limit)
+}
+
+// GetUserMessageHistory retrieves message history for a specific user
+func (r *MessageRepository) GetUserMessageHistory(username string, limit int) ([]*messagePkg.Message, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return r.queryMessages("retrieve user message history",
+		`SELECT `+messageColumns+` FROM messages WHERE username = ? ORDER BY timestamp DESC LIMIT ?`, username, limit)
+}
+
+// GetMessageCount returns the total number of messages in a room (every room when roomName is empty)
+func (r *MessageRepository) GetMessageCount(roomName string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM messages WHERE ?1 = '' OR room_name = ?1`, roomName).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %v", err)
+	}
+	return count, nil
+}
+
+// SearchMessages searches for messages whose content matches a case-insensitive regular expression
+func (r *MessageRepository) SearchMessages(query string, roomName string, limit int) ([]*messagePkg.Message, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return r.queryMessages("search messages", `
+		SELECT `+messageColumns+` FROM messages
+		WHERE content REGEXP ?1 AND (?2 = '' OR room_name = ?2)
+		ORDER BY timestamp DESC LIMIT ?3`, query, roomName, limit)
+}
+
+// ftsQuery converts search text to an FTS5 query: words and "phrases" must all match, -word excludes.
+// ทุกคำถูกครอบด้วย "" จึงไม่มีตัวอักษรใดถูกตีความเป็น syntax ของ FTS5
+func ftsQuery(text string) string {
+	var include, exclude []string
+	quote := func(term string) string {
+		return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		negate := strings.HasPrefix(text, "-")
+		if negate {
+			text = text[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(text, `"`) {
+			end := strings.Index(text[1:], `"`)
+			if end < 0 {
+				term, text = text[1:], ""
+			} else {
+				term, text = text[1:end+1], text[end+2:]
+			}
+		} else if space := strings.IndexAny(text, " \t\n"); space >= 0 {
+			term, text = text[:space], text[space:]
+		} else {
+			term, text = text, ""
+		}
+
+		if term = strings.TrimSpace(term); term == "" {
+			continue
+		}
+		if negate {
+			exclude = append(exclude, quote(term))
+		} else {
+			include = append(include, quote(term))
+		}
+	}
+
+	if len(include) == 0 {
+		return ""
+	}
+	query := strings.Join(include, " AND ")
+	for _, term := range exclude {
+		query = "(" + query + ") NOT " + term
+	}
+	return query
+}
+
+// SearchMessagesText searches messages with the FTS5 index, best matches first (bm25)
+func (r *MessageRepository) SearchMessagesText(query messagePkg.SearchQuery) ([]*messagePkg.ScoredMessage, string, error) {
+	match := ftsQuery(query.Text)
+	if match == "" {
+		return nil, "", fmt.Errorf("search text is required")
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > maxTextSearchLimit {
+		limit = maxTextSearchLimit
+	}
+	offset := query.Offset
+	if query.Cursor != "" {
+		parsed, err := strconv.Atoi(query.Cursor)
+		if err != nil || parsed < 0 {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+		offset = parsed
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	args := []interface{}{match}
+	conditions := []string{"is_deleted = 0"}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, condition)
+	}
+	if query.RoomName != "" {
+		addCondition("room_name = ?", query.RoomName)
+	}
+	if query.Username != "" {
+		addCondition("username = ?", query.Username)
+	}
+	if query.MessageType != "" {
+		addCondition("type = ?", query.MessageType)
+	}
+	if query.HasAttachment {
+		conditions = append(conditions, "json_array_length(attachments) > 0")
+	}
+	if query.StartDate != nil {
+		addCondition("timestamp >= ?", nanos(*query.StartDate))
+	}
+	if query.EndDate != nil {
+		addCondition("timestamp <= ?", nanos(*query.EndDate))
+	}
+
+	// bm25 ยิ่งน้อยยิ่งตรง จึงกลับเครื่องหมายให้ score มากคือดีเหมือน MongoDB
+	// และขอเกินมาหนึ่งรายการเพื่อรู้ว่ามีหน้าถัดไปหรือไม่
+	args = append(args, limit+1, offset)
+	statement := `
+		SELECT ` + messageColumns + `, hits.score
+		FROM messages
+		JOIN (SELECT rowid AS hit, -bm25(messages_fts) AS score FROM messages_fts WHERE messages_fts MATCH ?) hits ON hits.hit = messages.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY hits.score DESC, timestamp DESC
+		LIMIT ? OFFSET ?`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search messages: %v", err)
+	}
+	defer rows.Close()
+
+	results := make([]*messagePkg.ScoredMessage, 0, limit)
+	for rows.Next() {
+		var score float64
+		message, err := scanMessage(rows, &score)
+		if err != nil {
+			continue
+		}
+		results = append(results, &messagePkg.ScoredMessage{Message: message, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to search messages: %v", err)
+	}
+
+	nextCursor := ""
+	if len(results) > limit {
+		results = results[:limit]
+		nextCursor = strconv.Itoa(offset + limit)
+	}
+	return results, nextCursor, nil
+}
+
+// ToggleReaction adds the user's emoji reaction to a message, or removes it if already present.
+// อ่าน-แก้-เขียนภายใน transaction เดียว (SQLite เขียนได้ทีละ transaction จึงไม่มี reaction หาย)
+func (r *MessageRepository) ToggleReaction(messageID, emoji, username string) (bool, []messagePkg.MessageReaction, error) {
+	id, ok := parseID(messageID)
+	if !ok {
+		return false, nil, messagePkg.ErrMessageNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to add reaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var raw string
+	if err := tx.QueryRowContext(ctx, `SELECT reactions FROM messages WHERE id = ?`, id).Scan(&raw); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil, messagePkg.ErrMessageNotFound
+		}
+		return false, nil, fmt.Errorf("failed to load reactions: %v", err)
+	}
+	var reactions []messagePkg.MessageReaction
+	if err := json.Unmarshal([]byte(raw), &reactions); err != nil {
+		return false, nil, fmt.Errorf("failed to load reactions: %v", err)
+	}
+
+	added, reactions := messagePkg.ToggleReactionUser(reactions, emoji, username)
+	encoded, err := jsonArray(reactions)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to save reactions: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE messages SET reactions = ?, updated_at = ? WHERE id = ?`, encoded, nanos(time.Now()), id); err != nil {
+		return false, nil, fmt.Errorf("failed to save reactions: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, nil, fmt.Errorf("failed to save reactions: %v", err)
+	}
+	return added, reactions, nil
+}
+
+// EditMessage replaces a message's content and appends the previous content to its edit history.
+// ใน UPDATE เดียว content ทางขวาคือค่าเดิม จึงเก็บ history และแก้ไขพร้อมกันได้
+func (r *MessageRepository) EditMessage(messageID, content, editedBy string) (*messagePkg.Message, error) {
+	return r.rewriteMessage(messageID, content, false, messagePkg.MessageEdit{EditedBy: editedBy})
+}
+
+// SoftDeleteMessage marks a message as deleted, keeping its last content in the edit history as an audit trail
+func (r *MessageRepository) SoftDeleteMessage(messageID, deletedBy string) (*messagePkg.Message, error) {
+	return r.rewriteMessage(messageID, "", true, messagePkg.MessageEdit{EditedBy: deletedBy, EditReason: "deleted"})
+}
+
+// rewriteMessage replaces the content of a message that isn't deleted yet, recording the previous content as an edit
+func (r *MessageRepository) rewriteMessage(messageID, content string, deleted bool, edit messagePkg.MessageEdit) (*messagePkg.Message, error) {
+	id, ok := parseID(messageID)
+	if !ok {
+		return nil, messagePkg.ErrMessageNotFound
+	}
+
+	now := time.Now()
+	edit.EditedAt = now
+	entry, err := json.Marshal(edit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update message: %v", err)
+	}
+	var deletedAt sql.NullInt64
+	if deleted {
+		deletedAt = sql.NullInt64{Int64: nanos(now), Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := scanMessage(r.db.QueryRowContext(ctx, `
+		UPDATE messages SET
+			edit_history = json_insert(edit_history, '$[#]', json_set(json(?), '$.previous_content', content)),
+			content = ?,
+			is_deleted = ?,
+			deleted_at = ?,
+			updated_at = ?
+		WHERE id = ? AND is_deleted = 0
+		RETURNING `+messageColumns, string(entry), content, deleted, deletedAt, nanos(now), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, messagePkg.ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to update message: %v", err)
+	}
+	return message, nil
+}
+
+// GetRoomActivity returns message counts grouped into hour or day buckets (UTC, เหมือน $dateTrunc)
+func (r *MessageRepository) GetRoomActivity(roomName string, from, to time.Time, bucket string) ([]messagePkg.ActivityBucket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	size := int64(time.Hour)
+	if bucket == messagePkg.ActivityBucketDay {
+		size = int64(24 * time.Hour)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT (timestamp / ?1) * ?1 AS start, count(*)
+		FROM messages
+		WHERE room_name = ?2 AND timestamp >= ?3 AND timestamp < ?4
+		GROUP BY start ORDER BY start`, size, roomName, nanos(from), nanos(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate room activity: %v", err)
+	}
+	defer rows.Close()
+
+	var buckets []messagePkg.ActivityBucket
+	for rows.Next() {
+		var start int64
+		var activity messagePkg.ActivityBucket
+		if err := rows.Scan(&start, &activity.Count); err != nil {
+			return nil, fmt.Errorf("failed to decode room activity: %v", err)
+		}
+		activity.Start = fromNanos(start).UTC()
+		buckets = append(buckets, activity)
+	}
+	return buckets, rows.Err()
+}
+
+// retentionStore implements message.RetentionStore for SQLite
+type retentionStore struct {
+	db      *sql.DB
+	archive bool
+}
+
+// archiveColumns are copied into messages_archive
+const archiveColumns = `id, type, content, sender, username, room_name, timestamp, correlation_id, reactions,
+	edit_history, is_deleted, deleted_at, parent_id, seq, attachments, mentions, created_at, updated_at`
+
+// PurgeBatch deletes (or archives) up to batchSize of the oldest messages in scope older than before.
+// คัดลอกและลบใน transaction เดียว จึงไม่มีข้อความหายระหว่างสองขั้น
+func (s *retentionStore) PurgeBatch(scope messagePkg.RetentionScope, before time.Time, batchSize int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exclude, err := jsonArray(scope.ExcludeRooms)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired messages: %v", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired messages: %v", err)
+	}
+	defer tx.Rollback()
+
+	// เก็บ id ของ batch ไว้ใน temp table ให้ทั้งการคัดลอกและการลบใช้ชุดเดียวกัน
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS purge_batch (id INTEGER PRIMARY KEY)`); err != nil {
+		return 0, fmt.Errorf("failed to purge expired messages: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM purge_batch`); err != nil {
+		return 0, fmt.Errorf("failed to purge expired messages: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO purge_batch (id)
+		SELECT id FROM messages
+		WHERE timestamp < ?1 AND (?2 = '' OR room_name = ?2) AND room_name NOT IN (SELECT value FROM json_each(?3))
+		ORDER BY timestamp LIMIT ?4`, nanos(before), scope.Room, exclude, batchSize); err != nil {
+		return 0, fmt.Errorf("failed to find expired messages: %v", err)
+	}
+
+	if s.archive {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO messages_archive (`+archiveColumns+`, archived_at)
+			SELECT `+archiveColumns+`, ? FROM messages WHERE id IN (SELECT id FROM purge_batch)
+			ON CONFLICT (id) DO NOTHING`, nanos(time.Now())); err != nil {
+			return 0, fmt.Errorf("failed to archive messages: %v", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id IN (SELECT id FROM purge_batch)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %v", err)
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
@@ -0,0 +1,67 @@
+-- ตารางหลักของ chat: users, rooms และ messages
+-- เวลาเก็บเป็น unix nanoseconds (UTC) เพื่อให้เทียบและจัดกลุ่มได้ตรงๆ; array/object เก็บเป็น JSON text
+
+CREATE TABLE IF NOT EXISTS users (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    username         TEXT    NOT NULL UNIQUE,
+    conn_id          TEXT    NOT NULL UNIQUE,
+    current_room     TEXT    NOT NULL DEFAULT '',
+    joined_at        INTEGER NOT NULL,
+    last_active      INTEGER NOT NULL,
+    is_authenticated INTEGER NOT NULL DEFAULT 1,
+    node_id          TEXT    NOT NULL DEFAULT '',
+    created_at       INTEGER NOT NULL,
+    updated_at       INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS users_current_room_idx ON users (current_room);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    name          TEXT    NOT NULL,
+    created_at    INTEGER NOT NULL,
+    created_by    TEXT    NOT NULL,
+    max_users     INTEGER NOT NULL,
+    is_active     INTEGER NOT NULL DEFAULT 1,
+    last_activity INTEGER NOT NULL,
+    expires_at    INTEGER, -- NULL = ห้องถาวร
+    export_key    TEXT    NOT NULL DEFAULT '',
+    mirror_writer TEXT    NOT NULL DEFAULT '',
+    private       INTEGER NOT NULL DEFAULT 0,
+    is_private    INTEGER NOT NULL DEFAULT 0,
+    password_hash TEXT    NOT NULL DEFAULT '',
+    invited_users TEXT    NOT NULL DEFAULT '[]',
+    retention     INTEGER NOT NULL DEFAULT 0, -- nanoseconds (time.Duration)
+    topic         TEXT    NOT NULL DEFAULT '',
+    description   TEXT    NOT NULL DEFAULT '',
+    pinned        TEXT    NOT NULL DEFAULT '[]',
+    updated_at    INTEGER NOT NULL
+);
+
+-- ห้องที่ถูก archive แล้วเก็บไว้ได้ ชื่อจึงซ้ำได้เฉพาะห้องที่ไม่ active
+CREATE UNIQUE INDEX IF NOT EXISTS rooms_active_name_idx ON rooms (name) WHERE is_active = 1;
+
+CREATE TABLE IF NOT EXISTS messages (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    type           TEXT    NOT NULL,
+    content        TEXT    NOT NULL DEFAULT '',
+    sender         TEXT    NOT NULL DEFAULT '',
+    username       TEXT    NOT NULL DEFAULT '',
+    room_name      TEXT    NOT NULL DEFAULT '',
+    timestamp      INTEGER NOT NULL,
+    correlation_id TEXT    NOT NULL DEFAULT '',
+    reactions      TEXT    NOT NULL DEFAULT '[]',
+    edit_history   TEXT    NOT NULL DEFAULT '[]',
+    is_deleted     INTEGER NOT NULL DEFAULT 0,
+    deleted_at     INTEGER,
+    parent_id      INTEGER,
+    seq            INTEGER NOT NULL DEFAULT 0,
+    attachments    TEXT    NOT NULL DEFAULT '[]',
+    mentions       TEXT    NOT NULL DEFAULT '[]',
+    created_at     INTEGER NOT NULL,
+    updated_at     INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS messages_room_timestamp_idx ON messages (room_name, timestamp);
+CREATE INDEX IF NOT EXISTS messages_username_timestamp_idx ON messages (username, timestamp);
+CREATE INDEX IF NOT EXISTS messages_timestamp_idx ON messages (timestamp);
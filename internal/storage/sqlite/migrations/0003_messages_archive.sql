@@ -0,0 +1,23 @@
+-- ข้อความที่พ้น retention จะถูกย้ายมาที่นี่เมื่อเปิด retention_archive
+
+CREATE TABLE IF NOT EXISTS messages_archive (
+    id             INTEGER PRIMARY KEY,
+    type           TEXT    NOT NULL,
+    content        TEXT    NOT NULL DEFAULT '',
+    sender         TEXT    NOT NULL DEFAULT '',
+    username       TEXT    NOT NULL DEFAULT '',
+    room_name      TEXT    NOT NULL DEFAULT '',
+    timestamp      INTEGER NOT NULL,
+    correlation_id TEXT    NOT NULL DEFAULT '',
+    reactions      TEXT    NOT NULL DEFAULT '[]',
+    edit_history   TEXT    NOT NULL DEFAULT '[]',
+    is_deleted     INTEGER NOT NULL DEFAULT 0,
+    deleted_at     INTEGER,
+    parent_id      INTEGER,
+    seq            INTEGER NOT NULL DEFAULT 0,
+    attachments    TEXT    NOT NULL DEFAULT '[]',
+    mentions       TEXT    NOT NULL DEFAULT '[]',
+    created_at     INTEGER NOT NULL,
+    updated_at     INTEGER NOT NULL,
+    archived_at    INTEGER NOT NULL
+);
@@ -0,0 +1,21 @@
+-- account เป็นเจ้าของชื่อผู้ใช้ถาวร (id = account ID เดียวกับ profiles) ส่วน users เป็นแค่ connection ที่ออนไลน์
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id            TEXT    PRIMARY KEY,
+    username      TEXT    NOT NULL,
+    password_hash TEXT    NOT NULL,
+    created_at    INTEGER NOT NULL
+);
+
+-- เก็บ SHA-256 ของ token ไม่ใช่ token จริง
+CREATE TABLE IF NOT EXISTS account_sessions (
+    id         TEXT    PRIMARY KEY,
+    account_id TEXT    NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    username   TEXT    NOT NULL,
+    token_hash TEXT    NOT NULL UNIQUE,
+    user_agent TEXT    NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_sessions_account ON account_sessions (account_id, created_at DESC);
@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// ProfileRepository implements user.ProfileRepository using SQLite
+type ProfileRepository struct {
+	db *sql.DB
+}
+
+// GetProfile returns the account's profile
+func (r *ProfileRepository) GetProfile(accountID string) (*userPkg.Profile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var updatedAt int64
+	profile := &userPkg.Profile{AccountID: accountID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT display_name, avatar_url, status_text, updated_at FROM profiles WHERE account_id = ?`, accountID).
+		Scan(&profile.DisplayName, &profile.AvatarURL, &profile.StatusText, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, userPkg.ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %v", err)
+	}
+	profile.UpdatedAt = fromNanos(updatedAt)
+	return profile, nil
+}
+
+// SaveProfile creates or replaces the account's profile
+func (r *ProfileRepository) SaveProfile(profile *userPkg.Profile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO profiles (account_id, display_name, avatar_url, status_text, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (account_id) DO UPDATE SET
+			display_name = excluded.display_name,
+			avatar_url   = excluded.avatar_url,
+			status_text  = excluded.status_text,
+			updated_at   = excluded.updated_at`,
+		profile.AccountID, profile.DisplayName, profile.AvatarURL, profile.StatusText, nanos(profile.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+)
+
+// roomColumns is the column list scanned by scanRoom
+const roomColumns = `name, created_at, created_by, max_users, is_active, last_activity, expires_at, export_key,
+	mirror_writer, private, is_private, password_hash, invited_users, retention, topic, description, pinned`
+
+// RoomRepository implements room.Repository using SQLite.
+// สมาชิกของห้องอ่านจาก users.current_room เหมือน MongoDB mode
+type RoomRepository struct {
+	db *sql.DB
+}
+
+// scanRoom reads a row selected with roomColumns
+func scanRoom(row rowScanner) (*room.Room, error) {
+	var (
+		chatRoom     = &room.Room{Users: make(map[string]*userPkg.User)}
+		createdAt    int64
+		lastActivity int64
+		expiresAt    sql.NullInt64
+		invited      string
+		retention    int64
+		pinned       string
+	)
+	err := row.Scan(&chatRoom.Name, &createdAt, &chatRoom.CreatedBy, &chatRoom.MaxUsers, &chatRoom.IsActive,
+		&lastActivity, &expiresAt, &chatRoom.ExportKey, &chatRoom.MirrorWriter, &chatRoom.Private,
+		&chatRoom.IsPrivate, &chatRoom.PasswordHash, &invited, &retention, &chatRoom.Topic, &chatRoom.Description, &pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	chatRoom.CreatedAt = fromNanos(createdAt)
+	chatRoom.LastActivity = fromNanos(lastActivity)
+	if expiresAt.Valid {
+		expiry := fromNanos(expiresAt.Int64)
+		chatRoom.ExpiresAt = &expiry
+	}
+	chatRoom.Retention = time.Duration(retention)
+	if err := json.Unmarshal([]byte(invited), &chatRoom.InvitedUsers); err != nil {
+		return nil, fmt.Errorf("invalid invited users of room %s: %v", chatRoom.Name, err)
+	}
+	if err := json.Unmarshal([]byte(pinned), &chatRoom.Pinned); err != nil {
+		return nil, fmt.Errorf("invalid pinned messages of room %s: %v", chatRoom.Name, err)
+	}
+	if len(chatRoom.InvitedUsers) == 0 {
+		chatRoom.InvitedUsers = nil
+	}
+	if len(chatRoom.Pinned) == 0 {
+		chatRoom.Pinned = nil
+	}
+	return chatRoom, nil
+}
+
+// Create creates a new room
+func (r *RoomRepository) Create(name, creatorUsername string, maxUsers int) (*room.Room, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO rooms (name, created_at, created_by, max_users, is_active, last_activity, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?)`, name, nanos(now), creatorUsername, maxUsers, nanos(now), nanos(now))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("room '%s' already exists", name)
+		}
+		return nil, fmt.Errorf("failed to create room: %v", err)
+	}
+
+	return &room.Room{
+		Name:         name,
+		Users:        make(map[string]*userPkg.User),
+		CreatedAt:    now,
+		CreatedBy:    creatorUsername,
+		MaxUsers:     maxUsers,
+		IsActive:     true,
+		LastActivity: now,
+	}, nil
+}
+
+// GetByName gets an active room by name
+func (r *RoomRepository) GetByName(name string) (*room.Room, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	chatRoom, err := scanRoom(r.db.QueryRowContext(ctx, `SELECT `+roomColumns+` FROM rooms WHERE name = ? AND is_active = 1`, name))
+	if err != nil {
+		return nil, false
+	}
+	for _, user := range r.GetUsersInRoom(name) {
+		chatRoom.Users[user.ConnID] = user
+	}
+	return chatRoom, true
+}
+
+// GetActiveRooms returns all active rooms
+func (r *RoomRepository) GetActiveRooms() []*room.Room {
+	return r.list(`SELECT ` + roomColumns + ` FROM rooms WHERE is_active = 1 ORDER BY created_at DESC`)
+}
+
+// GetAll returns all rooms (active and inactive)
+func (r *RoomRepository) GetAll() []*room.Room {
+	return r.list(`SELECT ` + roomColumns + ` FROM rooms ORDER BY created_at DESC`)
+}
+
+// list loads rooms and their members with one query for the rooms and one for the users.
+// อ่าน rows ของห้องให้จบก่อน query ผู้ใช้ เพราะ pool มี connection เดียว
+func (r *RoomRepository) list(query string) []*room.Room {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return []*room.Room{}
+	}
+
+	rooms := []*room.Room{}
+	active := make(map[string]*room.Room)
+	for rows.Next() {
+		chatRoom, err := scanRoom(rows)
+		if err != nil {
+			continue
+		}
+		rooms = append(rooms, chatRoom)
+		if chatRoom.IsActive {
+			active[chatRoom.Name] = chatRoom
+		}
+	}
+	rows.Close()
+	if len(active) == 0 {
+		return rooms
+	}
+
+	userRows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE is_authenticated = 1 AND current_room <> ''`)
+	if err != nil {
+		return rooms
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		user, err := scanUser(userRows)
+		if err != nil {
+			continue
+		}
+		if chatRoom, ok := active[user.CurrentRoom]; ok {
+			chatRoom.Users[user.ConnID] = user
+		}
+	}
+	return rooms
+}
+
+// GetUsersInRoom returns all users in a specific room
+func (r *RoomRepository) GetUsersInRoom(roomName string) []*userPkg.User {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE current_room = ? AND is_authenticated = 1 ORDER BY joined_at`, roomName)
+	if err != nil {
+		return []*userPkg.User{}
+	}
+	defer rows.Close()
+
+	users := []*userPkg.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// GetRoomCount returns the number of active rooms
+func (r *RoomRepository) GetRoomCount() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM rooms WHERE is_active = 1`).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// JoinRoom adds a user to a room
+func (r *RoomRepository) JoinRoom(user *userPkg.User, roomName string) error {
+	chatRoom, exists := r.GetByName(roomName)
+	if !exists {
+		// Create default room if it doesn't exist
+		if roomName != "general" {
+			return fmt.Errorf("room '%s' does not exist", roomName)
+		}
+		if _, err := r.Create(roomName, "System", 100); err != nil {
+			return fmt.Errorf("failed to create default room: %v", err)
+		}
+	} else if len(chatRoom.Users) >= chatRoom.MaxUsers {
+		return fmt.Errorf("room '%s' is full (%d/%d)", roomName, len(chatRoom.Users), chatRoom.MaxUsers)
+	}
+
+	if err := r.setCurrentRoom(user.ConnID, roomName); err != nil {
+		return err
+	}
+	user.CurrentRoom = roomName
+	r.Touch(roomName)
+	return nil
+}
+
+// LeaveRoom removes a user from a room
+func (r *RoomRepository) LeaveRoom(user *userPkg.User, roomName string) error {
+	if err := r.setCurrentRoom(user.ConnID, ""); err != nil {
+		return err
+	}
+	user.CurrentRoom = ""
+	return nil
+}
+
+// setCurrentRoom updates the room a user is in
+func (r *RoomRepository) setCurrentRoom(connID, roomName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET current_room = ?, updated_at = ? WHERE conn_id = ?`, roomName, nanos(time.Now()), connID)
+	if err != nil {
+		return fmt.Errorf("failed to update user room: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("failed to update user room: user not found")
+	}
+	return nil
+}
+
+// update sets columns of an active room, returning "room not found" when nothing matched.
+// set ต้องเป็น "column = ?, ..." และ args เรียงตาม placeholder
+func (r *RoomRepository) update(action, roomName, set string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	args = append(args, nanos(time.Now()), roomName)
+	result, err := r.db.ExecContext(ctx, `UPDATE rooms SET `+set+`, updated_at = ? WHERE name = ? AND is_active = 1`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %v", action, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("room not found")
+	}
+	return nil
+}
+
+// UpdateMaxUsers changes the capacity of a room
+func (r *RoomRepository) UpdateMaxUsers(roomName string, maxUsers int) error {
+	return r.update("update room capacity", roomName, `max_users = ?`, maxUsers)
+}
+
+// SetExpiry sets when a room expires (zero time = permanent room)
+func (r *RoomRepository) SetExpiry(roomName string, expiresAt time.Time) error {
+	var expiry sql.NullInt64
+	if !expiresAt.IsZero() {
+		expiry = sql.NullInt64{Int64: nanos(expiresAt), Valid: true}
+	}
+	return r.update("set room expiry", roomName, `expires_at = ?`, expiry)
+}
+
+// SetExportKey stores the OpenPGP public key used to encrypt exported transcripts
+func (r *RoomRepository) SetExportKey(roomName, armoredKey string) error {
+	return r.update("set export key", roomName, `export_key = ?`, armoredKey)
+}
+
+// SetMirrorWriter sets (or clears) the node that accepts posts for a mirrored room
+func (r *RoomRepository) SetMirrorWriter(roomName, nodeID string) error {
+	return r.update("set mirror writer", roomName, `mirror_writer = ?`, nodeID)
+}
+
+// SetPrivate turns privacy mode on or off
+func (r *RoomRepository) SetPrivate(roomName string, private bool) error {
+	return r.update("set privacy mode", roomName, `private = ?`, private)
+}
+
+// SetAccess sets a room's invite-only flag and password hash
+func (r *RoomRepository) SetAccess(roomName string, inviteOnly bool, passwordHash string) error {
+	return r.update("set room access", roomName, `is_private = ?, password_hash = ?`, inviteOnly, passwordHash)
+}
+
+// AddInvite adds a user to a room's invitation list
+func (r *RoomRepository) AddInvite(roomName, username string) error {
+	return r.update("invite user", roomName, `invited_users = CASE
+		WHEN EXISTS (SELECT 1 FROM json_each(invited_users) WHERE lower(value) = lower(?1)) THEN invited_users
+		ELSE json_insert(invited_users, '$[#]', ?1) END`, username)
+}
+
+// SetRetention sets how long a room's messages are kept (0 = server default)
+func (r *RoomRepository) SetRetention(roomName string, retention time.Duration) error {
+	return r.update("set retention", roomName, `retention = ?`, int64(retention))
+}
+
+// SetTopic sets a room's topic and description
+func (r *RoomRepository) SetTopic(roomName, topic, description string) error {
+	return r.update("set topic", roomName, `topic = ?, description = ?`, topic, description)
+}
+
+// AddPin pins a message to a room; pinning the same message twice has no effect
+func (r *RoomRepository) AddPin(roomName string, pin room.PinnedMessage) error {
+	encoded, err := json.Marshal(pin)
+	if err != nil {
+		return fmt.Errorf("failed to pin message: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE rooms SET pinned = json_insert(pinned, '$[#]', json(?)), updated_at = ?
+		WHERE name = ? AND is_active = 1
+			AND NOT EXISTS (SELECT 1 FROM json_each(pinned) WHERE json_extract(value, '$.message_id') = ?)`,
+		string(encoded), nanos(time.Now()), roomName, pin.MessageID)
+	if err != nil {
+		return fmt.Errorf("failed to pin message: %v", err)
+	}
+	return nil
+}
+
+// RemovePin unpins a message from a room
+func (r *RoomRepository) RemovePin(roomName, messageID string) error {
+	return r.update("unpin message", roomName, `pinned = (
+		SELECT json_group_array(json(value)) FROM json_each(pinned) WHERE json_extract(value, '$.message_id') <> ?)`, messageID)
+}
+
+// DeactivateRoom archives a room and removes its members
+func (r *RoomRepository) DeactivateRoom(roomName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate room: %v", err)
+	}
+	defer tx.Rollback()
+
+	now := nanos(time.Now())
+	result, err := tx.ExecContext(ctx, `UPDATE rooms SET is_active = 0, updated_at = ? WHERE name = ? AND is_active = 1`, now, roomName)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate room: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("room '%s' does not exist", roomName)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET current_room = '', updated_at = ? WHERE current_room = ?`, now, roomName); err != nil {
+		return fmt.Errorf("failed to deactivate room: %v", err)
+	}
+	return tx.Commit()
+}
+
+// ReactivateRoom brings an archived room back with no members and no expiry
+func (r *RoomRepository) ReactivateRoom(roomName string) (*room.Room, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := nanos(time.Now())
+	chatRoom, err := scanRoom(r.db.QueryRowContext(ctx, `UPDATE rooms SET is_active = 1, expires_at = NULL, last_activity = ?, updated_at = ?
+		WHERE name = ? AND is_active = 0 RETURNING `+roomColumns, now, now, roomName))
+	if err == sql.ErrNoRows {
+		return nil, room.ErrRoomNotArchived
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reactivate room: %v", err)
+	}
+	return chatRoom, nil
+}
+
+// Touch records message activity in a room.
+// เขียนอย่างมากนาทีละครั้งต่อห้อง เพื่อไม่ให้ทุกข้อความต้อง UPDATE rooms
+func (r *RoomRepository) Touch(roomName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	r.db.ExecContext(ctx, `UPDATE rooms SET last_activity = ? WHERE name = ? AND is_active = 1 AND last_activity < ?`,
+		nanos(now), roomName, nanos(now.Add(-time.Minute)))
+}
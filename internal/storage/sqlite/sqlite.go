@@ -0,0 +1,247 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/account"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+
+	"modernc.org/sqlite" // pure Go driver: ไม่ต้องใช้ cgo จึง build เป็น binary เดียวได้
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Store is the embedded SQLite storage backend: users, rooms and messages in one file
+type Store struct {
+	db       *sql.DB
+	users    *UserRepository
+	rooms    *RoomRepository
+	messages *MessageRepository
+	profiles *ProfileRepository
+	accounts *AccountRepository
+}
+
+// registerOnce registers the REGEXP function used by SearchMessages
+var registerOnce sync.Once
+
+// Open opens (or creates) the database file and applies pending migrations
+func Open(path string) (*Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite path is required")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create sqlite directory: %v", err)
+		}
+	}
+
+	var registerErr error
+	registerOnce.Do(func() {
+		registerErr = sqlite.RegisterDeterministicScalarFunction("regexp", 2, regexpFunc)
+	})
+	if registerErr != nil {
+		return nil, fmt.Errorf("failed to register regexp function: %v", registerErr)
+	}
+
+	// WAL ให้อ่านได้ระหว่างเขียน; busy_timeout รอ lock แทนการคืน SQLITE_BUSY ทันที
+	dsn := "file:" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(on)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %v", err)
+	}
+	// SQLite เขียนได้ทีละ connection อยู่แล้ว ใช้ connection เดียวจึงไม่มี lock ชนกันระหว่าง transaction
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite: %v", err)
+	}
+
+	applied, err := migrate(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(applied) > 0 {
+		slog.Info("✅ Applied SQLite migrations", "migrations", applied)
+	}
+
+	// ไฟล์ database ใช้กับ process เดียว ผู้ใช้ที่ค้างจากรอบก่อนจึงไม่มี connection เหลือแล้วแน่นอน
+	// (MongoDB ใช้ user.Cleaner แยกตาม node แทน)
+	if _, err := db.ExecContext(ctx, `DELETE FROM users`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to clear stale users: %v", err)
+	}
+
+	return &Store{
+		db:       db,
+		users:    &UserRepository{db: db},
+		rooms:    &RoomRepository{db: db},
+		messages: &MessageRepository{db: db},
+		profiles: &ProfileRepository{db: db},
+		accounts: &AccountRepository{db: db},
+	}, nil
+}
+
+// Name returns the backend name
+func (s *Store) Name() string {
+	return "sqlite"
+}
+
+// Users returns the user repository
+func (s *Store) Users() userPkg.Repository {
+	return s.users
+}
+
+// Rooms returns the room repository
+func (s *Store) Rooms() room.Repository {
+	return s.rooms
+}
+
+// Messages returns the message repository
+func (s *Store) Messages() messagePkg.Repository {
+	return s.messages
+}
+
+// Profiles returns the profile repository
+func (s *Store) Profiles() userPkg.ProfileRepository {
+	return s.profiles
+}
+
+// Accounts returns the account repository
+func (s *Store) Accounts() account.Repository {
+	return s.accounts
+}
+
+// RetentionStore returns the store used by the message retention janitor
+func (s *Store) RetentionStore(archive bool) messagePkg.RetentionStore {
+	return &retentionStore{db: s.db, archive: archive}
+}
+
+// SetNodeID sets the node recorded on users created by this server
+func (s *Store) SetNodeID(nodeID string) {
+	s.users.nodeID = nodeID
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies the embedded migrations that aren't recorded in schema_migrations yet, in file name order
+func migrate(ctx context.Context, db *sql.DB) ([]string, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	var applied []string
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, version).Scan(&exists); err != nil {
+			return applied, fmt.Errorf("failed to check migration %s: %v", version, err)
+		}
+		if exists {
+			continue
+		}
+
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %v", version, err)
+		}
+
+		// แต่ละไฟล์รันใน transaction เดียวกับการบันทึก version จึงไม่ค้างครึ่งทาง
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return applied, fmt.Errorf("failed to begin migration %s: %v", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %v", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UnixNano()); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("failed to record migration %s: %v", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, fmt.Errorf("failed to commit migration %s: %v", version, err)
+		}
+		applied = append(applied, version)
+	}
+
+	return applied, nil
+}
+
+// lastPattern caches the most recent SearchMessages pattern; การค้นหาหนึ่งครั้งเรียก regexp ทุกแถวด้วย pattern เดียวกัน
+var lastPattern struct {
+	sync.Mutex
+	source   string
+	compiled *regexp.Regexp
+}
+
+// regexpFunc implements "content REGEXP pattern" as a case-insensitive match, like MongoDB's $regex with "i"
+func regexpFunc(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	pattern, _ := args[0].(string)
+	value, _ := args[1].(string)
+
+	lastPattern.Lock()
+	compiled := lastPattern.compiled
+	if compiled == nil || lastPattern.source != pattern {
+		var err error
+		if compiled, err = regexp.Compile("(?i)" + pattern); err != nil {
+			lastPattern.Unlock()
+			return nil, fmt.Errorf("invalid search pattern: %v", err)
+		}
+		lastPattern.source, lastPattern.compiled = pattern, compiled
+	}
+	lastPattern.Unlock()
+
+	if compiled.MatchString(value) {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+// nanos converts a time to the unix nanoseconds stored in the database
+func nanos(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+// fromNanos converts stored unix nanoseconds back to a time
+func fromNanos(n int64) time.Time {
+	return time.Unix(0, n)
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
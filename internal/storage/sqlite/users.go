@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	userPkg "realtime-chat/internal/user"
+)
+
+// userColumns is the column list scanned by scanUser
+const userColumns = `id, username, conn_id, current_room, joined_at, last_active, is_authenticated`
+
+// UserRepository implements user.Repository using SQLite
+type UserRepository struct {
+	db     *sql.DB
+	nodeID string
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row rowScanner) (*userPkg.User, error) {
+	var id, joinedAt, lastActive int64
+	user := &userPkg.User{}
+	if err := row.Scan(&id, &user.Username, &user.ConnID, &user.CurrentRoom, &joinedAt, &lastActive, &user.IsAuthenticated); err != nil {
+		return nil, err
+	}
+	user.ID = strconv.FormatInt(id, 10)
+	user.JoinedAt = fromNanos(joinedAt)
+	user.LastActive = fromNanos(lastActive)
+	return user, nil
+}
+
+// isUniqueViolation reports whether err comes from a UNIQUE constraint
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// Create creates a new user
+func (r *UserRepository) Create(connID, username string) (*userPkg.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (username, conn_id, joined_at, last_active, is_authenticated, node_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?)`,
+		username, connID, nanos(now), nanos(now), r.nodeID, nanos(now), nanos(now))
+	if err != nil {
+		// unique index ของ username ตัดสินแทนการเช็คก่อน insert จึงไม่มี race
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("username '%s' is already taken", username)
+		}
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+	id, _ := result.LastInsertId()
+
+	return &userPkg.User{
+		ID:              strconv.FormatInt(id, 10),
+		Username:        username,
+		ConnID:          connID,
+		JoinedAt:        now,
+		LastActive:      now,
+		IsAuthenticated: true,
+	}, nil
+}
+
+// GetByID gets a user by connection ID
+func (r *UserRepository) GetByID(connID string) (*userPkg.User, bool) {
+	return r.getOne(`SELECT `+userColumns+` FROM users WHERE conn_id = ?`, connID)
+}
+
+// GetByUsername gets a user by username
+func (r *UserRepository) GetByUsername(username string) (*userPkg.User, bool) {
+	return r.getOne(`SELECT `+userColumns+` FROM users WHERE username = ?`, username)
+}
+
+// getOne runs a query selecting at most one user
+func (r *UserRepository) getOne(query string, arg interface{}) (*userPkg.User, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		return nil, false
+	}
+	return user, true
+}
+
+// IsUsernameAvailable checks if a username is available
+func (r *UserRepository) IsUsernameAvailable(username string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = ?)`, username).Scan(&exists); err != nil {
+		return false
+	}
+	return !exists
+}
+
+// GetAll returns all users
+func (r *UserRepository) GetAll() []*userPkg.User {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY id`)
+	if err != nil {
+		return []*userPkg.User{}
+	}
+	defer rows.Close()
+
+	users := []*userPkg.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// UpdateLastActive updates user's last active time
+func (r *UserRepository) UpdateLastActive(connID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := nanos(time.Now())
+	r.db.ExecContext(ctx, `UPDATE users SET last_active = ?, updated_at = ? WHERE conn_id = ?`, now, now, connID)
+}
+
+// Delete removes a user
+func (r *UserRepository) Delete(connID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE conn_id = ?`, connID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return userPkg.ErrUserNotFound
+	}
+	return nil
+}
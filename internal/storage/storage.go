@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/config"
+	"realtime-chat/internal/database"
+	messagePkg "realtime-chat/internal/message"
+	"realtime-chat/internal/room"
+	userPkg "realtime-chat/internal/user"
+)
+
+// Storage backends selectable with ServerConfig.StorageBackend
+const (
+	BackendMemory   = "memory"
+	BackendMongoDB  = "mongodb"
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+)
+
+// Provider is the single set of repositories every storage backend offers.
+// chat, api และ services ใช้ interface ของแต่ละ domain (user/room/message.Repository) ผ่าน provider นี้
+// จึงสลับ backend ได้โดยไม่ต้องแก้โค้ดส่วนอื่น
+type Provider interface {
+	Name() string
+	Users() userPkg.Repository
+	Rooms() room.Repository
+	Messages() messagePkg.Repository // nil = backend ไม่เก็บประวัติข้อความ
+	Profiles() userPkg.ProfileRepository
+	Accounts() account.Repository
+	Close() error
+}
+
+// Backend returns the storage backend selected by the config.
+// storage_backend ว่างหมายถึงใช้ enable_mongodb แบบเดิม (mongodb หรือ memory)
+func Backend(cfg *config.ServerConfig) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(cfg.StorageBackend)); backend {
+	case "":
+		if cfg.EnableMongoDB {
+			return BackendMongoDB, nil
+		}
+		return BackendMemory, nil
+	case BackendMemory, BackendMongoDB:
+		return backend, nil
+	case BackendPostgres, "postgresql":
+		if cfg.PostgresDSN == "" {
+			return backend, fmt.Errorf("storage backend postgres requires postgres_dsn (CHAT_POSTGRES_DSN)")
+		}
+		return BackendPostgres, nil
+	case BackendSQLite, "sqlite3":
+		if cfg.SQLitePath == "" {
+			return backend, fmt.Errorf("storage backend sqlite requires sqlite_path (CHAT_SQLITE_PATH)")
+		}
+		return BackendSQLite, nil
+	default:
+		return backend, fmt.Errorf("unknown storage backend %q (use memory, mongodb, postgres or sqlite)", cfg.StorageBackend)
+	}
+}
+
+// MongoOnlyFeatures lists the enabled features whose repositories exist only on MongoDB.
+// backend อื่นไม่มี direct message, thread, timeline, read receipt, mailbox และ notification;
+// main และ self-test ใช้รายการนี้เตือนตอนเริ่มแทนที่จะปล่อยให้ feature หายไปเงียบ ๆ
+func MongoOnlyFeatures(cfg *config.ServerConfig) []string {
+	features := []string{"direct messages", "threads", "room timeline", "read receipts"}
+	if cfg.EnableOfflineMailbox {
+		features = append(features, "offline mailbox")
+	}
+	if cfg.EnableNotifications {
+		features = append(features, "notifications")
+	}
+	return features
+}
+
+// MemoryProvider keeps users, rooms, profiles and accounts in process memory; messages are not persisted
+type MemoryProvider struct {
+	users    *userPkg.InMemoryRepository
+	rooms    *room.InMemoryRepository
+	profiles *userPkg.InMemoryProfileRepository
+	accounts *account.InMemoryRepository
+}
+
+// NewMemory creates the in-memory storage backend
+func NewMemory() *MemoryProvider {
+	return &MemoryProvider{
+		users:    userPkg.NewInMemoryRepository(),
+		rooms:    room.NewInMemoryRepository(),
+		profiles: userPkg.NewInMemoryProfileRepository(),
+		accounts: account.NewInMemoryRepository(),
+	}
+}
+
+// Name returns the backend name
+func (p *MemoryProvider) Name() string { return BackendMemory }
+
+// Users returns the user repository
+func (p *MemoryProvider) Users() userPkg.Repository { return p.users }
+
+// Rooms returns the room repository
+func (p *MemoryProvider) Rooms() room.Repository { return p.rooms }
+
+// RoomStore returns the concrete room repository (ใช้เปิด hibernation)
+func (p *MemoryProvider) RoomStore() *room.InMemoryRepository { return p.rooms }
+
+// Messages returns nil: the in-memory backend keeps no message history
+func (p *MemoryProvider) Messages() messagePkg.Repository { return nil }
+
+// Profiles returns the profile repository (profiles last until restart)
+func (p *MemoryProvider) Profiles() userPkg.ProfileRepository { return p.profiles }
+
+// Accounts returns the account repository (accounts last until restart)
+func (p *MemoryProvider) Accounts() account.Repository { return p.accounts }
+
+// Close does nothing for the in-memory backend
+func (p *MemoryProvider) Close() error { return nil }
+
+// MongoProvider stores users, rooms and messages in MongoDB.
+// user, room, profile และ account repository ผ่าน circuit breaker ของ db ให้ตอบทันทีระหว่างที่ฐานข้อมูลล่ม;
+// message repository ถูกห่อใน main ด้วย message.ResilientRepository ซึ่ง buffer ข้อความไว้ด้วย
+type MongoProvider struct {
+	db        *database.MongoDB
+	userStore *userPkg.MongoRepository
+	users     userPkg.Repository
+	rooms     room.Repository
+	messages  messagePkg.Repository
+	profiles  userPkg.ProfileRepository
+	accounts  account.Repository
+}
+
+// NewMongo creates the MongoDB storage backend on an open connection
+func NewMongo(db *database.MongoDB, nodeID string) *MongoProvider {
+	users := userPkg.NewMongoRepository(db)
+	users.SetNodeID(nodeID)
+	breaker := db.Breaker()
+	return &MongoProvider{
+		db:        db,
+		userStore: users,
+		users:     userPkg.NewResilientRepository(users, breaker),
+		rooms:     room.NewResilientRepository(room.NewMongoRepository(db), breaker),
+		messages:  messagePkg.NewMongoRepository(db),
+		profiles:  userPkg.NewResilientProfileRepository(userPkg.NewMongoProfileRepository(db), breaker),
+		accounts:  account.NewResilientRepository(account.NewMongoRepository(db), breaker),
+	}
+}
+
+// Name returns the backend name
+func (p *MongoProvider) Name() string { return BackendMongoDB }
+
+// Users returns the user repository
+func (p *MongoProvider) Users() userPkg.Repository { return p.users }
+
+// UserStore returns the concrete user repository (ใช้กับ user.Cleaner)
+func (p *MongoProvider) UserStore() *userPkg.MongoRepository { return p.userStore }
+
+// Rooms returns the room repository
+func (p *MongoProvider) Rooms() room.Repository { return p.rooms }
+
+// Messages returns the message repository
+func (p *MongoProvider) Messages() messagePkg.Repository { return p.messages }
+
+// Profiles returns the profile repository
+func (p *MongoProvider) Profiles() userPkg.ProfileRepository { return p.profiles }
+
+// Accounts returns the account repository
+func (p *MongoProvider) Accounts() account.Repository { return p.accounts }
+
+// Close disconnects from MongoDB
+func (p *MongoProvider) Close() error { return p.db.Close() }
@@ -0,0 +1,283 @@
+package transfer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transfer states
+const (
+	StatePending   = "pending"  // รอผู้รับตอบรับ
+	StateAccepted  = "accepted" // กำลังส่ง chunk
+	StateDeclined  = "declined"
+	StateCancelled = "cancelled"
+	StateCompleted = "completed"
+)
+
+var (
+	ErrNotFound    = errors.New("file transfer not found")
+	ErrWindowFull  = errors.New("too many unacknowledged chunks")
+	ErrOutOfOrder  = errors.New("chunk out of order")
+	ErrNotAllowed  = errors.New("not allowed for this transfer")
+	ErrInvalidFile = errors.New("invalid file")
+)
+
+// FileInfo is the metadata a sender offers before any bytes are sent
+type FileInfo struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// Offer is a snapshot of a file transfer between two users
+type Offer struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	File        FileInfo  `json:"file"`
+	State       string    `json:"state"`
+	Transferred int64     `json:"transferred"` // bytes ที่ผู้รับ ack แล้ว
+	ChunkSize   int       `json:"chunk_size"`
+	Window      int       `json:"window"` // จำนวน chunk ที่ส่งค้างได้โดยยังไม่ได้ ack
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// transfer is the mutable state behind an Offer
+type transfer struct {
+	offer    Offer
+	nextSeq  int           // seq ที่ผู้ส่งต้องส่งถัดไป
+	inFlight map[int]int64 // seq -> bytes ที่ relay แล้วแต่ยังไม่ ack
+	sent     int64         // bytes ที่ relay แล้วทั้งหมด
+	touched  time.Time
+}
+
+// Manager relays negotiated file transfers between users, enforcing size limits and flow control.
+// server ไม่เก็บไฟล์ไว้เอง แค่ส่ง chunk ต่อจากผู้ส่งไปผู้รับทีละ window
+type Manager struct {
+	transfers   map[string]*transfer
+	maxSize     int64
+	chunkSize   int
+	window      int
+	idleTimeout time.Duration
+	mutex       sync.Mutex
+}
+
+// NewManager creates a new file transfer manager
+func NewManager(maxSize int64, chunkSize, window int, idleTimeout time.Duration) *Manager {
+	if chunkSize <= 0 {
+		chunkSize = 32 * 1024
+	}
+	if window <= 0 {
+		window = 8
+	}
+	return &Manager{
+		transfers:   make(map[string]*transfer),
+		maxSize:     maxSize,
+		chunkSize:   chunkSize,
+		window:      window,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// ChunkSize returns the largest chunk payload (decoded bytes) accepted from senders
+func (m *Manager) ChunkSize() int {
+	return m.chunkSize
+}
+
+// Offer creates a pending transfer from one user to another
+func (m *Manager) Offer(from, to string, file FileInfo) (Offer, error) {
+	file.Name = strings.TrimSpace(file.Name)
+	if file.Name == "" || len(file.Name) > 255 || strings.ContainsAny(file.Name, "/\\") {
+		return Offer{}, fmt.Errorf("%w: file name required (no path separators, max 255 characters)", ErrInvalidFile)
+	}
+	if file.Size <= 0 {
+		return Offer{}, fmt.Errorf("%w: file size required", ErrInvalidFile)
+	}
+	if m.maxSize > 0 && file.Size > m.maxSize {
+		return Offer{}, fmt.Errorf("%w: file too large (max %d bytes)", ErrInvalidFile, m.maxSize)
+	}
+
+	now := time.Now()
+	t := &transfer{
+		offer: Offer{
+			ID:        newTransferID(),
+			From:      from,
+			To:        to,
+			File:      file,
+			State:     StatePending,
+			ChunkSize: m.chunkSize,
+			Window:    m.window,
+			CreatedAt: now,
+		},
+		inFlight: make(map[int]int64),
+		touched:  now,
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.transfers[t.offer.ID] = t
+	return t.offer, nil
+}
+
+// Accept lets the recipient start a pending transfer
+func (m *Manager) Accept(id, username string) (Offer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.find(id, username)
+	if err != nil {
+		return Offer{}, err
+	}
+	if t.offer.To != username || t.offer.State != StatePending {
+		return Offer{}, ErrNotAllowed
+	}
+	t.offer.State = StateAccepted
+	t.touched = time.Now()
+	return t.offer, nil
+}
+
+// Decline lets the recipient refuse a pending transfer
+func (m *Manager) Decline(id, username string) (Offer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.find(id, username)
+	if err != nil {
+		return Offer{}, err
+	}
+	if t.offer.To != username || t.offer.State != StatePending {
+		return Offer{}, ErrNotAllowed
+	}
+	t.offer.State = StateDeclined
+	delete(m.transfers, id)
+	return t.offer, nil
+}
+
+// Cancel stops a pending or running transfer on behalf of either party
+func (m *Manager) Cancel(id, username string) (Offer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.find(id, username)
+	if err != nil {
+		return Offer{}, err
+	}
+	t.offer.State = StateCancelled
+	delete(m.transfers, id)
+	return t.offer, nil
+}
+
+// Chunk admits the next chunk from the sender, enforcing order, chunk size, the declared file size and the window
+func (m *Manager) Chunk(id, username string, seq int, size int) (Offer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.find(id, username)
+	if err != nil {
+		return Offer{}, err
+	}
+	if t.offer.From != username || t.offer.State != StateAccepted {
+		return Offer{}, ErrNotAllowed
+	}
+	if seq != t.nextSeq {
+		return Offer{}, fmt.Errorf("%w: expected seq %d", ErrOutOfOrder, t.nextSeq)
+	}
+	if size <= 0 || size > m.chunkSize {
+		return Offer{}, fmt.Errorf("%w: chunk must be 1-%d bytes", ErrInvalidFile, m.chunkSize)
+	}
+	if t.sent+int64(size) > t.offer.File.Size {
+		return Offer{}, fmt.Errorf("%w: chunk exceeds the offered file size", ErrInvalidFile)
+	}
+	if len(t.inFlight) >= m.window {
+		return Offer{}, ErrWindowFull
+	}
+
+	t.inFlight[seq] = int64(size)
+	t.sent += int64(size)
+	t.nextSeq++
+	t.touched = time.Now()
+	return t.offer, nil
+}
+
+// Ack records that the recipient received a chunk; completed is true once every byte is acknowledged
+func (m *Manager) Ack(id, username string, seq int) (offer Offer, completed bool, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	t, err := m.find(id, username)
+	if err != nil {
+		return Offer{}, false, err
+	}
+	if t.offer.To != username || t.offer.State != StateAccepted {
+		return Offer{}, false, ErrNotAllowed
+	}
+	size, exists := t.inFlight[seq]
+	if !exists {
+		return Offer{}, false, fmt.Errorf("%w: chunk %d is not awaiting an ack", ErrOutOfOrder, seq)
+	}
+
+	delete(t.inFlight, seq)
+	t.offer.Transferred += size
+	t.touched = time.Now()
+	if t.offer.Transferred == t.offer.File.Size {
+		t.offer.State = StateCompleted
+		delete(m.transfers, id)
+		return t.offer, true, nil
+	}
+	return t.offer, false, nil
+}
+
+// CancelUser cancels every transfer the user takes part in, e.g. when they disconnect
+func (m *Manager) CancelUser(username string) []Offer {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var cancelled []Offer
+	for id, t := range m.transfers {
+		if t.offer.From == username || t.offer.To == username {
+			t.offer.State = StateCancelled
+			cancelled = append(cancelled, t.offer)
+			delete(m.transfers, id)
+		}
+	}
+	return cancelled
+}
+
+// Reap drops transfers with no activity within the idle timeout
+func (m *Manager) Reap(aggressive bool) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cutoff := time.Now().Add(-m.idleTimeout)
+	reaped := 0
+	for id, t := range m.transfers {
+		if t.touched.Before(cutoff) {
+			delete(m.transfers, id)
+			reaped++
+		}
+	}
+	return reaped
+}
+
+// find returns a transfer the user takes part in; others get ErrNotFound so IDs cannot be probed
+func (m *Manager) find(id, username string) (*transfer, error) {
+	t, exists := m.transfers[id]
+	if !exists || (t.offer.From != username && t.offer.To != username) {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// newTransferID creates a random transfer ID
+func newTransferID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
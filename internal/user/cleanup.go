@@ -0,0 +1,275 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"realtime-chat/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// staleGrace keeps the sweep away from users registered moments ago whose connection may not be visible yet
+const staleGrace = time.Minute
+
+// StaleUserStore is a user store that can be left with users of closed connections when a delete fails
+type StaleUserStore interface {
+	Delete(connID string) error
+	Heartbeat(nodeID string) error
+	DeleteStale(nodeID string, isLive func(connID string) bool, grace, nodeTimeout time.Duration) (int, error)
+}
+
+// DeadLetter is a user delete that kept failing and is left to the periodic sweep
+type DeadLetter struct {
+	ConnID    string    `json:"conn_id"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// CleanupStats holds stale user cleanup counters
+type CleanupStats struct {
+	Pending     int          `json:"pending"`
+	Retried     int64        `json:"retried"`
+	Swept       int64        `json:"swept"`
+	LastSweep   time.Time    `json:"last_sweep"`
+	DeadLetters []DeadLetter `json:"dead_letters"`
+}
+
+// cleanupEntry is a failed delete waiting to be retried
+type cleanupEntry struct {
+	attempts    int
+	nextAttempt time.Time
+	lastError   string
+}
+
+// Cleaner retries user deletes that failed on disconnect and sweeps users whose connection is gone.
+// ถ้าลบไม่สำเร็จ (เช่น DB timeout) ชื่อผู้ใช้จะถูกจองค้างไว้ตลอดไป จึงต้อง retry แบบ backoff
+// และถ้ายังไม่สำเร็จให้ sweep เป็นระยะลบเอกสารที่ไม่มี connection อยู่จริงบน node ใดเลย
+type Cleaner struct {
+	store       StaleUserStore
+	nodeID      string
+	isLive      func(connID string) bool
+	config      *config.ServerConfig
+	pending     map[string]*cleanupEntry // connID -> entry
+	deadLetters map[string]*DeadLetter   // connID -> dead letter
+	retried     int64
+	swept       int64
+	lastSweep   time.Time
+	mutex       sync.Mutex
+}
+
+// NewCleaner creates a stale user cleaner; isLive reports whether a connection exists on this node
+func NewCleaner(store StaleUserStore, nodeID string, isLive func(connID string) bool, cfg *config.ServerConfig) *Cleaner {
+	return &Cleaner{
+		store:       store,
+		nodeID:      nodeID,
+		isLive:      isLive,
+		config:      cfg,
+		pending:     make(map[string]*cleanupEntry),
+		deadLetters: make(map[string]*DeadLetter),
+	}
+}
+
+// Enqueue schedules a failed delete for retry
+func (c *Cleaner) Enqueue(connID string, cause error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.pending[connID]; exists {
+		return
+	}
+	c.pending[connID] = &cleanupEntry{
+		nextAttempt: time.Now().Add(c.config.UserCleanupBackoff),
+		lastError:   cause.Error(),
+	}
+	slog.Warn("🧹 User delete queued for retry", "conn_id", connID, "error", cause)
+}
+
+// RetryPending retries queued deletes that are due; deletes failing UserCleanupMaxAttempts times become dead letters
+func (c *Cleaner) RetryPending() {
+	c.mutex.Lock()
+	now := time.Now()
+	due := make([]string, 0)
+	for connID, entry := range c.pending {
+		if !now.Before(entry.nextAttempt) {
+			due = append(due, connID)
+		}
+	}
+	c.mutex.Unlock()
+
+	// ลบนอก lock เพราะแต่ละครั้งอาจรอ DB นาน
+	for _, connID := range due {
+		err := c.store.Delete(connID)
+
+		c.mutex.Lock()
+		entry, exists := c.pending[connID]
+		if !exists {
+			c.mutex.Unlock()
+			continue
+		}
+		c.retried++
+		if err == nil || err == ErrUserNotFound {
+			delete(c.pending, connID)
+			c.mutex.Unlock()
+			slog.Info("🧹 Deleted user of closed connection", "conn_id", connID, "retries", entry.attempts+1)
+			continue
+		}
+
+		entry.attempts++
+		entry.lastError = err.Error()
+		if entry.attempts >= c.config.UserCleanupMaxAttempts {
+			delete(c.pending, connID)
+			c.deadLetters[connID] = &DeadLetter{
+				ConnID:    connID,
+				Attempts:  entry.attempts,
+				LastError: entry.lastError,
+				FailedAt:  time.Now(),
+			}
+			slog.Error("☠️ Giving up deleting user, leaving it to the sweep", "conn_id", connID, "attempts", entry.attempts, "error", err)
+		} else {
+			entry.nextAttempt = time.Now().Add(c.backoff(entry.attempts))
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// Sweep records this node's heartbeat and deletes users whose connection no longer exists on any node
+func (c *Cleaner) Sweep() {
+	if err := c.store.Heartbeat(c.nodeID); err != nil {
+		slog.Warn("⚠️ Failed to record node heartbeat", "error", err)
+		return
+	}
+
+	count, err := c.store.DeleteStale(c.nodeID, c.isLive, staleGrace, c.config.NodeHeartbeatTimeout)
+	if err != nil {
+		slog.Warn("⚠️ Stale user sweep failed", "error", err)
+		return
+	}
+
+	c.mutex.Lock()
+	c.swept += int64(count)
+	c.lastSweep = time.Now()
+	// sweep ครอบคลุมเอกสารของ connection ที่ปิดไปแล้วทั้งหมดของ node นี้ dead letter จึงถูกจัดการแล้ว
+	for connID := range c.deadLetters {
+		if !c.isLive(connID) {
+			delete(c.deadLetters, connID)
+		}
+	}
+	c.mutex.Unlock()
+
+	if count > 0 {
+		slog.Info("🧹 Swept stale users", "count", count)
+	}
+}
+
+// Stats returns cleanup counters and the current dead letters, oldest first
+func (c *Cleaner) Stats() CleanupStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := CleanupStats{
+		Pending:     len(c.pending),
+		Retried:     c.retried,
+		Swept:       c.swept,
+		LastSweep:   c.lastSweep,
+		DeadLetters: make([]DeadLetter, 0, len(c.deadLetters)),
+	}
+	for _, letter := range c.deadLetters {
+		stats.DeadLetters = append(stats.DeadLetters, *letter)
+	}
+	sort.Slice(stats.DeadLetters, func(i, j int) bool {
+		return stats.DeadLetters[i].FailedAt.Before(stats.DeadLetters[j].FailedAt)
+	})
+	return stats
+}
+
+// backoff returns the exponential delay before the next retry (assumes lock is held)
+func (c *Cleaner) backoff(attempts int) time.Duration {
+	delay := c.config.UserCleanupBackoff
+	for i := 0; i < attempts && delay < 5*time.Minute; i++ {
+		delay *= 2
+	}
+	if delay > 5*time.Minute {
+		delay = 5 * time.Minute
+	}
+	return delay
+}
+
+// Heartbeat records that a node is alive so other nodes keep its users during sweeps
+func (r *MongoRepository) Heartbeat(nodeID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.heartbeats.UpdateOne(ctx,
+		bson.M{"_id": nodeID},
+		bson.M{"$set": bson.M{"seen_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %v", err)
+	}
+	return nil
+}
+
+// DeleteStale deletes users older than grace that belong to this node but have no live connection,
+// or belong to a node without a heartbeat within nodeTimeout (including users saved before node_id existed)
+func (r *MongoRepository) DeleteStale(nodeID string, isLive func(connID string) bool, grace, nodeTimeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	joinedBefore := bson.M{"$lt": now.Add(-grace)}
+
+	// ผู้ใช้ของ node นี้ที่ connection ปิดไปแล้ว
+	cursor, err := r.collection.Find(ctx, bson.M{"node_id": nodeID, "joined_at": joinedBefore},
+		options.Find().SetProjection(bson.M{"conn_id": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users: %v", err)
+	}
+	var docs []UserDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, fmt.Errorf("failed to decode users: %v", err)
+	}
+	stale := make([]string, 0)
+	for _, doc := range docs {
+		if !isLive(doc.ConnID) {
+			stale = append(stale, doc.ConnID)
+		}
+	}
+
+	deleted := 0
+	if len(stale) > 0 {
+		result, err := r.collection.DeleteMany(ctx, bson.M{"node_id": nodeID, "conn_id": bson.M{"$in": stale}})
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete stale users: %v", err)
+		}
+		deleted += int(result.DeletedCount)
+	}
+
+	// ผู้ใช้ของ node ที่หยุดส่ง heartbeat (เช่น crash) ไม่มี connection เหลืออยู่แน่นอน
+	cursor, err = r.heartbeats.Find(ctx, bson.M{"seen_at": bson.M{"$gte": now.Add(-nodeTimeout)}})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to list node heartbeats: %v", err)
+	}
+	var heartbeats []struct {
+		NodeID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &heartbeats); err != nil {
+		return deleted, fmt.Errorf("failed to decode node heartbeats: %v", err)
+	}
+	liveNodes := []string{nodeID}
+	for _, heartbeat := range heartbeats {
+		liveNodes = append(liveNodes, heartbeat.NodeID)
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"node_id": bson.M{"$nin": liveNodes}, "joined_at": joinedBefore})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete users of dead nodes: %v", err)
+	}
+	return deleted + int(result.DeletedCount), nil
+}
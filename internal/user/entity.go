@@ -1,6 +1,7 @@
 package user
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -13,6 +14,14 @@ type User struct {
 	JoinedAt        time.Time `json:"joined_at"`
 	LastActive      time.Time `json:"last_active"`
 	IsAuthenticated bool      `json:"is_authenticated"`
+	Timezone        string    `json:"timezone,omitempty"`     // IANA timezone สำหรับแสดงเวลาใน output ของคำสั่ง
+	AccountID       string    `json:"account_id,omitempty"`   // ตัวตนที่คงที่ข้าม session (ดู AccountID)
+	Guest           bool      `json:"guest,omitempty"`        // เข้ามาโดยไม่มี account session ชื่อจึงไม่ใช่ตัวตนที่คงที่
+	DisplayName     string    `json:"display_name,omitempty"` // profile ที่โหลดตอน register (ดู ProfileStore)
+	AvatarURL       string    `json:"avatar_url,omitempty"`
+	StatusText      string    `json:"status_text,omitempty"`
+
+	location *time.Location
 }
 
 // GetIsAuthenticated returns the authentication status
@@ -28,4 +37,36 @@ func (u *User) GetUsername() string {
 // GetCurrentRoom returns the current room
 func (u *User) GetCurrentRoom() string {
 	return u.CurrentRoom
-}
\ No newline at end of file
+}
+// SetTimezone sets the IANA timezone (e.g. "Asia/Bangkok") used to format times for the user;
+// an empty name resets to server time
+func (u *User) SetTimezone(name string) error {
+	if name == "" {
+		u.Timezone = ""
+		u.location = nil
+		return nil
+	}
+
+	// "Local" คือเวลาของ server ซึ่งเป็นค่าเริ่มต้นอยู่แล้ว
+	if name == "Local" {
+		return fmt.Errorf("unknown timezone '%s'", name)
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown timezone '%s'", name)
+	}
+
+	u.Timezone = location.String()
+	u.location = location
+	return nil
+}
+
+// FormatTime formats t in the user's timezone (server time when none is set).
+// แปลงด้วย time.Location จึงรองรับช่วงเปลี่ยน DST ได้ถูกต้อง
+func (u *User) FormatTime(t time.Time, layout string) string {
+	if u.location == nil {
+		return t.Format(layout)
+	}
+	return t.In(u.location).Format(layout)
+}
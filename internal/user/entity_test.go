@@ -0,0 +1,96 @@
+package user
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // ไม่พึ่ง zoneinfo ของเครื่องที่รัน test
+)
+
+// เวลาใน output ของคำสั่งต้องตรงกับนาฬิกาของผู้ใช้ทั้งก่อนและหลังเปลี่ยน DST
+func TestFormatTimeAcrossDST(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		at       time.Time
+		want     string
+	}{
+		{"new york before spring forward", "America/New_York", time.Date(2026, 3, 8, 6, 59, 0, 0, time.UTC), "2026-03-08 01:59 EST"},
+		{"new york after spring forward", "America/New_York", time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC), "2026-03-08 03:00 EDT"},
+		{"new york first 01:30 on fall back", "America/New_York", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), "2026-11-01 01:30 EDT"},
+		{"new york second 01:30 on fall back", "America/New_York", time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), "2026-11-01 01:30 EST"},
+		{"london after spring forward", "Europe/London", time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC), "2026-03-29 02:00 BST"},
+		{"london after fall back", "Europe/London", time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC), "2026-10-25 01:00 GMT"},
+		{"sydney after fall back (southern hemisphere)", "Australia/Sydney", time.Date(2026, 4, 4, 16, 0, 0, 0, time.UTC), "2026-04-05 02:00 AEST"},
+		{"bangkok has no DST", "Asia/Bangkok", time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC), "2026-03-08 14:00 +07"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{}
+			if err := u.SetTimezone(tt.timezone); err != nil {
+				t.Fatalf("SetTimezone(%q): %v", tt.timezone, err)
+			}
+			if got := u.FormatTime(tt.at, "2006-01-02 15:04 MST"); got != tt.want {
+				t.Fatalf("FormatTime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// snooze เป็น duration จริง ช่วงที่ข้าม DST จึงแสดงเวลาสิ้นสุดตามนาฬิกาของผู้ใช้ที่ขยับไปแล้ว
+func TestFormatTimeOfDurationSpanningDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		start    time.Time
+		duration time.Duration
+		want     string
+	}{
+		{"8h across spring forward", time.Date(2026, 3, 7, 23, 0, 0, 0, newYork), 8 * time.Hour, "08:00 EDT"},
+		{"8h across fall back", time.Date(2026, 10, 31, 23, 0, 0, 0, newYork), 8 * time.Hour, "06:00 EST"},
+		{"8h without transition", time.Date(2026, 6, 1, 23, 0, 0, 0, newYork), 8 * time.Hour, "07:00 EDT"},
+	}
+
+	u := &User{}
+	if err := u.SetTimezone("America/New_York"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// เวลาเริ่มมาจาก server ที่อยู่ timezone อื่น
+			until := tt.start.UTC().Add(tt.duration)
+			if got := u.FormatTime(until, "15:04 MST"); got != tt.want {
+				t.Fatalf("FormatTime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    string
+		want    string
+		wantErr bool
+	}{
+		{"iana zone", "Europe/Berlin", "Europe/Berlin", false},
+		{"reset", "", "", false},
+		{"server local is not a user zone", "Local", "", true},
+		{"unknown", "Mars/Olympus", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{}
+			err := u.SetTimezone(tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTimezone(%q) error = %v, wantErr %v", tt.zone, err, tt.wantErr)
+			}
+			if u.Timezone != tt.want {
+				t.Fatalf("Timezone = %q, want %q", u.Timezone, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"realtime-chat/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ProfileDocument represents a profile document in MongoDB; _id is the account ID
+type ProfileDocument struct {
+	AccountID   string    `bson:"_id" json:"account_id"`
+	DisplayName string    `bson:"display_name" json:"display_name"`
+	AvatarURL   string    `bson:"avatar_url" json:"avatar_url"`
+	StatusText  string    `bson:"status_text" json:"status_text"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// MongoProfileRepository implements ProfileRepository using MongoDB.
+// แยกจาก collection users เพราะเอกสารใน users ถูกลบเมื่อ disconnect
+type MongoProfileRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoProfileRepository creates a new MongoDB profile repository
+func NewMongoProfileRepository(db *database.MongoDB) *MongoProfileRepository {
+	return &MongoProfileRepository{
+		collection: db.GetCollection("profiles"),
+	}
+}
+
+// GetProfile returns the account's profile
+func (r *MongoProfileRepository) GetProfile(accountID string) (*Profile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var doc ProfileDocument
+	if err := r.collection.FindOne(ctx, bson.M{"_id": accountID}).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get profile: %v", err)
+	}
+
+	return &Profile{
+		AccountID:   doc.AccountID,
+		DisplayName: doc.DisplayName,
+		AvatarURL:   doc.AvatarURL,
+		StatusText:  doc.StatusText,
+		UpdatedAt:   doc.UpdatedAt,
+	}, nil
+}
+
+// SaveProfile creates or replaces the account's profile
+func (r *MongoProfileRepository) SaveProfile(profile *Profile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	doc := ProfileDocument{
+		AccountID:   profile.AccountID,
+		DisplayName: profile.DisplayName,
+		AvatarURL:   profile.AvatarURL,
+		StatusText:  profile.StatusText,
+		UpdatedAt:   profile.UpdatedAt,
+	}
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": profile.AccountID}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %v", err)
+	}
+	return nil
+}
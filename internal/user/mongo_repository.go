@@ -21,6 +21,11 @@ type UserDocument struct {
 	JoinedAt        time.Time          `bson:"joined_at" json:"joined_at"`
 	LastActive      time.Time          `bson:"last_active" json:"last_active"`
 	IsAuthenticated bool               `bson:"is_authenticated" json:"is_authenticated"`
+	NodeID          string             `bson:"node_id,omitempty" json:"node_id,omitempty"` // node ที่ถือ connection ใช้ตอน sweep ผู้ใช้ค้าง
+	AccountID       string             `bson:"account_id,omitempty" json:"account_id,omitempty"`
+	DisplayName     string             `bson:"display_name,omitempty" json:"display_name,omitempty"` // สำเนาจาก collection profiles
+	AvatarURL       string             `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"`
+	StatusText      string             `bson:"status_text,omitempty" json:"status_text,omitempty"`
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -35,6 +40,10 @@ func (doc *UserDocument) ToUser() *User {
 		JoinedAt:        doc.JoinedAt,
 		LastActive:      doc.LastActive,
 		IsAuthenticated: doc.IsAuthenticated,
+		AccountID:       doc.AccountID,
+		DisplayName:     doc.DisplayName,
+		AvatarURL:       doc.AvatarURL,
+		StatusText:      doc.StatusText,
 	}
 }
 
@@ -46,6 +55,10 @@ func (doc *UserDocument) FromUser(user *User) {
 	doc.JoinedAt = user.JoinedAt
 	doc.LastActive = user.LastActive
 	doc.IsAuthenticated = user.IsAuthenticated
+	doc.AccountID = user.AccountID
+	doc.DisplayName = user.DisplayName
+	doc.AvatarURL = user.AvatarURL
+	doc.StatusText = user.StatusText
 	doc.CreatedAt = time.Now()
 	doc.UpdatedAt = time.Now()
 
@@ -59,15 +72,23 @@ func (doc *UserDocument) FromUser(user *User) {
 // MongoRepository implements Repository using MongoDB
 type MongoRepository struct {
 	collection *mongo.Collection
+	heartbeats *mongo.Collection
+	nodeID     string
 }
 
 // NewMongoRepository creates a new MongoDB user repository
-func NewMongoRepository(db *database.MongoDB) Repository {
+func NewMongoRepository(db *database.MongoDB) *MongoRepository {
 	return &MongoRepository{
 		collection: db.GetCollection("users"),
+		heartbeats: db.GetCollection("node_heartbeats"),
 	}
 }
 
+// SetNodeID sets the node recorded on users created by this server
+func (r *MongoRepository) SetNodeID(nodeID string) {
+	r.nodeID = nodeID
+}
+
 // Create creates a new user
 func (r *MongoRepository) Create(connID, username string) (*User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -86,6 +107,8 @@ func (r *MongoRepository) Create(connID, username string) (*User, error) {
 		JoinedAt:        now,
 		LastActive:      now,
 		IsAuthenticated: true,
+		NodeID:          r.nodeID,
+		AccountID:       AccountID(username),
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
@@ -123,15 +146,7 @@ func (r *MongoRepository) GetByID(connID string) (*User, bool) {
 		return nil, false
 	}
 
-	user := &User{
-		ID:              userDoc.ID.Hex(),
-		Username:        userDoc.Username,
-		ConnID:          userDoc.ConnID,
-		CurrentRoom:     userDoc.CurrentRoom,
-		JoinedAt:        userDoc.JoinedAt,
-		LastActive:      userDoc.LastActive,
-		IsAuthenticated: userDoc.IsAuthenticated,
-	}
+	user := userDoc.ToUser()
 
 	return user, true
 }
@@ -150,15 +165,7 @@ func (r *MongoRepository) GetByUsername(username string) (*User, bool) {
 		return nil, false
 	}
 
-	user := &User{
-		ID:              userDoc.ID.Hex(),
-		Username:        userDoc.Username,
-		ConnID:          userDoc.ConnID,
-		CurrentRoom:     userDoc.CurrentRoom,
-		JoinedAt:        userDoc.JoinedAt,
-		LastActive:      userDoc.LastActive,
-		IsAuthenticated: userDoc.IsAuthenticated,
-	}
+	user := userDoc.ToUser()
 
 	return user, true
 }
@@ -194,15 +201,7 @@ func (r *MongoRepository) GetAll() []*User {
 			continue
 		}
 
-		user := &User{
-			ID:              userDoc.ID.Hex(),
-			Username:        userDoc.Username,
-			ConnID:          userDoc.ConnID,
-			CurrentRoom:     userDoc.CurrentRoom,
-			JoinedAt:        userDoc.JoinedAt,
-			LastActive:      userDoc.LastActive,
-			IsAuthenticated: userDoc.IsAuthenticated,
-		}
+		user := userDoc.ToUser()
 		users = append(users, user)
 	}
 
@@ -248,6 +247,27 @@ func (r *MongoRepository) UpdateCurrentRoom(connID, roomName string) error {
 	return nil
 }
 
+// UpdateProfile copies the account profile onto the user's session document
+func (r *MongoRepository) UpdateProfile(connID string, profile *Profile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"account_id":   profile.AccountID,
+			"display_name": profile.DisplayName,
+			"avatar_url":   profile.AvatarURL,
+			"status_text":  profile.StatusText,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"conn_id": connID}, update); err != nil {
+		return fmt.Errorf("failed to update user profile: %v", err)
+	}
+	return nil
+}
+
 // Delete removes a user
 func (r *MongoRepository) Delete(connID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -259,7 +279,7 @@ func (r *MongoRepository) Delete(connID string) error {
 	}
 
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -289,15 +309,7 @@ func (r *MongoRepository) GetActiveUsers(since time.Duration) []*User {
 			continue
 		}
 
-		user := &User{
-			ID:              userDoc.ID.Hex(),
-			Username:        userDoc.Username,
-			ConnID:          userDoc.ConnID,
-			CurrentRoom:     userDoc.CurrentRoom,
-			JoinedAt:        userDoc.JoinedAt,
-			LastActive:      userDoc.LastActive,
-			IsAuthenticated: userDoc.IsAuthenticated,
-		}
+		user := userDoc.ToUser()
 		users = append(users, user)
 	}
 
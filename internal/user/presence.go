@@ -0,0 +1,90 @@
+package user
+
+import (
+	"sync"
+	"time"
+)
+
+// Presence statuses that external systems may set
+var PresenceStatuses = map[string]bool{
+	"available": true,
+	"busy":      true,
+	"away":      true,
+	"dnd":       true,
+	"ooo":       true, // out of office
+}
+
+// Presence is a rich status pushed by an external system (e.g. "In a meeting until 15:00")
+type Presence struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	Source    string    `json:"source,omitempty"` // เช่น "calendar"
+	Until     time.Time `json:"until"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Expired reports whether the presence is no longer in effect
+func (p *Presence) Expired(now time.Time) bool {
+	return !now.Before(p.Until)
+}
+
+// PresenceStore keeps externally-set presence per username until it expires
+type PresenceStore struct {
+	entries map[string]*Presence
+	mutex   sync.RWMutex
+}
+
+// NewPresenceStore creates a new presence store
+func NewPresenceStore() *PresenceStore {
+	return &PresenceStore{
+		entries: make(map[string]*Presence),
+	}
+}
+
+// Set replaces the presence of a user
+func (s *PresenceStore) Set(username string, presence Presence) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	presence.UpdatedAt = time.Now()
+	s.entries[username] = &presence
+}
+
+// Clear removes the presence of a user, reporting whether one was set
+func (s *PresenceStore) Clear(username string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, exists := s.entries[username]
+	delete(s.entries, username)
+	return exists
+}
+
+// Get returns a copy of the user's presence if it hasn't expired
+func (s *PresenceStore) Get(username string) (*Presence, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	presence, exists := s.entries[username]
+	if !exists || presence.Expired(time.Now()) {
+		return nil, false
+	}
+	copied := *presence
+	return &copied, true
+}
+
+// Reap removes expired presence entries
+func (s *PresenceStore) Reap(aggressive bool) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for username, presence := range s.entries {
+		if presence.Expired(now) {
+			delete(s.entries, username)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,145 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile is what other users see about an account besides its username
+type Profile struct {
+	AccountID   string    `json:"account_id"`
+	DisplayName string    `json:"display_name,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	StatusText  string    `json:"status_text,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsEmpty reports whether no profile field is set
+func (p *Profile) IsEmpty() bool {
+	return p.DisplayName == "" && p.AvatarURL == "" && p.StatusText == ""
+}
+
+// ProfileRepository persists profiles across sessions, keyed by account ID
+type ProfileRepository interface {
+	GetProfile(accountID string) (*Profile, error)
+	SaveProfile(profile *Profile) error
+}
+
+// ErrProfileNotFound is returned when an account has never saved a profile
+var ErrProfileNotFound = errors.New("profile not found")
+
+// AccountID returns the stable account ID of a username.
+// ยังไม่มีระบบบัญชีแยก ชื่อผู้ใช้ (ไม่สนตัวพิมพ์) จึงเป็นตัวตน; hash เพื่อให้ ID คงที่และไม่ขึ้นกับ session หรือ backend
+func AccountID(username string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(username)))
+	return "acct_" + hex.EncodeToString(sum[:8])
+}
+
+// InMemoryProfileRepository implements ProfileRepository using in-memory storage
+type InMemoryProfileRepository struct {
+	profiles map[string]*Profile // accountID -> Profile
+	mutex    sync.RWMutex
+}
+
+// NewInMemoryProfileRepository creates a new in-memory profile repository
+func NewInMemoryProfileRepository() *InMemoryProfileRepository {
+	return &InMemoryProfileRepository{
+		profiles: make(map[string]*Profile),
+	}
+}
+
+// GetProfile returns a copy of the account's profile
+func (r *InMemoryProfileRepository) GetProfile(accountID string) (*Profile, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	profile, exists := r.profiles[accountID]
+	if !exists {
+		return nil, ErrProfileNotFound
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+// SaveProfile stores a copy of the profile
+func (r *InMemoryProfileRepository) SaveProfile(profile *Profile) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	copied := *profile
+	r.profiles[profile.AccountID] = &copied
+	return nil
+}
+
+// ProfileStore caches profiles in front of a ProfileRepository.
+// users_list และข้อความทุกข้อความอ่าน profile จึงไม่ควรถาม database ทุกครั้ง
+type ProfileStore struct {
+	repo  ProfileRepository
+	cache map[string]*Profile // accountID -> Profile (nil = ไม่มี profile)
+	mutex sync.RWMutex
+}
+
+// NewProfileStore creates a profile cache backed by repo
+func NewProfileStore(repo ProfileRepository) *ProfileStore {
+	return &ProfileStore{
+		repo:  repo,
+		cache: make(map[string]*Profile),
+	}
+}
+
+// Get returns a copy of the profile of a username, or nil when none was saved
+func (s *ProfileStore) Get(username string) *Profile {
+	if s == nil {
+		return nil
+	}
+	accountID := AccountID(username)
+
+	s.mutex.RLock()
+	profile, cached := s.cache[accountID]
+	s.mutex.RUnlock()
+	if !cached {
+		loaded, err := s.repo.GetProfile(accountID)
+		if err != nil && err != ErrProfileNotFound {
+			// database ล่ม: ไม่ cache เพื่อลองใหม่ครั้งหน้า
+			return nil
+		}
+		s.mutex.Lock()
+		s.cache[accountID] = loaded
+		s.mutex.Unlock()
+		profile = loaded
+	}
+
+	if profile == nil {
+		return nil
+	}
+	copied := *profile
+	return &copied
+}
+
+// Save persists a profile and updates the cache
+func (s *ProfileStore) Save(profile *Profile) error {
+	profile.UpdatedAt = time.Now()
+	if err := s.repo.SaveProfile(profile); err != nil {
+		return err
+	}
+
+	copied := *profile
+	s.mutex.Lock()
+	s.cache[profile.AccountID] = &copied
+	s.mutex.Unlock()
+	return nil
+}
+
+// applyProfile copies profile fields onto a user (nil clears them)
+func applyProfile(user *User, profile *Profile) {
+	if profile == nil {
+		profile = &Profile{}
+	}
+	user.DisplayName = profile.DisplayName
+	user.AvatarURL = profile.AvatarURL
+	user.StatusText = profile.StatusText
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -17,6 +18,9 @@ type Repository interface {
 	UpdateLastActive(connID string)
 }
 
+// ErrUserNotFound is returned when deleting a user that no longer exists
+var ErrUserNotFound = errors.New("user not found")
+
 // InMemoryRepository implements Repository using in-memory storage
 type InMemoryRepository struct {
 	users       map[string]*User // connID -> User
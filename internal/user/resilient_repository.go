@@ -0,0 +1,100 @@
+package user
+
+import "realtime-chat/internal/database"
+
+// ResilientRepository wraps a Repository with the database circuit breaker.
+// ระหว่างที่ breaker เปิด ทุก call ตอบทันทีแทนที่จะรอ timeout ของ MongoDB;
+// error ของ repository นี้แยกไม่ออกว่าเป็นความผิดของผู้ใช้ (ชื่อซ้ำ) หรือฐานข้อมูลล่ม จึงไม่ป้อนผลกลับเข้า breaker
+type ResilientRepository struct {
+	Repository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientRepository creates a user repository that fails fast during outages
+func NewResilientRepository(repo Repository, breaker *database.CircuitBreaker) *ResilientRepository {
+	return &ResilientRepository{Repository: repo, breaker: breaker}
+}
+
+// Create fails fast while the database is unavailable
+func (r *ResilientRepository) Create(connID, username string) (*User, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.Repository.Create(connID, username)
+}
+
+// GetByID finds nothing while the database is unavailable
+func (r *ResilientRepository) GetByID(connID string) (*User, bool) {
+	if r.breaker.IsOpen() {
+		return nil, false
+	}
+	return r.Repository.GetByID(connID)
+}
+
+// GetByUsername finds nothing while the database is unavailable
+func (r *ResilientRepository) GetByUsername(username string) (*User, bool) {
+	if r.breaker.IsOpen() {
+		return nil, false
+	}
+	return r.Repository.GetByUsername(username)
+}
+
+// Delete fails fast while the database is unavailable
+func (r *ResilientRepository) Delete(connID string) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.Repository.Delete(connID)
+}
+
+// IsUsernameAvailable reports true while the database is unavailable so that Create returns the outage error
+// instead of "already taken"
+func (r *ResilientRepository) IsUsernameAvailable(username string) bool {
+	if r.breaker.IsOpen() {
+		return true
+	}
+	return r.Repository.IsUsernameAvailable(username)
+}
+
+// GetAll returns no users while the database is unavailable
+func (r *ResilientRepository) GetAll() []*User {
+	if r.breaker.IsOpen() {
+		return []*User{}
+	}
+	return r.Repository.GetAll()
+}
+
+// UpdateLastActive is skipped while the database is unavailable
+func (r *ResilientRepository) UpdateLastActive(connID string) {
+	if r.breaker.IsOpen() {
+		return
+	}
+	r.Repository.UpdateLastActive(connID)
+}
+
+// ResilientProfileRepository wraps a ProfileRepository with the database circuit breaker
+type ResilientProfileRepository struct {
+	ProfileRepository
+	breaker *database.CircuitBreaker
+}
+
+// NewResilientProfileRepository creates a profile repository that fails fast during outages
+func NewResilientProfileRepository(repo ProfileRepository, breaker *database.CircuitBreaker) *ResilientProfileRepository {
+	return &ResilientProfileRepository{ProfileRepository: repo, breaker: breaker}
+}
+
+// GetProfile fails fast while the database is unavailable
+func (r *ResilientProfileRepository) GetProfile(accountID string) (*Profile, error) {
+	if r.breaker.IsOpen() {
+		return nil, database.ErrUnavailable
+	}
+	return r.ProfileRepository.GetProfile(accountID)
+}
+
+// SaveProfile fails fast while the database is unavailable
+func (r *ResilientProfileRepository) SaveProfile(profile *Profile) error {
+	if r.breaker.IsOpen() {
+		return database.ErrUnavailable
+	}
+	return r.ProfileRepository.SaveProfile(profile)
+}
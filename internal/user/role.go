@@ -0,0 +1,146 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Role is a permission level; each role can do everything the roles below it can
+type Role int
+
+const (
+	RoleGuest     Role = iota // ถูกลดสิทธิ์: คุยได้แต่สร้างห้องหรือส่ง DM ไม่ได้
+	RoleMember                // ค่าเริ่มต้นของทุกคน
+	RoleModerator             // ดูแลห้อง (ทั้ง server หรือเฉพาะห้องที่ได้รับ)
+	RoleOwner                 // เจ้าของห้อง (ผู้สร้าง) ได้เฉพาะในห้องนั้น
+	RoleAdmin                 // admin ของ server
+)
+
+// String returns the role name
+func (r Role) String() string {
+	switch r {
+	case RoleGuest:
+		return "guest"
+	case RoleModerator:
+		return "moderator"
+	case RoleOwner:
+		return "owner"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "member"
+	}
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "guest":
+		return RoleGuest, nil
+	case "member":
+		return RoleMember, nil
+	case "moderator", "mod":
+		return RoleModerator, nil
+	case "owner":
+		return RoleOwner, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleMember, fmt.Errorf("unknown role %q (use guest, member, moderator or admin)", name)
+	}
+}
+
+// RoleStore holds roles assigned with /setrole: server-wide roles and roles inside a single room.
+// key เป็น account ID (ชื่อไม่สนตัวพิมพ์) role จึงตามผู้ใช้ไปทุก connection; admin จาก config ไม่ได้เก็บที่นี่
+type RoleStore struct {
+	global map[string]Role            // accountID -> role
+	rooms  map[string]map[string]Role // room -> accountID -> role
+	mutex  sync.RWMutex
+}
+
+// NewRoleStore creates an empty role store (everyone is a member)
+func NewRoleStore() *RoleStore {
+	return &RoleStore{
+		global: make(map[string]Role),
+		rooms:  make(map[string]map[string]Role),
+	}
+}
+
+// Global returns the server-wide role of a username (member when none was assigned)
+func (s *RoleStore) Global(username string) Role {
+	if s == nil {
+		return RoleMember
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if role, exists := s.global[AccountID(username)]; exists {
+		return role
+	}
+	return RoleMember
+}
+
+// Room returns the role assigned to a username inside a room (member when none was assigned)
+func (s *RoleStore) Room(roomName, username string) Role {
+	if s == nil {
+		return RoleMember
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if role, exists := s.rooms[roomName][AccountID(username)]; exists {
+		return role
+	}
+	return RoleMember
+}
+
+// SetGlobal assigns a server-wide role; member removes the assignment
+func (s *RoleStore) SetGlobal(username string, role Role) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if role == RoleMember {
+		delete(s.global, AccountID(username))
+		return
+	}
+	s.global[AccountID(username)] = role
+}
+
+// SetRoom assigns a role inside one room; member removes the assignment
+func (s *RoleStore) SetRoom(roomName, username string, role Role) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if role == RoleMember {
+		delete(s.rooms[roomName], AccountID(username))
+		if len(s.rooms[roomName]) == 0 {
+			delete(s.rooms, roomName)
+		}
+		return
+	}
+	if s.rooms[roomName] == nil {
+		s.rooms[roomName] = make(map[string]Role)
+	}
+	s.rooms[roomName][AccountID(username)] = role
+}
+
+// CopyRoom copies the roles assigned inside one room to another room (e.g. /clone); returns how many were copied
+func (s *RoleStore) CopyRoom(fromRoom, toRoom string) int {
+	if s == nil {
+		return 0
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.rooms[fromRoom]) == 0 {
+		return 0
+	}
+	if s.rooms[toRoom] == nil {
+		s.rooms[toRoom] = make(map[string]Role)
+	}
+	for accountID, role := range s.rooms[fromRoom] {
+		s.rooms[toRoom][accountID] = role
+	}
+	return len(s.rooms[fromRoom])
+}
@@ -1,7 +1,8 @@
 package user
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 
 	"realtime-chat/internal/config"
 )
@@ -15,12 +16,23 @@ type Service interface {
 	IsUsernameAvailable(username string) bool
 	GetAllUsers() []*User
 	UpdateLastActive(connID string)
+	SetCleaner(cleaner *Cleaner)
+	SetProfileStore(store *ProfileStore)
+	GetProfile(username string) *Profile
+	UpdateProfile(user *User, profile Profile) (*Profile, error)
+}
+
+// profileSyncer is implemented by repositories that keep profile fields on the session record too (MongoDB)
+type profileSyncer interface {
+	UpdateProfile(connID string, profile *Profile) error
 }
 
 // service implements Service
 type service struct {
 	repo    Repository
 	metrics *config.ServerMetrics
+	cleaner *Cleaner
+	profiles *ProfileStore
 }
 
 // NewService creates a new user service
@@ -38,7 +50,14 @@ func (s *service) RegisterUser(connID, username string) (*User, error) {
 		return nil, err
 	}
 
-	log.Printf("👤 User registered: %s (ConnID: %s)", username, connID)
+	// profile ผูกกับ account ไม่ใช่ session จึงตามมาทุกครั้งที่ login ด้วยชื่อเดิม
+	user.AccountID = AccountID(username)
+	if profile := s.profiles.Get(username); profile != nil {
+		applyProfile(user, profile)
+		s.syncProfile(connID, profile)
+	}
+
+	slog.Info("👤 User registered", "username", username, "conn_id", connID)
 	s.metrics.IncrementUsers()
 	return user, nil
 }
@@ -47,13 +66,56 @@ func (s *service) RegisterUser(connID, username string) (*User, error) {
 func (s *service) UnregisterUser(connID string) error {
 	err := s.repo.Delete(connID)
 	if err != nil {
+		// ลบไม่สำเร็จ (เช่น DB timeout) ให้ retry ภายหลัง ไม่งั้นชื่อผู้ใช้จะถูกจองค้าง
+		if s.cleaner != nil && err != ErrUserNotFound {
+			s.cleaner.Enqueue(connID, err)
+		}
 		return err
 	}
 
-	log.Printf("👋 User unregistered (ConnID: %s)", connID)
+	slog.Info("👋 User unregistered", "conn_id", connID)
 	return nil
 }
 
+// SetCleaner sets the cleaner that retries failed deletes
+func (s *service) SetCleaner(cleaner *Cleaner) {
+	s.cleaner = cleaner
+}
+
+// SetProfileStore enables profiles persisted across sessions
+func (s *service) SetProfileStore(store *ProfileStore) {
+	s.profiles = store
+}
+
+// GetProfile returns the saved profile of a username (nil when none or profiles are disabled)
+func (s *service) GetProfile(username string) *Profile {
+	return s.profiles.Get(username)
+}
+
+// UpdateProfile saves the profile of a user's account and applies it to the session
+func (s *service) UpdateProfile(user *User, profile Profile) (*Profile, error) {
+	if s.profiles == nil {
+		return nil, fmt.Errorf("profiles are not available")
+	}
+
+	profile.AccountID = AccountID(user.Username)
+	if err := s.profiles.Save(&profile); err != nil {
+		return nil, fmt.Errorf("failed to save profile: %v", err)
+	}
+	applyProfile(user, &profile)
+	s.syncProfile(user.ConnID, &profile)
+	return &profile, nil
+}
+
+// syncProfile copies the profile onto the session record of repositories that store it
+func (s *service) syncProfile(connID string, profile *Profile) {
+	if syncer, ok := s.repo.(profileSyncer); ok {
+		if err := syncer.UpdateProfile(connID, profile); err != nil {
+			slog.Warn("⚠️ Failed to sync profile", "conn_id", connID, "error", err)
+		}
+	}
+}
+
 // GetUser returns a user by connection ID
 func (s *service) GetUser(connID string) (*User, bool) {
 	return s.repo.GetByID(connID)
@@ -0,0 +1,59 @@
+package version
+
+import (
+	"runtime"
+	"sort"
+)
+
+// Build information, set at build time:
+//
+//	go build -ldflags "-X realtime-chat/internal/version.Version=1.4.0 \
+//	  -X realtime-chat/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X realtime-chat/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Protocol is the current version of the WebSocket JSON protocol
+const Protocol = 1
+
+// SupportedProtocols lists every protocol version this server can speak, oldest first
+var SupportedProtocols = []int{1}
+
+// Info describes the running server for the hello frame and /version
+type Info struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit"`
+	BuildTime string          `json:"build_time"`
+	GoVersion string          `json:"go_version"`
+	Protocol  int             `json:"protocol"`
+	Protocols []int           `json:"protocols"`
+	Features  map[string]bool `json:"features,omitempty"`
+}
+
+// Get returns the build information together with the given feature flags
+func Get(features map[string]bool) Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Protocol:  Protocol,
+		Protocols: SupportedProtocols,
+		Features:  features,
+	}
+}
+
+// EnabledFeatures returns the names of the enabled features in alphabetical order
+func (i Info) EnabledFeatures() []string {
+	names := make([]string, 0, len(i.Features))
+	for name, enabled := range i.Features {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
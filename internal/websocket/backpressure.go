@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"sort"
+)
+
+// Slow consumer policies: what happens when a connection's outbound queue (Send) is full
+const (
+	PolicyDropOldest = "drop_oldest" // ทิ้ง frame เก่าสุดในคิวเพื่อรับ frame ใหม่ (client เห็นข้อความล่าสุด)
+	PolicyDropNewest = "drop_newest" // ทิ้ง frame ใหม่ คิวเดิมส่งต่อตามลำดับ
+	PolicyDisconnect = "disconnect"  // ปิด connection ทันทีที่คิวเต็ม
+)
+
+// ValidBackpressurePolicy reports whether policy is a known slow consumer policy
+func ValidBackpressurePolicy(policy string) bool {
+	switch policy {
+	case PolicyDropOldest, PolicyDropNewest, PolicyDisconnect:
+		return true
+	default:
+		return false
+	}
+}
+
+// Backpressure decides what to do with frames for a connection whose outbound queue is full.
+// นับ drop ติดกันตั้งแต่ write pump เขียนสำเร็จครั้งล่าสุด: client ที่ช้าแต่ยังรับอยู่จะไม่ถูกปิด
+// ส่วน client ที่ค้างจน drop ครบ maxDrops ถูกปิดด้วย slow_consumer
+type Backpressure struct {
+	policy    string
+	queueSize int
+	maxDrops  int64 // 0 = ไม่ปิดเพราะ drop (ยกเว้น policy disconnect)
+}
+
+// NewBackpressure creates a backpressure policy; unknown policies fall back to drop_oldest
+func NewBackpressure(policy string, queueSize, maxDrops int) *Backpressure {
+	if !ValidBackpressurePolicy(policy) {
+		policy = PolicyDropOldest
+	}
+	if queueSize <= 0 {
+		queueSize = defaultSendQueueSize
+	}
+	if maxDrops < 0 {
+		maxDrops = 0
+	}
+	return &Backpressure{policy: policy, queueSize: queueSize, maxDrops: int64(maxDrops)}
+}
+
+// Policy returns the policy name (nil = disconnect, the behaviour before policies existed)
+func (b *Backpressure) Policy() string {
+	if b == nil {
+		return PolicyDisconnect
+	}
+	return b.policy
+}
+
+// QueueSize returns the outbound queue capacity of new connections
+func (b *Backpressure) QueueSize() int {
+	if b == nil {
+		return defaultSendQueueSize
+	}
+	return b.queueSize
+}
+
+// shouldEvict reports whether a connection with this many consecutive drops should be closed
+func (b *Backpressure) shouldEvict(streak int64) bool {
+	if b.Policy() == PolicyDisconnect {
+		return true
+	}
+	return b.maxDrops > 0 && streak >= b.maxDrops
+}
+
+// offer queues a frame under the backpressure policy.
+// คืนค่าว่า frame ถูกเข้าคิวหรือไม่ มี frame ถูกทิ้งหรือไม่ และ connection ควรถูกปิดเป็น slow consumer หรือไม่
+// (ไม่ปิดเองที่นี่ เพราะผู้เรียกอาจถือ lock ของ Manager อยู่ ดู Manager.evictSlowConsumer)
+func (c *WebSocketConnection) offer(frame []byte) (queued, dropped, evict bool) {
+	c.sendMutex.RLock()
+	defer c.sendMutex.RUnlock()
+	if c.sendClosed {
+		// connection กำลังถูกปิด ไม่นับเป็น drop ของ slow consumer
+		return false, false, false
+	}
+
+	select {
+	case c.Send <- frame:
+		return true, false, false
+	default:
+	}
+
+	if c.backpressure.Policy() == PolicyDropOldest {
+		// write pump อาจอ่าน frame ไปพร้อมกัน จึงไม่ถือว่าต้องได้ frame เก่าออกมาเสมอ
+		select {
+		case <-c.Send:
+		default:
+		}
+		select {
+		case c.Send <- frame:
+			queued = true
+		default:
+		}
+	}
+
+	c.dropped.Add(1)
+	streak := c.dropStreak.Add(1)
+	if c.onDrop != nil {
+		c.onDrop()
+	}
+	return queued, true, c.backpressure.shouldEvict(streak)
+}
+
+// recordWritten resets the drop streak after the write pump made progress
+func (c *WebSocketConnection) recordWritten() {
+	if c.dropStreak.Load() != 0 {
+		c.dropStreak.Store(0)
+	}
+}
+
+// ConnectionBackpressure is the outbound queue state of one connection
+type ConnectionBackpressure struct {
+	Label      string `json:"label"`
+	QueueLen   int    `json:"queue_len"`
+	QueueCap   int    `json:"queue_cap"`
+	Dropped    int64  `json:"dropped"`
+	DropStreak int64  `json:"drop_streak"` // drop ติดกันตั้งแต่เขียนสำเร็จครั้งล่าสุด
+}
+
+// BackpressureStats reports the slow consumer policy and the connections that dropped frames or are backed up
+type BackpressureStats struct {
+	Policy        string                   `json:"policy"`
+	QueueSize     int                      `json:"queue_size"`
+	MaxDrops      int64                    `json:"max_drops"`
+	DroppedFrames int64                    `json:"dropped_frames"`
+	Disconnects   int64                    `json:"slow_consumer_disconnects"`
+	Connections   []ConnectionBackpressure `json:"connections"`
+}
+
+// maxBackpressureEntries caps the per-connection list of BackpressureStats
+const maxBackpressureEntries = 100
+
+// BackpressureStats returns per-connection drop counters, most dropped first.
+// แสดงเฉพาะ connection ที่เคย drop หรือคิวใช้ไปเกินครึ่ง
+func (m *Manager) BackpressureStats() BackpressureStats {
+	metrics := m.metrics.GetMetrics()
+	stats := BackpressureStats{
+		Policy:        m.backpressure.Policy(),
+		QueueSize:     m.backpressure.QueueSize(),
+		DroppedFrames: metrics.DroppedFrames,
+		Disconnects:   metrics.SlowConsumerDisconnects,
+		Connections:   []ConnectionBackpressure{},
+	}
+	if m.backpressure != nil {
+		stats.MaxDrops = m.backpressure.maxDrops
+	}
+
+	m.mutex.RLock()
+	for _, conn := range m.connections {
+		entry := ConnectionBackpressure{
+			Label:      conn.GetLabel(),
+			QueueLen:   len(conn.Send),
+			QueueCap:   cap(conn.Send),
+			Dropped:    conn.dropped.Load(),
+			DropStreak: conn.dropStreak.Load(),
+		}
+		if entry.Dropped > 0 || entry.QueueLen*2 > entry.QueueCap {
+			stats.Connections = append(stats.Connections, entry)
+		}
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(stats.Connections, func(i, j int) bool {
+		if stats.Connections[i].Dropped != stats.Connections[j].Dropped {
+			return stats.Connections[i].Dropped > stats.Connections[j].Dropped
+		}
+		return stats.Connections[i].QueueLen > stats.Connections[j].QueueLen
+	})
+	if len(stats.Connections) > maxBackpressureEntries {
+		stats.Connections = stats.Connections[:maxBackpressureEntries]
+	}
+	return stats
+}
+
+// evictSlowConsumer closes a connection whose outbound queue stayed full.
+// ส่งต่อให้ Run loop ผ่าน unregister ใน goroutine แยก เพราะผู้เรียกอยู่ใน broadcast (ถือ lock และอยู่ใน Run loop เอง)
+// การปิดจึงผ่านทางเดียวกับ disconnect ปกติ (แจ้ง user_left, ออกจากห้อง, ลบ user)
+func (m *Manager) evictSlowConsumer(conn *WebSocketConnection) {
+	if !conn.evicting.CompareAndSwap(false, true) {
+		return
+	}
+	m.metrics.IncrementSlowConsumerDisconnects()
+	conn.Logger().Warn("🐢 Closing slow consumer", "label", conn.GetLabel(), "policy", m.backpressure.Policy(),
+		"dropped", conn.dropped.Load(), "queue_cap", cap(conn.Send))
+	conn.Trace(TraceDropped, 0, "slow consumer, closing")
+
+	go func() {
+		m.markClose(conn, CloseSlowConsumer, m.GetConnectionCount())
+		m.requestUnregister(conn)
+	}()
+}
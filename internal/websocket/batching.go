@@ -0,0 +1,190 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"realtime-chat/internal/config"
+)
+
+// BatchStats holds outbound batching counters
+type BatchStats struct {
+	Enabled       bool    `json:"enabled"`
+	WindowMs      int64   `json:"window_ms"`
+	MaxBatchSize  int     `json:"max_batch_size"`
+	Connections   int     `json:"connections"` // connection ที่ตกลงใช้ batching
+	Batches       int64   `json:"batches"`
+	BatchedFrames int64   `json:"batched_frames"`
+	AvgBatchSize  float64 `json:"avg_batch_size"`
+	LargestBatch  int64   `json:"largest_batch"`
+}
+
+// Batcher coalesces JSON frames queued for the same connection into one JSON array frame.
+// ใช้เฉพาะกับ connection ที่ขอ capability "batch" ตอน join เพราะ client ต้องรู้ว่า frame อาจเป็น array
+// write pump รอ frame ถัดไปได้ไม่เกิน window หลัง frame แรก ลด syscall และ frame overhead ของห้องที่คุยถี่
+type Batcher struct {
+	window   time.Duration
+	maxSize  int
+	maxBytes int // ไม่รวมเกิน max frame size (0 = ไม่จำกัด)
+
+	batches       atomic.Int64
+	batchedFrames atomic.Int64
+	largestBatch  atomic.Int64
+}
+
+// NewBatcher creates a batcher; it returns nil when window or maxSize disables batching
+func NewBatcher(window time.Duration, maxSize, maxBytes int) *Batcher {
+	if window <= 0 || maxSize <= 1 {
+		return nil
+	}
+	return &Batcher{window: window, maxSize: maxSize, maxBytes: maxBytes}
+}
+
+// newManagerBatcher creates the batcher shared by a manager's connections
+func newManagerBatcher(cfg *config.ServerConfig) *Batcher {
+	if !cfg.EnableBatching {
+		return nil
+	}
+	return NewBatcher(cfg.BatchWindow, cfg.BatchMaxSize, cfg.MaxOutboundFrameSize)
+}
+
+// Window returns how long the write pump waits for more frames
+func (b *Batcher) Window() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.window
+}
+
+// MaxSize returns the most frames sent in one batch
+func (b *Batcher) MaxSize() int {
+	if b == nil {
+		return 0
+	}
+	return b.maxSize
+}
+
+// Stats returns batching counters
+func (b *Batcher) Stats() BatchStats {
+	if b == nil {
+		return BatchStats{}
+	}
+	stats := BatchStats{
+		Enabled:       true,
+		WindowMs:      b.window.Milliseconds(),
+		MaxBatchSize:  b.maxSize,
+		Batches:       b.batches.Load(),
+		BatchedFrames: b.batchedFrames.Load(),
+		LargestBatch:  b.largestBatch.Load(),
+	}
+	if stats.Batches > 0 {
+		stats.AvgBatchSize = float64(stats.BatchedFrames) / float64(stats.Batches)
+	}
+	return stats
+}
+
+// record counts a batch of frames sent as one array
+func (b *Batcher) record(size int) {
+	b.batches.Add(1)
+	b.batchedFrames.Add(int64(size))
+	for {
+		largest := b.largestBatch.Load()
+		if int64(size) <= largest || b.largestBatch.CompareAndSwap(largest, int64(size)) {
+			return
+		}
+	}
+}
+
+// batchElement returns a frame as an element of a JSON array batch.
+// frame ที่ไม่ใช่ JSON object (plain text รวมถึงข้อความที่บังเอิญขึ้นต้นด้วย "{") ใส่เป็น JSON string
+// ให้ client แสดงแบบเดียวกับ frame plain text และ array ทั้งก้อนยัง parse ได้เสมอ
+func batchElement(frame []byte) []byte {
+	if len(frame) > 0 && frame[0] == '{' && json.Valid(frame) {
+		return frame
+	}
+	encoded, err := json.Marshal(string(frame))
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// collectBatch gathers frames that follow first within the batch window.
+// คืน frame ที่ใส่ batch ไม่ได้ (next) ให้ส่งแยกต่อจาก batch เพื่อรักษาลำดับ และ closed เมื่อ Send ถูกปิดระหว่างรอ
+func (c *WebSocketConnection) collectBatch(first []byte) (batch [][]byte, next []byte, closed bool) {
+	batch = [][]byte{first}
+	size := len(batchElement(first)) + 2
+
+	timer := time.NewTimer(c.batcher.window)
+	defer timer.Stop()
+
+	for len(batch) < c.batcher.maxSize {
+		select {
+		case frame, ok := <-c.Send:
+			if !ok {
+				return batch, nil, true
+			}
+			element := batchElement(frame)
+			if element == nil || (c.batcher.maxBytes > 0 && size+len(element)+1 > c.batcher.maxBytes) {
+				return batch, frame, false
+			}
+			batch = append(batch, frame)
+			size += len(element) + 1
+		case <-timer.C:
+			return batch, nil, false
+		}
+	}
+	return batch, nil, false
+}
+
+// joinBatch encodes frames as one JSON array; a single frame is sent unchanged
+func (c *WebSocketConnection) joinBatch(batch [][]byte) []byte {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+	c.batcher.record(len(batch))
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, frame := range batch {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(batchElement(frame))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// EnableBatching turns on batching for a connection that negotiated it; false when the server has batching disabled
+func (c *WebSocketConnection) EnableBatching() bool {
+	if c.batcher == nil {
+		return false
+	}
+	c.batching.Store(true)
+	return true
+}
+
+// BatchingEnabled reports whether outbound frames may be batched
+func (c *WebSocketConnection) BatchingEnabled() bool {
+	return c.batching.Load()
+}
+
+// BatchStats returns outbound batching counters and how many connections use batching
+func (m *Manager) BatchStats() BatchStats {
+	stats := m.batcher.Stats()
+	if m.batcher == nil {
+		return stats
+	}
+
+	m.mutex.RLock()
+	for _, conn := range m.connections {
+		if conn.BatchingEnabled() {
+			stats.Connections++
+		}
+	}
+	m.mutex.RUnlock()
+	return stats
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBatchElement(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+		want  interface{}
+	}{
+		{"json object", `{"type":"message","content":"hi"}`, map[string]interface{}{"type": "message", "content": "hi"}},
+		{"plain text", "alice joined", "alice joined"},
+		{"text starting with brace", "{hi there", "{hi there"},
+		{"truncated object", `{"type":"message"`, `{"type":"message"`},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got interface{}
+			if err := json.Unmarshal(batchElement([]byte(tt.frame)), &got); err != nil {
+				t.Fatalf("element is not valid JSON: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Fatalf("got %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+// batch ที่มีข้อความขึ้นต้นด้วย "{" ต้องยังเป็น JSON array ที่ client parse ได้
+func TestJoinBatchIsValidJSON(t *testing.T) {
+	conn := &WebSocketConnection{batcher: NewBatcher(time.Millisecond, 10, 0)}
+	batch := conn.joinBatch([][]byte{[]byte(`{"type":"message"}`), []byte("{hi there"), []byte("plain")})
+
+	var elements []interface{}
+	if err := json.Unmarshal(batch, &elements); err != nil {
+		t.Fatalf("batch is not valid JSON: %v (%s)", err, batch)
+	}
+	if len(elements) != 3 || elements[1] != "{hi there" {
+		t.Fatalf("unexpected batch elements: %v", elements)
+	}
+}
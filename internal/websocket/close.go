@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Application close codes sent in the WebSocket close frame on server-initiated closes.
+// ใช้ช่วง 4000-4999 ตามที่ RFC 6455 สงวนไว้สำหรับ application
+//
+//	4000 server_full      - เซิร์ฟเวอร์รับ connection เต็มแล้ว          (reconnect ได้ หลังรอสักครู่)
+//	4001 kicked           - ถูกเตะออกโดยผู้ดูแล                         (ไม่ควร reconnect อัตโนมัติ)
+//	4002 idle_timeout     - ไม่มีการใช้งานนานเกินกำหนด                  (reconnect ได้เมื่อผู้ใช้กลับมา)
+//	4003 unhealthy        - ไม่ตอบ ping/pong ตามเวลา                    (reconnect ได้ทันที)
+//	4004 server_shutdown  - เซิร์ฟเวอร์กำลังปิดหรือ restart              (reconnect ได้ หลังรอสักครู่)
+//	4005 slow_consumer    - รับข้อความไม่ทัน buffer ฝั่ง server เต็ม    (reconnect ได้ทันที)
+//	4006 throttled        - IP ถูก block ชั่วคราวจากการ login ผิดซ้ำๆ   (ไม่ควร reconnect อัตโนมัติ)
+//	4007 protocol_violation - ส่ง frame ผิด protocol ซ้ำจนถูกกักกัน      (ไม่ควร reconnect อัตโนมัติ)
+const (
+	CloseServerFull        = 4000
+	CloseKicked            = 4001
+	CloseIdleTimeout       = 4002
+	CloseUnhealthy         = 4003
+	CloseServerShutdown    = 4004
+	CloseSlowConsumer      = 4005
+	CloseThrottled         = 4006
+	CloseProtocolViolation = 4007
+)
+
+// closeWriteWait limits how long writing a close frame may block
+const closeWriteWait = time.Second
+
+// CloseReason describes why the server closed a connection
+type CloseReason struct {
+	Code      int
+	Reason    string
+	Reconnect bool   // client ควร reconnect อัตโนมัติหรือไม่
+	Message   string // ข้อความสำหรับแสดงผู้ใช้ ส่งใน reconnect_policy
+}
+
+// closeReasons maps application close codes to their documented reason strings
+var closeReasons = map[int]CloseReason{
+	CloseServerFull:        {Code: CloseServerFull, Reason: "server_full", Reconnect: true, Message: "Server is full, try again shortly"},
+	CloseKicked:            {Code: CloseKicked, Reason: "kicked", Reconnect: false, Message: "You were disconnected by an administrator"},
+	CloseIdleTimeout:       {Code: CloseIdleTimeout, Reason: "idle_timeout", Reconnect: true, Message: "Disconnected due to inactivity"},
+	CloseUnhealthy:         {Code: CloseUnhealthy, Reason: "unhealthy", Reconnect: true, Message: "Connection stopped responding"},
+	CloseServerShutdown:    {Code: CloseServerShutdown, Reason: "server_shutdown", Reconnect: true, Message: "Server is restarting"},
+	CloseSlowConsumer:      {Code: CloseSlowConsumer, Reason: "slow_consumer", Reconnect: true, Message: "Connection too slow to keep up"},
+	CloseThrottled:         {Code: CloseThrottled, Reason: "throttled", Reconnect: false, Message: "Too many failed attempts"},
+	CloseProtocolViolation: {Code: CloseProtocolViolation, Reason: "protocol_violation", Reconnect: false, Message: "Too many invalid messages"},
+}
+
+// GetCloseReason returns the documented close reason for an application close code
+func GetCloseReason(code int) CloseReason {
+	if reason, exists := closeReasons[code]; exists {
+		return reason
+	}
+	return CloseReason{Code: websocket.CloseNormalClosure, Reason: "normal_closure", Reconnect: false}
+}
+
+// pendingClose holds the close code and pre-close notice for a connection
+type pendingClose struct {
+	code   int
+	notice []byte
+}
+
+// MarkClose records the close code (and optional pre-close notice) to send once the
+// write loop sees the Send channel closed
+func (c *WebSocketConnection) MarkClose(code int, notice []byte) {
+	c.pendingClose.Store(&pendingClose{code: code, notice: notice})
+}
+
+// CloseNotice returns the text message to send right before the close frame (nil if none)
+func (c *WebSocketConnection) CloseNotice() []byte {
+	if pending := c.pendingClose.Load(); pending != nil {
+		return pending.notice
+	}
+	return nil
+}
+
+// CloseFrame returns the close frame payload for this connection's pending close code
+func (c *WebSocketConnection) CloseFrame() []byte {
+	pending := c.pendingClose.Load()
+	if pending == nil {
+		return websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	}
+	reason := GetCloseReason(pending.code)
+	c.Logger().Info("🚪 Closing connection", "code", reason.Code, "reason", reason.Reason)
+	return websocket.FormatCloseMessage(reason.Code, reason.Reason)
+}
+
+// SendClose writes the pre-close notice (if any) and a close frame with the given application close code.
+// ใช้เฉพาะกับ connection ที่ไม่มี write loop ทำงานอยู่ เพราะ WriteMessage เรียกพร้อมกันหลาย goroutine ไม่ได้
+func (c *WebSocketConnection) SendClose(code int, notice []byte) {
+	reason := GetCloseReason(code)
+	payload := websocket.FormatCloseMessage(reason.Code, reason.Reason)
+
+	c.Conn.SetWriteDeadline(time.Now().Add(closeWriteWait))
+	if notice != nil {
+		c.Conn.WriteMessage(websocket.TextMessage, notice)
+	}
+
+	if err := c.Conn.WriteControl(websocket.CloseMessage, payload, time.Now().Add(closeWriteWait)); err != nil {
+		c.Logger().Warn("⚠️ Failed to send close frame", "code", reason.Code, "reason", reason.Reason, "error", err)
+		return
+	}
+	c.Logger().Info("🚪 Closing connection", "code", reason.Code, "reason", reason.Reason)
+}
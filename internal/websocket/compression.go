@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"compress/flate"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"realtime-chat/internal/config"
+)
+
+// compressionSampleEvery is how often a compressed frame is also compressed here to estimate the ratio.
+// gorilla ไม่บอกขนาดหลังบีบอัด จึงประมาณจากตัวอย่างแทนการบีบอัดซ้ำทุก frame
+const compressionSampleEvery = 16
+
+// CompressionStats holds permessage-deflate counters
+type CompressionStats struct {
+	Enabled             bool    `json:"enabled"`
+	Threshold           int     `json:"threshold"`
+	Level               int     `json:"level"`
+	Connections         int     `json:"connections"` // connection ที่ตกลง permessage-deflate
+	CompressedFrames    int64   `json:"compressed_frames"`
+	UncompressedFrames  int64   `json:"uncompressed_frames"` // เล็กกว่า threshold บน connection ที่บีบอัดได้
+	CompressedBytesIn   int64   `json:"compressed_bytes_in"`
+	Ratio               float64 `json:"ratio"` // ขนาดหลังบีบอัด / ก่อนบีบอัด (ประมาณจากตัวอย่าง)
+	EstimatedBytesSaved int64   `json:"estimated_bytes_saved"`
+}
+
+// Compressor decides which outbound frames are sent with permessage-deflate and tracks the compression ratio.
+// frame เล็กบีบอัดแล้วแทบไม่เล็กลงแต่เสีย CPU จึงบีบอัดเฉพาะ payload ที่ใหญ่ถึง threshold เช่น history และผลค้นหา
+type Compressor struct {
+	threshold int
+	level     int
+
+	compressed   atomic.Int64
+	uncompressed atomic.Int64
+	bytesIn      atomic.Int64
+	sampledIn    atomic.Int64
+	sampledOut   atomic.Int64
+	writers      sync.Pool
+}
+
+// NewCompressor creates a compressor; it returns nil when compression is disabled
+func NewCompressor(cfg *config.ServerConfig) *Compressor {
+	if !cfg.EnableCompression {
+		return nil
+	}
+	return &Compressor{threshold: cfg.CompressionThreshold, level: cfg.CompressionLevel}
+}
+
+// CompressionOffered reports whether a WebSocket upgrade request offers permessage-deflate.
+// gorilla ตกลง permessage-deflate ทุกครั้งที่ client เสนอและ Upgrader.EnableCompression เปิดอยู่
+func CompressionOffered(r *http.Request) bool {
+	for _, header := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, extension := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(extension, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shouldCompress reports whether a frame of this size is compressed, counting it
+func (p *Compressor) shouldCompress(data []byte) bool {
+	if len(data) < p.threshold {
+		p.uncompressed.Add(1)
+		return false
+	}
+	if p.compressed.Add(1)%compressionSampleEvery == 1 {
+		p.sample(data)
+	}
+	p.bytesIn.Add(int64(len(data)))
+	return true
+}
+
+// sample compresses a frame with the connection's level to estimate the ratio
+func (p *Compressor) sample(data []byte) {
+	counter := &byteCounter{}
+	writer, _ := p.writers.Get().(*flate.Writer)
+	if writer == nil {
+		var err error
+		if writer, err = flate.NewWriter(counter, p.level); err != nil {
+			return
+		}
+	} else {
+		writer.Reset(counter)
+	}
+	writer.Write(data)
+	writer.Flush()
+	p.writers.Put(writer)
+
+	p.sampledIn.Add(int64(len(data)))
+	p.sampledOut.Add(counter.n)
+}
+
+// Stats returns compression counters
+func (p *Compressor) Stats() CompressionStats {
+	if p == nil {
+		return CompressionStats{}
+	}
+	stats := CompressionStats{
+		Enabled:            true,
+		Threshold:          p.threshold,
+		Level:              p.level,
+		CompressedFrames:   p.compressed.Load(),
+		UncompressedFrames: p.uncompressed.Load(),
+		CompressedBytesIn:  p.bytesIn.Load(),
+	}
+	if sampledIn := p.sampledIn.Load(); sampledIn > 0 {
+		stats.Ratio = float64(p.sampledOut.Load()) / float64(sampledIn)
+		stats.EstimatedBytesSaved = int64(float64(stats.CompressedBytesIn) * (1 - stats.Ratio))
+	}
+	return stats
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter struct {
+	n int64
+}
+
+// Write counts p and discards it
+func (b *byteCounter) Write(p []byte) (int, error) {
+	b.n += int64(len(p))
+	return len(p), nil
+}
+
+// EnableCompression marks that permessage-deflate was negotiated for the connection; false when the server has it disabled
+func (c *WebSocketConnection) EnableCompression() bool {
+	if c.compressor == nil {
+		return false
+	}
+	c.compressing.Store(true)
+	return true
+}
+
+// CompressionEnabled reports whether large outbound frames are compressed
+func (c *WebSocketConnection) CompressionEnabled() bool {
+	return c.compressing.Load()
+}
+
+// applyCompression turns compression on for the next frame if it reaches the threshold (write pump only)
+func (c *WebSocketConnection) applyCompression(data []byte) {
+	if !c.compressing.Load() {
+		return
+	}
+	compress := c.compressor.shouldCompress(data)
+	if compress {
+		c.Conn.SetCompressionLevel(c.compressor.level)
+	}
+	c.Conn.EnableWriteCompression(compress)
+}
+
+// CompressionStats returns permessage-deflate counters and how many connections negotiated it
+func (m *Manager) CompressionStats() CompressionStats {
+	stats := m.compressor.Stats()
+	if m.compressor == nil {
+		return stats
+	}
+
+	m.mutex.RLock()
+	for _, conn := range m.connections {
+		if conn.CompressionEnabled() {
+			stats.Connections++
+		}
+	}
+	m.mutex.RUnlock()
+	return stats
+}
@@ -1,7 +1,13 @@
 package websocket
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -12,19 +18,48 @@ import (
 type WebSocketConnection struct {
 	ID       string
 	Conn     *websocket.Conn
-	User     interface{} // ใช้ interface{} เพื่อหลีกเลี่ยง import cycle
 	LastSeen time.Time
 	Send     chan []byte
 	Health   *config.ConnectionHealth
+
+	// read loop ตั้ง user และ correlation ID ขณะที่ Run loop, broadcast และ logger อ่านจาก goroutine อื่น
+	identityMutex sync.RWMutex
+	user          interface{} // ใช้ interface{} เพื่อหลีกเลี่ยง import cycle
+	correlationID string      // correlation ID ของข้อความขาเข้าที่กำลังประมวลผลอยู่
+
+	pendingClose  atomic.Pointer[pendingClose] // close code ที่จะส่งตอนปิด (ดู close.go)
+	frames        *FrameGuard                  // แบ่ง payload ที่ใหญ่เกิน frame limit (nil = ส่งตรง)
+	appHeartbeat  atomic.Bool                  // client ตกลงใช้ heartbeat ระดับ application ("hb")
+	tracer        atomic.Pointer[TraceFunc]    // admin /trace (nil = ไม่ trace, ดู trace.go)
+	encoder       FrameEncoder                 // wire format ที่ตกลงตอน upgrade (nil = JSON text, ดู encoder.go)
+	batcher       *Batcher                     // รวม frame ขาออกเป็น array (nil = server ปิด batching, ดู batching.go)
+	batching      atomic.Bool                  // client ตกลงรับ batch ("batch")
+	compressor    *Compressor                  // เลือก frame ที่บีบอัด (nil = server ปิด compression, ดู compression.go)
+	compressing   atomic.Bool                  // ตกลง permessage-deflate ตอน upgrade แล้ว
+
+	backpressure  *Backpressure                // policy เมื่อคิว Send เต็ม (nil = ปิดทันที, ดู backpressure.go)
+	dropped       atomic.Int64                 // frame ที่ถูกทิ้งเพราะคิวเต็ม
+	dropStreak    atomic.Int64                 // drop ติดกันตั้งแต่ write pump เขียนสำเร็จครั้งล่าสุด
+	evicting      atomic.Bool                  // ถูกสั่งปิดเป็น slow consumer แล้ว
+	onDrop        func()                       // นับ drop ใน server metrics (nil = ไม่นับ)
+	evict         func(*WebSocketConnection)   // ปิด connection เป็น slow consumer (nil = ไม่ปิด)
+
+	// sendMutex กันการส่งเข้า Send พร้อมกับการปิด Send: ผู้ส่งถือ read lock, closeSend ถือ write lock
+	// ผู้ส่งจำนวนมากถือ Connection ไว้นอก lock ของ Manager จึงต้องป้องกันที่ตัว connection เอง
+	sendMutex sync.RWMutex
+	sendClosed bool
 }
 
+// defaultSendQueueSize is the outbound queue capacity when no backpressure policy is configured
+const defaultSendQueueSize = 256
+
 // NewWebSocketConnection creates a new WebSocket connection
 func NewWebSocketConnection(id string, conn *websocket.Conn) *WebSocketConnection {
 	return &WebSocketConnection{
 		ID:       id,
 		Conn:     conn,
 		LastSeen: time.Now(),
-		Send:     make(chan []byte, 256),
+		Send:     make(chan []byte, defaultSendQueueSize),
 		Health:   config.NewConnectionHealth(),
 	}
 }
@@ -34,31 +69,102 @@ func (c *WebSocketConnection) GetID() string {
 	return c.ID
 }
 
+// GetLabel returns a stable short label for logs and metrics (e.g. alice#ab12)
+func (c *WebSocketConnection) GetLabel() string {
+	name := "anon"
+	if user, ok := c.GetUser().(UserInterface); ok && user.GetUsername() != "" {
+		name = user.GetUsername()
+	}
+	return name + "#" + shortID(c.ID)
+}
+
+// GetCorrelationID returns the correlation ID of the inbound message being processed
+func (c *WebSocketConnection) GetCorrelationID() string {
+	c.identityMutex.RLock()
+	defer c.identityMutex.RUnlock()
+	return c.correlationID
+}
+
+// Logger returns a structured logger carrying the connection's conn_id, correlation_id, username and room
+func (c *WebSocketConnection) Logger() *slog.Logger {
+	logger := slog.With("conn_id", c.ID)
+	if correlationID := c.GetCorrelationID(); correlationID != "" {
+		logger = logger.With("correlation_id", correlationID)
+	}
+	if user, ok := c.GetUser().(UserInterface); ok && user.GetUsername() != "" {
+		logger = logger.With("username", user.GetUsername(), "room", user.GetCurrentRoom())
+	}
+	return logger
+}
+
+// SetCorrelationID sets the correlation ID for the inbound message being processed
+func (c *WebSocketConnection) SetCorrelationID(id string) {
+	c.identityMutex.Lock()
+	defer c.identityMutex.Unlock()
+	c.correlationID = id
+}
+
+// EnableAppHeartbeat marks that the client negotiated application-level heartbeats
+func (c *WebSocketConnection) EnableAppHeartbeat() {
+	c.appHeartbeat.Store(true)
+}
+
+// AppHeartbeatEnabled reports whether the client negotiated application-level heartbeats
+func (c *WebSocketConnection) AppHeartbeatEnabled() bool {
+	return c.appHeartbeat.Load()
+}
+
 // GetUser returns the user associated with this connection
 func (c *WebSocketConnection) GetUser() interface{} {
-	return c.User
+	c.identityMutex.RLock()
+	defer c.identityMutex.RUnlock()
+	return c.user
 }
 
 // SetUser sets the user for this connection
 func (c *WebSocketConnection) SetUser(user interface{}) {
-	c.User = user
+	c.identityMutex.Lock()
+	defer c.identityMutex.Unlock()
+	c.user = user
 }
 
-// SendMessage sends a message through the connection
+// SendMessage sends a message through the connection, chunking it if it exceeds the max frame size
 func (c *WebSocketConnection) SendMessage(message []byte) error {
-	c.Health.RecordActivity()
-	select {
-	case c.Send <- message:
-		return nil
-	default:
-		log.Printf("❌ Failed to send message to connection %s", c.ID)
-		return nil
+	return c.enqueue(message, true)
+}
+
+// enqueue queues the frames of a message for the write pump
+func (c *WebSocketConnection) enqueue(message []byte, traced bool) error {
+	for _, frame := range c.frames.Split(message) {
+		queued, dropped, evict := c.offer(frame)
+		if queued && traced {
+			c.Trace(TraceFrameOut, len(frame), "")
+		}
+		// log เฉพาะ drop แรกหลังเขียนสำเร็จ ไม่ให้ client ที่ค้างทำให้ log ท่วม
+		if dropped && c.dropStreak.Load() == 1 {
+			c.Logger().Warn("❌ Send queue full, dropping frame", "bytes", len(frame), "policy", c.backpressure.Policy())
+			if traced {
+				c.Trace(TraceDropped, len(frame), "send queue full ("+c.backpressure.Policy()+")")
+			}
+		}
+		if evict {
+			if c.evict != nil {
+				c.evict(c)
+			}
+			return nil
+		}
 	}
+	return nil
+}
+
+// RecordActivity marks that the client sent something; the idle reaper closes connections without activity
+func (c *WebSocketConnection) RecordActivity() {
+	c.Health.RecordActivity()
 }
 
-// GetSendChannel returns the send channel for this connection
-func (c *WebSocketConnection) GetSendChannel() chan []byte {
-	return c.Send
+// IdleFor returns how long ago the client last sent a frame (heartbeats excluded)
+func (c *WebSocketConnection) IdleFor() time.Duration {
+	return time.Since(c.Health.GetStats().LastActivity)
 }
 
 // IsHealthy checks if the connection is healthy
@@ -73,15 +179,58 @@ func (c *WebSocketConnection) GetHealthStats() *config.ConnectionHealth {
 
 // Close closes the connection
 func (c *WebSocketConnection) Close() error {
-	close(c.Send)
+	c.closeSend()
 	return c.Conn.Close()
 }
 
+// closeSend closes the outbound queue once, which stops the write pump.
+// เรียกซ้ำได้ และหลังปิดแล้ว frame ที่ส่งเข้ามาจะถูกทิ้งแทนที่จะ panic
+func (c *WebSocketConnection) closeSend() {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	if c.sendClosed {
+		return
+	}
+	c.sendClosed = true
+	close(c.Send)
+}
+
+// trySend queues a frame without blocking and without backpressure accounting (e.g. delivery probes)
+func (c *WebSocketConnection) trySend(frame []byte) bool {
+	c.sendMutex.RLock()
+	defer c.sendMutex.RUnlock()
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.Send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
 // generateConnectionID creates a unique connection ID
 func GenerateConnectionID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(6)
 }
 
+// NewCorrelationID creates a short random ID used to trace one inbound message through the logs
+func NewCorrelationID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// shortID derives a stable 4-character tag from a connection ID
+func shortID(id string) string {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return fmt.Sprintf("%04x", h.Sum32()&0xffff)
+}
+
 // randomString generates a random string of specified length
 func randomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"realtime-chat/internal/config"
+)
+
+// testUser implements UserInterface
+type testUser struct {
+	username string
+	room     string
+}
+
+func (u *testUser) GetIsAuthenticated() bool { return true }
+func (u *testUser) GetUsername() string      { return u.username }
+func (u *testUser) GetCurrentRoom() string   { return u.room }
+
+// newTestConn returns the server side of a real WebSocket connection
+func newTestConn(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	accepted := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	conn := <-accepted
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// read loop ตั้ง user/correlation ID ขณะที่ goroutine อื่นอ่านผ่าน label, logger และ GetUser (รันด้วย -race)
+func TestConnectionIdentityConcurrentAccess(t *testing.T) {
+	conn := NewWebSocketConnection("conn-1", nil)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			conn.SetUser(&testUser{username: "alice", room: "general"})
+			conn.SetCorrelationID(NewCorrelationID())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			conn.GetLabel()
+			conn.Logger()
+			conn.GetCorrelationID()
+			conn.GetUser()
+		}
+	}()
+	wg.Wait()
+
+	if label := conn.GetLabel(); label != "alice#"+shortID("conn-1") {
+		t.Fatalf("unexpected label %q", label)
+	}
+}
+
+// ส่ง auth request ไม่ได้ต้องคืน connection gauge ที่เพิ่งนับไป
+func TestRegisterConnectionReleasesGaugeOnFailedAuthRequest(t *testing.T) {
+	metrics := config.NewServerMetrics()
+	manager := NewManager(config.DefaultServerConfig(), nil, nil, metrics)
+
+	conn := NewWebSocketConnection("conn-1", newTestConn(t))
+	conn.closeSend()
+
+	if manager.registerConnection(conn) {
+		t.Fatal("registered a connection whose queue is closed")
+	}
+	if active := metrics.GetMetrics().ActiveConnections; active != 0 {
+		t.Fatalf("active connections = %d, want 0", active)
+	}
+	if manager.GetConnectionCount() != 0 {
+		t.Fatalf("connection left in the manager")
+	}
+}
+
+// ส่งเข้า Send พร้อมกับที่ unregister ปิด Send ต้องไม่ panic (send on closed channel) และไม่นับเป็น drop
+func TestSendWhileClosingQueue(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		conn := NewWebSocketConnection("conn-1", nil)
+		conn.Send = make(chan []byte, 1)
+
+		var wg sync.WaitGroup
+		for s := 0; s < 4; s++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					conn.offer([]byte("frame"))
+					conn.trySend([]byte("probe"))
+				}
+			}()
+		}
+		conn.closeSend()
+		wg.Wait()
+
+		if queued, dropped, evict := conn.offer([]byte("late")); queued || dropped || evict {
+			t.Fatalf("offer after close = %v %v %v, want all false", queued, dropped, evict)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeliveryProbe is sent to a sampled connection right after a broadcast; the client
+// replies with a delivery_ack carrying the same probe_id
+type DeliveryProbe struct {
+	Type      string    `json:"type"` // "delivery_probe"
+	ProbeID   string    `json:"probe_id"`
+	Room      string    `json:"room"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DeliveryStats holds end-to-end delivery latency percentiles for a room
+type DeliveryStats struct {
+	Room     string  `json:"room"`
+	Samples  int     `json:"samples"`
+	Timeouts int64   `json:"timeouts"`
+	P50Ms    float64 `json:"p50_ms"`
+	P90Ms    float64 `json:"p90_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// pendingProbe tracks a probe waiting for its ack
+type pendingProbe struct {
+	room   string
+	connID string
+	sentAt time.Time
+}
+
+// roomLatency keeps a bounded window of recent latency samples for a room
+type roomLatency struct {
+	samples  []time.Duration
+	next     int
+	timeouts int64
+}
+
+// DeliveryTracker samples broadcasts and measures delivery latency from acks
+type DeliveryTracker struct {
+	sampleSize  int
+	interval    time.Duration
+	timeout     time.Duration
+	windowSize  int
+	lastSampled map[string]time.Time // roomName -> เวลาที่สุ่มวัดล่าสุด
+	pending     map[string]*pendingProbe
+	rooms       map[string]*roomLatency
+	sequence    int64
+	mutex       sync.Mutex
+}
+
+// NewDeliveryTracker creates a new delivery tracker
+func NewDeliveryTracker(sampleSize int, interval, timeout time.Duration, windowSize int) *DeliveryTracker {
+	return &DeliveryTracker{
+		sampleSize:  sampleSize,
+		interval:    interval,
+		timeout:     timeout,
+		windowSize:  windowSize,
+		lastSampled: make(map[string]time.Time),
+		pending:     make(map[string]*pendingProbe),
+		rooms:       make(map[string]*roomLatency),
+	}
+}
+
+// Sample decides whether to probe this broadcast and returns probe payloads keyed by connection ID.
+// สุ่มวัดไม่เกินหนึ่งครั้งต่อ interval ต่อห้อง เพื่อไม่ให้ ack ท่วม server
+func (t *DeliveryTracker) Sample(roomName string, recipients []string) map[string][]byte {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.lastSampled[roomName]) < t.interval {
+		return nil
+	}
+	t.lastSampled[roomName] = now
+	t.expireLocked(now)
+
+	// สุ่มเลือก connection แบบ partial shuffle
+	count := t.sampleSize
+	if count > len(recipients) {
+		count = len(recipients)
+	}
+	for i := 0; i < count; i++ {
+		j := i + rand.Intn(len(recipients)-i)
+		recipients[i], recipients[j] = recipients[j], recipients[i]
+	}
+
+	probes := make(map[string][]byte, count)
+	for _, connID := range recipients[:count] {
+		t.sequence++
+		probeID := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatInt(t.sequence, 36)
+
+		data, err := json.Marshal(DeliveryProbe{
+			Type:      "delivery_probe",
+			ProbeID:   probeID,
+			Room:      roomName,
+			Timestamp: now,
+		})
+		if err != nil {
+			continue
+		}
+
+		t.pending[probeID] = &pendingProbe{room: roomName, connID: connID, sentAt: now}
+		probes[connID] = data
+	}
+	return probes
+}
+
+// Ack records a delivery ack, returning the measured latency
+func (t *DeliveryTracker) Ack(connID, probeID string) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	probe, exists := t.pending[probeID]
+	if !exists || probe.connID != connID {
+		return 0, false
+	}
+	delete(t.pending, probeID)
+
+	latency := time.Since(probe.sentAt)
+	room := t.roomLocked(probe.room)
+	if len(room.samples) < t.windowSize {
+		room.samples = append(room.samples, latency)
+	} else {
+		room.samples[room.next] = latency
+		room.next = (room.next + 1) % t.windowSize
+	}
+	return latency, true
+}
+
+// Stats returns delivery latency percentiles for every sampled room
+func (t *DeliveryTracker) Stats() []DeliveryStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.expireLocked(time.Now())
+
+	stats := make([]DeliveryStats, 0, len(t.rooms))
+	for roomName, room := range t.rooms {
+		entry := DeliveryStats{Room: roomName, Samples: len(room.samples), Timeouts: room.timeouts}
+		if len(room.samples) > 0 {
+			sorted := make([]time.Duration, len(room.samples))
+			copy(sorted, room.samples)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+			entry.P50Ms = percentileMs(sorted, 0.50)
+			entry.P90Ms = percentileMs(sorted, 0.90)
+			entry.P99Ms = percentileMs(sorted, 0.99)
+			entry.MaxMs = percentileMs(sorted, 1)
+		}
+		stats = append(stats, entry)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Room < stats[j].Room })
+	return stats
+}
+
+// Reap expires unacked probes (e.g. of closed connections) and forgets rooms not sampled recently.
+// ภายใต้ memory pressure จะล้าง latency window ของห้องที่ไม่ได้สุ่มวัดแล้วด้วย
+func (t *DeliveryTracker) Reap(aggressive bool) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	count := t.expireLocked(now)
+	for roomName, sampledAt := range t.lastSampled {
+		if now.Sub(sampledAt) <= t.interval {
+			continue
+		}
+		delete(t.lastSampled, roomName)
+		count++
+		if aggressive {
+			delete(t.rooms, roomName)
+		}
+	}
+	return count
+}
+
+// expireLocked drops probes that were never acked, counting them as timeouts (assumes lock is held)
+func (t *DeliveryTracker) expireLocked(now time.Time) int {
+	count := 0
+	for probeID, probe := range t.pending {
+		if now.Sub(probe.sentAt) > t.timeout {
+			t.roomLocked(probe.room).timeouts++
+			delete(t.pending, probeID)
+			count++
+		}
+	}
+	return count
+}
+
+// roomLocked returns the latency window for a room, creating it if needed (assumes lock is held)
+func (t *DeliveryTracker) roomLocked(roomName string) *roomLatency {
+	room, exists := t.rooms[roomName]
+	if !exists {
+		room = &roomLatency{}
+		t.rooms[roomName] = room
+	}
+	return room
+}
+
+// percentileMs returns the p-th percentile of sorted durations in milliseconds
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	index := int(p*float64(len(sorted))+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return float64(sorted[index].Microseconds()) / 1000
+}
@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"github.com/gorilla/websocket"
+)
+
+// FrameEncoder converts outbound JSON frames to a connection's wire format (see chat.Codec).
+// ใช้ interface เพื่อหลีกเลี่ยง import cycle กับ chat package
+type FrameEncoder interface {
+	EncodeFrame(frame []byte) ([]byte, error)
+	MessageType() int // websocket.TextMessage หรือ websocket.BinaryMessage
+}
+
+// wireFrame returns a queued frame in the connection's wire format.
+// encode ใน writePump เพื่อให้ทุกทาง (Send, broadcast, probe, close notice) ผ่านจุดเดียว
+// และงาน encode กระจายไปตาม goroutine ของแต่ละ connection
+func (c *WebSocketConnection) wireFrame(frame []byte) ([]byte, int) {
+	if c.encoder == nil {
+		return frame, websocket.TextMessage
+	}
+	encoded, err := c.encoder.EncodeFrame(frame)
+	if err != nil {
+		c.Logger().Warn("⚠️ Failed to encode frame, sending as text", "error", err)
+		return frame, websocket.TextMessage
+	}
+	return encoded, c.encoder.MessageType()
+}
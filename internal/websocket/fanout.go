@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// fanoutPool spreads large broadcasts over a fixed set of workers.
+// แต่ละ connection อยู่ shard เดียวเสมอ (hash ของ connection ID) และ broadcast รอทุก shard เสร็จก่อน return
+// ผู้รับทุกคนจึงยังเห็นข้อความตามลำดับเดียวกับ Run loop เหมือนตอนส่งทีละ connection
+type fanoutPool struct {
+	minRecipients int
+	shards        []chan fanoutJob
+	running       bool
+	mutex         sync.RWMutex
+	workers       sync.WaitGroup
+}
+
+// fanoutJob is one shard's part of a broadcast
+type fanoutJob struct {
+	conns  []*WebSocketConnection
+	frame  []byte
+	result *fanoutResult
+	done   *sync.WaitGroup
+}
+
+// fanoutResult collects the connections that got a frame and the slow consumers to close
+type fanoutResult struct {
+	recipients []string
+	evict      []*WebSocketConnection
+}
+
+// newFanoutPool creates a pool of workers; it returns nil when fewer than two workers are configured
+func newFanoutPool(workers, minRecipients int) *fanoutPool {
+	if workers <= 1 {
+		return nil
+	}
+	p := &fanoutPool{minRecipients: minRecipients, shards: make([]chan fanoutJob, workers)}
+	for i := range p.shards {
+		p.shards[i] = make(chan fanoutJob, 1)
+	}
+	return p
+}
+
+// start launches the workers (called by Run)
+func (p *fanoutPool) start() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.running {
+		return
+	}
+	p.running = true
+	for _, jobs := range p.shards {
+		p.workers.Add(1)
+		go func(jobs chan fanoutJob) {
+			defer p.workers.Done()
+			for job := range jobs {
+				*job.result = offerAll(job.conns, job.frame)
+				job.done.Done()
+			}
+		}(jobs)
+	}
+}
+
+// stop stops the workers; later broadcasts are sent by the caller (e.g. unregister after Run returned)
+func (p *fanoutPool) stop() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	if !p.running {
+		p.mutex.Unlock()
+		return
+	}
+	p.running = false
+	for i, jobs := range p.shards {
+		close(jobs)
+		p.shards[i] = make(chan fanoutJob, 1)
+	}
+	p.mutex.Unlock()
+	p.workers.Wait()
+}
+
+// deliver offers a frame to conns across the workers and waits for all of them.
+// คืน false เมื่อไม่ได้แบ่งงาน (pool ปิดอยู่ หรือผู้รับน้อยกว่า fanout_min_recipients) ให้ผู้เรียกส่งเอง
+func (p *fanoutPool) deliver(conns []*WebSocketConnection, frame []byte) (fanoutResult, bool) {
+	if p == nil || len(conns) < p.minRecipients {
+		return fanoutResult{}, false
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if !p.running {
+		return fanoutResult{}, false
+	}
+
+	parts := make([][]*WebSocketConnection, len(p.shards))
+	for _, conn := range conns {
+		shard := shardOf(conn.ID, len(p.shards))
+		parts[shard] = append(parts[shard], conn)
+	}
+
+	results := make([]fanoutResult, len(p.shards))
+	var done sync.WaitGroup
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		done.Add(1)
+		p.shards[i] <- fanoutJob{conns: part, frame: frame, result: &results[i], done: &done}
+	}
+	done.Wait()
+
+	merged := fanoutResult{recipients: make([]string, 0, len(conns))}
+	for _, result := range results {
+		merged.recipients = append(merged.recipients, result.recipients...)
+		merged.evict = append(merged.evict, result.evict...)
+	}
+	return merged, true
+}
+
+// offerAll offers a frame to each connection under the slow consumer policy
+func offerAll(conns []*WebSocketConnection, frame []byte) fanoutResult {
+	result := fanoutResult{recipients: make([]string, 0, len(conns))}
+	for _, conn := range conns {
+		// คิวเต็มจัดการตาม slow consumer policy (ดู backpressure.go)
+		queued, _, evict := conn.offer(frame)
+		if queued {
+			result.recipients = append(result.recipients, conn.ID)
+		}
+		if evict {
+			result.evict = append(result.evict, conn)
+		}
+	}
+	return result
+}
+
+// shardOf maps a connection ID to a worker
+func shardOf(connID string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(connID))
+	return int(h.Sum32() % uint32(shards))
+}
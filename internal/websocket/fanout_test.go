@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+
+	"realtime-chat/internal/config"
+)
+
+// benchmarkConns creates n connections whose queues are drained in the background like a write pump
+func benchmarkConns(b *testing.B, n int) []*WebSocketConnection {
+	b.Helper()
+	conns := make([]*WebSocketConnection, n)
+	for i := range conns {
+		conn := NewWebSocketConnection(fmt.Sprintf("conn-%d", i), nil)
+		conns[i] = conn
+		go func() {
+			for range conn.Send {
+			}
+		}()
+	}
+	b.Cleanup(func() {
+		for _, conn := range conns {
+			conn.closeSend()
+		}
+	})
+	return conns
+}
+
+// BenchmarkBroadcast compares offering one frame to a whole room from the Run loop
+// with splitting it over the sharded fan-out pool
+func BenchmarkBroadcast(b *testing.B) {
+	frame := []byte(`{"type":"message","content":"hello room","username":"alice","room_name":"general"}`)
+	workers := config.DefaultServerConfig().FanoutWorkers
+
+	for _, size := range []int{100, 1000, 10000} {
+		conns := benchmarkConns(b, size)
+
+		b.Run(fmt.Sprintf("sequential/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				offerAll(conns, frame)
+			}
+		})
+
+		b.Run(fmt.Sprintf("pool-%d/%d", workers, size), func(b *testing.B) {
+			pool := newFanoutPool(workers, 0)
+			pool.start()
+			defer pool.stop()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := pool.deliver(conns, frame); !ok {
+					b.Fatal("pool did not deliver")
+				}
+			}
+		})
+	}
+}
+
+// ผู้รับทุกคนได้ frame ครั้งเดียวไม่ว่าจะส่งเองหรือผ่าน pool
+func TestFanoutPoolDeliversOncePerConnection(t *testing.T) {
+	conns := make([]*WebSocketConnection, 64)
+	for i := range conns {
+		conns[i] = NewWebSocketConnection(fmt.Sprintf("conn-%d", i), nil)
+	}
+
+	pool := newFanoutPool(4, 16)
+	pool.start()
+	defer pool.stop()
+
+	if _, ok := pool.deliver(conns[:8], []byte("small")); ok {
+		t.Fatal("pool handled a broadcast below min recipients")
+	}
+	result, ok := pool.deliver(conns, []byte("frame"))
+	if !ok {
+		t.Fatal("pool did not deliver")
+	}
+	if len(result.recipients) != len(conns) || len(result.evict) != 0 {
+		t.Fatalf("recipients = %d, evict = %d", len(result.recipients), len(result.evict))
+	}
+	for _, conn := range conns {
+		if len(conn.Send) != 1 {
+			t.Fatalf("%s has %d frames queued", conn.ID, len(conn.Send))
+		}
+	}
+}
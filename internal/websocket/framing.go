@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+)
+
+const (
+	// minFrameSize keeps chunks large enough that the envelope isn't most of the frame
+	minFrameSize = 1024
+	// chunkEnvelopeOverhead reserves room for the JSON fields around each chunk's data
+	chunkEnvelopeOverhead = 128
+)
+
+// Chunk is one piece of an outbound payload that exceeded the max frame size.
+// client ต่อ data (base64) ทุกชิ้นตาม index แล้ว decode เป็น JSON ของข้อความเดิม
+type Chunk struct {
+	Type    string `json:"type"` // "chunk"
+	ChunkID string `json:"chunk_id"`
+	Index   int    `json:"index"`
+	Total   int    `json:"total"`
+	Data    string `json:"data"`
+}
+
+// FrameStats reports oversized outbound payload events
+type FrameStats struct {
+	MaxFrameSize      int   `json:"max_frame_size"`
+	OversizedPayloads int64 `json:"oversized_payloads"`
+	ChunksSent        int64 `json:"chunks_sent"`
+	LargestPayload    int64 `json:"largest_payload"`
+}
+
+// FrameGuard splits outbound payloads larger than the max frame size into chunk frames
+type FrameGuard struct {
+	maxSize   int
+	oversized atomic.Int64
+	chunks    atomic.Int64
+	largest   atomic.Int64
+}
+
+// NewFrameGuard creates a frame guard (maxSize <= 0 disables chunking)
+func NewFrameGuard(maxSize int) *FrameGuard {
+	if maxSize > 0 && maxSize < minFrameSize {
+		maxSize = minFrameSize
+	}
+	return &FrameGuard{maxSize: maxSize}
+}
+
+// Split returns the frames to send for a payload: the payload itself, or chunk envelopes when it is too large
+func (g *FrameGuard) Split(payload []byte) [][]byte {
+	if g == nil || g.maxSize <= 0 || len(payload) <= g.maxSize {
+		return [][]byte{payload}
+	}
+
+	g.oversized.Add(1)
+	for {
+		largest := g.largest.Load()
+		if int64(len(payload)) <= largest || g.largest.CompareAndSwap(largest, int64(len(payload))) {
+			break
+		}
+	}
+
+	// base64 ขยายขนาด 4/3 จึงคำนวณขนาดดิบต่อชิ้นจากพื้นที่ที่เหลือหลังหัก envelope
+	rawSize := (g.maxSize - chunkEnvelopeOverhead) / 4 * 3
+	total := (len(payload) + rawSize - 1) / rawSize
+	chunkID := NewCorrelationID()
+
+	frames := make([][]byte, 0, total)
+	for index := 0; index < total; index++ {
+		end := (index + 1) * rawSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		frame, err := json.Marshal(Chunk{
+			Type:    "chunk",
+			ChunkID: chunkID,
+			Index:   index,
+			Total:   total,
+			Data:    base64.StdEncoding.EncodeToString(payload[index*rawSize : end]),
+		})
+		if err != nil {
+			slog.Error("❌ Failed to marshal chunk frame", "error", err)
+			return [][]byte{payload}
+		}
+		frames = append(frames, frame)
+	}
+
+	g.chunks.Add(int64(len(frames)))
+	return frames
+}
+
+// Stats returns a snapshot of oversized payload counters
+func (g *FrameGuard) Stats() FrameStats {
+	return FrameStats{
+		MaxFrameSize:      g.maxSize,
+		OversizedPayloads: g.oversized.Load(),
+		ChunksSent:        g.chunks.Load(),
+		LargestPayload:    g.largest.Load(),
+	}
+}
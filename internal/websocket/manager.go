@@ -1,8 +1,10 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -14,10 +16,11 @@ import (
 // Connection interface for WebSocket connections (to avoid import cycle)
 type Connection interface {
 	GetID() string
+	GetLabel() string
+	GetCorrelationID() string
 	GetUser() interface{}
 	SetUser(user interface{})
 	SendMessage(message []byte) error
-	GetSendChannel() chan []byte
 	IsHealthy(timeout time.Duration) bool
 	GetHealthStats() *config.ConnectionHealth
 	Close() error
@@ -32,11 +35,20 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Formatted returns the text a connection receives for this message
+func (m *Message) Formatted() string {
+	if m.Type == "text" && m.Username != "" {
+		return fmt.Sprintf("[%s]: %s", m.Username, m.Content)
+	}
+	return m.Content
+}
+
 // BroadcastMessage represents a message with exclusion info (to avoid import cycle)
 type BroadcastMessage struct {
 	Message   *Message
 	ExcludeID string // ID ของ connection ที่ไม่ต้องการส่งไป
 	RoomName  string // ชื่อห้องที่จะส่งข้อความ (ถ้าว่างจะส่งให้ทุกคน)
+	EnqueuedAt time.Time // เวลาที่เข้าคิว broadcast ใช้วัด latency
 }
 
 // UserService interface (to avoid import cycle)
@@ -70,12 +82,30 @@ type Manager struct {
 	connections map[string]*WebSocketConnection
 	mutex       sync.RWMutex
 	broadcast   chan *BroadcastMessage
-	register    chan *WebSocketConnection
 	unregister  chan *WebSocketConnection
 	config      *config.ServerConfig
 	userService UserService
 	roomService RoomService
 	metrics     *config.ServerMetrics
+	delivery    *DeliveryTracker // optional broadcast delivery sampling
+	frames      *FrameGuard      // chunks unicast payloads larger than MaxOutboundFrameSize
+	latency     *config.LatencyRecorder // optional message path timing
+	backpressure *Backpressure       // slow consumer policy ของทุก connection
+
+	// roomIndex ให้ broadcast ในห้องวนเฉพาะ connection ที่อยู่ในห้องนั้น แทนการวนทุก connection
+	roomIndex map[string]map[string]struct{} // roomName -> connIDs
+	connRooms map[string]string              // connID -> roomName
+	roomMutex sync.RWMutex                   // แยกจาก mutex เพราะ LeaveRoom ถูกเรียกขณะถือ mutex อยู่
+
+	observers *roomObservers // ผู้ติดตามห้องที่ไม่ใช่ WebSocket (gRPC StreamRoomMessages)
+
+	fanout   *fanoutPool // nil = ส่ง broadcast ทีละ connection ใน Run loop
+	batcher  *Batcher    // nil = ปิด batching (ดู batching.go)
+	compressor *Compressor // nil = ปิด permessage-deflate (ดู compression.go)
+
+	stop     chan struct{} // ปิดโดย Stop
+	stopOnce sync.Once
+	stopped  chan struct{} // ปิดเมื่อ loop ของ Run จบแล้ว
 }
 
 // NewManager creates a new WebSocket manager
@@ -83,26 +113,82 @@ func NewManager(cfg *config.ServerConfig, userService UserService, roomService R
 	return &Manager{
 		connections: make(map[string]*WebSocketConnection),
 		broadcast:   make(chan *BroadcastMessage, cfg.BroadcastBuffer),
-		register:    make(chan *WebSocketConnection),
 		unregister:  make(chan *WebSocketConnection),
 		config:      cfg,
 		userService: userService,
 		roomService: roomService,
 		metrics:     metrics,
+		frames:      NewFrameGuard(cfg.MaxOutboundFrameSize),
+		backpressure: NewBackpressure(cfg.SlowConsumerPolicy, cfg.SendQueueSize, cfg.SlowConsumerMaxDrops),
+		roomIndex:   make(map[string]map[string]struct{}),
+		connRooms:   make(map[string]string),
+		observers:   newRoomObservers(),
+		fanout:      newFanoutPool(cfg.FanoutWorkers, cfg.FanoutMinRecipients),
+		batcher:     newManagerBatcher(cfg),
+		compressor:  NewCompressor(cfg),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 	}
 }
 
-// Run starts the manager's main loop
-func (m *Manager) Run() {
-	// เริ่ม health check goroutine ถ้า enable
+// FrameStats returns counters of outbound payloads that exceeded the max frame size
+func (m *Manager) FrameStats() FrameStats {
+	return m.frames.Stats()
+}
+
+// SetDeliveryTracker enables broadcast delivery latency sampling
+func (m *Manager) SetDeliveryTracker(tracker *DeliveryTracker) {
+	m.delivery = tracker
+}
+
+// SetLatencyRecorder enables fan-out timing of broadcasts
+func (m *Manager) SetLatencyRecorder(recorder *config.LatencyRecorder) {
+	m.latency = recorder
+}
+
+// RecordDeliveryAck records a delivery ack from a sampled connection
+func (m *Manager) RecordDeliveryAck(connID, probeID string) {
+	if m.delivery == nil {
+		return
+	}
+	m.delivery.Ack(connID, probeID)
+}
+
+// DeliveryStats returns per-room delivery latency percentiles (empty if sampling is disabled)
+func (m *Manager) DeliveryStats() []DeliveryStats {
+	if m.delivery == nil {
+		return []DeliveryStats{}
+	}
+	return m.delivery.Stats()
+}
+
+// Run starts the manager's main loop.
+// broadcast ทั้งหมดผ่าน loop นี้ตัวเดียวตามลำดับที่เข้า channel และ Send ของแต่ละ connection เป็น FIFO
+// ผู้รับทุกคนในห้องจึงเห็นข้อความตามลำดับเดียวกัน ห้องใหญ่ส่งผ่าน fan-out worker ได้ แต่ loop รอทุก worker
+// ก่อนหยิบ broadcast ถัดไป ลำดับนี้จึงยังเหมือนเดิม
+// Run returns when ctx is cancelled or Stop is called, after the health check has stopped.
+func (m *Manager) Run(ctx context.Context) error {
+	m.fanout.start()
+	defer m.fanout.stop()
+
+	// health check หยุดพร้อม loop หลัก (ดู stopped) และ Run รอให้จบก่อน return
+	var healthCheck sync.WaitGroup
 	if m.config.EnableHealthCheck {
-		go m.runHealthCheck()
+		healthCheck.Add(1)
+		go func() {
+			defer healthCheck.Done()
+			m.runHealthCheck()
+		}()
 	}
-	
+
+loop:
 	for {
 		select {
-		case conn := <-m.register:
-			m.registerConnection(conn)
+		case <-ctx.Done():
+			break loop
+
+		case <-m.stop:
+			break loop
 
 		case conn := <-m.unregister:
 			m.unregisterConnection(conn)
@@ -111,16 +197,76 @@ func (m *Manager) Run() {
 			m.broadcastMessage(broadcastMsg)
 		}
 	}
+
+	close(m.stopped)
+	healthCheck.Wait()
+	slog.Info("🛑 WebSocket manager stopped")
+	return nil
+}
+
+// Stop stops Run (and the health check); connections closed afterwards are unregistered directly
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// requestUnregister hands a connection to Run for removal, or removes it directly once Run has stopped
+func (m *Manager) requestUnregister(conn *WebSocketConnection) {
+	select {
+	case m.unregister <- conn:
+	case <-m.stopped:
+		m.unregisterConnection(conn)
+	}
 }
 
-// AddConnection adds a new WebSocket connection
-func (m *Manager) AddConnection(conn *websocket.Conn) string {
+// AddConnection registers a new WebSocket connection and starts its write pump.
+// hello (ถ้ามี) เป็น frame แรกที่ client ได้รับ; encoder (nil = JSON text) แปลงทุก frame ขาออกเป็น wire format ของ connection
+// คืนค่า false ถ้าเซิร์ฟเวอร์เต็มและ connection ถูกปฏิเสธ
+// register เสร็จก่อน return เสมอ ผู้เรียกจึงเริ่มอ่านข้อความได้ทันทีโดยไม่ต้องรอ
+func (m *Manager) AddConnection(conn *websocket.Conn, hello []byte, encoder FrameEncoder) (string, bool) {
 	connID := GenerateConnectionID()
 	
 	wsConn := NewWebSocketConnection(connID, conn)
-	m.register <- wsConn
+	wsConn.Send = make(chan []byte, m.backpressure.QueueSize())
+	wsConn.frames = m.frames
+	wsConn.batcher = m.batcher
+	wsConn.compressor = m.compressor
+	wsConn.encoder = encoder
+	wsConn.backpressure = m.backpressure
+	wsConn.onDrop = m.metrics.RecordDroppedFrame
+	wsConn.evict = m.evictSlowConsumer
+	if hello != nil {
+		wsConn.trySend(hello)
+	}
+	if !m.registerConnection(wsConn) {
+		return "", false
+	}
 	
-	return connID
+	return connID, true
+}
+
+// SendMessage queues a message for a connection by ID.
+// frame ที่ส่งหลัง unregister ปิด Send แล้วจะถูกทิ้ง (ดู WebSocketConnection.closeSend)
+func (m *Manager) SendMessage(connID string, message []byte) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	conn, exists := m.connections[connID]
+	if !exists {
+		return fmt.Errorf("connection %s not found", connID)
+	}
+	return conn.SendMessage(message)
+}
+
+// SendUntraced queues a message for a connection by ID without emitting trace events (see trace.go)
+func (m *Manager) SendUntraced(connID string, message []byte) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	conn, exists := m.connections[connID]
+	if !exists {
+		return fmt.Errorf("connection %s not found", connID)
+	}
+	return conn.SendUntraced(message)
 }
 
 // RemoveConnection removes a connection
@@ -130,7 +276,7 @@ func (m *Manager) RemoveConnection(connID string) {
 	m.mutex.RUnlock()
 
 	if exists {
-		m.unregister <- conn
+		m.requestUnregister(conn)
 	}
 }
 
@@ -149,99 +295,131 @@ func (m *Manager) GetConnectionCount() int {
 	return len(m.connections)
 }
 
+// Usernames returns the username of every joined connection, keyed by connection ID.
+// อ่านจาก connection ในหน่วยความจำ จึงใช้ได้แม้ user repository (ฐานข้อมูล) จะล่ม
+func (m *Manager) Usernames() map[string]string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	usernames := make(map[string]string, len(m.connections))
+	for connID, conn := range m.connections {
+		if user, ok := conn.GetUser().(UserInterface); ok && user != nil && user.GetIsAuthenticated() {
+			usernames[connID] = user.GetUsername()
+		}
+	}
+	return usernames
+}
+
 // BroadcastMessage broadcasts a message to all connections except sender (adapter for interface compatibility)
 func (m *Manager) BroadcastMessage(message interface{}, excludeID string) {
-	// Convert interface{} message to our internal Message type
-	if msg, ok := message.(*Message); ok {
-		m.BroadcastToRoom(msg, excludeID, "")
-	} else if msgPkg, ok := message.(*messagePkg.Message); ok {
-		// Convert from message.Message type
-		msg := &Message{
-			Type:      msgPkg.Type,
-			Content:   msgPkg.Content,
-			Sender:    msgPkg.Sender,
-			Username:  msgPkg.Username,
-			Timestamp: msgPkg.Timestamp,
-		}
-		m.BroadcastToRoom(msg, excludeID, "")
-	} else {
-		// Try to convert from chat.Message type
-		if chatMsg, ok := message.(MessageInterface); ok {
-			msg := &Message{
-				Type:      chatMsg.GetType(),
-				Content:   chatMsg.GetContent(),
-				Sender:    chatMsg.GetSender(),
-				Username:  chatMsg.GetUsername(),
-				Timestamp: chatMsg.GetTimestamp(),
-			}
-			m.BroadcastToRoom(msg, excludeID, "")
-		} else {
-			log.Printf("⚠️ Unknown message type in BroadcastMessage: %T", message)
-		}
+	msg, ok := ToMessage(message)
+	if !ok {
+		slog.Warn("⚠️ Unknown message type in BroadcastMessage", "type", fmt.Sprintf("%T", message))
+		return
 	}
+	m.BroadcastToRoom(msg, excludeID, "")
 }
 
 // BroadcastToRoom broadcasts a message to connections in a specific room (adapter for interface compatibility)
 func (m *Manager) BroadcastToRoom(message interface{}, excludeID, roomName string) {
-	var msg *Message
-	
-	// Convert interface{} message to our internal Message type
-	if m, ok := message.(*Message); ok {
-		msg = m
-	} else if msgPkg, ok := message.(*messagePkg.Message); ok {
-		// Convert from message.Message type
-		msg = &Message{
-			Type:      msgPkg.Type,
-			Content:   msgPkg.Content,
-			Sender:    msgPkg.Sender,
-			Username:  msgPkg.Username,
-			Timestamp: msgPkg.Timestamp,
-		}
-	} else {
-		// Try to convert from chat.Message type
-		if chatMsg, ok := message.(MessageInterface); ok {
-			msg = &Message{
-				Type:      chatMsg.GetType(),
-				Content:   chatMsg.GetContent(),
-				Sender:    chatMsg.GetSender(),
-				Username:  chatMsg.GetUsername(),
-				Timestamp: chatMsg.GetTimestamp(),
-			}
-		} else {
-			log.Printf("⚠️ Unknown message type in BroadcastToRoom: %T", message)
-			return
-		}
+	msg, ok := ToMessage(message)
+	if !ok {
+		slog.Warn("⚠️ Unknown message type in BroadcastToRoom", "type", fmt.Sprintf("%T", message))
+		return
 	}
 
 	broadcastMsg := &BroadcastMessage{
 		Message:   msg,
 		ExcludeID: excludeID,
 		RoomName:  roomName,
+		EnqueuedAt: time.Now(),
 	}
 
 	select {
 	case m.broadcast <- broadcastMsg:
 	default:
-		log.Println("⚠️ Broadcast channel is full, dropping message")
+		slog.Warn("⚠️ Broadcast channel is full, dropping message", "room", roomName)
 	}
 }
 
-// registerConnection adds a new connection
-func (m *Manager) registerConnection(conn *WebSocketConnection) {
+// ToMessage converts the message types accepted by BroadcastToRoom to the internal Message.
+// cluster mode ใช้ส่ง broadcast ข้าม node ในรูป JSON ของ Message
+func ToMessage(message interface{}) (*Message, bool) {
+	if msg, ok := message.(*Message); ok {
+		return msg, true
+	}
+	if data, ok := message.([]byte); ok {
+		// JSON ที่ encode มาแล้ว (เช่น ServerMessage) ส่งต่อแบบ raw
+		return &Message{Type: "json", Content: string(data)}, true
+	}
+	if msgPkg, ok := message.(*messagePkg.Message); ok {
+		// ส่ง JSON เต็มของข้อความ client จึงได้ id, parent_id, seq, mentions, display_name/avatar_url และ probe_id
+		data, err := json.Marshal(msgPkg)
+		if err != nil {
+			return nil, false
+		}
+		return &Message{
+			Type:      "json",
+			Content:   string(data),
+			Sender:    msgPkg.Sender,
+			Username:  msgPkg.Username,
+			Timestamp: msgPkg.Timestamp,
+		}, true
+	}
+	// Try to convert from chat.Message type
+	if chatMsg, ok := message.(MessageInterface); ok {
+		return &Message{
+			Type:      chatMsg.GetType(),
+			Content:   chatMsg.GetContent(),
+			Sender:    chatMsg.GetSender(),
+			Username:  chatMsg.GetUsername(),
+			Timestamp: chatMsg.GetTimestamp(),
+		}, true
+	}
+	return nil, false
+}
+
+// LocalRooms returns the rooms that have at least one connection or room observer on this server
+func (m *Manager) LocalRooms() []string {
+	m.roomMutex.RLock()
+	defer m.roomMutex.RUnlock()
+
+	rooms := make([]string, 0, len(m.roomIndex))
+	for roomName, members := range m.roomIndex {
+		if len(members) > 0 {
+			rooms = append(rooms, roomName)
+		}
+	}
+	// ห้องที่มีผู้ติดตามผ่าน gRPC ต้องได้รับ broadcast จาก node อื่นด้วย
+	for _, roomName := range m.observers.rooms() {
+		if len(m.roomIndex[roomName]) == 0 {
+			rooms = append(rooms, roomName)
+		}
+	}
+	return rooms
+}
+
+// registerConnection adds a new connection and starts its write pump
+func (m *Manager) registerConnection(conn *WebSocketConnection) bool {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	// ตรวจสอบ connection limits
 	if len(m.connections) >= m.config.MaxConnections {
-		log.Printf("❌ Connection limit reached, rejecting: %s", conn.ID)
-		conn.Conn.WriteMessage(websocket.TextMessage, []byte("❌ เซิร์ฟเวอร์เต็ม กรุณาลองใหม่ภายหลัง"))
-		conn.Conn.Close()
-		return
+		conn.Logger().Warn("❌ Connection limit reached, rejecting", "max_connections", m.config.MaxConnections)
+		policy, _ := json.Marshal(m.reconnectPolicy(CloseServerFull, len(m.connections)))
+		go func() {
+			conn.Conn.WriteMessage(websocket.TextMessage, []byte("❌ เซิร์ฟเวอร์เต็ม กรุณาลองใหม่ภายหลัง"))
+			conn.SendClose(CloseServerFull, policy)
+			conn.Conn.Close()
+		}()
+		return false
 	}
 
 	m.connections[conn.ID] = conn
+	go conn.writePump(m.config.HeartbeatInterval, m.config.WriteTimeout, m.latency)
 	m.metrics.IncrementConnections()
-	log.Printf("📝 Connection registered: %s (Total: %d/%d)", conn.ID, len(m.connections), m.config.MaxConnections)
+	conn.Logger().Info("📝 Connection registered", "label", conn.GetLabel(), "total", len(m.connections), "max_connections", m.config.MaxConnections)
 
 	// ส่งข้อความขอ username
 	authMsg := &Message{
@@ -252,12 +430,14 @@ func (m *Manager) registerConnection(conn *WebSocketConnection) {
 		Timestamp: time.Now(),
 	}
 
-	select {
-	case conn.Send <- []byte(authMsg.Content):
-	default:
-		close(conn.Send)
+	if !conn.trySend([]byte(authMsg.Content)) {
+		conn.closeSend()
 		delete(m.connections, conn.ID)
+		m.untrackConnection(conn.ID)
+		m.metrics.DecrementConnections()
+		return false
 	}
+	return true
 }
 
 // unregisterConnection removes a connection
@@ -267,9 +447,9 @@ func (m *Manager) unregisterConnection(conn *WebSocketConnection) {
 
 	if _, exists := m.connections[conn.ID]; exists {
 		// ถ้ามี user ให้แจ้งเตือนคนอื่น
-		if conn.User != nil {
+		if connUser := conn.GetUser(); connUser != nil {
 			// Type assertion to access user fields
-			if user, ok := conn.User.(UserInterface); ok && user.GetIsAuthenticated() {
+			if user, ok := connUser.(UserInterface); ok && user.GetIsAuthenticated() {
 				// ส่งข้อความแจ้งว่ามีคนออก
 				leaveMsg := &Message{
 					Type:      "user_left",
@@ -279,15 +459,15 @@ func (m *Manager) unregisterConnection(conn *WebSocketConnection) {
 					Timestamp: time.Now(),
 				}
 				
-				// Broadcast ข้อความแจ้งให้คนอื่นรู้
-				m.broadcastMessage(&BroadcastMessage{
+				// Broadcast ข้อความแจ้งให้คนอื่นรู้ (ถือ lock อยู่แล้ว)
+				m.broadcastLocked(&BroadcastMessage{
 					Message:   leaveMsg,
 					ExcludeID: "", // ส่งให้ทุกคน
 				})
 
 				// ออกจากห้องปัจจุบัน
 				if user.GetCurrentRoom() != "" {
-					m.roomService.LeaveRoom(conn.User, user.GetCurrentRoom())
+					m.roomService.LeaveRoom(connUser, user.GetCurrentRoom())
 				}
 
 				// ลบ user จาก user service
@@ -297,55 +477,76 @@ func (m *Manager) unregisterConnection(conn *WebSocketConnection) {
 		}
 
 		delete(m.connections, conn.ID)
-		close(conn.Send)
+		m.untrackConnection(conn.ID)
+		conn.closeSend()
 		m.metrics.DecrementConnections()
-		log.Printf("🗑️ Connection unregistered: %s (Total: %d/%d)", conn.ID, len(m.connections), m.config.MaxConnections)
+		conn.Logger().Info("🗑️ Connection unregistered", "label", conn.GetLabel(), "total", len(m.connections), "max_connections", m.config.MaxConnections)
 	}
 }
 
-// broadcastMessage sends a message to all connections except the sender
+// broadcastMessage sends a message to all connections except the sender.
+// read lock พอ เพราะ slow consumer ไม่ถูกลบระหว่าง broadcast แต่ส่งต่อให้ unregister (ดู evictSlowConsumer)
 func (m *Manager) broadcastMessage(broadcastMsg *BroadcastMessage) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
+	m.broadcastLocked(broadcastMsg)
+}
 
+// broadcastLocked sends a broadcast; room broadcasts only visit connections in the room index (assumes lock is held, read or write)
+func (m *Manager) broadcastLocked(broadcastMsg *BroadcastMessage) {
 	message := broadcastMsg.Message
 	excludeID := broadcastMsg.ExcludeID
+	excludeLabel := excludeID
+	if excluded, exists := m.connections[excludeID]; exists {
+		excludeLabel = excluded.GetLabel()
+	}
 	roomName := broadcastMsg.RoomName
-	sentCount := 0
 
 	// สร้างข้อความที่จะส่ง
-	var formattedMessage string
-	if message.Type == "text" && message.Username != "" {
-		formattedMessage = fmt.Sprintf("[%s]: %s", message.Username, message.Content)
-	} else {
-		formattedMessage = message.Content
+	formattedMessage := message.Formatted()
+
+	targets := m.connections
+	if roomName != "" {
+		targets = m.roomConnectionsLocked(roomName)
 	}
 
-	for connID, conn := range m.connections {
+	conns := make([]*WebSocketConnection, 0, len(targets))
+	for connID, conn := range targets {
 		// ไม่ส่งข้อความกลับไปยังผู้ส่ง
 		if connID == excludeID {
 			continue
 		}
+		conns = append(conns, conn)
+	}
 
-		// ตรวจสอบว่า connection มี user และอยู่ในห้องที่ถูกต้องหรือไม่
-		if roomName != "" && conn.User != nil {
-			// Type assertion to access CurrentRoom field
-			if user, ok := conn.User.(UserInterface); ok {
-				if user.GetCurrentRoom() != roomName {
-					continue // ไม่อยู่ในห้องเดียวกัน ข้าม
-				}
+	// ห้องใหญ่แบ่ง connection ให้ fan-out worker ส่งพร้อมกัน (ดู fanout.go)
+	// ทุก connection ใช้ frame ชุดเดียวกัน write pump อ่านอย่างเดียว
+	frame := []byte(formattedMessage)
+	delivered, parallel := m.fanout.deliver(conns, frame)
+	if !parallel {
+		delivered = offerAll(conns, frame)
+	}
+	for _, conn := range delivered.evict {
+		m.evictSlowConsumer(conn)
+	}
+	recipients := delivered.recipients
+	sentCount := len(recipients)
+
+	// สุ่มส่ง probe ตามหลังข้อความ เพื่อวัดเวลาที่ข้อความไปถึง client จริง
+	if m.delivery != nil && roomName != "" {
+		for connID, probe := range m.delivery.Sample(roomName, recipients) {
+			if conn, exists := m.connections[connID]; exists {
+				conn.trySend(probe)
 			}
 		}
+	}
 
-		select {
-		case conn.Send <- []byte(formattedMessage):
-			sentCount++
-		default:
-			// Connection ไม่ตอบสนอง ลบออก
-			close(conn.Send)
-			delete(m.connections, connID)
-			log.Printf("🔌 Removed unresponsive connection: %s", connID)
-		}
+	if roomName != "" {
+		m.observers.notify(roomName, message)
+	}
+
+	if !broadcastMsg.EnqueuedAt.IsZero() {
+		m.latency.ObserveSince(config.StageFanout, broadcastMsg.EnqueuedAt)
 	}
 
 	// นับ message metrics
@@ -354,9 +555,97 @@ func (m *Manager) broadcastMessage(broadcastMsg *BroadcastMessage) {
 	}
 
 	if roomName != "" {
-		log.Printf("📡 Broadcasted message to %d connections in room '%s' (excluded: %s)", sentCount, roomName, excludeID)
+		slog.Debug("📡 Broadcasted message", "room", roomName, "sent", sentCount, "excluded", excludeLabel)
 	} else {
-		log.Printf("📡 Broadcasted message to %d connections (excluded: %s)", sentCount, excludeID)
+		slog.Debug("📡 Broadcasted message", "sent", sentCount, "excluded", excludeLabel)
+	}
+}
+
+// TrackRoomMembership updates the room delivery index after a user joins or leaves a room
+func (m *Manager) TrackRoomMembership(roomName, connID string, joined bool) {
+	m.roomMutex.Lock()
+	defer m.roomMutex.Unlock()
+
+	if !joined {
+		if m.connRooms[connID] == roomName {
+			m.untrackLocked(connID)
+		}
+		return
+	}
+
+	m.untrackLocked(connID) // connection อยู่ได้ทีละห้อง
+	members, exists := m.roomIndex[roomName]
+	if !exists {
+		members = make(map[string]struct{})
+		m.roomIndex[roomName] = members
+	}
+	members[connID] = struct{}{}
+	m.connRooms[connID] = roomName
+}
+
+// untrackConnection removes a closed connection from the room index
+func (m *Manager) untrackConnection(connID string) {
+	m.roomMutex.Lock()
+	defer m.roomMutex.Unlock()
+	m.untrackLocked(connID)
+}
+
+// untrackLocked removes a connection from its indexed room (assumes roomMutex is held)
+func (m *Manager) untrackLocked(connID string) {
+	roomName, exists := m.connRooms[connID]
+	if !exists {
+		return
+	}
+	delete(m.connRooms, connID)
+	delete(m.roomIndex[roomName], connID)
+	if len(m.roomIndex[roomName]) == 0 {
+		delete(m.roomIndex, roomName)
+	}
+}
+
+// roomConnectionsLocked returns the live connections indexed in a room (assumes mutex is held).
+// ยืนยัน CurrentRoom ซ้ำเฉพาะสมาชิกในห้อง เพราะการปิดห้องย้ายผู้ใช้ออกโดยไม่ผ่าน LeaveRoom
+func (m *Manager) roomConnectionsLocked(roomName string) map[string]*WebSocketConnection {
+	m.roomMutex.RLock()
+	connIDs := make([]string, 0, len(m.roomIndex[roomName]))
+	for connID := range m.roomIndex[roomName] {
+		connIDs = append(connIDs, connID)
+	}
+	m.roomMutex.RUnlock()
+
+	targets := make(map[string]*WebSocketConnection, len(connIDs))
+	for _, connID := range connIDs {
+		conn, exists := m.connections[connID]
+		if !exists {
+			continue
+		}
+		if user, ok := conn.GetUser().(UserInterface); ok && user.GetCurrentRoom() != roomName {
+			m.TrackRoomMembership(roomName, connID, false)
+			continue
+		}
+		targets[connID] = conn
+	}
+	return targets
+}
+
+// Shutdown sends a reconnect policy and server_shutdown close frame to every connection.
+// การปิดผ่าน Send channel ให้ write loop ของแต่ละ connection เป็นคนส่ง close frame เอง
+func (m *Manager) Shutdown() {
+	m.mutex.Lock()
+	count := len(m.connections)
+	for connID, conn := range m.connections {
+		m.markClose(conn, CloseServerShutdown, count)
+		conn.closeSend()
+		delete(m.connections, connID)
+		m.untrackConnection(connID)
+	}
+	m.mutex.Unlock()
+
+	slog.Info("🛑 Closed WebSocket connections", "count", count)
+
+	// รอให้ write loop ส่ง close frame ออกไปก่อนปิด server
+	if count > 0 {
+		time.Sleep(closeWriteWait)
 	}
 }
 
@@ -365,41 +654,57 @@ func (m *Manager) runHealthCheck() {
 	ticker := time.NewTicker(m.config.HealthCheckInterval)
 	defer ticker.Stop()
 	
-	log.Printf("💓 Starting connection health monitor (interval: %v)", m.config.HealthCheckInterval)
+	slog.Info("💓 Starting connection health monitor", "interval", m.config.HealthCheckInterval)
 	
 	for {
 		select {
 		case <-ticker.C:
 			m.performHealthCheck()
+		case <-m.stopped:
+			return
 		}
 	}
 }
 
-// performHealthCheck checks health of all connections and removes unhealthy ones
+// performHealthCheck checks health of all connections and removes unhealthy and idle ones
 func (m *Manager) performHealthCheck() {
 	m.mutex.RLock()
 	unhealthyConnections := make([]*WebSocketConnection, 0)
+	idleConnections := make([]*WebSocketConnection, 0)
 	healthyCount := 0
 	
 	for _, conn := range m.connections {
 		if !conn.IsHealthy(m.config.PongTimeout) {
 			unhealthyConnections = append(unhealthyConnections, conn)
+		} else if m.config.ConnectionTimeout > 0 && conn.IdleFor() > m.config.ConnectionTimeout {
+			// ตอบ ping ได้แต่ client ไม่ได้ส่งอะไรมานาน (heartbeat ไม่นับ) เช่นแท็บที่เปิดทิ้งไว้
+			idleConnections = append(idleConnections, conn)
 		} else {
 			healthyCount++
 		}
 	}
 	m.mutex.RUnlock()
 	
+	connCount := healthyCount + len(unhealthyConnections) + len(idleConnections)
+	
 	// ลบ connections ที่ไม่ healthy
 	for _, conn := range unhealthyConnections {
-		log.Printf("💔 Removing unhealthy connection: %s (missed pongs: %d)", 
-			conn.ID, conn.Health.GetStats().MissedPongs)
-		m.unregister <- conn
+		conn.Logger().Warn("💔 Removing unhealthy connection", "label", conn.GetLabel(), "missed_pongs", conn.Health.GetStats().MissedPongs)
+		conn.Trace(TraceHealth, 0, "unhealthy: pong timeout, closing")
+		m.markClose(conn, CloseUnhealthy, connCount)
+		m.requestUnregister(conn)
 	}
 	
-	if len(unhealthyConnections) > 0 {
-		log.Printf("💓 Health check completed: %d healthy, %d removed", 
-			healthyCount, len(unhealthyConnections))
+	for _, conn := range idleConnections {
+		idle := conn.IdleFor().Round(time.Second)
+		conn.Logger().Info("💤 Disconnecting idle connection", "label", conn.GetLabel(), "idle", idle)
+		conn.Trace(TraceHealth, 0, fmt.Sprintf("idle for %v, closing", idle))
+		m.markClose(conn, CloseIdleTimeout, connCount)
+		m.requestUnregister(conn)
+	}
+	
+	if len(unhealthyConnections) > 0 || len(idleConnections) > 0 {
+		slog.Info("💓 Health check completed", "healthy", healthyCount, "removed", len(unhealthyConnections), "idle", len(idleConnections))
 	}
 }
 
@@ -414,14 +719,40 @@ func (m *Manager) GetConnectionHealth(connID string) (*config.ConnectionHealth,
 	return nil, false
 }
 
-// GetAllConnectionsHealth returns health statistics for all connections
+// GetAllConnectionsHealth returns health statistics for all connections, keyed by connection label
 func (m *Manager) GetAllConnectionsHealth() map[string]*config.ConnectionHealth {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 	
 	healthStats := make(map[string]*config.ConnectionHealth)
-	for id, conn := range m.connections {
-		healthStats[id] = conn.GetHealthStats()
+	for _, conn := range m.connections {
+		healthStats[conn.GetLabel()] = conn.GetHealthStats()
 	}
 	return healthStats
-}
\ No newline at end of file
+}
+
+// Disconnect force-closes a connection by ID or label with the kicked close code
+// (clients are told not to reconnect automatically); returns the label of the closed connection
+func (m *Manager) Disconnect(target string) (string, bool) {
+	m.mutex.RLock()
+	conn, exists := m.connections[target]
+	if !exists {
+		for _, candidate := range m.connections {
+			if candidate.GetLabel() == target {
+				conn, exists = candidate, true
+				break
+			}
+		}
+	}
+	connCount := len(m.connections)
+	m.mutex.RUnlock()
+
+	if !exists {
+		return "", false
+	}
+	label := conn.GetLabel()
+	m.markClose(conn, CloseKicked, connCount)
+	m.requestUnregister(conn)
+	conn.Logger().Info("👢 Force-disconnected", "label", label)
+	return label, true
+}
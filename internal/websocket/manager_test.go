@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"realtime-chat/internal/config"
+	messagePkg "realtime-chat/internal/message"
+)
+
+// client ใช้ id (react, mark_read), parent_id (thread), seq (timeline) และ mentions จาก broadcast โดยตรง
+func TestToMessageCarriesMessageFields(t *testing.T) {
+	sent := &messagePkg.Message{
+		ID:          "42",
+		Type:        "message",
+		Content:     "hi @bob",
+		Sender:      "conn-1",
+		Username:    "alice",
+		RoomName:    "general",
+		Timestamp:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ParentID:    "41",
+		Seq:         7,
+		ProbeID:     "alice-1",
+		Mentions:    []string{"bob"},
+		DisplayName: "Alice",
+		AvatarURL:   "https://example.com/a.png",
+	}
+
+	msg, ok := ToMessage(sent)
+	if !ok {
+		t.Fatal("ToMessage rejected *message.Message")
+	}
+
+	var received messagePkg.Message
+	if err := json.Unmarshal([]byte(msg.Formatted()), &received); err != nil {
+		t.Fatalf("frame is not JSON: %v (%q)", err, msg.Formatted())
+	}
+	if !reflect.DeepEqual(&received, sent) {
+		t.Fatalf("frame lost fields:\n got  %+v\n want %+v", received, *sent)
+	}
+}
+
+// broadcast (ผ่าน fan-out worker) พร้อมกับที่ connection ออกและถูกปิดเป็น slow consumer (รันด้วย -race)
+func TestBroadcastWhileConnectionsLeave(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	cfg.SendQueueSize = 2
+	cfg.SlowConsumerPolicy = PolicyDisconnect
+	cfg.FanoutMinRecipients = 1
+	metrics := config.NewServerMetrics()
+	manager := NewManager(cfg, nil, nil, metrics)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Run(ctx)
+
+	var ids []string
+	for i := 0; i < 16; i++ {
+		id, ok := manager.AddConnection(newTestConn(t), nil, nil)
+		if !ok {
+			t.Fatalf("connection %d rejected", i)
+		}
+		ids = append(ids, id)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			manager.BroadcastMessage(&Message{Type: "text", Username: "alice", Content: fmt.Sprint(i)}, "")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, id := range ids[:8] {
+			manager.RemoveConnection(id)
+			manager.SendMessage(id, []byte("after remove"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			manager.GetConnectionCount()
+			manager.BatchStats()
+			manager.CompressionStats()
+		}
+	}()
+	wg.Wait()
+
+	// รอให้ unregister ที่ค้างใน Run loop ทำเสร็จ
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.GetConnectionCount() > 8 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := manager.GetConnectionCount(); count > 8 {
+		t.Fatalf("%d connections left, want at most 8", count)
+	}
+	if active := metrics.GetMetrics().ActiveConnections; active != int64(manager.GetConnectionCount()) {
+		t.Fatalf("active connections gauge = %d, manager has %d", active, manager.GetConnectionCount())
+	}
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import "sync"
+
+// RoomObserver receives every broadcast delivered to a room on this server.
+// ถูกเรียกขณะ manager กระจายข้อความ จึงต้องไม่ block (ส่งต่อเข้า channel แบบไม่รอ)
+type RoomObserver func(roomName string, message *Message)
+
+// roomObservers holds the observers of each room
+type roomObservers struct {
+	observers map[string]map[int]RoomObserver // roomName -> observer ID -> observer
+	nextID    int
+	mutex     sync.RWMutex
+}
+
+// newRoomObservers creates an empty observer registry
+func newRoomObservers() *roomObservers {
+	return &roomObservers{observers: make(map[string]map[int]RoomObserver)}
+}
+
+// add registers an observer and returns the function that removes it
+func (o *roomObservers) add(roomName string, observer RoomObserver) func() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.nextID++
+	id := o.nextID
+	if o.observers[roomName] == nil {
+		o.observers[roomName] = make(map[int]RoomObserver)
+	}
+	o.observers[roomName][id] = observer
+
+	return func() {
+		o.mutex.Lock()
+		defer o.mutex.Unlock()
+		delete(o.observers[roomName], id)
+		if len(o.observers[roomName]) == 0 {
+			delete(o.observers, roomName)
+		}
+	}
+}
+
+// notify passes a room broadcast to the room's observers
+func (o *roomObservers) notify(roomName string, message *Message) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	for _, observer := range o.observers[roomName] {
+		observer(roomName, message)
+	}
+}
+
+// rooms returns the rooms that have at least one observer
+func (o *roomObservers) rooms() []string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	rooms := make([]string, 0, len(o.observers))
+	for roomName := range o.observers {
+		rooms = append(rooms, roomName)
+	}
+	return rooms
+}
+
+// ObserveRoom registers an observer for a room's broadcasts; call the returned function to stop observing
+func (m *Manager) ObserveRoom(roomName string, observer RoomObserver) func() {
+	return m.observers.add(roomName, observer)
+}
+
+// NotifyRoomObservers passes a message delivered without BroadcastToRoom (e.g. sampled delivery
+// that sends to each member directly) to the room's observers
+func (m *Manager) NotifyRoomObservers(message interface{}, roomName string) {
+	if msg, ok := ToMessage(message); ok {
+		m.observers.notify(roomName, msg)
+	}
+}
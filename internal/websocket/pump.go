@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"realtime-chat/internal/config"
+)
+
+// writePump writes queued messages and heartbeat pings to the client.
+// Manager เริ่ม pump ตอน register และหยุดด้วยการปิด Send ตอน unregister
+// pump เป็น goroutine เดียวที่เขียนลง Conn จึงไม่มีการเขียนพร้อมกัน
+func (c *WebSocketConnection) writePump(pingInterval, writeTimeout time.Duration, latency *config.LatencyRecorder) {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				c.writeClose(writeTimeout)
+				return
+			}
+
+			if !c.BatchingEnabled() {
+				if !c.writeFrame(message, writeTimeout, latency) {
+					return
+				}
+				continue
+			}
+
+			// รวม frame ที่ตามมาใน batch window เป็น array เดียว (ดู batching.go)
+			batch, next, closed := c.collectBatch(message)
+			if !c.writeFrame(c.joinBatch(batch), writeTimeout, latency) {
+				return
+			}
+			if next != nil && !c.writeFrame(next, writeTimeout, latency) {
+				return
+			}
+			if closed {
+				c.writeClose(writeTimeout)
+				return
+			}
+
+		case <-ticker.C:
+			// ส่ง ping เพื่อ keep connection alive
+			c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			c.Health.RecordPing()
+			c.Trace(TracePing, 0, "")
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Logger().Warn("❌ Failed to send ping", "label", c.GetLabel(), "error", err)
+				return
+			}
+
+			c.Logger().Debug("💓 Sent heartbeat ping", "label", c.GetLabel())
+		}
+	}
+}
+
+// writeFrame writes one queued frame, reporting whether the connection is still writable
+func (c *WebSocketConnection) writeFrame(message []byte, writeTimeout time.Duration, latency *config.LatencyRecorder) bool {
+	c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	writeStart := time.Now()
+	data, messageType := c.wireFrame(message)
+	c.applyCompression(data)
+	err := c.Conn.WriteMessage(messageType, data)
+	latency.ObserveSince(config.StageWrite, writeStart)
+	if err != nil {
+		c.Logger().Warn("❌ Failed to send message", "label", c.GetLabel(), "error", err)
+		return false
+	}
+	c.recordWritten()
+	return true
+}
+
+// writeClose sends the reconnect policy and close frame after Send was closed
+func (c *WebSocketConnection) writeClose(writeTimeout time.Duration) {
+	// Channel ถูกปิด - ส่ง reconnect policy และ close frame ให้ client ตัดสินใจว่าจะ reconnect หรือไม่
+	c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if notice := c.CloseNotice(); notice != nil {
+		data, messageType := c.wireFrame(notice)
+		c.Conn.WriteMessage(messageType, data)
+	}
+	c.Conn.WriteMessage(websocket.CloseMessage, c.CloseFrame())
+}
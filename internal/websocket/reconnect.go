@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReconnectPolicy is sent as a text message right before a server-initiated close frame,
+// telling the client how long to back off before reconnecting
+type ReconnectPolicy struct {
+	Type         string    `json:"type"` // "reconnect_policy"
+	Code         int       `json:"code"`
+	Reason       string    `json:"reason"`
+	Reconnect    bool      `json:"reconnect"`
+	Message      string    `json:"message,omitempty"`
+	MinBackoffMs int64     `json:"min_backoff_ms"`
+	MaxBackoffMs int64     `json:"max_backoff_ms"`
+	Jitter       float64   `json:"jitter"` // สัดส่วนสุ่มบวก/ลบของ delay (0-1)
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// reconnectPolicy builds a retry hint scaled by current server load.
+// ยิ่งมี connection มาก ยิ่งกระจายช่วง reconnect ให้กว้าง เพื่อไม่ให้ client ทุกตัวกลับมาพร้อมกัน
+func (m *Manager) reconnectPolicy(code, connCount int) ReconnectPolicy {
+	reason := GetCloseReason(code)
+	policy := ReconnectPolicy{
+		Type:      "reconnect_policy",
+		Code:      reason.Code,
+		Reason:    reason.Reason,
+		Reconnect: reason.Reconnect,
+		Message:   reason.Message,
+		Jitter:    m.config.ReconnectJitter,
+		Timestamp: time.Now(),
+	}
+	if !reason.Reconnect {
+		return policy
+	}
+
+	load := 0.0
+	if m.config.MaxConnections > 0 {
+		load = float64(connCount) / float64(m.config.MaxConnections)
+	}
+	if load > 1 {
+		load = 1
+	}
+
+	minBackoff := time.Duration(float64(m.config.ReconnectMinBackoff) * (1 + load))
+	maxBackoff := m.config.ReconnectMaxBackoff
+
+	// กระจายการ reconnect ของทุก connection ให้ไม่เกินอัตราที่ server รับไหว
+	if m.config.ReconnectRatePerSecond > 0 {
+		spread := time.Duration(connCount/m.config.ReconnectRatePerSecond) * time.Second
+		if spread > maxBackoff {
+			maxBackoff = spread
+		}
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+
+	policy.MinBackoffMs = minBackoff.Milliseconds()
+	policy.MaxBackoffMs = maxBackoff.Milliseconds()
+	return policy
+}
+
+// markClose records the close code and reconnect policy on a connection (caller closes Send)
+func (m *Manager) markClose(conn *WebSocketConnection, code, connCount int) {
+	data, err := json.Marshal(m.reconnectPolicy(code, connCount))
+	if err != nil {
+		conn.Logger().Error("❌ Failed to marshal reconnect policy", "error", err)
+		data = nil
+	}
+	conn.MarkClose(code, data)
+}
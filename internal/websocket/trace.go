@@ -0,0 +1,78 @@
+package websocket
+
+import "time"
+
+// Trace event kinds
+const (
+	TraceFrameIn  = "frame_in"
+	TraceFrameOut = "frame_out"
+	TraceDropped  = "dropped"
+	TracePing     = "ping"
+	TracePong     = "pong"
+	TraceHealth   = "health"
+)
+
+// TraceEvent is one verbose event of a traced connection
+type TraceEvent struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Bytes      int       `json:"bytes,omitempty"`
+	QueueDepth int       `json:"queue_depth"` // ข้อความที่รอใน Send ตอนเกิด event
+	QueueCap   int       `json:"queue_cap"`
+	Healthy    bool      `json:"healthy"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// TraceFunc receives trace events; it is called on the goroutine that produced the event
+type TraceFunc func(event TraceEvent)
+
+// SetTracer enables verbose tracing of this connection (nil disables it)
+func (c *WebSocketConnection) SetTracer(fn TraceFunc) {
+	if fn == nil {
+		c.tracer.Store(nil)
+		return
+	}
+	c.tracer.Store(&fn)
+}
+
+// Traced reports whether the connection is being traced
+func (c *WebSocketConnection) Traced() bool {
+	return c.tracer.Load() != nil
+}
+
+// Trace emits an event to the tracer, if any. ไม่มี tracer = ไม่มีค่าใช้จ่ายนอกจาก atomic load
+func (c *WebSocketConnection) Trace(kind string, bytes int, detail string) {
+	fn := c.tracer.Load()
+	if fn == nil {
+		return
+	}
+	(*fn)(TraceEvent{
+		Time:       time.Now(),
+		Kind:       kind,
+		Bytes:      bytes,
+		QueueDepth: len(c.Send),
+		QueueCap:   cap(c.Send),
+		Healthy:    c.Health.GetStats().IsHealthy,
+		Detail:     detail,
+	})
+}
+
+// SendUntraced queues a message without emitting trace events.
+// ใช้ส่ง trace event ไปยัง admin เพื่อไม่ให้การ trace ซ้อนกันวนส่งไม่รู้จบ
+func (c *WebSocketConnection) SendUntraced(message []byte) error {
+	return c.enqueue(message, false)
+}
+
+// RecordPong records a pong and traces a health transition back to healthy
+func (c *WebSocketConnection) RecordPong() {
+	traced := c.Traced()
+	wasHealthy := !traced || c.Health.GetStats().IsHealthy
+	c.Health.RecordPong()
+	if !traced {
+		return
+	}
+	c.Trace(TracePong, 0, "")
+	if !wasHealthy {
+		c.Trace(TraceHealth, 0, "recovered: pong received")
+	}
+}
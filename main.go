@@ -2,25 +2,57 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"realtime-chat/internal/account"
+	"realtime-chat/internal/announcement"
+	"realtime-chat/internal/api"
+	"realtime-chat/internal/attachment"
+	"realtime-chat/internal/changefeed"
 	"realtime-chat/internal/chat"
+	"realtime-chat/internal/cluster"
 	"realtime-chat/internal/config"
 	"realtime-chat/internal/database"
+	"realtime-chat/internal/grpcapi"
+	"realtime-chat/internal/lifecycle"
+	"realtime-chat/internal/logging"
 	"realtime-chat/internal/message"
+	"realtime-chat/internal/metricstore"
+	"realtime-chat/internal/migration"
+	"realtime-chat/internal/moderation"
+	"realtime-chat/internal/naming"
+	"realtime-chat/internal/presence"
+	"realtime-chat/internal/reaper"
 	"realtime-chat/internal/room"
+	"realtime-chat/internal/scheduler"
+	"realtime-chat/internal/search"
+	"realtime-chat/internal/security"
+	"realtime-chat/internal/selftest"
+	"realtime-chat/internal/storage"
+	"realtime-chat/internal/storage/postgres"
+	"realtime-chat/internal/storage/sqlite"
+	"realtime-chat/internal/transfer"
 	"realtime-chat/internal/user"
 	userPkg "realtime-chat/internal/user"
+	"realtime-chat/internal/version"
 	wsocket "realtime-chat/internal/websocket"
 
 	"github.com/gorilla/websocket"
 )
 
+// staticDir holds the test client served at /
+const staticDir = "./static/"
+
 // wsRoomServiceAdapter adapts room.Service to websocket.RoomService
 type wsRoomServiceAdapter struct {
 	roomService room.Service
@@ -36,13 +68,22 @@ func (r *wsRoomServiceAdapter) LeaveRoom(user interface{}, roomName string) erro
 // wsManagerAdapter adapts websocket.Manager to chat.WebSocketManager
 type wsManagerAdapter struct {
 	wsManager *wsocket.Manager
+	cluster   *cluster.Node // nil = server เดียว
 }
 
-func (w *wsManagerAdapter) AddConnection(conn interface{}) string {
+func (w *wsManagerAdapter) AddConnection(conn interface{}, hello []byte, encoder wsocket.FrameEncoder) (string, bool) {
 	if wsConn, ok := conn.(*websocket.Conn); ok {
-		return w.wsManager.AddConnection(wsConn)
+		return w.wsManager.AddConnection(wsConn, hello, encoder)
 	}
-	return ""
+	return "", false
+}
+
+func (w *wsManagerAdapter) SendMessage(connID string, message []byte) error {
+	return w.wsManager.SendMessage(connID, message)
+}
+
+func (w *wsManagerAdapter) SendUntraced(connID string, message []byte) error {
+	return w.wsManager.SendUntraced(connID, message)
 }
 
 func (w *wsManagerAdapter) RemoveConnection(connID string) {
@@ -54,11 +95,28 @@ func (w *wsManagerAdapter) GetConnection(connID string) (chat.Connection, bool)
 	return conn, exists
 }
 
+func (w *wsManagerAdapter) Usernames() map[string]string {
+	return w.wsManager.Usernames()
+}
+
 func (w *wsManagerAdapter) BroadcastMessage(message interface{}, excludeID string) {
+	if w.cluster != nil {
+		w.BroadcastToRoom(message, excludeID, "")
+		return
+	}
 	w.wsManager.BroadcastMessage(message, excludeID)
 }
 
 func (w *wsManagerAdapter) BroadcastToRoom(message interface{}, excludeID, roomName string) {
+	// cluster mode: ส่งผ่าน node ที่เป็นเจ้าของห้อง เพื่อให้ถึง connection บน node อื่นด้วย
+	if w.cluster != nil {
+		if msg, ok := wsocket.ToMessage(message); ok {
+			if payload, err := json.Marshal(msg); err == nil {
+				w.cluster.BroadcastToRoom(roomName, payload, excludeID)
+				return
+			}
+		}
+	}
 	w.wsManager.BroadcastToRoom(message, excludeID, roomName)
 }
 
@@ -67,30 +125,100 @@ func (w *wsManagerAdapter) GetConnectionHealth(connID string) (interface{}, bool
 	return health, exists
 }
 
+func (w *wsManagerAdapter) RecordDeliveryAck(connID, probeID string) {
+	w.wsManager.RecordDeliveryAck(connID, probeID)
+}
+
+func (w *wsManagerAdapter) NotifyRoomObservers(message interface{}, roomName string) {
+	w.wsManager.NotifyRoomObservers(message, roomName)
+}
+
 func main() {
+	checkOnly := flag.Bool("check-only", false, "run the startup self-test and exit non-zero if any check fails")
+	flag.Parse()
+
 	// สร้าง configuration manager
 	configManager := config.NewConfigManager("config.json")
 
 	// โหลด configuration
 	if err := configManager.Initialize(); err != nil {
-		log.Printf("⚠️ Failed to initialize config manager: %v", err)
-		log.Println("🔄 Using default configuration")
+		slog.Warn("⚠️ Failed to initialize config manager, using default configuration", "error", err)
 	}
 
 	// ดึง configuration
 	cfg := configManager.GetConfig()
+	// ค่าที่เปลี่ยนผ่าน PATCH /admin/config หรือแก้ไฟล์ config มีผลกับ server ที่รันอยู่ทันที
+	configManager.RegisterCallback(cfg.ApplyRuntime)
+
+	// structured logging: log ของ library ภายนอกถูกส่งผ่าน handler เดียวกันด้วย
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Warn("⚠️ Invalid logging configuration, using text/info", "error", err)
+	}
+
+	// --check-only: รัน self-test แล้วจบ ใช้เป็น gate ก่อน deploy
+	if *checkOnly {
+		os.Exit(runCheckOnly(cfg))
+	}
+	// เลือก storage backend (storage_backend ว่าง = ตาม enable_mongodb แบบเดิม)
+	backend, err := storage.Backend(cfg)
+	if err != nil {
+		slog.Error("❌ Invalid storage configuration", "error", err)
+		os.Exit(1)
+	}
+	// feature ที่ผูกกับ MongoDB (threads, timeline, change feed ฯลฯ) ใช้ cfg.EnableMongoDB เป็นตัวเปิด
+	cfg.EnableMongoDB = backend == storage.BackendMongoDB
+	mongoRequested := cfg.EnableMongoDB
+	var mongoErr error
 
 	// สร้าง metrics
 	metrics := config.NewServerMetrics()
 
+	// worker ที่รันตลอดอายุ server อยู่ในกลุ่มเดียวกัน ตอน shutdown ยกเลิกและรอให้จบพร้อมกัน
+	workers := lifecycle.New()
+
 	// สร้าง repositories
+	var store storage.Provider
 	var userRepo user.Repository
+	var mongoUsers *user.MongoRepository
 	var roomRepo room.Repository
 	var messageRepo message.Repository
 	var mongoDB *database.MongoDB
+	var postgresStore *postgres.Store
+	var sqliteStore *sqlite.Store
+	var migrationRunner *migration.Runner
+
+	if backend == storage.BackendPostgres {
+		slog.Info("🔄 Initializing PostgreSQL connection")
+		postgresStore, err = postgres.Open(postgres.Config{
+			DSN:            cfg.PostgresDSN,
+			MaxOpenConns:   cfg.PostgresMaxConns,
+			ConnectTimeout: cfg.MongoConnectTimeout,
+		})
+		if err != nil {
+			slog.Error("❌ Failed to connect to PostgreSQL, falling back to in-memory repositories", "error", err)
+		} else {
+			postgresStore.SetNodeID(cfg.NodeID)
+			store = postgresStore
+			messageRepo = postgresStore.Messages()
+			slog.Info("✅ PostgreSQL repositories initialized")
+		}
+	}
+
+	if backend == storage.BackendSQLite {
+		slog.Info("🔄 Opening SQLite database", "path", cfg.SQLitePath)
+		sqliteStore, err = sqlite.Open(cfg.SQLitePath)
+		if err != nil {
+			slog.Error("❌ Failed to open SQLite database, falling back to in-memory repositories", "error", err)
+		} else {
+			sqliteStore.SetNodeID(cfg.NodeID)
+			store = sqliteStore
+			messageRepo = sqliteStore.Messages()
+			slog.Info("✅ SQLite repositories initialized")
+		}
+	}
 
 	if cfg.EnableMongoDB {
-		log.Println("🔄 Initializing MongoDB connection...")
+		slog.Info("🔄 Initializing MongoDB connection")
 
 		// สร้าง MongoDB configuration
 		mongoConfig := &database.MongoConfig{
@@ -100,84 +228,506 @@ func main() {
 			PingTimeout:    cfg.MongoPingTimeout,
 			MaxPoolSize:    cfg.MongoMaxPoolSize,
 			MinPoolSize:    cfg.MongoMinPoolSize,
+
+			BreakerThreshold:    cfg.MongoBreakerThreshold,
+			BreakerCooldown:     cfg.MongoBreakerCooldown,
+			HealthCheckInterval: cfg.MongoHealthCheckInterval,
 		}
 
 		// เชื่อมต่อ MongoDB
-		var err error
 		mongoDB, err = database.NewMongoDB(mongoConfig)
 		if err != nil {
-			log.Printf("❌ Failed to connect to MongoDB: %v", err)
-			log.Println("🔄 Falling back to in-memory repositories")
+			mongoErr = err
+			slog.Error("❌ Failed to connect to MongoDB, falling back to in-memory repositories", "error", err)
 			cfg.EnableMongoDB = false
 		} else {
 			// สร้าง indexes
 			if err := mongoDB.CreateIndexes(); err != nil {
-				log.Printf("⚠️ Failed to create MongoDB indexes: %v", err)
+				slog.Warn("⚠️ Failed to create MongoDB indexes", "error", err)
+			}
+
+			// migration ของ schema (ล็อกข้าม node ให้รันได้ทีละ node)
+			migrationRunner, err = migration.NewRunner(mongoDB, cfg.NodeID, migration.Builtin())
+			if err != nil {
+				slog.Error("❌ Invalid migration registry", "error", err)
+				os.Exit(1)
+			}
+			if cfg.AutoMigrate {
+				if applied, err := migrationRunner.Apply(false); err != nil {
+					slog.Warn("⚠️ Schema migrations not applied", "error", err)
+				} else if len(applied) > 0 {
+					slog.Info("✅ Applied schema migrations", "count", len(applied))
+				}
 			}
 
 			// สร้าง MongoDB repositories
-			userRepo = user.NewMongoRepository(mongoDB)
-			roomRepo = room.NewMongoRepository(mongoDB)
-			messageRepo = message.NewMongoRepository(mongoDB)
+			mongoStore := storage.NewMongo(mongoDB, cfg.NodeID)
+			store = mongoStore
+			mongoUsers = mongoStore.UserStore()
+			resilientRepo := message.NewResilientRepository(mongoStore.Messages(), mongoDB.Breaker(), cfg.MessageBufferSize)
+			messageRepo = resilientRepo
+
+			// เปิดใช้ write-ahead journal สำหรับข้อความระหว่าง DB ล่มหรือช้า
+			if cfg.JournalDir != "" {
+				journal, err := message.NewJournal(cfg.JournalDir, cfg.JournalSegmentSize)
+				if err != nil {
+					slog.Warn("⚠️ Failed to open message journal", "error", err)
+				} else {
+					resilientRepo.SetJournal(journal, cfg.MongoSlowThreshold)
+					workers.Go("journal-reconcile", func(ctx context.Context) error {
+						return journal.RunReconciler(ctx, resilientRepo.Repository, cfg.JournalReconcileInterval, func() bool {
+							return mongoDB.Breaker().State() == database.BreakerClosed
+						})
+					})
+					slog.Info("📓 Message journal enabled", "dir", cfg.JournalDir)
+				}
+			}
+
+			// ตรวจสอบสถานะ MongoDB เป็นระยะ เพื่อสลับเข้า/ออกจาก degraded mode
+			workers.Go("mongo-health", mongoDB.MonitorHealth)
 
-			log.Println("✅ MongoDB repositories initialized")
+			slog.Info("✅ MongoDB repositories initialized")
 		}
 	}
 
-	// ถ้าไม่ใช้ MongoDB หรือเชื่อมต่อไม่ได้ ให้ใช้ in-memory repositories
-	if !cfg.EnableMongoDB {
-		log.Println("🔄 Using in-memory repositories")
-		userRepo = user.NewInMemoryRepository()
-		roomRepo = room.NewInMemoryRepository()
+	// ถ้าไม่ได้เลือก database หรือเชื่อมต่อไม่ได้ ให้ใช้ in-memory repositories
+	if store == nil {
+		slog.Info("🔄 Using in-memory repositories")
+		memoryStore := storage.NewMemory()
+		store = memoryStore
+
+		// พักห้องที่ว่างนานไว้บน disk เพื่อคืนหน่วยความจำ
+		if cfg.EnableRoomHibernation && cfg.RoomHibernationDir != "" {
+			inMemoryRooms := memoryStore.RoomStore()
+			if err := inMemoryRooms.EnableHibernation(cfg.RoomHibernationDir); err != nil {
+				slog.Warn("⚠️ Failed to enable room hibernation", "error", err)
+			} else {
+				workers.Go("room-hibernator", func(ctx context.Context) error {
+					return inMemoryRooms.RunHibernator(ctx, cfg.RoomIdleTimeout, cfg.RoomHibernationInterval, metrics)
+				})
+				slog.Info("💤 Room hibernation enabled", "dir", cfg.RoomHibernationDir, "idle", cfg.RoomIdleTimeout)
+			}
+		}
 	}
+	userRepo = store.Users()
+	roomRepo = store.Rooms()
 
 	// สร้าง services
 	userService := user.NewService(userRepo, metrics)
-	roomService := room.NewService(roomRepo, cfg.MaxRooms, cfg.MaxUsersPerRoom, metrics)
+	userService.SetProfileStore(user.NewProfileStore(store.Profiles()))
+	accounts := account.NewService(store.Accounts(), cfg)
+	cfg.AdminUsernames = registeredAdmins(cfg.AdminUsernames, accounts)
+	roomService := room.NewService(roomRepo, cfg.MaxRooms, cfg.MaxUsersPerRoom, cfg.MaxRoomCapacity, metrics)
+	roomService.SetReactivateArchived(cfg.ReactivateArchivedRooms)
 
 	// สร้าง WebSocket manager
 	wsRoomAdapter := &wsRoomServiceAdapter{roomService}
 	wsManager := wsocket.NewManager(cfg, userService, wsRoomAdapter, metrics)
 
+	// index สมาชิกของแต่ละห้อง ให้ broadcast ในห้องไม่ต้องวนทุก connection
+	roomService.OnMembershipChange(func(roomName string, u *userPkg.User, joined bool) {
+		wsManager.TrackRoomMembership(roomName, u.ConnID, joined)
+	})
+
+	// ลบผู้ใช้ที่ค้างใน MongoDB เมื่อลบตอน disconnect ไม่สำเร็จ
+	var userCleaner *user.Cleaner
+	if mongoUsers != nil {
+		userCleaner = user.NewCleaner(mongoUsers, cfg.NodeID, func(connID string) bool {
+			_, exists := wsManager.GetConnection(connID)
+			return exists
+		}, cfg)
+		userService.SetCleaner(userCleaner)
+	}
+
+	// ล้าง state ในหน่วยความจำที่หมดอายุแล้วจากทุก component เป็นระยะ
+	stateReaper := reaper.New(cfg.ReaperHeapThreshold, metrics)
+
+	// สุ่มวัด delivery latency ของ broadcast เพื่อคำนวณ SLO
+	if cfg.EnableDeliverySampling {
+		deliveryTracker := wsocket.NewDeliveryTracker(cfg.DeliverySampleSize, cfg.DeliverySampleInterval, cfg.DeliveryProbeTimeout, cfg.DeliveryWindowSize)
+		wsManager.SetDeliveryTracker(deliveryTracker)
+		stateReaper.Register("delivery_probes", deliveryTracker)
+	}
+
+	// cluster mode: แต่ละ node เป็นเจ้าของห้องส่วนหนึ่ง broadcast ของห้องส่งผ่านเจ้าของ (ดู internal/cluster)
+	var clusterNode *cluster.Node
+	if cfg.EnableCluster {
+		clusterNode, err = cluster.New(cfg, func(roomName string, payload []byte, excludeID string) {
+			var msg wsocket.Message
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				slog.Warn("⚠️ Invalid cluster frame", "room", roomName, "error", err)
+				return
+			}
+			wsManager.BroadcastToRoom(&msg, excludeID, roomName)
+		}, wsManager.LocalRooms)
+		if err != nil {
+			slog.Error("❌ Invalid cluster configuration", "error", err)
+			os.Exit(1)
+		}
+		if err := clusterNode.Start(); err != nil {
+			slog.Error("❌ Failed to start cluster node", "error", err)
+			os.Exit(1)
+		}
+		roomService.OnMembershipChange(func(roomName string, u *userPkg.User, joined bool) {
+			if joined {
+				clusterNode.Interest(roomName)
+			}
+		})
+	}
+
 	// สร้าง adapter สำหรับ WebSocket manager
-	wsManagerAdapted := &wsManagerAdapter{wsManager}
+	wsManagerAdapted := &wsManagerAdapter{wsManager: wsManager, cluster: clusterNode}
 
 	// สร้าง message service
 	messageService := chat.NewMessageService(wsManagerAdapted)
 
 	// สร้าง command service
 	commandService := chat.NewCommandService(userService, roomService, messageService, wsManagerAdapted, metrics, cfg, configManager)
+	if clusterNode != nil {
+		// DM ถึงผู้ใช้ที่ต่อกับ node อื่น (region ไหนก็ได้) ส่งผ่าน cluster
+		commandService.SetUserRouter(clusterNode)
+		clusterNode.SetUserDeliverer(commandService.DeliverToUser)
+	}
 
 	// สร้าง HTTP handler
 	handler := chat.NewHandler(wsManagerAdapted, userService, roomService, commandService, messageService, cfg)
-
-	// Set message repository if MongoDB is enabled
-	if cfg.EnableMongoDB && messageRepo != nil {
+	handler.SetServerMetrics(metrics)
+	handler.SetReaper(stateReaper)
+	handler.SetAccounts(accounts)
+
+	// role ที่ admin ตั้งด้วย /setrole (admin จาก config ไม่ต้องตั้ง)
+	roles := user.NewRoleStore()
+	handler.SetRoles(roles)
+	commandService.SetRoles(roles)
+
+	// Set message repository if the storage backend persists messages
+	var timelineRepo message.TimelineRepository
+	if messageRepo != nil {
 		commandService.SetMessageRepository(messageRepo)
 		handler.SetMessageRepository(messageRepo)
-		log.Println("✅ Message persistence enabled")
+		slog.Info("✅ Message persistence enabled", "backend", store.Name())
+	}
+	// repositories เสริมที่ยังมีเฉพาะบน MongoDB
+	if cfg.EnableMongoDB && messageRepo != nil {
+		breaker := mongoDB.Breaker()
+		commandService.SetDirectMessageRepository(message.NewResilientDirectMessageRepository(message.NewMongoDirectMessageRepository(mongoDB), breaker))
+		handler.SetThreadRepository(message.NewResilientThreadRepository(message.NewMongoThreadRepository(mongoDB), breaker))
+		timelineRepo = message.NewResilientTimelineRepository(message.NewMongoTimelineRepository(mongoDB), breaker)
+		handler.SetTimelineRepository(timelineRepo)
+		handler.SetReadReceiptRepository(message.NewResilientReadReceiptRepository(message.NewMongoReadReceiptRepository(mongoDB), breaker))
+		if cfg.EnableOfflineMailbox {
+			handler.SetMailbox(message.NewResilientMailboxRepository(message.NewMongoMailboxRepository(mongoDB), breaker))
+		}
+		if cfg.EnableNotifications {
+			handler.SetNotificationRepository(message.NewResilientNotificationRepository(message.NewMongoNotificationRepository(mongoDB), breaker))
+		}
+	}
+	if !cfg.EnableMongoDB {
+		slog.Warn("⚠️ Storage backend does not support these features; they are disabled",
+			"backend", store.Name(), "features", strings.Join(storage.MongoOnlyFeatures(cfg), ", "),
+			"hint", "use storage_backend=mongodb to enable them")
+	}
+	// แจ้ง admin ที่ออนไลน์เมื่อฐานข้อมูลล่ม/กลับมา (ผ่าน WebSocket ไม่ใช่แค่ log)
+	if cfg.EnableMongoDB {
+		mongoDB.Breaker().OnStateChange(func(from, to database.BreakerState) {
+			switch to {
+			case database.BreakerOpen:
+				commandService.AlertAdmins("🚨 MongoDB is unavailable: messages are buffered, history, search and room changes fail until it recovers")
+			case database.BreakerClosed:
+				commandService.AlertAdmins("✅ MongoDB recovered; buffered messages are being replayed")
+			}
+		})
+	}
+	if migrationRunner != nil {
+		commandService.SetMigrationRunner(migrationRunner)
 	}
 
-	// เริ่ม WebSocket manager ใน goroutine
-	go wsManager.Run()
+	// จับเวลาแต่ละขั้นของเส้นทางข้อความ (read → validate → persist → enqueue → fanout → write)
+	if cfg.EnableLatencyMetrics {
+		latency := config.NewLatencyRecorder()
+		handler.SetLatencyRecorder(latency)
+		commandService.SetLatencyRecorder(latency)
+		wsManager.SetLatencyRecorder(latency)
+	}
 
-	// เริ่ม metrics logging goroutine
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				log.Printf("📊 Active connections: %d", wsManager.GetConnectionCount())
+	// จำกัดการเชื่อมต่อ/login ที่ถี่ผิดปกติต่อ IP
+	var throttle *security.ConnectionThrottle
+	// load test mode ไม่จำกัด เพราะ client สังเคราะห์ทั้งหมดมาจาก IP เดียว
+	if cfg.LoadTestMode {
+		slog.Warn("🧪 Load test mode enabled: probe_id is echoed and connection throttling is off")
+	} else if cfg.EnableConnectionThrottle {
+		throttle = security.NewConnectionThrottle(cfg)
+		stateReaper.Register("connection_throttle", throttle)
+		handler.SetConnectionThrottle(throttle)
+		commandService.SetConnectionThrottle(throttle)
+		slog.Info("🛡️ Connection throttling enabled", "attempts", cfg.ConnectAttemptLimit, "window", cfg.ConnectAttemptWindow)
+	}
+
+	// จำกัด connection พร้อมกันต่อ IP ไม่ให้ client เดียวใช้ MaxConnections หมด (ชนซ้ำบ่อยถูก block ผ่าน throttle)
+	if !cfg.LoadTestMode && cfg.MaxConnectionsPerIP > 0 {
+		ipLimiter := security.NewIPLimiter(cfg, throttle)
+		stateReaper.Register("ip_limiter", ipLimiter)
+		handler.SetIPLimiter(ipLimiter)
+		commandService.SetIPLimiter(ipLimiter)
+		slog.Info("🛡️ Per-IP connection limit enabled", "max_per_ip", cfg.MaxConnectionsPerIP, "ban_after", cfg.IPViolationLimit)
+	}
+
+	// จำกัดการสลับห้อง (join/leave) ที่ถี่เกินไปต่อผู้ใช้
+	var churnLimiter *security.ChurnLimiter
+	if cfg.EnableChurnLimit && cfg.RoomSwitchLimit > 0 && cfg.RoomSwitchWindow > 0 {
+		churnLimiter = security.NewChurnLimiter(cfg)
+		stateReaper.Register("room_switch_limiter", churnLimiter)
+		handler.SetChurnLimiter(churnLimiter)
+		commandService.SetChurnLimiter(churnLimiter)
+		slog.Info("🚦 Room switch limiting enabled", "switches", cfg.RoomSwitchLimit, "window", cfg.RoomSwitchWindow)
+	}
+
+	// จำกัด frame ขาเข้าทุก type ต่อ connection (ข้อความแชทมี rate limiter แยกอีกชั้น)
+	if cfg.EnableFrameRateLimit && cfg.FrameRateLimit > 0 && cfg.ExpensiveFrameRateLimit > 0 && cfg.FrameRateWindow > 0 {
+		handler.SetFrameLimiter(security.NewFrameLimiter(cfg, metrics))
+		slog.Info("🚦 Frame limiting enabled", "frames", cfg.FrameRateLimit, "expensive", cfg.ExpensiveFrameRateLimit, "window", cfg.FrameRateWindow)
+	}
+
+	// ห้อง/คำสั่งกับดักสำหรับตรวจจับ bot และการกักกัน connection ที่ผิด protocol ใช้ audit log เดียวกัน
+	auditLog := moderation.NewAuditLog(cfg.ModerationLogSize)
+	if cfg.EnableHoneypots {
+		honeypot := moderation.NewHoneypot(cfg.HoneypotRooms, cfg.HoneypotCommands, auditLog)
+		handler.SetHoneypot(honeypot)
+		commandService.SetHoneypot(honeypot)
+		slog.Info("🍯 Honeypots enabled", "rooms", len(cfg.HoneypotRooms), "commands", len(cfg.HoneypotCommands))
+	}
+	if cfg.EnableQuarantine {
+		handler.SetQuarantine(moderation.NewQuarantine(cfg.ProtocolViolationLimit, cfg.QuarantineGrace, auditLog, metrics))
+		slog.Info("🚫 Protocol quarantine enabled", "violations", cfg.ProtocolViolationLimit, "grace", cfg.QuarantineGrace)
+	}
+	// lockout ของ password login ก็บันทึกลง audit log นี้ (ดูได้ด้วย /flags)
+	commandService.SetAuditLog(auditLog)
+
+	// ประกาศจาก admin พร้อมติดตามการรับทราบ
+	announcements := announcement.NewStore(cfg.MaxAnnouncements)
+	handler.SetAnnouncements(announcements)
+	commandService.SetAnnouncements(announcements)
+
+	// ส่งไฟล์ระหว่างผู้ใช้ผ่าน server แบบ relay
+	if cfg.EnableFileTransfer {
+		transfers := transfer.NewManager(cfg.MaxFileSize, cfg.FileChunkSize, cfg.FileTransferWindow, cfg.FileTransferIdleTimeout)
+		handler.SetFileTransfers(transfers)
+		stateReaper.Register("file_transfers", transfers)
+		slog.Info("📎 File transfer enabled", "max_bytes", cfg.MaxFileSize, "chunk_bytes", cfg.FileChunkSize, "window", cfg.FileTransferWindow)
+	}
+
+	// presence จากระบบภายนอก (เช่น calendar) ผ่าน API
+	presenceStore := userPkg.NewPresenceStore()
+	handler.SetPresence(presenceStore)
+	stateReaper.Register("presence", presenceStore)
+
+	// online/away/offline จากกิจกรรมและสุขภาพของ connection
+	presenceTracker := presence.NewTracker(handler.PresenceSignals, cfg.PresenceAwayAfter)
+	handler.SetPresenceTracker(presenceTracker)
+	commandService.SetPresenceTracker(presenceTracker)
+
+	// ตั้งชื่อสุ่มให้ guest และห้องชั่วคราวที่ไม่ได้ระบุชื่อ
+	if cfg.EnableGuestNames {
+		names := naming.NewGenerator(cfg.GuestNameLocale)
+		handler.SetNameGenerator(names)
+		commandService.SetNameGenerator(names)
+		slog.Info("🏷️ Guest names enabled", "locale", names.Locale())
+	}
+
+	// เปิดใช้ external search index ถ้ากำหนดไว้
+	if cfg.EnableSearchIndex {
+		backend, err := search.NewElasticsearchBackend(cfg.ElasticsearchURL, cfg.ElasticsearchIndex)
+		if err != nil {
+			slog.Warn("⚠️ Failed to initialize search index, falling back to MongoDB search", "error", err)
+		} else {
+			indexer := search.NewIndexer(backend, cfg.SearchIndexQueueSize)
+			go indexer.Run()
+			commandService.SetSearchIndex(indexer)
+			handler.SetSearchIndex(indexer)
+			slog.Info("🔍 Search index enabled", "url", cfg.ElasticsearchURL, "index", cfg.ElasticsearchIndex)
+		}
+	}
+
+	// ติดตามการเปลี่ยนแปลงใน database (รวมจาก node อื่น) สำหรับ live search และสถิติ
+	var changeFeed changefeed.ChangeFeed
+	var changeCounters *changefeed.Counters
+	if cfg.EnableChangeFeed && cfg.EnableMongoDB && mongoDB != nil {
+		changeFeed = changefeed.New(mongoDB, cfg.ChangeFeedPollInterval)
+		handler.SetChangeFeed(changeFeed)
+		changeCounters = changefeed.NewCounters(changeFeed, "messages", "rooms", "users")
+		changeFeed.Start()
+	}
+
+	// บันทึก metrics เป็นระยะ เพื่อดูแนวโน้มใน /stats และไม่ให้ตัวนับหายเมื่อ restart
+	var metricsHistory *metricstore.History
+	var metricsStore metricstore.Store
+	if cfg.EnableMongoDB && mongoDB != nil {
+		store, err := metricstore.NewMongoStore(mongoDB)
+		if err != nil {
+			slog.Warn("⚠️ Failed to initialize metrics history", "error", err)
+		} else {
+			metricsStore = store
+		}
+	} else if cfg.MetricsFile != "" {
+		store, err := metricstore.NewFileStore(cfg.MetricsFile)
+		if err != nil {
+			slog.Warn("⚠️ Failed to open metrics history file", "error", err)
+		} else {
+			metricsStore = store
+		}
+	}
+	if metricsStore != nil {
+		metricsHistory = metricstore.NewHistory(metricsStore, metrics, cfg.NodeID, cfg.MetricsRetention)
+		if err := metricsHistory.Restore(); err != nil {
+			slog.Warn("⚠️ Failed to restore metrics history", "error", err)
+		}
+		commandService.SetMetricsHistory(metricsHistory)
+	}
+
+	// งานเบื้องหลังที่รันเป็นระยะ
+	jobs := scheduler.New()
+	if metricsHistory != nil {
+		jobs.Every("metrics-history", cfg.MetricsSnapshotInterval, metricsHistory.Record)
+	}
+	jobs.Every("room-expiry", cfg.RoomExpiryCheckInterval, func() {
+		if count := roomService.CheckExpiries(cfg.RoomExpiryWarnings); count > 0 {
+			slog.Info("⌛ Archived expired rooms", "count", count)
+		}
+	})
+	if cfg.RoomArchiveAfter > 0 {
+		jobs.Every("room-archive", cfg.RoomArchiveInterval, func() {
+			if count := roomService.ArchiveIdle(cfg.RoomArchiveAfter); count > 0 {
+				slog.Info("🗄️ Archived idle rooms", "count", count)
+			}
+		})
+	}
+	jobs.Every("state-reaper", cfg.ReaperInterval, stateReaper.Run)
+	if cfg.EnableRateLimit {
+		jobs.Every("rate-limit-eviction", cfg.RateLimitIdleTTL, handler.EvictIdleRateLimits)
+	}
+	jobs.Every("presence-sweep", cfg.PresenceSweepInterval, presenceTracker.Sweep)
+	if cfg.EnableRoomSampling {
+		jobs.Every("room-digests", cfg.DigestInterval, handler.FlushDigests)
+	}
+	// ลบ/ย้ายข้อความที่เกินอายุ ห้องที่ตั้ง /retention เองใช้ค่าของห้อง
+	var retentionJanitor *message.Janitor
+	var retentionStore message.RetentionStore
+	if cfg.EnableMongoDB && mongoDB != nil {
+		retentionStore = message.NewMongoRetentionStore(mongoDB, cfg.RetentionArchive)
+	} else if postgresStore != nil {
+		retentionStore = postgresStore.RetentionStore(cfg.RetentionArchive)
+	} else if sqliteStore != nil {
+		retentionStore = sqliteStore.RetentionStore(cfg.RetentionArchive)
+	}
+	if retentionStore != nil {
+		retentionJanitor = message.NewJanitor(retentionStore, func() map[string]time.Duration {
+			overrides := make(map[string]time.Duration)
+			for _, chatRoom := range roomService.GetRooms() {
+				if chatRoom.Retention > 0 {
+					overrides[chatRoom.Name] = chatRoom.Retention
+				}
+			}
+			return overrides
+		}, cfg, metrics)
+		jobs.Every("message-retention", cfg.RetentionCheckInterval, retentionJanitor.Run)
+	}
+	if userCleaner != nil {
+		jobs.Every("user-cleanup-retry", cfg.UserCleanupBackoff, userCleaner.RetryPending)
+		jobs.Every("stale-user-sweep", cfg.UserSweepInterval, userCleaner.Sweep)
+	}
+	jobs.Every("connection-metrics", 30*time.Second, func() {
+		slog.Info("📊 Active connections", "count", wsManager.GetConnectionCount())
+	})
+	jobs.Start()
+
+	workers.Go("websocket-manager", wsManager.Run)
+	workers.Go("config-watch", configManager.Watch)
+
+	// สร้าง REST API handler
+	apiHandler := api.NewHandler(roomService, userService)
+	apiHandler.SetFrameReporter(wsManager)
+	apiHandler.SetBackpressureReporter(wsManager)
+	apiHandler.SetBatchReporter(wsManager)
+	apiHandler.SetCompressionReporter(wsManager)
+	if userCleaner != nil {
+		apiHandler.SetUserCleaner(userCleaner)
+	}
+	if retentionJanitor != nil {
+		apiHandler.SetRetentionJanitor(retentionJanitor)
+	}
+	if churnLimiter != nil {
+		apiHandler.SetChurnLimiter(churnLimiter)
+	}
+	apiHandler.SetValidator(security.NewInputValidator(cfg))
+	apiHandler.SetAccounts(accounts)
+	loginLockout := security.NewLoginLockout(cfg)
+	stateReaper.Register("login_lockout", loginLockout)
+	apiHandler.SetLoginLockout(loginLockout, commandService, auditLog)
+	commandService.SetLoginLockout(loginLockout)
+	apiHandler.SetProxyPolicy(security.NewProxyPolicy(cfg.TrustedProxies))
+	if throttle != nil {
+		apiHandler.SetConnectionThrottle(throttle)
+	}
+	if cfg.EnableMetrics {
+		apiHandler.SetServerMetrics(metrics)
+	}
+	if cfg.EnableDeliverySampling {
+		apiHandler.SetDeliveryReporter(wsManager)
+	}
+	if messageRepo != nil {
+		apiHandler.SetMessageRepository(messageRepo)
+	}
+	if timelineRepo != nil {
+		apiHandler.SetTimelineRepository(timelineRepo)
+	}
+	if changeCounters != nil {
+		apiHandler.SetChangeReporter(changeCounters)
+	}
+	apiHandler.SetAnnouncements(announcements)
+	apiHandler.SetCommandCatalog(commandService)
+	if clusterNode != nil {
+		apiHandler.SetRegionReporter(clusterNode)
+	} else {
+		apiHandler.SetRegionReporter(cluster.NewStandalone(cfg))
+	}
+	apiHandler.SetAPIKeys(cfg.APIKeys)
+	apiHandler.SetAdmin(wsManager, handler, configManager)
+	apiHandler.SetPresence(presenceStore, handler, cfg.MaxPresenceDuration)
+
+	// อัปโหลดไฟล์แนบผ่าน POST /api/upload แล้วโพสต์เป็นข้อความในห้อง
+	if cfg.EnableUploads {
+		if storage, err := newUploadStorage(cfg); err != nil {
+			slog.Warn("⚠️ Uploads disabled", "error", err)
+		} else {
+			apiHandler.SetUploads(attachment.NewUploader(storage, cfg.MaxUploadSize, cfg.AllowedUploadTypes, cfg.ThumbnailSize), handler)
+			// local storage ที่ base URL เป็น path ถูกเสิร์ฟจาก server นี้ (URL เต็มหมายถึง CDN/reverse proxy เสิร์ฟเอง)
+			if prefix := strings.TrimSuffix(cfg.UploadBaseURL, "/") + "/"; cfg.UploadStorage != "s3" && strings.HasPrefix(prefix, "/") && prefix != "/" {
+				http.Handle(prefix, http.StripPrefix(prefix, noDirectoryListing(http.FileServer(http.Dir(cfg.UploadDir)))))
 			}
+			slog.Info("📎 Uploads enabled", "storage", storage.Name(), "max_bytes", cfg.MaxUploadSize, "types", strings.Join(cfg.AllowedUploadTypes, ","))
 		}
-	}()
+	}
 
 	// ตั้งค่า HTTP routes
 	http.HandleFunc("/ws", handler.HandleWebSocket)
+	http.HandleFunc("/events", handler.HandleEvents) // SSE fallback ของ session เดียวกับ WebSocket
+	apiHandler.RegisterRoutes(http.DefaultServeMux)
 
 	// เสิร์ฟ static files สำหรับ test client
-	http.Handle("/", http.FileServer(http.Dir("./static/")))
+	http.Handle("/", http.FileServer(http.Dir(staticDir)))
+
+	// gRPC API สำหรับ service ภายใน ใช้ room service และ path ส่งข้อความเดียวกับ WebSocket
+	var grpcServer *grpcapi.Server
+	if cfg.EnableGRPC {
+		grpcServer = grpcapi.NewServer(cfg, roomService, security.NewInputValidator(cfg), handler, wsManager)
+		if messageRepo != nil {
+			grpcServer.SetMessageRepository(messageRepo)
+		}
+		if err := grpcServer.Start(); err != nil {
+			slog.Error("❌ Failed to start gRPC API", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// สร้าง HTTP server
 	port := cfg.Port
@@ -190,56 +740,246 @@ func main() {
 		WriteTimeout: cfg.WriteTimeout,
 	}
 
-	// ตั้งค่า graceful shutdown
+	// TLS: ตั้ง cert/key หรือ autocert แล้ว server รับ https:// และ wss:// (ดู tls_* ใน config)
+	tlsSetup, err := security.NewTLSSetup(cfg)
+	if err != nil {
+		slog.Error("❌ Invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+	var redirectServer *http.Server
+	if tlsSetup != nil {
+		server.TLSConfig = tlsSetup.Config
+		if cfg.TLSRedirectAddr != "" {
+			redirectServer = &http.Server{
+				Addr:         cfg.TLSRedirectAddr,
+				Handler:      tlsSetup.RedirectHandler(strings.TrimPrefix(port, ":")),
+				ReadTimeout:  cfg.ReadTimeout,
+				WriteTimeout: cfg.WriteTimeout,
+			}
+		}
+	}
+
+	// ตั้งค่า graceful shutdown (จาก signal หรือคำสั่ง /shutdown ของ admin)
+	shutdownRequests := make(chan string, 1)
+	commandService.SetShutdown(func(reason string) {
+		select {
+		case shutdownRequests <- reason:
+		default:
+		}
+	})
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-		sig := <-sigChan
-		log.Printf("🛑 Received signal: %v", sig)
-		log.Println("🔄 Starting graceful shutdown...")
+		select {
+		case sig := <-sigChan:
+			slog.Info("🛑 Received signal, starting graceful shutdown", "signal", sig.String())
+		case reason := <-shutdownRequests:
+			slog.Info("🛑 Shutdown requested with /shutdown, starting graceful shutdown", "reason", reason)
+		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		// ปิด MongoDB connection ถ้ามี
+		// แจ้ง client ทุกคนด้วย close frame ก่อนปิด เพื่อให้ reconnect ได้ถูกจังหวะ
+		wsManager.Shutdown()
+		// SSE fallback ต้องปิดเอง ไม่อย่างนั้น server.Shutdown จะรอ stream ที่ค้างอยู่จนหมดเวลา
+		handler.CloseEventStreams()
+
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+
+		// ออกจาก cluster หลังแจ้ง client แล้ว node อื่นจะรับห้องของเราไปเมื่อครบ node timeout
+		if clusterNode != nil {
+			clusterNode.Stop()
+		}
+
+		// หยุดงานเบื้องหลังก่อนปิด database
+		if err := workers.Stop(10 * time.Second); err != nil {
+			slog.Warn("⚠️ Background workers did not stop cleanly", "error", err)
+		}
+		jobs.Stop()
+		if metricsHistory != nil {
+			metricsHistory.Record()
+		}
+		if changeFeed != nil {
+			changeFeed.Stop()
+		}
+
+		// ปิด database connection ถ้ามี
 		if mongoDB != nil {
 			if err := mongoDB.Close(); err != nil {
-				log.Printf("⚠️ Error closing MongoDB connection: %v", err)
+				slog.Warn("⚠️ Error closing MongoDB connection", "error", err)
+			}
+		}
+		if postgresStore != nil {
+			if err := postgresStore.Close(); err != nil {
+				slog.Warn("⚠️ Error closing PostgreSQL connection", "error", err)
+			}
+		}
+		if sqliteStore != nil {
+			if err := sqliteStore.Close(); err != nil {
+				slog.Warn("⚠️ Error closing SQLite database", "error", err)
 			}
 		}
 
+		if redirectServer != nil {
+			redirectServer.Shutdown(ctx)
+		}
 		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("❌ Server shutdown error: %v", err)
+			slog.Error("❌ Server shutdown error", "error", err)
 		} else {
-			log.Println("✅ Server shutdown completed")
+			slog.Info("✅ Server shutdown completed")
 		}
 	}()
 
-	log.Printf("🚀 Starting WebSocket Chat Server on port %s", cfg.Port)
-	log.Printf("📡 WebSocket endpoint: ws://localhost%s/ws", cfg.Port)
-	log.Printf("🌐 Test page: http://localhost%s", cfg.Port)
-	log.Printf("👥 Connection Manager: Ready (Max: %d)", cfg.MaxConnections)
-	log.Printf("🔐 User Manager: Ready")
-	log.Printf("🏠 Room Manager: Ready (Max: %d)", cfg.MaxRooms)
-	log.Printf("📋 Command Handler: Ready")
-	log.Printf("📊 Message Service: Ready")
+	slog.Info("🚀 Starting WebSocket Chat Server", "version", version.Version, "commit", version.Commit, "protocol", version.Protocol, "port", cfg.Port)
+	wsScheme, httpScheme, host := "ws", "http", "localhost"
+	if tlsSetup != nil {
+		wsScheme, httpScheme = "wss", "https"
+		if cfg.TLSAutocert {
+			host = strings.TrimSpace(cfg.TLSAutocertHosts[0])
+		}
+	}
+	slog.Info("📡 WebSocket endpoint", "url", wsScheme+"://"+host+port+"/ws")
+	slog.Info("🌐 Test page", "url", httpScheme+"://"+host+port)
+	slog.Info("👥 Connection Manager: Ready", "max_connections", cfg.MaxConnections)
+	slog.Info("🔐 User Manager: Ready")
+	slog.Info("🏠 Room Manager: Ready", "max_rooms", cfg.MaxRooms)
+	slog.Info("📋 Command Handler: Ready")
+	slog.Info("📊 Message Service: Ready")
 
 	if cfg.EnableMongoDB && mongoDB != nil {
-		log.Printf("🗄️  Database: MongoDB (%s/%s)", cfg.MongoURI, cfg.MongoDatabase)
+		slog.Info("🗄️  Database: MongoDB", "uri", cfg.MongoURI, "database", cfg.MongoDatabase)
+	} else if postgresStore != nil {
+		slog.Info("🗄️  Database: PostgreSQL", "max_conns", cfg.PostgresMaxConns)
+	} else if sqliteStore != nil {
+		slog.Info("🗄️  Database: SQLite", "path", cfg.SQLitePath)
 	} else {
-		log.Printf("🗄️  Database: In-Memory")
+		slog.Info("🗄️  Database: In-Memory")
 	}
 
-	log.Printf("⚙️  Configuration: Heartbeat=%v, ReadTimeout=%v, WriteTimeout=%v",
-		cfg.HeartbeatInterval, cfg.ReadTimeout, cfg.WriteTimeout)
+	slog.Info("⚙️  Configuration", "heartbeat", cfg.HeartbeatInterval, "read_timeout", cfg.ReadTimeout, "write_timeout", cfg.WriteTimeout,
+		"log_level", cfg.LogLevel, "log_format", cfg.LogFormat)
+
+	// self-test ตอนเริ่ม: รายงานอย่างเดียว ไม่หยุด server (ใช้ --check-only เพื่อให้ล้มเหลวได้)
+	report := selftest.Run(selftest.Options{
+		Config:       cfg,
+		MongoEnabled: mongoRequested,
+		MongoDB:      mongoDB,
+		MongoErr:     mongoErr,
+		StaticDir:    staticDir,
+		CheckPort:    true,
+	})
+	report.Print(log.Writer())
+	if report.Failed() {
+		slog.Warn("⚠️ Self-test reported failures, starting anyway")
+	}
 
-	log.Println("🛑 Press Ctrl+C for graceful shutdown")
+	slog.Info("🛑 Press Ctrl+C for graceful shutdown")
 
 	// เริ่ม server
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("❌ Server failed to start: %v", err)
+	if redirectServer != nil {
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("❌ HTTP to HTTPS redirect server failed", "addr", cfg.TLSRedirectAddr, "error", err)
+			}
+		}()
+		slog.Info("↪️ Redirecting HTTP to HTTPS", "addr", cfg.TLSRedirectAddr)
+	}
+	if tlsSetup != nil {
+		err = server.ListenAndServeTLS(tlsSetup.CertFile, tlsSetup.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		slog.Error("❌ Server failed to start", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("👋 Server stopped gracefully")
+}
+
+// registeredAdmins keeps the admin_usernames entries that belong to a registered account.
+// ชื่อที่ยังไม่มี account ใครก็สมัครหรือใช้เป็น guest ได้ก่อน จึงไม่ให้สิทธิ์ admin จนกว่าจะสมัครแล้ว restart
+func registeredAdmins(names []string, accounts *account.Service) []string {
+	admins := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		registered, err := accounts.IsRegistered(name)
+		if err != nil || !registered {
+			slog.Error("❌ Ignoring admin without a registered account", "username", name, "error", err,
+				"hint", "register the account with POST /api/register, then restart (the memory backend forgets accounts on restart)")
+			continue
+		}
+		admins = append(admins, name)
+	}
+	return admins
+}
+
+// runCheckOnly runs the self-test without starting the server and returns the process exit code
+func runCheckOnly(cfg *config.ServerConfig) int {
+	if backend, err := storage.Backend(cfg); err == nil {
+		cfg.EnableMongoDB = backend == storage.BackendMongoDB
+	}
+	opts := selftest.Options{
+		Config:       cfg,
+		MongoEnabled: cfg.EnableMongoDB,
+		StaticDir:    staticDir,
+		CheckPort:    true,
 	}
 
-	log.Println("👋 Server stopped gracefully")
+	// เชื่อมต่ออย่างเดียว ไม่สร้าง index หรือรัน migration เพื่อให้เห็นสถานะจริงของ database
+	if cfg.EnableMongoDB {
+		mongoDB, err := database.NewMongoDB(&database.MongoConfig{
+			URI:            cfg.MongoURI,
+			Database:       cfg.MongoDatabase,
+			ConnectTimeout: cfg.MongoConnectTimeout,
+			PingTimeout:    cfg.MongoPingTimeout,
+			MaxPoolSize:    cfg.MongoMaxPoolSize,
+			MinPoolSize:    cfg.MongoMinPoolSize,
+		})
+		opts.MongoDB, opts.MongoErr = mongoDB, err
+		if mongoDB != nil {
+			defer mongoDB.Close()
+		}
+	}
+
+	report := selftest.Run(opts)
+	report.Print(os.Stdout)
+	if report.Failed() {
+		fmt.Fprintln(os.Stderr, "❌ Self-test failed")
+		return 1
+	}
+	return 0
+}
+
+// newUploadStorage creates the attachment storage selected by config (local disk or S3-compatible)
+func newUploadStorage(cfg *config.ServerConfig) (attachment.Storage, error) {
+	if cfg.UploadStorage == "s3" {
+		return attachment.NewS3Storage(attachment.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+		})
+	}
+	return attachment.NewLocalStorage(cfg.UploadDir, cfg.UploadBaseURL)
+}
+
+// noDirectoryListing serves files only, so uploaded attachments cannot be enumerated
+func noDirectoryListing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
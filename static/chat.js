@@ -1,4 +1,44 @@
 // Chat Application JavaScript
+//
+// Reference client for the JSON protocol served on /ws. Every frame is a JSON object with a "type".
+//
+// Client -> server
+//   join            {username, timezone, capabilities: ['hb', 'batch'], session_token?}   first frame, answered by welcome/rooms_list/users_list
+//                   with 'batch' accepted, a frame may be a JSON array of server messages sent within batch_window_ms
+//                   session_token comes from POST /api/login and joins as the account's username;
+//                   registered names without it fail with error code account_required
+//   resume          {token}                                       instead of join after a reconnect, answered by resumed
+//   message         {content, parent_id?, client_msg_id?}         parent_id makes it a thread reply; client_msg_id
+//                   is answered by ack, and a retry with the same id is acked again without a second message
+//   join_room / leave_room / create_room {room}
+//   get_history     {room, limit}          get_thread {parent_id, limit}
+//   react           {target: message id, value: emoji}          toggles the reaction
+//   typing_start / typing_stop             hb (after the capability is accepted)
+//   command         {command: '/edit <id> <text>' ...}          see /help for the full list
+//   offer_file      {target: user, file: {name, size, mime_type}}
+//   accept_file / decline_file / cancel_file {transfer_id}
+//   file_chunk      {transfer_id, seq, data: base64}             sender, at most transfer.window unacked
+//   file_chunk_ack  {transfer_id, seq}                           recipient, after each chunk
+//   set_profile     {display_name?, avatar_url?, status_text?}   omitted fields are kept, "" clears
+//
+// Server -> client
+//   hello {server: {version, commit, protocol, protocols, features}}  always the first frame
+//   message (with id, parent_id), history, thread, thread_updated, reaction_added/reaction_removed,
+//   message_edited, message_deleted, typing_start/typing_stop, presence_changed, users_list,
+//   rooms_list {rooms: [{name, user_count, max_users, topic, last_activity}]}, room_joined, capabilities, hb, reconnect_policy {code, reason, message} (sent before the server closes), error {message, code, details},
+//   file_offer, file_offer_sent, file_accepted, file_declined, file_cancelled, file_progress, file_complete {transfer},
+//   file_chunk {chunk: {transfer_id, seq, data}}, session {resume_token}, resumed {room, messages, total},
+//   profile_updated {username, profile: {display_name, avatar_url, status_text}},
+//   ack {client_msg_id, message_id, room, status: persisted|delivered, duplicate} once our message was saved and broadcast
+//   room_archived / room_reactivated {room, message} when an empty room is archived or re-created with /create
+//   transport {status: websocket|sse} which transport delivers room messages now
+//
+// SSE fallback: GET /events?token=<resume_token> streams the same frames (one JSON object per data line) for
+// this session. While the WebSocket is up it only carries keepalives; when the WebSocket drops, room messages
+// arrive here instead of waiting for resume, each on exactly one transport. It survives resume, so it is only
+// reopened (with the latest resume token) if the stream itself closes.
+const CLIENT_PROTOCOL = 1;
+
 class ChatApp {
     constructor() {
         this.ws = null;
@@ -6,8 +46,20 @@ class ChatApp {
         this.currentUser = null;
         this.currentRoom = 'general';
         this.rooms = new Set(['general']);
+        this.roomInfo = {}; // room -> {user_count, max_users, topic, last_activity} from rooms_list
+        this.unread = {};
         this.users = new Set();
+        this.presence = {};
+        this.states = {}; // username -> online/away/offline
+        this.profiles = {}; // username -> {display_name, avatar_url, status_text}
+        this.pendingMessages = {}; // client_msg_id -> message frame awaiting its ack
+        this.typingUsers = new Map(); // username -> timer that hides the indicator
+        this.typingSentAt = 0;
         this.messageHistory = [];
+        this.replyTo = null; // message id the next message replies to
+        this.resumeRoom = null; // room to rejoin after a reconnect
+        this.outgoingFiles = new Map(); // transfer id -> {file, nextSeq, inFlight}
+        this.incomingFiles = new Map(); // transfer id -> {transfer, parts}
         
         this.initializeElements();
         this.bindEvents();
@@ -26,6 +78,7 @@ class ChatApp {
         this.messages = document.getElementById('messages');
         this.messageInput = document.getElementById('messageInput');
         this.sendBtn = document.getElementById('sendBtn');
+        this.typingIndicator = document.getElementById('typingIndicator');
 
         // Status elements
         this.connectionStatus = document.getElementById('connectionStatus');
@@ -63,6 +116,10 @@ class ChatApp {
         this.messageInput.addEventListener('keypress', (e) => {
             if (e.key === 'Enter') this.sendMessage();
         });
+        this.messageInput.addEventListener('input', () => {
+            this.scheduleDraftSave();
+            this.notifyTyping();
+        });
 
         // Room events
         this.createRoomBtn.addEventListener('click', () => this.showCreateRoomModal());
@@ -157,10 +214,11 @@ class ChatApp {
     }
 
     connectWebSocket() {
-        const wsUrl = `ws://${window.location.host}/ws`;
+        const scheme = window.location.protocol === 'https:' ? 'wss' : 'ws';
+        const wsUrl = `${scheme}://${window.location.host}/ws`;
         
         try {
-            this.ws = new WebSocket(wsUrl);
+            this.ws = new WebSocket(wsUrl, ['chat.v1.json']);
             
             this.ws.onopen = () => {
                 this.onWebSocketOpen();
@@ -186,41 +244,149 @@ class ChatApp {
 
     onWebSocketOpen() {
         this.isConnected = true;
+        this.reconnectAttempts = 0;
         this.updateConnectionStatus(true);
         this.showChatInterface();
         
-        // Send username to server
-        this.sendToServer({
+        // Within the server's grace period a resume token restores our session and missed messages
+        if (this.resumeToken && this.resumeRoom) {
+            this.sendToServer({ type: 'resume', token: this.resumeToken });
+        } else {
+            this.sendJoin();
+        }
+        this.showNotification('Connected to chat server', 'success');
+    }
+
+    sendJoin() {
+        this.resumeToken = null;
+        const join = {
             type: 'join',
-            username: this.currentUser
-        });
+            username: this.currentUser,
+            timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+            capabilities: ['hb', 'batch']
+        };
+        // Token saved after POST /api/login; the server then uses the account's username
+        const sessionToken = localStorage.getItem('chatSessionToken');
+        if (sessionToken) {
+            join.session_token = sessionToken;
+        }
+        this.sendToServer(join);
         
-        this.showNotification('Connected to chat server', 'success');
+        // Rejoin: the server always starts us in 'general', so rejoin the room we were in before the drop
+        if (this.resumeRoom && this.resumeRoom !== 'general') {
+            this.sendToServer({ type: 'join_room', room: this.resumeRoom });
+        } else {
+            this.currentRoom = 'general';
+            this.currentRoomName.textContent = 'general';
+            this.clearMessages();
+            this.requestHistory(25, true);
+        }
+        this.resumeRoom = null;
     }
 
     onWebSocketMessage(event) {
         try {
             const data = JSON.parse(event.data);
-            this.handleServerMessage(data);
+            // Batched frames (capability 'batch') carry several messages in send order;
+            // plain text frames appear in a batch as strings
+            (Array.isArray(data) ? data : [data]).forEach(frame => {
+                if (typeof frame === 'string') {
+                    this.displayPlainText(frame);
+                } else if (frame.type === 'chunk') {
+                    this.handleChunk(frame);
+                } else {
+                    this.handleServerMessage(frame);
+                }
+            });
         } catch (error) {
             // Handle plain text messages (for backward compatibility)
-            this.displayMessage({
-                type: 'message',
-                content: event.data,
-                sender: 'System',
-                timestamp: new Date().toISOString()
-            });
+            this.displayPlainText(event.data);
         }
     }
 
+    displayPlainText(text) {
+        this.displayMessage({
+            type: 'message',
+            content: text,
+            sender: 'System',
+            timestamp: new Date().toISOString()
+        });
+    }
+
+    // Reassemble a payload the server split because it exceeded the max frame size
+    handleChunk(data) {
+        this.chunks = this.chunks || {};
+        const pending = this.chunks[data.chunk_id] || (this.chunks[data.chunk_id] = { parts: [], received: 0 });
+        if (pending.parts[data.index] === undefined) {
+            pending.parts[data.index] = data.data;
+            pending.received++;
+        }
+        if (pending.received < data.total) return;
+
+        delete this.chunks[data.chunk_id];
+        const binary = pending.parts.map(part => atob(part)).join('');
+        const bytes = Uint8Array.from(binary, c => c.charCodeAt(0));
+        this.handleServerMessage(JSON.parse(new TextDecoder().decode(bytes)));
+    }
+
     onWebSocketClose(event) {
         this.isConnected = false;
+        clearInterval(this.heartbeatTimer);
         this.updateConnectionStatus(false);
+        this.resumeRoom = this.currentRoom;
+        
+        const policy = this.reconnectPolicy;
+        this.reconnectPolicy = null;
         
-        if (event.code !== 1000) { // Not a normal closure
-            this.showNotification('Connection lost. Attempting to reconnect...', 'warning');
-            setTimeout(() => this.connectWebSocket(), 3000);
+        if (event.code === 1000) { // Normal closure
+            return;
+        }
+        
+        // Application close codes sent by the server (see internal/websocket/close.go)
+        if (event.code === 4006) { // throttled
+            this.showNotification('Too many failed attempts. Please wait before reconnecting.', 'error');
+            return;
+        }
+        if (event.code === 4007) { // protocol_violation
+            this.showNotification('Disconnected for sending invalid messages', 'error');
+            return;
+        }
+        if (event.code === 4002) { // idle_timeout
+            // Reconnecting right away would just idle out again; wait until the user is back
+            this.showNotification((policy && policy.message) || 'Disconnected due to inactivity', 'warning');
+            this.reconnectOnActivity();
+            return;
         }
+        if (event.code === 4001 || (policy && !policy.reconnect)) { // kicked
+            this.showNotification('You were removed from the server', 'error');
+            return;
+        }
+        
+        const delay = this.getReconnectDelay(policy);
+        this.reconnectAttempts = (this.reconnectAttempts || 0) + 1;
+        this.showNotification(`Connection lost. Reconnecting in ${Math.round(delay / 1000)}s...`, 'warning');
+        setTimeout(() => this.connectWebSocket(), delay);
+    }
+
+    reconnectOnActivity() {
+        const events = ['focus', 'keydown', 'pointerdown'];
+        const reconnect = () => {
+            events.forEach(name => window.removeEventListener(name, reconnect));
+            this.connectWebSocket();
+        };
+        events.forEach(name => window.addEventListener(name, reconnect));
+    }
+
+    // Exponential backoff bounded by the server's reconnect_policy hint, with jitter
+    getReconnectDelay(policy) {
+        const minBackoff = policy ? policy.min_backoff_ms : 3000;
+        const maxBackoff = policy ? policy.max_backoff_ms : 30000;
+        const jitter = policy ? policy.jitter : 0.5;
+        
+        const attempt = this.reconnectAttempts || 0;
+        const base = Math.min(maxBackoff, minBackoff * Math.pow(2, attempt));
+        const spread = base * jitter;
+        return Math.max(minBackoff, base - spread + Math.random() * spread * 2);
     }
 
     onWebSocketError(error) {
@@ -231,7 +397,38 @@ class ChatApp {
     handleServerMessage(data) {
         switch (data.type) {
             case 'message':
+                this.setTyping(data.username, false);
                 this.displayMessage(data);
+                // Messages shown in the open room count as read
+                if (data.id && data.room_name === this.currentRoom) {
+                    this.sendToServer({ type: 'mark_read', room: data.room_name, target: data.id });
+                }
+                break;
+            case 'digest':
+                // Very large room in sampled mode: messages arrive batched unless you take part
+                if (data.room === this.currentRoom) {
+                    (data.messages || []).forEach(msg => this.displayMessage(msg));
+                    if (data.total > (data.messages || []).length) {
+                        this.displaySystemMessage(`${data.total - data.messages.length} more messages in ${data.room} — open history to see them`);
+                    }
+                }
+                break;
+            case 'delivery_mode':
+                if (data.room === this.currentRoom) {
+                    this.displaySystemMessage(data.status === 'sampled'
+                        ? `${data.room} is very busy: you will get messages in batches until you post or are mentioned`
+                        : `${data.room} is back to live delivery`);
+                }
+                break;
+            case 'unread_counts':
+                this.unread = data.unread || {};
+                this.updateRoomsList(Array.from(this.rooms));
+                break;
+            case 'typing_start':
+            case 'typing_stop':
+                if (data.room === this.currentRoom) {
+                    this.setTyping(data.username, data.type === 'typing_start');
+                }
                 break;
             case 'user_joined':
                 this.handleUserJoined(data);
@@ -244,28 +441,271 @@ class ChatApp {
                 break;
             case 'room_joined':
                 this.handleRoomJoined(data);
+                this.sendToServer({ type: 'get_unread_counts' });
                 break;
             case 'room_left':
                 this.handleRoomLeft(data);
                 break;
             case 'users_list':
-                this.updateUsersList(data.users);
+                this.updateUsersList(data.users, data.members);
+                break;
+            case 'profile_updated':
+                // Display name, avatar or status of a member changed (including our own)
+                this.profiles[data.username] = data.profile || {};
+                this.updateUsersList(Array.from(this.users));
+                break;
+            case 'presence_changed':
+                // Online/away/offline derived by the server from activity and connection health
+                this.states[data.username] = data.status;
+                this.updateUsersList(Array.from(this.users));
+                break;
+            case 'presence':
+                // External status (e.g. calendar busy) changed for a member of this room
+                if (data.presence) {
+                    this.presence[data.username] = data.presence;
+                } else {
+                    delete this.presence[data.username];
+                }
+                this.updateUsersList(Array.from(this.users));
                 break;
             case 'rooms_list':
-                this.updateRoomsList(data.rooms);
+                this.applyRoomSummaries(data.rooms || []);
                 break;
             case 'history':
-                this.displayHistory(data.messages);
+                if (this.inlineHistoryRoom && data.room === this.inlineHistoryRoom) {
+                    // History requested on join is shown in the conversation itself
+                    this.inlineHistoryRoom = null;
+                    (data.messages || []).forEach(msg => this.displayMessage(msg));
+                } else {
+                    this.displayHistory(data.messages || []);
+                }
                 break;
             case 'search_results':
                 this.displaySearchResults(data.messages);
                 break;
+            case 'ack':
+                this.handleAck(data);
+                break;
             case 'error':
+                if (data.client_msg_id) {
+                    // Rejected for good: retrying the same message would fail again
+                    delete this.pendingMessages[data.client_msg_id];
+                    const failed = this.findPendingMessage(data.client_msg_id);
+                    if (failed) {
+                        failed.classList.remove('pending');
+                        failed.classList.add('failed');
+                    }
+                }
+                if (data.code === 'session_active') {
+                    // The server has not noticed our old connection dropped yet
+                    const retry = ((data.details && data.details.retry_after_seconds) || 5) * 1000;
+                    setTimeout(() => this.sendToServer({ type: 'resume', token: this.resumeToken }), retry);
+                    break;
+                }
+                if (data.code === 'resume_failed') {
+                    this.sendJoin();
+                    break;
+                }
+                if (data.code === 'invalid_session') {
+                    // Expired or revoked login: forget it so the next join is a guest join
+                    localStorage.removeItem('chatSessionToken');
+                }
                 this.showNotification(data.message, 'error');
                 break;
-            case 'system':
+            case 'session':
+                // Single-use token for resuming this session after a reconnect
+                this.resumeToken = data.resume_token;
+                this.openFallback(data.resume_token);
+                break;
+            case 'transport':
+                // The server switched delivery between the WebSocket and the SSE fallback
+                this.transport = data.status;
+                if (!this.isConnected && data.status === 'sse') {
+                    this.statusText.textContent = 'Reconnecting (receiving messages)';
+                }
+                break;
+            case 'resumed':
+                this.resumeRoom = null;
+                this.currentRoom = data.room;
+                this.currentRoomName.textContent = data.room;
+                this.displaySystemMessage(data.message);
+                (data.messages || []).forEach(message => this.displayMessage(message));
+                if (data.total > (data.messages || []).length) {
+                    this.displaySystemMessage(`${data.total - data.messages.length} older messages were missed — open history to see them`);
+                }
+                // Messages sent while the connection was dropping may never have arrived; the server dedups retries
+                Object.values(this.pendingMessages).forEach(msg => this.sendToServer(msg));
+                break;
+            case 'delivery_probe':
+                // Server is sampling delivery latency; acknowledge immediately
+                this.sendToServer({ type: 'delivery_ack', probe_id: data.probe_id });
+                break;
+            case 'hello':
+                // First frame on every connection: server build, protocol versions and feature flags
+                this.server = data.server;
+                if (data.server && !(data.server.protocols || []).includes(CLIENT_PROTOCOL)) {
+                    this.showNotification(`Server ${data.server.version} does not support protocol ${CLIENT_PROTOCOL}; some features may not work`, 'warning');
+                }
+                break;
+            case 'capabilities':
+                // Application-level heartbeat keeps us alive behind proxies that strip WS pings
+                clearInterval(this.heartbeatTimer);
+                if ((data.features || []).includes('hb')) {
+                    const interval = ((data.details && data.details.heartbeat_interval_seconds) || 30) * 1000;
+                    this.heartbeatTimer = setInterval(() => this.sendToServer({ type: 'hb' }), interval);
+                }
+                break;
+            case 'hb':
+                break;
+            case 'reconnect_policy':
+                // Server is about to close the connection; remember how to back off
+                this.reconnectPolicy = data;
+                break;
+            case 'draft':
+                // Restore a draft saved from this or another device
+                if (data.room === this.currentRoom && !this.messageInput.value && data.content) {
+                    this.messageInput.value = data.content;
+                }
+                break;
+            case 'reaction_added':
+            case 'reaction_removed':
+                // Counts are authoritative; replace the whole bar
+                this.renderReactions(data.target, data.reactions || []);
+                break;
+            case 'thread_updated':
+                this.renderReplyCount(data.target, data.thread ? data.thread.reply_count : 0);
+                break;
+            case 'thread':
+                this.displayThread(data);
+                break;
+            case 'message_edited': {
+                const el = this.findMessage(data.target);
+                if (el) {
+                    el.querySelector('.message-content').textContent = data.content;
+                    el.querySelector('.message-edited').textContent = '(edited)';
+                }
+                break;
+            }
+            case 'message_deleted': {
+                const el = this.findMessage(data.target);
+                if (el) {
+                    el.classList.add('deleted');
+                    el.querySelector('.message-content').textContent = '🗑️ message deleted';
+                    el.querySelector('.message-reactions').innerHTML = '';
+                }
+                break;
+            }
+            case 'preferences':
+                this.applyPreferences(data.preferences || {});
+                break;
+            case 'room_expiring':
+                this.showNotification(data.message, 'warning');
                 this.displaySystemMessage(data.message);
                 break;
+            case 'room_expired':
+                // Server archived a temporary room and moved us out of it
+                this.rooms.delete(data.room);
+                this.updateRoomsList(Array.from(this.rooms));
+                if (this.currentRoom === data.room) {
+                    this.currentRoom = null;
+                    this.currentRoomName.textContent = 'No room';
+                }
+                this.displaySystemMessage(data.message);
+                break;
+            case 'room_archived':
+                // Nobody used the room for a while; /create brings it back with its history
+                this.rooms.delete(data.room);
+                this.updateRoomsList(Array.from(this.rooms));
+                this.displaySystemMessage(data.message);
+                break;
+            case 'room_reactivated':
+                this.rooms.add(data.room);
+                this.updateRoomsList(Array.from(this.rooms));
+                break;
+            case 'direct_message':
+                // Private message: only the sender and the recipient receive it
+                if (data.username === this.currentUser) {
+                    this.displaySystemMessage(`💬 [DM → ${data.target}] ${data.content}`);
+                } else {
+                    this.displaySystemMessage(`💬 [DM from ${data.username}] ${data.content}`);
+                }
+                break;
+            case 'announcement':
+                this.displaySystemMessage(`📢 ${data.content}`);
+                if (window.confirm(`📢 Announcement from ${data.username}:\n\n${data.content}\n\nAcknowledge?`)) {
+                    this.sendToServer({ type: 'ack_announcement', target: data.target });
+                }
+                break;
+            case 'missed_messages':
+                // Mentions and DMs queued while offline; acknowledge them so they are not delivered again
+                this.displaySystemMessage(data.message);
+                data.missed.forEach(missed => {
+                    const where = missed.kind === 'direct' ? 'DM' : `#${missed.room_name}`;
+                    const time = new Date(missed.timestamp).toLocaleString();
+                    this.displaySystemMessage(`📭 [${where}] ${missed.from} (${time}): ${missed.content}`);
+                });
+                this.sendToServer({ type: 'mark_read', ids: data.missed.map(missed => missed.id) });
+                break;
+            case 'marked_read':
+                break;
+            case 'rate_limit_status':
+                // Sent once when only a few messages remain before the rate limit kicks in
+                this.displaySystemMessage(data.message);
+                break;
+            case 'notification':
+                // Someone mentioned us; the notification stays unread until /notifications read
+                this.displaySystemMessage(`🔔 ${data.notification.title}: ${data.notification.message}`);
+                break;
+            case 'notifications':
+                (data.notifications || []).forEach(notification => {
+                    const marker = notification.is_read ? '•' : '🆕';
+                    this.displaySystemMessage(`${marker} ${notification.title}: ${notification.message}`);
+                });
+                break;
+            case 'trace_event': {
+                const ev = data.trace.event;
+                const dropped = data.trace.dropped ? ` (${data.trace.dropped} dropped)` : '';
+                console.log(`[trace ${data.trace.id}] ${data.trace.connection}`, ev);
+                this.displaySystemMessage(`🔬 ${data.trace.connection} ${ev.kind} ${ev.bytes || 0}B queue ${ev.queue_depth}/${ev.queue_cap}${ev.healthy ? '' : ' unhealthy'}${ev.detail ? ' - ' + ev.detail : ''}${dropped}`);
+                break;
+            }
+            case 'trace_stopped':
+                this.displaySystemMessage(data.message);
+                break;
+            case 'announcement_acked':
+                this.showNotification('Announcement acknowledged', 'success');
+                break;
+            case 'file_offer':
+                this.handleFileOffer(data.transfer);
+                break;
+            case 'file_offer_sent':
+                this.displaySystemMessage(`📎 Offered ${data.transfer.file.name} to ${data.transfer.to}, waiting for a reply...`);
+                break;
+            case 'file_accepted':
+                this.sendFileChunks(data.transfer);
+                break;
+            case 'file_chunk':
+                this.receiveFileChunk(data.chunk);
+                break;
+            case 'file_progress':
+                this.sendFileChunks(data.transfer);
+                break;
+            case 'file_complete':
+                this.finishFileTransfer(data.transfer);
+                break;
+            case 'file_declined':
+            case 'file_cancelled':
+                this.outgoingFiles.delete(data.transfer.id);
+                this.incomingFiles.delete(data.transfer.id);
+                this.displaySystemMessage(`📎 Transfer of ${data.transfer.file.name} was ${data.transfer.state}`);
+                break;
+            case 'system':
+                // Command replies carry text in content plus structured fields (rooms, users, messages, results)
+                this.displaySystemMessage(data.message || data.content);
+                if (data.rooms) {
+                    this.applyRoomSummaries(data.rooms);
+                }
+                break;
             default:
                 console.log('Unknown message type:', data);
         }
@@ -287,14 +727,33 @@ class ChatApp {
         if (content.startsWith('/')) {
             this.handleCommand(content);
         } else {
-            this.sendToServer({
+            const msg = {
+                type: 'message',
+                content: content,
+                room: this.currentRoom,
+                client_msg_id: this.newClientMsgId()
+            };
+            if (this.replyTo) {
+                msg.parent_id = this.replyTo;
+            }
+            this.pendingMessages[msg.client_msg_id] = msg;
+            this.sendToServer(msg);
+            // The server does not echo our own messages back; shown as pending until the ack
+            this.displayMessage({
                 type: 'message',
                 content: content,
-                room: this.currentRoom
+                username: this.currentUser,
+                parent_id: this.replyTo,
+                client_msg_id: msg.client_msg_id,
+                timestamp: new Date().toISOString()
             });
         }
 
+        this.setReplyTo(null);
         this.messageInput.value = '';
+        // The server treats a sent message as the end of typing
+        clearTimeout(this.typingStopTimer);
+        this.typingSentAt = 0;
     }
 
     handleCommand(command) {
@@ -335,6 +794,13 @@ class ChatApp {
                 const myLimit = args.length > 0 ? parseInt(args[0]) : 25;
                 this.requestMyHistory(myLimit);
                 break;
+            case '/sendfile':
+                if (args.length > 0) {
+                    this.offerFile(args[0]);
+                } else {
+                    this.showNotification('Usage: /sendfile <user>', 'error');
+                }
+                break;
             default:
                 // Send command to server
                 this.sendToServer({
@@ -345,6 +811,104 @@ class ChatApp {
         }
     }
 
+    // Pick a file and offer it to another user; bytes are only sent after they accept
+    offerFile(username) {
+        const input = document.createElement('input');
+        input.type = 'file';
+        input.onchange = () => {
+            const file = input.files[0];
+            if (!file) {
+                return;
+            }
+            this.pendingFile = file;
+            this.sendToServer({
+                type: 'offer_file',
+                target: username,
+                file: { name: file.name, size: file.size, mime_type: file.type }
+            });
+        };
+        input.click();
+    }
+
+    handleFileOffer(transfer) {
+        const accept = window.confirm(`📎 ${transfer.from} wants to send you ${transfer.file.name} (${transfer.file.size} bytes). Accept?`);
+        if (accept) {
+            this.incomingFiles.set(transfer.id, { transfer, parts: [] });
+        }
+        this.sendToServer({ type: accept ? 'accept_file' : 'decline_file', transfer_id: transfer.id });
+    }
+
+    // Send chunks until the window of unacknowledged chunks is full; called again on every file_progress
+    async sendFileChunks(transfer) {
+        if (transfer.from !== this.currentUser) {
+            return;
+        }
+        let state = this.outgoingFiles.get(transfer.id);
+        if (!state) {
+            state = { file: this.pendingFile, nextSeq: 0 };
+            this.pendingFile = null;
+            this.outgoingFiles.set(transfer.id, state);
+        }
+        if (!state.file) {
+            this.sendToServer({ type: 'cancel_file', transfer_id: transfer.id });
+            return;
+        }
+
+        const acked = Math.ceil(transfer.transferred / transfer.chunk_size);
+        while (state.nextSeq - acked < transfer.window && state.nextSeq * transfer.chunk_size < transfer.file.size) {
+            // Claim the seq before awaiting so overlapping progress events never send it twice
+            const seq = state.nextSeq++;
+            const start = seq * transfer.chunk_size;
+            const bytes = new Uint8Array(await state.file.slice(start, start + transfer.chunk_size).arrayBuffer());
+            let binary = '';
+            bytes.forEach(b => { binary += String.fromCharCode(b); });
+            this.sendToServer({ type: 'file_chunk', transfer_id: transfer.id, seq, data: btoa(binary) });
+        }
+    }
+
+    receiveFileChunk(chunk) {
+        const state = this.incomingFiles.get(chunk.transfer_id);
+        if (!state) {
+            return;
+        }
+        const binary = atob(chunk.data);
+        state.parts.push(Uint8Array.from(binary, c => c.charCodeAt(0)));
+        this.sendToServer({ type: 'file_chunk_ack', transfer_id: chunk.transfer_id, seq: chunk.seq });
+    }
+
+    finishFileTransfer(transfer) {
+        this.outgoingFiles.delete(transfer.id);
+        const state = this.incomingFiles.get(transfer.id);
+        this.incomingFiles.delete(transfer.id);
+        if (!state) {
+            this.displaySystemMessage(`📎 ${transfer.file.name} delivered to ${transfer.to}`);
+            return;
+        }
+
+        const blob = new Blob(state.parts, { type: transfer.file.mime_type || 'application/octet-stream' });
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(blob);
+        link.download = transfer.file.name;
+        link.click();
+        URL.revokeObjectURL(link.href);
+        this.displaySystemMessage(`📎 Received ${transfer.file.name} from ${transfer.from}`);
+    }
+
+    // SSE fallback for the same session (see the protocol notes at the top)
+    openFallback(token) {
+        if (!window.EventSource || (this.fallback && this.fallback.readyState !== EventSource.CLOSED)) {
+            return;
+        }
+        this.fallback = new EventSource(`/events?token=${encodeURIComponent(token)}`);
+        this.fallback.onmessage = (event) => this.handleServerMessage(JSON.parse(event.data));
+        this.fallback.onerror = () => {
+            // A rejected token closes the stream for good; the next session frame reopens it
+            if (this.fallback && this.fallback.readyState === EventSource.CLOSED) {
+                this.fallback = null;
+            }
+        };
+    }
+
     sendToServer(data) {
         if (this.isConnected && this.ws) {
             this.ws.send(JSON.stringify(data));
@@ -354,27 +918,64 @@ class ChatApp {
     displayMessage(data) {
         const messageDiv = document.createElement('div');
         messageDiv.className = 'message';
+        // Chat messages carry the sender's username; sender is the connection id
+        const author = data.username || data.sender;
         
         // Determine message type
-        if (data.sender === this.currentUser) {
+        if (author === this.currentUser) {
             messageDiv.classList.add('own');
         } else if (data.type === 'system') {
             messageDiv.classList.add('system');
         } else {
             messageDiv.classList.add('other');
         }
+        if (data.id) {
+            messageDiv.dataset.messageId = data.id;
+        }
+        if (data.client_msg_id && !data.id) {
+            messageDiv.dataset.clientMsgId = data.client_msg_id;
+            messageDiv.classList.add('pending');
+        }
+        if (data.is_deleted) {
+            messageDiv.classList.add('deleted');
+        }
 
         const timestamp = new Date(data.timestamp).toLocaleTimeString();
         
         let messageHTML = '';
-        if (data.type !== 'system' && data.sender !== this.currentUser) {
-            messageHTML += `<div class="message-header">${data.sender}</div>`;
+        if (data.type !== 'system' && author !== this.currentUser) {
+            // display_name/avatar_url are the sender's profile when the message was sent
+            const name = data.display_name || (this.profiles[author] || {}).display_name || author;
+            const avatar = data.avatar_url || (this.profiles[author] || {}).avatar_url;
+            messageHTML += `<div class="message-header">${this.renderAvatar(avatar)}${this.escapeHtml(name)}</div>`;
+        }
+        if (data.parent_id) {
+            messageHTML += `<div class="message-reply-to">↪ reply to ${this.escapeHtml(data.parent_id)}</div>`;
         }
         
-        messageHTML += `<div class="message-content">${this.escapeHtml(data.content)}</div>`;
-        messageHTML += `<div class="message-time">${timestamp}</div>`;
+        const content = data.is_deleted ? '🗑️ message deleted' : data.content;
+        messageHTML += `<div class="message-content">${this.escapeHtml(content)}</div>`;
+        if (data.attachments && !data.is_deleted) {
+            messageHTML += this.renderAttachments(data.attachments);
+        }
+        messageHTML += `<div class="message-time">${timestamp} <span class="message-edited">${data.edit_history && !data.is_deleted ? '(edited)' : ''}</span></div>`;
+        messageHTML += '<div class="message-reactions"></div>';
+        if (data.id && data.type === 'message' && !data.is_deleted) {
+            messageHTML += `
+                <div class="message-actions">
+                    <button class="btn btn-sm" data-action="react" title="React">👍</button>
+                    <button class="btn btn-sm" data-action="reply" title="Reply"><i class="fas fa-reply"></i></button>
+                    <button class="btn btn-sm" data-action="thread" title="View thread"><i class="fas fa-comments"></i> <span class="reply-count"></span></button>
+                </div>`;
+        }
         
         messageDiv.innerHTML = messageHTML;
+        if (data.id) {
+            messageDiv.querySelectorAll('[data-action]').forEach(button => {
+                button.addEventListener('click', () => this.onMessageAction(data.id, button.dataset.action));
+            });
+            this.renderReactions(data.id, data.reactions || [], messageDiv);
+        }
         
         this.messages.appendChild(messageDiv);
         this.scrollToBottom();
@@ -386,6 +987,20 @@ class ChatApp {
         }
     }
 
+    // Uploaded files: images show their thumbnail, other files a download link
+    renderAttachments(attachments) {
+        return attachments.map(file => {
+            const url = this.escapeAttr(file.url);
+            const name = this.escapeHtml(file.file_name);
+            const size = `${Math.max(1, Math.round(file.file_size / 1024))} KB`;
+            if (file.file_type === 'image') {
+                const preview = this.escapeAttr(file.thumbnail_url || file.url);
+                return `<a class="message-attachment image" href="${url}" target="_blank" rel="noopener"><img src="${preview}" alt="${this.escapeAttr(file.file_name)}"></a>`;
+            }
+            return `<a class="message-attachment" href="${url}" target="_blank" rel="noopener"><i class="fas fa-paperclip"></i> ${name} (${size})</a>`;
+        }).join('');
+    }
+
     displaySystemMessage(message) {
         this.displayMessage({
             type: 'system',
@@ -395,6 +1010,98 @@ class ChatApp {
         });
     }
 
+    findMessage(messageId) {
+        return this.messages.querySelector(`[data-message-id="${CSS.escape(messageId)}"]`);
+    }
+
+    findPendingMessage(clientMsgId) {
+        return this.messages.querySelector(`[data-client-msg-id="${CSS.escape(clientMsgId)}"]`);
+    }
+
+    newClientMsgId() {
+        if (window.crypto && crypto.randomUUID) {
+            return crypto.randomUUID();
+        }
+        return `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`;
+    }
+
+    // Our message reached the server: give the optimistic copy its server id so reactions and edits work
+    handleAck(data) {
+        delete this.pendingMessages[data.client_msg_id];
+        const el = this.findPendingMessage(data.client_msg_id);
+        if (!el) {
+            return;
+        }
+        el.classList.remove('pending');
+        if (data.message_id) {
+            el.dataset.messageId = data.message_id;
+        }
+    }
+
+    onMessageAction(messageId, action) {
+        switch (action) {
+            case 'react':
+                this.sendToServer({ type: 'react', target: messageId, value: '👍' });
+                break;
+            case 'reply':
+                this.setReplyTo(messageId);
+                this.messageInput.focus();
+                break;
+            case 'thread':
+                this.sendToServer({ type: 'get_thread', parent_id: messageId });
+                break;
+        }
+    }
+
+    setReplyTo(messageId) {
+        this.replyTo = messageId;
+        this.messageInput.placeholder = messageId ? `Reply to ${messageId}...` : 'Type a message...';
+    }
+
+    renderReactions(messageId, reactions, el = this.findMessage(messageId)) {
+        if (!el) return;
+        const bar = el.querySelector('.message-reactions');
+        bar.innerHTML = '';
+        reactions.forEach(reaction => {
+            const chip = document.createElement('button');
+            chip.className = 'reaction-chip';
+            if ((reaction.users || []).includes(this.currentUser)) {
+                chip.classList.add('mine');
+            }
+            chip.textContent = `${reaction.emoji} ${reaction.count}`;
+            chip.title = (reaction.users || []).join(', ');
+            chip.addEventListener('click', () => this.sendToServer({ type: 'react', target: messageId, value: reaction.emoji }));
+            bar.appendChild(chip);
+        });
+    }
+
+    renderReplyCount(messageId, count) {
+        const el = this.findMessage(messageId);
+        const counter = el && el.querySelector('.reply-count');
+        if (counter) {
+            counter.textContent = count > 0 ? count : '';
+        }
+    }
+
+    displayThread(data) {
+        const messages = data.messages || [];
+        if (data.thread) {
+            this.renderReplyCount(data.target, data.thread.reply_count);
+        }
+        const historyMessages = document.getElementById('historyMessages');
+        this.displayHistory(messages);
+        historyMessages.insertAdjacentHTML('afterbegin',
+            `<div class="text-muted">🧵 Thread with ${messages.length - 1} repl${messages.length === 2 ? 'y' : 'ies'}</div>`);
+        this.showModal(this.historyModal);
+    }
+
+    // Rooms arrive as summaries ({name, user_count, max_users, topic, last_activity}) in rooms_list and /rooms
+    applyRoomSummaries(rooms) {
+        this.roomInfo = {};
+        rooms.forEach(room => { this.roomInfo[room.name] = room; });
+        this.updateRoomsList(rooms.map(room => room.name));
+    }
+
     handleUserJoined(data) {
         this.users.add(data.username);
         this.updateUsersList(Array.from(this.users));
@@ -415,26 +1122,114 @@ class ChatApp {
 
     handleRoomJoined(data) {
         this.currentRoom = data.room;
+        this.typingUsers.forEach(timer => clearTimeout(timer));
+        this.typingUsers.clear();
+        this.renderTyping();
         this.currentRoomName.textContent = data.room;
         this.clearMessages();
         this.updateRoomsList(Array.from(this.rooms));
         this.displaySystemMessage(`Joined room: ${data.room}`);
+        if (data.capabilities && data.capabilities.private) {
+            this.displaySystemMessage('🔒 This room is in privacy mode: messages are delivered only, never stored or searchable');
+        }
+        this.messageInput.value = '';
+        this.setReplyTo(null);
+        this.requestHistory(25, true);
+        this.sendToServer({ type: 'get_draft', room: data.room });
+    }
+
+    setPreference(key, value) {
+        // Stored server-side so other devices pick it up on next login
+        this.sendToServer({ type: 'set_pref', key: key, value: String(value) });
+    }
+
+    applyPreferences(prefs) {
+        this.preferences = prefs;
+        document.body.classList.toggle('compact', prefs.compact_mode === 'true');
+        if (prefs.theme) {
+            document.body.dataset.theme = prefs.theme;
+        }
+    }
+
+    scheduleDraftSave() {
+        // Save the draft after the user pauses typing instead of on every keystroke
+        clearTimeout(this.draftTimer);
+        this.draftTimer = setTimeout(() => {
+            if (!this.isConnected || !this.currentRoom) return;
+            this.sendToServer({ type: 'save_draft', room: this.currentRoom, content: this.messageInput.value });
+        }, 1000);
+    }
+
+    notifyTyping() {
+        if (!this.isConnected || !this.currentRoom || this.messageInput.value.startsWith('/')) return;
+        // The server debounces too; re-send start periodically so other clients keep showing the indicator
+        const now = Date.now();
+        if (now - this.typingSentAt > 2000) {
+            this.typingSentAt = now;
+            this.sendToServer({ type: 'typing_start' });
+        }
+        clearTimeout(this.typingStopTimer);
+        this.typingStopTimer = setTimeout(() => {
+            this.typingSentAt = 0;
+            this.sendToServer({ type: 'typing_stop' });
+        }, 3000);
+    }
+
+    setTyping(username, typing) {
+        clearTimeout(this.typingUsers.get(username));
+        this.typingUsers.delete(username);
+        if (typing) {
+            // Hide the indicator if the sender disappears without a typing_stop
+            this.typingUsers.set(username, setTimeout(() => this.setTyping(username, false), 6000));
+        }
+        this.renderTyping();
+    }
+
+    renderTyping() {
+        const names = Array.from(this.typingUsers.keys());
+        if (names.length === 0) {
+            this.typingIndicator.textContent = '';
+        } else if (names.length <= 2) {
+            this.typingIndicator.textContent = `${names.join(' and ')} ${names.length === 1 ? 'is' : 'are'} typing...`;
+        } else {
+            this.typingIndicator.textContent = 'Several people are typing...';
+        }
     }
 
     handleRoomLeft(data) {
         this.displaySystemMessage(`Left room: ${data.room}`);
     }
 
-    updateUsersList(users) {
+    updateUsersList(users, members) {
         this.users = new Set(users);
         this.usersList.innerHTML = '';
+        if (members) {
+            this.presence = {};
+            this.states = {};
+            members.forEach(m => {
+                if (m.presence) this.presence[m.username] = m.presence;
+                this.states[m.username] = m.status;
+                this.profiles[m.username] = {
+                    display_name: m.display_name,
+                    avatar_url: m.avatar_url,
+                    status_text: m.status_text
+                };
+            });
+        }
         
         users.forEach(user => {
             const userDiv = document.createElement('div');
             userDiv.className = 'user-item';
+            const presence = this.presence[user];
+            const presenceText = presence
+                ? `${presence.message || presence.status} (until ${new Date(presence.until).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })})`
+                : (this.profiles[user] || {}).status_text || '';
+            const profile = this.profiles[user] || {};
             userDiv.innerHTML = `
-                <div class="status-dot"></div>
-                <span>${this.escapeHtml(user)}</span>
+                <div class="status-dot ${this.states[user] || 'online'}" title="${this.states[user] || 'online'}"></div>
+                ${this.renderAvatar(profile.avatar_url)}
+                <span title="${this.escapeHtml(user)}">${this.escapeHtml(profile.display_name || user)}</span>
+                ${presenceText ? `<small class="user-presence">${this.escapeHtml(presenceText)}</small>` : ''}
             `;
             this.usersList.appendChild(userDiv);
         });
@@ -442,6 +1237,14 @@ class ChatApp {
         this.roomUserCount.textContent = `${users.length} user${users.length !== 1 ? 's' : ''}`;
     }
 
+    renderAvatar(url) {
+        // The server only accepts http(s) avatar URLs
+        if (!url || !/^https?:\/\//.test(url)) {
+            return '';
+        }
+        return `<img class="avatar" src="${this.escapeHtml(url)}" alt="" loading="lazy" referrerpolicy="no-referrer">`;
+    }
+
     updateRoomsList(rooms) {
         this.rooms = new Set(rooms);
         this.roomsList.innerHTML = '';
@@ -454,6 +1257,21 @@ class ChatApp {
             }
             
             roomDiv.textContent = room;
+            const info = this.roomInfo[room];
+            if (info) {
+                const count = document.createElement('span');
+                count.className = 'room-user-count';
+                count.textContent = ` (${info.user_count}/${info.max_users})`;
+                roomDiv.appendChild(count);
+                roomDiv.title = info.topic || '';
+            }
+            const unread = this.unread[room];
+            if (unread && room !== this.currentRoom) {
+                const badge = document.createElement('span');
+                badge.className = 'unread-badge';
+                badge.textContent = unread >= 100 ? '99+' : unread;
+                roomDiv.appendChild(badge);
+            }
             roomDiv.addEventListener('click', () => this.joinRoom(room));
             
             this.roomsList.appendChild(roomDiv);
@@ -536,7 +1354,9 @@ class ChatApp {
         this.requestHistory(limit);
     }
 
-    requestHistory(limit = 25) {
+    requestHistory(limit = 25, inline = false) {
+        // inline history fills the conversation after joining instead of the history modal
+        this.inlineHistoryRoom = inline ? this.currentRoom : null;
         this.sendToServer({
             type: 'get_history',
             room: this.currentRoom,
@@ -562,11 +1382,11 @@ class ChatApp {
             const timestamp = new Date(msg.timestamp).toLocaleString();
             messageDiv.innerHTML = `
                 <div class="message-meta">
-                    <strong>${this.escapeHtml(msg.sender)}</strong> 
-                    in <em>${this.escapeHtml(msg.room || 'general')}</em> 
+                    <strong>${this.escapeHtml(msg.username || msg.sender)}</strong> 
+                    in <em>${this.escapeHtml(msg.room_name || msg.room || 'general')}</em> 
                     at ${timestamp}
                 </div>
-                <div class="message-content">${this.escapeHtml(msg.content)}</div>
+                <div class="message-content">${this.escapeHtml(msg.is_deleted ? '🗑️ message deleted' : msg.content)}</div>
             `;
             
             historyMessages.appendChild(messageDiv);
@@ -681,6 +1501,11 @@ class ChatApp {
         div.textContent = text;
         return div.innerHTML;
     }
+
+    // escapeHtml leaves quotes alone, which is not enough inside attribute values
+    escapeAttr(text) {
+        return this.escapeHtml(text).replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+    }
 }
 
 // Initialize the chat application when the page loads